@@ -0,0 +1,286 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package depgraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ghodss/yaml"
+)
+
+// ExportSchemaVersion : version of the declarative graph export schema.
+// Incremented whenever the schema changes in a backward-incompatible way,
+// so that consumers (config review tooling, golden-file tests) can detect
+// exports produced by a different schema.
+const ExportSchemaVersion = 1
+
+// ExportedGraph : content of a dependency (sub)graph serialized into
+// a stable, declarative schema. Can be marshalled into JSON or YAML
+// (all fields have lower-camel-case keys) and rebuilt back into a Graph
+// using GraphImporter.
+// The output is deterministic: items are ordered by (type, name) and
+// subgraphs by their names, making the serialized form suitable for diffing
+// and for golden-file regression testing.
+// Note that only the graph content (the intended configuration) is exported;
+// runtime state data stored alongside items (ItemState) are not included.
+type ExportedGraph struct {
+	// SchemaVersion : always equal to ExportSchemaVersion at the time
+	// of the export. Only present in the top-level graph (zero in subgraphs).
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+	// Name of the (sub)graph.
+	Name string `json:"name"`
+	// Description of the (sub)graph.
+	Description string `json:"description,omitempty"`
+	// Items : items placed directly under this (sub)graph,
+	// ordered by (type, name).
+	Items []ExportedItem `json:"items,omitempty"`
+	// SubGraphs : direct subgraphs of this graph, ordered by name.
+	SubGraphs []ExportedGraph `json:"subGraphs,omitempty"`
+}
+
+// ExportedItem : a single graph item serialized into the declarative schema.
+type ExportedItem struct {
+	// Type of the item (Item.Type()).
+	Type string `json:"type"`
+	// Name of the item (Item.Name()), unique within items of the same type.
+	Name string `json:"name"`
+	// Label of the item (Item.Label()), omitted when equal to the name.
+	Label string `json:"label,omitempty"`
+	// External : true for items not managed by the owner of the graph
+	// (Item.External()).
+	External bool `json:"external,omitempty"`
+	// Description : human-readable description of the item (Item.String()).
+	Description string `json:"description,omitempty"`
+	// Attributes : all attributes of the item, serialized from the underlying
+	// item structure using encoding/json.
+	Attributes json.RawMessage `json:"attributes,omitempty"`
+	// Dependencies of the item (Item.Dependencies()).
+	Dependencies []ExportedDependency `json:"dependencies,omitempty"`
+}
+
+// ExportedDependency : item dependency serialized into the declarative schema.
+// Dependencies are exported for review purposes only - they are not used
+// by GraphImporter, which instead lets the rebuilt items declare their
+// dependencies themselves (Item.Dependencies()).
+type ExportedDependency struct {
+	// ItemType : type of the required item.
+	ItemType string `json:"itemType"`
+	// ItemName : name of the required item.
+	ItemName string `json:"itemName"`
+	// Description of the dependency.
+	Description string `json:"description,omitempty"`
+	// MustSatisfy : true if the required item must not only exist but also
+	// satisfy a programmatic condition (which itself is not serializable).
+	MustSatisfy bool `json:"mustSatisfy,omitempty"`
+	// RecreateWhenModified : see DependencyAttributes.RecreateWhenModified.
+	RecreateWhenModified bool `json:"recreateWhenModified,omitempty"`
+	// AutoDeletedByExternal : see DependencyAttributes.AutoDeletedByExternal.
+	AutoDeletedByExternal bool `json:"autoDeletedByExternal,omitempty"`
+	// ExternalHoldDownTime : see DependencyAttributes.ExternalHoldDownTime.
+	// Serialized in nanoseconds.
+	ExternalHoldDownTime int64 `json:"externalHoldDownTime,omitempty"`
+}
+
+// GraphExporter exports the content of a dependency graph into the stable,
+// declarative schema described by ExportedGraph. Unlike DotExporter, which
+// targets visualization, this output is meant to be machine-processed:
+// reviewed offline, diffed between EVE versions or compared against golden
+// files in regression tests of reconciler output.
+type GraphExporter struct{}
+
+// Export serializes the graph (including all nested subgraphs) into
+// the declarative schema.
+func (e *GraphExporter) Export(graph GraphR) (ExportedGraph, error) {
+	exported, err := e.exportSubGraph(graph)
+	if err != nil {
+		return exported, err
+	}
+	exported.SchemaVersion = ExportSchemaVersion
+	return exported, nil
+}
+
+// ExportToJSON serializes the graph into an indented JSON document.
+func (e *GraphExporter) ExportToJSON(graph GraphR) (string, error) {
+	exported, err := e.Export(graph)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal exported graph %s: %w",
+			graph.Name(), err)
+	}
+	return string(data), nil
+}
+
+// ExportToYAML serializes the graph into a YAML document.
+func (e *GraphExporter) ExportToYAML(graph GraphR) (string, error) {
+	exported, err := e.Export(graph)
+	if err != nil {
+		return "", err
+	}
+	jsonData, err := json.Marshal(exported)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal exported graph %s: %w",
+			graph.Name(), err)
+	}
+	yamlData, err := yaml.JSONToYAML(jsonData)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert exported graph %s to YAML: %w",
+			graph.Name(), err)
+	}
+	return string(yamlData), nil
+}
+
+func (e *GraphExporter) exportSubGraph(graph GraphR) (ExportedGraph, error) {
+	exported := ExportedGraph{
+		Name:        graph.Name(),
+		Description: graph.Description(),
+	}
+	iter := graph.Items(false)
+	for iter.Next() {
+		item, _ := iter.Item()
+		exportedItem, err := e.exportItem(item)
+		if err != nil {
+			return exported, err
+		}
+		exported.Items = append(exported.Items, exportedItem)
+	}
+	sort.Slice(exported.Items, func(i, j int) bool {
+		ref := ItemRef{
+			ItemType: exported.Items[i].Type,
+			ItemName: exported.Items[i].Name,
+		}
+		ref2 := ItemRef{
+			ItemType: exported.Items[j].Type,
+			ItemName: exported.Items[j].Name,
+		}
+		return ref.Compare(ref2) == -1
+	})
+	subGraphs := graph.SubGraphs()
+	for subGraphs.Next() {
+		exportedSubGraph, err := e.exportSubGraph(subGraphs.SubGraph())
+		if err != nil {
+			return exported, err
+		}
+		exported.SubGraphs = append(exported.SubGraphs, exportedSubGraph)
+	}
+	sort.Slice(exported.SubGraphs, func(i, j int) bool {
+		return exported.SubGraphs[i].Name < exported.SubGraphs[j].Name
+	})
+	return exported, nil
+}
+
+func (e *GraphExporter) exportItem(item Item) (ExportedItem, error) {
+	exported := ExportedItem{
+		Type:        item.Type(),
+		Name:        item.Name(),
+		External:    item.External(),
+		Description: item.String(),
+	}
+	if label := item.Label(); label != item.Name() {
+		exported.Label = label
+	}
+	attrs, err := json.Marshal(item)
+	if err != nil {
+		return exported, fmt.Errorf("failed to marshal attributes of item %v: %w",
+			Reference(item), err)
+	}
+	exported.Attributes = attrs
+	for _, dep := range item.Dependencies() {
+		exported.Dependencies = append(exported.Dependencies, ExportedDependency{
+			ItemType:              dep.RequiredItem.ItemType,
+			ItemName:              dep.RequiredItem.ItemName,
+			Description:           dep.Description,
+			MustSatisfy:           dep.MustSatisfy != nil,
+			RecreateWhenModified:  dep.Attributes.RecreateWhenModified,
+			AutoDeletedByExternal: dep.Attributes.AutoDeletedByExternal,
+			ExternalHoldDownTime:  int64(dep.Attributes.ExternalHoldDownTime),
+		})
+	}
+	return exported, nil
+}
+
+// ItemDecoder rebuilds a concrete Item implementation from its attributes
+// as serialized by GraphExporter (i.e. encoding/json marshalling of the
+// underlying item structure).
+type ItemDecoder func(attributes json.RawMessage) (Item, error)
+
+// GraphImporter rebuilds a dependency graph from the declarative schema
+// produced by GraphExporter. Since items are interface implementations,
+// the user must register a decoder for every item type present in the
+// imported document.
+type GraphImporter struct {
+	itemDecoders map[string]ItemDecoder
+}
+
+// RegisterItemDecoder registers a decoder used to rebuild items of the given
+// type. Replaces a previously registered decoder for the same item type.
+func (i *GraphImporter) RegisterItemDecoder(itemType string, decoder ItemDecoder) {
+	if i.itemDecoders == nil {
+		i.itemDecoders = make(map[string]ItemDecoder)
+	}
+	i.itemDecoders[itemType] = decoder
+}
+
+// Import rebuilds a graph (including all nested subgraphs) from the
+// declarative schema. The exported dependencies are not consulted - the
+// rebuilt items declare their dependencies themselves.
+func (i *GraphImporter) Import(exported ExportedGraph) (Graph, error) {
+	if exported.SchemaVersion != ExportSchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version: %d (expected %d)",
+			exported.SchemaVersion, ExportSchemaVersion)
+	}
+	return i.importSubGraph(exported)
+}
+
+// ImportFromJSON rebuilds a graph from a JSON document produced
+// by GraphExporter.ExportToJSON.
+func (i *GraphImporter) ImportFromJSON(data []byte) (Graph, error) {
+	var exported ExportedGraph
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exported graph: %w", err)
+	}
+	return i.Import(exported)
+}
+
+// ImportFromYAML rebuilds a graph from a YAML document produced
+// by GraphExporter.ExportToYAML.
+func (i *GraphImporter) ImportFromYAML(data []byte) (Graph, error) {
+	jsonData, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert exported graph to JSON: %w", err)
+	}
+	return i.ImportFromJSON(jsonData)
+}
+
+func (i *GraphImporter) importSubGraph(exported ExportedGraph) (Graph, error) {
+	graph := New(InitArgs{
+		Name:        exported.Name,
+		Description: exported.Description,
+	})
+	for _, exportedItem := range exported.Items {
+		decoder, hasDecoder := i.itemDecoders[exportedItem.Type]
+		if !hasDecoder {
+			return nil, fmt.Errorf("missing decoder for item type %s",
+				exportedItem.Type)
+		}
+		item, err := decoder(exportedItem.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode item %s/%s: %w",
+				exportedItem.Type, exportedItem.Name, err)
+		}
+		graph.PutItem(item, nil)
+	}
+	for _, exportedSubGraph := range exported.SubGraphs {
+		subGraph, err := i.importSubGraph(exportedSubGraph)
+		if err != nil {
+			return nil, err
+		}
+		graph.PutSubGraph(subGraph)
+	}
+	return graph, nil
+}
@@ -0,0 +1,26 @@
+module github.com/lf-edge/eve-libs
+
+go 1.20
+
+require (
+	cloud.google.com/go/storage v1.36.0
+	github.com/Azure/azure-pipeline-go v0.2.3
+	github.com/Azure/azure-storage-blob-go v0.15.0
+	github.com/aws/aws-sdk-go v1.44.82
+	github.com/ghodss/yaml v1.0.0
+	github.com/golang-design/lockfree v0.0.1
+	github.com/google/go-containerregistry v0.14.0
+	github.com/google/gopacket v1.1.19
+	github.com/lithammer/shortuuid/v4 v4.0.0
+	github.com/mdlayher/netlink v1.7.1
+	github.com/packetcap/go-pcap v0.0.0-20230717110547-c34843f9206d
+	github.com/pkg/sftp v1.13.1
+	github.com/sirupsen/logrus v1.9.3
+	github.com/ti-mo/conntrack v0.4.0
+	golang.org/x/crypto v0.21.0
+	golang.org/x/net v0.23.0
+	golang.org/x/oauth2 v0.16.0
+	golang.org/x/sys v0.18.0
+	golang.org/x/text v0.14.0
+	google.golang.org/api v0.160.0
+)
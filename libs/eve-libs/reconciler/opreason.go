@@ -0,0 +1,58 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package reconciler
+
+import "context"
+
+// OperationReason : cause for which a Create/Modify/Delete operation
+// is being executed by the reconciler.
+// Configurators may use the reason to optimize operations, for example
+// to skip flushing flows or releasing resources when a Delete is merely
+// the first half of a Recreate, or to avoid touching an interface which
+// is disappearing together with its dependency.
+type OperationReason int
+
+const (
+	// ReasonUnknown : reason for the operation was not specified.
+	ReasonUnknown OperationReason = iota
+	// ReasonExplicit : operation follows directly from a change
+	// in the intended state (item was added, modified or removed).
+	ReasonExplicit
+	// ReasonRecreate : operation is part of a Recreate - a Delete followed
+	// by a Create of the modified item (see Configurator.NeedsRecreate).
+	ReasonRecreate
+	// ReasonDepCascade : Delete is executed because one or more dependencies
+	// of the item are no longer satisfied.
+	ReasonDepCascade
+)
+
+// String returns a human-readable description of the operation reason.
+func (r OperationReason) String() string {
+	switch r {
+	case ReasonExplicit:
+		return "explicit"
+	case ReasonRecreate:
+		return "recreate"
+	case ReasonDepCascade:
+		return "dependency-cascade"
+	}
+	return "unknown"
+}
+
+// withOperationReason returns a new context recording the reason for
+// the operation that is about to be executed.
+func withOperationReason(ctx context.Context, reason OperationReason) context.Context {
+	return context.WithValue(ctx, opReasonCtxKey, reason)
+}
+
+// GetOperationReason : reason for the operation that the Configurator
+// is being called for. To be used from inside Create/Modify/Delete.
+// Returns ReasonUnknown if the context does not come from the reconciler.
+func GetOperationReason(ctx context.Context) OperationReason {
+	reason, withReason := ctx.Value(opReasonCtxKey).(OperationReason)
+	if !withReason {
+		return ReasonUnknown
+	}
+	return reason
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package reconciler
+
+import (
+	"time"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+)
+
+// tombstoneKey identifies a tombstone record: a dependency edge from a dependent
+// item to a required (external) item that is currently not satisfied.
+// The dependent item is part of the key because the same required item may
+// satisfy dependencies of multiple items differently (via MustSatisfy).
+type tombstoneKey struct {
+	dependentItem dg.ItemRef
+	requiredItem  dg.ItemRef
+}
+
+// unsatisfiedDepsHeldDown returns true if every unsatisfied dependency of the
+// item is within its configured hold-down period (DependencyAttributes.
+// ExternalHoldDownTime). In that case the deletion of the item is postponed,
+// giving rapidly flapping external items a chance to reappear without
+// triggering a delete+create storm of dependents.
+// When a dependency with a non-zero hold-down time is first noticed as
+// unsatisfied, a tombstone with the current time is recorded and a resume
+// signal is scheduled for the moment when the hold-down period expires,
+// so that the caller knows to re-run the reconciliation.
+func (r *reconciler) unsatisfiedDepsHeldDown(gctx *graphCtx,
+	currentFullState dg.GraphR, graphName string, item dg.Item) bool {
+	var heldDown bool
+	now := time.Now()
+	for _, dep := range item.Dependencies() {
+		key := tombstoneKey{
+			dependentItem: dg.Reference(item),
+			requiredItem:  dep.RequiredItem,
+		}
+		if r.satisfiedDep(currentFullState, dep) {
+			// The required item (re)appeared, remove any stale tombstone.
+			delete(gctx.tombstones, key)
+			continue
+		}
+		holdDownTime := dep.Attributes.ExternalHoldDownTime
+		if holdDownTime == 0 {
+			// Dependency without hold-down, delete the dependent immediately.
+			return false
+		}
+		since, haveTombstone := gctx.tombstones[key]
+		if !haveTombstone {
+			gctx.tombstones[key] = now
+			gctx.asyncManager.scheduleResume(graphName, holdDownTime)
+			heldDown = true
+			continue
+		}
+		if now.Sub(since) < holdDownTime {
+			heldDown = true
+			continue
+		}
+		// The required item remained absent beyond the grace period.
+		delete(gctx.tombstones, key)
+		return false
+	}
+	return heldDown
+}
+
+// delTombstonesFor removes all tombstones recorded for the given dependent item.
+// Called when the item is deleted from the current state for another reason
+// (e.g. removed from the intended state).
+func (r *reconciler) delTombstonesFor(gctx *graphCtx, item dg.ItemRef) {
+	for key := range gctx.tombstones {
+		if key.dependentItem == item {
+			delete(gctx.tombstones, key)
+		}
+	}
+}
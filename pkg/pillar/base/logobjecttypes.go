@@ -136,6 +136,10 @@ const (
 	NetworkInstanceMetricsLogType LogObjectType = "network_instance_metrics"
 	// NetworkMetricsLogType:
 	NetworkMetricsLogType LogObjectType = "network_metrics"
+	// NetworkCapabilitiesLogType:
+	NetworkCapabilitiesLogType LogObjectType = "network_capabilities"
+	// NetworkTopologyLogType:
+	NetworkTopologyLogType LogObjectType = "network_topology"
 	// WwanConfigLogType:
 	WwanConfigLogType LogObjectType = "wwan_config"
 	// WwanStatusLogType:
@@ -83,7 +83,7 @@ func TestPostKubeconfig(t *testing.T) {
 	niStatus := types.NetworkInstanceStatus{
 		NetworkInstanceInfo: types.NetworkInstanceInfo{
 			IPAssignments: map[string]types.AssignedAddrs{"k": {
-				IPv4Addr: net.ParseIP("192.168.1.1"),
+				IPv4Addr: types.AssignedAddr{Address: net.ParseIP("192.168.1.1")},
 			}},
 		},
 		SelectedUplinkIntfName: "eth0",
@@ -68,10 +68,10 @@ func (srv *Msrv) lookupNetworkInstanceStatusByAppIP(
 	for _, st := range items {
 		status := st.(types.NetworkInstanceStatus)
 		for _, addrs := range status.IPAssignments {
-			if ip.Equal(addrs.IPv4Addr) {
+			if ip.Equal(addrs.GetIPv4Addr()) {
 				return &status
 			}
-			for _, nip := range addrs.IPv6Addrs {
+			for _, nip := range addrs.GetIPv6Addrs() {
 				if ip.Equal(nip) {
 					return &status
 				}
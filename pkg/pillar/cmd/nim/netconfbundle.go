@@ -0,0 +1,163 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package nim
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/netconfbundle"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	fileutils "github.com/lf-edge/eve/pkg/pillar/utils/file"
+)
+
+// This file wires the network config bundle exporter and importer
+// (see pkg netconfbundle) into NIM. The export is requested by the operator
+// through ZedAgentStatus and writes the bundle into the /persist partition,
+// from where it can be collected. The import looks for a bundle inside
+// the read-only config partition and applies the carried DevicePortConfig
+// as an override network configuration, just like the DPC json files
+// in /config/DevicePortConfig.
+
+const (
+	// netconfBundleKeyFile : pre-shared key used to sign and verify network
+	// config bundles. Provisioned into the config partition, typically
+	// during device manufacturing.
+	netconfBundleKeyFile = types.IdentityDirname + "/netconf-bundle.key"
+	// netconfBundleImportFile : location inside the config partition from
+	// which a network config bundle is imported.
+	netconfBundleImportFile = types.IdentityDirname + "/netconf-bundle.json"
+	// netconfBundleExportFile : location where the exported network config
+	// bundle is written.
+	netconfBundleExportFile = types.PersistDir + "/netconf-bundle.json"
+	// netconfBundleDPCKey : key under which the DPC imported from a network
+	// config bundle is applied.
+	netconfBundleDPCKey = "netconf-bundle"
+)
+
+// handleNetconfExportRequest exports the full network configuration of the
+// device into a signed bundle when requested by the operator
+// (see types.NetconfExportRequest).
+func (n *nim) handleNetconfExportRequest(request types.NetconfExportRequest) {
+	if request.RequestedAt.IsZero() {
+		// No export was requested.
+		return
+	}
+	if !request.RequestedAt.After(n.netconfExportReqTime) {
+		// This request was already handled.
+		return
+	}
+	n.netconfExportReqTime = request.RequestedAt
+	key, err := os.ReadFile(netconfBundleKeyFile)
+	if err != nil {
+		n.Log.Errorf("Cannot export network config bundle: "+
+			"failed to read the signing key: %v", err)
+		return
+	}
+	obj, err := n.pubDevicePortConfigList.Get("global")
+	if err != nil {
+		n.Log.Errorf("Cannot export network config bundle: "+
+			"failed to get published DPCL: %v", err)
+		return
+	}
+	dpcl := obj.(types.DevicePortConfigList)
+	if dpcl.CurrentIndex < 0 || dpcl.CurrentIndex >= len(dpcl.PortConfigList) {
+		n.Log.Errorf("Cannot export network config bundle: " +
+			"no currently applied DPC")
+		return
+	}
+	bundle := netconfbundle.Bundle{
+		CreatedAt:        time.Now(),
+		DevicePortConfig: dpcl.PortConfigList[dpcl.CurrentIndex],
+	}
+	if obj, err = n.subOnboardStatus.Get("global"); err == nil {
+		bundle.DeviceUUID = obj.(types.OnboardingStatus).DeviceUUID
+	}
+	for _, obj = range n.subNetworkInstConfig.GetAll() {
+		bundle.NetworkInstances = append(bundle.NetworkInstances,
+			obj.(types.NetworkInstanceConfig))
+	}
+	data, err := netconfbundle.Export(bundle, key)
+	if err != nil {
+		n.Log.Errorf("Failed to export network config bundle: %v", err)
+		return
+	}
+	if err = fileutils.WriteRename(netconfBundleExportFile, data); err != nil {
+		n.Log.Errorf("Failed to write network config bundle to %s: %v",
+			netconfBundleExportFile, err)
+		return
+	}
+	n.Log.Noticef("Exported network config bundle to %s (%d ports, "+
+		"%d network instances)", netconfBundleExportFile,
+		len(bundle.DevicePortConfig.Ports), len(bundle.NetworkInstances))
+}
+
+// importNetconfBundle looks for a network config bundle inside the config
+// partition, verifies its signature and applies the carried DevicePortConfig
+// as an override network configuration (published into
+// /run/global/DevicePortConfig, like the DPC json files from
+// /config/DevicePortConfig). The sha of an already ingested bundle is
+// persisted to avoid re-application of the same config.
+// The network instance definitions carried by the bundle are validated but
+// not applied device-side - network instances are provisioned by the
+// controller once the device is onboarded.
+func (n *nim) importNetconfBundle() {
+	if _, err := os.Stat(netconfBundleImportFile); err != nil {
+		// No bundle to import.
+		return
+	}
+	key, err := os.ReadFile(netconfBundleKeyFile)
+	if err != nil {
+		n.Log.Errorf("Cannot import network config bundle: "+
+			"failed to read the verification key: %v", err)
+		return
+	}
+	data, err := fileutils.ReadWithMaxSize(n.Log, netconfBundleImportFile,
+		maxReadSize)
+	if err != nil {
+		n.Log.Errorf("Failed to read network config bundle %s: %v",
+			netconfBundleImportFile, err)
+		return
+	}
+	bundle, err := netconfbundle.Import(data, key)
+	if err != nil {
+		n.Log.Errorf("Refusing to import network config bundle %s: %v",
+			netconfBundleImportFile, err)
+		return
+	}
+	// Use sha to determine if the bundle has already been ingested.
+	shaFilename := filepath.Join(types.IngestedDirname, "NetconfBundle",
+		netconfBundleDPCKey) + ".sha"
+	changed, bundleSha, err := fileutils.CompareSha(netconfBundleImportFile,
+		shaFilename)
+	dpc := bundle.DevicePortConfig
+	if err != nil {
+		n.Log.Errorf("CompareSha failed: %s", err)
+	} else if changed {
+		dpc.ShaFile = shaFilename
+		dpc.ShaValue = bundleSha
+	} else {
+		n.Log.Noticef("No change to %s", netconfBundleImportFile)
+		return
+	}
+	dpc.DoSanitize(n.Log, true, true, netconfBundleDPCKey, true, true)
+	data, err = json.Marshal(dpc)
+	if err != nil {
+		n.Log.Fatalf("Failed to json marshall imported DevicePortConfig "+
+			"err %s", err)
+	}
+	filename := path.Join(runDevicePortConfigDir, netconfBundleDPCKey+".json")
+	err = fileutils.WriteRename(filename, data)
+	if err != nil {
+		n.Log.Errorf("Failed to write imported DevicePortConfig to %s: %s",
+			filename, err)
+		return
+	}
+	n.Log.Noticef("Imported network config bundle created at %v by device %s "+
+		"(%d ports, %d network instances referenced)", bundle.CreatedAt,
+		bundle.DeviceUUID, len(dpc.Ports), len(bundle.NetworkInstances))
+}
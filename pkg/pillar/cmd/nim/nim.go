@@ -22,7 +22,11 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/dpcmanager"
 	"github.com/lf-edge/eve/pkg/pillar/dpcreconciler"
 	"github.com/lf-edge/eve/pkg/pillar/flextimer"
+	"github.com/lf-edge/eve/pkg/pillar/iptables"
+	"github.com/lf-edge/eve/pkg/pillar/netconformance"
 	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
+	"github.com/lf-edge/eve/pkg/pillar/oobchannel"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/pubsub"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	fileutils "github.com/lf-edge/eve/pkg/pillar/utils/file"
@@ -44,6 +48,9 @@ const (
 	runDevicePortConfigDir    = "/run/global/DevicePortConfig"
 	maxReadSize               = 16384       // Punt on too large files
 	dpcAvailableTimeLimit     = time.Minute // TODO: make configurable?
+	// oobDPCKey : key used for override DPC injected through the out-of-band
+	// management channel (see oobchannel).
+	oobDPCKey = "serial-oob"
 )
 
 // Really a constant
@@ -61,16 +68,21 @@ type nim struct {
 	Logger *logrus.Logger
 	PubSub *pubsub.PubSub
 
-	useStdout bool
+	useStdout    bool
+	useNetworkd  bool
+	oobSerialDev string
 
 	// CLI args
-	stdoutPtr *bool
+	stdoutPtr    *bool
+	networkdPtr  *bool
+	oobSerialPtr *string
 
 	// NIM components
 	connTester     *conntester.ZedcloudConnectivityTester
 	dpcManager     *dpcmanager.DpcManager
 	dpcReconciler  dpcreconciler.DpcReconciler
 	networkMonitor netmonitor.NetworkMonitor
+	oobChannel     *oobchannel.OOBChannel
 
 	// Subscriptions
 	subGlobalConfig       pubsub.Subscription
@@ -83,6 +95,7 @@ type nim struct {
 	subAssignableAdapters pubsub.Subscription
 	subOnboardStatus      pubsub.Subscription
 	subWwanStatus         pubsub.Subscription
+	subNetworkInstConfig  pubsub.Subscription
 
 	// Publications
 	pubDummyDevicePortConfig pubsub.Publication // For logging
@@ -90,15 +103,22 @@ type nim struct {
 	pubDevicePortConfigList  pubsub.Publication
 	pubCipherBlockStatus     pubsub.Publication
 	pubDeviceNetworkStatus   pubsub.Publication
+	pubNetworkReadiness      pubsub.Publication
+	pubDPCMetrics            pubsub.Publication
+	pubSpeedTestMetrics      pubsub.Publication
 	pubZedcloudMetrics       pubsub.Publication
 	pubCipherMetrics         pubsub.Publication
 	pubCachedResolvedIPs     pubsub.Publication
 	pubWwanConfig            pubsub.Publication
+	pubNetworkCapabilities   pubsub.Publication
 
 	// Metrics
 	zedcloudMetrics *zedcloud.AgentMetrics
 	cipherMetrics   *cipher.AgentMetrics
 
+	// Capability report from the boot-time conformance checker.
+	netCapabilities types.NetworkCapabilities
+
 	// Configuration
 	globalConfig       types.ConfigItemValueMap
 	gcInitialized      bool // Received initial GlobalConfig
@@ -106,16 +126,25 @@ type nim struct {
 	enabledLastResort  bool
 	forceLastResort    bool
 	lastResort         *types.DevicePortConfig
+
+	// Network config bundle export (see netconfbundle.go)
+	netconfExportReqTime time.Time
 }
 
 // AddAgentSpecificCLIFlags adds CLI options
 func (n *nim) AddAgentSpecificCLIFlags(flagSet *flag.FlagSet) {
 	n.stdoutPtr = flagSet.Bool("s", false, "Use stdout")
+	n.networkdPtr = flagSet.Bool("networkd", false,
+		"Use systemd-networkd to manage network configuration")
+	n.oobSerialPtr = flagSet.String("oob-serial", "",
+		"Serial device to run the out-of-band management channel on")
 }
 
 // ProcessAgentSpecificCLIFlags process received CLI options
 func (n *nim) ProcessAgentSpecificCLIFlags(_ *flag.FlagSet) {
 	n.useStdout = *n.stdoutPtr
+	n.useNetworkd = *n.networkdPtr
+	n.oobSerialDev = *n.oobSerialPtr
 }
 
 // Run - Main function - invoked from zedbox.go
@@ -151,6 +180,22 @@ func (n *nim) init() (err error) {
 		return err
 	}
 
+	// Verify network prerequisites (kernel modules, iptables/ipset, sysctls,
+	// binaries) before anything starts reconciling network configuration.
+	checker := &netconformance.Checker{Log: n.Log}
+	n.netCapabilities = checker.Check()
+	n.Log.Noticef("Network capabilities: %+v", n.netCapabilities)
+	if n.netCapabilities.IptablesBackend == types.IptablesBackendUnknown &&
+		n.netCapabilities.BinaryVersions["iptables-legacy"] != "" {
+		n.Log.Warnf("Default iptables backend is not usable, " +
+			"falling back to the legacy backend")
+		iptables.UseLegacyCmds()
+	}
+	if err = n.pubNetworkCapabilities.Publish(
+		n.netCapabilities.Key(), n.netCapabilities); err != nil {
+		return err
+	}
+
 	// Initialize NIM components (for Linux network stack).
 	linuxNetMonitor := &netmonitor.LinuxNetworkMonitor{
 		Log: n.Log,
@@ -161,18 +206,34 @@ func (n *nim) init() (err error) {
 		AgentName: agentName,
 		Metrics:   n.zedcloudMetrics,
 	}
-	n.dpcReconciler = &dpcreconciler.LinuxDpcReconciler{
-		Log:                  n.Log,
-		ExportCurrentState:   true, // XXX make configurable
-		ExportIntendedState:  true, // XXX make configurable
-		AgentName:            agentName,
-		NetworkMonitor:       linuxNetMonitor,
-		SubControllerCert:    n.subControllerCert,
-		SubEdgeNodeCert:      n.subEdgeNodeCert,
-		PubCipherBlockStatus: n.pubCipherBlockStatus,
-		PubWwanConfig:        n.pubWwanConfig,
-		CipherMetrics:        n.cipherMetrics,
-		HVTypeKube:           base.IsHVTypeKube(),
+	var procSupervisor *procsupervisor.ProcSupervisor
+	if n.useNetworkd {
+		// Alternative backend for EVE variants based on standard distros:
+		// DPC is rendered into systemd-networkd config files instead of
+		// being applied directly via netlink/dhcpcd.
+		// Helper daemons are managed by systemd, hence no process supervisor
+		// is needed here.
+		n.dpcReconciler = &dpcreconciler.NetworkdDpcReconciler{
+			Log:       n.Log,
+			AgentName: agentName,
+		}
+	} else {
+		procSupervisor = procsupervisor.NewProcSupervisor(
+			n.Log, procsupervisor.DefaultConfig())
+		n.dpcReconciler = &dpcreconciler.LinuxDpcReconciler{
+			Log:                  n.Log,
+			ExportCurrentState:   true, // XXX make configurable
+			ExportIntendedState:  true, // XXX make configurable
+			AgentName:            agentName,
+			NetworkMonitor:       linuxNetMonitor,
+			SubControllerCert:    n.subControllerCert,
+			SubEdgeNodeCert:      n.subEdgeNodeCert,
+			PubCipherBlockStatus: n.pubCipherBlockStatus,
+			PubWwanConfig:        n.pubWwanConfig,
+			CipherMetrics:        n.cipherMetrics,
+			ProcSupervisor:       procSupervisor,
+			HVTypeKube:           base.IsHVTypeKube(),
+		}
 	}
 	n.dpcManager = &dpcmanager.DpcManager{
 		Log:                      n.Log,
@@ -181,9 +242,13 @@ func (n *nim) init() (err error) {
 		NetworkMonitor:           n.networkMonitor,
 		DpcReconciler:            n.dpcReconciler,
 		ConnTester:               n.connTester,
+		ProcSupervisor:           procSupervisor,
 		PubDummyDevicePortConfig: n.pubDummyDevicePortConfig,
 		PubDevicePortConfigList:  n.pubDevicePortConfigList,
 		PubDeviceNetworkStatus:   n.pubDeviceNetworkStatus,
+		PubNetworkReadiness:      n.pubNetworkReadiness,
+		PubDPCMetrics:            n.pubDPCMetrics,
+		PubSpeedTestMetrics:      n.pubSpeedTestMetrics,
 		ZedcloudMetrics:          n.zedcloudMetrics,
 	}
 	return nil
@@ -200,6 +265,13 @@ func (n *nim) run(ctx context.Context) (err error) {
 		return err
 	}
 
+	// Start the out-of-band management channel if enabled.
+	// Not fatal if it fails to initialize (e.g. the pre-shared key
+	// is not provisioned) - NIM can function without it.
+	if n.oobSerialDev != "" {
+		n.startOOBChannel(ctx)
+	}
+
 	// Wait for initial GlobalConfig.
 	if err = n.subGlobalConfig.Activate(); err != nil {
 		return err
@@ -217,6 +289,10 @@ func (n *nim) run(ctx context.Context) (err error) {
 	// take into account by copying it to /run/global/DevicePortConfig/
 	n.ingestDevicePortConfig()
 
+	// Check if we have a network config bundle exported from another device
+	// which we need to apply as an override network configuration.
+	n.importNetconfBundle()
+
 	// Activate some subscriptions.
 	// Not all yet though, first we wait for last-resort and AA to initialize.
 	if err = n.subControllerCert.Activate(); err != nil {
@@ -296,6 +372,9 @@ func (n *nim) run(ctx context.Context) (err error) {
 		if err = n.subZedAgentStatus.Activate(); err != nil {
 			return err
 		}
+		if err = n.subNetworkInstConfig.Activate(); err != nil {
+			return err
+		}
 		if err = n.subAssignableAdapters.Activate(); err != nil {
 			return err
 		}
@@ -350,6 +429,9 @@ func (n *nim) run(ctx context.Context) (err error) {
 		case change := <-n.subZedAgentStatus.MsgChan():
 			n.subZedAgentStatus.ProcessChange(change)
 
+		case change := <-n.subNetworkInstConfig.MsgChan():
+			n.subNetworkInstConfig.ProcessChange(change)
+
 		case change := <-n.subAssignableAdapters.MsgChan():
 			n.subAssignableAdapters.ProcessChange(change)
 
@@ -414,6 +496,33 @@ func (n *nim) initPublications() (err error) {
 		return err
 	}
 
+	n.pubNetworkReadiness, err = n.PubSub.NewPublication(
+		pubsub.PublicationOptions{
+			AgentName: agentName,
+			TopicType: types.NetworkReadiness{},
+		})
+	if err != nil {
+		return err
+	}
+
+	n.pubDPCMetrics, err = n.PubSub.NewPublication(
+		pubsub.PublicationOptions{
+			AgentName: agentName,
+			TopicType: types.DPCMetrics{},
+		})
+	if err != nil {
+		return err
+	}
+
+	n.pubSpeedTestMetrics, err = n.PubSub.NewPublication(
+		pubsub.PublicationOptions{
+			AgentName: agentName,
+			TopicType: types.SpeedTestMetrics{},
+		})
+	if err != nil {
+		return err
+	}
+
 	n.pubZedcloudMetrics, err = n.PubSub.NewPublication(
 		pubsub.PublicationOptions{
 			AgentName: agentName,
@@ -495,6 +604,14 @@ func (n *nim) initPublications() (err error) {
 	if err != nil {
 		return err
 	}
+
+	n.pubNetworkCapabilities, err = n.PubSub.NewPublication(pubsub.PublicationOptions{
+		AgentName: agentName,
+		TopicType: types.NetworkCapabilities{},
+	})
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -609,6 +726,20 @@ func (n *nim) initSubscriptions() (err error) {
 		return err
 	}
 
+	// Network instance definitions are included in exported network
+	// config bundles (see netconfbundle.go).
+	n.subNetworkInstConfig, err = n.PubSub.NewSubscription(pubsub.SubscriptionOptions{
+		AgentName:   "zedagent",
+		MyAgentName: agentName,
+		TopicImpl:   types.NetworkInstanceConfig{},
+		Activate:    false,
+		WarningTime: warningTime,
+		ErrorTime:   errorTime,
+	})
+	if err != nil {
+		return err
+	}
+
 	// To determine which ports are in PCIBack.
 	n.subAssignableAdapters, err = n.PubSub.NewSubscription(pubsub.SubscriptionOptions{
 		AgentName:     "domainmgr",
@@ -804,6 +935,12 @@ func (n *nim) handleZedAgentStatusModify(_ interface{}, key string, statusArg, _
 func (n *nim) handleZedAgentStatusImpl(_ string, statusArg interface{}) {
 	zedagentStatus := statusArg.(types.ZedAgentStatus)
 	n.dpcManager.UpdateRadioSilence(zedagentStatus.RadioSilence)
+	n.dpcManager.UpdateMgmtTrafficPin(zedagentStatus.MgmtTrafficPin)
+	n.dpcManager.RequestCableTest(zedagentStatus.CableTest)
+	n.dpcManager.RequestSpeedTest(zedagentStatus.SpeedTest)
+	n.dpcManager.RequestDPCRetest(zedagentStatus.DPCRetest)
+	n.dpcManager.UpdateDPCPin(zedagentStatus.DPCPin)
+	n.handleNetconfExportRequest(zedagentStatus.NetconfExport)
 }
 
 func (n *nim) handleOnboardStatusCreate(_ interface{}, key string, statusArg interface{}) {
@@ -994,6 +1131,38 @@ func (n *nim) removeLastResortDPC() {
 	n.lastResort = nil
 }
 
+// startOOBChannel starts the out-of-band management channel on the serial
+// device selected with the -oob-serial CLI option.
+func (n *nim) startOOBChannel(ctx context.Context) {
+	var err error
+	n.oobChannel, err = oobchannel.NewOOBChannel(n.Log,
+		oobchannel.Config{Device: n.oobSerialDev},
+		oobchannel.Handlers{
+			GetStatus: n.dpcManager.GetDNS,
+			InjectDPC: n.injectOOBDPC,
+		})
+	if err != nil {
+		n.Log.Errorf("Failed to start out-of-band channel on %s: %v",
+			n.oobSerialDev, err)
+		return
+	}
+	go n.oobChannel.Run(ctx)
+}
+
+// injectOOBDPC applies an override DPC received through the out-of-band
+// management channel. The DPC is published under a dedicated key and with
+// the current time priority, so that it takes precedence over the existing
+// (likely broken) configuration. It is further processed (sanitized, tested)
+// just like DPCs from the other sources.
+func (n *nim) injectOOBDPC(dpc types.DevicePortConfig) error {
+	dpc.Key = oobDPCKey
+	dpc.TimePriority = time.Now()
+	if err := n.pubDevicePortConfig.Publish(oobDPCKey, dpc); err != nil {
+		return fmt.Errorf("failed to publish out-of-band DPC: %v", err)
+	}
+	return nil
+}
+
 func (n *nim) makeLastResortDPC() (types.DevicePortConfig, error) {
 	config := types.DevicePortConfig{}
 	config.Key = dpcmanager.LastResortKey
@@ -1529,6 +1529,14 @@ func sendMetricsProtobufByURL(ctx *getconfigContext, metricsURL string,
 
 	buf := bytes.NewBuffer(data)
 	size := int64(proto.Size(ReportMetrics))
+	if trafficScheduler != nil &&
+		!trafficScheduler.Admit(zedcloud.TrafficClassMetrics, size) {
+		// Metrics are aggregated, skipping one upload cycle merely lowers
+		// the reporting rate while the uplink is saturated or metered.
+		log.Functionf("sendMetricsProtobufByURL: metrics upload postponed "+
+			"by the traffic budget (%d bytes)", size)
+		return
+	}
 	const bailOnHTTPErr = false
 	const withNetTrace = false
 	ctxWork, cancel := zedcloud.GetContextForAllIntfFunctions(zedcloudCtx)
@@ -110,10 +110,10 @@ func prepareAndPublishNetworkInstanceInfoMsg(ctx *zedagentContext,
 		for mac, addrs := range status.IPAssignments {
 			assignment := new(zinfo.ZmetIPAssignmentEntry)
 			assignment.MacAddress = mac
-			if !addrs.IPv4Addr.Equal(net.IP{}) {
-				assignment.IpAddress = append(assignment.IpAddress, addrs.IPv4Addr.String())
+			if !addrs.GetIPv4Addr().Equal(net.IP{}) {
+				assignment.IpAddress = append(assignment.IpAddress, addrs.GetIPv4Addr().String())
 			}
-			for _, ip := range addrs.IPv6Addrs {
+			for _, ip := range addrs.GetIPv6Addrs() {
 				assignment.IpAddress = append(assignment.IpAddress, ip.String())
 			}
 			info.IpAssignments = append(info.IpAssignments, assignment)
@@ -416,6 +416,14 @@ func publishFlowMessage(flowMsg *flowlog.FlowMessage, iteration int) error {
 	buf := bytes.NewBuffer(data)
 	size := int64(proto.Size(flowMsg))
 
+	if trafficScheduler != nil &&
+		!trafficScheduler.Admit(zedcloud.TrafficClassFlowlog, size) {
+		// Returned error triggers the existing truncated exponential backoff,
+		// i.e. the message is retried once the class budget allows.
+		return fmt.Errorf("publishFlowMessage: postponed by the flowlog "+
+			"traffic budget (%d bytes)", size)
+	}
+
 	flowlogURL := zedcloud.URLPathString(serverNameAndPort, zedcloudCtx.V2API, devUUID, "flowlog")
 	const bailOnHTTPErr = false
 	const withNetTrace = false
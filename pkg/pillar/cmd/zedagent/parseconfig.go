@@ -2254,10 +2254,18 @@ func parseAppNetAdapterConfig(appInstance *types.AppInstanceConfig,
 	if len(appInstance.AppNetAdapterList) > 1 {
 		log.Functionf("XXX pre sort %+v", appInstance.AppNetAdapterList)
 	}
-	sort.Slice(appInstance.AppNetAdapterList[:],
+	// Sort deterministically: use a stable sort and break IntfOrder ties
+	// by the adapter name, so that the guest-facing interface ordering
+	// (VIF creation order, MAC addresses, PCI slot hints) does not vary
+	// across republications of the same config.
+	sort.SliceStable(appInstance.AppNetAdapterList[:],
 		func(i, j int) bool {
-			return appInstance.AppNetAdapterList[i].IntfOrder <
-				appInstance.AppNetAdapterList[j].IntfOrder
+			adapterI := &appInstance.AppNetAdapterList[i]
+			adapterJ := &appInstance.AppNetAdapterList[j]
+			if adapterI.IntfOrder != adapterJ.IntfOrder {
+				return adapterI.IntfOrder < adapterJ.IntfOrder
+			}
+			return adapterI.Name < adapterJ.Name
 		})
 
 	// calculate IfIdx field for interfaces connected to the same network
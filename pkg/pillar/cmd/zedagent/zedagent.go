@@ -63,6 +63,13 @@ const (
 
 	// Factor by which the dormant time needs to be scaled up.
 	dormantTimeScaleFactor = 3
+
+	// Rate budgets for the bulk uploads, enforced by the traffic scheduler
+	// so that on a saturated or metered uplink the critical config/info
+	// exchange is never starved. Chosen high enough to not matter
+	// on a healthy uplink.
+	metricsClassBytesPerSec = 512 << 10 // 512 KiB/s sustained
+	flowlogClassBytesPerSec = 256 << 10 // 256 KiB/s sustained
 )
 
 // XXX move to a context? Which? Used in handleconfig and handlemetrics!
@@ -249,6 +256,10 @@ var logger *logrus.Logger
 var log *base.LogObject
 var zedcloudCtx *zedcloud.ZedCloudContext
 
+// Shared by all senders of this agent so that the per-class traffic
+// budgets apply globally (see zedcloud.TrafficScheduler).
+var trafficScheduler *zedcloud.TrafficScheduler
+
 // Destination bitset as unsigned integer
 type destinationBitset uint
 
@@ -434,12 +445,33 @@ func Run(ps *pubsub.PubSub, loggerArg *logrus.Logger, logArg *base.LogObject, ar
 		warningTime, errorTime,
 		getDeferredSentHandlerFunction(zedagentCtx),
 		getDeferredPriorityFunctions()...)
+	// Make events survive zedagent restarts - store the queue in /persist
+	// and replay unsent events once connectivity returns.
+	if err := zedcloudCtx.DeferredEventCtx.EnablePersistence(
+		types.PersistDeferredDir); err != nil {
+		log.Errorf("Failed to enable persistence for deferred events: %v", err)
+	}
 	zedcloudCtx.DeferredPeriodicCtx = zedcloud.CreateDeferredCtx(zedcloudCtx,
 		zedagentCtx.ps, agentName, "DeferredPeriodic",
 		warningTime, errorTime, nil)
 	zedcloudCtx.DeferredLOCPeriodicCtx = zedcloud.CreateDeferredCtx(zedcloudCtx,
 		zedagentCtx.ps, agentName, "DeferredLOCPeriodic",
 		warningTime, errorTime, nil)
+
+	// Schedule requests by traffic class, with rate budgets for the bulk
+	// metric and flow-log uploads.
+	trafficScheduler = zedcloud.NewTrafficScheduler(log)
+	trafficScheduler.SetBudget(zedcloud.TrafficClassMetrics,
+		zedcloud.ClassBudget{BytesPerSecond: metricsClassBytesPerSec})
+	trafficScheduler.SetBudget(zedcloud.TrafficClassFlowlog,
+		zedcloud.ClassBudget{BytesPerSecond: flowlogClassBytesPerSec})
+	for _, deferredCtx := range []*zedcloud.DeferredContext{
+		zedcloudCtx.DeferredEventCtx,
+		zedcloudCtx.DeferredPeriodicCtx,
+		zedcloudCtx.DeferredLOCPeriodicCtx,
+	} {
+		deferredCtx.SetTrafficScheduler(trafficScheduler, classifyDeferredItem)
+	}
 	// XXX defer this until we have some config from cloud or saved copy
 	getconfigCtx.pubAppInstanceConfig.SignalRestarted()
 
@@ -2618,6 +2650,18 @@ func getDeferredSentHandlerFunction(ctx *zedagentContext) *zedcloud.SentHandlerF
 	return &function
 }
 
+// classifyDeferredItem maps the type of a deferred item to a traffic class
+// (see zedcloud.TrafficScheduler).
+func classifyDeferredItem(itemType interface{}) zedcloud.TrafficClass {
+	switch itemType.(type) {
+	case attest.ZAttestReqType:
+		return zedcloud.TrafficClassConfig
+	case info.ZInfoTypes:
+		return zedcloud.TrafficClassInfo
+	}
+	return zedcloud.TrafficClassUnspecified
+}
+
 func getDeferredPriorityFunctions() []zedcloud.TypePriorityCheckFunction {
 	var functions []zedcloud.TypePriorityCheckFunction
 	functions = append(functions, func(itemType interface{}) bool {
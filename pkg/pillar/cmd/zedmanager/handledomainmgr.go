@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -126,9 +127,21 @@ func MaybeAddDomainConfig(ctx *zedmanagerContext,
 	}
 	if ns != nil {
 		adapterCount := len(ns.AppNetAdapterList)
+		adapters := make([]types.AppNetAdapterStatus, adapterCount)
+		copy(adapters, ns.AppNetAdapterList)
+		// The hypervisor attaches VIFs to the guest in the VifList order,
+		// which defines their PCI slot and boot order. Sort by the requested
+		// interface order (with ties broken by the adapter name) to make
+		// interface enumeration inside the guest deterministic.
+		sort.SliceStable(adapters, func(i, j int) bool {
+			if adapters[i].IntfOrder != adapters[j].IntfOrder {
+				return adapters[i].IntfOrder < adapters[j].IntfOrder
+			}
+			return adapters[i].Name < adapters[j].Name
+		})
 		dc.VifList = make([]types.VifConfig, adapterCount)
 		mtuStrList := make([]string, adapterCount)
-		for i, adapter := range ns.AppNetAdapterList {
+		for i, adapter := range adapters {
 			dc.VifList[i] = adapter.VifInfo.VifConfig
 			mtuStrList[i] = strconv.Itoa(int(adapter.MTU))
 		}
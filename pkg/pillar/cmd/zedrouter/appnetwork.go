@@ -5,6 +5,8 @@ package zedrouter
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 
 	"github.com/lf-edge/eve/pkg/pillar/nireconciler"
 	"github.com/lf-edge/eve/pkg/pillar/types"
@@ -114,6 +116,17 @@ func (z *zedrouter) prepareConfigForVIFs(config types.AppNetworkConfig,
 			PodVIF:         adapterStatus.PodVif,
 		})
 	}
+	// Record the final order -> MAC -> network instance mapping
+	// for the controller.
+	status.VIFOrdering = make([]types.VIFOrderEntry, 0, len(status.AppNetAdapterList))
+	for i := range status.AppNetAdapterList {
+		adapterStatus := &status.AppNetAdapterList[i]
+		status.VIFOrdering = append(status.VIFOrdering, types.VIFOrderEntry{
+			Order:   adapterStatus.IntfOrder,
+			MAC:     adapterStatus.Mac,
+			Network: adapterStatus.Network,
+		})
+	}
 	return vifs, nil
 }
 
@@ -148,6 +161,8 @@ func (z *zedrouter) doActivateAppNetwork(config types.AppNetworkConfig,
 	// Update state data collecting to include this application.
 	z.checkAppContainerStatsCollecting(&config, status)
 	z.updateVIFsForStateCollecting(nil, &config)
+	z.updateSNIFiltering()
+	z.updateVIPTrackingForApp(status.UUIDandVersion.UUID)
 }
 
 func (z *zedrouter) updateNIStatusAfterAppNetworkActivate(status *types.AppNetworkStatus) {
@@ -169,10 +184,8 @@ func (z *zedrouter) updateNIStatusAfterAppNetworkActivate(status *types.AppNetwo
 		netInstStatus.AddVif(z.log, adapterStatus.Vif, adapterStatus.Mac,
 			status.UUIDandVersion.UUID)
 		netInstStatus.IPAssignments[adapterStatus.Mac.String()] =
-			types.AssignedAddrs{
-				IPv4Addr:  adapterStatus.AllocatedIPv4Addr,
-				IPv6Addrs: adapterStatus.AllocatedIPv6List,
-			}
+			types.AssignedAddrsFromIPs(
+				adapterStatus.AllocatedIPv4Addr, adapterStatus.AllocatedIPv6List)
 		z.publishNetworkInstanceStatus(netInstStatus)
 	}
 }
@@ -214,9 +227,30 @@ func (z *zedrouter) doCopyAppNetworkConfigToStatus(
 		// see zedrouter/validation.go).
 		if i < len(prevNetStatus) && prevNetStatus[i].Network == netConfig.Network {
 			status.AppNetAdapterList[i] = prevNetStatus[i]
+			// ACL flow samples are collected since the last ACL change.
+			if !reflect.DeepEqual(prevNetStatus[i].ACLs, netConfig.ACLs) {
+				status.AppNetAdapterList[i].ACLFlowSamples = nil
+			}
 		}
 		status.AppNetAdapterList[i].AppNetAdapterConfig = netConfig
 	}
+	// Honor the requested interface order: VIFs are created, assigned MAC
+	// addresses and attached to the guest in the order of this list (see
+	// prepareConfigForVIFs and zedmanager's MaybeAddDomainConfig), hence keep
+	// it sorted by IntfOrder (with ties broken by the adapter name) to make
+	// interface enumeration inside the guest deterministic.
+	sortAdaptersByIntfOrder(status.AppNetAdapterList)
+}
+
+// sortAdaptersByIntfOrder sorts application network adapters by the requested
+// interface order, deterministically breaking ties by the adapter name.
+func sortAdaptersByIntfOrder(adapters []types.AppNetAdapterStatus) {
+	sort.SliceStable(adapters, func(i, j int) bool {
+		if adapters[i].IntfOrder != adapters[j].IntfOrder {
+			return adapters[i].IntfOrder < adapters[j].IntfOrder
+		}
+		return adapters[i].Name < adapters[j].Name
+	})
 }
 
 // Called when a NetworkInstance is added or when an error is cleared
@@ -309,6 +343,7 @@ func (z *zedrouter) doUpdateActivatedAppNetwork(oldConfig, newConfig types.AppNe
 	z.reloadStatusOfAssignedIPs(status)
 	z.publishAppNetworkStatus(status)
 	z.updateNIStatusAfterAppNetworkActivate(status)
+	z.updateSNIFiltering()
 }
 
 func (z *zedrouter) doInactivateAppNetwork(config types.AppNetworkConfig,
@@ -337,6 +372,8 @@ func (z *zedrouter) doInactivateAppNetwork(config types.AppNetworkConfig,
 	z.updateNIStatusAfterAppNetworkInactivate(status)
 	z.removeAssignedIPsFromAppNetStatus(status)
 	z.publishAppNetworkStatus(status)
+	z.updateSNIFiltering()
+	z.updateVIPTrackingForApp(status.UUIDandVersion.UUID)
 }
 
 // Check if any references to network instances have changed and potentially update
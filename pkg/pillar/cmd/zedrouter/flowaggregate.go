@@ -0,0 +1,148 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedrouter
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// This file implements on-device aggregation of flow records into per-app
+// destination statistics (see types.AppDestinationStats). Every flow record
+// is bucketed by the remote CIDR prefix and byte counts are accumulated over
+// a time window. At the end of the window only the top buckets by total byte
+// count are published, allowing to identify bandwidth hogs in the controller
+// without shipping full flow logs.
+
+const (
+	// appDestStatsWindow : the length of the aggregation window.
+	appDestStatsWindow = 10 * time.Minute
+	// appDestIPv4PrefixLen : IPv4 destinations are bucketed by /24 prefixes.
+	appDestIPv4PrefixLen = 24
+	// appDestIPv6PrefixLen : IPv6 destinations are bucketed by /64 prefixes.
+	appDestIPv6PrefixLen = 64
+)
+
+// appDestAggr accumulates destination buckets of one application over
+// the current aggregation window.
+type appDestAggr struct {
+	windowStart time.Time
+	buckets     map[string]*types.AppDestinationBucket // key: prefix in CIDR notation
+}
+
+// aggregateFlowStats adds the given flow records into the destination
+// buckets of the application.
+func (z *zedrouter) aggregateFlowStats(flow types.IPFlow) {
+	appUUID := flow.Scope.AppUUID
+	aggr := z.appDestAggrs[appUUID]
+	if aggr == nil {
+		aggr = &appDestAggr{
+			windowStart: time.Now(),
+			buckets:     make(map[string]*types.AppDestinationBucket),
+		}
+		z.appDestAggrs[appUUID] = aggr
+	}
+	for _, flowRec := range flow.Flows {
+		if flowRec.Action == types.ACLActionDrop {
+			// Count only traffic which was actually exchanged.
+			continue
+		}
+		prefix := appDestPrefix(flowRec.Flow.Dst)
+		if prefix == nil {
+			continue
+		}
+		key := prefix.String()
+		bucket := aggr.buckets[key]
+		if bucket == nil {
+			bucket = &types.AppDestinationBucket{Prefix: *prefix}
+			aggr.buckets[key] = bucket
+		}
+		bucket.TxBytes += uint64(flowRec.TxBytes)
+		bucket.RxBytes += uint64(flowRec.RxBytes)
+		bucket.FlowCount++
+	}
+}
+
+// appDestPrefix returns the CIDR bucket of the given remote IP address.
+func appDestPrefix(dst net.IP) *net.IPNet {
+	if dst == nil {
+		return nil
+	}
+	if dst4 := dst.To4(); dst4 != nil {
+		mask := net.CIDRMask(appDestIPv4PrefixLen, 32)
+		return &net.IPNet{IP: dst4.Mask(mask), Mask: mask}
+	}
+	mask := net.CIDRMask(appDestIPv6PrefixLen, 128)
+	return &net.IPNet{IP: dst.Mask(mask), Mask: mask}
+}
+
+// maybePublishAppDestStats publishes destination statistics of applications
+// whose aggregation window has elapsed and opens a new window for them.
+// Aggregates of applications which are no longer deployed are unpublished.
+func (z *zedrouter) maybePublishAppDestStats() {
+	now := time.Now()
+	for appUUID, aggr := range z.appDestAggrs {
+		if z.lookupAppNetworkStatus(appUUID.String()) == nil {
+			z.unpublishAppDestStats(appUUID)
+			delete(z.appDestAggrs, appUUID)
+			continue
+		}
+		if now.Sub(aggr.windowStart) < appDestStatsWindow {
+			continue
+		}
+		stats := types.AppDestinationStats{
+			AppUUID:         appUUID,
+			WindowStart:     aggr.windowStart,
+			WindowEnd:       now,
+			TopDestinations: topAppDestinations(aggr.buckets),
+		}
+		err := z.pubAppDestStats.Publish(stats.Key(), stats)
+		if err != nil {
+			z.log.Errorf("Failed to publish destination stats of app %s: %v",
+				appUUID, err)
+		}
+		aggr.windowStart = now
+		aggr.buckets = make(map[string]*types.AppDestinationBucket)
+	}
+}
+
+// topAppDestinations returns the destination buckets with the highest total
+// byte counts, ordered from the highest to the lowest.
+// At most types.MaxAppDestinationBuckets entries are returned.
+func topAppDestinations(
+	buckets map[string]*types.AppDestinationBucket) []types.AppDestinationBucket {
+	top := make([]types.AppDestinationBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		top = append(top, *bucket)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		totalI := top[i].TxBytes + top[i].RxBytes
+		totalJ := top[j].TxBytes + top[j].RxBytes
+		if totalI != totalJ {
+			return totalI > totalJ
+		}
+		// Make the order deterministic also between equally-sized buckets.
+		return top[i].Prefix.String() < top[j].Prefix.String()
+	})
+	if len(top) > types.MaxAppDestinationBuckets {
+		top = top[:types.MaxAppDestinationBuckets]
+	}
+	return top
+}
+
+func (z *zedrouter) unpublishAppDestStats(appUUID uuid.UUID) {
+	key := appUUID.String()
+	st, _ := z.pubAppDestStats.Get(key)
+	if st == nil {
+		return
+	}
+	err := z.pubAppDestStats.Unpublish(key)
+	if err != nil {
+		z.log.Errorf("unpublishAppDestStats failed: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/lf-edge/eve/pkg/pillar/nistate"
 	"github.com/lf-edge/eve/pkg/pillar/types"
@@ -120,10 +121,10 @@ func (z *zedrouter) lookupOrAllocateIPv4ForVIF(niStatus *types.NetworkInstanceSt
 	// Lookup to see if it is already allocated.
 	if ipAddr == nil {
 		addrs := niStatus.IPAssignments[adapterStatus.Mac.String()]
-		if !isEmptyIP(addrs.IPv4Addr) {
+		if !isEmptyIP(addrs.GetIPv4Addr()) {
 			z.log.Functionf("lookupOrAllocateIPv4(NI:%v, app:%v): found IP %v for MAC %v",
-				networkID, appID, addrs.IPv4Addr, adapterStatus.Mac)
-			ipAddr = addrs.IPv4Addr
+				networkID, appID, addrs.GetIPv4Addr(), adapterStatus.Mac)
+			ipAddr = addrs.GetIPv4Addr()
 		}
 	}
 
@@ -165,7 +166,11 @@ func (z *zedrouter) lookupOrAllocateIPv4ForVIF(niStatus *types.NetworkInstanceSt
 	// which snoops DHCP traffic and watches DNS server leases to learn the *actual*
 	// IP address assignments.
 	addrs := niStatus.IPAssignments[adapterStatus.Mac.String()] // preserve IPv6 addresses
-	addrs.IPv4Addr = ipAddr
+	addrs.IPv4Addr = types.AssignedAddr{
+		Address:  ipAddr,
+		Source:   types.AddressSourceEVEInternal,
+		LastSeen: time.Now(),
+	}
 	niStatus.IPAssignments[adapterStatus.Mac.String()] = addrs
 	z.publishNetworkInstanceStatus(niStatus)
 	z.log.Functionf("lookupOrAllocateIPv4(NI:%v, app:%v): allocated IP %v for MAC %v",
@@ -188,7 +193,7 @@ func (z *zedrouter) recordAssignedIPsToAdapterStatus(adapter *types.AppNetAdapte
 		z.removeAssignedIPsFromAdapterStatus(adapter)
 		return
 	}
-	adapter.AllocatedIPv4Addr = vifAddrs.IPv4Addr
+	adapter.AllocatedIPv4Addr = vifAddrs.GetIPv4Addr()
 	if !isEmptyIP(adapter.AppIPAddr) &&
 		!adapter.AppIPAddr.Equal(adapter.AllocatedIPv4Addr) {
 		// Config and status do not match.
@@ -196,8 +201,8 @@ func (z *zedrouter) recordAssignedIPsToAdapterStatus(adapter *types.AppNetAdapte
 	} else {
 		adapter.IPAddrMisMatch = false
 	}
-	adapter.AllocatedIPv6List = vifAddrs.IPv6Addrs
-	adapter.IPv4Assigned = !isEmptyIP(vifAddrs.IPv4Addr)
+	adapter.AllocatedIPv6List = vifAddrs.GetIPv6Addrs()
+	adapter.IPv4Assigned = !isEmptyIP(vifAddrs.GetIPv4Addr())
 }
 
 func (z *zedrouter) removeAssignedIPsFromAppNetStatus(status *types.AppNetworkStatus) {
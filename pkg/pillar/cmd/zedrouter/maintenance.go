@@ -0,0 +1,49 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedrouter
+
+import (
+	"context"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// How often to run the per-NI maintenance (dnsmasq log rotation, lease DB
+// compaction, garbage collection of orphaned ipsets).
+const niMaintenanceInterval = 30 * time.Minute
+
+// runNIMaintenance asks the NI reconciler to perform the periodic maintenance
+// for every activated network instance and accumulates metrics about
+// the reclaimed resources (published as part of NetworkInstanceMetrics).
+func (z *zedrouter) runNIMaintenance(ctx context.Context) {
+	for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+		status := item.(types.NetworkInstanceStatus)
+		if !status.Activated {
+			continue
+		}
+		report, err := z.niReconciler.RunMaintenance(ctx, status.UUID)
+		if err != nil {
+			z.log.Errorf("runNIMaintenance: failed for NI %s: %v",
+				status.UUID, err)
+			continue
+		}
+		metrics := z.niMaintenanceMetrics[status.UUID]
+		if metrics == nil {
+			metrics = &types.NIMaintenanceMetrics{}
+			z.niMaintenanceMetrics[status.UUID] = metrics
+		}
+		metrics.LastRun = time.Now()
+		metrics.RotatedLogBytes += report.RotatedLogBytes
+		metrics.CompactedLeases += report.CompactedLeases
+		metrics.RemovedIPSets += report.RemovedIPSets
+		if report.RotatedLogBytes != 0 || report.CompactedLeases != 0 ||
+			report.RemovedIPSets != 0 {
+			z.log.Noticef("runNIMaintenance: NI %s: rotated %dB of dnsmasq "+
+				"logs, compacted %d leases, removed %d orphaned ipsets",
+				status.UUID, report.RotatedLogBytes, report.CompactedLeases,
+				report.RemovedIPSets)
+		}
+	}
+}
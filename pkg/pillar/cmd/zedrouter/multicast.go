@@ -0,0 +1,76 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedrouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// Structures matching the JSON output of "bridge -j mdb show".
+type mdbShowOutput struct {
+	Mdb []mdbEntry `json:"mdb"`
+}
+
+type mdbEntry struct {
+	Dev  string `json:"dev"`
+	Port string `json:"port"`
+	Grp  string `json:"grp"`
+}
+
+// updateMulticastGroups refreshes the multicast group membership information
+// (learned by IGMP/MLD snooping) published for every activated switch network
+// instance. Called periodically from the main event loop.
+func (z *zedrouter) updateMulticastGroups() {
+	for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+		status := item.(types.NetworkInstanceStatus)
+		if !status.Activated || status.Type != types.NetworkInstanceTypeSwitch {
+			continue
+		}
+		groups, err := z.getBridgeMulticastGroups(&status)
+		if err != nil {
+			z.log.Errorf("updateMulticastGroups: failed to get multicast "+
+				"groups for NI %s: %v", status.UUID, err)
+			continue
+		}
+		if reflect.DeepEqual(status.MulticastGroups, groups) {
+			continue
+		}
+		status.MulticastGroups = groups
+		z.publishNetworkInstanceStatus(&status)
+	}
+}
+
+// getBridgeMulticastGroups returns multicast group addresses with members
+// behind each VIF of the given network instance, read from the multicast
+// database of the NI bridge.
+func (z *zedrouter) getBridgeMulticastGroups(
+	status *types.NetworkInstanceStatus) (map[string][]string, error) {
+	output, err := base.Exec(z.log, "bridge", "-j", "mdb", "show",
+		"br", status.BridgeName).Output()
+	if err != nil {
+		return nil, fmt.Errorf("bridge mdb show failed for %s: %w",
+			status.BridgeName, err)
+	}
+	var mdbShow []mdbShowOutput
+	if err = json.Unmarshal(output, &mdbShow); err != nil {
+		return nil, fmt.Errorf("failed to parse bridge mdb output for %s: %w",
+			status.BridgeName, err)
+	}
+	groups := make(map[string][]string)
+	for _, mdb := range mdbShow {
+		for _, entry := range mdb.Mdb {
+			if !status.IsVifInBridge(entry.Port) {
+				// Skip non-VIF ports (e.g. the uplink trunk port).
+				continue
+			}
+			groups[entry.Port] = append(groups[entry.Port], entry.Grp)
+		}
+	}
+	return groups, nil
+}
@@ -4,11 +4,16 @@
 package zedrouter
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"reflect"
+	"strings"
 
 	"github.com/lf-edge/eve/pkg/pillar/nireconciler"
+	"github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
 	"github.com/lf-edge/eve/pkg/pillar/nistate"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	uuid "github.com/satori/go.uuid"
 )
@@ -66,11 +71,33 @@ func (z *zedrouter) getNIBridgeConfig(
 		MACAddress: status.BridgeMac,
 		IPAddress:  ipAddr,
 		Uplink:     z.getNIUplinkConfig(status),
+		ExtraPorts: z.getNIExtraPortsConfig(status),
 		IPConflict: status.IPConflictErr.HasError(),
 		MTU:        status.MTU,
 	}
 }
 
+func (z *zedrouter) getNIExtraPortsConfig(
+	status *types.NetworkInstanceStatus) (extraPorts []nireconciler.SwitchPort) {
+	for _, portConfig := range status.ExtraBridgePorts {
+		ports := z.deviceNetworkStatus.GetPortsByLogicallabel(portConfig.LogicalLabel)
+		if len(ports) == 0 {
+			// Port may appear with a later DPC update.
+			z.log.Warnf("Label of extra bridge port (%s) configured for NI %s "+
+				"does not match any port", portConfig.LogicalLabel, status.UUID)
+			continue
+		}
+		for _, port := range ports {
+			extraPorts = append(extraPorts, nireconciler.SwitchPort{
+				LogicalLabel: port.Logicallabel,
+				IfName:       port.IfName,
+				AccessVlanID: portConfig.AccessVlanID,
+			})
+		}
+	}
+	return extraPorts
+}
+
 func (z *zedrouter) getNIUplinkConfig(
 	status *types.NetworkInstanceStatus) nireconciler.Uplink {
 	if status.PortLogicalLabel == "" {
@@ -85,12 +112,18 @@ func (z *zedrouter) getNIUplinkConfig(
 	if port == nil {
 		return nireconciler.Uplink{}
 	}
+	dnsServers := types.GetDNSServers(*z.deviceNetworkStatus, ifName)
+	if z.dnsProber != nil {
+		// Put healthy resolvers with the lowest latency first and filter
+		// out dead ones so that dnsmasq does not waste time on them.
+		dnsServers = z.dnsProber.SortByHealth(dnsServers)
+	}
 	return nireconciler.Uplink{
 		LogicalLabel: port.Logicallabel,
 		IfName:       ifName,
 		IsMgmt:       port.IsMgmt,
 		MTU:          port.MTU,
-		DNSServers:   types.GetDNSServers(*z.deviceNetworkStatus, ifName),
+		DNSServers:   dnsServers,
 		NTPServers:   types.GetNTPServers(*z.deviceNetworkStatus, ifName),
 	}
 }
@@ -201,8 +234,8 @@ func (z *zedrouter) doUpdateNIUplink(uplinkLogicalLabel string,
 func (z *zedrouter) doActivateNetworkInstance(config types.NetworkInstanceConfig,
 	status *types.NetworkInstanceStatus) {
 	// Create network instance inside the network stack.
-	niRecStatus, err := z.niReconciler.AddNI(
-		z.runCtx, config, z.getNIBridgeConfig(status))
+	bridgeCfg := z.getNIBridgeConfig(status)
+	niRecStatus, err := z.niReconciler.AddNI(z.runCtx, config, bridgeCfg)
 	if err != nil {
 		z.log.Errorf("Failed to activate network instance %s: %v", status.UUID, err)
 		status.ReconcileErr.SetErrorNow(err.Error())
@@ -212,8 +245,14 @@ func (z *zedrouter) doActivateNetworkInstance(config types.NetworkInstanceConfig
 	z.log.Functionf("Activated network instance %s (%s)", status.UUID,
 		status.DisplayName)
 	z.processNIReconcileStatus(niRecStatus, status)
+	if len(niRecStatus.FailedItems) == 0 {
+		z.takeNISnapshot(config, bridgeCfg)
+	}
 	status.Activated = true
+	status.ActiveSplitDNS = config.SplitDNS
+	z.updateVIPTracking(status)
 	z.publishNetworkInstanceStatus(status)
+	z.updateDatapathProbing()
 	// Start collecting state data and metrics for this network instance.
 	br, vifs, err := z.getArgsForNIStateCollecting(config.UUID)
 	if err == nil {
@@ -240,24 +279,42 @@ func (z *zedrouter) doInactivateNetworkInstance(status *types.NetworkInstanceSta
 	z.log.Functionf("Deactivated network instance %s (%s)", status.UUID,
 		status.DisplayName)
 	z.processNIReconcileStatus(niRecStatus, status)
+	// The snapshot is no longer applicable - everything was removed
+	// from the network stack. A new one is taken on re-activation.
+	z.clearNISnapshot(status.UUID)
 	status.Activated = false
+	status.ActiveSplitDNS = nil
+	z.updateVIPTracking(status)
 	z.publishNetworkInstanceStatus(status)
+	z.updateDatapathProbing()
 }
 
 func (z *zedrouter) doUpdateActivatedNetworkInstance(config types.NetworkInstanceConfig,
 	status *types.NetworkInstanceStatus) {
-	niRecStatus, err := z.niReconciler.UpdateNI(
-		z.runCtx, config, z.getNIBridgeConfig(status))
+	bridgeCfg := z.getNIBridgeConfig(status)
+	niRecStatus, err := z.niReconciler.UpdateNI(z.runCtx, config, bridgeCfg)
 	if err != nil {
 		z.log.Errorf("Failed to update activated network instance %s: %v",
 			status.UUID, err)
-		status.ReconcileErr.SetErrorNow(err.Error())
-		z.publishNetworkInstanceStatus(status)
+		if !z.maybeRollbackNI(status, err) {
+			status.ReconcileErr.SetErrorNow(err.Error())
+			z.publishNetworkInstanceStatus(status)
+		}
 		return
 	}
 	z.log.Functionf("Updated activated network instance %s (%s)", status.UUID,
 		status.DisplayName)
+	status.ActiveSplitDNS = config.SplitDNS
 	z.processNIReconcileStatus(niRecStatus, status)
+	if len(niRecStatus.FailedItems) > 0 {
+		// processNIReconcileStatus summarized the failed items
+		// into ReconcileErr.
+		if z.maybeRollbackNI(status, errors.New(status.ReconcileErr.Error)) {
+			return
+		}
+	} else {
+		z.takeNISnapshot(config, bridgeCfg)
+	}
 	_, vifs, err := z.getArgsForNIStateCollecting(config.UUID)
 	if err == nil {
 		err = z.niStateCollector.UpdateCollectingForNI(config, vifs)
@@ -265,6 +322,7 @@ func (z *zedrouter) doUpdateActivatedNetworkInstance(config types.NetworkInstanc
 	if err != nil {
 		z.log.Error(err)
 	}
+	z.updateVIPTracking(status)
 	z.publishNetworkInstanceStatus(status)
 }
 
@@ -315,6 +373,8 @@ func (z *zedrouter) delNetworkInstance(status *types.NetworkInstanceStatus) {
 			z.log.Error(err)
 		}
 	}
+	z.clearNISnapshot(status.UUID)
+	delete(z.niMaintenanceMetrics, status.UUID)
 	if status.BridgeNum != 0 {
 		bridgeNumKey := types.UuidToNumKey{UUID: status.UUID}
 		err := z.bridgeNumAllocator.Free(bridgeNumKey, false)
@@ -385,3 +445,37 @@ func (z *zedrouter) checkAllNetworkInstanceIPConflicts() {
 		}
 	}
 }
+
+// processProcHealthUpdates updates the ProcessHealth field of network instance
+// status to reflect the latest health of helper daemons (dnsmasq) spawned
+// by zedrouter for the network instances.
+func (z *zedrouter) processProcHealthUpdates(
+	procHealth []procsupervisor.ProcessStatus) {
+	healthByBridge := make(map[string][]types.ProcessHealth)
+	for _, procStatus := range procHealth {
+		if !strings.HasPrefix(procStatus.Name, genericitems.DnsmasqProcNamePrefix) {
+			continue
+		}
+		brIfName := strings.TrimPrefix(procStatus.Name,
+			genericitems.DnsmasqProcNamePrefix)
+		healthByBridge[brIfName] = append(healthByBridge[brIfName],
+			types.ProcessHealth{
+				ProcessName:      procStatus.Name,
+				Running:          procStatus.Running,
+				Pid:              procStatus.Pid,
+				Restarts:         procStatus.Restarts,
+				LastExitTime:     procStatus.LastExitTime,
+				LastRestartTime:  procStatus.LastRestartTime,
+				LastRestartError: procStatus.LastRestartError,
+			})
+	}
+	for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+		status := item.(types.NetworkInstanceStatus)
+		health := healthByBridge[status.BridgeName]
+		if reflect.DeepEqual(health, status.ProcessHealth) {
+			continue
+		}
+		status.ProcessHealth = health
+		z.publishNetworkInstanceStatus(&status)
+	}
+}
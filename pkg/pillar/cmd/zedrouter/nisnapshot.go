@@ -0,0 +1,93 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedrouter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/nireconciler"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Snapshots of the last successfully applied network instance states.
+// A snapshot is taken whenever a network instance config is (re)applied
+// in the network stack without errors. When a later modification of the NI
+// config fails to activate (e.g. dnsmasq refuses to start with the new
+// configuration), zedrouter uses the snapshot to automatically roll the NI
+// back to the last working state, instead of leaving the NI (and all
+// the connected applications) without connectivity until the controller
+// pushes a fixed config.
+
+// niSnapshot : the last successfully applied state of a network instance.
+type niSnapshot struct {
+	// config : NI configuration as submitted to NIReconciler.
+	config types.NetworkInstanceConfig
+	// bridge : derived bridge arguments (allocated bridge number, generated
+	// MAC address, selected uplink, etc.) matching the config.
+	bridge nireconciler.NIBridge
+	// takenAt : time when the snapshot was taken.
+	takenAt time.Time
+}
+
+// takeNISnapshot records the given NI config and the derived bridge arguments
+// as the last known working state of the network instance.
+// Should be called only after the config was applied without errors.
+func (z *zedrouter) takeNISnapshot(config types.NetworkInstanceConfig,
+	bridge nireconciler.NIBridge) {
+	if z.niSnapshots == nil {
+		z.niSnapshots = make(map[uuid.UUID]*niSnapshot)
+	}
+	z.niSnapshots[config.UUID] = &niSnapshot{
+		config:  config,
+		bridge:  bridge,
+		takenAt: time.Now(),
+	}
+}
+
+// clearNISnapshot removes the snapshot of the given network instance.
+// Called when the NI is deleted.
+func (z *zedrouter) clearNISnapshot(niID uuid.UUID) {
+	delete(z.niSnapshots, niID)
+}
+
+// maybeRollbackNI tries to roll the network instance back to the last
+// successfully applied state after a failed config modification.
+// Returns true if the rollback was performed (successfully or not).
+// The error from the failed modification is preserved in the NI status
+// so that the controller learns that the new config was not accepted.
+func (z *zedrouter) maybeRollbackNI(status *types.NetworkInstanceStatus,
+	modifyErr error) bool {
+	snapshot := z.niSnapshots[status.UUID]
+	if snapshot == nil {
+		return false
+	}
+	z.log.Warnf("Rolling back network instance %s (%s) to the last working "+
+		"config (applied at %v) after failed modification: %v",
+		status.UUID, status.DisplayName, snapshot.takenAt, modifyErr)
+	niRecStatus, err := z.niReconciler.UpdateNI(
+		z.runCtx, snapshot.config, snapshot.bridge)
+	if err != nil {
+		z.log.Errorf("Failed to roll back network instance %s: %v",
+			status.UUID, err)
+		status.ReconcileErr.SetErrorNow(fmt.Sprintf(
+			"failed to apply new config (%v) and to roll back "+
+				"to the previous working config (%v)", modifyErr, err))
+		z.publishNetworkInstanceStatus(status)
+		return true
+	}
+	z.processNIReconcileStatus(niRecStatus, status)
+	if len(niRecStatus.FailedItems) > 0 {
+		z.log.Errorf("Rollback of network instance %s did not fully succeed: %v",
+			status.UUID, niRecStatus.FailedItems)
+	}
+	// Even if the rollback succeeded, keep reporting the error from the failed
+	// modification - the NI is not running the requested config.
+	status.ReconcileErr.SetErrorNow(fmt.Sprintf(
+		"failed to apply new config (%v); rolled back to the previous "+
+			"working config from %v", modifyErr, snapshot.takenAt))
+	z.publishNetworkInstanceStatus(status)
+	return true
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedrouter
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Device-wide registry of port-map (DNAT) reservations.
+// Every port-map ACL rule reserves the tuple (uplink interface, protocol,
+// external port). Two applications sharing an uplink cannot map the same
+// external port - the second DNAT rule would silently misbehave. The registry
+// detects such conflicts already at config time, so that the later rule is
+// rejected with a clear error in AppNetworkStatus instead.
+
+// portMapKey identifies one externally visible port mapping.
+type portMapKey struct {
+	// uplinkIfName : interface name of the uplink port on which the external
+	// port is exposed.
+	uplinkIfName string
+	// protocol from the ACE match (e.g. "tcp", "udp").
+	protocol string
+	// externalPort : the external ("lport") port number from the ACE match.
+	externalPort string
+}
+
+// getAppPortMaps collects all port mappings requested by the given
+// app network adapters. Adapters referencing not-yet-known network instances
+// are skipped - they are re-checked when the app network is re-created after
+// the network instance appears.
+func (z *zedrouter) getAppPortMaps(
+	adapters []types.AppNetAdapterConfig) []portMapKey {
+	var portMaps []portMapKey
+	for _, adapterCfg := range adapters {
+		niStatus := z.lookupNetworkInstanceStatus(adapterCfg.Network.String())
+		if niStatus == nil || niStatus.SelectedUplinkIntfName == "" {
+			continue
+		}
+		for _, ace := range adapterCfg.ACLs {
+			var isPortMap bool
+			for _, action := range ace.Actions {
+				if action.PortMap {
+					isPortMap = true
+					break
+				}
+			}
+			if !isPortMap {
+				continue
+			}
+			portMap := portMapKey{
+				uplinkIfName: niStatus.SelectedUplinkIntfName,
+			}
+			for _, match := range ace.Matches {
+				switch match.Type {
+				case "protocol":
+					portMap.protocol = match.Value
+				case "lport":
+					portMap.externalPort = match.Value
+				}
+			}
+			portMaps = append(portMaps, portMap)
+		}
+	}
+	return portMaps
+}
+
+// reserveAppPortMaps reserves all port mappings requested by the given app.
+// If any of them conflicts with a reservation held by another app, no
+// reservation is made and an error describing the conflict is returned.
+func (z *zedrouter) reserveAppPortMaps(appID uuid.UUID,
+	adapters []types.AppNetAdapterConfig) error {
+	if z.portMapReservations == nil {
+		z.portMapReservations = make(map[portMapKey]uuid.UUID)
+	}
+	portMaps := z.getAppPortMaps(adapters)
+	for _, portMap := range portMaps {
+		if reservedBy, isReserved := z.portMapReservations[portMap]; isReserved &&
+			reservedBy != appID {
+			return fmt.Errorf("external port %s/%s on uplink %s is already "+
+				"mapped by app %s", portMap.protocol, portMap.externalPort,
+				portMap.uplinkIfName, reservedBy)
+		}
+	}
+	for _, portMap := range portMaps {
+		z.portMapReservations[portMap] = appID
+	}
+	return nil
+}
+
+// freeAppPortMaps releases all port mappings reserved by the given app.
+func (z *zedrouter) freeAppPortMaps(appID uuid.UUID) {
+	for portMap, reservedBy := range z.portMapReservations {
+		if reservedBy == appID {
+			delete(z.portMapReservations, portMap)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package zedrouter
 
 import (
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
@@ -52,6 +53,12 @@ func (z *zedrouter) handleGlobalConfigImpl(ctxArg interface{}, key string,
 		}
 		z.enableArpSnooping = gcp.GlobalValueBool(types.EnableARPSnoop)
 		z.localLegacyMACAddr = gcp.GlobalValueBool(types.NetworkLocalLegacyMACAddress)
+		z.maxACLRulesPerVIF = gcp.GlobalValueInt(types.NetworkMaxACLRulesPerVIF)
+		z.maxACLRulesPerNI = gcp.GlobalValueInt(types.NetworkMaxACLRulesPerNI)
+		if niDatapathProbe := gcp.GlobalValueBool(types.NIDatapathProbe); niDatapathProbe != z.niDatapathProbe {
+			z.niDatapathProbe = niDatapathProbe
+			z.updateDatapathProbing()
+		}
 		z.niReconciler.ApplyUpdatedGCP(z.runCtx, *gcp)
 	}
 	z.log.Functionf("handleGlobalConfigImpl done for %s", key)
@@ -128,6 +135,17 @@ func (z *zedrouter) handleDNSImpl(ctxArg interface{}, key string,
 	if z.uplinkProber != nil {
 		z.uplinkProber.ApplyDNSUpdate(status)
 	}
+	if z.dnsProber != nil {
+		// Probe health of DNS servers from all device ports, including
+		// the app-shared ones (which cannot be selected with GetDNSServers).
+		var dnsServers []net.IP
+		for _, port := range status.Ports {
+			dnsServers = append(dnsServers, port.DNSServers...)
+		}
+		z.dnsProber.TrackServers(dnsServers)
+	}
+	// Uplink gateways may have changed.
+	z.updateDatapathProbing()
 	z.log.Functionf("handleDNSImpl done for %s", key)
 }
 
@@ -217,7 +235,10 @@ func (z *zedrouter) handleNetworkInstanceCreate(ctxArg interface{}, key string,
 
 	// Set bridge IP address.
 	if status.Gateway != nil {
-		addrs := types.AssignedAddrs{IPv4Addr: status.Gateway}
+		addrs := types.AssignedAddrs{IPv4Addr: types.AssignedAddr{
+			Address: status.Gateway,
+			Source:  types.AddressSourceStatic,
+		}}
 		status.IPAssignments[status.BridgeMac.String()] = addrs
 		status.BridgeIPAddr = status.Gateway
 	}
@@ -348,7 +369,10 @@ func (z *zedrouter) handleNetworkInstanceModify(ctxArg interface{}, key string,
 		delete(status.IPAssignments, status.BridgeMac.String())
 	}
 	if status.Gateway != nil && status.BridgeMac != nil {
-		addrs := types.AssignedAddrs{IPv4Addr: status.Gateway}
+		addrs := types.AssignedAddrs{IPv4Addr: types.AssignedAddr{
+			Address: status.Gateway,
+			Source:  types.AddressSourceStatic,
+		}}
 		status.IPAssignments[status.BridgeMac.String()] = addrs
 		status.BridgeIPAddr = status.Gateway
 	}
@@ -546,6 +570,14 @@ func (z *zedrouter) handleAppNetworkCreate(ctxArg interface{}, key string,
 		return
 	}
 
+	// Reserve external ports requested by port-map ACL rules.
+	err = z.reserveAppPortMaps(config.UUIDandVersion.UUID, config.AppNetAdapterList)
+	if err != nil {
+		z.log.Errorf("handleAppNetworkCreate(%v): %v", config.UUIDandVersion.UUID, err)
+		z.addAppNetworkError(&status, "handleAppNetworkCreate", err)
+		return
+	}
+
 	if config.Activate {
 		z.doActivateAppNetwork(config, &status)
 	}
@@ -596,6 +628,18 @@ func (z *zedrouter) handleAppNetworkModify(ctxArg interface{}, key string,
 		return
 	}
 
+	// Re-reserve external ports requested by port-map ACL rules of the new config.
+	appID := newConfig.UUIDandVersion.UUID
+	z.freeAppPortMaps(appID)
+	err = z.reserveAppPortMaps(appID, newConfig.AppNetAdapterList)
+	if err != nil {
+		// Keep the reservations of the still-applied old config.
+		_ = z.reserveAppPortMaps(appID, oldConfig.AppNetAdapterList)
+		z.log.Errorf("handleAppNetworkModify(%v): %v", newConfig.UUIDandVersion.UUID, err)
+		z.addAppNetworkError(status, "handleAppNetworkModify", err)
+		return
+	}
+
 	if !newConfig.Activate && status.Activated {
 		z.doInactivateAppNetwork(newConfig, status)
 		z.doCopyAppNetworkConfigToStatus(newConfig, status)
@@ -655,6 +699,7 @@ func (z *zedrouter) handleAppNetworkDelete(ctxArg interface{}, key string,
 		// Continue anyway...
 	}
 	z.freeAppIntfNums(status)
+	z.freeAppPortMaps(status.UUIDandVersion.UUID)
 
 	// Did this free up any last references against any deleted Network Instance?
 	for i := range status.AppNetAdapterList {
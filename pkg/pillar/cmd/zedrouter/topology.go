@@ -0,0 +1,77 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedrouter
+
+import (
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// publishNetworkTopology assembles and publishes a consolidated snapshot
+// of the device networking topology: uplink ports, network instances with
+// their bridges and application interfaces connected to them.
+// It is re-published periodically (driven by the publish ticker) so that
+// consumers get an up-to-date view without correlating multiple topics.
+func (z *zedrouter) publishNetworkTopology() {
+	topology := types.NetworkTopology{
+		UpdatedAt: time.Now(),
+	}
+	if z.deviceNetworkStatus != nil {
+		for _, port := range z.deviceNetworkStatus.Ports {
+			uplink := types.UplinkTopology{
+				LogicalLabel: port.Logicallabel,
+				IfName:       port.IfName,
+				IsMgmt:       port.IsMgmt,
+				Cost:         port.Cost,
+				Up:           port.LastSucceeded.After(port.LastFailed),
+			}
+			for _, addrInfo := range port.AddrInfoList {
+				uplink.Addrs = append(uplink.Addrs, addrInfo.Addr)
+			}
+			topology.Uplinks = append(topology.Uplinks, uplink)
+		}
+	}
+	appStatuses := z.pubAppNetworkStatus.GetAll()
+	for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+		niStatus := item.(types.NetworkInstanceStatus)
+		niTopology := types.NITopology{
+			NI:                         niStatus.UUID,
+			DisplayName:                niStatus.DisplayName,
+			Type:                       niStatus.Type,
+			Activated:                  niStatus.Activated,
+			BridgeName:                 niStatus.BridgeName,
+			BridgeIPAddr:               niStatus.BridgeIPAddr,
+			Subnet:                     niStatus.Subnet,
+			SelectedUplinkLogicalLabel: niStatus.SelectedUplinkLogicalLabel,
+			SelectedUplinkIfName:       niStatus.SelectedUplinkIntfName,
+		}
+		for _, appItem := range appStatuses {
+			appStatus := appItem.(types.AppNetworkStatus)
+			appID := appStatus.UUIDandVersion.UUID
+			for _, adapterStatus := range appStatus.AppNetAdapterList {
+				if adapterStatus.Network != niStatus.UUID {
+					continue
+				}
+				vifTopology := types.VIFTopology{
+					App:            appID,
+					AppName:        appStatus.DisplayName,
+					NetAdapterName: adapterStatus.Name,
+					HostIfName:     adapterStatus.Vif,
+					IPv4Addr:       adapterStatus.AllocatedIPv4Addr,
+					ACLCount:       len(adapterStatus.ACLs),
+				}
+				if adapterStatus.Mac != nil {
+					vifTopology.GuestIfMAC = adapterStatus.Mac.String()
+				}
+				niTopology.VIFs = append(niTopology.VIFs, vifTopology)
+			}
+		}
+		topology.NIs = append(topology.NIs, niTopology)
+	}
+	err := z.pubNetworkTopology.Publish(topology.Key(), topology)
+	if err != nil {
+		z.log.Errorf("Failed to publish network topology: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ package zedrouter
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -49,6 +50,192 @@ func (z *zedrouter) doNetworkInstanceSanityCheck(config *types.NetworkInstanceCo
 	default:
 		return fmt.Errorf("IpType %d not supported", config.IpType)
 	}
+	if err := z.doNetworkInstanceSplitDNSSanityCheck(config); err != nil {
+		return err
+	}
+	if err := z.doNetworkInstanceDHCPClassSanityCheck(config); err != nil {
+		return err
+	}
+	if err := z.doNetworkInstanceVirtualIPSanityCheck(config); err != nil {
+		return err
+	}
+	if config.GateDHCPOnConnectivity {
+		if config.Type != types.NetworkInstanceTypeLocal {
+			return fmt.Errorf("gating DHCP on connectivity is supported " +
+				"only on local network instances")
+		}
+		if config.PortLogicalLabel == "" {
+			return fmt.Errorf("gating DHCP on connectivity is not applicable " +
+				"to air-gapped network instances")
+		}
+	}
+	return z.doNetworkInstanceExtraPortsSanityCheck(config)
+}
+
+func (z *zedrouter) doNetworkInstanceExtraPortsSanityCheck(
+	config *types.NetworkInstanceConfig) error {
+	if len(config.ExtraBridgePorts) == 0 {
+		return nil
+	}
+	if config.Type != types.NetworkInstanceTypeSwitch {
+		return fmt.Errorf("extra bridge ports are supported only on switch " +
+			"network instances")
+	}
+	labels := make(map[string]struct{})
+	for _, port := range config.ExtraBridgePorts {
+		if port.LogicalLabel == "" {
+			return fmt.Errorf("extra bridge port without a logical label: %+v",
+				port)
+		}
+		if _, duplicate := labels[port.LogicalLabel]; duplicate {
+			return fmt.Errorf("multiple references to the same extra bridge "+
+				"port (%s)", port.LogicalLabel)
+		}
+		labels[port.LogicalLabel] = struct{}{}
+		if port.LogicalLabel == config.PortLogicalLabel {
+			return fmt.Errorf("extra bridge port (%s) refers to the same port "+
+				"as the network instance uplink", port.LogicalLabel)
+		}
+		// VIDs 4094 and 4095 are reserved for internal use.
+		if port.AccessVlanID > 4093 {
+			return fmt.Errorf("access VLAN ID (%d) of the extra bridge port "+
+				"(%s) is out of range", port.AccessVlanID, port.LogicalLabel)
+		}
+	}
+	return nil
+}
+
+func (z *zedrouter) doNetworkInstanceDHCPClassSanityCheck(
+	config *types.NetworkInstanceConfig) error {
+	if len(config.DHCPClientClasses) == 0 {
+		return nil
+	}
+	if config.Type != types.NetworkInstanceTypeLocal {
+		return fmt.Errorf("DHCP client classes are supported only on local " +
+			"network instances")
+	}
+	names := make(map[string]struct{})
+	for _, class := range config.DHCPClientClasses {
+		if class.Name == "" {
+			return fmt.Errorf("DHCP client class without a name: %+v", class)
+		}
+		if _, duplicate := names[class.Name]; duplicate {
+			return fmt.Errorf("multiple definitions of the DHCP client "+
+				"class (%s)", class.Name)
+		}
+		names[class.Name] = struct{}{}
+		if class.VendorClass == "" && class.UserClass == "" {
+			return fmt.Errorf("DHCP client class (%s) without a vendor-class "+
+				"or user-class to match", class.Name)
+		}
+		if class.IPRange.Start == nil {
+			continue
+		}
+		if class.IPRange.End == nil {
+			return fmt.Errorf("address pool of the DHCP client class (%s) "+
+				"is missing the end of the range", class.Name)
+		}
+		if !config.Subnet.Contains(class.IPRange.Start) ||
+			!config.Subnet.Contains(class.IPRange.End) {
+			return fmt.Errorf("address pool (%v,%v) of the DHCP client "+
+				"class (%s) not within Subnet(%s)", class.IPRange.Start,
+				class.IPRange.End, class.Name, config.Subnet.String())
+		}
+		if config.DhcpRange.Contains(class.IPRange.Start) ||
+			config.DhcpRange.Contains(class.IPRange.End) {
+			return fmt.Errorf("address pool (%v,%v) of the DHCP client "+
+				"class (%s) overlaps with DHCP Range(%v,%v)",
+				class.IPRange.Start, class.IPRange.End, class.Name,
+				config.DhcpRange.Start, config.DhcpRange.End)
+		}
+		if class.IPRange.Contains(config.Gateway) {
+			return fmt.Errorf("address pool (%v,%v) of the DHCP client "+
+				"class (%s) contains the gateway IP (%s)", class.IPRange.Start,
+				class.IPRange.End, class.Name, config.Gateway)
+		}
+	}
+	return nil
+}
+
+func (z *zedrouter) doNetworkInstanceVirtualIPSanityCheck(
+	config *types.NetworkInstanceConfig) error {
+	if len(config.VirtualIPs) == 0 {
+		return nil
+	}
+	if config.Type != types.NetworkInstanceTypeLocal {
+		return fmt.Errorf("virtual IPs are supported only on local " +
+			"network instances")
+	}
+	for _, vip := range config.VirtualIPs {
+		if vip.IP == nil || vip.IP.IsUnspecified() {
+			return fmt.Errorf("virtual IP is not specified: %+v", vip)
+		}
+		if vip.IP.To4() == nil {
+			// Failover is performed using (gratuitous) ARP.
+			return fmt.Errorf("virtual IP (%s) is not an IPv4 address", vip.IP)
+		}
+		if config.Subnet.IP != nil && !config.Subnet.Contains(vip.IP) {
+			return fmt.Errorf("virtual IP (%s) not within Subnet(%s)",
+				vip.IP, config.Subnet.String())
+		}
+		if config.DhcpRange.Contains(vip.IP) {
+			return fmt.Errorf("virtual IP (%s) is in DHCP Range(%v,%v)",
+				vip.IP, config.DhcpRange.Start, config.DhcpRange.End)
+		}
+		if netutils.EqualIPs(vip.IP, config.Gateway) {
+			return fmt.Errorf("virtual IP (%s) collides with the gateway IP",
+				vip.IP)
+		}
+		if len(vip.Apps) == 0 {
+			return fmt.Errorf("virtual IP (%s) without any candidate "+
+				"application", vip.IP)
+		}
+		apps := make(map[uuid.UUID]struct{})
+		for _, app := range vip.Apps {
+			if _, duplicate := apps[app]; duplicate {
+				return fmt.Errorf("virtual IP (%s) with duplicate candidate "+
+					"application (%s)", vip.IP, app)
+			}
+			apps[app] = struct{}{}
+		}
+		if vip.ProbePort == 0 {
+			return fmt.Errorf("virtual IP (%s) without the health-probe port",
+				vip.IP)
+		}
+	}
+	for i, vip := range config.VirtualIPs {
+		for _, vip2 := range config.VirtualIPs[i+1:] {
+			if netutils.EqualIPs(vip.IP, vip2.IP) {
+				return fmt.Errorf("multiple definitions of the virtual IP (%s)",
+					vip.IP)
+			}
+		}
+	}
+	return nil
+}
+
+func (z *zedrouter) doNetworkInstanceSplitDNSSanityCheck(
+	config *types.NetworkInstanceConfig) error {
+	for _, rule := range config.SplitDNS {
+		if rule.Domain == "" {
+			return fmt.Errorf("split-DNS rule with empty domain")
+		}
+		if strings.Contains(rule.Domain, "/") {
+			// Would break the dnsmasq server=/domain/ip config syntax.
+			return fmt.Errorf("split-DNS domain (%s) contains invalid character",
+				rule.Domain)
+		}
+		if len(rule.Servers) == 0 {
+			return fmt.Errorf("split-DNS rule for domain (%s) without any "+
+				"DNS server", rule.Domain)
+		}
+		for _, server := range rule.Servers {
+			if server == nil || server.IsUnspecified() {
+				return fmt.Errorf("split-DNS rule for domain (%s) with invalid "+
+					"DNS server IP (%v)", rule.Domain, server)
+			}
+		}
+	}
 	return nil
 }
 
@@ -110,18 +297,20 @@ func (z *zedrouter) checkNetworkInstanceIPConflicts(
 			continue
 		}
 		if netutils.OverlappingSubnets(&config.Subnet, &niConfig2.Subnet) {
-			return fmt.Errorf("subnet (%s) overlaps with another "+
-				"network instance (%s-%s) subnet (%s)",
-				config.Subnet.String(), niConfig2.DisplayName, niConfig2.UUID,
-				niConfig2.Subnet.String())
+			return types.NewNetworkError(types.NetworkErrorCodeSubnetOverlap,
+				fmt.Errorf("subnet (%s) overlaps with another "+
+					"network instance (%s-%s) subnet (%s)",
+					config.Subnet.String(), niConfig2.DisplayName, niConfig2.UUID,
+					niConfig2.Subnet.String()))
 		}
 	}
 	// Check for overlapping subnets between the NI and device ports.
 	for _, port := range z.deviceNetworkStatus.Ports {
 		if netutils.OverlappingSubnets(&config.Subnet, &port.Subnet) {
-			return fmt.Errorf("subnet (%s) overlaps with device port %s "+
-				"subnet (%s)", config.Subnet.String(), port.Logicallabel,
-				port.Subnet.String())
+			return types.NewNetworkError(types.NetworkErrorCodeSubnetOverlap,
+				fmt.Errorf("subnet (%s) overlaps with device port %s "+
+					"subnet (%s)", config.Subnet.String(), port.Logicallabel,
+					port.Subnet.String()))
 		}
 	}
 	return nil
@@ -163,6 +352,15 @@ func (z *zedrouter) validateAppNetworkConfig(appNetConfig types.AppNetworkConfig
 	if z.containsHangingACLPortMapRule(adapterCfgList1) {
 		return fmt.Errorf("network with no uplink, has portmap")
 	}
+	if err := z.checkACLRuleLimits(appNetConfig); err != nil {
+		return err
+	}
+	if err := z.checkVifAttachmentModes(appNetConfig); err != nil {
+		return err
+	}
+	if err := z.checkVhostUserVIFs(appNetConfig); err != nil {
+		return err
+	}
 	sub := z.subAppNetworkConfig
 	items := sub.GetAll()
 	for _, c := range items {
@@ -245,6 +443,143 @@ func (z *zedrouter) checkNetworkReferencesFromApp(config types.AppNetworkConfig)
 }
 
 // Check if there is a portmap rule for a network instance with no uplink interface.
+// checkVifAttachmentModes verifies constraints of the L2-only (MACVLAN/IPVLAN)
+// VIF attachment modes: only supported for switch network instances with
+// a port, without Kubernetes networking, and cannot be combined with
+// user-configured ACLs (the traffic does not traverse the bridge datapath
+// where ACLs are implemented).
+func (z *zedrouter) checkVifAttachmentModes(config types.AppNetworkConfig) error {
+	for _, adapterCfg := range config.AppNetAdapterList {
+		if adapterCfg.AttachmentMode == types.VifAttachmentBridge {
+			continue
+		}
+		if z.withKubeNetworking {
+			return fmt.Errorf("adapter %s: L2-only VIF attachment is not "+
+				"supported with Kubernetes networking", adapterCfg.Name)
+		}
+		if len(adapterCfg.ACLs) > 0 {
+			return fmt.Errorf("adapter %s: user-configured ACLs are not "+
+				"supported with L2-only VIF attachment", adapterCfg.Name)
+		}
+		niStatus := z.lookupNetworkInstanceStatus(adapterCfg.Network.String())
+		if niStatus == nil {
+			// App network is put into the network-wait state and re-validated
+			// once the network instance appears.
+			continue
+		}
+		if niStatus.Type != types.NetworkInstanceTypeSwitch {
+			return fmt.Errorf("adapter %s: L2-only VIF attachment requires "+
+				"a switch network instance", adapterCfg.Name)
+		}
+		if niStatus.PortLogicalLabel == "" {
+			return fmt.Errorf("adapter %s: L2-only VIF attachment requires "+
+				"a network instance with a port (NI %s is air-gapped)",
+				adapterCfg.Name, adapterCfg.Network)
+		}
+	}
+	return nil
+}
+
+// checkVhostUserVIFs verifies constraints of VIFs backed by a DPDK/vhost-user
+// datapath. Traffic of such VIFs bypasses kernel netfilter and is therefore
+// not subject to the iptables-based ACL implementation. Unless the ACL
+// enforcement strategy selected for the adapter (see DpdkAclMode) is actually
+// available on the device, activation is denied - security policy must never
+// be silently bypassed just because the app uses a high-performance datapath.
+func (z *zedrouter) checkVhostUserVIFs(config types.AppNetworkConfig) error {
+	for _, adapterCfg := range config.AppNetAdapterList {
+		if !adapterCfg.VhostUser {
+			continue
+		}
+		if z.withKubeNetworking {
+			return fmt.Errorf("adapter %s: vhost-user VIF is not supported "+
+				"with Kubernetes networking", adapterCfg.Name)
+		}
+		if adapterCfg.AttachmentMode != types.VifAttachmentBridge {
+			return fmt.Errorf("adapter %s: vhost-user VIF cannot be combined "+
+				"with L2-only VIF attachment", adapterCfg.Name)
+		}
+		if len(adapterCfg.ACLs) == 0 {
+			// Nothing to enforce.
+			continue
+		}
+		switch adapterCfg.DpdkAclMode {
+		case types.DpdkAclModeDeny:
+			return fmt.Errorf("adapter %s: user-configured ACLs cannot be "+
+				"enforced on a vhost-user VIF (kernel netfilter is bypassed); "+
+				"remove the ACLs or select an alternative enforcement "+
+				"strategy available on this device", adapterCfg.Name)
+		case types.DpdkAclModeOVS:
+			if !ovsDatapathAvailable() {
+				return fmt.Errorf("adapter %s: OVS-based ACL enforcement "+
+					"requires an Open vSwitch datapath, which is not running "+
+					"on this device", adapterCfg.Name)
+			}
+			return fmt.Errorf("adapter %s: OVS-based ACL enforcement is not "+
+				"supported by the Linux network stack implementation of the "+
+				"NI reconciler", adapterCfg.Name)
+		case types.DpdkAclModeEBPF:
+			return fmt.Errorf("adapter %s: eBPF-based ACL enforcement for "+
+				"vhost-user VIFs is not yet supported", adapterCfg.Name)
+		default:
+			return fmt.Errorf("adapter %s: unsupported ACL enforcement "+
+				"strategy (%v) for a vhost-user VIF", adapterCfg.Name,
+				adapterCfg.DpdkAclMode)
+		}
+	}
+	return nil
+}
+
+// ovsDatapathAvailable returns true if an Open vSwitch datapath is running
+// on the device (not the case for stock EVE images).
+func ovsDatapathAvailable() bool {
+	_, err := os.Stat("/run/openvswitch/ovs-vswitchd.pid")
+	return err == nil
+}
+
+// checkACLRuleLimits verifies that the ACL lists requested for the application
+// do not exceed the configured per-VIF limit and would not push the aggregate
+// number of ACL rules of any of the referenced network instances over
+// the per-NI limit (see NetworkMaxACLRulesPerVIF and NetworkMaxACLRulesPerNI).
+// The aggregate per-NI count includes ACL rules of applications which are
+// already deployed and connected to the same network instance.
+func (z *zedrouter) checkACLRuleLimits(config types.AppNetworkConfig) error {
+	perNICount := make(map[uuid.UUID]uint32)
+	for _, adapterCfg := range config.AppNetAdapterList {
+		count := uint32(len(adapterCfg.ACLs))
+		if z.maxACLRulesPerVIF != 0 && count > z.maxACLRulesPerVIF {
+			return fmt.Errorf("adapter %s of app %s has %d ACL rules, "+
+				"exceeding the limit of %d rules per VIF",
+				adapterCfg.Name, config.DisplayName, count, z.maxACLRulesPerVIF)
+		}
+		perNICount[adapterCfg.Network] += count
+	}
+	if z.maxACLRulesPerNI == 0 {
+		return nil
+	}
+	// Include ACL rules of other applications connected to the same
+	// network instances.
+	for _, c := range z.subAppNetworkConfig.GetAll() {
+		appNetConfig2 := c.(types.AppNetworkConfig)
+		if config.Key() == appNetConfig2.Key() {
+			continue
+		}
+		for _, adapterCfg := range appNetConfig2.AppNetAdapterList {
+			if _, used := perNICount[adapterCfg.Network]; used {
+				perNICount[adapterCfg.Network] += uint32(len(adapterCfg.ACLs))
+			}
+		}
+	}
+	for niID, count := range perNICount {
+		if count > z.maxACLRulesPerNI {
+			return fmt.Errorf("network instance %s would have %d ACL rules "+
+				"in total with app %s deployed, exceeding the limit of %d rules per NI",
+				niID, count, config.DisplayName, z.maxACLRulesPerNI)
+		}
+	}
+	return nil
+}
+
 func (z *zedrouter) containsHangingACLPortMapRule(
 	adapterCfgList []types.AppNetAdapterConfig) bool {
 	for _, adapterCfg := range adapterCfgList {
@@ -0,0 +1,143 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Management of floating (virtual) service IPs shared by groups of
+// application instances connected to the same local network instance
+// (see types.VirtualIPConfig). Zedrouter resolves the candidate
+// applications to their current VIF IP/MAC addresses and hands the
+// health-checking and the ARP takeover over to the VIP manager
+// (see pkg/pillar/vipmanager). Ownership changes reported back by the
+// manager are reflected in the published NetworkInstanceStatus.
+
+package zedrouter
+
+import (
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/lf-edge/eve/pkg/pillar/vipmanager"
+	uuid "github.com/satori/go.uuid"
+)
+
+// updateVIPTracking rebuilds the set of virtual IPs tracked by the VIP
+// manager for the given network instance. Called when the NI is (re)activated
+// or updated and whenever the VIF IP assignments potentially changed.
+func (z *zedrouter) updateVIPTracking(status *types.NetworkInstanceStatus) {
+	if z.vipManager == nil {
+		return
+	}
+	vipConfigs := status.NetworkInstanceConfig.VirtualIPs
+	if !status.Activated || len(vipConfigs) == 0 {
+		z.vipManager.ForgetNI(status.UUID)
+		status.ActiveVirtualIPs = nil
+		return
+	}
+	niConfig := vipmanager.NIConfig{
+		NI:           status.UUID,
+		BridgeIfName: status.BridgeName,
+	}
+	for _, vip := range vipConfigs {
+		vipConfig := vipmanager.VIPConfig{
+			IP:                 vip.IP,
+			ProbePort:          vip.ProbePort,
+			ProbeInterval:      time.Duration(vip.ProbeInterval) * time.Second,
+			ProbeFailThreshold: vip.ProbeFailThreshold,
+		}
+		for _, app := range vip.Apps {
+			vipConfig.Candidates = append(vipConfig.Candidates,
+				z.getVIPCandidate(status, app))
+		}
+		niConfig.VIPs = append(niConfig.VIPs, vipConfig)
+	}
+	z.vipManager.TrackNI(niConfig)
+	status.ActiveVirtualIPs = z.getVIPStatusList(status.UUID)
+}
+
+// getVIPCandidate resolves the given candidate application to the current
+// IP/MAC addresses of its VIF connected to the network instance.
+// The addresses are left nil when not (yet) known - e.g. when the app is not
+// deployed or its VIF has not yet received an IP address. Such candidate is
+// not eligible to own a virtual IP.
+func (z *zedrouter) getVIPCandidate(status *types.NetworkInstanceStatus,
+	app uuid.UUID) vipmanager.VIPCandidate {
+	candidate := vipmanager.VIPCandidate{App: app}
+	appNetStatus := z.lookupAppNetworkStatus(app.String())
+	if appNetStatus == nil || !appNetStatus.Activated {
+		return candidate
+	}
+	for _, adapterStatus := range appNetStatus.GetAdaptersStatusForNI(status.UUID) {
+		if len(adapterStatus.Mac) == 0 {
+			continue
+		}
+		candidate.MAC = adapterStatus.Mac
+		assignments := status.IPAssignments[adapterStatus.Mac.String()]
+		candidate.IP = assignments.IPv4Addr.Address
+		if candidate.IP == nil {
+			// Not yet snooped from DHCP, fall back to the allocated address.
+			candidate.IP = adapterStatus.AllocatedIPv4Addr
+		}
+		break
+	}
+	return candidate
+}
+
+// updateVIPTrackingForApp refreshes the VIP tracking of every network
+// instance for which the given application is one of the virtual IP
+// candidates. Called when the application network is activated/inactivated.
+func (z *zedrouter) updateVIPTrackingForApp(appID uuid.UUID) {
+	if z.vipManager == nil {
+		return
+	}
+	for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+		niStatus := item.(types.NetworkInstanceStatus)
+		var isCandidate bool
+		for _, vip := range niStatus.NetworkInstanceConfig.VirtualIPs {
+			if vip.HasCandidate(appID) {
+				isCandidate = true
+				break
+			}
+		}
+		if !isCandidate {
+			continue
+		}
+		z.updateVIPTracking(&niStatus)
+		z.publishNetworkInstanceStatus(&niStatus)
+	}
+}
+
+// processVIPFailoverEvents updates the published status of network instances
+// affected by virtual IP ownership changes reported by the VIP manager.
+func (z *zedrouter) processVIPFailoverEvents(events []vipmanager.FailoverEvent) {
+	updatedNIs := make(map[uuid.UUID]struct{})
+	for _, event := range events {
+		z.log.Noticef("Virtual IP %v of NI %v failed over from app %v "+
+			"to app %v", event.VIP, event.NI, event.PrevOwner, event.NewOwner)
+		updatedNIs[event.NI] = struct{}{}
+	}
+	for niID := range updatedNIs {
+		status := z.lookupNetworkInstanceStatus(niID.String())
+		if status == nil {
+			z.log.Errorf("Failed to get status for network instance %s "+
+				"(needed to update virtual IP status)", niID)
+			continue
+		}
+		status.ActiveVirtualIPs = z.getVIPStatusList(niID)
+		z.publishNetworkInstanceStatus(status)
+	}
+}
+
+// getVIPStatusList converts the VIP manager view of the virtual IPs
+// of the given network instance into the status representation published
+// to zedagent.
+func (z *zedrouter) getVIPStatusList(niID uuid.UUID) []types.VirtualIPStatus {
+	var statusList []types.VirtualIPStatus
+	for _, vipStatus := range z.vipManager.GetVIPStatus(niID) {
+		statusList = append(statusList, types.VirtualIPStatus{
+			IP:            vipStatus.IP,
+			ActiveApp:     vipStatus.Owner,
+			FailoverCount: vipStatus.FailoverCount,
+			LastFailover:  vipStatus.LastFailover,
+		})
+	}
+	return statusList
+}
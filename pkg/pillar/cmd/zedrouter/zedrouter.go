@@ -27,6 +27,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"strings"
 	"sync"
@@ -36,17 +37,24 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/agentlog"
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/cmd/msrv"
+	"github.com/lf-edge/eve/pkg/pillar/datapathprober"
 	"github.com/lf-edge/eve/pkg/pillar/devicenetwork"
+	"github.com/lf-edge/eve/pkg/pillar/dnsprober"
 	"github.com/lf-edge/eve/pkg/pillar/flextimer"
+	"github.com/lf-edge/eve/pkg/pillar/netlog"
 	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
 	"github.com/lf-edge/eve/pkg/pillar/nireconciler"
 	"github.com/lf-edge/eve/pkg/pillar/nistate"
 	"github.com/lf-edge/eve/pkg/pillar/objtonum"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/pubsub"
+	"github.com/lf-edge/eve/pkg/pillar/snifilter"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	"github.com/lf-edge/eve/pkg/pillar/uplinkprober"
 	"github.com/lf-edge/eve/pkg/pillar/utils/wait"
+	"github.com/lf-edge/eve/pkg/pillar/vipmanager"
 	"github.com/lf-edge/eve/pkg/pillar/zedcloud"
+	uuid "github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -73,8 +81,11 @@ type zedrouter struct {
 	runCtx context.Context
 
 	// CLI options
-	enableArpSnooping  bool // enable/disable switch NI arp snooping
-	localLegacyMACAddr bool // switch to legacy MAC address generation
+	enableArpSnooping  bool   // enable/disable switch NI arp snooping
+	localLegacyMACAddr bool   // switch to legacy MAC address generation
+	niDatapathProbe    bool   // enable/disable probing of the NI datapath
+	maxACLRulesPerVIF  uint32 // limit for the number of ACL rules per app VIF (0 = no limit)
+	maxACLRulesPerNI   uint32 // limit for the total number of ACL rules per NI (0 = no limit)
 
 	agentStartTime     time.Time
 	receivedConfigTime time.Time
@@ -92,6 +103,11 @@ type zedrouter struct {
 	niReconciler     nireconciler.NIReconciler
 	reachProber      uplinkprober.ReachabilityProber
 	uplinkProber     *uplinkprober.UplinkProber
+	dnsProber        *dnsprober.DNSProber
+	datapathProber   *datapathprober.DatapathProber
+	sniFilter        *snifilter.SNIFilter
+	vipManager       *vipmanager.VIPManager
+	procSupervisor   *procsupervisor.ProcSupervisor
 
 	// Number allocators
 	appNumAllocator     *objtonum.Allocator
@@ -100,6 +116,13 @@ type zedrouter struct {
 	appIntfNumAllocator map[string]*objtonum.Allocator // key: network instance UUID as string
 	appMACGeneratorMap  objtonum.Map
 
+	// Device-wide registry of port-map (DNAT) reservations (see portmap.go).
+	portMapReservations map[portMapKey]uuid.UUID
+
+	// Last successfully applied NI states, used for automatic rollback
+	// of failed NI config modifications (see nisnapshot.go).
+	niSnapshots map[uuid.UUID]*niSnapshot
+
 	// To collect uplink info
 	subDeviceNetworkStatus pubsub.Subscription
 
@@ -109,6 +132,14 @@ type zedrouter struct {
 	// Metrics for all network interfaces
 	pubNetworkMetrics pubsub.Publication
 
+	// Consolidated snapshot of the device networking topology
+	pubNetworkTopology pubsub.Publication
+
+	// Rate-limited logging of repeated datapath/reconciliation errors,
+	// with periodically published suppression counters.
+	netLog            *netlog.RateLimitedLogger
+	pubNetLogCounters pubsub.Publication
+
 	// Status and metrics collected for Network Instances
 	pubNetworkInstanceStatus  pubsub.Publication
 	pubNetworkInstanceMetrics pubsub.Publication
@@ -135,10 +166,20 @@ type zedrouter struct {
 	pubAppFlowMonitor pubsub.Publication
 	flowPublishMap    map[string]time.Time
 
+	// Per-app aggregation of flow records into top-N destination statistics
+	// (see flowaggregate.go).
+	pubAppDestStats pubsub.Publication
+	appDestAggrs    map[uuid.UUID]*appDestAggr
+
 	// Ticker for periodic publishing of metrics
 	metricInterval uint32 // In seconds
 	publishTicker  *flextimer.FlexTickerHandle
 
+	// Periodic per-NI maintenance and the cumulative metrics about resources
+	// reclaimed by it (see maintenance.go).
+	maintenanceTicker    *time.Ticker
+	niMaintenanceMetrics map[uuid.UUID]*types.NIMaintenanceMetrics
+
 	// Retry NI or app network config that zedrouter failed to apply
 	retryTimer *time.Timer
 
@@ -205,6 +246,8 @@ func (z *zedrouter) init() (err error) {
 	z.agentStartTime = time.Now()
 	z.appContainerLogger = agentlog.CustomLogInit(logrus.InfoLevel)
 	z.flowPublishMap = make(map[string]time.Time)
+	z.appDestAggrs = make(map[uuid.UUID]*appDestAggr)
+	z.niMaintenanceMetrics = make(map[uuid.UUID]*types.NIMaintenanceMetrics)
 	z.deviceNetworkStatus = &types.DeviceNetworkStatus{}
 
 	z.zedcloudMetrics = zedcloud.NewAgentMetrics()
@@ -236,12 +279,16 @@ func (z *zedrouter) init() (err error) {
 
 	// Initialize Zedrouter components (for Linux network stack).
 	z.networkMonitor = &netmonitor.LinuxNetworkMonitor{Log: z.log}
-	z.niStateCollector = nistate.NewLinuxCollector(z.log)
+	z.niStateCollector = nistate.NewLinuxCollector(z.log, z.networkMonitor)
 	controllerReachProber := uplinkprober.NewControllerReachProber(
 		z.log, agentName, z.zedcloudMetrics)
 	z.reachProber = controllerReachProber
+	z.procSupervisor = procsupervisor.NewProcSupervisor(
+		z.log, procsupervisor.DefaultConfig())
+	z.netLog = netlog.NewRateLimitedLogger(
+		z.log, netlog.DefaultBurst, netlog.DefaultWindow)
 	z.niReconciler = nireconciler.NewLinuxNIReconciler(z.log, z.logger, z.networkMonitor,
-		z.metadataServer.MakeMetadataHandler(), true, true,
+		z.metadataServer.MakeMetadataHandler(), z.procSupervisor, z.netLog, true, true,
 		z.withKubeNetworking)
 
 	z.initNumberAllocators()
@@ -297,6 +344,9 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 		time.Duration(max))
 	z.publishTicker = &publishTicker
 
+	// Periodic per-NI maintenance (see maintenance.go).
+	z.maintenanceTicker = time.NewTicker(niMaintenanceInterval)
+
 	// Start watchers
 	reconcilerUpdates := z.niReconciler.WatchReconcilerUpdates()
 	flowUpdates := z.niStateCollector.WatchFlows()
@@ -304,6 +354,14 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 	z.uplinkProber = uplinkprober.NewUplinkProber(
 		z.log, uplinkprober.DefaultConfig(), z.reachProber)
 	probeUpdates := z.uplinkProber.WatchProbeUpdates()
+	z.dnsProber = dnsprober.NewDNSProber(z.log, dnsprober.DefaultConfig())
+	dnsHealthUpdates := z.dnsProber.WatchHealthUpdates()
+	z.datapathProber = datapathprober.NewDatapathProber(
+		z.log, datapathprober.DefaultConfig())
+	z.sniFilter = snifilter.NewSNIFilter(z.log, snifilter.DefaultConfig())
+	z.vipManager = vipmanager.NewVIPManager(z.log, vipmanager.DefaultConfig())
+	vipFailoverUpdates := z.vipManager.WatchFailoverUpdates()
+	procHealthUpdates := z.procSupervisor.WatchHealthUpdates()
 
 	// Activate all subscriptions.
 	inactiveSubs := []pubsub.Subscription{
@@ -359,10 +417,17 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 				z.log.Errorln(err)
 			}
 
+			z.publishNetworkTopology()
+			z.updateMulticastGroups()
+			z.publishNetLogCounters()
+
 			z.pubSub.CheckMaxTimeTopic(agentName, "publishMetrics", start,
 				warningTime, errorTime)
 			// Check and remove stale flowlog publications.
 			z.checkFlowUnpublish()
+			// Publish per-app destination statistics for elapsed
+			// aggregation windows.
+			z.maybePublishAppDestStats()
 
 		case recUpdate := <-reconcilerUpdates:
 			switch recUpdate.UpdateType {
@@ -400,8 +465,16 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 			}
 
 		case flowUpdate := <-flowUpdates:
+			z.recordACLFlowSamples(flowUpdate)
+			z.aggregateFlowStats(flowUpdate)
 			z.flowPublish(flowUpdate)
 
+		case failoverEvents := <-vipFailoverUpdates:
+			z.processVIPFailoverEvents(failoverEvents)
+
+		case procHealth := <-procHealthUpdates:
+			z.processProcHealthUpdates(procHealth)
+
 		case ipAssignUpdates := <-ipAssignUpdates:
 			for _, ipAssignUpdate := range ipAssignUpdates {
 				vif := ipAssignUpdate.Prev.VIF
@@ -415,10 +488,9 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 						niKey, vif.NetAdapterName)
 					continue
 				}
-				netStatus.IPAssignments[mac] = types.AssignedAddrs{
-					IPv4Addr:  newAddrs.IPv4Addr,
-					IPv6Addrs: newAddrs.IPv6Addrs,
-				}
+				netStatus.IPAssignments[mac] = newAddrs.AssignedAddrs
+				// Addresses of virtual IP candidates may have changed.
+				z.updateVIPTracking(netStatus)
 				z.publishNetworkInstanceStatus(netStatus)
 				appKey := vif.App.String()
 				appStatus := z.lookupAppNetworkStatus(appKey)
@@ -439,6 +511,8 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 				}
 				z.publishAppNetworkStatus(appStatus)
 			}
+			// Guest IPs used for SNI filtering may have changed.
+			z.updateSNIFiltering()
 
 		case updates := <-probeUpdates:
 			start := time.Now()
@@ -460,6 +534,26 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 			z.pubSub.CheckMaxTimeTopic(agentName, "probeUpdates", start,
 				warningTime, errorTime)
 
+		case <-dnsHealthUpdates:
+			start := time.Now()
+			z.log.Tracef("DNS health update at %v", time.Now())
+			// Reachability of some of the upstream DNS servers changed.
+			// Re-apply uplink config to all activated network instances
+			// so that their dnsmasq instances forward queries to healthy
+			// resolvers first.
+			for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+				status := item.(types.NetworkInstanceStatus)
+				niKey := status.UUID.String()
+				config := z.lookupNetworkInstanceConfig(niKey)
+				if config == nil {
+					z.log.Errorf("Failed to get config for network instance %s", niKey)
+					continue
+				}
+				z.doUpdateNIUplink(status.SelectedUplinkLogicalLabel, &status, *config)
+			}
+			z.pubSub.CheckMaxTimeTopic(agentName, "dnsHealthUpdates", start,
+				warningTime, errorTime)
+
 		case req := <-z.cniRequests:
 			start := time.Now()
 			z.handleRPC(req)
@@ -473,6 +567,12 @@ func (z *zedrouter) run(ctx context.Context) (err error) {
 			z.pubSub.CheckMaxTimeTopic(agentName, "scanAppNetworkStatus", start,
 				warningTime, errorTime)
 
+		case <-z.maintenanceTicker.C:
+			start := time.Now()
+			z.runNIMaintenance(ctx)
+			z.pubSub.CheckMaxTimeTopic(agentName, "niMaintenance", start,
+				warningTime, errorTime)
+
 		case <-stillRunning.C:
 		}
 		z.pubSub.StillRunning(agentName, warningTime, errorTime)
@@ -524,6 +624,14 @@ func (z *zedrouter) initPublications() (err error) {
 		return err
 	}
 
+	z.pubAppDestStats, err = z.pubSub.NewPublication(pubsub.PublicationOptions{
+		AgentName: agentName,
+		TopicType: types.AppDestinationStats{},
+	})
+	if err != nil {
+		return err
+	}
+
 	z.pubAppContainerStats, err = z.pubSub.NewPublication(pubsub.PublicationOptions{
 		AgentName: agentName,
 		TopicType: types.AppContainerMetrics{},
@@ -540,6 +648,22 @@ func (z *zedrouter) initPublications() (err error) {
 		log.Fatal(err)
 	}
 
+	z.pubNetworkTopology, err = z.pubSub.NewPublication(pubsub.PublicationOptions{
+		AgentName: agentName,
+		TopicType: types.NetworkTopology{},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	z.pubNetLogCounters, err = z.pubSub.NewPublication(pubsub.PublicationOptions{
+		AgentName: agentName,
+		TopicType: types.NetLogCounters{},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	z.pubZedcloudMetrics, err = z.pubSub.NewPublication(
 		pubsub.PublicationOptions{
 			AgentName: agentName,
@@ -878,6 +1002,61 @@ func (z *zedrouter) flowPublish(flow types.IPFlow) {
 	}
 }
 
+// recordACLFlowSamples keeps the first few flow tuples that matched each
+// dropping ACL rule (incl. traffic exceeding the rate of a LIMIT rule and
+// the implicit default-drop) and exposes them via AppNetworkStatus,
+// helping to understand which traffic a given rule is actually catching.
+// Samples are cleared whenever the ACLs of the app network adapter change
+// (see doCopyAppNetworkConfigToStatus).
+func (z *zedrouter) recordACLFlowSamples(flow types.IPFlow) {
+	status := z.lookupAppNetworkStatus(flow.Scope.AppUUID.String())
+	if status == nil {
+		return
+	}
+	var changed bool
+	for i := range status.AppNetAdapterList {
+		adapterStatus := &status.AppNetAdapterList[i]
+		if adapterStatus.Name != flow.Scope.NetAdapterName {
+			continue
+		}
+		for _, flowRec := range flow.Flows {
+			if flowRec.Action != types.ACLActionDrop {
+				continue
+			}
+			var sampleCount int
+			var alreadySampled bool
+			for _, sample := range adapterStatus.ACLFlowSamples {
+				if sample.ACLID != flowRec.ACLID {
+					continue
+				}
+				sampleCount++
+				if sample.Flow.Src.Equal(flowRec.Flow.Src) &&
+					sample.Flow.Dst.Equal(flowRec.Flow.Dst) &&
+					sample.Flow.SrcPort == flowRec.Flow.SrcPort &&
+					sample.Flow.DstPort == flowRec.Flow.DstPort &&
+					sample.Flow.Proto == flowRec.Flow.Proto {
+					alreadySampled = true
+					break
+				}
+			}
+			if alreadySampled || sampleCount >= types.MaxACLFlowSamplesPerRule {
+				continue
+			}
+			adapterStatus.ACLFlowSamples = append(adapterStatus.ACLFlowSamples,
+				types.ACLFlowSample{
+					ACLID:      flowRec.ACLID,
+					Flow:       flowRec.Flow,
+					Inbound:    flowRec.Inbound,
+					RecordedAt: time.Now(),
+				})
+			changed = true
+		}
+	}
+	if changed {
+		z.publishAppNetworkStatus(status)
+	}
+}
+
 func (z *zedrouter) checkFlowUnpublish() {
 	for k, m := range z.flowPublishMap {
 		passed := int64(time.Since(m) / time.Second)
@@ -916,6 +1095,18 @@ func (z *zedrouter) publishNetworkInstanceMetricsAll(nms *types.NetworkMetrics)
 	}
 }
 
+// publishNetLogCounters publishes the counters of log entries suppressed
+// by the rate-limited logging facility (see pillar/netlog).
+func (z *zedrouter) publishNetLogCounters() {
+	counters := types.NetLogCounters{
+		SuppressedEntries: z.netLog.SuppressionCounters(),
+	}
+	err := z.pubNetLogCounters.Publish(counters.Key(), counters)
+	if err != nil {
+		z.log.Errorf("publishNetLogCounters failed: %v", err)
+	}
+}
+
 func (z *zedrouter) createNetworkInstanceMetrics(status *types.NetworkInstanceStatus,
 	nms *types.NetworkMetrics) *types.NetworkInstanceMetrics {
 
@@ -934,6 +1125,11 @@ func (z *zedrouter) createNetworkInstanceMetrics(status *types.NetworkInstanceSt
 			netMetrics.MetricList = append(netMetrics.MetricList, vifMetrics)
 		}
 	}
+	for _, extraPort := range z.getNIExtraPortsConfig(status) {
+		if portMetrics, found := nms.LookupNetworkMetrics(extraPort.IfName); found {
+			netMetrics.MetricList = append(netMetrics.MetricList, portMetrics)
+		}
+	}
 	niMetrics.NetworkMetrics = netMetrics
 
 	if status.WithUplinkProbing() {
@@ -945,11 +1141,112 @@ func (z *zedrouter) createNetworkInstanceMetrics(status *types.NetworkInstanceSt
 		}
 	}
 
+	niMetrics.InstalledACLRules = z.niReconciler.GetInstalledACLRuleCount(status.UUID)
+	niMetrics.NAT64.DynamicMappings = z.niReconciler.GetNAT64MappingCount(status.UUID)
+	if maintenance, haveMetrics := z.niMaintenanceMetrics[status.UUID]; haveMetrics {
+		niMetrics.Maintenance = *maintenance
+	}
 	niMetrics.VlanMetrics.NumTrunkPorts = status.NumTrunkPorts
 	niMetrics.VlanMetrics.VlanCounts = status.VlanMap
+
+	if z.dnsProber != nil && status.SelectedUplinkIntfName != "" {
+		dnsServers := types.GetDNSServers(
+			*z.deviceNetworkStatus, status.SelectedUplinkIntfName)
+		for _, server := range z.dnsProber.GetServerStatus(dnsServers) {
+			niMetrics.DNSMetrics = append(niMetrics.DNSMetrics,
+				types.DNSServerMetrics{
+					Server:           server.Server.String(),
+					Reachable:        server.Reachable,
+					AvgLatencyInMsec: uint32(server.AvgLatency.Milliseconds()),
+				})
+		}
+	}
+	if z.datapathProber != nil {
+		for _, endpoint := range z.datapathProber.GetMetrics(status.UUID) {
+			niMetrics.DatapathMetrics = append(niMetrics.DatapathMetrics,
+				types.DatapathEndpointMetrics{
+					Endpoint:         endpoint.Endpoint.String(),
+					IsGateway:        endpoint.IsGateway,
+					SentCnt:          endpoint.SentCnt,
+					RecvCnt:          endpoint.RecvCnt,
+					RecentLossPct:    endpoint.RecentLossPct,
+					AvgLatencyInMsec: uint32(endpoint.AvgLatency.Milliseconds()),
+				})
+		}
+	}
 	return &niMetrics
 }
 
+// updateDatapathProbing refreshes the set of network instances whose datapath
+// should be probed (see datapathprober). Called whenever the set of activated
+// NIs, their selected uplinks, or the enable/disable config may have changed.
+func (z *zedrouter) updateDatapathProbing() {
+	if z.datapathProber == nil {
+		return
+	}
+	var probedNIs []datapathprober.NIProbeConfig
+	if z.niDatapathProbe {
+		for _, item := range z.pubNetworkInstanceStatus.GetAll() {
+			status := item.(types.NetworkInstanceStatus)
+			if !status.Activated || status.BridgeIPAddr == nil {
+				continue
+			}
+			port := z.deviceNetworkStatus.GetPortByIfName(
+				status.SelectedUplinkIntfName)
+			if port == nil || len(port.DefaultRouters) == 0 {
+				continue
+			}
+			probedNIs = append(probedNIs, datapathprober.NIProbeConfig{
+				NI:        status.UUID,
+				BridgeIP:  status.BridgeIPAddr,
+				GatewayIP: port.DefaultRouters[0],
+			})
+		}
+	}
+	z.datapathProber.TrackNIs(probedNIs)
+}
+
+// updateSNIFiltering refreshes the set of VIFs whose egress TLS traffic
+// is subject to SNI allowlisting (see snifilter). Called whenever application
+// networks are (in)activated, modified, or VIF IP assignments change.
+func (z *zedrouter) updateSNIFiltering() {
+	if z.sniFilter == nil {
+		return
+	}
+	var filteredVIFs []snifilter.VIFConfig
+	for _, item := range z.pubAppNetworkStatus.GetAll() {
+		status := item.(types.AppNetworkStatus)
+		if !status.Activated {
+			continue
+		}
+		for _, adapterStatus := range status.AppNetAdapterList {
+			if len(adapterStatus.SNIAllowlist) == 0 {
+				continue
+			}
+			var fallbackDrop bool
+			niConfig := z.lookupNetworkInstanceConfig(adapterStatus.Network.String())
+			if niConfig != nil {
+				fallbackDrop = niConfig.SNIFilterFallbackDrop
+			}
+			var guestIPs []net.IP
+			if adapterStatus.AllocatedIPv4Addr != nil {
+				guestIPs = append(guestIPs, adapterStatus.AllocatedIPv4Addr)
+			}
+			guestIPs = append(guestIPs, adapterStatus.AllocatedIPv6List...)
+			for _, guestIP := range guestIPs {
+				filteredVIFs = append(filteredVIFs, snifilter.VIFConfig{
+					App:          status.UUIDandVersion.UUID,
+					NI:           adapterStatus.Network,
+					GuestIP:      guestIP,
+					Allowlist:    adapterStatus.SNIAllowlist,
+					FallbackDrop: fallbackDrop,
+				})
+			}
+		}
+	}
+	z.sniFilter.TrackVIFs(filteredVIFs)
+}
+
 func (z *zedrouter) deleteNetworkInstanceMetrics(key string) {
 	pub := z.pubNetworkInstanceMetrics
 	if metrics := z.lookupNetworkInstanceMetrics(key); metrics != nil {
@@ -4,6 +4,7 @@
 package conntester
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lf-edge/eve/pkg/pillar/netdump"
@@ -16,7 +17,9 @@ type ConnectivityTester interface {
 	// TestConnectivity returns nil error if connectivity test has passed.
 	// Additionally, it returns test result for each tested device network interface
 	// and network traces of executed probes if withNetTrace was enabled.
-	TestConnectivity(dns types.DeviceNetworkStatus,
+	// The test is aborted early when the given context is canceled, with ctx.Err()
+	// returned inside the error.
+	TestConnectivity(ctx context.Context, dns types.DeviceNetworkStatus,
 		withNetTrace bool) (types.IntfStatusMap, []netdump.TracedNetRequest, error)
 }
 
@@ -4,6 +4,7 @@
 package conntester
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -47,8 +48,9 @@ func (t *MockConnectivityTester) SetConnectivityError(dpcKey, ifName string, err
 }
 
 // TestConnectivity simulates connectivity test.
-func (t *MockConnectivityTester) TestConnectivity(dns types.DeviceNetworkStatus,
-	withNetTrace bool) (intfStatusMap types.IntfStatusMap, tracedReqs []netdump.TracedNetRequest, err error) {
+func (t *MockConnectivityTester) TestConnectivity(ctx context.Context,
+	dns types.DeviceNetworkStatus, withNetTrace bool) (
+	intfStatusMap types.IntfStatusMap, tracedReqs []netdump.TracedNetRequest, err error) {
 	t.Lock()
 	defer t.Unlock()
 
@@ -89,7 +91,12 @@ func (t *MockConnectivityTester) TestConnectivity(dns types.DeviceNetworkStatus,
 			intfStatusMap.RecordFailure(ifName, err.Error())
 			continue
 		}
-		time.Sleep(t.TestDuration)
+		select {
+		case <-time.After(t.TestDuration):
+		case <-ctx.Done():
+			err = fmt.Errorf("connectivity test was canceled: %w", ctx.Err())
+			return intfStatusMap, nil, err
+		}
 		ifRef := ifRef{dpcKey: dns.DPCKey, ifName: ifName}
 		err = t.connErrors[ifRef]
 		if _, rtf := err.(*RemoteTemporaryFailure); rtf {
@@ -4,6 +4,7 @@
 package conntester
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -41,8 +42,9 @@ type ZedcloudConnectivityTester struct {
 
 // TestConnectivity uses VerifyAllIntf from the zedcloud package, which
 // tries to call the "ping" API of the controller.
-func (t *ZedcloudConnectivityTester) TestConnectivity(dns types.DeviceNetworkStatus,
-	withNetTrace bool) (types.IntfStatusMap, []netdump.TracedNetRequest, error) {
+func (t *ZedcloudConnectivityTester) TestConnectivity(ctx context.Context,
+	dns types.DeviceNetworkStatus, withNetTrace bool) (
+	types.IntfStatusMap, []netdump.TracedNetRequest, error) {
 
 	t.iteration++
 	intfStatusMap := *types.NewIntfStatusMap()
@@ -107,8 +109,11 @@ func (t *ZedcloudConnectivityTester) TestConnectivity(dns types.DeviceNetworkSta
 			return intfStatusMap, nil, err
 		}
 	}
-	rv, err := zedcloud.VerifyAllIntf(&zedcloudCtx, testURL, requiredSuccessCount,
-		t.iteration, withNetTrace)
+	rv, err := t.verifyAllIntf(ctx, &zedcloudCtx, testURL, withNetTrace)
+	if err != nil && ctx.Err() != nil {
+		// Test was canceled by the caller, do not interpret (incomplete) results.
+		return intfStatusMap, nil, err
+	}
 	intfStatusMap.SetOrUpdateFromMap(rv.IntfStatusMap)
 	t.Log.Tracef("TestConnectivity: intfStatusMap = %+v", intfStatusMap)
 	for i := range rv.TracedReqs {
@@ -149,6 +154,33 @@ func (t *ZedcloudConnectivityTester) TestConnectivity(dns types.DeviceNetworkSta
 	return intfStatusMap, rv.TracedReqs, err
 }
 
+// verifyAllIntf runs zedcloud.VerifyAllIntf in a separate goroutine so that
+// the caller is not blocked for the whole duration of the test when the context
+// is canceled in the meantime. VerifyAllIntf itself does not support
+// cancellation - when the context is canceled, probing continues in the
+// background but the results are discarded.
+func (t *ZedcloudConnectivityTester) verifyAllIntf(ctx context.Context,
+	zedcloudCtx *zedcloud.ZedCloudContext, testURL string,
+	withNetTrace bool) (zedcloud.VerifyRetval, error) {
+	type verifyResult struct {
+		rv  zedcloud.VerifyRetval
+		err error
+	}
+	resultCh := make(chan verifyResult, 1)
+	go func() {
+		rv, err := zedcloud.VerifyAllIntf(zedcloudCtx, testURL, requiredSuccessCount,
+			t.iteration, withNetTrace)
+		resultCh <- verifyResult{rv: rv, err: err}
+	}()
+	select {
+	case result := <-resultCh:
+		return result.rv, result.err
+	case <-ctx.Done():
+		return zedcloud.VerifyRetval{},
+			fmt.Errorf("connectivity test was canceled: %w", ctx.Err())
+	}
+}
+
 func (t *ZedcloudConnectivityTester) getPortsNotReady(
 	verifyErr error, dns types.DeviceNetworkStatus) (ports []string) {
 	if sendErr, isSendErr := verifyErr.(*zedcloud.SendError); isSendErr {
@@ -174,6 +206,26 @@ func (t *ZedcloudConnectivityTester) getPortsNotReady(
 	return ports
 }
 
+// ExtractCaptivePortals extracts from the error returned by TestConnectivity
+// the set of interfaces detected to be behind a captive portal, as a map
+// of interface name to the portal URL (which may be empty if the URL was
+// not learned).
+func ExtractCaptivePortals(testErr error) map[string]string {
+	portals := make(map[string]string)
+	// TestConnectivity may have wrapped the error from VerifyAllIntf
+	// inside PortsNotReady or RemoteTemporaryFailure.
+	var sendErr *zedcloud.SendError
+	if errors.As(testErr, &sendErr) {
+		for _, attempt := range sendErr.Attempts {
+			var cpErr *types.CaptivePortalError
+			if errors.As(attempt.Err, &cpErr) {
+				portals[cpErr.IfName] = cpErr.PortalURL
+			}
+		}
+	}
+	return portals
+}
+
 // Enable all net traces, including packet capture - ping and google.com requests
 // are quite small.
 func (t *ZedcloudConnectivityTester) netTraceOpts(
@@ -28,6 +28,17 @@ func (f PortMapFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
 	return match
 }
 
+// MarkFilter : Custom filter to match flows on (masked) connection mark.
+type MarkFilter struct {
+	Mark     uint32
+	MarkMask uint32
+}
+
+// MatchConntrackFlow : Implements CustomConntrackFilter interface to filter flows
+func (f MarkFilter) MatchConntrackFlow(flow *netlink.ConntrackFlow) bool {
+	return flow.Mark&f.MarkMask == f.Mark&f.MarkMask
+}
+
 // SrcIPFilter : Custom filter to match on source IP address, source port, protocol and mark
 type SrcIPFilter struct {
 	Log       *base.LogObject
@@ -0,0 +1,259 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package datapathprober is used by zedrouter to measure packet loss and
+// latency on the application datapath of local network instances.
+// Unlike uplinkprober, which probes from the management plane (using uplink
+// IP addresses as the source), this prober sends periodic ICMP pings sourced
+// from the NI bridge IP towards the gateway of the selected uplink and
+// towards a remote endpoint. The probes therefore traverse the same routing
+// and NAT path as application traffic, making datapath degradation visible
+// separately from management-plane probing.
+package datapathprober
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	uuid "github.com/satori/go.uuid"
+	"github.com/tatsushid/go-fastping"
+)
+
+// DatapathProber periodically probes the datapath of every tracked network
+// instance and records loss and latency statistics per probed endpoint.
+type DatapathProber struct {
+	sync.Mutex
+	log    *base.LogObject
+	config Config
+
+	nis map[uuid.UUID]*niProbeState
+
+	probeTicker *time.Ticker
+}
+
+// Config : configuration for DatapathProber.
+// Currently, this is not configurable via controller.
+type Config struct {
+	// ProbeInterval : how often to probe the datapath of every tracked NI.
+	ProbeInterval time.Duration
+	// ProbeTimeout : timeout for a single ICMP probe.
+	ProbeTimeout time.Duration
+	// RemoteEndpoint : remote IP address to ping in order to evaluate
+	// the datapath beyond the uplink gateway.
+	RemoteEndpoint net.IP
+	// LossWindowSize : number of most recent probes from which the recent
+	// loss percentage is calculated.
+	LossWindowSize uint8
+}
+
+// DefaultConfig : default configuration for DatapathProber.
+// Since these options are currently not configurable via controller,
+// non-default config is used only in unit tests.
+func DefaultConfig() Config {
+	return Config{
+		ProbeInterval:  30 * time.Second,
+		ProbeTimeout:   3 * time.Second,
+		RemoteEndpoint: net.IPv4(8, 8, 8, 8),
+		LossWindowSize: 20,
+	}
+}
+
+// NIProbeConfig : probing parameters for a single network instance.
+type NIProbeConfig struct {
+	// NI : network instance UUID.
+	NI uuid.UUID
+	// BridgeIP : IP address of the NI bridge, used as the source address
+	// for the probes.
+	BridgeIP net.IP
+	// GatewayIP : IP address of the gateway of the uplink currently selected
+	// for the NI.
+	GatewayIP net.IP
+}
+
+// EndpointMetrics : loss/latency statistics for one probed endpoint
+// of one network instance.
+type EndpointMetrics struct {
+	// Endpoint : IP address of the probed endpoint.
+	Endpoint net.IP
+	// IsGateway : true if the endpoint is the gateway of the selected uplink
+	// (as opposed to the remote endpoint).
+	IsGateway bool
+	// SentCnt : total number of probes sent.
+	SentCnt uint32
+	// RecvCnt : total number of probe replies received.
+	RecvCnt uint32
+	// RecentLossPct : percentage of probes lost within the recent window
+	// of probes (see Config.LossWindowSize).
+	RecentLossPct uint8
+	// AvgLatency : average latency of recent successful probes.
+	// Zero if the endpoint never responded.
+	AvgLatency time.Duration
+}
+
+type endpointState struct {
+	EndpointMetrics
+	// Ring buffer with results of the most recent probes
+	// (true = reply received).
+	recentResults []bool
+	nextResultIdx int
+}
+
+type niProbeState struct {
+	config    NIProbeConfig
+	endpoints []*endpointState
+}
+
+// NewDatapathProber is a constructor for DatapathProber.
+// The prober starts probing as soon as it is given some network instances
+// to track (see TrackNIs).
+func NewDatapathProber(log *base.LogObject, config Config) *DatapathProber {
+	p := &DatapathProber{
+		log:         log,
+		config:      config,
+		nis:         make(map[uuid.UUID]*niProbeState),
+		probeTicker: time.NewTicker(config.ProbeInterval),
+	}
+	go p.runProbing()
+	return p
+}
+
+// TrackNIs : update the set of network instances to probe.
+// NIs no longer present in the given list stop being tracked and their
+// statistics are dropped. Statistics of an already tracked NI are preserved
+// as long as its probing parameters remain unchanged.
+func (p *DatapathProber) TrackNIs(nis []NIProbeConfig) {
+	p.Lock()
+	defer p.Unlock()
+	tracked := make(map[uuid.UUID]struct{})
+	for _, niConfig := range nis {
+		tracked[niConfig.NI] = struct{}{}
+		ni, haveIt := p.nis[niConfig.NI]
+		if haveIt && ni.config.BridgeIP.Equal(niConfig.BridgeIP) &&
+			ni.config.GatewayIP.Equal(niConfig.GatewayIP) {
+			continue
+		}
+		ni = &niProbeState{config: niConfig}
+		for _, endpoint := range []net.IP{niConfig.GatewayIP, p.config.RemoteEndpoint} {
+			if endpoint == nil {
+				continue
+			}
+			ni.endpoints = append(ni.endpoints, &endpointState{
+				EndpointMetrics: EndpointMetrics{
+					Endpoint:  endpoint,
+					IsGateway: endpoint.Equal(niConfig.GatewayIP),
+				},
+				recentResults: make([]bool, 0, p.config.LossWindowSize),
+			})
+		}
+		p.nis[niConfig.NI] = ni
+	}
+	for niID := range p.nis {
+		if _, isTracked := tracked[niID]; !isTracked {
+			delete(p.nis, niID)
+		}
+	}
+}
+
+// GetMetrics returns the datapath probing statistics collected for the given
+// network instance. Returns nil if the NI is not tracked.
+func (p *DatapathProber) GetMetrics(niID uuid.UUID) []EndpointMetrics {
+	p.Lock()
+	defer p.Unlock()
+	ni, haveIt := p.nis[niID]
+	if !haveIt {
+		return nil
+	}
+	metrics := make([]EndpointMetrics, 0, len(ni.endpoints))
+	for _, endpoint := range ni.endpoints {
+		metrics = append(metrics, endpoint.EndpointMetrics)
+	}
+	return metrics
+}
+
+func (p *DatapathProber) runProbing() {
+	for range p.probeTicker.C {
+		p.probeNIs()
+	}
+}
+
+func (p *DatapathProber) probeNIs() {
+	p.Lock()
+	nis := make([]*niProbeState, 0, len(p.nis))
+	for _, ni := range p.nis {
+		nis = append(nis, ni)
+	}
+	p.Unlock()
+	for _, ni := range nis {
+		for _, endpoint := range ni.endpoints {
+			latency, err := p.probe(ni.config.BridgeIP, endpoint.Endpoint)
+			if err != nil {
+				p.log.Functionf("DatapathProber: probe of %v from NI %v "+
+					"failed: %v", endpoint.Endpoint, ni.config.NI, err)
+			}
+			p.Lock()
+			p.recordProbeResult(endpoint, latency, err == nil)
+			p.Unlock()
+		}
+	}
+}
+
+// recordProbeResult updates statistics of the given endpoint with the outcome
+// of one probe. The caller must hold the prober lock.
+func (p *DatapathProber) recordProbeResult(endpoint *endpointState,
+	latency time.Duration, success bool) {
+	endpoint.SentCnt++
+	if success {
+		endpoint.RecvCnt++
+		if endpoint.AvgLatency == 0 {
+			endpoint.AvgLatency = latency
+		} else {
+			// Exponentially weighted moving average.
+			endpoint.AvgLatency = (3*endpoint.AvgLatency + latency) / 4
+		}
+	}
+	if len(endpoint.recentResults) < int(p.config.LossWindowSize) {
+		endpoint.recentResults = append(endpoint.recentResults, success)
+	} else {
+		endpoint.recentResults[endpoint.nextResultIdx] = success
+		endpoint.nextResultIdx =
+			(endpoint.nextResultIdx + 1) % int(p.config.LossWindowSize)
+	}
+	var lostCnt int
+	for _, replied := range endpoint.recentResults {
+		if !replied {
+			lostCnt++
+		}
+	}
+	endpoint.RecentLossPct = uint8(100 * lostCnt / len(endpoint.recentResults))
+}
+
+// probe sends a single ICMP echo request from the given source address
+// and measures how long it took to get the reply.
+func (p *DatapathProber) probe(srcIP, dstIP net.IP) (time.Duration, error) {
+	var (
+		latency time.Duration
+		success bool
+	)
+	pinger := fastping.NewPinger()
+	pinger.AddIPAddr(&net.IPAddr{IP: dstIP})
+	if _, err := pinger.Source(srcIP.String()); err != nil {
+		return 0, err
+	}
+	pinger.MaxRTT = p.config.ProbeTimeout
+	pinger.OnRecv = func(ip *net.IPAddr, rtt time.Duration) {
+		if ip != nil && ip.IP.Equal(dstIP) {
+			latency = rtt
+			success = true
+		}
+	}
+	if err := pinger.Run(); err != nil {
+		return 0, err
+	}
+	if !success {
+		return 0, fmt.Errorf("no ping response received from %v", dstIP)
+	}
+	return latency, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package depgraphtest_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/depgraphtest"
+)
+
+func exportTestGraph() dg.Graph {
+	graph := dg.New(dg.InitArgs{
+		Name:        "TestGraph",
+		Description: "graph for export testing",
+	})
+	graph.PutItem(depgraphtest.MockItem{
+		ItemName: "A",
+		ItemType: mockItemType,
+		Attrs:    "attrs of A",
+	}, nil)
+	subGraph := dg.New(dg.InitArgs{
+		Name:        "SubGraph",
+		Description: "subgraph for export testing",
+	})
+	subGraph.PutItem(depgraphtest.MockItem{
+		ItemName: "B",
+		ItemType: mockItemType,
+		Attrs:    "attrs of B",
+	}, nil)
+	graph.PutSubGraph(subGraph)
+	return graph
+}
+
+func TestExportToYAML(test *testing.T) {
+	graph := exportTestGraph()
+	exporter := &dg.GraphExporter{}
+	yamlStr, err := exporter.ExportToYAML(graph)
+	if err != nil {
+		test.Fatalf("ExportToYAML failed: %v", err)
+	}
+	for _, expected := range []string{
+		"schemaVersion: 1",
+		"name: TestGraph",
+		"name: SubGraph",
+		"type: " + mockItemType,
+		"name: A",
+		"name: B",
+		"attrs of A",
+		"attrs of B",
+	} {
+		if !strings.Contains(yamlStr, expected) {
+			test.Errorf("exported YAML does not contain %q:\n%s",
+				expected, yamlStr)
+		}
+	}
+}
+
+func TestExportImportRoundTrip(test *testing.T) {
+	graph := exportTestGraph()
+	exporter := &dg.GraphExporter{}
+	yamlStr, err := exporter.ExportToYAML(graph)
+	if err != nil {
+		test.Fatalf("ExportToYAML failed: %v", err)
+	}
+	importer := &dg.GraphImporter{}
+	importer.RegisterItemDecoder(mockItemType,
+		func(attrs json.RawMessage) (dg.Item, error) {
+			var item depgraphtest.MockItem
+			err := json.Unmarshal(attrs, &item)
+			return item, err
+		})
+	imported, err := importer.ImportFromYAML([]byte(yamlStr))
+	if err != nil {
+		test.Fatalf("ImportFromYAML failed: %v", err)
+	}
+	if diff := graph.DiffItems(imported); len(diff) != 0 {
+		test.Errorf("imported graph differs from the original: %v", diff)
+	}
+	if imported.SubGraph("SubGraph") == nil {
+		test.Error("imported graph is missing the subgraph")
+	}
+}
+
+func TestImportWithoutDecoder(test *testing.T) {
+	graph := exportTestGraph()
+	exporter := &dg.GraphExporter{}
+	jsonStr, err := exporter.ExportToJSON(graph)
+	if err != nil {
+		test.Fatalf("ExportToJSON failed: %v", err)
+	}
+	importer := &dg.GraphImporter{}
+	if _, err = importer.ImportFromJSON([]byte(jsonStr)); err == nil {
+		test.Error("import with no registered decoder unexpectedly succeeded")
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package depgraphtest provides a reusable test harness for packages which
+// build their configuration management on top of the dependency graph
+// (see libs/depgraph and libs/reconciler). It exports a mock item, a pluggable
+// mock Configurator which records every executed operation, and Gomega-free
+// assertion helpers to verify the ordering of the recorded operations,
+// so that tests of reconciler-based packages do not have to duplicate
+// this scaffolding.
+package depgraphtest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+)
+
+// MockItem : a minimal item implementation to build test graphs from.
+type MockItem struct {
+	// ItemName : name of the item, unique within items of the same type.
+	ItemName string
+	// ItemType : type of the item. There should be a (mock) Configurator
+	// registered for every item type used by the test.
+	ItemType string
+	// Attrs : arbitrary item attributes. Two instances of the same item
+	// are considered equal only if their attributes match.
+	Attrs string
+	// IsExternal : true if the item is created outside of the reconciler.
+	IsExternal bool
+	// Deps : dependencies of the item.
+	Deps []dg.Dependency
+}
+
+// Name of the item.
+func (m MockItem) Name() string {
+	return m.ItemName
+}
+
+// Label of the item.
+func (m MockItem) Label() string {
+	return m.ItemName
+}
+
+// Type of the item.
+func (m MockItem) Type() string {
+	return m.ItemType
+}
+
+// Equal compares item attributes.
+// Dependencies are intentionally not compared - they may contain
+// non-comparable MustSatisfy callbacks.
+func (m MockItem) Equal(other dg.Item) bool {
+	m2, isMockItem := other.(MockItem)
+	if !isMockItem {
+		return false
+	}
+	return m.Attrs == m2.Attrs && m.IsExternal == m2.IsExternal
+}
+
+// External returns MockItem.IsExternal.
+func (m MockItem) External() bool {
+	return m.IsExternal
+}
+
+// String describes the item.
+func (m MockItem) String() string {
+	return fmt.Sprintf("mock item %s/%s with attributes: %s",
+		m.ItemType, m.ItemName, m.Attrs)
+}
+
+// Dependencies returns MockItem.Deps.
+func (m MockItem) Dependencies() []dg.Dependency {
+	return m.Deps
+}
+
+// RecordedOp : a single operation executed by MockConfigurator.
+type RecordedOp struct {
+	// Item : reference to the item the operation was executed for.
+	Item dg.ItemRef
+	// Operation : executed operation (Create/Modify/Delete).
+	Operation reconciler.Operation
+	// Err : error returned by the operation (nil if it succeeded).
+	Err error
+}
+
+// String describes the recorded operation.
+func (o RecordedOp) String() string {
+	str := fmt.Sprintf("%v of %v", o.Operation, o.Item)
+	if o.Err != nil {
+		str += fmt.Sprintf(" (failed with: %v)", o.Err)
+	}
+	return str
+}
+
+// OpsRecorder : records operations executed by mock configurators in the order
+// of their execution. Safe for concurrent use.
+type OpsRecorder struct {
+	sync.Mutex
+	ops []RecordedOp
+}
+
+// NewOpsRecorder is a constructor for OpsRecorder.
+func NewOpsRecorder() *OpsRecorder {
+	return &OpsRecorder{}
+}
+
+func (r *OpsRecorder) record(op RecordedOp) {
+	r.Lock()
+	defer r.Unlock()
+	r.ops = append(r.ops, op)
+}
+
+// Clear removes all recorded operations.
+// Typically called before triggering the reconciliation under test.
+func (r *OpsRecorder) Clear() {
+	r.Lock()
+	defer r.Unlock()
+	r.ops = nil
+}
+
+// Ops returns a copy of all recorded operations, ordered by execution time.
+func (r *OpsRecorder) Ops() []RecordedOp {
+	r.Lock()
+	defer r.Unlock()
+	ops := make([]RecordedOp, len(r.ops))
+	copy(ops, r.ops)
+	return ops
+}
+
+// String : a multi-line log of all recorded operations.
+func (r *OpsRecorder) String() string {
+	r.Lock()
+	defer r.Unlock()
+	ops := make([]string, 0, len(r.ops))
+	for i, op := range r.ops {
+		ops = append(ops, fmt.Sprintf("%d. %v", i+1, op))
+	}
+	return strings.Join(ops, "\n")
+}
+
+// OpMatcher : matches recorded operations of the given type executed
+// for the given item. Used by the assertion methods of OpsRecorder.
+type OpMatcher struct {
+	operation reconciler.Operation
+	item      dg.ItemRef
+}
+
+// Created matches Create operation executed for the referenced item.
+func Created(item dg.ItemRef) OpMatcher {
+	return OpMatcher{operation: reconciler.OperationCreate, item: item}
+}
+
+// Modified matches Modify operation executed for the referenced item.
+func Modified(item dg.ItemRef) OpMatcher {
+	return OpMatcher{operation: reconciler.OperationModify, item: item}
+}
+
+// Deleted matches Delete operation executed for the referenced item.
+func Deleted(item dg.ItemRef) OpMatcher {
+	return OpMatcher{operation: reconciler.OperationDelete, item: item}
+}
+
+// String describes the matcher.
+func (m OpMatcher) String() string {
+	return fmt.Sprintf("%v of %v", m.operation, m.item)
+}
+
+func (m OpMatcher) matches(op RecordedOp) bool {
+	return op.Operation == m.operation && op.Item == m.item
+}
+
+// Executed returns true if an operation matched by the given matcher
+// was recorded.
+func (r *OpsRecorder) Executed(matcher OpMatcher) bool {
+	for _, op := range r.Ops() {
+		if matcher.matches(op) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyOrder checks that operations matched by the given matchers were all
+// executed and in the given order (not necessarily consecutively).
+// Returns nil if the expectation holds, otherwise an error describing
+// the first unsatisfied matcher together with the full operations log.
+func (r *OpsRecorder) VerifyOrder(matchers ...OpMatcher) error {
+	ops := r.Ops()
+	next := 0
+	for _, matcher := range matchers {
+		var matched bool
+		for ; next < len(ops); next++ {
+			if matcher.matches(ops[next]) {
+				matched = true
+				next++
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("operation <%v> was not executed in the expected "+
+				"order, executed operations:\n%v", matcher, r)
+		}
+	}
+	return nil
+}
+
+// VerifyNotExecuted checks that no operation matched by the given matcher
+// was recorded.
+func (r *OpsRecorder) VerifyNotExecuted(matcher OpMatcher) error {
+	if r.Executed(matcher) {
+		return fmt.Errorf("operation <%v> was executed, executed operations:\n%v",
+			matcher, r)
+	}
+	return nil
+}
+
+// MockConfigurator implements Configurator interface (libs/reconciler)
+// for mock items, recording every executed operation into the given
+// OpsRecorder. The behavior of individual operations can be customized
+// using the optional hooks, e.g. to inject errors.
+type MockConfigurator struct {
+	// ItemType : item type this configurator is registered for.
+	ItemType string
+	// Recorder : where the executed operations are recorded.
+	Recorder *OpsRecorder
+	// OnCreate : optional hook called by Create. Error returned by the hook
+	// is recorded and returned as the operation result.
+	OnCreate func(item dg.Item) error
+	// OnModify : optional hook called by Modify.
+	OnModify func(oldItem, newItem dg.Item) error
+	// OnDelete : optional hook called by Delete.
+	OnDelete func(item dg.Item) error
+	// WithoutModify : if enabled, NeedsRecreate returns true and changed
+	// items are re-created instead of being modified.
+	WithoutModify bool
+}
+
+// Create records and optionally simulates the Create operation.
+func (c *MockConfigurator) Create(_ context.Context, item dg.Item) error {
+	var err error
+	if c.OnCreate != nil {
+		err = c.OnCreate(item)
+	}
+	c.Recorder.record(RecordedOp{
+		Item:      dg.Reference(item),
+		Operation: reconciler.OperationCreate,
+		Err:       err,
+	})
+	return err
+}
+
+// Modify records and optionally simulates the Modify operation.
+func (c *MockConfigurator) Modify(_ context.Context, oldItem, newItem dg.Item) error {
+	var err error
+	if c.OnModify != nil {
+		err = c.OnModify(oldItem, newItem)
+	}
+	c.Recorder.record(RecordedOp{
+		Item:      dg.Reference(newItem),
+		Operation: reconciler.OperationModify,
+		Err:       err,
+	})
+	return err
+}
+
+// Delete records and optionally simulates the Delete operation.
+func (c *MockConfigurator) Delete(_ context.Context, item dg.Item) error {
+	var err error
+	if c.OnDelete != nil {
+		err = c.OnDelete(item)
+	}
+	c.Recorder.record(RecordedOp{
+		Item:      dg.Reference(item),
+		Operation: reconciler.OperationDelete,
+		Err:       err,
+	})
+	return err
+}
+
+// NeedsRecreate returns MockConfigurator.WithoutModify.
+func (c *MockConfigurator) NeedsRecreate(_, _ dg.Item) bool {
+	return c.WithoutModify
+}
+
+// RegisterMockItems registers MockConfigurator (recording into the given
+// OpsRecorder) for every listed mock item type.
+func RegisterMockItems(registry *reconciler.DefaultRegistry,
+	recorder *OpsRecorder, itemTypes ...string) error {
+	for _, itemType := range itemTypes {
+		err := registry.Register(
+			&MockConfigurator{ItemType: itemType, Recorder: recorder}, itemType)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package depgraphtest_test
+
+import (
+	"context"
+	"testing"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+	"github.com/lf-edge/eve/pkg/pillar/depgraphtest"
+)
+
+const mockItemType = "Mock-Item"
+
+func mockItemRef(name string) dg.ItemRef {
+	return dg.Reference(depgraphtest.MockItem{ItemName: name, ItemType: mockItemType})
+}
+
+func itemDep(name string) dg.Dependency {
+	return dg.Dependency{
+		RequiredItem: mockItemRef(name),
+		Description:  "dependency on mock item " + name,
+	}
+}
+
+func TestOperationOrdering(test *testing.T) {
+	recorder := depgraphtest.NewOpsRecorder()
+	registry := &reconciler.DefaultRegistry{}
+	if err := depgraphtest.RegisterMockItems(
+		registry, recorder, mockItemType); err != nil {
+		test.Fatalf("failed to register mock items: %v", err)
+	}
+	r := reconciler.New(registry)
+	itemA := depgraphtest.MockItem{ItemName: "A", ItemType: mockItemType}
+	itemB := depgraphtest.MockItem{
+		ItemName: "B",
+		ItemType: mockItemType,
+		Deps:     []dg.Dependency{itemDep("A")},
+	}
+	intent := dg.New(dg.InitArgs{Name: "TestGraph"})
+	intent.PutItem(itemA, nil)
+	intent.PutItem(itemB, nil)
+
+	// B depends on A, therefore A must be created first.
+	status := r.Reconcile(context.Background(), nil, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if err := recorder.VerifyOrder(
+		depgraphtest.Created(dg.Reference(itemA)),
+		depgraphtest.Created(dg.Reference(itemB)),
+	); err != nil {
+		test.Error(err)
+	}
+
+	// On removal the order is reversed - B must be deleted before A.
+	recorder.Clear()
+	intent.DelItem(dg.Reference(itemA))
+	intent.DelItem(dg.Reference(itemB))
+	status = r.Reconcile(context.Background(), status.NewCurrentState, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if err := recorder.VerifyOrder(
+		depgraphtest.Deleted(dg.Reference(itemB)),
+		depgraphtest.Deleted(dg.Reference(itemA)),
+	); err != nil {
+		test.Error(err)
+	}
+	if err := recorder.VerifyNotExecuted(
+		depgraphtest.Modified(dg.Reference(itemA))); err != nil {
+		test.Error(err)
+	}
+}
+
+func TestItemRecreate(test *testing.T) {
+	recorder := depgraphtest.NewOpsRecorder()
+	registry := &reconciler.DefaultRegistry{}
+	err := registry.Register(&depgraphtest.MockConfigurator{
+		ItemType:      mockItemType,
+		Recorder:      recorder,
+		WithoutModify: true,
+	}, mockItemType)
+	if err != nil {
+		test.Fatalf("failed to register mock configurator: %v", err)
+	}
+	r := reconciler.New(registry)
+	item := depgraphtest.MockItem{
+		ItemName: "A",
+		ItemType: mockItemType,
+		Attrs:    "attrs1",
+	}
+	intent := dg.New(dg.InitArgs{Name: "TestGraph"})
+	intent.PutItem(item, nil)
+	status := r.Reconcile(context.Background(), nil, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+
+	// With Modify not supported, changed item is deleted and re-created.
+	recorder.Clear()
+	item.Attrs = "attrs2"
+	intent.PutItem(item, nil)
+	status = r.Reconcile(context.Background(), status.NewCurrentState, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if err := recorder.VerifyOrder(
+		depgraphtest.Deleted(dg.Reference(item)),
+		depgraphtest.Created(dg.Reference(item)),
+	); err != nil {
+		test.Error(err)
+	}
+}
+
+func TestReconcileItems(test *testing.T) {
+	recorder := depgraphtest.NewOpsRecorder()
+	registry := &reconciler.DefaultRegistry{}
+	if err := depgraphtest.RegisterMockItems(
+		registry, recorder, mockItemType); err != nil {
+		test.Fatalf("failed to register mock items: %v", err)
+	}
+	r := reconciler.New(registry)
+	itemA := depgraphtest.MockItem{ItemName: "A", ItemType: mockItemType}
+	itemB := depgraphtest.MockItem{
+		ItemName: "B",
+		ItemType: mockItemType,
+		Deps:     []dg.Dependency{itemDep("A")},
+	}
+	itemC := depgraphtest.MockItem{ItemName: "C", ItemType: mockItemType}
+	intent := dg.New(dg.InitArgs{Name: "TestGraph"})
+	intent.PutItem(itemA, nil)
+	intent.PutItem(itemB, nil)
+	intent.PutItem(itemC, nil)
+	status := r.Reconcile(context.Background(), nil, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	current := status.NewCurrentState
+
+	// Remove A and change C in the intended state, but reconcile only A.
+	// B depends on A, so it is inside the dependency closure of A and gets
+	// removed first. C is unrelated and must be left untouched.
+	recorder.Clear()
+	intent.DelItem(dg.Reference(itemA))
+	itemC.Attrs = "attrs2"
+	intent.PutItem(itemC, nil)
+	status = r.ReconcileItems(context.Background(), current, intent,
+		[]dg.ItemRef{dg.Reference(itemA)})
+	if status.Err != nil {
+		test.Fatalf("ReconcileItems failed: %v", status.Err)
+	}
+	if err := recorder.VerifyOrder(
+		depgraphtest.Deleted(dg.Reference(itemB)),
+		depgraphtest.Deleted(dg.Reference(itemA)),
+	); err != nil {
+		test.Error(err)
+	}
+	if err := recorder.VerifyNotExecuted(
+		depgraphtest.Modified(dg.Reference(itemC))); err != nil {
+		test.Error(err)
+	}
+
+	// The full reconciliation handles the remaining out-of-sync item.
+	recorder.Clear()
+	status = r.Reconcile(context.Background(), current, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if !recorder.Executed(depgraphtest.Modified(dg.Reference(itemC))) {
+		test.Errorf("item C was not modified by the full reconciliation")
+	}
+}
+
+func TestExternalItemChange(test *testing.T) {
+	recorder := depgraphtest.NewOpsRecorder()
+	registry := &reconciler.DefaultRegistry{}
+	if err := depgraphtest.RegisterMockItems(
+		registry, recorder, mockItemType); err != nil {
+		test.Fatalf("failed to register mock items: %v", err)
+	}
+	r := reconciler.New(registry)
+	extItem := depgraphtest.MockItem{
+		ItemName:   "E",
+		ItemType:   mockItemType,
+		Attrs:      "ready",
+		IsExternal: true,
+	}
+	depItem := depgraphtest.MockItem{
+		ItemName: "D",
+		ItemType: mockItemType,
+		Deps: []dg.Dependency{{
+			RequiredItem: dg.Reference(extItem),
+			MustSatisfy: func(item dg.Item) bool {
+				return item.(depgraphtest.MockItem).Attrs == "ready"
+			},
+			Description: "external item E must be ready",
+		}},
+	}
+	// External items are published into the current state by their
+	// (external) manager, not created by the reconciler.
+	current := dg.New(dg.InitArgs{Name: "TestGraph"})
+	current.PutItem(extItem, nil)
+	intent := dg.New(dg.InitArgs{Name: "TestGraph"})
+	intent.PutItem(depItem, nil)
+	status := r.Reconcile(context.Background(), current, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if !recorder.Executed(depgraphtest.Created(dg.Reference(depItem))) {
+		test.Errorf("item D was not created")
+	}
+
+	// A reconciliation without any changes must be a no-op.
+	recorder.Clear()
+	status = r.Reconcile(context.Background(), current, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if ops := recorder.Ops(); len(ops) > 0 {
+		test.Errorf("unexpected operations:\n%v", recorder)
+	}
+
+	// When the external item stops satisfying the dependency,
+	// the dependent item is automatically deleted.
+	recorder.Clear()
+	extItem.Attrs = "not-ready"
+	current.PutItem(extItem, nil)
+	status = r.Reconcile(context.Background(), current, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if !recorder.Executed(depgraphtest.Deleted(dg.Reference(depItem))) {
+		test.Errorf("item D was not deleted")
+	}
+
+	// Once the external item is ready again, the pending dependent
+	// is re-created.
+	recorder.Clear()
+	extItem.Attrs = "ready"
+	current.PutItem(extItem, nil)
+	status = r.Reconcile(context.Background(), current, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	if !recorder.Executed(depgraphtest.Created(dg.Reference(depItem))) {
+		test.Errorf("item D was not re-created")
+	}
+}
+
+func TestReconcileCluster(test *testing.T) {
+	recorder := depgraphtest.NewOpsRecorder()
+	registry := &reconciler.DefaultRegistry{}
+	if err := depgraphtest.RegisterMockItems(
+		registry, recorder, mockItemType); err != nil {
+		test.Fatalf("failed to register mock items: %v", err)
+	}
+	r := reconciler.New(registry)
+	itemA := depgraphtest.MockItem{ItemName: "A", ItemType: mockItemType}
+	itemB := depgraphtest.MockItem{ItemName: "B", ItemType: mockItemType}
+	intent := dg.New(dg.InitArgs{Name: "TestGraph"})
+	subGraph1 := dg.New(dg.InitArgs{Name: "SG1"})
+	subGraph1.PutItem(itemA, nil)
+	intent.PutSubGraph(subGraph1)
+	subGraph2 := dg.New(dg.InitArgs{Name: "SG2"})
+	subGraph2.PutItem(itemB, nil)
+	intent.PutSubGraph(subGraph2)
+	status := r.Reconcile(context.Background(), nil, intent)
+	if status.Err != nil {
+		test.Fatalf("Reconcile failed: %v", status.Err)
+	}
+	current := status.NewCurrentState
+
+	// Change items in both subgraphs, but reconcile only the first one.
+	recorder.Clear()
+	itemA.Attrs = "attrs2"
+	subGraph1.PutItem(itemA, nil)
+	itemB.Attrs = "attrs2"
+	subGraph2.PutItem(itemB, nil)
+	status = r.ReconcileCluster(context.Background(), current, intent,
+		dg.NewSubGraphPath("SG1"))
+	if status.Err != nil {
+		test.Fatalf("ReconcileCluster failed: %v", status.Err)
+	}
+	if !recorder.Executed(depgraphtest.Modified(dg.Reference(itemA))) {
+		test.Errorf("item A was not modified by the cluster reconciliation")
+	}
+	if err := recorder.VerifyNotExecuted(
+		depgraphtest.Modified(dg.Reference(itemB))); err != nil {
+		test.Error(err)
+	}
+
+	// Reconciliation of a newly added cluster creates the subgraph
+	// of the current state on the fly.
+	recorder.Clear()
+	itemC := depgraphtest.MockItem{ItemName: "C", ItemType: mockItemType}
+	subGraph3 := dg.New(dg.InitArgs{Name: "SG3"})
+	subGraph3.PutItem(itemC, nil)
+	intent.PutSubGraph(subGraph3)
+	status = r.ReconcileCluster(context.Background(), current, intent,
+		dg.NewSubGraphPath("SG3"))
+	if status.Err != nil {
+		test.Fatalf("ReconcileCluster failed: %v", status.Err)
+	}
+	if !recorder.Executed(depgraphtest.Created(dg.Reference(itemC))) {
+		test.Errorf("item C was not created by the cluster reconciliation")
+	}
+	if current.SubGraph("SG3") == nil {
+		test.Errorf("subgraph SG3 was not added into the current state")
+	}
+}
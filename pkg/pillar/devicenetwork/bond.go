@@ -0,0 +1,125 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package devicenetwork
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/vishvananda/netlink"
+)
+
+const bondProcDir = "/proc/net/bonding"
+
+// GetBondStatus collects the operational state of a bond interface
+// (mode, active slave, LACP aggregator, per-slave link state) from netlink
+// and /proc/net/bonding.
+func GetBondStatus(log *base.LogObject, ifName string) (types.BondStatus, error) {
+	var status types.BondStatus
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return status, fmt.Errorf("failed to get link for bond %s: %w", ifName, err)
+	}
+	bond, isBond := link.(*netlink.Bond)
+	if !isBond {
+		return status, fmt.Errorf("interface %s is not a bond", ifName)
+	}
+	status.Mode = bondModeFromNetlink(bond.Mode)
+	if bond.ActiveSlave > 0 {
+		slaveLink, err := netlink.LinkByIndex(bond.ActiveSlave)
+		if err == nil {
+			status.ActiveSlave = slaveLink.Attrs().Name
+		} else {
+			log.Warnf("GetBondStatus: failed to get link for active slave "+
+				"(index %d) of bond %s: %v", bond.ActiveSlave, ifName, err)
+		}
+	}
+	err = parseBondProcFile(ifName, &status)
+	if err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// parseBondProcFile parses /proc/net/bonding/<ifName> to obtain the active
+// 802.3ad aggregator ID and the operational state of every slave.
+func parseBondProcFile(ifName string, status *types.BondStatus) error {
+	procFile, err := os.Open(filepath.Join(bondProcDir, ifName))
+	if err != nil {
+		return fmt.Errorf("failed to read bonding state of %s: %w", ifName, err)
+	}
+	defer procFile.Close()
+	var slave *types.BondSlaveStatus
+	scanner := bufio.NewScanner(procFile)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(fields[0])
+		value := strings.TrimSpace(fields[1])
+		switch key {
+		case "Slave Interface":
+			if slave != nil {
+				status.Slaves = append(status.Slaves, *slave)
+			}
+			slave = &types.BondSlaveStatus{IfName: value}
+		case "MII Status":
+			if slave != nil {
+				slave.MiiUp = value == "up"
+			}
+		case "Link Failure Count":
+			if slave != nil {
+				count, err := strconv.Atoi(value)
+				if err == nil {
+					slave.LinkFailureCount = uint32(count)
+				}
+			}
+		case "Aggregator ID":
+			aggrID, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			if slave != nil {
+				slave.AggregatorID = uint16(aggrID)
+			} else {
+				// Bond-level info (listed before slave sections) contains
+				// the ID of the active aggregator.
+				status.AggregatorID = uint16(aggrID)
+			}
+		}
+	}
+	if slave != nil {
+		status.Slaves = append(status.Slaves, *slave)
+	}
+	return scanner.Err()
+}
+
+// bondModeFromNetlink converts bond mode from the netlink representation.
+func bondModeFromNetlink(mode netlink.BondMode) types.BondMode {
+	switch mode {
+	case netlink.BOND_MODE_BALANCE_RR:
+		return types.BondModeBalanceRR
+	case netlink.BOND_MODE_ACTIVE_BACKUP:
+		return types.BondModeActiveBackup
+	case netlink.BOND_MODE_BALANCE_XOR:
+		return types.BondModeBalanceXOR
+	case netlink.BOND_MODE_BROADCAST:
+		return types.BondModeBroadcast
+	case netlink.BOND_MODE_802_3AD:
+		return types.BondMode802Dot3AD
+	case netlink.BOND_MODE_BALANCE_TLB:
+		return types.BondModeBalanceTLB
+	case netlink.BOND_MODE_BALANCE_ALB:
+		return types.BondModeBalanceALB
+	}
+	return types.BondModeUnspecified
+}
@@ -28,4 +28,12 @@ const (
 	PbrNatOutPrio = 10000
 	// PbrNatInPrio : IP rule priority for external packets coming in towards apps
 	PbrNatInPrio = 11000
+
+	// IPv6DefRouteBaseMetric : base metric for explicitly managed IPv6 default
+	// routes installed into the main routing table. The port cost is added
+	// to the base so that default routes of lower-cost ports are preferred.
+	// The base is well below the metric used by the kernel for RA-learned
+	// routes (1024), hence the explicit routes always take precedence over
+	// the kernel's own RA route selection.
+	IPv6DefRouteBaseMetric = 100
 )
@@ -3,6 +3,15 @@
 
 package devicenetwork
 
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
 const (
 	// WpaFilename : path to WiFi wpa_supplicant file.
 	WpaFilename = "/run/wlan/wpa_supplicant.conf"
@@ -10,4 +19,57 @@ const (
 	RunWlanDir = "/run/wlan"
 	// WpaTempname : name used for a temporary wpa_supplicant file.
 	WpaTempname = "wpa_supplicant.temp"
+	// WpaCtrlDir : directory with wpa_supplicant control sockets
+	// (one per WLAN interface).
+	WpaCtrlDir = RunWlanDir
+	// wpaCtrlTimeout : timeout for a single request sent to the wpa_supplicant
+	// control socket.
+	wpaCtrlTimeout = 2 * time.Second
 )
+
+// GetConnectedSSID queries the wpa_supplicant control socket of the given
+// WLAN interface and returns the SSID of the currently associated WiFi
+// network. An empty string is returned if the adapter is not (yet) associated.
+func GetConnectedSSID(ifName string) (string, error) {
+	ctrlSock := filepath.Join(WpaCtrlDir, ifName)
+	// wpa_supplicant replies to the address from which the request was sent,
+	// hence the local socket must be bound to a filesystem path.
+	localSock := filepath.Join(os.TempDir(),
+		fmt.Sprintf("wpa-ctrl-%s-%d", ifName, os.Getpid()))
+	defer os.Remove(localSock)
+	laddr := net.UnixAddr{Name: localSock, Net: "unixgram"}
+	raddr := net.UnixAddr{Name: ctrlSock, Net: "unixgram"}
+	conn, err := net.DialUnix("unixgram", &laddr, &raddr)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to wpa_supplicant control "+
+			"socket %s: %w", ctrlSock, err)
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(wpaCtrlTimeout)); err != nil {
+		return "", err
+	}
+	if _, err = conn.Write([]byte("STATUS")); err != nil {
+		return "", fmt.Errorf("failed to send STATUS request to %s: %w",
+			ctrlSock, err)
+	}
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to read STATUS reply from %s: %w",
+			ctrlSock, err)
+	}
+	var ssid string
+	var associated bool
+	for _, line := range strings.Split(string(buf[:n]), "\n") {
+		if value, found := strings.CutPrefix(line, "ssid="); found {
+			ssid = value
+		}
+		if value, found := strings.CutPrefix(line, "wpa_state="); found {
+			associated = value == "COMPLETED"
+		}
+	}
+	if !associated {
+		return "", nil
+	}
+	return ssid, nil
+}
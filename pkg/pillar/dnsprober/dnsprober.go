@@ -0,0 +1,289 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dnsprober is used by zedrouter to actively check the health
+// of upstream DNS resolvers used by network instances. dnsmasq forwards
+// application queries to the resolvers in the configured order - with
+// a dead server listed first, every query suffers seconds of extra
+// latency before dnsmasq retries with the next server. The prober allows
+// zedrouter to reorder the resolvers by their measured health/latency
+// and to remove dead ones from the dnsmasq configuration.
+package dnsprober
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+// DNSProber periodically probes a set of tracked DNS servers by running
+// a simple query against each of them and measuring the response latency.
+// Whenever the health status of any server changes, watchers are notified
+// so that the dnsmasq configuration can be updated.
+type DNSProber struct {
+	sync.Mutex
+	log    *base.LogObject
+	config Config
+
+	servers    map[string]*serverStatus // key: server IP as string
+	watcherChs []chan []ServerStatus
+
+	probeTicker *time.Ticker
+}
+
+// Config : configuration for DNS prober.
+// Currently, this is not configurable via controller.
+type Config struct {
+	// ProbeInterval : how often to probe every tracked DNS server.
+	ProbeInterval time.Duration
+	// ProbeTimeout : timeout for a single DNS query.
+	ProbeTimeout time.Duration
+	// ProbeHostname : hostname to resolve when probing.
+	ProbeHostname string
+	// MaxContFailCnt : maximum number of continuous failures that is allowed
+	// to happen before the DNS server is declared as unreachable.
+	MaxContFailCnt uint8
+	// MaxContSuccessCnt : maximum number of continuous successes that is
+	// allowed to happen before the DNS server is declared as reachable.
+	MaxContSuccessCnt uint8
+}
+
+// DefaultConfig : default configuration for DNSProber.
+// Since these options are currently not configurable via controller,
+// non-default config is used only in unit tests.
+func DefaultConfig() Config {
+	return Config{
+		ProbeInterval:     30 * time.Second,
+		ProbeTimeout:      3 * time.Second,
+		ProbeHostname:     "www.google.com",
+		MaxContFailCnt:    3,
+		MaxContSuccessCnt: 2,
+	}
+}
+
+// ServerStatus : health status of a single tracked DNS server.
+type ServerStatus struct {
+	// Server : IP address of the DNS server.
+	Server net.IP
+	// Reachable : true if the server responds to queries.
+	// Note that a newly tracked server is optimistically considered
+	// reachable until the first probes prove otherwise.
+	Reachable bool
+	// AvgLatency : average latency of recent successful probes.
+	// Zero if the server never responded.
+	AvgLatency time.Duration
+	// LastProbe : time when the server was last probed.
+	LastProbe time.Time
+}
+
+type serverStatus struct {
+	ServerStatus
+	contFailCnt    uint8
+	contSuccessCnt uint8
+}
+
+// NewDNSProber is a constructor for DNSProber.
+// The prober starts probing as soon as it is given some servers to track
+// (see TrackServers).
+func NewDNSProber(log *base.LogObject, config Config) *DNSProber {
+	p := &DNSProber{
+		log:         log,
+		config:      config,
+		servers:     make(map[string]*serverStatus),
+		probeTicker: time.NewTicker(config.ProbeInterval),
+	}
+	go p.runProbing()
+	return p
+}
+
+// TrackServers : update the set of DNS servers to probe.
+// Servers no longer present in the given list stop being tracked.
+func (p *DNSProber) TrackServers(servers []net.IP) {
+	p.Lock()
+	defer p.Unlock()
+	tracked := make(map[string]struct{})
+	for _, server := range servers {
+		key := server.String()
+		tracked[key] = struct{}{}
+		if _, haveIt := p.servers[key]; !haveIt {
+			p.servers[key] = &serverStatus{
+				ServerStatus: ServerStatus{
+					Server: server,
+					// Optimistically assume reachability until probes
+					// prove otherwise.
+					Reachable: true,
+				},
+			}
+		}
+	}
+	for key := range p.servers {
+		if _, isTracked := tracked[key]; !isTracked {
+			delete(p.servers, key)
+		}
+	}
+}
+
+// WatchHealthUpdates returns channel where the prober publishes the status
+// of all tracked servers whenever the reachability of any of them changes.
+func (p *DNSProber) WatchHealthUpdates() <-chan []ServerStatus {
+	p.Lock()
+	defer p.Unlock()
+	watcherCh := make(chan []ServerStatus, 1)
+	p.watcherChs = append(p.watcherChs, watcherCh)
+	return watcherCh
+}
+
+// GetServerStatus returns the health status of the given tracked servers.
+// Servers which are not tracked are returned as reachable with zero latency.
+func (p *DNSProber) GetServerStatus(servers []net.IP) []ServerStatus {
+	p.Lock()
+	defer p.Unlock()
+	statuses := make([]ServerStatus, 0, len(servers))
+	for _, server := range servers {
+		if status, haveIt := p.servers[server.String()]; haveIt {
+			statuses = append(statuses, status.ServerStatus)
+		} else {
+			statuses = append(statuses, ServerStatus{
+				Server: server, Reachable: true})
+		}
+	}
+	return statuses
+}
+
+// SortByHealth reorders the given DNS servers so that reachable servers
+// with the lowest latency come first and removes servers declared as
+// unreachable. If all servers are unreachable, the original list is
+// returned unchanged - no servers would be worse than dead servers.
+func (p *DNSProber) SortByHealth(servers []net.IP) []net.IP {
+	p.Lock()
+	defer p.Unlock()
+	var healthy []net.IP
+	var latencies []time.Duration
+	for _, server := range servers {
+		status, haveIt := p.servers[server.String()]
+		if haveIt && !status.Reachable {
+			continue
+		}
+		var latency time.Duration
+		if haveIt {
+			latency = status.AvgLatency
+		}
+		// Insertion sort by latency, preserving the original order
+		// between servers with equal latency.
+		index := len(healthy)
+		for i := range healthy {
+			if latency < latencies[i] {
+				index = i
+				break
+			}
+		}
+		healthy = append(healthy[:index],
+			append([]net.IP{server}, healthy[index:]...)...)
+		latencies = append(latencies[:index],
+			append([]time.Duration{latency}, latencies[index:]...)...)
+	}
+	if len(healthy) == 0 {
+		return servers
+	}
+	return healthy
+}
+
+func (p *DNSProber) runProbing() {
+	for range p.probeTicker.C {
+		if p.probeServers() {
+			p.notifyWatchers()
+		}
+	}
+}
+
+// probeServers probes all tracked servers and returns true if the
+// reachability status of any of them changed.
+func (p *DNSProber) probeServers() (changed bool) {
+	p.Lock()
+	servers := make([]*serverStatus, 0, len(p.servers))
+	for _, server := range p.servers {
+		servers = append(servers, server)
+	}
+	p.Unlock()
+	for _, server := range servers {
+		latency, err := p.probe(server.Server)
+		p.Lock()
+		server.LastProbe = time.Now()
+		if err == nil {
+			server.contFailCnt = 0
+			if server.AvgLatency == 0 {
+				server.AvgLatency = latency
+			} else {
+				// Exponentially weighted moving average.
+				server.AvgLatency = (3*server.AvgLatency + latency) / 4
+			}
+			if !server.Reachable {
+				server.contSuccessCnt++
+				if server.contSuccessCnt > p.config.MaxContSuccessCnt {
+					p.log.Noticef("DNSProber: server %v is reachable again "+
+						"(latency %v)", server.Server, latency)
+					server.Reachable = true
+					server.contSuccessCnt = 0
+					changed = true
+				}
+			}
+		} else {
+			server.contSuccessCnt = 0
+			if server.Reachable {
+				server.contFailCnt++
+				if server.contFailCnt > p.config.MaxContFailCnt {
+					p.log.Warnf("DNSProber: server %v is unreachable: %v",
+						server.Server, err)
+					server.Reachable = false
+					server.contFailCnt = 0
+					changed = true
+				}
+			}
+		}
+		p.Unlock()
+	}
+	return changed
+}
+
+// probe runs a single DNS query against the given server and measures
+// how long it took to get the response.
+func (p *DNSProber) probe(server net.IP) (latency time.Duration, err error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: p.config.ProbeTimeout}
+			return dialer.DialContext(ctx, network,
+				net.JoinHostPort(server.String(), "53"))
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.config.ProbeTimeout)
+	defer cancel()
+	startTime := time.Now()
+	_, err = resolver.LookupHost(ctx, p.config.ProbeHostname)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve %s via %v: %w",
+			p.config.ProbeHostname, server, err)
+	}
+	return time.Since(startTime), nil
+}
+
+func (p *DNSProber) notifyWatchers() {
+	p.Lock()
+	defer p.Unlock()
+	statuses := make([]ServerStatus, 0, len(p.servers))
+	for _, server := range p.servers {
+		statuses = append(statuses, server.ServerStatus)
+	}
+	for _, watcherCh := range p.watcherChs {
+		select {
+		case watcherCh <- statuses:
+		default:
+			// Watcher is lagging behind, drop the update - it will learn
+			// the current state from the next one.
+		}
+	}
+}
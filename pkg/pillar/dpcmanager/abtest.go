@@ -0,0 +1,269 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/conntester"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// On devices with multiple uplinks, switching to a newly received DPC all
+// at once risks losing the controller connectivity on all ports at the same
+// time should the new configuration turn out to be broken. When A/B testing
+// is enabled (config item NetworkABTestingEnable), a newly received DPC is
+// instead first applied only to the secondary uplinks, while the primary
+// uplink (the lowest-cost working management port) continues running the
+// current, known-to-work configuration. Only after the candidate proves
+// to provide working controller connectivity on the tested ports does the
+// full cutover (standard verification of the whole DPC) take place.
+// If the candidate fails the test, it is recorded as failed in the published
+// DPC list and the tested ports are reverted to the current configuration.
+// The key of the candidate under test is published as part of
+// DeviceNetworkStatus.
+
+// startABTestIfApplicable starts A/B testing of the newly received DPC
+// if it is enabled and the device state allows it (the current DPC is working
+// and there is at least one secondary uplink to test the candidate on).
+// Returns true if the A/B test was started and the normal DPC verification
+// should therefore not (yet) be triggered for this DPC.
+func (m *DpcManager) startABTestIfApplicable(
+	ctx context.Context, dpc types.DevicePortConfig) bool {
+	if !m.abTestEnabled || m.dpcTestDuration == 0 {
+		return false
+	}
+	if m.abTestDpc != nil {
+		// At most one candidate is tested at a time - a newly received DPC
+		// always supersedes the one currently under test.
+		m.abortABTest(ctx, "superseded by a newer DPC")
+	}
+	if m.dpcVerify.inProgress {
+		return false
+	}
+	curDPC := m.currentDPC()
+	if curDPC == nil || curDPC.State != types.DPCStateSuccess {
+		// Without working connectivity there is nothing to protect - let the
+		// standard verification handle the new DPC.
+		return false
+	}
+	if !dpc.TimePriority.After(curDPC.TimePriority) {
+		// An older DPC would not become the current one anyway.
+		return false
+	}
+	testPorts := m.selectABTestPorts(curDPC, &dpc)
+	if len(testPorts) == 0 {
+		return false
+	}
+	hybrid := makeABHybridDPC(curDPC, &dpc, testPorts)
+	m.abTestDpc = &dpc
+	m.abTestHybrid = &hybrid
+	m.abTestPorts = testPorts
+	m.abTestSince = time.Now()
+	m.Log.Noticef("Starting A/B test of DPC (%s/%v) on ports %v",
+		dpc.Key, dpc.TimePriority, testPorts)
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	m.abTestTimer = time.NewTimer(m.dpcTestDuration)
+	m.updateDNS()
+	return true
+}
+
+// selectABTestPorts returns logical labels of the candidate's management
+// ports on which the new configuration will be tested. The primary uplink
+// (the lowest-cost working management port of the current DPC) is excluded
+// so that it keeps running the current configuration.
+func (m *DpcManager) selectABTestPorts(
+	curDPC, candidate *types.DevicePortConfig) (testPorts []string) {
+	var primary *types.NetworkPortConfig
+	for i := range curDPC.Ports {
+		port := &curDPC.Ports[i]
+		if !port.IsMgmt || port.IfName == "" {
+			continue
+		}
+		if port.LastSucceeded.IsZero() || port.LastFailed.After(port.LastSucceeded) {
+			continue
+		}
+		if primary == nil || port.Cost < primary.Cost {
+			primary = port
+		}
+	}
+	if primary == nil {
+		return nil
+	}
+	for i := range candidate.Ports {
+		port := &candidate.Ports[i]
+		if !port.IsMgmt || port.Logicallabel == primary.Logicallabel {
+			continue
+		}
+		testPorts = append(testPorts, port.Logicallabel)
+	}
+	return testPorts
+}
+
+// makeABHybridDPC combines the current DPC with the candidate's configuration
+// of the tested ports. This is what is actually applied into the network stack
+// for the duration of the A/B test.
+func makeABHybridDPC(curDPC, candidate *types.DevicePortConfig,
+	testPorts []string) types.DevicePortConfig {
+	hybrid := *curDPC
+	hybrid.Ports = make([]types.NetworkPortConfig, len(curDPC.Ports))
+	copy(hybrid.Ports, curDPC.Ports)
+	for _, label := range testPorts {
+		candPort := candidate.LookupPortByLogicallabel(label)
+		if candPort == nil {
+			continue
+		}
+		var replaced bool
+		for i := range hybrid.Ports {
+			if hybrid.Ports[i].Logicallabel == label {
+				hybrid.Ports[i] = *candPort
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			// Port newly added by the candidate.
+			hybrid.Ports = append(hybrid.Ports, *candPort)
+		}
+	}
+	return hybrid
+}
+
+// runABTest checks the controller connectivity over the tested ports.
+// Called whenever abTestTimer fires.
+func (m *DpcManager) runABTest(ctx context.Context) {
+	if m.abTestDpc == nil {
+		return
+	}
+	// Re-run reconciliation first - some configuration items may have
+	// dependencies that have become satisfied in the meantime.
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	testDNS := m.abTestDNS()
+	var err error
+	if len(testDNS.Ports) > 0 {
+		var intfStatusMap types.IntfStatusMap
+		intfStatusMap, _, err = m.ConnTester.TestConnectivity(ctx, testDNS, false)
+		m.abTestDpc.UpdatePortStatusFromIntfStatusMap(intfStatusMap)
+	} else {
+		err = fmt.Errorf("no usable port to test the candidate DPC on")
+	}
+	if err == nil {
+		m.finishABTest(ctx)
+		return
+	}
+	if _, rtf := err.(*conntester.RemoteTemporaryFailure); rtf {
+		// The tested ports were able to reach the controller - the failure
+		// is on the remote side and would affect the current DPC just as well.
+		m.finishABTest(ctx)
+		return
+	}
+	elapsed := time.Since(m.abTestSince)
+	if elapsed < waitForIPDNSRetries*m.dpcTestDuration {
+		m.Log.Noticef("A/B test of DPC (%s/%v) has not succeeded yet (%v); "+
+			"will retry", m.abTestDpc.Key, m.abTestDpc.TimePriority, err)
+		m.abTestTimer = time.NewTimer(m.dpcTestDuration)
+		return
+	}
+	m.failABTest(ctx, err)
+}
+
+// abTestDNS builds the network status restricted to the tested ports,
+// used as the input for the connectivity test of the candidate DPC.
+func (m *DpcManager) abTestDNS() types.DeviceNetworkStatus {
+	dns := types.DeviceNetworkStatus{
+		DPCKey:  m.abTestDpc.Key,
+		Version: m.abTestDpc.Version,
+	}
+	for _, label := range m.abTestPorts {
+		port := m.abTestDpc.LookupPortByLogicallabel(label)
+		if port == nil || port.IfName == "" {
+			continue
+		}
+		portStatus := types.NetworkPortStatus{
+			IfName:       port.IfName,
+			Phylabel:     port.Phylabel,
+			Logicallabel: port.Logicallabel,
+			IsMgmt:       port.IsMgmt,
+			IsL3Port:     port.IsL3Port,
+			Cost:         port.Cost,
+			ProxyConfig:  port.ProxyConfig,
+			Dhcp:         port.Dhcp,
+			Type:         port.Type,
+			DomainName:   port.DomainName,
+			DNSServers:   port.DNSServers,
+			NtpServer:    port.NTPServer,
+		}
+		ifindex, exists, err := m.NetworkMonitor.GetInterfaceIndex(port.IfName)
+		if !exists || err != nil {
+			continue
+		}
+		ipAddrs, macAddr, err := m.NetworkMonitor.GetInterfaceAddrs(ifindex)
+		if err == nil {
+			portStatus.AddrInfoList = make([]types.AddrInfo, len(ipAddrs))
+			for i, addr := range ipAddrs {
+				portStatus.AddrInfoList[i].Addr = addr.IP
+			}
+		}
+		portStatus.MacAddr = macAddr
+		if err := m.getDHCPInfo(&portStatus); err != nil {
+			m.Log.Warnf("abTestDNS: %v", err)
+		}
+		if err := m.getDNSInfo(&portStatus); err != nil {
+			m.Log.Warnf("abTestDNS: %v", err)
+		}
+		dns.Ports = append(dns.Ports, portStatus)
+	}
+	return dns
+}
+
+// finishABTest is called when the candidate DPC passed the connectivity test
+// on the secondary uplinks. The candidate is added into the DPC list and the
+// standard verification takes over to complete the cutover.
+func (m *DpcManager) finishABTest(ctx context.Context) {
+	dpc := *m.abTestDpc
+	m.Log.Noticef("A/B test of DPC (%s/%v) succeeded on ports %v; "+
+		"proceeding with the full cutover", dpc.Key, dpc.TimePriority, m.abTestPorts)
+	m.clearABTestState()
+	m.updateDPCListAndPublish(dpc, false)
+	m.dpcVerify.inProgress = false
+	m.restartVerify(ctx,
+		fmt.Sprintf("A/B tested DPC (%s/%v)", dpc.Key, dpc.TimePriority))
+}
+
+// failABTest is called when the candidate DPC repeatedly failed the
+// connectivity test on the secondary uplinks. The candidate is recorded
+// as failed in the published DPC list and the tested ports are reverted
+// back to the current configuration.
+func (m *DpcManager) failABTest(ctx context.Context, testErr error) {
+	dpc := *m.abTestDpc
+	m.Log.Errorf("A/B test of DPC (%s/%v) failed: %v; keeping the current DPC",
+		dpc.Key, dpc.TimePriority, testErr)
+	dpc.RecordFailure(testErr.Error())
+	m.clearABTestState()
+	m.updateDPCListAndPublish(dpc, false)
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	m.updateDNS()
+}
+
+// abortABTest reverts the tested ports back to the current configuration
+// without recording any verdict for the candidate.
+// Called when the candidate is superseded or removed, when A/B testing
+// is disabled by configuration, or when the full DPC verification restarts.
+func (m *DpcManager) abortABTest(ctx context.Context, reason string) {
+	m.Log.Noticef("Aborting A/B test of DPC (%s/%v): %s",
+		m.abTestDpc.Key, m.abTestDpc.TimePriority, reason)
+	m.clearABTestState()
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	m.updateDNS()
+}
+
+func (m *DpcManager) clearABTestState() {
+	m.abTestDpc = nil
+	m.abTestHybrid = nil
+	m.abTestPorts = nil
+	m.abTestSince = time.Time{}
+	m.abTestTimer = &time.Timer{}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
+)
+
+// Asymmetric routing occurs when a reply leaves the device through a different
+// port than the one where the request arrived. This typically happens when
+// multiple uplinks share a subnet or when routes installed for different ports
+// conflict with each other - the reply then follows whichever route the main
+// routing table prefers, the source address no longer matches the egress
+// interface and the controller connectivity flaps.
+// DpcManager periodically inspects the applied port configuration for such
+// risks (overlapping port subnets, conflicting routes, failing bound-socket
+// probes). Whenever a risk is newly detected, it triggers reconciliation
+// to make sure that the corrective source-based IP rules are installed
+// (see dpcreconciler and linuxitems.SrcIPRule) and records an explanatory
+// entry in the port status.
+
+// checkAsymRouting re-evaluates the risk of asymmetric routing for every
+// L3 port and self-heals by re-running the reconciliation when the risk
+// assessment changes.
+func (m *DpcManager) checkAsymRouting(ctx context.Context) {
+	changed := false
+	for i := range m.deviceNetStatus.Ports {
+		port := &m.deviceNetStatus.Ports[i]
+		if !port.IsL3Port || port.IfName == "" {
+			continue
+		}
+		warning := m.detectAsymRouting(port)
+		if m.asymRouteWarning[port.IfName] != warning {
+			if warning != "" {
+				m.Log.Warnf("checkAsymRouting: port %s: %s",
+					port.Logicallabel, warning)
+			} else {
+				m.Log.Noticef("checkAsymRouting: port %s: risk of asymmetric "+
+					"routing is gone", port.Logicallabel)
+			}
+			m.asymRouteWarning[port.IfName] = warning
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	// Reconciliation (re)installs the source-based IP rules which pin
+	// locally-originated traffic to the routing table of the port owning
+	// the source address, making replies leave through the right interface.
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	m.updateDNS()
+}
+
+// detectAsymRouting returns a description of the detected risk of asymmetric
+// routing on the given port, or an empty string if there is none.
+func (m *DpcManager) detectAsymRouting(port *types.NetworkPortStatus) string {
+	var problems []string
+	// 1. Check for another port with an overlapping subnet.
+	for i := range m.deviceNetStatus.Ports {
+		port2 := &m.deviceNetStatus.Ports[i]
+		if port2.IfName == port.IfName || !port2.IsL3Port {
+			continue
+		}
+		if port.Subnet.IP != nil && port2.Subnet.IP != nil &&
+			netutils.OverlappingSubnets(&port.Subnet, &port2.Subnet) {
+			problems = append(problems, fmt.Sprintf(
+				"subnet %s overlaps with port %s",
+				port.Subnet.String(), port2.Logicallabel))
+		}
+	}
+	// 2. Check for a route of another port conflicting with the port subnet
+	// inside the main routing table.
+	if conflict := m.findConflictingRoute(port); conflict != "" {
+		problems = append(problems, conflict)
+	}
+	// 3. Probe the reply path with bound sockets - connecting a UDP socket
+	// bound to the port IP address does not send any packets, but forces
+	// the kernel to make the same routing decision as for a reply sent
+	// from this address.
+	for _, addrInfo := range port.AddrInfoList {
+		for _, router := range port.DefaultRouters {
+			if !netutils.SameIPVersions(addrInfo.Addr, router) {
+				continue
+			}
+			if err := probeReplyPath(addrInfo.Addr, router); err != nil {
+				problems = append(problems, fmt.Sprintf(
+					"bound-socket probe from %s failed: %v",
+					addrInfo.Addr, err))
+			}
+		}
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+	return "risk of asymmetric routing: " + strings.Join(problems, ", ") +
+		"; corrective source-based IP rules are installed"
+}
+
+// findConflictingRoute looks for a route in the main routing table which
+// covers the subnet of the given port but leads through another interface.
+func (m *DpcManager) findConflictingRoute(port *types.NetworkPortStatus) string {
+	if port.Subnet.IP == nil {
+		return ""
+	}
+	ifIndex, found, err := m.NetworkMonitor.GetInterfaceIndex(port.IfName)
+	if err != nil || !found {
+		return ""
+	}
+	routes, err := m.NetworkMonitor.ListRoutes(netmonitor.RouteFilters{
+		FilterByTable: true,
+		Table:         syscall.RT_TABLE_MAIN,
+	})
+	if err != nil {
+		m.Log.Warnf("findConflictingRoute: ListRoutes failed: %v", err)
+		return ""
+	}
+	for _, route := range routes {
+		if route.IfIndex == ifIndex || route.Dst == nil {
+			continue
+		}
+		if netutils.OverlappingSubnets(route.Dst, &port.Subnet) {
+			attrs, err := m.NetworkMonitor.GetInterfaceAttrs(route.IfIndex)
+			ifName := "<unknown>"
+			if err == nil {
+				ifName = attrs.IfName
+			}
+			return fmt.Sprintf("route %s via interface %s conflicts with "+
+				"the port subnet %s", route.Dst.String(), ifName,
+				port.Subnet.String())
+		}
+	}
+	return ""
+}
+
+// probeReplyPath checks that the kernel is able to route a packet sent
+// from the given source IP address towards the default router.
+func probeReplyPath(srcIP, router net.IP) error {
+	conn, err := net.DialUDP("udp",
+		&net.UDPAddr{IP: srcIP},
+		&net.UDPAddr{IP: router, Port: 53})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
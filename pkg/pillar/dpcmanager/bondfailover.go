@@ -0,0 +1,60 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// Detection of bond (active-backup mode) failovers.
+// When the kernel switches a bond to a different active slave, the path
+// that the device uses to reach the controller changes and the connectivity
+// state previously verified for the DPC is no longer trustworthy (e.g. the
+// backup link may lead through a different uplink with a proxy or captive
+// portal in the way). The active slave reported by the kernel is compared
+// against the last published value whenever the device network status is
+// being refreshed, and a flip triggers re-verification of the current DPC.
+
+// detectBondFailover compares the currently active slave of the given bond
+// port against the last observed value and schedules re-verification
+// of the current DPC if the bond failed over to a different slave.
+// Called from updateDNS, i.e. always from the run loop.
+func (m *DpcManager) detectBondFailover(ifName string, status types.BondStatus) {
+	if status.Mode != types.BondModeActiveBackup || status.ActiveSlave == "" {
+		return
+	}
+	prevSlave, known := m.bondActiveSlaves[ifName]
+	m.bondActiveSlaves[ifName] = status.ActiveSlave
+	if !known {
+		// First sighting of this bond - just record the active slave.
+		return
+	}
+	if prevSlave == status.ActiveSlave {
+		return
+	}
+	reason := fmt.Sprintf("bond %s failed over from active slave %s to %s",
+		ifName, prevSlave, status.ActiveSlave)
+	m.Log.Noticef("detectBondFailover: %s", reason)
+	m.pendingBondVerify = append(m.pendingBondVerify, reason)
+	// updateDNS cannot restart verification directly (it lacks the run-loop
+	// context), instead let the run loop pick it up via the timer.
+	m.bondFailoverTimer = time.NewTimer(0)
+}
+
+// processBondFailoverTimer restarts connectivity verification in reaction
+// to a recently detected bond failover.
+func (m *DpcManager) processBondFailoverTimer(ctx context.Context) {
+	m.bondFailoverTimer = &time.Timer{}
+	if len(m.pendingBondVerify) == 0 {
+		return
+	}
+	reason := m.pendingBondVerify[0]
+	m.pendingBondVerify = nil
+	m.restartVerify(ctx, reason)
+	m.updateDNS()
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// This file implements on-demand Ethernet cable testing (see
+// types.CableTestRequest). Where the NIC PHY supports it, ethtool is able
+// to report the status of every wire pair together with the distance to
+// a detected fault (open/short circuit), allowing to remotely distinguish
+// cabling faults from configuration issues on dead uplinks.
+// The result is recorded in the port status inside DeviceNetworkStatus
+// (see types.CableTestStatus).
+
+// doCableTest executes an operator request to run a cable test on a port.
+// Note that the test takes the link down for a few seconds, hence it is
+// executed only on an explicit request.
+func (m *DpcManager) doCableTest(request types.CableTestRequest) {
+	if request.PortLabel == "" {
+		// No test is requested.
+		return
+	}
+	if !request.RequestedAt.After(m.cableTestReqTime) {
+		// Not a new request (e.g. a re-published ZedAgentStatus).
+		return
+	}
+	m.cableTestReqTime = request.RequestedAt
+	m.Log.Noticef("Cable test: running test of port %s as requested at %v",
+		request.PortLabel, request.RequestedAt)
+	status := types.CableTestStatus{RequestedAt: request.RequestedAt}
+	var ifName string
+	var wirelessType types.WirelessType
+	for _, port := range m.deviceNetStatus.Ports {
+		if port.Logicallabel == request.PortLabel {
+			ifName = port.IfName
+			wirelessType = port.WirelessCfg.WType
+			break
+		}
+	}
+	switch {
+	case ifName == "":
+		m.Log.Errorf("Cable test: port %s is not part of the current DPC",
+			request.PortLabel)
+		return
+	case wirelessType != types.WirelessTypeNone:
+		status.Error = fmt.Sprintf("port %s is not an Ethernet port",
+			request.PortLabel)
+	default:
+		output, err := base.Exec(m.Log, "ethtool", "--cable-test", ifName).
+			CombinedOutput()
+		if err != nil {
+			status.Error = fmt.Sprintf("cable test of port %s failed: %v (%s)",
+				request.PortLabel, err, strings.TrimSpace(string(output)))
+		} else {
+			status.Pairs = parseCableTestOutput(string(output))
+		}
+	}
+	status.CompletedAt = time.Now()
+	if status.Error != "" {
+		m.Log.Errorf("Cable test: %s", status.Error)
+	} else {
+		m.Log.Noticef("Cable test: port %s test results: %+v",
+			request.PortLabel, status.Pairs)
+	}
+	m.cableTestResults[request.PortLabel] = status
+	m.updateDNS()
+}
+
+// parseCableTestOutput parses per-pair results from the ethtool cable-test
+// output, e.g.:
+//
+//	Cable test started for device eth0.
+//	Cable test completed for device eth0.
+//	Pair A code OK
+//	Pair B code Open Circuit
+//	Pair B, fault length: 25.00m
+func parseCableTestOutput(output string) (pairs []types.CableTestPairStatus) {
+	pairIndex := make(map[string]int) // pair name -> index inside pairs
+	getPair := func(name string) *types.CableTestPairStatus {
+		if index, haveEntry := pairIndex[name]; haveEntry {
+			return &pairs[index]
+		}
+		pairs = append(pairs, types.CableTestPairStatus{Pair: name})
+		pairIndex[name] = len(pairs) - 1
+		return &pairs[len(pairs)-1]
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "Pair ") {
+			continue
+		}
+		if name, code, found := strings.Cut(line, " code "); found {
+			pair := getPair(strings.TrimSpace(name))
+			pair.Code = strings.TrimSpace(code)
+			continue
+		}
+		if name, length, found := strings.Cut(line, ", fault length: "); found {
+			meters, err := strconv.ParseFloat(
+				strings.TrimSuffix(strings.TrimSpace(length), "m"), 32)
+			if err != nil {
+				continue
+			}
+			pair := getPair(strings.TrimSpace(name))
+			pair.FaultDistanceMeters = float32(meters)
+			pair.HasFaultDistance = true
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/lf-edge/eve/pkg/pillar/zedcloud"
+	"golang.org/x/sys/unix"
+)
+
+// Detection and coarse correction of clock skew.
+// TLS connection to the controller fails when the device clock is badly
+// skewed (e.g. dead RTC battery) and without special handling this surfaces
+// as a generic certificate error, condemning a perfectly usable DPC.
+// DpcManager therefore consults the NTP probing results (see srvprobe.go)
+// and the Date header of an HTTP response from the controller to recognize
+// the skew, classifies the failure as CLOCK_SKEW and attempts a coarse
+// one-off synchronization of the clock (precise synchronization remains
+// the job of the NTP client).
+
+const (
+	// maxToleratedClockSkew : clock offsets beyond this threshold are
+	// considered large enough to break TLS certificate validation.
+	maxToleratedClockSkew = 5 * time.Minute
+	// httpDateProbeTimeout : timeout for the HTTP Date-header probe.
+	httpDateProbeTimeout = 10 * time.Second
+)
+
+// detectClockSkew is called when the connectivity test failed with what looks
+// like a TLS certificate validity error. It returns true if the available
+// clock-sanity signals indicate that the error is caused by a skewed device
+// clock rather than by a genuinely invalid controller certificate.
+// When the skew is confirmed by the controller itself (via the Date header),
+// a coarse clock synchronization is attempted so that the next test of the
+// same DPC can succeed.
+func (m *DpcManager) detectClockSkew(testErr error) bool {
+	if testErr == nil || !isTLSCertTimeError(testErr) {
+		return false
+	}
+	ntpOffset, ntpSkewed := m.ntpReportedClockSkew()
+	if ntpSkewed {
+		m.Log.Warnf("detectClockSkew: reachable NTP server reports clock "+
+			"offset %v, exceeding the tolerated maximum %v",
+			ntpOffset, maxToleratedClockSkew)
+	}
+	// Ask the controller for its notion of time. The TCP+TLS transport
+	// is working (the handshake got as far as certificate validation),
+	// only the certificate validity check is failing.
+	realTime, err := m.probeHTTPDate()
+	if err != nil {
+		m.Log.Warnf("detectClockSkew: HTTP Date-header probe failed: %v", err)
+		// Fall back to the NTP signal alone.
+		return ntpSkewed
+	}
+	offset := time.Until(realTime)
+	if offset < maxToleratedClockSkew && offset > -maxToleratedClockSkew {
+		// The controller-reported time agrees with the local clock,
+		// the certificate error is genuine.
+		return false
+	}
+	m.Log.Warnf("detectClockSkew: local clock is off by %v per the Date "+
+		"header of the controller response", offset)
+	if err := setSystemClock(realTime); err != nil {
+		m.Log.Errorf("detectClockSkew: coarse clock sync to %v failed: %v",
+			realTime, err)
+	} else {
+		m.Log.Noticef("detectClockSkew: coarsely synchronized the clock "+
+			"to %v (moved by %v)", realTime, offset)
+	}
+	return true
+}
+
+// isTLSCertTimeError returns true if the connectivity test error looks like
+// a failure to validate the time validity bounds of the controller
+// certificate.
+func isTLSCertTimeError(err error) bool {
+	var sendErr *zedcloud.SendError
+	if errors.As(err, &sendErr) {
+		for _, attempt := range sendErr.Attempts {
+			if isCertTimeErr(attempt.Err) {
+				return true
+			}
+		}
+	}
+	return isCertTimeErr(err)
+}
+
+func isCertTimeErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	var certErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) {
+		return certErr.Reason == x509.Expired
+	}
+	// The typed error may be lost when errors cross a string boundary,
+	// fall back to matching the standard library message.
+	return strings.Contains(err.Error(),
+		"certificate has expired or is not yet valid")
+}
+
+// ntpReportedClockSkew consults the results of the periodic NTP server
+// probing (see srvprobe.go). It returns true if a reachable NTP server
+// reports a clock offset exceeding the tolerated maximum.
+func (m *DpcManager) ntpReportedClockSkew() (offset time.Duration, skewed bool) {
+	for _, ntpReach := range m.ntpReachability {
+		for _, reach := range ntpReach {
+			if !reach.Reachable {
+				continue
+			}
+			offset = time.Duration(reach.OffsetInMsec) * time.Millisecond
+			if offset > maxToleratedClockSkew || offset < -maxToleratedClockSkew {
+				return offset, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// probeHTTPDate sends an HTTP request to the controller and returns the time
+// reported in the Date header of the response. The request goes out through
+// whichever management port the kernel currently routes the controller
+// towards. Certificate verification is deliberately skipped - it is exactly
+// what is failing when the clock is skewed - and the obtained time is used
+// solely to detect/correct gross clock skew, never for trust decisions.
+func (m *DpcManager) probeHTTPDate() (time.Time, error) {
+	server, err := os.ReadFile(types.ServerFileName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	serverNameAndPort := strings.TrimSpace(string(server))
+	client := &http.Client{
+		Timeout: httpDateProbeTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	resp, err := client.Head("https://" + serverNameAndPort)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	dateHdr := resp.Header.Get("Date")
+	if dateHdr == "" {
+		return time.Time{}, errors.New("response is missing the Date header")
+	}
+	return http.ParseTime(dateHdr)
+}
+
+// setSystemClock performs a one-off coarse adjustment of the system clock.
+func setSystemClock(t time.Time) error {
+	tv := unix.NsecToTimeval(t.UnixNano())
+	return unix.Settimeofday(&tv)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+const (
+	// When the used portion of the cellular data plan reaches this percentage,
+	// the cost of the port is automatically bumped to bumpedPortCost.
+	wwanQuotaBumpPercent = 90
+	// An already applied quota-based cost bump is removed only after the usage
+	// drops below this percentage (e.g. a new billing period has started).
+	// Keeping this below wwanQuotaBumpPercent provides hysteresis, preventing
+	// the cost from oscillating when the usage hovers around the threshold.
+	wwanQuotaUnbumpPercent = 80
+	// Cost assigned to a cellular port which is roaming or close to exhausting
+	// its data-plan quota. With the maximum possible cost, every other usable
+	// port is preferred over the bumped one.
+	bumpedPortCost = types.PortCostMax
+)
+
+// updateWwanCostBumps re-evaluates automatic cost bumps of cellular ports
+// based on the roaming status and the data-plan usage reported by the wwan
+// service. A bumped port gets the maximum cost so that traffic falls back
+// to cheaper uplinks without manual intervention.
+// Returns true if the set of applied cost bumps has changed.
+func (m *DpcManager) updateWwanCostBumps(dpc *types.DevicePortConfig) (changed bool) {
+	newBumps := make(map[string]string)
+	for i := range dpc.Ports {
+		port := &dpc.Ports[i]
+		if port.WirelessCfg.WType != types.WirelessTypeCellular {
+			continue
+		}
+		wwanNetStatus := m.wwanStatus.GetNetworkStatus(port.Logicallabel)
+		if wwanNetStatus == nil {
+			continue
+		}
+		if wwanNetStatus.CurrentProvider.Roaming {
+			// Intentionally coarse-grained reason string - it is copied
+			// into DeviceNetworkStatus and frequent changes would trigger
+			// unnecessary re-publishing.
+			newBumps[port.Logicallabel] = "connection is roaming"
+			continue
+		}
+		dataPlan := wwanNetStatus.DataPlan
+		if dataPlan.TotalBytes == 0 {
+			// Quota is not known or the plan is unlimited.
+			continue
+		}
+		usedPercent := 100 * dataPlan.UsedBytes / dataPlan.TotalBytes
+		threshold := uint64(wwanQuotaBumpPercent)
+		if m.wwanCostBumps[port.Logicallabel] != "" {
+			threshold = wwanQuotaUnbumpPercent
+		}
+		if usedPercent >= threshold {
+			m.Log.Noticef("Port %s has used %d%% of the data-plan quota "+
+				"(%d of %d bytes)", port.Logicallabel, usedPercent,
+				dataPlan.UsedBytes, dataPlan.TotalBytes)
+			newBumps[port.Logicallabel] = "data-plan quota is almost exhausted"
+		}
+	}
+	for portLL, reason := range newBumps {
+		if m.wwanCostBumps[portLL] != reason {
+			changed = true
+			m.Log.Noticef("Bumping cost of port %s to %d: %s",
+				portLL, bumpedPortCost, reason)
+		}
+	}
+	for portLL, reason := range m.wwanCostBumps {
+		if newBumps[portLL] == "" {
+			changed = true
+			m.Log.Noticef("Removing cost bump of port %s (was applied because: %s)",
+				portLL, reason)
+		}
+	}
+	m.wwanCostBumps = newBumps
+	return changed
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"strings"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// When the management traffic is being switched over to a different DPC,
+// ports which keep their DHCP client running across the switch may still
+// hold a stale, half-expired lease. Testing connectivity with such a lease
+// risks an immediate re-failure right after the switch-over (e.g. the DHCP
+// server has meanwhile re-allocated the address or changed the DNS servers).
+// Therefore, before the connectivity verification of a different-than-applied
+// DPC, the DHCP clients of the candidate management ports are asked to rebind
+// their leases and are given a bounded amount of time (the standard
+// DPCStateIPDNSWait handling) to obtain fresh addresses and DNS configuration.
+
+// renewDHCPLeases asks the running DHCP clients of the DPC's management ports
+// to renew (rebind) their leases. Ports without an existing lease are skipped -
+// their DHCP clients are still negotiating and there is nothing to refresh.
+// Returns true if renewal of at least one lease was triggered.
+func (m *DpcManager) renewDHCPLeases(dpc *types.DevicePortConfig) (renewed bool) {
+	for i := range dpc.Ports {
+		port := &dpc.Ports[i]
+		if !port.IsMgmt || port.IfName == "" {
+			continue
+		}
+		if port.Dhcp != types.DhcpTypeClient {
+			continue
+		}
+		if port.WirelessCfg.WType == types.WirelessTypeCellular {
+			// Cellular connectivity is managed by the wwan microservice,
+			// there is no dhcpcd instance to signal.
+			continue
+		}
+		ifIndex, exists, err := m.NetworkMonitor.GetInterfaceIndex(port.IfName)
+		if !exists || err != nil {
+			continue
+		}
+		ipAddrs, _, err := m.NetworkMonitor.GetInterfaceAddrs(ifIndex)
+		if err != nil || len(ipAddrs) == 0 {
+			// Without an existing lease there is nothing to renew.
+			continue
+		}
+		output, err := base.Exec(m.Log, "dhcpcd", "--rebind", port.IfName).
+			CombinedOutput()
+		if err != nil {
+			m.Log.Warnf("DPC verify: failed to trigger DHCP lease renewal "+
+				"for port %s: %v (%s)", port.Logicallabel, err,
+				strings.TrimSpace(string(output)))
+			continue
+		}
+		m.Log.Noticef("DPC verify: triggered DHCP lease renewal for port %s",
+			port.Logicallabel)
+		renewed = true
+	}
+	return renewed
+}
@@ -6,6 +6,10 @@ package dpcmanager
 import (
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/eriknordmark/ipinfo"
@@ -13,6 +17,31 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/types"
 )
 
+// getEthLinkStatus reads the actual negotiated link parameters (speed, duplex)
+// of an Ethernet interface from sysfs. Returns zero values if the link is down
+// or the parameters cannot be determined.
+func getEthLinkStatus(ifName string) (ethLink types.EthLinkStatus) {
+	sysDir := filepath.Join("/sys/class/net", ifName)
+	speed, err := os.ReadFile(filepath.Join(sysDir, "speed"))
+	if err == nil {
+		// Note: sysfs reports speed of -1 when the link is down.
+		speedMbps, err := strconv.Atoi(strings.TrimSpace(string(speed)))
+		if err == nil && speedMbps > 0 {
+			ethLink.Speed = uint32(speedMbps)
+		}
+	}
+	duplex, err := os.ReadFile(filepath.Join(sysDir, "duplex"))
+	if err == nil {
+		switch strings.TrimSpace(string(duplex)) {
+		case "half":
+			ethLink.Duplex = types.DuplexHalf
+		case "full":
+			ethLink.Duplex = types.DuplexFull
+		}
+	}
+	return ethLink
+}
+
 type geoService struct{}
 
 // GetGeolocationInfo tries to obtain geolocation information corresponding
@@ -32,6 +61,7 @@ func (g *geoService) GetGeolocationInfo(ipAddr net.IP) (*ipinfo.IPInfo, error) {
 
 func (m *DpcManager) updateDNS() {
 	defer m.publishDNS()
+	defer m.updateNetworkReadiness()
 	dpc := m.currentDPC()
 	if dpc == nil {
 		m.deviceNetStatus = types.DeviceNetworkStatus{}
@@ -43,9 +73,44 @@ func (m *DpcManager) updateDNS() {
 	m.deviceNetStatus.Testing = m.dpcVerify.inProgress
 	m.deviceNetStatus.CurrentIndex = m.dpcList.CurrentIndex
 	m.deviceNetStatus.RadioSilence = m.rsStatus
+	if m.deferredDpc != nil {
+		m.deviceNetStatus.DeferredDpcKey = m.deferredDpc.Key
+		m.deviceNetStatus.DeferredSince = m.deferredSince
+	} else {
+		m.deviceNetStatus.DeferredDpcKey = ""
+		m.deviceNetStatus.DeferredSince = time.Time{}
+	}
+	if m.abTestDpc != nil {
+		m.deviceNetStatus.ABTestDpcKey = m.abTestDpc.Key
+		m.deviceNetStatus.ABTestSince = m.abTestSince
+	} else {
+		m.deviceNetStatus.ABTestDpcKey = ""
+		m.deviceNetStatus.ABTestSince = time.Time{}
+	}
+	m.deviceNetStatus.PinnedMgmtPort = m.mgmtPinPort
+	m.deviceNetStatus.PinnedMgmtPortUntil = m.mgmtPinUntil
+	if m.dpcPinDpc != nil {
+		m.deviceNetStatus.PinnedDpcKey = m.dpcPinDpc.Key
+		m.deviceNetStatus.PinnedDpcUntil = m.dpcPinUntil
+	} else {
+		m.deviceNetStatus.PinnedDpcKey = ""
+		m.deviceNetStatus.PinnedDpcUntil = time.Time{}
+	}
+	m.deviceNetStatus.LastDPCRetest = m.dpcRetestAt
+	m.deviceNetStatus.ProcessHealth = m.getProcessHealth()
+	m.deviceNetStatus.SharedPortLabels = m.sharedPortLabels
 	oldDNS := m.deviceNetStatus
 	m.deviceNetStatus.Ports = make([]types.NetworkPortStatus, len(dpc.Ports))
 	for ix, port := range dpc.Ports {
+		// If the underlying NIC was found by the DPC reconciler under
+		// a different name than configured (e.g. after a change in the
+		// kernel/udev enumeration order across reboots), use the actual
+		// interface name for state retrieval and report the remapping.
+		actualIfName, renamed := m.reconcileStatus.RenamedPorts[port.Logicallabel]
+		if renamed && actualIfName != port.IfName {
+			m.deviceNetStatus.Ports[ix].ConfiguredIfName = port.IfName
+			port.IfName = actualIfName
+		}
 		m.deviceNetStatus.Ports[ix].IfName = port.IfName
 		m.deviceNetStatus.Ports[ix].Phylabel = port.Phylabel
 		m.deviceNetStatus.Ports[ix].Logicallabel = port.Logicallabel
@@ -53,8 +118,22 @@ func (m *DpcManager) updateDNS() {
 		m.deviceNetStatus.Ports[ix].IsMgmt = port.IsMgmt
 		m.deviceNetStatus.Ports[ix].IsL3Port = port.IsL3Port
 		m.deviceNetStatus.Ports[ix].Cost = port.Cost
+		if reason := m.wwanCostBumps[port.Logicallabel]; reason != "" {
+			m.deviceNetStatus.Ports[ix].Cost = bumpedPortCost
+			m.deviceNetStatus.Ports[ix].CostBumpReason = reason
+		}
 		m.deviceNetStatus.Ports[ix].ProxyConfig = port.ProxyConfig
 		m.deviceNetStatus.Ports[ix].WirelessCfg = port.WirelessCfg
+		m.deviceNetStatus.Ports[ix].SecondaryAddrs = port.SecondaryAddresses
+		m.deviceNetStatus.Ports[ix].AsymRouteWarning = m.asymRouteWarning[port.IfName]
+		m.deviceNetStatus.Ports[ix].DNSReachability = m.dnsReachability[port.IfName]
+		m.deviceNetStatus.Ports[ix].NTPReachability = m.ntpReachability[port.IfName]
+		if flapState := m.carrierFlaps[port.IfName]; flapState != nil {
+			m.deviceNetStatus.Ports[ix].FlapCount = flapState.flapCount
+		}
+		m.deviceNetStatus.Ports[ix].CaptivePortalURL = m.captivePortals[port.IfName]
+		m.deviceNetStatus.Ports[ix].CableTest = m.cableTestResults[port.Logicallabel]
+		m.deviceNetStatus.Ports[ix].Transceiver = m.transceivers[port.Logicallabel]
 		// Set fields from the config...
 		m.deviceNetStatus.Ports[ix].Dhcp = port.Dhcp
 		m.deviceNetStatus.Ports[ix].Type = port.Type
@@ -80,6 +159,12 @@ func (m *DpcManager) updateDNS() {
 				m.deviceNetStatus.Ports[ix].WirelessStatus.Cellular = *wwanNetStatus
 			}
 		}
+		// For WiFi connectivity, report the association status separately
+		// for every configured SSID.
+		if port.WirelessCfg.WType == types.WirelessTypeWifi {
+			m.deviceNetStatus.Ports[ix].WirelessStatus.Wifi =
+				m.getWifiNetworkStatus(port)
+		}
 		// Do not try to get state data for interface which is in PCIback.
 		ioBundle := m.adapters.LookupIoBundleLogicallabel(port.Logicallabel)
 		if ioBundle != nil && ioBundle.IsPCIBack {
@@ -118,6 +203,19 @@ func (m *DpcManager) updateDNS() {
 			m.deviceNetStatus.Ports[ix].Up = ifAttrs.LowerUp
 			m.deviceNetStatus.Ports[ix].MTU = ifAttrs.MTU
 		}
+		if port.WirelessCfg.WType == types.WirelessTypeNone {
+			m.deviceNetStatus.Ports[ix].EthLink = getEthLinkStatus(port.IfName)
+		}
+		if port.L2Type == types.L2LinkTypeBond {
+			bondStatus, err := devicenetwork.GetBondStatus(m.Log, port.IfName)
+			if err != nil {
+				m.Log.Warnf("updateDNS: failed to get status of bond %s: %v",
+					port.IfName, err)
+			} else {
+				m.deviceNetStatus.Ports[ix].BondStatus = bondStatus
+				m.detectBondFailover(port.IfName, bondStatus)
+			}
+		}
 		ipAddrs, macAddr, err := m.NetworkMonitor.GetInterfaceAddrs(ifindex)
 		if err != nil {
 			m.Log.Warnf(
@@ -190,6 +288,30 @@ func (m *DpcManager) updateDNS() {
 			ai.LastGeoTimestamp = oai.LastGeoTimestamp
 		}
 	}
+
+	// Explain why ports are (not) used for management traffic.
+	m.updateMgmtPortExplanations()
+}
+
+// getProcessHealth returns the health of network helper daemons tracked
+// by the process supervisor (nil when the supervisor is not used).
+func (m *DpcManager) getProcessHealth() []types.ProcessHealth {
+	if m.ProcSupervisor == nil {
+		return nil
+	}
+	var health []types.ProcessHealth
+	for _, status := range m.ProcSupervisor.GetProcessStatus() {
+		health = append(health, types.ProcessHealth{
+			ProcessName:      status.Name,
+			Running:          status.Running,
+			Pid:              status.Pid,
+			Restarts:         status.Restarts,
+			LastExitTime:     status.LastExitTime,
+			LastRestartTime:  status.LastRestartTime,
+			LastRestartError: status.LastRestartError,
+		})
+	}
+	return health
 }
 
 func (m *DpcManager) publishDNS() {
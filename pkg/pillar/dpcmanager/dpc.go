@@ -23,6 +23,7 @@ func (m *DpcManager) currentDPC() *types.DevicePortConfig {
 
 func (m *DpcManager) doAddDPC(ctx context.Context, dpc types.DevicePortConfig) {
 	m.setDiscoveredWwanIfNames(&dpc)
+	m.kpiRecordDpcReceived(dpc)
 	mgmtCount := dpc.CountMgmtPorts(false)
 	if mgmtCount == 0 {
 		// This DPC will be ignored when we check IsDPCUsable which
@@ -31,6 +32,25 @@ func (m *DpcManager) doAddDPC(ctx context.Context, dpc types.DevicePortConfig) {
 			"will be ignored", dpc.Key)
 	}
 
+	// Disruptive changes may have to wait for the maintenance window to open.
+	if m.deferDPCIfDisruptive(dpc) {
+		return
+	}
+
+	// On dual-uplink devices the new configuration may be first verified
+	// on the secondary uplinks only, while the primary continues running
+	// the current, known-to-work configuration.
+	if m.startABTestIfApplicable(ctx, dpc) {
+		return
+	}
+
+	// If the new DPC only adds or removes ports (e.g. a port moving to/from
+	// app direct-assignment), apply it incrementally without re-verifying
+	// the whole configuration.
+	if m.tryPortHandover(ctx, dpc) {
+		return
+	}
+
 	// XXX really need to know whether anything with current or lower
 	// index has changed. We don't care about inserts at the end of the list.
 	configChanged := m.updateDPCListAndPublish(dpc, false)
@@ -57,6 +77,23 @@ func (m *DpcManager) doAddDPC(ctx context.Context, dpc types.DevicePortConfig) {
 
 func (m *DpcManager) doDelDPC(ctx context.Context, dpc types.DevicePortConfig) {
 	m.setDiscoveredWwanIfNames(&dpc)
+	m.kpiForgetDpc(dpc)
+	if m.deferredDpc != nil && m.deferredDpc.Key == dpc.Key &&
+		m.deferredDpc.TimePriority == dpc.TimePriority {
+		m.dropDeferredDPC(dpc)
+		return
+	}
+	if m.abTestDpc != nil && m.abTestDpc.Key == dpc.Key &&
+		m.abTestDpc.TimePriority == dpc.TimePriority {
+		m.abortABTest(ctx, "candidate DPC was removed")
+		return
+	}
+	if m.dpcPinDpc != nil && m.dpcPinDpc.Key == dpc.Key &&
+		m.dpcPinDpc.TimePriority == dpc.TimePriority {
+		// The restartVerify below re-runs the standard DPC selection.
+		m.Log.Noticef("Pinned DPC was removed, clearing the pin")
+		m.clearDPCPin()
+	}
 	configChanged := m.updateDPCListAndPublish(dpc, true)
 	if !configChanged {
 		m.Log.Functionf("doDelDPC: System current. No change detected.\n")
@@ -92,6 +129,11 @@ func (m *DpcManager) updateDPCListAndPublish(
 			m.Log.Functionf("updateDPCListAndPublish: no change but timestamps %v %v\n",
 				oldConfig.TimePriority, dpc.TimePriority)
 
+			// Even if the config is semantically unchanged, the credentials
+			// may have been re-encrypted by the controller (encryption key
+			// rotation). Retain the latest ciphertext.
+			oldConfig.UpdateCipherBlocks(dpc)
+
 			// If this is current and current is in use (index=0)
 			// then no work needed. Otherwise we reorder.
 			if current != nil && current.MostlyEqual(oldConfig) && currentIndex == 0 {
@@ -265,6 +307,7 @@ func (m *DpcManager) ingestDPCList() (dpclPresentAtBoot bool) {
 			continue
 		}
 		dpcl.PortConfigList = append(dpcl.PortConfigList, portConfig)
+		m.kpiRecordDpcReceived(portConfig)
 		// We have at least one port
 		dpclPresentAtBoot = true
 	}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/eriknordmark/ipinfo"
@@ -16,8 +17,10 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/flextimer"
 	"github.com/lf-edge/eve/pkg/pillar/netdump"
 	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/pubsub"
 	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
 	"github.com/lf-edge/eve/pkg/pillar/zedcloud"
 	uuid "github.com/satori/go.uuid"
 )
@@ -76,10 +79,18 @@ type DpcManager struct {
 	DpcReconciler  dpcreconciler.DpcReconciler
 	ConnTester     conntester.ConnectivityTester
 
+	// ProcSupervisor : optional; when defined, the health of network helper
+	// daemons (dhcpcd, wpa_supplicant) tracked by the supervisor is published
+	// in DeviceNetworkStatus.
+	ProcSupervisor *procsupervisor.ProcSupervisor
+
 	// Publications
 	PubDummyDevicePortConfig pubsub.Publication // for logging
 	PubDevicePortConfigList  pubsub.Publication
 	PubDeviceNetworkStatus   pubsub.Publication
+	PubNetworkReadiness      pubsub.Publication
+	PubDPCMetrics            pubsub.Publication
+	PubSpeedTestMetrics      pubsub.Publication
 
 	// Metrics
 	ZedcloudMetrics *zedcloud.AgentMetrics
@@ -93,25 +104,125 @@ type DpcManager struct {
 	rsStatus         types.RadioSilence
 	enableLastResort bool
 	devUUID          uuid.UUID
+	// Controller-defined shared port labels, parsed from the global config
+	// item NetworkSharedPortLabels and published as part of DNS.
+	sharedPortLabels []types.SharedPortLabel
 	// Boot-time configuration
 	dpclPresentAtBoot bool
 
 	// DPC verification
 	dpcVerify dpcVerify
 
+	// Cancellation of an in-flight connectivity test.
+	// Accessed from both AddDPC (caller's goroutine) and the run loop,
+	// hence protected by mutex.
+	connTestMx      sync.Mutex
+	connTestCancel  context.CancelFunc
+	connTestDpcPrio time.Time
+
+	// Deferral of disruptive DPC changes outside of the maintenance window
+	// (see maintenance.go).
+	maintWindowStartHour int           // hour of the day (UTC)
+	maintWindowDuration  time.Duration // zero means that the gating is disabled
+	maintWindowOverride  bool
+	deferredDpc          *types.DevicePortConfig
+	deferredSince        time.Time
+
+	// Temporary pinning of management traffic to a single port (see pin.go).
+	mgmtPinConfig types.MgmtTrafficPin // last applied request
+	mgmtPinPort   string               // logical label, empty if no pin active
+	mgmtPinUntil  time.Time
+
+	// Operator-triggered retest and temporary pinning of a specific DPC list
+	// entry (see dpcpin.go).
+	dpcRetestReqTime time.Time               // RequestedAt of the last executed retest request
+	dpcRetestAt      time.Time               // time when the last requested retest was started
+	dpcPinConfig     types.DPCPinRequest     // last applied request
+	dpcPinDpc        *types.DevicePortConfig // pinned entry, nil if no pin active
+	dpcPinUntil      time.Time
+
+	// A/B testing of a candidate DPC on the secondary uplinks (see abtest.go).
+	abTestEnabled bool
+	abTestDpc     *types.DevicePortConfig // candidate being A/B tested
+	abTestHybrid  *types.DevicePortConfig // current DPC + candidate's test ports
+	abTestPorts   []string                // logical labels of the tested ports
+	abTestSince   time.Time
+
+	// Cooperative handover of ports to/from app direct-assignment
+	// (see handover.go).
+	handoverPorts []string // logical labels of added ports to re-verify
+	handoverSince time.Time
+
+	// Detection of asymmetric routing (see asymroute.go).
+	// Key = interface name.
+	asymRouteWarning map[string]string
+
+	// Results of DNS/NTP server probing (see srvprobe.go).
+	// Key = interface name.
+	dnsReachability map[string][]types.DNSServerReachability
+	ntpReachability map[string][]types.NTPServerReachability
+
+	// Detection and dampening of interface carrier flapping (see flap.go).
+	// Key = interface name.
+	carrierFlaps map[string]*carrierFlapState
+
+	// Detection of bond failovers (see bondfailover.go).
+	// Key = bond interface name, value = last observed active slave.
+	bondActiveSlaves  map[string]string
+	pendingBondVerify []string // reasons for pending re-verification
+
+	// Captive portals detected by the latest connectivity test.
+	// Key = interface name, value = portal URL (may be empty).
+	captivePortals map[string]string
+
+	// Automatic cost bumps of cellular ports (see cost.go).
+	// Key = port logical label, value = reason for the cost bump.
+	wwanCostBumps map[string]string
+
+	// Results of on-demand cable tests (see cabletest.go).
+	// Key = port logical label.
+	cableTestResults map[string]types.CableTestStatus
+	cableTestReqTime time.Time // RequestedAt of the last executed request
+
+	// Watchdog-safe handling of long reconciliations (see reconcileprogress.go).
+	slowReconcileLogAt time.Time // last "slow reconciliation" log
+
+	// Results of on-demand uplink throughput measurements (see speedtest.go).
+	speedTestMetrics types.SpeedTestMetrics
+	speedTestReqTime time.Time // RequestedAt of the last executed request
+
+	// Transceiver (SFP) diagnostics periodically collected from ports
+	// (see sfpdom.go). Key = port logical label.
+	transceivers map[string]types.TransceiverStatus
+
+	// Time-to-connectivity KPIs recorded for DPC applications (see kpi.go).
+	// Key of dpcReceivedAt = DevicePortConfig.PubKey().
+	dpcMetrics    types.DPCMetrics
+	dpcReceivedAt map[string]time.Time
+
 	// Current status
-	reconcileStatus dpcreconciler.ReconcileStatus
-	deviceNetStatus types.DeviceNetworkStatus
-	wwanStatus      types.WwanStatus
+	reconcileStatus  dpcreconciler.ReconcileStatus
+	deviceNetStatus  types.DeviceNetworkStatus
+	networkReadiness types.NetworkReadiness
+	wwanStatus       types.WwanStatus
 
 	// Channels
-	inputCommands chan inputCommand
-	networkEvents <-chan netmonitor.Event
+	inputCommands     chan inputCommand
+	networkEvents     <-chan netmonitor.Event
+	procHealthUpdates <-chan []procsupervisor.ProcessStatus // nil without ProcSupervisor
 
 	// Timers
 	dpcTestTimer          *time.Timer
 	dpcTestBetterTimer    *time.Timer
 	pendingDpcTimer       *time.Timer
+	maintWindowTimer      *time.Timer
+	flapHoldDownTimer     *time.Timer
+	bondFailoverTimer     *time.Timer
+	abTestTimer           *time.Timer
+	handoverTimer         *time.Timer
+	mgmtPinTimer          *time.Timer
+	dpcPinTimer           *time.Timer
+	transceiverTimer      *time.Timer
 	geoTimer              flextimer.FlexTickerHandle
 	dpcTestDuration       time.Duration // Wait for DHCP address
 	dpcTestInterval       time.Duration // Test interval in minutes.
@@ -186,6 +297,11 @@ const (
 	commandUpdateRS
 	commandUpdateDevUUID
 	commandProcessWwanStatus
+	commandUpdateMgmtPin
+	commandCableTest
+	commandSpeedTest
+	commandDPCRetest
+	commandUpdateDPCPin
 )
 
 type inputCommand struct {
@@ -196,10 +312,17 @@ type inputCommand struct {
 	rs         types.RadioSilence       // for commandUpdateRS
 	devUUID    uuid.UUID                // for commandUpdateDevUUID
 	wwanStatus types.WwanStatus         // for commandProcessWwanStatus
+	mgmtPin    types.MgmtTrafficPin     // for commandUpdateMgmtPin
+	cableTest  types.CableTestRequest   // for commandCableTest
+	speedTest  types.SpeedTestRequest   // for commandSpeedTest
+	dpcRetest  types.DPCRetestRequest   // for commandDPCRetest
+	dpcPin     types.DPCPinRequest      // for commandUpdateDPCPin
 }
 
 type dpcVerify struct {
 	inProgress     bool
+	interrupted    bool // test was canceled by a queued higher-priority DPC
+	renewDHCP      bool // renew DHCP leases before the connectivity test (see dhcprenew.go)
 	startedAt      time.Time
 	cloudConnWorks bool
 	crucialIfs     map[string]netmonitor.IfAttrs // key = ifName, change triggers restartVerify
@@ -208,6 +331,15 @@ type dpcVerify struct {
 // Init DpcManager
 func (m *DpcManager) Init(ctx context.Context) error {
 	m.dpcVerify.crucialIfs = make(map[string]netmonitor.IfAttrs)
+	m.asymRouteWarning = make(map[string]string)
+	m.dnsReachability = make(map[string][]types.DNSServerReachability)
+	m.ntpReachability = make(map[string][]types.NTPServerReachability)
+	m.carrierFlaps = make(map[string]*carrierFlapState)
+	m.bondActiveSlaves = make(map[string]string)
+	m.captivePortals = make(map[string]string)
+	m.wwanCostBumps = make(map[string]string)
+	m.cableTestResults = make(map[string]types.CableTestStatus)
+	m.transceivers = make(map[string]types.TransceiverStatus)
 	m.inputCommands = make(chan inputCommand, 10)
 	if m.GeoService == nil {
 		m.GeoService = &geoService{}
@@ -223,7 +355,17 @@ func (m *DpcManager) Init(ctx context.Context) error {
 	m.dpcTestTimer = &time.Timer{}
 	m.dpcTestBetterTimer = &time.Timer{}
 	m.pendingDpcTimer = &time.Timer{}
+	m.maintWindowTimer = &time.Timer{}
+	m.flapHoldDownTimer = &time.Timer{}
+	m.bondFailoverTimer = &time.Timer{}
+	m.abTestTimer = &time.Timer{}
+	m.handoverTimer = &time.Timer{}
+	m.mgmtPinTimer = &time.Timer{}
+	m.dpcPinTimer = &time.Timer{}
 	m.geoTimer = flextimer.FlexTickerHandle{}
+	// Transceiver diagnostics are collected from the start, independently
+	// of GCP (see sfpdom.go).
+	m.transceiverTimer = time.NewTimer(transceiverInitialDelay)
 
 	// Ingest persisted list of DPCs. ingestDPCList will return false
 	// to indicate the file is missing in /persist
@@ -235,12 +377,19 @@ func (m *DpcManager) Init(ctx context.Context) error {
 func (m *DpcManager) Run(ctx context.Context) (err error) {
 	m.startTime = time.Now()
 	m.networkEvents = m.NetworkMonitor.WatchEvents(ctx, "dpc-reconciler")
+	if m.ProcSupervisor != nil {
+		m.procHealthUpdates = m.ProcSupervisor.WatchHealthUpdates()
+	}
 	go m.run(ctx)
 	return nil
 }
 
 func (m *DpcManager) run(ctx context.Context) {
 	wdName := m.AgentName + "-DpcManager"
+	// Receive progress checkpoints from within long reconciliations
+	// to touch the watchdog and log slow operations
+	// (see reconcileprogress.go).
+	ctx = m.watchReconcileProgress(ctx, wdName)
 	stillRunning := time.NewTicker(watchdogPeriod)
 	m.Watchdog.StillRunning(wdName, warningTime, errorTime)
 	m.Watchdog.RegisterFileWatchdog(wdName)
@@ -266,6 +415,16 @@ func (m *DpcManager) run(ctx context.Context) {
 				m.doUpdateRadioSilence(ctx, inputCmd.rs)
 			case commandUpdateDevUUID:
 				m.doUpdateDevUUID(ctx, inputCmd.devUUID)
+			case commandUpdateMgmtPin:
+				m.doUpdateMgmtTrafficPin(inputCmd.mgmtPin)
+			case commandCableTest:
+				m.doCableTest(inputCmd.cableTest)
+			case commandSpeedTest:
+				m.doSpeedTest(inputCmd.speedTest)
+			case commandDPCRetest:
+				m.doDPCRetest(ctx, inputCmd.dpcRetest)
+			case commandUpdateDPCPin:
+				m.doUpdateDPCPin(ctx, inputCmd.dpcPin)
 			case commandProcessWwanStatus:
 				m.processWwanStatus(ctx, inputCmd.wwanStatus)
 			}
@@ -294,6 +453,8 @@ func (m *DpcManager) run(ctx context.Context) {
 					m.Log.Noticef("Device connectivity to cloud failed (%v). Took %v",
 						err, time.Since(start))
 				}
+				m.checkAsymRouting(ctx)
+				m.probeDNSAndNTPServers()
 			}
 			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "TestTimer", start,
 				warningTime, errorTime)
@@ -325,6 +486,86 @@ func (m *DpcManager) run(ctx context.Context) {
 			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "PendTimer", start,
 				warningTime, errorTime)
 
+		case _, ok := <-m.maintWindowTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("Maintenance window timer stopped?")
+			} else {
+				m.applyDeferredDPC(ctx, "maintenance window opened")
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "MaintWindowTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.flapHoldDownTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("Flap hold-down timer stopped?")
+			} else {
+				m.processFlapHoldDownTimer(ctx)
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "FlapHoldDownTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.bondFailoverTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("Bond failover timer stopped?")
+			} else {
+				m.processBondFailoverTimer(ctx)
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "BondFailoverTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.abTestTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("A/B test timer stopped?")
+			} else {
+				m.runABTest(ctx)
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "ABTestTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.handoverTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("Port handover timer stopped?")
+			} else {
+				m.runPortHandoverTest(ctx)
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "HandoverTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.mgmtPinTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("Management traffic pin timer stopped?")
+			} else {
+				m.expireMgmtTrafficPin()
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "MgmtPinTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.dpcPinTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("DPC pin timer stopped?")
+			} else {
+				m.expireDPCPin(ctx)
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "DPCPinTimer", start,
+				warningTime, errorTime)
+
+		case _, ok := <-m.transceiverTimer.C:
+			start := time.Now()
+			if !ok {
+				m.Log.Noticef("Transceiver diagnostics timer stopped?")
+			} else {
+				m.collectTransceiverDiags()
+			}
+			m.Watchdog.CheckMaxTimeTopic(m.AgentName, "TransceiverTimer", start,
+				warningTime, errorTime)
+
 		case <-m.geoTimer.C:
 			start := time.Now()
 			m.Log.Trace("GeoTimer at", start)
@@ -343,12 +584,23 @@ func (m *DpcManager) run(ctx context.Context) {
 					delete(m.dpcVerify.crucialIfs, ifName)
 					continue
 				}
-				m.Log.Noticef("Crucial port %s changed", ifName)
 				newAttrs := ev.Attrs
-				m.dpcVerify.crucialIfs[ifName] = newAttrs
 				prevAttrs, known := m.dpcVerify.crucialIfs[ifName]
-				if !known ||
-					prevAttrs.AdminUp != newAttrs.AdminUp ||
+				m.dpcVerify.crucialIfs[ifName] = newAttrs
+				if !known {
+					// First sighting of this crucial port - there is no
+					// attribute change to react to, just seed the cache.
+					continue
+				}
+				m.Log.Noticef("Crucial port %s changed", ifName)
+				if prevAttrs.LowerUp != newAttrs.LowerUp &&
+					m.recordCarrierTransition(ifName) {
+					// Carrier is flapping - hold down re-verification until
+					// the link stabilizes (flap.go will trigger it later).
+					m.updateDNS()
+					continue
+				}
+				if prevAttrs.AdminUp != newAttrs.AdminUp ||
 					prevAttrs.LowerUp != newAttrs.LowerUp ||
 					prevAttrs.Enslaved != newAttrs.Enslaved ||
 					prevAttrs.IfIndex != newAttrs.IfIndex {
@@ -372,7 +624,8 @@ func (m *DpcManager) run(ctx context.Context) {
 						case types.DPCStateIPDNSWait,
 							types.DPCStatePCIWait,
 							types.DPCStateIntfWait,
-							types.DPCStateWwanWait:
+							types.DPCStateWwanWait,
+							types.DPCStateWifiWait:
 							// Note that DPCStatePCIWait and DPCStateIntfWait can be returned
 							// also in scenarios where some ports are in PCIBack while others
 							// are waiting for IP addresses.
@@ -384,10 +637,16 @@ func (m *DpcManager) run(ctx context.Context) {
 					}
 				}
 				m.updateDNS()
+				m.checkAsymRouting(ctx)
 			case netmonitor.DNSInfoChange:
 				m.updateDNS()
 			}
 
+		case <-m.procHealthUpdates:
+			// Health of a supervised helper daemon (dhcpcd, wpa_supplicant)
+			// has changed - re-publish DNS with the new health information.
+			m.updateDNS()
+
 		case <-ctx.Done():
 			return
 
@@ -404,7 +663,11 @@ func (m *DpcManager) reconcilerArgs() dpcreconciler.Args {
 		AA:  m.adapters,
 		RS:  m.rsConfig,
 	}
-	if m.currentDPC() != nil {
+	if m.abTestHybrid != nil {
+		// While A/B testing is in progress, the secondary uplinks run
+		// the candidate's port configuration.
+		args.DPC = *m.abTestHybrid
+	} else if m.currentDPC() != nil {
 		args.DPC = *m.currentDPC()
 	}
 	return args
@@ -414,12 +677,32 @@ func (m *DpcManager) reconcilerArgs() dpcreconciler.Args {
 // It will be added into the list at a position determined by the TimePriority
 // attribute. The higher the timestamp is, the higher the priority is.
 func (m *DpcManager) AddDPC(dpc types.DevicePortConfig) {
+	m.maybeInterruptConnTest(dpc)
 	m.inputCommands <- inputCommand{
 		cmd: commandAddDPC,
 		dpc: dpc,
 	}
 }
 
+// maybeInterruptConnTest cancels an in-flight connectivity test if the queued
+// DPC has a strictly higher priority than the DPC currently being tested.
+// Without this, the run loop would first finish a potentially lengthy test
+// of the older configuration before even looking at the new one.
+func (m *DpcManager) maybeInterruptConnTest(dpc types.DevicePortConfig) {
+	m.connTestMx.Lock()
+	defer m.connTestMx.Unlock()
+	if m.connTestCancel == nil {
+		return
+	}
+	if dpc.TimePriority.After(m.connTestDpcPrio) {
+		m.Log.Noticef("Interrupting connectivity test of DPC with priority %v - "+
+			"DPC with higher priority (%s/%v) was queued",
+			m.connTestDpcPrio, dpc.Key, dpc.TimePriority)
+		m.connTestCancel()
+		m.connTestCancel = nil
+	}
+}
+
 // DelDPC : remove DPC from the list of configurations to work with.
 func (m *DpcManager) DelDPC(dpc types.DevicePortConfig) {
 	m.inputCommands <- inputCommand{
@@ -458,6 +741,51 @@ func (m *DpcManager) UpdateRadioSilence(rs types.RadioSilence) {
 	}
 }
 
+// UpdateMgmtTrafficPin : apply an operator request to temporarily pin all
+// management traffic to a single port (see types.MgmtTrafficPin).
+func (m *DpcManager) UpdateMgmtTrafficPin(pin types.MgmtTrafficPin) {
+	m.inputCommands <- inputCommand{
+		cmd:     commandUpdateMgmtPin,
+		mgmtPin: pin,
+	}
+}
+
+// RequestCableTest : apply an operator request to run an on-demand Ethernet
+// cable test on a port (see types.CableTestRequest).
+func (m *DpcManager) RequestCableTest(request types.CableTestRequest) {
+	m.inputCommands <- inputCommand{
+		cmd:       commandCableTest,
+		cableTest: request,
+	}
+}
+
+// RequestSpeedTest : apply an operator request to run an on-demand throughput
+// measurement on one or all uplink ports (see types.SpeedTestRequest).
+func (m *DpcManager) RequestSpeedTest(request types.SpeedTestRequest) {
+	m.inputCommands <- inputCommand{
+		cmd:       commandSpeedTest,
+		speedTest: request,
+	}
+}
+
+// RequestDPCRetest : apply an operator request to immediately re-run
+// the connectivity verification of the DPC list (see types.DPCRetestRequest).
+func (m *DpcManager) RequestDPCRetest(request types.DPCRetestRequest) {
+	m.inputCommands <- inputCommand{
+		cmd:       commandDPCRetest,
+		dpcRetest: request,
+	}
+}
+
+// UpdateDPCPin : apply an operator request to temporarily switch to a specific
+// entry of the DPC list (see types.DPCPinRequest).
+func (m *DpcManager) UpdateDPCPin(pin types.DPCPinRequest) {
+	m.inputCommands <- inputCommand{
+		cmd:    commandUpdateDPCPin,
+		dpcPin: pin,
+	}
+}
+
 // UpdateDevUUID : apply an update of the UUID assigned to the device by the controller.
 func (m *DpcManager) UpdateDevUUID(devUUID uuid.UUID) {
 	m.inputCommands <- inputCommand{
@@ -500,6 +828,38 @@ func (m *DpcManager) doUpdateGCP(ctx context.Context, gcp types.ConfigItemValueM
 	fallbackAnyEth := m.globalCfg.GlobalValueTriState(types.NetworkFallbackAnyEth)
 	m.enableLastResort = fallbackAnyEth == types.TS_ENABLED
 
+	sharedLabels, err := types.ParseSharedPortLabels(
+		m.globalCfg.GlobalValueString(types.NetworkSharedPortLabels))
+	if err != nil {
+		// Keep the previously applied labels.
+		m.Log.Errorf("doUpdateGCP: ignoring invalid %s: %v",
+			types.NetworkSharedPortLabels, err)
+	} else if !generics.EqualLists(m.sharedPortLabels, sharedLabels) {
+		m.sharedPortLabels = sharedLabels
+		m.updateDNS()
+	}
+
+	maintWindowStart := int(m.globalCfg.GlobalValueInt(types.NetworkMaintWindowStart))
+	maintWindowDuration := time.Second *
+		time.Duration(m.globalCfg.GlobalValueInt(types.NetworkMaintWindowDuration))
+	maintWindowOverride := m.globalCfg.GlobalValueBool(types.NetworkMaintWindowOverride)
+	if m.maintWindowStartHour != maintWindowStart ||
+		m.maintWindowDuration != maintWindowDuration ||
+		m.maintWindowOverride != maintWindowOverride {
+		m.maintWindowStartHour = maintWindowStart
+		m.maintWindowDuration = maintWindowDuration
+		m.maintWindowOverride = maintWindowOverride
+		m.reevaluateMaintWindow(ctx)
+	}
+
+	abTestEnabled := m.globalCfg.GlobalValueBool(types.NetworkABTestingEnable)
+	if m.abTestEnabled != abTestEnabled {
+		m.abTestEnabled = abTestEnabled
+		if !abTestEnabled && m.abTestDpc != nil {
+			m.abortABTest(ctx, "A/B testing was disabled by configuration")
+		}
+	}
+
 	if m.dpcTestInterval != testInterval {
 		if testInterval == 0 {
 			m.Log.Warn("NOT running TestTimer")
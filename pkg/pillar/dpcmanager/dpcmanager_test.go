@@ -44,6 +44,7 @@ var (
 	pubDummyDPC    pubsub.Publication // for logging
 	pubDPCList     pubsub.Publication
 	pubDNS         pubsub.Publication
+	pubNetReady    pubsub.Publication
 )
 
 func initTest(test *testing.T) *GomegaWithT {
@@ -82,6 +83,14 @@ func initTest(test *testing.T) *GomegaWithT {
 	if err != nil {
 		log.Fatal(err)
 	}
+	pubNetReady, err = ps.NewPublication(
+		pubsub.PublicationOptions{
+			AgentName: "test",
+			TopicType: types.NetworkReadiness{},
+		})
+	if err != nil {
+		log.Fatal(err)
+	}
 	networkMonitor = &netmonitor.MockNetworkMonitor{
 		Log:    logObj,
 		MainRT: syscall.RT_TABLE_MAIN,
@@ -108,6 +117,7 @@ func initTest(test *testing.T) *GomegaWithT {
 		PubDummyDevicePortConfig: pubDummyDPC,
 		PubDevicePortConfigList:  pubDPCList,
 		PubDeviceNetworkStatus:   pubDNS,
+		PubNetworkReadiness:      pubNetReady,
 		ZedcloudMetrics:          zedcloud.NewAgentMetrics(),
 	}
 	ctx := reconciler.MockRun(context.Background())
@@ -730,8 +740,9 @@ func TestSingleDPC(test *testing.T) {
 	t.Expect(dpcList[0].TimePriority.Equal(timePrio1)).To(BeTrue())
 	t.Expect(dpcList[0].State).To(Equal(types.DPCStateFail))
 	t.Expect(dpcList[0].LastFailed.After(dpcList[0].LastSucceeded)).To(BeTrue())
-	t.Expect(dpcList[0].LastError).To(Equal("not enough working ports (0); failed with: " +
-		"[interface eth0: no suitable IP address available]"))
+	t.Expect(dpcList[0].LastError).To(Equal("DHCP_TIMEOUT: not enough working ports (0); " +
+		"failed with: [interface eth0: no suitable IP address available]"))
+	t.Expect(dpcList[0].LastErrorCode).To(Equal(types.NetworkErrorCodeDHCPTimeout))
 
 	// Simulate the interface obtaining the IP address back after a while.
 	time.Sleep(5 * time.Second)
@@ -1063,6 +1074,11 @@ func TestDNS(test *testing.T) {
 	t.Expect(eth1State.Dhcp).To(BeEquivalentTo(types.DhcpTypeClient))
 	t.Expect(eth1State.DefaultRouters).To(HaveLen(1))
 	t.Expect(eth1State.DefaultRouters[0].String()).To(Equal("172.20.1.1"))
+	// eth0 is usable for management and therefore has no skip explanation,
+	// while eth1 failed the connectivity test.
+	t.Expect(dns.MgmtPortExplanations).ToNot(HaveKey("mock-eth0"))
+	t.Expect(dns.MgmtPortExplanations).To(HaveKeyWithValue("mock-eth1",
+		"connectivity test failed: failed to connect over eth1"))
 }
 
 func TestWireless(test *testing.T) {
@@ -1315,7 +1331,9 @@ func TestDPCWithAssignedInterface(test *testing.T) {
 	t.Expect(dpcList[0].TimePriority.Equal(timePrio1)).To(BeTrue())
 	t.Expect(dpcList[0].State).To(Equal(types.DPCStateFail))
 	t.Expect(dpcList[0].LastFailed.After(dpcList[0].LastSucceeded)).To(BeTrue())
-	t.Expect(dpcList[0].LastError).To(Equal("port eth1 in PCIBack is used by ccf4c2f8-1d0f-4b44-b55a-220f7a138f6d"))
+	t.Expect(dpcList[0].LastError).To(Equal(
+		"PORT_IN_PCIBACK: port eth1 in PCIBack is used by ccf4c2f8-1d0f-4b44-b55a-220f7a138f6d"))
+	t.Expect(dpcList[0].LastErrorCode).To(Equal(types.NetworkErrorCodePortInPCIBack))
 
 	// eth1 was released from the application but it is still in PCIBack.
 	aa.IoBundleList[1].UsedByUUID = uuid.UUID{}
@@ -1837,3 +1855,114 @@ func TestOldDPC(test *testing.T) {
 	eth0Dhcpcd := dg.Reference(generic.Dhcpcd{AdapterIfName: "eth0"})
 	t.Expect(itemIsCreated(eth0Dhcpcd)).To(BeTrue())
 }
+
+func TestOperatorDPCRetestAndPin(test *testing.T) {
+	t := initTest(test)
+	t.Expect(dpcManager.GetDNS().DPCKey).To(BeEmpty())
+
+	// Prepare simulated network stack.
+	eth0 := mockEth0()
+	networkMonitor.AddOrUpdateInterface(eth0)
+
+	// Apply global config first.
+	dpcManager.UpdateGCP(globalConfigWithLastresort())
+
+	// Apply "lastresort" DPC with single ethernet port.
+	aa := makeAA(selectedIntfs{eth0: true})
+	timePrio1 := time.Time{} // zero timestamp for lastresort
+	dpc := makeDPC("lastresort", timePrio1, selectedIntfs{eth0: true})
+	dpcManager.UpdateAA(aa)
+	dpcManager.AddDPC(dpc)
+
+	t.Eventually(dpcIdxCb()).Should(Equal(0))
+	t.Eventually(dpcStateCb(0)).Should(Equal(types.DPCStateSuccess))
+
+	// Add a higher-priority DPC from the controller; both entries work.
+	timePrio2 := time.Now()
+	dpc = makeDPC("zedagent", timePrio2, selectedIntfs{eth0: true})
+	dpcManager.AddDPC(dpc)
+
+	t.Eventually(testingInProgressCb()).Should(BeTrue())
+	t.Eventually(testingInProgressCb()).Should(BeFalse())
+	t.Eventually(dpcIdxCb()).Should(Equal(0))
+	t.Eventually(dpcKeyCb(0)).Should(Equal("zedagent"))
+	t.Eventually(dnsKeyCb()).Should(Equal("zedagent"))
+
+	// Operator requests an immediate retest - the verification should run
+	// right away and the execution time should be reported back via DNS.
+	retestAt := time.Now()
+	dpcManager.RequestDPCRetest(types.DPCRetestRequest{RequestedAt: retestAt})
+
+	t.Eventually(func() bool {
+		dns := getDNS()
+		return !dns.LastDPCRetest.Before(retestAt) && !dns.Testing
+	}).Should(BeTrue())
+	t.Expect(getDPC(0).State).To(Equal(types.DPCStateSuccess))
+	t.Expect(getDNS().CurrentIndex).To(Equal(0))
+
+	// Operator pins the device to the lastresort entry (index 1).
+	pinReqAt := time.Now()
+	dpcManager.UpdateDPCPin(types.DPCPinRequest{
+		Index:       1,
+		Duration:    10 * time.Second,
+		RequestedAt: pinReqAt,
+	})
+
+	t.Eventually(dpcIdxCb()).Should(Equal(1))
+	t.Eventually(dnsKeyCb()).Should(Equal("lastresort"))
+	t.Eventually(func() string { return getDNS().PinnedDpcKey }).
+		Should(Equal("lastresort"))
+	t.Expect(getDNS().PinnedDpcUntil.After(pinReqAt)).To(BeTrue())
+
+	// Despite the working higher-priority entry, the device stays
+	// on the pinned DPC.
+	t.Consistently(dpcIdxCb()).Should(Equal(1))
+
+	// After the TTL elapses, the pin is automatically cleared and the device
+	// returns to the standard priority-based selection.
+	t.Eventually(func() string { return getDNS().PinnedDpcKey }).Should(BeEmpty())
+	t.Eventually(dpcIdxCb()).Should(Equal(0))
+	t.Eventually(dnsKeyCb()).Should(Equal("zedagent"))
+	t.Eventually(dpcStateCb(0)).Should(Equal(types.DPCStateSuccess))
+}
+
+func TestSharedPortLabelsFromGlobalConfig(test *testing.T) {
+	t := initTest(test)
+
+	// Prepare simulated network stack.
+	eth0 := mockEth0()
+	networkMonitor.AddOrUpdateInterface(eth0)
+
+	// Apply global config with a controller-defined shared port label.
+	gcp := globalConfig()
+	gcp.SetGlobalValueString(types.NetworkSharedPortLabels,
+		`[{"Label": "all-ethernets", "MaxCost": 255, "Media": 1, `+
+			`"LogicallabelPattern": "mock-eth[0-9]+"}]`)
+	dpcManager.UpdateGCP(gcp)
+
+	// Apply DPC with single ethernet port.
+	aa := makeAA(selectedIntfs{eth0: true})
+	timePrio1 := time.Now()
+	dpc := makeDPC("zedagent", timePrio1, selectedIntfs{eth0: true})
+	dpcManager.UpdateAA(aa)
+	dpcManager.AddDPC(dpc)
+	t.Eventually(dpcStateCb(0)).Should(Equal(types.DPCStateSuccess))
+
+	// The controller-defined label is published as part of DNS
+	// and resolves to the set of matched ports.
+	dns := getDNS()
+	t.Expect(dns.SharedPortLabels).To(HaveLen(1))
+	t.Expect(dns.SharedPortLabels[0].Label).To(Equal("all-ethernets"))
+	ports := dns.GetPortsByLogicallabel("all-ethernets")
+	t.Expect(ports).To(HaveLen(1))
+	t.Expect(ports[0].Logicallabel).To(Equal("mock-eth0"))
+
+	// An invalid set of labels is ignored, the previously applied labels
+	// are kept.
+	gcp.SetGlobalValueString(types.NetworkSharedPortLabels,
+		`[{"Label": "uplink"}]`)
+	dpcManager.UpdateGCP(gcp)
+	t.Consistently(func() []types.SharedPortLabel {
+		return getDNS().SharedPortLabels
+	}).Should(HaveLen(1))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// Operator-triggered control over the DPC selection. Normally the testing
+// cadence is purely timer-driven and the index of the applied DPC cannot
+// be influenced remotely. For troubleshooting purposes the operator can
+// (via ZedAgentStatus):
+//   - request an immediate re-run of the connectivity verification,
+//     starting from the highest-priority DPC (e.g. right after fixing
+//     an external problem, instead of waiting for the test timer),
+//   - temporarily pin the device to a specific entry of the published
+//     DPC list, suspending the automatic priority-based selection
+//     and fallback.
+//
+// The pin is bounded in time and automatically reverts to the standard
+// DPC selection when it expires. The pinned entry and the expiration time
+// are published in DeviceNetworkStatus, while the test results are reported
+// back via the published DevicePortConfigList.
+
+// maxDPCPinDuration bounds the time window for which the device can be
+// pinned to a single DPC list entry. Zero duration as well as values above
+// the limit are clamped to this maximum.
+const maxDPCPinDuration = time.Hour
+
+// doDPCRetest executes an operator request for an immediate re-run
+// of the connectivity verification of the DPC list.
+func (m *DpcManager) doDPCRetest(ctx context.Context, request types.DPCRetestRequest) {
+	if !request.RequestedAt.After(m.dpcRetestReqTime) {
+		return
+	}
+	m.dpcRetestReqTime = request.RequestedAt
+	if m.dpcVerify.inProgress {
+		m.Log.Noticef("Ignoring operator-requested DPC retest - " +
+			"verification is already in progress")
+		return
+	}
+	m.dpcRetestAt = time.Now()
+	m.restartVerify(ctx, "operator-requested retest")
+}
+
+// doUpdateDPCPin applies an operator request to pin the device to a specific
+// entry of the DPC list or to clear an active pin (negative index).
+func (m *DpcManager) doUpdateDPCPin(ctx context.Context, pin types.DPCPinRequest) {
+	if !pin.RequestedAt.After(m.dpcPinConfig.RequestedAt) {
+		return
+	}
+	m.dpcPinConfig = pin
+	if pin.Index < 0 {
+		if m.dpcPinDpc != nil {
+			m.Log.Noticef("Clearing pin to DPC %s/%v on operator request",
+				m.dpcPinDpc.Key, m.dpcPinDpc.TimePriority)
+			m.clearDPCPin()
+			m.restartVerify(ctx, "DPC pin cleared")
+		}
+		return
+	}
+	if pin.Index >= len(m.dpcList.PortConfigList) {
+		m.Log.Errorf("Ignoring pin to out-of-range DPC index %d "+
+			"(DPC list has %d entries)", pin.Index, len(m.dpcList.PortConfigList))
+		return
+	}
+	duration := pin.Duration
+	if duration == 0 || duration > maxDPCPinDuration {
+		duration = maxDPCPinDuration
+	}
+	dpc := m.dpcList.PortConfigList[pin.Index]
+	m.dpcPinDpc = &dpc
+	m.dpcPinUntil = time.Now().Add(duration)
+	m.dpcPinTimer = time.NewTimer(duration)
+	m.Log.Noticef("Pinning device to DPC %s/%v (index %d) until %v",
+		dpc.Key, dpc.TimePriority, pin.Index, m.dpcPinUntil)
+	// Switch to the pinned DPC (restartVerify only re-verifies the pinned
+	// entry while the pin is active).
+	m.dpcVerify.inProgress = false
+	m.restartVerify(ctx, fmt.Sprintf("operator pinned DPC at index %d", pin.Index))
+	m.updateDNS()
+}
+
+// expireDPCPin returns to the standard priority-based DPC selection when
+// the pin time window elapses.
+func (m *DpcManager) expireDPCPin(ctx context.Context) {
+	if m.dpcPinDpc == nil {
+		return
+	}
+	m.Log.Noticef("Pin to DPC %s/%v expired",
+		m.dpcPinDpc.Key, m.dpcPinDpc.TimePriority)
+	m.clearDPCPin()
+	m.restartVerify(ctx, "DPC pin expired")
+}
+
+// clearDPCPin resets the pin state. It is up to the caller to restart
+// the DPC verification when appropriate.
+func (m *DpcManager) clearDPCPin() {
+	m.dpcPinDpc = nil
+	m.dpcPinUntil = time.Time{}
+	m.dpcPinTimer = &time.Timer{}
+	m.updateDNS()
+}
+
+// pinnedDPCIndex returns the current index of the pinned DPC list entry,
+// or -1 if no pin is active or the pinned entry is no longer in the list.
+func (m *DpcManager) pinnedDPCIndex() int {
+	if m.dpcPinDpc == nil {
+		return -1
+	}
+	_, index := m.lookupDPC(*m.dpcPinDpc)
+	return index
+}
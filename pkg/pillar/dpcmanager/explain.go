@@ -0,0 +1,92 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// updateMgmtPortExplanations re-generates DeviceNetworkStatus.MgmtPortExplanations,
+// recording for every port which is currently not used to carry management
+// traffic a human-readable explanation of why it was skipped.
+// Called from updateDNS to cover all events potentially affecting the port
+// selection (connectivity test results, link and address changes, radio-silence,
+// management traffic pinning, etc.).
+func (m *DpcManager) updateMgmtPortExplanations() {
+	ports := m.deviceNetStatus.Ports
+	explanations := make(map[string]string, len(ports))
+	usable := make([]*types.NetworkPortStatus, 0, len(ports))
+	for ix := range ports {
+		port := &ports[ix]
+		if reason := m.explainSkippedMgmtPort(port); reason != "" {
+			explanations[port.Logicallabel] = reason
+			continue
+		}
+		usable = append(usable, port)
+	}
+	// Among the usable management ports, the lower-cost ones are preferred
+	// for the controller communication and ports with a higher cost are kept
+	// on standby.
+	lowestCost := types.PortCostMax
+	for _, port := range usable {
+		if port.Cost < lowestCost {
+			lowestCost = port.Cost
+		}
+	}
+	for _, port := range usable {
+		if port.Cost > lowestCost {
+			explanations[port.Logicallabel] = fmt.Sprintf(
+				"on standby: higher cost (%d) than the preferred port(s) "+
+					"(cost %d)", port.Cost, lowestCost)
+		}
+	}
+	m.deviceNetStatus.MgmtPortExplanations = explanations
+}
+
+// explainSkippedMgmtPort returns a non-empty explanation if the given port
+// cannot currently carry management traffic at all. The preference between
+// usable ports (given by the port cost) is evaluated separately
+// by updateMgmtPortExplanations.
+func (m *DpcManager) explainSkippedMgmtPort(port *types.NetworkPortStatus) string {
+	if !port.IsMgmt {
+		return "not configured for management"
+	}
+	if port.InvalidConfig {
+		return "invalid port configuration: " + port.LastError
+	}
+	ioBundle := m.adapters.LookupIoBundleLogicallabel(port.Logicallabel)
+	if ioBundle != nil && ioBundle.IsPCIBack {
+		return "assigned to PCIBack (directly attached to an application)"
+	}
+	if m.rsStatus.Imposed && port.WirelessCfg.WType != types.WirelessTypeNone {
+		return "radio-silence mode is imposed"
+	}
+	if m.mgmtPinPort != "" && m.mgmtPinPort != port.Logicallabel {
+		return fmt.Sprintf("management traffic is pinned to port %s",
+			m.mgmtPinPort)
+	}
+	if port.IfName == "" {
+		return "underlying network interface is not (yet) known"
+	}
+	if !port.Up {
+		return "interface is without a carrier (link is down)"
+	}
+	var hasIP bool
+	for _, addr := range port.AddrInfoList {
+		if addr.Addr.IsLinkLocalUnicast() {
+			continue
+		}
+		hasIP = true
+		break
+	}
+	if !hasIP {
+		return "no usable IP address assigned"
+	}
+	if port.LastError != "" && port.LastFailed.After(port.LastSucceeded) {
+		return "connectivity test failed: " + port.LastError
+	}
+	return ""
+}
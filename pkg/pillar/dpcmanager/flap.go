@@ -0,0 +1,131 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Detection and dampening of interface carrier flapping.
+// A flapping ethernet link (e.g. due to bad cabling) would otherwise cause
+// repeated full connectivity re-verifications and log storms. When too many
+// carrier transitions are observed on a port within a short time window,
+// reactions to further carrier changes of that port are held down until
+// the link stabilizes. The total number of carrier transitions is published
+// as part of the port status so that the controller can flag bad cabling.
+
+const (
+	// carrierFlapCountThreshold : number of carrier transitions within
+	// carrierFlapDetectWindow at which the port is declared as flapping.
+	carrierFlapCountThreshold = 4
+	// carrierFlapDetectWindow : time window within which carrier transitions
+	// are counted towards flap detection.
+	carrierFlapDetectWindow = time.Minute
+	// carrierHoldDownPeriod : for how long reactions to carrier changes
+	// of a flapping port are dampened.
+	carrierHoldDownPeriod = 2 * time.Minute
+)
+
+// carrierFlapState : per-port state of carrier flap detection.
+type carrierFlapState struct {
+	// Timestamps of recent carrier transitions
+	// (limited to carrierFlapDetectWindow).
+	transitions []time.Time
+	// Total number of carrier transitions observed on the port.
+	flapCount uint32
+	// True if reactions to carrier changes of the port are being dampened.
+	heldDown bool
+	// Time when the hold-down period elapses.
+	holdDownEnd time.Time
+	// True if a carrier change was suppressed during the hold-down period
+	// and connectivity verification should be restarted once it expires.
+	pendingVerify bool
+}
+
+// recordCarrierTransition records a carrier (link up/down) transition
+// of the given port and returns true if reactions to the change should
+// be dampened because the port carrier is flapping.
+func (m *DpcManager) recordCarrierTransition(ifName string) (dampen bool) {
+	now := time.Now()
+	state := m.carrierFlaps[ifName]
+	if state == nil {
+		state = &carrierFlapState{}
+		m.carrierFlaps[ifName] = state
+	}
+	state.flapCount++
+	state.transitions = append(state.transitions, now)
+	// Drop transitions which are outside of the detection window.
+	var recent []time.Time
+	for _, transition := range state.transitions {
+		if now.Sub(transition) <= carrierFlapDetectWindow {
+			recent = append(recent, transition)
+		}
+	}
+	state.transitions = recent
+	if state.heldDown {
+		if now.Before(state.holdDownEnd) {
+			state.pendingVerify = true
+			return true
+		}
+		state.heldDown = false
+	}
+	if len(state.transitions) >= carrierFlapCountThreshold {
+		m.Log.Warnf("Carrier of port %s is flapping (%d transitions in "+
+			"the last %v) - dampening reactions for %v", ifName,
+			len(state.transitions), carrierFlapDetectWindow,
+			carrierHoldDownPeriod)
+		state.heldDown = true
+		state.holdDownEnd = now.Add(carrierHoldDownPeriod)
+		state.pendingVerify = true
+		m.armFlapHoldDownTimer()
+		return true
+	}
+	return false
+}
+
+// processFlapHoldDownTimer releases ports whose hold-down period has elapsed
+// and restarts connectivity verification if any carrier change was suppressed
+// in the meantime.
+func (m *DpcManager) processFlapHoldDownTimer(ctx context.Context) {
+	now := time.Now()
+	var restartReasons []string
+	for ifName, state := range m.carrierFlaps {
+		if !state.heldDown || now.Before(state.holdDownEnd) {
+			continue
+		}
+		state.heldDown = false
+		if state.pendingVerify {
+			state.pendingVerify = false
+			restartReasons = append(restartReasons,
+				fmt.Sprintf("carrier flap hold-down for port %s expired", ifName))
+		}
+	}
+	if len(restartReasons) > 0 {
+		m.restartVerify(ctx, restartReasons[0])
+		m.updateDNS()
+	}
+	m.armFlapHoldDownTimer()
+}
+
+// armFlapHoldDownTimer (re-)arms the hold-down timer to fire when the earliest
+// hold-down period among the currently dampened ports elapses.
+func (m *DpcManager) armFlapHoldDownTimer() {
+	var earliest time.Time
+	for _, state := range m.carrierFlaps {
+		if !state.heldDown {
+			continue
+		}
+		if earliest.IsZero() || state.holdDownEnd.Before(earliest) {
+			earliest = state.holdDownEnd
+		}
+	}
+	if earliest.IsZero() {
+		// Keep the timer inactive.
+		m.flapHoldDownTimer = &time.Timer{}
+		return
+	}
+	m.flapHoldDownTimer = time.NewTimer(time.Until(earliest))
+}
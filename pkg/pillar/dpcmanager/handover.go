@@ -0,0 +1,230 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/conntester"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// When a port moves between app direct-assignment and management usage,
+// the controller submits a new DPC which differs from the current one only
+// by the set of ports - the handed-over port is removed from (or added back
+// into) the configuration while all the other ports stay unchanged.
+// Running the standard verification for such a DPC re-tests the whole list
+// from index 0, which needlessly disturbs the unaffected uplinks and risks
+// a controller-connection blip. Instead, the new DPC is applied incrementally:
+// the reconciler tears down / creates only the affected port's configuration
+// items, the test results of the unchanged ports are inherited from the
+// current, known-to-work DPC, and only the connectivity of the added ports
+// is re-verified (restricted connectivity test, just like during A/B testing).
+// Should the re-verification of an added port repeatedly fail, the standard
+// full verification takes over as a fallback.
+
+// tryPortHandover checks if the newly received DPC differs from the currently
+// applied and working configuration only by the set of ports and, if so,
+// applies it incrementally without restarting the full DPC verification.
+// Returns true if the DPC was handled here.
+func (m *DpcManager) tryPortHandover(
+	ctx context.Context, dpc types.DevicePortConfig) bool {
+	if m.dpcTestDuration == 0 {
+		return false
+	}
+	if m.dpcVerify.inProgress || m.abTestDpc != nil {
+		return false
+	}
+	if m.rsStatus.ChangeInProgress || m.rsStatus.Imposed {
+		return false
+	}
+	curDPC := m.currentDPC()
+	if curDPC == nil || curDPC.State != types.DPCStateSuccess ||
+		m.dpcList.CurrentIndex != 0 {
+		// Without a working configuration at the top of the list there is
+		// nothing to hand over from - let the standard verification handle
+		// the new DPC.
+		return false
+	}
+	if dpc.Key != curDPC.Key || !dpc.TimePriority.After(curDPC.TimePriority) {
+		return false
+	}
+	addedPorts, removedPorts, onlyPortSetDiff := diffDPCPortSets(curDPC, &dpc)
+	if !onlyPortSetDiff || len(addedPorts)+len(removedPorts) == 0 {
+		return false
+	}
+	// At least one unchanged management port must be working, otherwise
+	// the controller connectivity depends on the added ports and the full
+	// verification is the safer path.
+	var hasWorkingMgmtPort bool
+	for i := range curDPC.Ports {
+		port := &curDPC.Ports[i]
+		if !port.IsMgmt || dpc.LookupPortByLogicallabel(port.Logicallabel) == nil {
+			continue
+		}
+		if !port.LastSucceeded.IsZero() && !port.LastFailed.After(port.LastSucceeded) {
+			hasWorkingMgmtPort = true
+			break
+		}
+	}
+	if !hasWorkingMgmtPort {
+		return false
+	}
+
+	m.Log.Noticef("Port handover: applying DPC (%s/%v) incrementally; "+
+		"added ports: %v, removed ports: %v",
+		dpc.Key, dpc.TimePriority, addedPorts, removedPorts)
+
+	// Inherit the test results from the current DPC - the unchanged ports
+	// keep running the exact same configuration.
+	dpc.TestResults = curDPC.TestResults
+	dpc.LastIPAndDNS = curDPC.LastIPAndDNS
+	dpc.State = types.DPCStateSuccess
+	for i := range dpc.Ports {
+		prevPort := curDPC.LookupPortByLogicallabel(dpc.Ports[i].Logicallabel)
+		if prevPort != nil {
+			dpc.Ports[i].TestResults = prevPort.TestResults
+		}
+	}
+	m.updateDPCListAndPublish(dpc, false)
+	// The new DPC has the highest priority and therefore sits at index 0.
+	m.dpcList.CurrentIndex = 0
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	m.updateDNS()
+
+	// Re-verify connectivity of the added management ports only.
+	// Ports of a pending handover re-test which are still present in the new
+	// DPC remain scheduled.
+	var testPorts []string
+	for _, label := range m.handoverPorts {
+		if dpc.LookupPortByLogicallabel(label) != nil {
+			testPorts = append(testPorts, label)
+		}
+	}
+	for _, label := range addedPorts {
+		port := dpc.LookupPortByLogicallabel(label)
+		if port != nil && port.IsMgmt {
+			testPorts = append(testPorts, label)
+		}
+	}
+	m.handoverPorts = testPorts
+	if len(m.handoverPorts) > 0 {
+		m.handoverSince = time.Now()
+		m.handoverTimer = time.NewTimer(m.dpcTestDuration)
+	}
+	m.compressAndPublishDPCL()
+	return true
+}
+
+// diffDPCPortSets compares ports of the two DPCs by their logical labels.
+// Returns the labels of ports present only in the new DPC (added) and only
+// in the current DPC (removed). onlyPortSetDiff is false if any port present
+// in both DPCs has a changed configuration, i.e. the difference between the
+// DPCs goes beyond the port sets.
+func diffDPCPortSets(curDPC, newDPC *types.DevicePortConfig) (
+	addedPorts, removedPorts []string, onlyPortSetDiff bool) {
+	for i := range newDPC.Ports {
+		newPort := &newDPC.Ports[i]
+		curPort := curDPC.LookupPortByLogicallabel(newPort.Logicallabel)
+		if curPort == nil {
+			addedPorts = append(addedPorts, newPort.Logicallabel)
+			continue
+		}
+		if !curPort.MostlyEqual(newPort) {
+			return nil, nil, false
+		}
+	}
+	for i := range curDPC.Ports {
+		curPort := &curDPC.Ports[i]
+		if newDPC.LookupPortByLogicallabel(curPort.Logicallabel) == nil {
+			removedPorts = append(removedPorts, curPort.Logicallabel)
+		}
+	}
+	return addedPorts, removedPorts, true
+}
+
+// runPortHandoverTest checks the controller connectivity over the ports added
+// by an incremental port handover. Called whenever handoverTimer fires.
+func (m *DpcManager) runPortHandoverTest(ctx context.Context) {
+	if len(m.handoverPorts) == 0 {
+		return
+	}
+	dpc := m.currentDPC()
+	if dpc == nil || m.dpcVerify.inProgress {
+		m.clearPortHandoverState()
+		return
+	}
+	// Re-run reconciliation first - the handed-over port may have just
+	// returned from PCIBack and its configuration items might have been
+	// pending on the interface presence.
+	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
+	m.updateDNS()
+	testDNS := m.handoverTestDNS()
+	var err error
+	if len(testDNS.Ports) > 0 {
+		var intfStatusMap types.IntfStatusMap
+		intfStatusMap, _, err = m.ConnTester.TestConnectivity(ctx, testDNS, false)
+		dpc.UpdatePortStatusFromIntfStatusMap(intfStatusMap)
+		m.deviceNetStatus.UpdatePortStatusFromIntfStatusMap(intfStatusMap)
+	} else {
+		err = fmt.Errorf("ports %v are not yet present", m.handoverPorts)
+	}
+	_, rtf := err.(*conntester.RemoteTemporaryFailure)
+	if err == nil || rtf {
+		// The handed-over ports were able to reach the controller
+		// (remote temporary failure still means that the controller was
+		// reachable over them).
+		m.Log.Noticef("Port handover: connectivity over ports %v verified",
+			m.handoverPorts)
+		m.clearPortHandoverState()
+		m.publishDPCL()
+		m.publishDNS()
+		return
+	}
+	elapsed := time.Since(m.handoverSince)
+	if elapsed < waitForIPDNSRetries*m.dpcTestDuration {
+		m.Log.Noticef("Port handover: connectivity test over ports %v "+
+			"has not succeeded yet (%v); will retry", m.handoverPorts, err)
+		m.handoverTimer = time.NewTimer(m.dpcTestDuration)
+		return
+	}
+	// The handed-over ports repeatedly failed the connectivity test.
+	// Fall back to the standard full verification.
+	m.Log.Errorf("Port handover: connectivity test over ports %v failed: %v; "+
+		"falling back to the full DPC verification", m.handoverPorts, err)
+	for _, label := range m.handoverPorts {
+		port := dpc.LookupPortByLogicallabel(label)
+		if port != nil && port.IfName != "" {
+			dpc.RecordPortFailure(port.IfName, err.Error())
+		}
+	}
+	m.clearPortHandoverState()
+	m.restartVerify(ctx, "handed-over port failed connectivity test")
+}
+
+// handoverTestDNS builds the network status restricted to the handed-over
+// ports, used as the input for their connectivity re-test.
+func (m *DpcManager) handoverTestDNS() types.DeviceNetworkStatus {
+	dns := types.DeviceNetworkStatus{
+		DPCKey:  m.deviceNetStatus.DPCKey,
+		Version: m.deviceNetStatus.Version,
+	}
+	for _, port := range m.deviceNetStatus.Ports {
+		for _, label := range m.handoverPorts {
+			if port.Logicallabel == label {
+				dns.Ports = append(dns.Ports, port)
+				break
+			}
+		}
+	}
+	return dns
+}
+
+func (m *DpcManager) clearPortHandoverState() {
+	m.handoverPorts = nil
+	m.handoverSince = time.Time{}
+	m.handoverTimer = &time.Timer{}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// This file implements recording of time-to-connectivity KPIs for DPC
+// applications (see types.DPCApplicationKPIs). For every run of the DPC
+// verification, DpcManager records when the applied DPC was received,
+// when the config reconciliation (fully) completed, when a management port
+// obtained a usable IP address and when the controller connectivity was
+// verified. The recorded KPIs are published via PubDPCMetrics.
+
+// kpiRecordDpcReceived remembers the time when the given DPC was received
+// by DpcManager (or loaded from persisted storage after boot), so that
+// latencies of a later application of this DPC can be measured from this
+// point in time.
+func (m *DpcManager) kpiRecordDpcReceived(dpc types.DevicePortConfig) {
+	if m.dpcReceivedAt == nil {
+		m.dpcReceivedAt = make(map[string]time.Time)
+	}
+	if _, haveTimestamp := m.dpcReceivedAt[dpc.PubKey()]; haveTimestamp {
+		// Repeated publication of the same DPC - keep the original timestamp.
+		return
+	}
+	m.dpcReceivedAt[dpc.PubKey()] = time.Now()
+}
+
+// kpiForgetDpc drops the receive timestamp of a removed DPC.
+func (m *DpcManager) kpiForgetDpc(dpc types.DevicePortConfig) {
+	delete(m.dpcReceivedAt, dpc.PubKey())
+}
+
+// kpiStartDpcApplication opens a new KPI record for an application of the
+// given DPC, started by setupVerify. KPIs of the previously recorded
+// application (if any) are finalized as they are.
+func (m *DpcManager) kpiStartDpcApplication(dpc types.DevicePortConfig) {
+	receivedAt := m.dpcReceivedAt[dpc.PubKey()]
+	if receivedAt.IsZero() {
+		// DPC receive time is not known (e.g. KPI recording started after
+		// the DPC was received) - measure latencies from this point at least.
+		receivedAt = time.Now()
+	}
+	m.dpcMetrics.AddKPIs(types.DPCApplicationKPIs{
+		DPCKey:          dpc.Key,
+		DPCTimePriority: dpc.TimePriority,
+		ReceivedAt:      receivedAt,
+	})
+	m.publishDpcMetrics()
+}
+
+// kpiRecordDpcProgress updates the KPI record of the latest DPC application
+// based on the outcome of a single round of DPC verification.
+// Milestone timestamps are recorded only once - when the milestone is reached
+// for the first time.
+func (m *DpcManager) kpiRecordDpcProgress(dpc *types.DevicePortConfig,
+	verifyStatus types.DPCState) {
+	if len(m.dpcMetrics.AppliedKPIs) == 0 {
+		return
+	}
+	kpis := &m.dpcMetrics.AppliedKPIs[0]
+	if kpis.DPCKey != dpc.Key || !kpis.DPCTimePriority.Equal(dpc.TimePriority) {
+		// The latest KPI record is for a different DPC (e.g. verification
+		// ended with a lower-priority DPC after interruption).
+		return
+	}
+	var changed bool
+	now := time.Now()
+	if kpis.AppliedAt.IsZero() && !m.reconcileStatus.AsyncInProgress &&
+		verifyStatus != types.DPCStateAsyncWait {
+		kpis.AppliedAt = now
+		changed = true
+	}
+	if kpis.IPAcquiredAt.IsZero() && m.checkIfMgmtPortsHaveIPandDNS() {
+		kpis.IPAcquiredAt = now
+		changed = true
+	}
+	if kpis.VerifiedAt.IsZero() && verifyStatus == types.DPCStateSuccess {
+		kpis.VerifiedAt = now
+		changed = true
+		m.Log.Noticef("DPC KPIs: DPC %s verified; time to apply: %v, "+
+			"time to acquire IP: %v, time to verify: %v", dpc.PubKey(),
+			kpis.TimeToApply(), kpis.TimeToAcquireIP(), kpis.TimeToVerify())
+	}
+	if changed {
+		m.publishDpcMetrics()
+	}
+}
+
+func (m *DpcManager) publishDpcMetrics() {
+	if m.PubDPCMetrics == nil {
+		return
+	}
+	err := m.PubDPCMetrics.Publish(m.dpcMetrics.Key(), m.dpcMetrics)
+	if err != nil {
+		m.Log.Errorf("Failed to publish DPC metrics: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// Disruptive DPC changes, such as a change of an uplink IP configuration
+// or a bond reconfiguration, may temporarily interrupt the connectivity
+// with the controller as well as the traffic of deployed applications.
+// The controller/operator can therefore limit when such changes are applied
+// by configuring a daily maintenance window (config items
+// NetworkMaintWindowStart and NetworkMaintWindowDuration).
+// A newly received DPC classified as disruptive is queued until the window
+// opens, while non-disruptive changes are still applied immediately.
+// NetworkMaintWindowOverride can be set in an emergency to apply the queued
+// DPC right away, without waiting for the window to open.
+// The key of the queued DPC is published as part of DeviceNetworkStatus.
+
+// maintWindowIsOpen returns true if the gating of disruptive DPC changes
+// is disabled or overridden, or if the maintenance window is currently open.
+func (m *DpcManager) maintWindowIsOpen(now time.Time) bool {
+	if m.maintWindowDuration == 0 || m.maintWindowOverride {
+		return true
+	}
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(),
+		m.maintWindowStartHour, 0, 0, 0, time.UTC)
+	if start.After(now) {
+		// The window opening today has not yet started, but one opened
+		// yesterday may still be running.
+		start = start.AddDate(0, 0, -1)
+	}
+	return now.Before(start.Add(m.maintWindowDuration))
+}
+
+// maintWindowNextOpen returns the time when the next maintenance window opens.
+// Should not be called when the gating is disabled (zero window duration).
+func (m *DpcManager) maintWindowNextOpen(now time.Time) time.Time {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(),
+		m.maintWindowStartHour, 0, 0, 0, time.UTC)
+	if start.After(now) {
+		return start
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// isDisruptiveDPCChange classifies the transition from the currently applied
+// DPC to a newly received one. The change is considered disruptive if it
+// removes a port which is currently in use, changes the L3 configuration
+// of a port (e.g. switches an uplink between DHCP and a static IP address),
+// or changes the L2 configuration (bond or VLAN parameters).
+// Changes limited to e.g. proxies, DNS/NTP servers or wireless credentials
+// are considered non-disruptive.
+func (m *DpcManager) isDisruptiveDPCChange(oldDPC, newDPC *types.DevicePortConfig) bool {
+	for i := range oldDPC.Ports {
+		oldPort := &oldDPC.Ports[i]
+		newPort := newDPC.LookupPortByLogicallabel(oldPort.Logicallabel)
+		if newPort == nil {
+			// Port is being removed from the configuration.
+			return true
+		}
+		if oldPort.Dhcp != newPort.Dhcp ||
+			oldPort.AddrSubnet != newPort.AddrSubnet ||
+			!oldPort.Gateway.Equal(newPort.Gateway) {
+			return true
+		}
+		if !reflect.DeepEqual(oldPort.L2LinkConfig, newPort.L2LinkConfig) {
+			return true
+		}
+	}
+	return false
+}
+
+// deferDPCIfDisruptive queues the newly received DPC for the next maintenance
+// window if the change is classified as disruptive and the window is currently
+// closed. Returns true if the DPC was queued.
+func (m *DpcManager) deferDPCIfDisruptive(dpc types.DevicePortConfig) bool {
+	now := time.Now()
+	if m.maintWindowIsOpen(now) {
+		return false
+	}
+	curDPC := m.currentDPC()
+	if curDPC == nil {
+		// Without any DPC applied there is no connectivity to disrupt.
+		return false
+	}
+	if !m.isDisruptiveDPCChange(curDPC, &dpc) {
+		return false
+	}
+	// At most one DPC is queued - a newer config always supersedes an older one.
+	opensAt := m.maintWindowNextOpen(now)
+	m.deferredDpc = &dpc
+	m.deferredSince = now
+	m.maintWindowTimer = time.NewTimer(opensAt.Sub(now))
+	m.Log.Noticef("Deferring disruptive DPC (%s/%v) until the maintenance "+
+		"window opens at %v", dpc.Key, dpc.TimePriority, opensAt)
+	m.updateDNS()
+	return true
+}
+
+// applyDeferredDPC applies the queued DPC, if there is any.
+// Called when the maintenance window opens or when the gating is disabled
+// or overridden by the configuration.
+func (m *DpcManager) applyDeferredDPC(ctx context.Context, reason string) {
+	if m.deferredDpc == nil {
+		return
+	}
+	dpc := *m.deferredDpc
+	m.deferredDpc = nil
+	m.deferredSince = time.Time{}
+	m.maintWindowTimer = &time.Timer{}
+	m.Log.Noticef("Applying deferred DPC (%s/%v): %s",
+		dpc.Key, dpc.TimePriority, reason)
+	m.doAddDPC(ctx, dpc)
+	m.updateDNS()
+}
+
+// dropDeferredDPC forgets the queued DPC without applying it.
+// Called when the DPC is removed before the maintenance window opens.
+func (m *DpcManager) dropDeferredDPC(dpc types.DevicePortConfig) {
+	m.Log.Noticef("Dropping deferred DPC (%s/%v) - removed before it was applied",
+		dpc.Key, dpc.TimePriority)
+	m.deferredDpc = nil
+	m.deferredSince = time.Time{}
+	m.maintWindowTimer = &time.Timer{}
+	m.updateDNS()
+}
+
+// reevaluateMaintWindow is called whenever the maintenance window configuration
+// changes. It either applies the queued DPC right away (window just opened,
+// gating was disabled or the emergency override was set) or reschedules
+// the timer for the new window start.
+func (m *DpcManager) reevaluateMaintWindow(ctx context.Context) {
+	if m.deferredDpc == nil {
+		return
+	}
+	now := time.Now()
+	if m.maintWindowIsOpen(now) {
+		m.applyDeferredDPC(ctx, "maintenance window configuration changed")
+		return
+	}
+	opensAt := m.maintWindowNextOpen(now)
+	m.maintWindowTimer = time.NewTimer(opensAt.Sub(now))
+	m.Log.Noticef("Rescheduled deferred DPC (%s/%v) to the maintenance "+
+		"window opening at %v", m.deferredDpc.Key, m.deferredDpc.TimePriority, opensAt)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// For troubleshooting purposes (e.g. validating a newly installed link before
+// changing port costs), the operator can temporarily pin all management
+// (controller) traffic to a single port, overriding the cost-based uplink
+// selection. The pin is bounded in time and automatically reverts when it
+// expires. The pinned port and the expiration time are published as part
+// of DeviceNetworkStatus and enforced by the management port selection
+// functions (see types.GetMgmtPortsSortedCost).
+
+// maxMgmtPinDuration bounds the time window for which management traffic
+// can be pinned to a single port. Zero duration as well as values above
+// the limit are clamped to this maximum.
+const maxMgmtPinDuration = time.Hour
+
+// doUpdateMgmtTrafficPin applies an operator request to pin management traffic
+// to a single port or to clear an active pin (empty port label).
+func (m *DpcManager) doUpdateMgmtTrafficPin(pin types.MgmtTrafficPin) {
+	if !pin.RequestedAt.After(m.mgmtPinConfig.RequestedAt) {
+		return
+	}
+	m.mgmtPinConfig = pin
+	if pin.PortLabel == "" {
+		if m.mgmtPinPort != "" {
+			m.Log.Noticef("Clearing management traffic pin to port %s "+
+				"on operator request", m.mgmtPinPort)
+			m.clearMgmtTrafficPin()
+		}
+		return
+	}
+	dpc := m.currentDPC()
+	if dpc == nil || dpc.LookupPortByLogicallabel(pin.PortLabel) == nil {
+		m.Log.Errorf("Ignoring management traffic pin to unknown port %s",
+			pin.PortLabel)
+		return
+	}
+	duration := pin.Duration
+	if duration == 0 || duration > maxMgmtPinDuration {
+		duration = maxMgmtPinDuration
+	}
+	m.mgmtPinPort = pin.PortLabel
+	m.mgmtPinUntil = time.Now().Add(duration)
+	m.mgmtPinTimer = time.NewTimer(duration)
+	m.Log.Noticef("Pinning management traffic to port %s until %v",
+		m.mgmtPinPort, m.mgmtPinUntil)
+	m.updateDNS()
+}
+
+// expireMgmtTrafficPin reverts to the cost-based uplink selection when
+// the pin time window elapses.
+func (m *DpcManager) expireMgmtTrafficPin() {
+	if m.mgmtPinPort == "" {
+		return
+	}
+	m.Log.Noticef("Management traffic pin to port %s expired", m.mgmtPinPort)
+	m.clearMgmtTrafficPin()
+}
+
+func (m *DpcManager) clearMgmtTrafficPin() {
+	m.mgmtPinPort = ""
+	m.mgmtPinUntil = time.Time{}
+	m.mgmtPinTimer = &time.Timer{}
+	m.updateDNS()
+}
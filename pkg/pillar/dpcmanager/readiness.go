@@ -0,0 +1,57 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"fmt"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// updateNetworkReadiness re-evaluates the NetworkReadiness status
+// (an explicit "network ready" barrier for other agents, see types package)
+// and publishes it if it differs from the last published instance.
+// Called from updateDNS to cover all events potentially affecting
+// the readiness.
+func (m *DpcManager) updateNetworkReadiness() {
+	readiness := types.NetworkReadiness{
+		MgmtConnectivityVerified: m.dpcVerify.cloudConnWorks,
+	}
+	dpc := m.currentDPC()
+	if dpc != nil {
+		readiness.LastSucceededAt = dpc.LastSucceeded
+	}
+	if m.rsStatus.Imposed {
+		readiness.DegradedReasons = append(readiness.DegradedReasons,
+			"radio-silence mode is imposed")
+	}
+	if m.dpcList.CurrentIndex > 0 {
+		readiness.DegradedReasons = append(readiness.DegradedReasons,
+			fmt.Sprintf("fallback device port configuration is applied "+
+				"(index %d)", m.dpcList.CurrentIndex))
+	}
+	for _, port := range m.deviceNetStatus.Ports {
+		if !port.IsMgmt {
+			continue
+		}
+		if port.LastError != "" && port.LastFailed.After(port.LastSucceeded) {
+			readiness.DegradedReasons = append(readiness.DegradedReasons,
+				fmt.Sprintf("management port %s: %s",
+					port.Logicallabel, port.LastError))
+		}
+	}
+	if m.networkReadiness.Equal(readiness) {
+		return
+	}
+	if m.networkReadiness.MgmtConnectivityVerified !=
+		readiness.MgmtConnectivityVerified {
+		m.Log.Noticef("Network readiness: management connectivity verified "+
+			"changed to %t", readiness.MgmtConnectivityVerified)
+	}
+	m.networkReadiness = readiness
+	err := m.PubNetworkReadiness.Publish(readiness.Key(), readiness)
+	if err != nil {
+		m.Log.Errorf("Failed to publish NetworkReadiness: %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"time"
+
+	"github.com/lf-edge/eve-libs/reconciler"
+)
+
+// This file makes long config reconciliations watchdog-safe. Some operations
+// (e.g. cellular modem bring-up) can take long enough for the agent watchdog
+// to expire while the run loop is blocked inside DpcReconciler.Reconcile.
+// DpcManager therefore registers a progress watcher with the reconciler
+// (see reconciler.WatchProgress), which touches the watchdog on every
+// cooperative checkpoint - as long as the reconciliation is progressing,
+// the agent is not killed - and logs which operation exactly is slow.
+
+// slowReconcileLogPeriod : once a reconciliation runs for longer than this
+// period, its progress is logged, though not more often than once per period.
+const slowReconcileLogPeriod = 10 * time.Second
+
+// watchReconcileProgress wraps the given context so that reconciliations
+// run with it report cooperative progress checkpoints back to DpcManager.
+func (m *DpcManager) watchReconcileProgress(
+	ctx context.Context, wdName string) context.Context {
+	return reconciler.WatchProgress(ctx,
+		func(update reconciler.ProgressUpdate) {
+			// The reconciliation is progressing - let the watchdog know that
+			// the run loop is fine even though it is blocked inside Reconcile.
+			m.Watchdog.StillRunning(wdName, warningTime, errorTime)
+			sinceStart := time.Since(update.StartedAt)
+			if sinceStart < slowReconcileLogPeriod || update.OpDone {
+				return
+			}
+			if time.Since(m.slowReconcileLogAt) < slowReconcileLogPeriod {
+				return
+			}
+			m.slowReconcileLogAt = time.Now()
+			m.Log.Warnf("Reconciliation is running for %v "+
+				"(%d operations done), currently executing %v of item %v",
+				sinceStart.Round(time.Second), update.DoneOps,
+				update.CurrentOp, update.CurrentItem)
+		})
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
+)
+
+// Periodic collection of pluggable transceiver (SFP) diagnostics.
+// Where a port is equipped with a transceiver module supporting digital
+// optical monitoring (DOM), ethtool is able to read the measured optical
+// parameters (rx/tx power, module temperature) together with the alarm
+// and warning flags raised against the thresholds programmed into the
+// module by its vendor. The data is published per port as part of
+// DeviceNetworkStatus (see types.TransceiverStatus), allowing a degrading
+// fiber link to be spotted and replaced before it fails completely.
+
+const (
+	// transceiverInitialDelay : how long after startup the first collection
+	// of transceiver diagnostics is run (gives ports time to come up).
+	transceiverInitialDelay = 2 * time.Minute
+	// transceiverCollectInterval : how often transceiver diagnostics
+	// are collected.
+	transceiverCollectInterval = 10 * time.Minute
+)
+
+// collectTransceiverDiags reads diagnostics from the transceiver modules
+// of all Ethernet ports of the current DPC and publishes any changes
+// as part of DeviceNetworkStatus.
+func (m *DpcManager) collectTransceiverDiags() {
+	defer func() {
+		m.transceiverTimer = time.NewTimer(transceiverCollectInterval)
+	}()
+	var changed bool
+	for _, port := range m.deviceNetStatus.Ports {
+		if port.IfName == "" || port.WirelessCfg.WType != types.WirelessTypeNone {
+			continue
+		}
+		prevStatus := m.transceivers[port.Logicallabel]
+		output, err := base.Exec(m.Log, "ethtool", "-m", port.IfName).
+			CombinedOutput()
+		if err != nil {
+			// Most likely the port simply has no pluggable transceiver
+			// (fixed copper NICs do not implement the module EEPROM access).
+			m.Log.Tracef("Transceiver diags: failed to read module EEPROM "+
+				"of port %s: %v (%s)", port.Logicallabel, err,
+				strings.TrimSpace(string(output)))
+			if prevStatus.Present {
+				delete(m.transceivers, port.Logicallabel)
+				changed = true
+			}
+			continue
+		}
+		status := parseTransceiverDiags(string(output))
+		for _, warning := range status.Warnings {
+			if !generics.ContainsItem(prevStatus.Warnings, warning) {
+				m.Log.Warnf("Transceiver of port %s reports: %s "+
+					"(rx power %.2f dBm, tx power %.2f dBm, temperature %.1f C)",
+					port.Logicallabel, warning, status.RxPowerDBm,
+					status.TxPowerDBm, status.TemperatureC)
+			}
+		}
+		status.CollectedAt = time.Now()
+		prevStatus.CollectedAt = status.CollectedAt
+		if !reflect.DeepEqual(prevStatus, status) {
+			changed = true
+		}
+		m.transceivers[port.Logicallabel] = status
+	}
+	if changed {
+		m.updateDNS()
+	}
+}
+
+// parseTransceiverDiags parses (SFF-8472) transceiver diagnostics
+// from the ethtool module EEPROM dump, e.g.:
+//
+//	Identifier                                : 0x03 (SFP)
+//	...
+//	Module temperature                        : 33.30 degrees C / 91.94 degrees F
+//	Laser output power                        : 0.5230 mW / -2.81 dBm
+//	Receiver signal average optical power     : 0.4310 mW / -3.66 dBm
+//	Laser output power low warning            : On
+//	Laser bias current high alarm             : Off
+func parseTransceiverDiags(output string) types.TransceiverStatus {
+	status := types.TransceiverStatus{Present: true}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "Module temperature":
+			if degrees, err := strconv.ParseFloat(
+				strings.Fields(value)[0], 32); err == nil {
+				status.TemperatureC = float32(degrees)
+				status.HasDOM = true
+			}
+		case key == "Laser output power":
+			if dbm, parsed := parseDBmValue(value); parsed {
+				status.TxPowerDBm = dbm
+				status.HasDOM = true
+			}
+		case key == "Receiver signal average optical power":
+			if dbm, parsed := parseDBmValue(value); parsed {
+				status.RxPowerDBm = dbm
+				status.HasDOM = true
+			}
+		case strings.HasSuffix(key, " alarm") || strings.HasSuffix(key, " warning"):
+			if value == "On" {
+				status.Warnings = append(status.Warnings, key)
+			}
+		}
+	}
+	return status
+}
+
+// parseDBmValue extracts the dBm part from an optical power value formatted
+// by ethtool as e.g. "0.5230 mW / -2.81 dBm".
+func parseDBmValue(value string) (dbm float32, parsed bool) {
+	for _, part := range strings.Split(value, "/") {
+		part = strings.TrimSpace(part)
+		if !strings.HasSuffix(part, " dBm") {
+			continue
+		}
+		parsedVal, err := strconv.ParseFloat(
+			strings.TrimSuffix(part, " dBm"), 32)
+		if err != nil {
+			continue
+		}
+		return float32(parsedVal), true
+	}
+	return 0, false
+}
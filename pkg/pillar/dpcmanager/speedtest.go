@@ -0,0 +1,220 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// This file implements on-demand uplink throughput measurement (see
+// types.SpeedTestRequest). On an operator request, DpcManager downloads
+// a capped amount of data over HTTP(S) from the configured endpoint
+// separately over each selected port and measures the achieved throughput,
+// the request latency and the jitter of data arrival. This allows fleet
+// operators to validate bandwidth SLAs of the underlying links without
+// having to deploy a measurement application.
+// The results are published via PubSpeedTestMetrics
+// (see types.SpeedTestMetrics).
+
+const (
+	// speedTestDefaultMaxBytes : how much data is downloaded per port when
+	// the request does not specify a cap.
+	speedTestDefaultMaxBytes = 8 << 20
+	// speedTestMaxBytesLimit : upper bound for the download cap.
+	speedTestMaxBytesLimit = 100 << 20
+	// speedTestPortTimeout : maximum duration of the measurement of one port.
+	speedTestPortTimeout = 15 * time.Second
+	// speedTestChunkSize : size of the read buffer; inter-arrival times
+	// of these chunks are used to compute the jitter.
+	speedTestChunkSize = 32 << 10
+)
+
+// doSpeedTest executes an operator request to measure the throughput
+// of one or all management ports.
+// Note that the download competes with management and application traffic
+// for the uplink bandwidth, hence it is executed only on an explicit request.
+func (m *DpcManager) doSpeedTest(request types.SpeedTestRequest) {
+	if request.RequestedAt.IsZero() {
+		// No measurement is requested.
+		return
+	}
+	if !request.RequestedAt.After(m.speedTestReqTime) {
+		// Not a new request (e.g. a re-published ZedAgentStatus).
+		return
+	}
+	m.speedTestReqTime = request.RequestedAt
+	var ports []*types.NetworkPortStatus
+	portLabel := request.PortLabel
+	if portLabel != "" {
+		ports = m.deviceNetStatus.GetPortsByLogicallabel(portLabel)
+		if len(ports) == 0 {
+			m.Log.Errorf("Speed test: port %s is not part of the current DPC",
+				portLabel)
+			return
+		}
+	} else {
+		portLabel = "all management ports"
+		for i := range m.deviceNetStatus.Ports {
+			if m.deviceNetStatus.Ports[i].IsMgmt {
+				ports = append(ports, &m.deviceNetStatus.Ports[i])
+			}
+		}
+	}
+	m.Log.Noticef("Speed test: measuring %s with URL %s as requested at %v",
+		portLabel, request.DownloadURL, request.RequestedAt)
+	m.speedTestMetrics.Results = make(map[string]types.SpeedTestResult)
+	for _, port := range ports {
+		result := m.measurePortThroughput(port, request)
+		result.CompletedAt = time.Now()
+		if result.Error != "" {
+			m.Log.Errorf("Speed test: port %s: %s",
+				port.Logicallabel, result.Error)
+		} else {
+			m.Log.Noticef("Speed test: port %s: downloaded %d bytes, "+
+				"throughput %.2f Mbps, latency %d ms, jitter %d ms",
+				port.Logicallabel, result.DownloadedBytes,
+				result.ThroughputMbps, result.LatencyMsec, result.JitterMsec)
+		}
+		m.speedTestMetrics.Results[port.Logicallabel] = result
+	}
+	m.publishSpeedTestMetrics()
+}
+
+// measurePortThroughput downloads (a capped amount of) data from the
+// requested URL over the given port and measures throughput, latency
+// and jitter.
+func (m *DpcManager) measurePortThroughput(port *types.NetworkPortStatus,
+	request types.SpeedTestRequest) types.SpeedTestResult {
+	result := types.SpeedTestResult{
+		DownloadURL: request.DownloadURL,
+		RequestedAt: request.RequestedAt,
+	}
+	if request.DownloadURL == "" {
+		result.Error = "no download URL configured"
+		return result
+	}
+	maxBytes := request.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = speedTestDefaultMaxBytes
+	} else if maxBytes > speedTestMaxBytesLimit {
+		maxBytes = speedTestMaxBytesLimit
+	}
+	// Bind the local side of the connection to an IP address of the port
+	// to force the kernel to route the download through it.
+	dialViaPort := func(ctx context.Context, network, addr string) (
+		net.Conn, error) {
+		host, tcpPort, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		dstIP := net.ParseIP(host)
+		if dstIP == nil {
+			return nil, fmt.Errorf("failed to parse destination IP %s", host)
+		}
+		srcIP := portSrcIPForDst(port, dstIP)
+		if srcIP == nil {
+			return nil, fmt.Errorf("no %s source address available on port %s",
+				ipVersionStr(dstIP), port.Logicallabel)
+		}
+		dialer := net.Dialer{LocalAddr: &net.TCPAddr{IP: srcIP}}
+		return dialer.DialContext(ctx, network,
+			net.JoinHostPort(host, tcpPort))
+	}
+	client := http.Client{
+		Transport: &http.Transport{DialContext: dialViaPort},
+		Timeout:   speedTestPortTimeout,
+	}
+	defer client.CloseIdleConnections()
+	startTime := time.Now()
+	resp, err := client.Get(request.DownloadURL)
+	if err != nil {
+		result.Error = fmt.Sprintf("download from %s failed: %v",
+			request.DownloadURL, err)
+		return result
+	}
+	defer resp.Body.Close()
+	result.LatencyMsec = uint32(time.Since(startTime).Milliseconds())
+	if resp.StatusCode != http.StatusOK {
+		result.Error = fmt.Sprintf("download from %s failed: %s",
+			request.DownloadURL, resp.Status)
+		return result
+	}
+	var interArrivals []time.Duration
+	firstByteTime := time.Now()
+	lastReadTime := firstByteTime
+	buffer := make([]byte, speedTestChunkSize)
+	for result.DownloadedBytes < maxBytes {
+		n, err := resp.Body.Read(buffer)
+		if n > 0 {
+			now := time.Now()
+			if result.DownloadedBytes == 0 {
+				firstByteTime = now
+			} else {
+				interArrivals = append(interArrivals, now.Sub(lastReadTime))
+			}
+			lastReadTime = now
+			result.DownloadedBytes += uint64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.Error = fmt.Sprintf("download from %s failed: %v",
+				request.DownloadURL, err)
+			return result
+		}
+	}
+	if result.DownloadedBytes == 0 {
+		result.Error = fmt.Sprintf("download from %s returned no data",
+			request.DownloadURL)
+		return result
+	}
+	elapsed := lastReadTime.Sub(firstByteTime)
+	if elapsed > 0 {
+		result.ThroughputMbps = float32(result.DownloadedBytes) * 8 /
+			float32(elapsed.Seconds()) / 1e6
+	}
+	result.JitterMsec = uint32(meanDeviation(interArrivals).Milliseconds())
+	return result
+}
+
+// meanDeviation returns the mean absolute deviation of the given durations
+// from their average.
+func meanDeviation(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, duration := range durations {
+		sum += duration
+	}
+	mean := sum / time.Duration(len(durations))
+	var devSum time.Duration
+	for _, duration := range durations {
+		deviation := duration - mean
+		if deviation < 0 {
+			deviation = -deviation
+		}
+		devSum += deviation
+	}
+	return devSum / time.Duration(len(durations))
+}
+
+func (m *DpcManager) publishSpeedTestMetrics() {
+	if m.PubSpeedTestMetrics == nil {
+		return
+	}
+	err := m.PubSpeedTestMetrics.Publish(
+		m.speedTestMetrics.Key(), m.speedTestMetrics)
+	if err != nil {
+		m.Log.Errorf("Failed to publish speed test metrics: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/miekg/dns"
+)
+
+// Periodic probing of DNS and NTP servers configured for every port.
+// Controller connectivity testing alone cannot distinguish "internet
+// reachable but DNS broken" from a full outage. DpcManager therefore
+// additionally measures per-port DNS resolution success/latency and NTP
+// server reachability/clock offset and publishes the results as part of
+// DeviceNetworkStatus.
+
+const (
+	// Hostname resolved when probing DNS servers.
+	dnsProbeHostname = "www.google.com."
+	// Timeout for a single DNS or NTP test query.
+	srvProbeTimeout = 3 * time.Second
+	// Offset in seconds between the NTP epoch (1900) and the Unix epoch (1970).
+	ntpEpochOffset = 2208988800
+)
+
+// probeDNSAndNTPServers measures reachability of DNS and NTP servers
+// of every L3 port with at least one IP address and records the results
+// for the next DeviceNetworkStatus update.
+func (m *DpcManager) probeDNSAndNTPServers() {
+	for i := range m.deviceNetStatus.Ports {
+		port := &m.deviceNetStatus.Ports[i]
+		if !port.IsL3Port || port.IfName == "" {
+			continue
+		}
+		if len(port.AddrInfoList) == 0 {
+			// Without an IP address there is nothing to probe from.
+			delete(m.dnsReachability, port.IfName)
+			delete(m.ntpReachability, port.IfName)
+			continue
+		}
+		var dnsReach []types.DNSServerReachability
+		for _, server := range port.DNSServers {
+			latency, err := m.probeDNSServer(port, server)
+			reach := types.DNSServerReachability{
+				Server:    server,
+				Reachable: err == nil,
+				TestedAt:  time.Now(),
+			}
+			if err == nil {
+				reach.LatencyInMsec = uint32(latency.Milliseconds())
+			} else {
+				m.Log.Functionf("probeDNSAndNTPServers: DNS server %v "+
+					"of port %s is not reachable: %v",
+					server, port.Logicallabel, err)
+			}
+			dnsReach = append(dnsReach, reach)
+		}
+		m.dnsReachability[port.IfName] = dnsReach
+		var ntpReach []types.NTPServerReachability
+		ntpServers := port.NtpServers
+		if port.NtpServer != nil {
+			ntpServers = append([]net.IP{port.NtpServer}, ntpServers...)
+		}
+		for _, server := range ntpServers {
+			offset, err := m.probeNTPServer(port, server)
+			reach := types.NTPServerReachability{
+				Server:    server,
+				Reachable: err == nil,
+				TestedAt:  time.Now(),
+			}
+			if err == nil {
+				reach.OffsetInMsec = offset.Milliseconds()
+			} else {
+				m.Log.Functionf("probeDNSAndNTPServers: NTP server %v "+
+					"of port %s is not reachable: %v",
+					server, port.Logicallabel, err)
+			}
+			ntpReach = append(ntpReach, reach)
+		}
+		m.ntpReachability[port.IfName] = ntpReach
+	}
+	// MostlyEqual inside updateDNS/publishDNS makes sure that the status
+	// is not re-published when only latencies/offsets have changed.
+	m.updateDNS()
+}
+
+// probeDNSServer runs a single test query against the given DNS server,
+// using a source IP address of the port to force the kernel to route
+// the query through it.
+func (m *DpcManager) probeDNSServer(port *types.NetworkPortStatus,
+	server net.IP) (latency time.Duration, err error) {
+	srcIP := portSrcIPForDst(port, server)
+	if srcIP == nil {
+		return 0, fmt.Errorf("no %s source address available",
+			ipVersionStr(server))
+	}
+	dialer := net.Dialer{LocalAddr: &net.UDPAddr{IP: srcIP}}
+	dnsClient := dns.Client{Dialer: &dialer, Timeout: srvProbeTimeout}
+	msg := dns.Msg{}
+	msg.SetQuestion(dnsProbeHostname, dns.TypeA)
+	_, rtt, err := dnsClient.Exchange(&msg,
+		net.JoinHostPort(server.String(), "53"))
+	if err != nil {
+		return 0, err
+	}
+	return rtt, nil
+}
+
+// probeNTPServer runs a single SNTP query against the given NTP server
+// and computes the clock offset between the device and the server.
+func (m *DpcManager) probeNTPServer(port *types.NetworkPortStatus,
+	server net.IP) (offset time.Duration, err error) {
+	srcIP := portSrcIPForDst(port, server)
+	if srcIP == nil {
+		return 0, fmt.Errorf("no %s source address available",
+			ipVersionStr(server))
+	}
+	conn, err := net.DialUDP("udp", &net.UDPAddr{IP: srcIP},
+		&net.UDPAddr{IP: server, Port: 123})
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(srvProbeTimeout)); err != nil {
+		return 0, err
+	}
+	// Standard 48-byte SNTP request: leap indicator 0, version 4,
+	// mode 3 (client).
+	request := make([]byte, 48)
+	request[0] = 4<<3 | 3
+	sendTime := time.Now()
+	if _, err = conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, 48)
+	if _, err = conn.Read(response); err != nil {
+		return 0, err
+	}
+	recvTime := time.Now()
+	// Offset = ((T2 - T1) + (T3 - T4)) / 2, where T1/T4 are the local
+	// send/receive times and T2/T3 are the server receive/transmit
+	// timestamps taken from the response.
+	t2 := ntpTimestampToTime(response[32:40])
+	t3 := ntpTimestampToTime(response[40:48])
+	if t2.IsZero() || t3.IsZero() {
+		return 0, fmt.Errorf("invalid timestamps in the response")
+	}
+	offset = (t2.Sub(sendTime) + t3.Sub(recvTime)) / 2
+	return offset, nil
+}
+
+// ntpTimestampToTime converts an 8-byte NTP timestamp (seconds since 1900
+// plus a binary fraction of a second) to time.Time.
+func ntpTimestampToTime(timestamp []byte) time.Time {
+	seconds := binary.BigEndian.Uint32(timestamp[:4])
+	fraction := binary.BigEndian.Uint32(timestamp[4:])
+	if seconds == 0 {
+		return time.Time{}
+	}
+	nsec := (uint64(fraction) * uint64(time.Second)) >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, int64(nsec))
+}
+
+// portSrcIPForDst returns a non-link-local IP address of the port matching
+// the IP version of the given destination, or nil if there is none.
+func portSrcIPForDst(port *types.NetworkPortStatus, dst net.IP) net.IP {
+	for _, addrInfo := range port.AddrInfoList {
+		if addrInfo.Addr == nil || addrInfo.Addr.IsLinkLocalUnicast() {
+			continue
+		}
+		if (addrInfo.Addr.To4() == nil) == (dst.To4() == nil) {
+			return addrInfo.Addr
+		}
+	}
+	return nil
+}
+
+func ipVersionStr(ip net.IP) string {
+	if ip.To4() != nil {
+		return "IPv4"
+	}
+	return "IPv6"
+}
@@ -19,6 +19,7 @@ const (
 	waitForAsyncRetries = 2
 	waitForIPDNSRetries = 5
 	waitForWwanRetries  = 3
+	waitForWifiRetries  = 3
 )
 
 func (m *DpcManager) restartVerify(ctx context.Context, reason string) {
@@ -28,6 +29,16 @@ func (m *DpcManager) restartVerify(ctx context.Context, reason string) {
 		m.Log.Noticef("DPC verify: DPC list verification in progress")
 		return
 	}
+	if m.abTestDpc != nil {
+		// Full verification of the DPC list supersedes the partial testing
+		// of a candidate on the secondary uplinks.
+		m.abortABTest(ctx, "full DPC verification was restarted")
+	}
+	if len(m.handoverPorts) > 0 {
+		// Full verification re-tests everything, the partial re-test
+		// of the handed-over ports is superfluous.
+		m.clearPortHandoverState()
+	}
 	if m.currentDPC() != nil &&
 		!m.rsStatus.ChangeInProgress && m.rsStatus.Imposed {
 		m.Log.Noticef("DPC verify: Radio-silence is imposed, skipping DPC verification")
@@ -38,6 +49,22 @@ func (m *DpcManager) restartVerify(ctx context.Context, reason string) {
 	// link updates happening during the verify will not be missed.
 	m.rebuildCrucialIfs()
 
+	// While a DPC pin is active, only the pinned entry is (re-)verified
+	// and the priority-based selection is suspended (see dpcpin.go).
+	if m.dpcPinDpc != nil {
+		pinnedIndex := m.pinnedDPCIndex()
+		if pinnedIndex == -1 {
+			m.Log.Warnf("DPC verify: pinned DPC %s/%v is no longer in the list, "+
+				"clearing the pin", m.dpcPinDpc.Key, m.dpcPinDpc.TimePriority)
+			m.clearDPCPin()
+		} else {
+			m.setupVerify(pinnedIndex, reason+" (DPC pin active)")
+			m.runVerify(ctx, reason)
+			m.compressAndPublishDPCL()
+			return
+		}
+	}
+
 	// Restart at index zero, then skip entries with LastFailed after
 	// LastSucceeded and a recent LastFailed (a minute or less).
 	nextIndex := m.getNextTestableDPCIndex(0)
@@ -61,11 +88,21 @@ func (m *DpcManager) restartVerify(ctx context.Context, reason string) {
 func (m *DpcManager) setupVerify(index int, reason string) {
 	m.Log.Noticef("DPC verify: Setting up verification for DPC at index %d, reason: %s",
 		index, reason)
+	prevDPC := m.currentDPC()
 	m.dpcList.CurrentIndex = index
+	// When switching to a different configuration, refresh possibly stale
+	// DHCP leases of the candidate ports before testing the connectivity
+	// (see dhcprenew.go).
+	if dpc := m.currentDPC(); dpc != nil && prevDPC != nil {
+		m.dpcVerify.renewDHCP = !prevDPC.MostlyEqual(dpc)
+	} else {
+		m.dpcVerify.renewDHCP = false
+	}
 	m.dpcVerify.inProgress = true
 	m.dpcVerify.startedAt = time.Now()
 	if dpc := m.currentDPC(); dpc != nil {
 		m.setDiscoveredWwanIfNames(dpc)
+		m.kpiStartDpcApplication(*dpc)
 	}
 	m.Log.Functionf("DPC verify: Started testing DPC (index %d): %v",
 		m.dpcList.CurrentIndex, m.dpcList.PortConfigList[m.dpcList.CurrentIndex])
@@ -95,11 +132,22 @@ func (m *DpcManager) runVerify(ctx context.Context, reason string) {
 	var res types.DPCState
 	for !endloop {
 		res = m.verifyDPC(ctx)
+		if m.dpcVerify.interrupted {
+			// A higher-priority DPC was queued while the connectivity test
+			// was still running. Stop the verification here; it will be
+			// restarted from index 0 as soon as the run loop processes
+			// the queued DPC.
+			m.dpcVerify.interrupted = false
+			m.Log.Noticef("DPC verify: Verification was interrupted " +
+				"by a queued higher-priority DPC")
+			break
+		}
 		m.Log.Noticef("DPC verify: Received status %s for DPC at index %d",
 			res.String(), m.dpcList.CurrentIndex)
 
 		// Publish DPC via dummy for logging purposes.
 		dpc := m.currentDPC()
+		m.kpiRecordDpcProgress(dpc, res)
 		_ = m.PubDummyDevicePortConfig.Publish(dpc.PubKey(), *dpc)
 
 		// Decide next action based on the verification status.
@@ -111,12 +159,23 @@ func (m *DpcManager) runVerify(ctx context.Context, reason string) {
 			types.DPCStatePCIWait,
 			// verifyDPC has already published the new DNS for domainmgr.
 			// Wait until we hear from domainmgr or until PendTimer triggers.
-			types.DPCStateIPDNSWait, types.DPCStateIntfWait, types.DPCStateWwanWait:
+			types.DPCStateIPDNSWait, types.DPCStateIntfWait, types.DPCStateWwanWait,
+			types.DPCStateWifiWait:
 			// Either addressChange or PendTimer will result in calling us again.
 			m.pendingDpcTimer = time.NewTimer(m.dpcTestDuration)
 			return
 
 		case types.DPCStateFail, types.DPCStateFailWithIPAndDNS:
+			if m.dpcPinDpc != nil {
+				// The operator pinned this DPC - keep it applied despite
+				// the failed test and let the recorded test results be
+				// reported back via the published DPC list.
+				m.Log.Warnf("DPC verify: pinned DPC at index %d failed "+
+					"the connectivity test; keeping it applied until "+
+					"the pin expires", m.dpcList.CurrentIndex)
+				endloop = true
+				break
+			}
 			m.compressAndPublishDPCL()
 			if m.dpcList.PortConfigList[0].IsDPCUntested() ||
 				m.dpcList.PortConfigList[0].WasDPCWorking() {
@@ -150,7 +209,9 @@ func (m *DpcManager) runVerify(ctx context.Context, reason string) {
 	}
 
 	// Try to get back to the latest and the highest priority DPC.
-	if m.dpcList.CurrentIndex != 0 {
+	// Not applicable while a DPC pin is active - the device intentionally
+	// stays on the pinned entry until the pin expires.
+	if m.dpcList.CurrentIndex != 0 && m.dpcPinDpc == nil {
 		m.Log.Warnf("DPC verify: Working with DPC configuration found "+
 			"at index %d in DPC list", m.dpcList.CurrentIndex)
 		if m.dpcTestBetterInterval != 0 {
@@ -171,7 +232,9 @@ func (m *DpcManager) runVerify(ctx context.Context, reason string) {
 	m.updateDNS()
 
 	// Did we get a new DPC at index zero?
-	if m.dpcList.PortConfigList[0].IsDPCUntested() {
+	// (ignored while a DPC pin is active; the new DPC will be verified
+	// once the pin expires)
+	if m.dpcPinDpc == nil && m.dpcList.PortConfigList[0].IsDPCUntested() {
 		m.Log.Warn("DPC verify: %w: New DPC arrived "+
 			"or a old working DPC moved up to top of DPC list while network testing "+
 			"was in progress. Restarting DPC verification.", res)
@@ -203,11 +266,12 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 	// Check if there is any port assigned to an application.
 	assignedPort, ifName, usedByUUID := dpc.IsAnyPortInPciBack(m.Log, &m.adapters, true)
 	if assignedPort {
-		errStr := fmt.Sprintf("port %s in PCIBack is "+
-			"used by %s", ifName, usedByUUID.String())
-		m.Log.Errorf("DPC verify: %s\n", errStr)
-		dpc.RecordFailure(errStr)
-		dpc.RecordPortFailure(ifName, errStr)
+		err := types.NewNetworkError(types.NetworkErrorCodePortInPCIBack,
+			fmt.Errorf("port %s in PCIBack is used by %s",
+				ifName, usedByUUID.String()))
+		m.Log.Errorf("DPC verify: %v\n", err)
+		dpc.RecordFailureFromError(err)
+		dpc.RecordPortFailure(ifName, err.Error())
 		status = types.DPCStateFail
 		dpc.State = status
 		return status
@@ -216,30 +280,45 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 	// Apply configuration even if some ports are missing (could be still in PCIBack).
 	// Reconciler will automatically create pending configuration items.
 	// If then connectivity check fails, we may decide if it is worth waiting for something.
-	elapsed := time.Since(m.dpcVerify.startedAt)
+	// Slow per-port operations (e.g. cellular registration, DHCP lease acquisition)
+	// continue asynchronously in the background, allowing the remaining ports
+	// to be configured and tested in parallel.
 	m.reconcileStatus = m.DpcReconciler.Reconcile(ctx, m.reconcilerArgs())
-	if m.reconcileStatus.AsyncInProgress {
-		if elapsed < waitForAsyncRetries*m.dpcTestDuration {
-			status = types.DPCStateAsyncWait
+
+	// Before testing a different-than-previously-applied configuration,
+	// ask the DHCP clients of the candidate ports to refresh their leases -
+	// a stale half-expired lease could fail the connectivity test right
+	// after the switch-over.
+	if m.dpcVerify.renewDHCP {
+		m.dpcVerify.renewDHCP = false
+		if m.renewDHCPLeases(dpc) {
+			// Give the DHCP clients a bounded amount of time to obtain fresh
+			// addresses and DNS configuration before the connectivity test.
+			status = types.DPCStateIPDNSWait
 			dpc.State = status
 			return status
 		}
-		// Async operations were running for too long, cancel them and try connectivity.
-		m.Log.Warnf("DPC verify: async ops are running for too long (waited for %v), "+
-			"canceling them and continue with verification", elapsed)
-		m.reconcileStatus.CancelAsyncOps()
 	}
 
 	// Check cloud connectivity.
 	m.updateDNS()
 	withNetTrace := m.traceNextConnTest()
+	testCtx := m.startConnTest(ctx, dpc)
 	intfStatusMap, tracedProbes, err := m.ConnTester.TestConnectivity(
-		m.deviceNetStatus, withNetTrace)
+		testCtx, m.deviceNetStatus, withNetTrace)
+	if m.finishConnTest(testCtx) {
+		m.Log.Noticef("DPC verify: connectivity test of DPC at index %d "+
+			"was interrupted by a higher-priority DPC", m.dpcList.CurrentIndex)
+		m.dpcVerify.interrupted = true
+		status = dpc.State
+		return status
+	}
 	// Use TestResults to update the DevicePortConfigList and DeviceNetworkStatus
 	// Note that the TestResults will at least have an updated timestamp
 	// for one of the ports.
 	dpc.UpdatePortStatusFromIntfStatusMap(intfStatusMap)
 	m.deviceNetStatus.UpdatePortStatusFromIntfStatusMap(intfStatusMap)
+	m.updateCaptivePortals(err)
 	defer func() {
 		// Publish DPCL, DNS and potentially also netdump at the end when dpc.State
 		// is determined.
@@ -285,8 +364,48 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 		return status
 	}
 
+	// A TLS certificate validity error caused by a badly skewed clock does
+	// not mean that this DPC is bad - we did reach the controller. Record
+	// the failure as CLOCK_SKEW, keep the DPC applied and wait for re-test
+	// (detectClockSkew attempts a coarse clock sync, so the next test of
+	// this DPC has a chance to pass).
+	if m.detectClockSkew(err) {
+		err = types.NewNetworkError(types.NetworkErrorCodeClockSkew, err)
+		m.Log.Errorf("DPC verify: %v", err)
+		dpc.RecordFailureFromError(err)
+		status = types.DPCStateRemoteWait
+		dpc.State = status
+		return status
+	}
+
+	// We did reach the controller (the TLS handshake got as far as certificate
+	// validation) and the clock-sanity signals do not blame the local clock -
+	// the certificate expiry error is genuine.
+	if isTLSCertTimeError(err) {
+		err = types.NewNetworkError(types.NetworkErrorCodeTLSCertExpired, err)
+	}
+
 	// Connectivity test failed, maybe we are missing an interface or an address.
-	elapsed = time.Since(m.dpcVerify.startedAt)
+	elapsed := time.Since(m.dpcVerify.startedAt)
+
+	// Some configuration operations may still be running in the background.
+	// As long as any port could become usable once they complete, give them
+	// some time before recording this DPC as failed.
+	if m.reconcileStatus.AsyncInProgress {
+		if elapsed < waitForAsyncRetries*m.dpcTestDuration {
+			m.Log.Noticef("DPC verify: some config operations are still running "+
+				"in the background (waiting for %v)", elapsed)
+			status = types.DPCStateAsyncWait
+			dpc.State = status
+			return status
+		}
+		// Async operations were running for too long, cancel them and continue
+		// with the checks below.
+		m.Log.Warnf("DPC verify: async ops are running for too long (waited for %v), "+
+			"canceling them and continue with verification", elapsed)
+		m.reconcileStatus.CancelAsyncOps()
+	}
+
 	portInPciBack, ifName, _ := dpc.IsAnyPortInPciBack(m.Log, &m.adapters, false)
 	if portInPciBack {
 		if elapsed < waitForIfRetries*m.dpcTestDuration {
@@ -315,6 +434,22 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 			elapsed)
 	}
 
+	// Association with a WiFi network may take a while after wpa_supplicant
+	// receives a new configuration. If a WiFi adapter is used for management,
+	// wait for it to associate with any of the configured SSIDs.
+	if m.waitForWifiAssoc() {
+		if elapsed < waitForWifiRetries*m.dpcTestDuration {
+			m.Log.Noticef("DPC verify: WiFi association is still in progress "+
+				"(waiting for %v)", elapsed)
+			status = types.DPCStateWifiWait
+			dpc.State = status
+			return status
+		}
+		// Continue...
+		m.Log.Warnf("DPC verify: WiFi adapter is still not associated "+
+			"with any of the configured SSIDs (waited for %v)", elapsed)
+	}
+
 	availablePorts, missingPorts := m.checkMgmtPortsPresence()
 	if len(missingPorts) > 0 {
 		// Still waiting for network interface(s) to appear
@@ -341,7 +476,7 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 	if len(availablePorts) == 0 {
 		m.Log.Errorf("DPC verify: no available mgmt ports: exceeded timeout "+
 			"(waited for %v): %v for %+v\n", elapsed, err, dpc)
-		dpc.RecordFailure(err.Error())
+		dpc.RecordFailureFromError(err)
 		status = types.DPCStateFail
 		dpc.State = status
 		return status
@@ -359,7 +494,14 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 		}
 		m.Log.Errorf("DPC verify: no IP/DNS: exceeded timeout (waited for %v): "+
 			"%v for %+v\n", elapsed, err, dpc)
-		dpc.RecordFailure(unwrapPortsNotReady(err).Error())
+		recordErr := unwrapPortsNotReady(err)
+		if anyMgmtPortUsesDHCP(dpc) {
+			// The DHCP clients of the management ports failed to obtain
+			// a lease (or DNS config) within the bounded wait.
+			recordErr = types.NewNetworkError(
+				types.NetworkErrorCodeDHCPTimeout, recordErr)
+		}
+		dpc.RecordFailureFromError(recordErr)
 		status = types.DPCStateFail
 		dpc.State = status
 		return status
@@ -390,13 +532,39 @@ func (m *DpcManager) verifyDPC(ctx context.Context) (status types.DPCState) {
 	}
 
 	m.Log.Errorf("DPC verify: %s\n", err)
-	dpc.RecordFailure(err.Error())
+	dpc.RecordFailureFromError(err)
 	dpc.LastIPAndDNS = dpc.LastFailed
 	status = types.DPCStateFailWithIPAndDNS
 	dpc.State = status
 	return status
 }
 
+// startConnTest prepares a cancelable context for a connectivity test of the
+// given DPC, allowing AddDPC (called from a separate goroutine) to interrupt
+// the test when a strictly higher-priority DPC is queued.
+func (m *DpcManager) startConnTest(ctx context.Context,
+	dpc *types.DevicePortConfig) context.Context {
+	testCtx, cancel := context.WithCancel(ctx)
+	m.connTestMx.Lock()
+	defer m.connTestMx.Unlock()
+	m.connTestCancel = cancel
+	m.connTestDpcPrio = dpc.TimePriority
+	return testCtx
+}
+
+// finishConnTest clears the cancel callback installed by startConnTest
+// and tells whether the test was interrupted.
+func (m *DpcManager) finishConnTest(testCtx context.Context) (interrupted bool) {
+	m.connTestMx.Lock()
+	defer m.connTestMx.Unlock()
+	interrupted = testCtx.Err() != nil
+	if m.connTestCancel != nil {
+		m.connTestCancel() // release context resources
+		m.connTestCancel = nil
+	}
+	return interrupted
+}
+
 func (m *DpcManager) testConnectivityToCloud(ctx context.Context) error {
 	dpc := m.currentDPC()
 	if dpc == nil {
@@ -406,8 +574,16 @@ func (m *DpcManager) testConnectivityToCloud(ctx context.Context) error {
 	}
 
 	withNetTrace := m.traceNextConnTest()
+	testCtx := m.startConnTest(ctx, dpc)
 	intfStatusMap, tracedProbes, err := m.ConnTester.TestConnectivity(
-		m.deviceNetStatus, withNetTrace)
+		testCtx, m.deviceNetStatus, withNetTrace)
+	if m.finishConnTest(testCtx) {
+		m.Log.Noticef("testConnectivityToCloud: connectivity test was " +
+			"interrupted by a higher-priority DPC")
+		// The queued DPC will restart verification; just re-arm the test timer.
+		m.dpcTestTimer = time.NewTimer(m.dpcTestInterval)
+		return nil
+	}
 	dpc.UpdatePortStatusFromIntfStatusMap(intfStatusMap)
 	if err == nil {
 		dpc.State = types.DPCStateSuccess
@@ -415,6 +591,7 @@ func (m *DpcManager) testConnectivityToCloud(ctx context.Context) error {
 	}
 	_ = m.PubDummyDevicePortConfig.Publish(dpc.PubKey(), *dpc)
 	m.publishDPCL() // publish updated port errors
+	m.updateCaptivePortals(err)
 	m.updateDNS()
 
 	if err == nil {
@@ -586,6 +763,17 @@ func (m *DpcManager) checkIfMgmtPortsHaveIPandDNS() bool {
 	return false
 }
 
+// anyMgmtPortUsesDHCP tells whether at least one management port of the DPC
+// runs a DHCP client to obtain its IP configuration.
+func anyMgmtPortUsesDHCP(dpc *types.DevicePortConfig) bool {
+	for _, port := range dpc.Ports {
+		if port.IsMgmt && port.Dhcp == types.DhcpTypeClient {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *DpcManager) checkMgmtPortsPresence() (available, missing []string) {
 	mgmtPorts := types.GetMgmtPortsAny(m.deviceNetStatus, 0)
 	for _, ifName := range mgmtPorts {
@@ -631,3 +819,15 @@ func unwrapPortsNotReady(err error) error {
 	}
 	return err
 }
+
+// Record which interfaces were detected by the latest connectivity test
+// as being intercepted by a captive portal (see types.CaptivePortalError)
+// and reflect this in DeviceNetworkStatus so that the portal URL is surfaced
+// to the operator.
+func (m *DpcManager) updateCaptivePortals(testErr error) {
+	m.captivePortals = conntester.ExtractCaptivePortals(testErr)
+	for ix := range m.deviceNetStatus.Ports {
+		ifName := m.deviceNetStatus.Ports[ix].IfName
+		m.deviceNetStatus.Ports[ix].CaptivePortalURL = m.captivePortals[ifName]
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcmanager
+
+import (
+	"github.com/lf-edge/eve/pkg/pillar/devicenetwork"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// getWifiNetworkStatus returns the association status reported separately
+// for every WiFi network configured for the given port.
+func (m *DpcManager) getWifiNetworkStatus(
+	port types.NetworkPortConfig) []types.WifiNetworkStatus {
+	connectedSSID, err := devicenetwork.GetConnectedSSID(port.IfName)
+	if err != nil {
+		// wpa_supplicant may not be running (yet).
+		m.Log.Functionf("getWifiNetworkStatus: failed to get connected SSID "+
+			"for port %s: %v", port.Logicallabel, err)
+	}
+	var wifiStatus []types.WifiNetworkStatus
+	for _, wifi := range port.WirelessCfg.Wifi {
+		wifiStatus = append(wifiStatus, types.WifiNetworkStatus{
+			SSID:      wifi.SSID,
+			Connected: connectedSSID != "" && wifi.SSID == connectedSSID,
+		})
+	}
+	return wifiStatus
+}
+
+// waitForWifiAssoc returns true if a WiFi adapter used for management is not
+// yet associated with any of the configured SSIDs.
+func (m *DpcManager) waitForWifiAssoc() bool {
+	dpc := m.currentDPC()
+	if dpc == nil {
+		return false
+	}
+	for _, port := range dpc.Ports {
+		if !port.IsMgmt || port.WirelessCfg.WType != types.WirelessTypeWifi {
+			continue
+		}
+		if len(port.WirelessCfg.Wifi) == 0 {
+			continue
+		}
+		connectedSSID, err := devicenetwork.GetConnectedSSID(port.IfName)
+		if err != nil || connectedSSID == "" {
+			return true
+		}
+	}
+	return false
+}
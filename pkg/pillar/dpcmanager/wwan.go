@@ -81,6 +81,7 @@ func (m *DpcManager) processWwanStatus(ctx context.Context, status types.WwanSta
 			if changedDPC {
 				m.publishDPCL()
 			}
+			m.updateWwanCostBumps(m.currentDPC())
 		}
 		m.updateDNS()
 		if dpc.State == types.DPCStateWwanWait {
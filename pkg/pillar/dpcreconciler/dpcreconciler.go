@@ -57,6 +57,13 @@ type ReconcileStatus struct {
 	// Not to be confused with device network status
 	// (which DPC reconciler does not work with).
 	DNS DNSStatus
+	// RenamedPorts : ports whose underlying network interface was found under
+	// a different name than configured (e.g. after a change in the kernel/udev
+	// enumeration order across reboots) and whose configuration was therefore
+	// remapped to the actual interface at runtime.
+	// Maps the port logical label to the actual interface name.
+	// Nil when no port is affected.
+	RenamedPorts map[string]string
 	// XXX Add more as needed...
 }
 
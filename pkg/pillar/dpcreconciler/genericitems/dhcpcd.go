@@ -18,6 +18,7 @@ import (
 	"github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve-libs/reconciler"
 	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 )
 
@@ -98,6 +99,10 @@ func (c Dhcpcd) Dependencies() (deps []depgraph.Dependency) {
 // DhcpcdConfigurator implements Configurator interface (libs/reconciler) for dhcpcd.
 type DhcpcdConfigurator struct {
 	Log *base.LogObject
+	// ProcSupervisor : if defined, every started dhcpcd instance is registered
+	// with the supervisor, which will monitor its liveness and restart it
+	// should it exit unexpectedly.
+	ProcSupervisor *procsupervisor.ProcSupervisor
 }
 
 // Create starts dhcpcd.
@@ -170,8 +175,9 @@ func (c *DhcpcdConfigurator) Create(ctx context.Context, item depgraph.Item) err
 		// Wait for a bit then give up
 		for !c.dhcpcdExists(ifName) {
 			if time.Since(startTime) > dhcpcdStartTimeout {
-				err := fmt.Errorf("dhcpcd for interface %s failed to start in time",
-					ifName)
+				err := types.NewNetworkError(types.NetworkErrorCodeDHCPTimeout,
+					fmt.Errorf("dhcpcd for interface %s failed to start in time",
+						ifName))
 				c.Log.Error(err)
 				done(err)
 				return
@@ -179,6 +185,7 @@ func (c *DhcpcdConfigurator) Create(ctx context.Context, item depgraph.Item) err
 			time.Sleep(1 * time.Second)
 		}
 		c.Log.Functionf("dhcpcd for interface %s is running", ifName)
+		c.superviseDhcpcd(ifName, op, args)
 		done(nil)
 	}()
 	return nil
@@ -204,6 +211,12 @@ func (c *DhcpcdConfigurator) Delete(ctx context.Context, item depgraph.Item) err
 		ifName := client.AdapterIfName
 		config := client.DhcpConfig
 
+		// Stop supervising first so that the supervisor will not try
+		// to restart the client while we are stopping it.
+		if c.ProcSupervisor != nil {
+			c.ProcSupervisor.ForgetProcess(dhcpcdProcName(ifName))
+		}
+
 		switch config.Dhcp {
 		case types.DhcpTypeNone:
 			done(nil)
@@ -398,6 +411,58 @@ func (c *DhcpcdConfigurator) dhcpcdExists(ifName string) bool {
 	return true
 }
 
+// superviseDhcpcd registers a started DHCP client with the process supervisor
+// (if there is one), which will then monitor its liveness and restart it
+// should it exit unexpectedly.
+func (c *DhcpcdConfigurator) superviseDhcpcd(ifName, op string, extras []string) {
+	if c.ProcSupervisor == nil {
+		return
+	}
+	c.ProcSupervisor.TrackProcess(procsupervisor.ProcessConfig{
+		Name: dhcpcdProcName(ifName),
+		CheckAlive: func() (pid int, running bool) {
+			return c.dhcpcdPid(ifName)
+		},
+		Restart: func() error {
+			// Safe to run synchronously - dhcpcd is started with "-b"
+			// and therefore daemonizes immediately.
+			return c.dhcpcdCmd(op, extras, ifName, false)
+		},
+	})
+}
+
+// dhcpcdPid returns the PID of the DHCP client running for the given
+// interface, or false if it is not running.
+func (c *DhcpcdConfigurator) dhcpcdPid(ifName string) (pid int, running bool) {
+	out, err := base.Exec(c.Log, dhcpcdBinary, "-P", ifName).CombinedOutput()
+	if err != nil {
+		return 0, false
+	}
+	pidfileName := strings.TrimSpace(string(out))
+	val, _ := c.statAndRead(pidfileName)
+	if val == "" {
+		return 0, false
+	}
+	pid, err = strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return 0, false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, false
+	}
+	if err := p.Signal(syscall.Signal(0)); err != nil {
+		return 0, false
+	}
+	return pid, true
+}
+
+// dhcpcdProcName returns the name under which the DHCP client of the given
+// interface is registered with the process supervisor.
+func dhcpcdProcName(ifName string) string {
+	return "dhcpcd-" + ifName
+}
+
 // Returns content and Modtime
 func (c *DhcpcdConfigurator) statAndRead(filename string) (string, time.Time) {
 	fi, err := os.Stat(filename)
@@ -0,0 +1,342 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package genericitems
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+// Hook points at which user-provided scripts can be executed.
+// Scripts are installed into HookScriptDir (on the read-only /config
+// partition) and must be listed with their SHA-256 checksums inside
+// HookManifestFile - scripts not covered by the manifest or with
+// a non-matching checksum are never executed.
+// The script filename determines the hook point: it must start with
+// the hook name followed by a dash, e.g. "post-port-up-ddns.sh".
+const (
+	// HookPostPortUp : executed after a port (network adapter) is brought up.
+	// The script receives the interface name in the HOOK_PORT env variable.
+	HookPostPortUp = "post-port-up"
+	// HookPrePortDown : executed before a port is brought down or released.
+	// The script receives the interface name in the HOOK_PORT env variable.
+	HookPrePortDown = "pre-port-down"
+	// HookPostDPCVerified : executed after connectivity verification
+	// of the applied DPC succeeds.
+	HookPostDPCVerified = "post-dpc-verified"
+)
+
+const (
+	// HookScriptDir : directory with user-provided hook scripts.
+	HookScriptDir = "/config/network-hooks"
+	// HookManifestFile : manifest with SHA-256 checksums of the hook scripts,
+	// one per line in the "<hex-checksum>  <filename>" format (as produced
+	// by the sha256sum utility).
+	HookManifestFile = HookScriptDir + "/manifest.sha256"
+	// HookResultDir : directory where the result of the last execution
+	// of every hook script is published (one JSON file per script).
+	HookResultDir = "/run/network-hooks"
+	// HookTimeout : how long a hook script is allowed to run before
+	// it is killed.
+	HookTimeout = 30 * time.Second
+	// Output of a hook script is truncated to this many bytes
+	// in the published result.
+	hookMaxOutputLen = 4096
+)
+
+// HookScript : user-provided script executed at a safe point of the device
+// connectivity reconciliation.
+// For HookPostPortUp the script is run when the item is created (i.e. after
+// the adapter it depends on was configured), while for HookPrePortDown
+// the script is run when the item is deleted, which the dependency on
+// the adapter guarantees to happen before the adapter is un-configured.
+type HookScript struct {
+	// Hook : one of HookPostPortUp, HookPrePortDown, HookPostDPCVerified.
+	Hook string
+	// Filename : script filename inside HookScriptDir.
+	Filename string
+	// Checksum : SHA-256 checksum of the script content, taken from
+	// the manifest. Re-verified against the actual file content just
+	// before every execution.
+	Checksum string
+	// PortIfName : interface name of the port which this hook instance
+	// is associated with. Empty for HookPostDPCVerified.
+	PortIfName string
+}
+
+// Name combines the hook point, the script filename and the port.
+func (s HookScript) Name() string {
+	if s.PortIfName == "" {
+		return s.Filename
+	}
+	return s.Filename + "/" + s.PortIfName
+}
+
+// Label is not defined.
+func (s HookScript) Label() string {
+	return ""
+}
+
+// Type of the item.
+func (s HookScript) Type() string {
+	return HookScriptTypename
+}
+
+// Equal compares two HookScript instances.
+// Difference in the checksum (i.e. updated script content) triggers re-run.
+func (s HookScript) Equal(other depgraph.Item) bool {
+	s2, isHookScript := other.(HookScript)
+	if !isHookScript {
+		return false
+	}
+	return s == s2
+}
+
+// External returns false.
+func (s HookScript) External() bool {
+	return false
+}
+
+// String describes the hook script.
+func (s HookScript) String() string {
+	str := fmt.Sprintf("Hook script %s for hook point %s", s.Filename, s.Hook)
+	if s.PortIfName != "" {
+		str += fmt.Sprintf(" and port %s", s.PortIfName)
+	}
+	return str
+}
+
+// Dependencies returns the associated adapter as the only dependency
+// (no dependencies for HookPostDPCVerified).
+func (s HookScript) Dependencies() (deps []depgraph.Dependency) {
+	if s.PortIfName == "" {
+		return nil
+	}
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: AdapterTypename,
+				ItemName: s.PortIfName,
+			},
+			Description: "Network adapter must exist",
+		},
+	}
+}
+
+// FindHookScripts returns items for all verified hook scripts installed
+// for the given hook point. Scripts not listed in the manifest or listed
+// with a non-matching checksum are skipped (with an error logged).
+// PortIfName is left empty and should be filled in by the caller
+// for per-port hook points.
+func FindHookScripts(log *base.LogObject, hook string) (scripts []HookScript) {
+	dirEntries, err := os.ReadDir(HookScriptDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("FindHookScripts: failed to read %s: %v",
+				HookScriptDir, err)
+		}
+		return nil
+	}
+	manifest := readHookManifest(log)
+	for _, entry := range dirEntries {
+		filename := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(filename, hook+"-") {
+			continue
+		}
+		checksum, listed := manifest[filename]
+		if !listed {
+			log.Errorf("FindHookScripts: script %s is not listed in %s "+
+				"- skipping", filename, HookManifestFile)
+			continue
+		}
+		actualChecksum, err := fileSHA256(filepath.Join(HookScriptDir, filename))
+		if err != nil {
+			log.Errorf("FindHookScripts: failed to checksum script %s: %v",
+				filename, err)
+			continue
+		}
+		if actualChecksum != checksum {
+			log.Errorf("FindHookScripts: script %s does not match "+
+				"the checksum from %s - skipping", filename, HookManifestFile)
+			continue
+		}
+		scripts = append(scripts, HookScript{
+			Hook:     hook,
+			Filename: filename,
+			Checksum: checksum,
+		})
+	}
+	return scripts
+}
+
+// readHookManifest parses HookManifestFile into a filename->checksum map.
+func readHookManifest(log *base.LogObject) map[string]string {
+	manifest := make(map[string]string)
+	content, err := os.ReadFile(HookManifestFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("readHookManifest: failed to read %s: %v",
+				HookManifestFile, err)
+		}
+		return manifest
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		manifest[fields[1]] = strings.ToLower(fields[0])
+	}
+	return manifest
+}
+
+func fileSHA256(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	checksum := sha256.Sum256(content)
+	return hex.EncodeToString(checksum[:]), nil
+}
+
+// hookScriptResult : result of the last execution of a hook script,
+// published as a JSON file under HookResultDir.
+type hookScriptResult struct {
+	Script     string    `json:"script"`
+	Hook       string    `json:"hook"`
+	Port       string    `json:"port,omitempty"`
+	StartTime  time.Time `json:"startTime"`
+	DurationMs int64     `json:"durationMs"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Output     string    `json:"output,omitempty"`
+}
+
+// HookScriptConfigurator implements Configurator interface (libs/reconciler)
+// for user-provided hook scripts.
+type HookScriptConfigurator struct {
+	Log *base.LogObject
+}
+
+// Create runs the script of a post-* hook point.
+// For HookPrePortDown the item creation is a no-op - the script is run
+// when the item is deleted.
+func (c *HookScriptConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	script, isHookScript := item.(HookScript)
+	if !isHookScript {
+		return fmt.Errorf("invalid item type: %T (expected HookScript)", item)
+	}
+	if script.Hook == HookPrePortDown {
+		return nil
+	}
+	return c.runScript(ctx, script)
+}
+
+// Modify is not implemented.
+func (c *HookScriptConfigurator) Modify(ctx context.Context,
+	oldItem, newItem depgraph.Item) (err error) {
+	return fmt.Errorf("not implemented")
+}
+
+// Delete runs the script of the pre-port-down hook point.
+// For post-* hook points the item deletion is a no-op.
+func (c *HookScriptConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	script, isHookScript := item.(HookScript)
+	if !isHookScript {
+		return fmt.Errorf("invalid item type: %T (expected HookScript)", item)
+	}
+	if script.Hook != HookPrePortDown {
+		return nil
+	}
+	return c.runScript(ctx, script)
+}
+
+// NeedsRecreate returns true - Modify is not implemented.
+func (c *HookScriptConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
+
+// runScript executes the hook script with a timeout, after re-verifying
+// the checksum of the script content, and publishes the result.
+func (c *HookScriptConfigurator) runScript(ctx context.Context, script HookScript) error {
+	scriptPath := filepath.Join(HookScriptDir, script.Filename)
+	result := hookScriptResult{
+		Script:    script.Filename,
+		Hook:      script.Hook,
+		Port:      script.PortIfName,
+		StartTime: time.Now(),
+	}
+	err := c.execScript(ctx, script, scriptPath, &result)
+	result.DurationMs = time.Since(result.StartTime).Milliseconds()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+		c.Log.Errorf("Hook script %s failed: %v", script.Name(), err)
+	}
+	c.publishResult(script, result)
+	return err
+}
+
+func (c *HookScriptConfigurator) execScript(ctx context.Context, script HookScript,
+	scriptPath string, result *hookScriptResult) error {
+	// Re-verify the script content just before execution.
+	actualChecksum, err := fileSHA256(scriptPath)
+	if err != nil {
+		return fmt.Errorf("failed to checksum script %s: %w", scriptPath, err)
+	}
+	if actualChecksum != script.Checksum {
+		return fmt.Errorf("script %s does not match the checksum "+
+			"from %s", scriptPath, HookManifestFile)
+	}
+	execCtx, cancel := context.WithTimeout(ctx, HookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(execCtx, "/bin/sh", scriptPath)
+	cmd.Env = append(os.Environ(),
+		"HOOK_NAME="+script.Hook,
+		"HOOK_PORT="+script.PortIfName)
+	output, err := cmd.CombinedOutput()
+	if len(output) > hookMaxOutputLen {
+		output = output[:hookMaxOutputLen]
+	}
+	result.Output = string(output)
+	if execCtx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("script %s timed out after %v", scriptPath, HookTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("script %s failed: %w", scriptPath, err)
+	}
+	return nil
+}
+
+// publishResult writes the result of the script execution into HookResultDir.
+func (c *HookScriptConfigurator) publishResult(script HookScript,
+	result hookScriptResult) {
+	if err := os.MkdirAll(HookResultDir, 0755); err != nil {
+		c.Log.Errorf("publishResult: failed to create %s: %v",
+			HookResultDir, err)
+		return
+	}
+	resultData, err := json.Marshal(result)
+	if err != nil {
+		c.Log.Errorf("publishResult: failed to marshal result of %s: %v",
+			script.Name(), err)
+		return
+	}
+	resultFile := filepath.Join(HookResultDir,
+		strings.ReplaceAll(script.Name(), "/", "-")+".json")
+	if err := os.WriteFile(resultFile, resultData, 0644); err != nil {
+		c.Log.Errorf("publishResult: failed to write %s: %v", resultFile, err)
+	}
+}
@@ -6,20 +6,24 @@ package genericitems
 import (
 	"github.com/lf-edge/eve-libs/reconciler"
 	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/pubsub"
 )
 
 // RegisterItems : register all configurators implemented by this package.
 func RegisterItems(log *base.LogObject, registry *reconciler.DefaultRegistry,
-	pubWwanConfig pubsub.Publication) error {
+	pubWwanConfig pubsub.Publication,
+	procSupervisor *procsupervisor.ProcSupervisor) error {
 	type configurator struct {
 		c reconciler.Configurator
 		t string
 	}
 	configurators := []configurator{
-		{c: &DhcpcdConfigurator{Log: log}, t: DhcpcdTypename},
+		{c: &DhcpcdConfigurator{Log: log, ProcSupervisor: procSupervisor},
+			t: DhcpcdTypename},
 		{c: &ResolvConfConfigurator{Log: log}, t: ResolvConfTypename},
 		{c: &SSHAuthKeysConfigurator{Log: log}, t: SSHAuthKeysTypename},
+		{c: &HookScriptConfigurator{Log: log}, t: HookScriptTypename},
 		{c: &WwanConfigurator{Log: log, PubWwanConfig: pubWwanConfig}, t: WwanTypename},
 	}
 	for _, configurator := range configurators {
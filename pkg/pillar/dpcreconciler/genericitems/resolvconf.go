@@ -10,6 +10,7 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve/pkg/pillar/base"
@@ -22,7 +23,8 @@ const (
 
 // ResolvConf : a singleton item representing resolv.conf.
 type ResolvConf struct {
-	DNSServers map[string][]net.IP // interface name -> DNS servers
+	DNSServers    map[string][]net.IP // interface name -> DNS servers
+	SearchDomains map[string][]string // interface name -> DNS search domains
 }
 
 // Name returns the full path of the resolv.conf file.
@@ -46,7 +48,8 @@ func (r ResolvConf) Equal(other depgraph.Item) bool {
 	if !isResolvConf {
 		return false
 	}
-	return reflect.DeepEqual(r.DNSServers, r2.DNSServers)
+	return reflect.DeepEqual(r.DNSServers, r2.DNSServers) &&
+		reflect.DeepEqual(r.SearchDomains, r2.SearchDomains)
 }
 
 // External returns false.
@@ -63,6 +66,9 @@ func (r ResolvConf) String() string {
 	for ifName, dnsServers := range r.DNSServers {
 		str += fmt.Sprintf("\n  *  %s: %v", ifName, dnsServers)
 	}
+	for ifName, domains := range r.SearchDomains {
+		str += fmt.Sprintf("\n  *  %s search domains: %v", ifName, domains)
+	}
 	return str
 }
 
@@ -143,6 +149,30 @@ func (c *ResolvConfConfigurator) generateResolvConf(item depgraph.Item) error {
 			}
 		}
 	}
+	// Combine search domains of all interfaces into a single "search" line
+	// (only the last "search" entry would be used by the resolver anyway).
+	var domains []string
+	for _, ifDomains := range config.SearchDomains {
+		for _, domain := range ifDomains {
+			duplicate := false
+			for _, d := range domains {
+				if d == domain {
+					duplicate = true
+				}
+			}
+			if !duplicate {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	if len(domains) > 0 {
+		_, err = destfile.WriteString(
+			fmt.Sprintf("search %s\n", strings.Join(domains, " ")))
+		if err != nil {
+			c.Log.Error(err)
+			return err
+		}
+	}
 	if _, err = destfile.WriteString("options rotate\n"); err != nil {
 		c.Log.Error(err)
 		return err
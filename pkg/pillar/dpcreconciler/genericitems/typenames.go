@@ -35,6 +35,9 @@ const (
 	UnsupportedRouteTypename = "Unsupported-Route"
 	// SSHAuthKeysTypename : typename for singleton item representing file authorized_keys.
 	SSHAuthKeysTypename = "SSH-Authorized-Keys"
+	// HookScriptTypename : typename for user-provided hook scripts executed
+	// at safe points of the device connectivity reconciliation.
+	HookScriptTypename = "Hook-Script"
 	// VlanTypename : typename for VLAN sub-interface.
 	// Not implemented in genericitems (implementation specific to network stack).
 	VlanTypename = "VLAN"
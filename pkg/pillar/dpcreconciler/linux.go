@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"strconv"
 	"strings"
@@ -23,6 +24,7 @@ import (
 	linux "github.com/lf-edge/eve/pkg/pillar/dpcreconciler/linuxitems"
 	"github.com/lf-edge/eve/pkg/pillar/iptables"
 	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/pubsub"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	fileutils "github.com/lf-edge/eve/pkg/pillar/utils/file"
@@ -135,6 +137,8 @@ const (
 	IPv4ACLsSG = "IPv4Rules"
 	// IPv6ACLsSG : sub-graph of ACLsSG with IPv6 rules.
 	IPv6ACLsSG = "IPv6Rules"
+	// HooksSG : sub-graph with user-provided hook scripts.
+	HooksSG = "Hooks"
 )
 
 const (
@@ -178,6 +182,11 @@ type LinuxDpcReconciler struct {
 	PubCipherBlockStatus pubsub.Publication
 	CipherMetrics        *cipher.AgentMetrics
 	PubWwanConfig        pubsub.Publication
+	// ProcSupervisor : optional; when defined, helper daemons spawned
+	// by the reconciler (dhcpcd) or monitored on its behalf (wpa_supplicant)
+	// are registered with the supervisor for liveness tracking and automatic
+	// restarts.
+	ProcSupervisor *procsupervisor.ProcSupervisor
 
 	currentState  dg.Graph
 	intendedState dg.Graph
@@ -198,6 +207,12 @@ type LinuxDpcReconciler struct {
 	radioSilence types.RadioSilence
 	HVTypeKube   bool
 	intfMTU      map[string]uint16
+
+	// VLAN IDs dynamically assigned by upstream switches, learned from LLDP
+	// neighbors of VLAN parent ports (see VLANConfig.DynamicID).
+	// Key = interface name of the parent port.
+	lldpVlanIDs    map[string]uint16
+	lldpVlanTicker *time.Ticker
 }
 
 type pendingReconcile struct {
@@ -241,10 +256,12 @@ func (r *LinuxDpcReconciler) init() (startWatcher func()) {
 		r.Log.Fatal("Already initialized")
 	}
 	registry := &reconciler.DefaultRegistry{}
-	if err := generic.RegisterItems(r.Log, registry, r.PubWwanConfig); err != nil {
+	if err := generic.RegisterItems(r.Log, registry, r.PubWwanConfig,
+		r.ProcSupervisor); err != nil {
 		r.Log.Fatal(err)
 	}
-	if err := linux.RegisterItems(r.Log, registry, r.NetworkMonitor); err != nil {
+	if err := linux.RegisterItems(r.Log, registry, r.NetworkMonitor,
+		r.ProcSupervisor); err != nil {
 		r.Log.Fatal(err)
 	}
 	if err := iptables.RegisterItems(r.Log, registry); err != nil {
@@ -254,6 +271,7 @@ func (r *LinuxDpcReconciler) init() (startWatcher func()) {
 	configurator := registry.GetConfigurator(generic.Wwan{})
 	r.wwanConfigurator = configurator.(*generic.WwanConfigurator)
 	r.watcherControl = make(chan watcherCtrl, 10)
+	r.lldpVlanTicker = time.NewTicker(lldpVlanRefreshPeriod)
 	netEvents := r.NetworkMonitor.WatchEvents(
 		context.Background(), "linux-dpc-reconciler")
 	go r.watcher(netEvents)
@@ -324,6 +342,11 @@ func (r *LinuxDpcReconciler) watcher(netEvents <-chan netmonitor.Event) {
 				}
 			}
 
+		case <-r.lldpVlanTicker.C:
+			if r.updateLLDPAssignedVlans(r.lastArgs.DPC) {
+				r.addPendingReconcile(LogicalIoSG, "LLDP-assigned VLAN change", true)
+			}
+
 		case ctrl = <-r.watcherControl:
 			if ctrl == watcherCtrlPause {
 				r.Unlock()
@@ -379,6 +402,10 @@ func (r *LinuxDpcReconciler) Reconcile(ctx context.Context, args Args) Reconcile
 		reconcileAll bool
 		reconcileSG  string
 	)
+	// Detect network interfaces renamed by the kernel/udev (e.g. due to
+	// a changed NIC enumeration order across reboots) and remap the port
+	// configuration to the actual interface names (see rename.go).
+	renamedPorts := r.resolvePortIfNames(&args.DPC)
 	if !r.initialized {
 		// This is the first state reconciliation.
 		startWatcher := r.init()
@@ -407,6 +434,14 @@ func (r *LinuxDpcReconciler) Reconcile(ctx context.Context, args Args) Reconcile
 		if r.rsChanged(args.RS) {
 			r.addPendingReconcile(WirelessSG, "RS change", false)
 		}
+		if r.dpcVerifyStateChanged(args.DPC) {
+			r.addPendingReconcile(HooksSG, "DPC verification state change", false)
+		}
+	}
+	// Refresh VLAN IDs dynamically assigned by upstream switches before
+	// (re-)building the intended state (see VLANConfig.DynamicID).
+	if r.updateLLDPAssignedVlans(args.DPC) {
+		r.addPendingReconcile(LogicalIoSG, "LLDP-assigned VLAN change", false)
 	}
 	if r.pendingReconcile.isPending {
 		reconcileSG = r.pendingReconcile.forSubGraph
@@ -415,6 +450,7 @@ func (r *LinuxDpcReconciler) Reconcile(ctx context.Context, args Args) Reconcile
 		newStatus := r.prevStatus
 		newStatus.Error = nil
 		newStatus.FailingItems = nil
+		newStatus.RenamedPorts = renamedPorts
 		return newStatus
 	}
 	if reconcileSG == GraphName {
@@ -454,6 +490,8 @@ func (r *LinuxDpcReconciler) Reconcile(ctx context.Context, args Args) Reconcile
 			intSG = r.getIntendedWirelessCfg(args.DPC, args.AA, args.RS)
 		case ACLsSG:
 			intSG = r.getIntendedACLs(args.DPC, args.GCP)
+		case HooksSG:
+			intSG = r.getIntendedHooks(args.DPC)
 		default:
 			// Only these top-level subgraphs are used for selective-reconcile for now.
 			r.Log.Fatalf("Unexpected SG select for reconcile: %s", reconcileSG)
@@ -563,6 +601,7 @@ func (r *LinuxDpcReconciler) Reconcile(ctx context.Context, args Args) Reconcile
 			Error:   dnsError,
 			Servers: resolvConf.DNSServers,
 		},
+		RenamedPorts: renamedPorts,
 	}
 
 	// Update the internal state.
@@ -647,6 +686,15 @@ func (r *LinuxDpcReconciler) rsChanged(newRS types.RadioSilence) bool {
 	return r.lastArgs.RS.Imposed != newRS.Imposed
 }
 
+// dpcVerifyStateChanged returns true if the DPC transitioned between
+// the verified and not (yet) verified state. Note that MostlyEqual used
+// by dpcChanged intentionally ignores the State attribute.
+func (r *LinuxDpcReconciler) dpcVerifyStateChanged(newDPC types.DevicePortConfig) bool {
+	prevVerified := r.lastArgs.DPC.State == types.DPCStateSuccess
+	newVerified := newDPC.State == types.DPCStateSuccess
+	return prevVerified != newVerified
+}
+
 func (r *LinuxDpcReconciler) gcpChanged(newGCP types.ConfigItemValueMap) bool {
 	prevAuthKeys := r.lastArgs.GCP.GlobalValueString(types.SSHAuthorizedKeys)
 	newAuthKeys := newGCP.GlobalValueString(types.SSHAuthorizedKeys)
@@ -816,6 +864,32 @@ func (r *LinuxDpcReconciler) updateCurrentRoutes(dpc types.DevicePortConfig) (ch
 				LastOperation: reconciler.OperationCreate,
 			})
 		}
+		// Capture explicitly managed IPv6 default routes present in the main
+		// table (shadows of RA-learned routes, see getIntendedRoutes).
+		mainRoutes, err := r.NetworkMonitor.ListRoutes(netmonitor.RouteFilters{
+			FilterByTable: true,
+			Table:         syscall.RT_TABLE_MAIN,
+			FilterByIf:    true,
+			IfIndex:       ifIndex,
+		})
+		if err != nil {
+			r.Log.Errorf("updateCurrentRoutes: ListRoutes failed for ifIndex %d: %v",
+				ifIndex, err)
+		}
+		for _, rt := range mainRoutes {
+			rtData := rt.Data.(netlink.Route)
+			if !isManagedIPv6DefRoute(rtData) {
+				continue
+			}
+			currentRoutes.PutItem(linux.Route{
+				Route:         rtData,
+				AdapterIfName: port.IfName,
+				AdapterLL:     port.Logicallabel,
+			}, &reconciler.ItemStateData{
+				State:         reconciler.ItemStateCreated,
+				LastOperation: reconciler.OperationCreate,
+			})
+		}
 
 		if cniIfIndex != -1 {
 			cniRoutes, err := r.NetworkMonitor.ListRoutes(netmonitor.RouteFilters{
@@ -861,6 +935,37 @@ func (r *LinuxDpcReconciler) updateIntendedState(args Args) {
 	r.intendedState.PutSubGraph(r.getIntendedL3Cfg(args.DPC))
 	r.intendedState.PutSubGraph(r.getIntendedWirelessCfg(args.DPC, args.AA, args.RS))
 	r.intendedState.PutSubGraph(r.getIntendedACLs(args.DPC, args.GCP))
+	r.intendedState.PutSubGraph(r.getIntendedHooks(args.DPC))
+}
+
+// getIntendedHooks returns the intended set of user-provided hook scripts
+// to execute, modeled as graph items so that their ordering wrt. port
+// configuration is guaranteed (see genericitems.HookScript).
+func (r *LinuxDpcReconciler) getIntendedHooks(dpc types.DevicePortConfig) dg.Graph {
+	graphArgs := dg.InitArgs{
+		Name:        HooksSG,
+		Description: "User-provided hook scripts",
+	}
+	intendedHooks := dg.New(graphArgs)
+	perPortHooks := generic.FindHookScripts(r.Log, generic.HookPostPortUp)
+	perPortHooks = append(perPortHooks,
+		generic.FindHookScripts(r.Log, generic.HookPrePortDown)...)
+	for _, hookScript := range perPortHooks {
+		for _, port := range dpc.Ports {
+			if port.IfName == "" || port.InvalidConfig {
+				continue
+			}
+			hookScript.PortIfName = port.IfName
+			intendedHooks.PutItem(hookScript, nil)
+		}
+	}
+	if dpc.State == types.DPCStateSuccess {
+		for _, hookScript := range generic.FindHookScripts(
+			r.Log, generic.HookPostDPCVerified) {
+			intendedHooks.PutItem(hookScript, nil)
+		}
+	}
+	return intendedHooks
 }
 
 func (r *LinuxDpcReconciler) getIntendedGlobalCfg(dpc types.DevicePortConfig) dg.Graph {
@@ -876,28 +981,45 @@ func (r *LinuxDpcReconciler) getIntendedGlobalCfg(dpc types.DevicePortConfig) dg
 	}
 	// Intended content of /etc/resolv.conf
 	dnsServers := make(map[string][]net.IP)
+	searchDomains := make(map[string][]string)
 	for _, port := range dpc.Ports {
 		if !port.IsMgmt || port.IfName == "" || port.InvalidConfig {
 			continue
 		}
-		ifIndex, found, err := r.NetworkMonitor.GetInterfaceIndex(port.IfName)
-		if err != nil {
-			r.Log.Errorf("getIntendedGlobalCfg: failed to get ifIndex for %s: %v",
-				port.IfName, err)
-			continue
-		}
-		if !found {
-			continue
+		var servers []net.IP
+		var domains []string
+		if !port.DNSOverride.IgnoreDhcpDNS {
+			ifIndex, found, err := r.NetworkMonitor.GetInterfaceIndex(port.IfName)
+			if err != nil {
+				r.Log.Errorf("getIntendedGlobalCfg: failed to get ifIndex for %s: %v",
+					port.IfName, err)
+				continue
+			}
+			if !found {
+				continue
+			}
+			dnsInfo, err := r.NetworkMonitor.GetInterfaceDNSInfo(ifIndex)
+			if err != nil {
+				r.Log.Errorf("getIntendedGlobalCfg: failed to get DNS info for %s: %v",
+					port.IfName, err)
+				continue
+			}
+			servers = append(servers, dnsInfo.DNSServers...)
+			domains = append(domains, dnsInfo.Domains...)
 		}
-		dnsInfo, err := r.NetworkMonitor.GetInterfaceDNSInfo(ifIndex)
-		if err != nil {
-			r.Log.Errorf("getIntendedGlobalCfg: failed to get DNS info for %s: %v",
-				port.IfName, err)
-			continue
+		// Apply user-configured overrides of the DHCP-provided DNS config
+		// (see types.DNSOverride).
+		servers = append(servers, port.DNSOverride.DNSServers...)
+		domains = append(domains, port.DNSOverride.SearchDomains...)
+		dnsServers[port.IfName] = servers
+		if len(domains) > 0 {
+			searchDomains[port.IfName] = domains
 		}
-		dnsServers[port.IfName] = dnsInfo.DNSServers
 	}
-	intendedCfg.PutItem(generic.ResolvConf{DNSServers: dnsServers}, nil)
+	intendedCfg.PutItem(generic.ResolvConf{
+		DNSServers:    dnsServers,
+		SearchDomains: searchDomains,
+	}, nil)
 	return intendedCfg
 }
 
@@ -969,6 +1091,7 @@ func (r *LinuxDpcReconciler) getIntendedPhysicalIfs(dpc types.DevicePortConfig)
 					Usage:        generic.IOUsageL3Adapter,
 					WirelessType: port.WirelessCfg.WType,
 					MTU:          r.intfMTU[port.Logicallabel],
+					EthLink:      port.EthLink,
 				}, nil)
 			}
 		case types.L2LinkTypeVLAN:
@@ -980,6 +1103,7 @@ func (r *LinuxDpcReconciler) getIntendedPhysicalIfs(dpc types.DevicePortConfig)
 					Usage:        generic.IOUsageVlanParent,
 					WirelessType: port.WirelessCfg.WType,
 					MTU:          r.intfMTU[port.Logicallabel],
+					EthLink:      parent.EthLink,
 				}, nil)
 			}
 		case types.L2LinkTypeBond:
@@ -992,6 +1116,7 @@ func (r *LinuxDpcReconciler) getIntendedPhysicalIfs(dpc types.DevicePortConfig)
 						MasterIfName: port.IfName,
 						WirelessType: port.WirelessCfg.WType,
 						MTU:          r.intfMTU[port.Logicallabel],
+						EthLink:      nps.EthLink,
 					}, nil)
 				}
 			}
@@ -1014,13 +1139,28 @@ func (r *LinuxDpcReconciler) getIntendedLogicalIO(dpc types.DevicePortConfig) dg
 		case types.L2LinkTypeVLAN:
 			parent := dpc.LookupPortByLogicallabel(port.VLAN.ParentPort)
 			if parent != nil {
+				vlanID := port.VLAN.ID
+				if port.VLAN.DynamicID {
+					learnedID, learned := r.lldpVlanIDs[parent.IfName]
+					if !learned {
+						// The VLAN ID assigned by the switch was not learned
+						// from LLDP yet. The sub-interface (and, transitively,
+						// the L3 configuration of this port) will be created
+						// once the assignment is advertised by the switch.
+						r.Log.Noticef("VLAN ID for port %s is not yet learned "+
+							"from the LLDP neighbor of parent port %s",
+							port.Logicallabel, parent.IfName)
+						continue
+					}
+					vlanID = learnedID
+				}
 				vlan := linux.Vlan{
 					LogicalLabel: port.Logicallabel,
 					IfName:       port.IfName,
 					ParentLL:     port.VLAN.ParentPort,
 					ParentIfName: parent.IfName,
 					ParentL2Type: parent.L2Type,
-					ID:           port.VLAN.ID,
+					ID:           vlanID,
 					MTU:          r.intfMTU[port.Logicallabel],
 				}
 				intendedIO.PutItem(vlan, nil)
@@ -1100,6 +1240,23 @@ func (r *LinuxDpcReconciler) getIntendedAdapters(dpc types.DevicePortConfig) dg.
 				DhcpConfig:    port.DhcpConfig,
 			}, nil)
 		}
+		for _, secondary := range port.SecondaryAddresses {
+			if secondary.Address == nil {
+				continue
+			}
+			intendedAdapters.PutItem(linux.IPAlias{
+				AdapterLL:     port.Logicallabel,
+				AdapterIfName: port.IfName,
+				Address:       secondary.Address,
+			}, nil)
+		}
+		if port.Mirroring.Enable && port.Mirroring.CaptureIfName != "" {
+			intendedAdapters.PutItem(linux.PortMirror{
+				PortLL:     port.Logicallabel,
+				PortIfName: port.IfName,
+				Config:     port.Mirroring,
+			}, nil)
+		}
 		// Inside the intended state the external items (like AdapterAddrs)
 		// are only informatory, hence ignore any errors below.
 		if ifIndex, found, _ := r.NetworkMonitor.GetInterfaceIndex(port.IfName); found {
@@ -1207,6 +1364,12 @@ func (r *LinuxDpcReconciler) getIntendedRoutes(dpc types.DevicePortConfig) dg.Gr
 		}
 		for _, rt := range routes {
 			rtCopy := rt.Data.(netlink.Route)
+			if isManagedIPv6DefRoute(rtCopy) {
+				// Shadow default route installed by a previous reconciliation
+				// (see below) - do not copy it to the per-port table, where
+				// the RA-learned route is copied instead.
+				continue
+			}
 			rtCopy.Table = dstTable
 			r.prepareRouteForCopy(&rtCopy)
 			intendedRoutes.PutItem(linux.Route{
@@ -1215,6 +1378,29 @@ func (r *LinuxDpcReconciler) getIntendedRoutes(dpc types.DevicePortConfig) dg.Gr
 				AdapterLL:     port.Logicallabel,
 			}, nil)
 		}
+		// With multiple ports receiving Router Advertisements, the kernel's
+		// own route selection in the main table may prefer a non-management
+		// or a higher-cost port. For the (preferred) RA-learned default route
+		// of each port we therefore install a shadow IPv6 default route with
+		// the metric ordered by the port cost, taking precedence over the
+		// kernel's RA routes (see IPv6DefRouteBaseMetric).
+		for _, rt := range routes {
+			rtData := rt.Data.(netlink.Route)
+			if !isRaDefRoute(rtData) {
+				continue
+			}
+			rtCopy := rtData
+			rtCopy.Priority = devicenetwork.IPv6DefRouteBaseMetric + int(port.Cost)
+			rtCopy.Protocol = syscall.RTPROT_STATIC
+			intendedRoutes.PutItem(linux.Route{
+				Route:         rtCopy,
+				AdapterIfName: port.IfName,
+				AdapterLL:     port.Logicallabel,
+			}, nil)
+			// Only one default route per table/port pair can be managed
+			// (the route destination acts as the item identifier).
+			break
+		}
 		for _, rt := range cniRoutes {
 			rtCopy := rt.Data.(netlink.Route)
 			rtCopy.Table = dstTable
@@ -1228,6 +1414,31 @@ func (r *LinuxDpcReconciler) getIntendedRoutes(dpc types.DevicePortConfig) dg.Gr
 	return intendedRoutes
 }
 
+// isRaDefRoute returns true for an IPv6 default route learned from a Router
+// Advertisement.
+func isRaDefRoute(route netlink.Route) bool {
+	return route.Family == netlink.FAMILY_V6 && isDefaultDst(route) &&
+		route.Gw != nil && route.Protocol == syscall.RTPROT_RA
+}
+
+// isManagedIPv6DefRoute returns true for an IPv6 default route in the main
+// table which is explicitly managed by the reconciler - i.e. a shadow of
+// an RA-learned route with the metric derived from the port cost.
+func isManagedIPv6DefRoute(route netlink.Route) bool {
+	return route.Family == netlink.FAMILY_V6 && isDefaultDst(route) &&
+		route.Protocol == syscall.RTPROT_STATIC &&
+		route.Priority >= devicenetwork.IPv6DefRouteBaseMetric &&
+		route.Priority <= devicenetwork.IPv6DefRouteBaseMetric+math.MaxUint8
+}
+
+func isDefaultDst(route netlink.Route) bool {
+	if route.Dst == nil {
+		return true
+	}
+	ones, _ := route.Dst.Mask.Size()
+	return ones == 0 && route.Dst.IP.IsUnspecified()
+}
+
 func (r *LinuxDpcReconciler) prepareRouteForCopy(route *netlink.Route) {
 	// Multiple IPv6 link-locals can't be added to the same
 	// table unless the Priority differs.
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcreconciler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// How often to re-query LLDP neighbors of VLAN parent ports for dynamically
+// assigned VLAN IDs (see VLANConfig.DynamicID).
+const lldpVlanRefreshPeriod = 30 * time.Second
+
+// updateLLDPAssignedVlans refreshes the set of VLAN IDs dynamically assigned
+// by the upstream switch, learned from the LLDP neighbors of parent ports
+// of VLAN sub-interfaces with DynamicID enabled.
+// Returns true if any of the learned VLAN IDs has changed.
+// The caller must hold the reconciler lock.
+func (r *LinuxDpcReconciler) updateLLDPAssignedVlans(
+	dpc types.DevicePortConfig) (changed bool) {
+	parents := make(map[string]struct{})
+	for _, port := range dpc.Ports {
+		if port.InvalidConfig || port.L2Type != types.L2LinkTypeVLAN ||
+			!port.VLAN.DynamicID {
+			continue
+		}
+		parent := dpc.LookupPortByLogicallabel(port.VLAN.ParentPort)
+		if parent == nil || parent.IfName == "" {
+			continue
+		}
+		parents[parent.IfName] = struct{}{}
+	}
+	if r.lldpVlanIDs == nil {
+		r.lldpVlanIDs = make(map[string]uint16)
+	}
+	// Forget VLAN IDs learned for ports no longer used as dynamic VLAN parents.
+	for ifName := range r.lldpVlanIDs {
+		if _, used := parents[ifName]; !used {
+			delete(r.lldpVlanIDs, ifName)
+			changed = true
+		}
+	}
+	for ifName := range parents {
+		vlanID, err := r.getLLDPAssignedVlanID(ifName)
+		if err != nil {
+			r.Log.Warnf("Failed to get LLDP-assigned VLAN ID for port %s: %v",
+				ifName, err)
+			continue
+		}
+		prevID, learned := r.lldpVlanIDs[ifName]
+		if vlanID == 0 {
+			// Keep the previously learned VLAN ID (if any) rather than flapping
+			// the sub-interface when the LLDP neighbor entry temporarily expires.
+			continue
+		}
+		if !learned || prevID != vlanID {
+			r.lldpVlanIDs[ifName] = vlanID
+			r.Log.Noticef("Learned VLAN ID %d assigned by the switch "+
+				"connected to port %s", vlanID, ifName)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// getLLDPAssignedVlanID queries the LLDP neighbor of the given port
+// and returns the VLAN ID assigned to the port (0 if none is advertised).
+func (r *LinuxDpcReconciler) getLLDPAssignedVlanID(ifName string) (uint16, error) {
+	out, err := base.Exec(r.Log, "lldpcli", "-f", "keyvalue", "show", "neighbors",
+		"ports", ifName, "details").CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("lldpcli failed: %s (%w)", out, err)
+	}
+	return parseLLDPAssignedVlanID(string(out)), nil
+}
+
+// parseLLDPAssignedVlanID extracts the assigned VLAN ID from the keyvalue
+// formatted output of lldpcli. The VLAN ID of the LLDP-MED network policy
+// is preferred; the port VLAN ID advertised with the 802.1 Port VLAN TLV
+// is used as a fallback. Malformed entries are ignored.
+func parseLLDPAssignedVlanID(output string) uint16 {
+	var portVlanID uint16
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found {
+			continue
+		}
+		vlanID, err := strconv.ParseUint(value, 10, 16)
+		if err != nil || vlanID == 0 {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(key, ".lldp-med.policy.vlan.vid"):
+			return uint16(vlanID)
+		case strings.HasSuffix(key, ".vlan.vlan-id"):
+			if portVlanID == 0 {
+				portVlanID = uint16(vlanID)
+			}
+		}
+	}
+	return portVlanID
+}
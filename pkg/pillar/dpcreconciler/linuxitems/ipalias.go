@@ -0,0 +1,151 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/dpcreconciler/genericitems"
+	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
+	"github.com/vishvananda/netlink"
+)
+
+// IPAlias : a secondary IP address statically assigned to a network adapter
+// alongside the primary address (see NetworkPortConfig.SecondaryAddresses).
+type IPAlias struct {
+	// AdapterLL : Adapter's logical label.
+	AdapterLL string
+	// AdapterIfName : name of the interface to which the alias is assigned.
+	AdapterIfName string
+	// Address : IP address with the subnet mask.
+	Address *net.IPNet
+}
+
+// Name combines the interface name and the aliased IP address.
+func (a IPAlias) Name() string {
+	return fmt.Sprintf("%s/%v", a.AdapterIfName, a.Address)
+}
+
+// Label for the item.
+func (a IPAlias) Label() string {
+	return fmt.Sprintf("IP alias %v for %s", a.Address, a.AdapterLL)
+}
+
+// Type of the item.
+func (a IPAlias) Type() string {
+	return IPAliasTypename
+}
+
+// Equal compares the aliased IP addresses.
+func (a IPAlias) Equal(other depgraph.Item) bool {
+	a2, isIPAlias := other.(IPAlias)
+	if !isIPAlias {
+		return false
+	}
+	return a.Address.IP.Equal(a2.Address.IP) &&
+		bytes.Equal(a.Address.Mask, a2.Address.Mask)
+}
+
+// External returns false.
+func (a IPAlias) External() bool {
+	return false
+}
+
+// String describes the IP alias.
+func (a IPAlias) String() string {
+	return fmt.Sprintf("Secondary IP address %v assigned to adapter %s",
+		a.Address, a.AdapterLL)
+}
+
+// Dependencies lists the adapter as the only dependency - the address
+// cannot be assigned until the interface exists.
+func (a IPAlias) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: genericitems.AdapterTypename,
+				ItemName: a.AdapterIfName,
+			},
+			Description: "Underlying adapter must exist",
+		},
+	}
+}
+
+// IPAliasConfigurator implements Configurator interface (libs/reconciler)
+// for secondary IP addresses.
+type IPAliasConfigurator struct {
+	Log            *base.LogObject
+	NetworkMonitor netmonitor.NetworkMonitor
+}
+
+// Create assigns the secondary IP address to the adapter.
+func (c *IPAliasConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	alias := item.(IPAlias)
+	link, err := c.getAdapterLink(alias)
+	if err != nil {
+		return err
+	}
+	if err := netlink.AddrAdd(link, &netlink.Addr{IPNet: alias.Address}); err != nil {
+		err = fmt.Errorf("netlink.AddrAdd %v on %s failed: %v",
+			alias.Address, alias.AdapterIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *IPAliasConfigurator) getAdapterLink(alias IPAlias) (netlink.Link, error) {
+	ifIdx, exists, err := c.NetworkMonitor.GetInterfaceIndex(alias.AdapterIfName)
+	if !exists {
+		// Dependencies should prevent this.
+		err := fmt.Errorf("missing interface %s", alias.AdapterIfName)
+		c.Log.Error(err)
+		return nil, err
+	}
+	if err != nil {
+		err := fmt.Errorf("GetInterfaceIndex(%s) failed: %v",
+			alias.AdapterIfName, err)
+		c.Log.Error(err)
+		return nil, err
+	}
+	link, err := netlink.LinkByIndex(ifIdx)
+	if err != nil {
+		err := fmt.Errorf("netlink.LinkByIndex(%d) failed: %v", ifIdx, err)
+		c.Log.Error(err)
+		return nil, err
+	}
+	return link, nil
+}
+
+// Modify is not implemented.
+func (c *IPAliasConfigurator) Modify(_ context.Context, _, _ depgraph.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete removes the secondary IP address from the adapter.
+func (c *IPAliasConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	alias := item.(IPAlias)
+	link, err := c.getAdapterLink(alias)
+	if err != nil {
+		return err
+	}
+	if err := netlink.AddrDel(link, &netlink.Addr{IPNet: alias.Address}); err != nil {
+		err = fmt.Errorf("netlink.AddrDel %v on %s failed: %v",
+			alias.Address, alias.AdapterIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns true - Modify is not implemented.
+func (c *IPAliasConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
@@ -6,6 +6,7 @@ package linuxitems
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	"github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve/pkg/pillar/base"
@@ -44,6 +45,9 @@ type PhysIf struct {
 	WirelessType types.WirelessType
 	// MTU : Maximum transmission unit size.
 	MTU uint16
+	// EthLink : ethtool-style link parameters (forced speed/duplex, EEE,
+	// flow control). Only applied to Ethernet interfaces.
+	EthLink types.EthLinkConfig
 }
 
 // Name returns the physical interface name.
@@ -70,7 +74,8 @@ func (p PhysIf) Equal(other depgraph.Item) bool {
 	return p.Usage == p2.Usage &&
 		p.MasterIfName == p2.MasterIfName &&
 		p.WirelessType == p.WirelessType &&
-		p.MTU == p2.MTU
+		p.MTU == p2.MTU &&
+		p.EthLink == p2.EthLink
 }
 
 // External returns false.
@@ -109,13 +114,92 @@ type PhysIfConfigurator struct {
 	Log *base.LogObject
 }
 
-// Create configures attributes of the physical network interface (currently only MTU).
+// Create configures attributes of the physical network interface
+// (MTU and Ethernet link parameters).
 func (c *PhysIfConfigurator) Create(_ context.Context, item depgraph.Item) error {
 	physIf, isPhysIf := item.(PhysIf)
 	if !isPhysIf {
 		return fmt.Errorf("invalid item type %T, expected PhysIf", item)
 	}
-	return c.updateMTU(physIf.PhysIfName, physIf.WirelessType, physIf.GetMTU())
+	err := c.updateMTU(physIf.PhysIfName, physIf.WirelessType, physIf.GetMTU())
+	if err != nil {
+		return err
+	}
+	return c.updateEthLink(physIf.PhysIfName, physIf.WirelessType,
+		types.EthLinkConfig{}, physIf.EthLink)
+}
+
+// updateEthLink applies ethtool-style link parameters to an Ethernet interface.
+// Only parameters which differ between the previous and the new config are
+// touched, i.e. with undefined EthLinkConfig the NIC is left completely alone.
+func (c *PhysIfConfigurator) updateEthLink(ifName string, wType types.WirelessType,
+	prevCfg, newCfg types.EthLinkConfig) error {
+	if wType != types.WirelessTypeNone {
+		// Link parameters are only applicable to Ethernet ports.
+		return nil
+	}
+	if newCfg.ForcedSpeed != prevCfg.ForcedSpeed ||
+		newCfg.ForcedDuplex != prevCfg.ForcedDuplex {
+		var args []string
+		if newCfg.ForcedSpeed != 0 {
+			args = []string{"-s", ifName, "autoneg", "off",
+				"speed", strconv.Itoa(int(newCfg.ForcedSpeed))}
+			if newCfg.ForcedDuplex != types.DuplexUnspecified {
+				args = append(args, "duplex", newCfg.ForcedDuplex.String())
+			}
+		} else {
+			// Forced speed/duplex is being removed, re-enable auto-negotiation.
+			args = []string{"-s", ifName, "autoneg", "on"}
+		}
+		out, err := base.Exec(c.Log, "ethtool", args...).CombinedOutput()
+		if err != nil {
+			err = fmt.Errorf("failed to set speed/duplex/autoneg for interface %s: "+
+				"%s (%w)", ifName, out, err)
+			c.Log.Error(err)
+			return err
+		}
+	}
+	if newCfg.DisableEEE != prevCfg.DisableEEE {
+		eee := "on"
+		if newCfg.DisableEEE {
+			eee = "off"
+		}
+		out, err := base.Exec(c.Log, "ethtool", "--set-eee", ifName,
+			"eee", eee).CombinedOutput()
+		if err != nil {
+			// EEE is not supported by all NICs. Report error only when EEE
+			// should be explicitly disabled.
+			if newCfg.DisableEEE {
+				err = fmt.Errorf("failed to disable EEE for interface %s: %s (%w)",
+					ifName, out, err)
+				c.Log.Error(err)
+				return err
+			}
+			c.Log.Warnf("failed to re-enable EEE for interface %s: %s (%v)",
+				ifName, out, err)
+		}
+	}
+	if newCfg.DisableFlowControl != prevCfg.DisableFlowControl {
+		flowControl := "on"
+		if newCfg.DisableFlowControl {
+			flowControl = "off"
+		}
+		out, err := base.Exec(c.Log, "ethtool", "-A", ifName,
+			"rx", flowControl, "tx", flowControl).CombinedOutput()
+		if err != nil {
+			// Flow control is not supported by all NICs. Report error only
+			// when it should be explicitly disabled.
+			if newCfg.DisableFlowControl {
+				err = fmt.Errorf("failed to disable flow control for "+
+					"interface %s: %s (%w)", ifName, out, err)
+				c.Log.Error(err)
+				return err
+			}
+			c.Log.Warnf("failed to re-enable flow control for interface %s: %s (%v)",
+				ifName, out, err)
+		}
+	}
+	return nil
 }
 
 func (c *PhysIfConfigurator) updateMTU(ifName string, wType types.WirelessType,
@@ -151,13 +235,22 @@ func (c *PhysIfConfigurator) updateMTU(ifName string, wType types.WirelessType,
 	return nil
 }
 
-// Modify is able to change the MTU value.
-func (c *PhysIfConfigurator) Modify(_ context.Context, _, newItem depgraph.Item) (err error) {
+// Modify is able to change the MTU value and the Ethernet link parameters.
+func (c *PhysIfConfigurator) Modify(_ context.Context, oldItem, newItem depgraph.Item) (err error) {
+	oldPhysIf, isPhysIf := oldItem.(PhysIf)
+	if !isPhysIf {
+		return fmt.Errorf("invalid item type %T, expected PhysIf", oldItem)
+	}
 	physIf, isPhysIf := newItem.(PhysIf)
 	if !isPhysIf {
 		return fmt.Errorf("invalid item type %T, expected PhysIf", newItem)
 	}
-	return c.updateMTU(physIf.PhysIfName, physIf.WirelessType, physIf.GetMTU())
+	err = c.updateMTU(physIf.PhysIfName, physIf.WirelessType, physIf.GetMTU())
+	if err != nil {
+		return err
+	}
+	return c.updateEthLink(physIf.PhysIfName, physIf.WirelessType,
+		oldPhysIf.EthLink, physIf.EthLink)
 }
 
 // Delete sets the default network interface attributes.
@@ -166,7 +259,12 @@ func (c *PhysIfConfigurator) Delete(_ context.Context, item depgraph.Item) error
 	if !isPhysIf {
 		return fmt.Errorf("invalid item type %T, expected PhysIf", item)
 	}
-	return c.updateMTU(physIf.PhysIfName, physIf.WirelessType, types.DefaultMTU)
+	err := c.updateMTU(physIf.PhysIfName, physIf.WirelessType, types.DefaultMTU)
+	if err != nil {
+		return err
+	}
+	return c.updateEthLink(physIf.PhysIfName, physIf.WirelessType,
+		physIf.EthLink, types.EthLinkConfig{})
 }
 
 // NeedsRecreate returns true if Usage or MasterIfName changed. This will intentionally
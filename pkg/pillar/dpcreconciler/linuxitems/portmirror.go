@@ -0,0 +1,192 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/dpcreconciler/genericitems"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// PortMirror : tc-based mirroring of (a subset of) port traffic into
+// a dedicated capture interface (see types.PortMirrorConfig).
+type PortMirror struct {
+	// PortLL : logical label of the mirrored port.
+	PortLL string
+	// PortIfName : name of the mirrored port interface.
+	PortIfName string
+	// Config : parameters of the traffic mirroring.
+	Config types.PortMirrorConfig
+}
+
+// Name returns the interface name of the mirrored port.
+// This ensures that there cannot be two mirroring configs installed
+// for the same port at the same time.
+func (m PortMirror) Name() string {
+	return m.PortIfName
+}
+
+// Label for the item.
+func (m PortMirror) Label() string {
+	return m.PortLL + " (port mirroring)"
+}
+
+// Type of the item.
+func (m PortMirror) Type() string {
+	return PortMirrorTypename
+}
+
+// Equal compares two PortMirror instances.
+func (m PortMirror) Equal(other depgraph.Item) bool {
+	m2, isPortMirror := other.(PortMirror)
+	if !isPortMirror {
+		return false
+	}
+	return m == m2
+}
+
+// External returns false.
+func (m PortMirror) External() bool {
+	return false
+}
+
+// String describes the port mirroring config.
+func (m PortMirror) String() string {
+	return fmt.Sprintf("PortMirror: {port: %s, captureIfName: %s, vlanID: %d, "+
+		"bpfFilter: %s, rateLimitMbps: %d}",
+		m.PortIfName, m.Config.CaptureIfName, m.Config.VLANID,
+		m.Config.BPFFilter, m.Config.RateLimitMbps)
+}
+
+// Dependencies lists the mirrored port adapter as the only dependency.
+// The capture interface is managed outside of the device connectivity
+// reconciler (it is typically an application VIF), hence its presence
+// is checked only at the time of the tc filter installation.
+func (m PortMirror) Dependencies() (deps []depgraph.Dependency) {
+	return []depgraph.Dependency{
+		{
+			RequiredItem: depgraph.ItemRef{
+				ItemType: genericitems.AdapterTypename,
+				ItemName: m.PortIfName,
+			},
+			Description: "Mirrored port adapter must exist",
+		},
+	}
+}
+
+// PortMirrorConfigurator implements Configurator interface (libs/reconciler)
+// for tc-based port mirroring.
+type PortMirrorConfigurator struct {
+	Log *base.LogObject
+}
+
+const tcCmd = "tc"
+
+// Create installs the clsact qdisc on the mirrored port with ingress and
+// egress filters copying the selected traffic into the capture interface
+// (tc mirred mirror action). When a bandwidth cap is configured, a tbf qdisc
+// is additionally installed on the capture interface, dropping excess
+// mirrored packets without affecting the original traffic of the port.
+func (c *PortMirrorConfigurator) Create(ctx context.Context, item depgraph.Item) error {
+	mirror, isPortMirror := item.(PortMirror)
+	if !isPortMirror {
+		return fmt.Errorf("invalid item type %T, expected PortMirror", item)
+	}
+	portIfName := mirror.PortIfName
+	args := []string{"qdisc", "add", "dev", portIfName, "clsact"}
+	if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to add clsact qdisc to port %s: %s (err: %w)",
+			portIfName, outputStr, err)
+		c.Log.Error(err)
+		return err
+	}
+	for _, direction := range []string{"ingress", "egress"} {
+		args = []string{"filter", "add", "dev", portIfName, direction, "pref", "1"}
+		switch {
+		case mirror.Config.BPFFilter != "":
+			args = append(args, "protocol", "all", "bpf",
+				"bytecode", mirror.Config.BPFFilter)
+		case mirror.Config.VLANID != 0:
+			args = append(args, "protocol", "802.1q", "flower", "vlan_id",
+				strconv.FormatUint(uint64(mirror.Config.VLANID), 10))
+		default:
+			args = append(args, "protocol", "all", "matchall")
+		}
+		args = append(args, "action", "mirred", "egress", "mirror",
+			"dev", mirror.Config.CaptureIfName)
+		if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+			outputStr := strings.TrimSpace(string(output))
+			err = fmt.Errorf("failed to add %s mirroring filter to port %s: "+
+				"%s (err: %w)", direction, portIfName, outputStr, err)
+			c.Log.Error(err)
+			return err
+		}
+	}
+	if mirror.Config.RateLimitMbps != 0 {
+		rate := strconv.FormatUint(uint64(mirror.Config.RateLimitMbps), 10)
+		args = []string{"qdisc", "add", "dev", mirror.Config.CaptureIfName,
+			"root", "tbf", "rate", rate + "mbit", "burst", "64k",
+			"latency", "50ms"}
+		if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+			outputStr := strings.TrimSpace(string(output))
+			err = fmt.Errorf("failed to add rate-limiting qdisc to capture "+
+				"interface %s: %s (err: %w)",
+				mirror.Config.CaptureIfName, outputStr, err)
+			c.Log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *PortMirrorConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) (err error) {
+	return fmt.Errorf("not implemented")
+}
+
+// Delete removes the clsact qdisc from the mirrored port, which also removes
+// all the attached mirroring filters, and uninstalls the rate-limiting qdisc
+// from the capture interface (if it was installed).
+func (c *PortMirrorConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
+	mirror, isPortMirror := item.(PortMirror)
+	if !isPortMirror {
+		return fmt.Errorf("invalid item type %T, expected PortMirror", item)
+	}
+	portIfName := mirror.PortIfName
+	if reconciler.GetOperationReason(ctx) != reconciler.ReasonDepCascade {
+		args := []string{"qdisc", "del", "dev", portIfName, "clsact"}
+		if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+			outputStr := strings.TrimSpace(string(output))
+			err = fmt.Errorf("failed to delete clsact qdisc from port %s: "+
+				"%s (err: %w)", portIfName, outputStr, err)
+			c.Log.Error(err)
+			return err
+		}
+	}
+	if mirror.Config.RateLimitMbps != 0 {
+		// The capture interface may be already gone together with
+		// the monitoring app, hence ignore errors from here.
+		args := []string{"qdisc", "del", "dev", mirror.Config.CaptureIfName,
+			"root", "tbf"}
+		if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+			c.Log.Warnf("failed to delete rate-limiting qdisc from capture "+
+				"interface %s: %s (err: %v)", mirror.Config.CaptureIfName,
+				strings.TrimSpace(string(output)), err)
+		}
+	}
+	return nil
+}
+
+// NeedsRecreate returns true - Modify is not implemented.
+func (c *PortMirrorConfigurator) NeedsRecreate(oldItem, newItem depgraph.Item) (recreate bool) {
+	return true
+}
@@ -8,11 +8,13 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/dpcreconciler/genericitems"
 	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 )
 
 // RegisterItems : register all configurators implemented by this package.
 func RegisterItems(log *base.LogObject, registry *reconciler.DefaultRegistry,
-	monitor netmonitor.NetworkMonitor) error {
+	monitor netmonitor.NetworkMonitor,
+	procSupervisor *procsupervisor.ProcSupervisor) error {
 	type configurator struct {
 		c reconciler.Configurator
 		t string
@@ -22,12 +24,15 @@ func RegisterItems(log *base.LogObject, registry *reconciler.DefaultRegistry,
 		{c: &AdapterConfigurator{Log: log, NetworkMonitor: monitor}, t: genericitems.AdapterTypename},
 		{c: &ArpConfigurator{Log: log}, t: genericitems.ArpTypename},
 		{c: &BondConfigurator{Log: log, NetworkMonitor: monitor}, t: genericitems.BondTypename},
+		{c: &IPAliasConfigurator{Log: log, NetworkMonitor: monitor}, t: IPAliasTypename},
 		{c: &LocalIPRuleConfigurator{Log: log}, t: LocalIPRuleTypename},
+		{c: &PortMirrorConfigurator{Log: log}, t: PortMirrorTypename},
 		{c: &RouteConfigurator{Log: log}, t: genericitems.IPv4RouteTypename},
 		{c: &RouteConfigurator{Log: log}, t: genericitems.IPv6RouteTypename},
 		{c: &SrcIPRuleConfigurator{Log: log, NetworkMonitor: monitor}, t: SrcIPRuleTypename},
 		{c: &VlanConfigurator{Log: log, NetworkMonitor: monitor}, t: genericitems.VlanTypename},
-		{c: &WlanConfigurator{Log: log}, t: genericitems.WlanTypename},
+		{c: &WlanConfigurator{Log: log, ProcSupervisor: procSupervisor},
+			t: genericitems.WlanTypename},
 	}
 	for _, configurator := range configurators {
 		err := registry.Register(configurator.c, configurator.t)
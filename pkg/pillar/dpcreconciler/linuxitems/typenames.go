@@ -8,4 +8,9 @@ const (
 	LocalIPRuleTypename = "Local-IP-Rule"
 	// SrcIPRuleTypename : typename for source-based IP rules.
 	SrcIPRuleTypename = "Src-IP-Rule"
+	// IPAliasTypename : typename for secondary IP addresses assigned to adapters.
+	IPAliasTypename = "IP-Alias"
+	// PortMirrorTypename : typename for tc-based mirroring of port traffic
+	// into a capture interface.
+	PortMirrorTypename = "Port-Mirror"
 )
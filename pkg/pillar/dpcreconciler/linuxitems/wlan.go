@@ -11,9 +11,11 @@ import (
 	"strings"
 
 	"github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/devicenetwork"
 	"github.com/lf-edge/eve/pkg/pillar/dpcreconciler/genericitems"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 )
 
@@ -80,35 +82,60 @@ func (w Wlan) Dependencies() (deps []depgraph.Dependency) {
 // WlanConfigurator implements Configurator interface (libs/reconciler) for wpa_supplicant.conf.
 type WlanConfigurator struct {
 	Log *base.LogObject
+	// ProcSupervisor : if defined, the wpa_supplicant daemon is registered
+	// with the supervisor whenever there is WiFi network to connect to.
+	// The supervisor will only monitor and report daemon liveness - restarting
+	// is left to the service which spawned wpa_supplicant (outside of pillar).
+	ProcSupervisor *procsupervisor.ProcSupervisor
 }
 
 // Create installs wpa_supplicant.conf.
+// The rfkill operation can take a while on some RF hardware, therefore it is
+// run asynchronously so that bring-up of other ports is not blocked.
 func (c *WlanConfigurator) Create(ctx context.Context, item depgraph.Item) error {
 	wlan := item.(Wlan)
-	err := c.installWifiConfig(wlan.Config)
-	if err != nil {
-		return err
-	}
-	return c.toggleWlanRF(wlan.EnableRF)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := c.installWifiConfig(wlan.Config)
+		if err != nil {
+			done(err)
+			return
+		}
+		c.superviseWpaSupplicant(wlan)
+		done(c.toggleWlanRF(wlan.EnableRF))
+	}()
+	return nil
 }
 
 // Modify updates the content of wpa_supplicant.conf.
 func (c *WlanConfigurator) Modify(ctx context.Context, oldItem, newItem depgraph.Item) error {
 	wlan := newItem.(Wlan)
-	err := c.installWifiConfig(wlan.Config)
-	if err != nil {
-		return err
-	}
-	return c.toggleWlanRF(wlan.EnableRF)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := c.installWifiConfig(wlan.Config)
+		if err != nil {
+			done(err)
+			return
+		}
+		c.superviseWpaSupplicant(wlan)
+		done(c.toggleWlanRF(wlan.EnableRF))
+	}()
+	return nil
 }
 
 // Delete clears previously installed wpa file.
 func (c *WlanConfigurator) Delete(ctx context.Context, item depgraph.Item) error {
-	err := c.installWifiConfig([]WifiConfig{})
-	if err != nil {
-		return err
-	}
-	return c.toggleWlanRF(false)
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := c.installWifiConfig([]WifiConfig{})
+		if err != nil {
+			done(err)
+			return
+		}
+		c.superviseWpaSupplicant(Wlan{})
+		done(c.toggleWlanRF(false))
+	}()
+	return nil
 }
 
 // NeedsRecreate returns false - Modify can apply any change.
@@ -146,6 +173,9 @@ func (c *WlanConfigurator) installWifiConfig(config []WifiConfig) error {
 		tmpfile.WriteString("# No WiFi config received\n")
 	} else {
 		tmpfile.WriteString("# Automatically generated\n")
+		// Control socket allows pillar to query the association status.
+		s := fmt.Sprintf("ctrl_interface=%s\n", devicenetwork.WpaCtrlDir)
+		tmpfile.WriteString(s)
 		for _, wifi := range config {
 			tmpfile.WriteString("network={\n")
 			s := fmt.Sprintf("        ssid=\"%s\"\n", wifi.SSID)
@@ -181,6 +211,23 @@ func (c *WlanConfigurator) installWifiConfig(config []WifiConfig) error {
 				s = fmt.Sprintf("        priority=%d\n", wifi.Priority)
 				tmpfile.WriteString(s)
 			}
+			if wifi.Bgscan.Enable {
+				shortInterval := wifi.Bgscan.ShortInterval
+				if shortInterval == 0 {
+					shortInterval = 30
+				}
+				longInterval := wifi.Bgscan.LongInterval
+				if longInterval == 0 {
+					longInterval = 300
+				}
+				signalThreshold := wifi.Bgscan.SignalThreshold
+				if signalThreshold == 0 {
+					signalThreshold = -70
+				}
+				s = fmt.Sprintf("        bgscan=\"simple:%d:%d:%d\"\n",
+					shortInterval, signalThreshold, longInterval)
+				tmpfile.WriteString(s)
+			}
 			tmpfile.WriteString("}\n")
 		}
 	}
@@ -224,3 +271,28 @@ func (c *WlanConfigurator) toggleWlanRF(enableRF bool) error {
 	}
 	return nil
 }
+
+// Command name under which wpa_supplicant runs and is registered with
+// the process supervisor.
+const wpaProcName = "wpa_supplicant"
+
+// superviseWpaSupplicant registers the wpa_supplicant daemon with the process
+// supervisor when there is at least one WiFi network configured and the radio
+// transmission is enabled, and unregisters it otherwise.
+// The registration is monitor-only (see WlanConfigurator.ProcSupervisor).
+func (c *WlanConfigurator) superviseWpaSupplicant(wlan Wlan) {
+	if c.ProcSupervisor == nil {
+		return
+	}
+	if len(wlan.Config) == 0 || !wlan.EnableRF {
+		c.ProcSupervisor.ForgetProcess(wpaProcName)
+		return
+	}
+	c.ProcSupervisor.TrackProcess(procsupervisor.ProcessConfig{
+		Name:       wpaProcName,
+		CheckAlive: procsupervisor.ProcNameCheckAlive(wpaProcName),
+		// wpa_supplicant is spawned by a separate service (outside of pillar),
+		// here we only monitor and report its liveness.
+		Restart: nil,
+	})
+}
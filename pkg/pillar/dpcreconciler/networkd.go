@@ -0,0 +1,335 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcreconciler
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+const (
+	// NetworkdConfigDir : directory where systemd-networkd loads runtime
+	// configuration files from.
+	NetworkdConfigDir = "/run/systemd/network"
+	// Prefix used for all config files rendered by this reconciler.
+	// Allows to distinguish them from files installed by other tools.
+	networkdFilePrefix = "10-eve-"
+
+	networkdDbusService  = "org.freedesktop.network1"
+	networkdDbusObject   = "/org/freedesktop/network1"
+	networkdReloadMethod = "org.freedesktop.network1.Manager.Reload"
+	networkdFilePerm     = 0644
+)
+
+// NetworkdDpcReconciler implements DpcReconciler for EVE variants based
+// on standard Linux distributions running systemd-networkd. Instead of
+// directly managing network configuration via netlink and dhcpcd (like
+// LinuxDpcReconciler does), it renders *.network/*.netdev files under
+// /run/systemd/network and asks networkd to reload them over DBus.
+// This allows to reuse the DPC manager logic (DPC testing, fallback, etc.)
+// on top of a distro-managed network stack.
+// Feature coverage is limited in comparison to LinuxDpcReconciler:
+// wireless connectivity, proxies, ARP/GARP tuning, and cipher-protected
+// credentials are not handled by this backend.
+type NetworkdDpcReconciler struct {
+	sync.Mutex
+
+	// Note: the exported attributes below should be injected.
+	Log       *base.LogObject // mandatory
+	AgentName string
+	// ConfigDir : directory where the rendered config files are placed.
+	// Leave empty to use the default NetworkdConfigDir.
+	ConfigDir string
+
+	// Rendered config files from the last Reconcile, keyed by file name.
+	renderedFiles map[string]string
+	// Never signaled - this backend has no asynchronous operations
+	// and does not watch the current state.
+	resumeReconcile chan struct{}
+}
+
+// Reconcile renders the DPC into networkd configuration files and asks
+// networkd to reload them if anything has changed.
+// All operations are synchronous.
+func (r *NetworkdDpcReconciler) Reconcile(ctx context.Context, args Args) ReconcileStatus {
+	r.Lock()
+	defer r.Unlock()
+	if r.resumeReconcile == nil {
+		r.resumeReconcile = make(chan struct{}, 1)
+	}
+	status := ReconcileStatus{
+		ResumeReconcile: r.resumeReconcile,
+		CancelAsyncOps:  func() {},
+		WaitForAsyncOps: func() {},
+		RS:              args.RS,
+		DNS: DNSStatus{
+			Servers: make(map[string][]net.IP),
+		},
+	}
+	files := r.renderConfigFiles(args.DPC)
+	for _, port := range args.DPC.Ports {
+		if !port.IsL3Port || port.InvalidConfig {
+			continue
+		}
+		status.DNS.Servers[port.IfName] = port.DNSServers
+	}
+	if r.filesUnchanged(files) {
+		return status
+	}
+	if err := r.installConfigFiles(files); err != nil {
+		status.Error = err
+		return status
+	}
+	r.renderedFiles = files
+	if err := r.reloadNetworkd(ctx); err != nil {
+		status.Error = err
+		return status
+	}
+	return status
+}
+
+// renderConfigFiles builds the intended set of networkd configuration files
+// (file name -> content) for the given DPC.
+func (r *NetworkdDpcReconciler) renderConfigFiles(
+	dpc types.DevicePortConfig) map[string]string {
+	files := make(map[string]string)
+	// VLAN sub-interfaces and bond slaves require entries in the config
+	// of their parent/master interface.
+	vlansByParent := make(map[string][]string) // parent ifName -> VLAN ifNames
+	bondBySlave := make(map[string]string)     // slave ifName -> bond ifName
+	for _, port := range dpc.Ports {
+		switch port.L2LinkConfig.L2Type {
+		case types.L2LinkTypeVLAN:
+			parent := r.logicalLabelToIfName(dpc, port.L2LinkConfig.VLAN.ParentPort)
+			vlansByParent[parent] = append(vlansByParent[parent], port.IfName)
+		case types.L2LinkTypeBond:
+			for _, slave := range port.L2LinkConfig.Bond.AggregatedPorts {
+				bondBySlave[r.logicalLabelToIfName(dpc, slave)] = port.IfName
+			}
+		}
+	}
+	for _, port := range dpc.Ports {
+		if port.InvalidConfig || port.IfName == "" {
+			continue
+		}
+		switch port.L2LinkConfig.L2Type {
+		case types.L2LinkTypeVLAN:
+			files[networkdFilePrefix+port.IfName+".netdev"] =
+				r.renderVLANNetdev(port)
+		case types.L2LinkTypeBond:
+			files[networkdFilePrefix+port.IfName+".netdev"] =
+				r.renderBondNetdev(port)
+		}
+		files[networkdFilePrefix+port.IfName+".network"] = r.renderNetwork(
+			port, vlansByParent[port.IfName], bondBySlave[port.IfName])
+	}
+	// Bond slaves and VLAN parents are not necessarily present in the DPC
+	// as separate ports - make sure they get a .network file as well.
+	for parent, vlans := range vlansByParent {
+		fileName := networkdFilePrefix + parent + ".network"
+		if _, rendered := files[fileName]; !rendered {
+			files[fileName] = r.renderNetwork(types.NetworkPortConfig{
+				IfName: parent,
+			}, vlans, bondBySlave[parent])
+		}
+	}
+	for slave, bond := range bondBySlave {
+		fileName := networkdFilePrefix + slave + ".network"
+		if _, rendered := files[fileName]; !rendered {
+			files[fileName] = r.renderNetwork(types.NetworkPortConfig{
+				IfName: slave,
+			}, vlansByParent[slave], bond)
+		}
+	}
+	return files
+}
+
+// renderNetwork renders the .network file for the given port.
+func (r *NetworkdDpcReconciler) renderNetwork(port types.NetworkPortConfig,
+	vlans []string, bond string) string {
+	var sb strings.Builder
+	sb.WriteString("# Automatically generated by EVE - do not edit\n")
+	sb.WriteString("[Match]\n")
+	fmt.Fprintf(&sb, "Name=%s\n", port.IfName)
+	sb.WriteString("\n[Network]\n")
+	switch port.Dhcp {
+	case types.DhcpTypeClient:
+		switch port.Type {
+		case types.NetworkTypeIPv4, types.NetworkTypeIpv4Only:
+			sb.WriteString("DHCP=ipv4\n")
+		case types.NetworkTypeIPV6, types.NetworkTypeIpv6Only:
+			sb.WriteString("DHCP=ipv6\n")
+		default:
+			sb.WriteString("DHCP=yes\n")
+		}
+	case types.DhcpTypeStatic:
+		if port.AddrSubnet != "" {
+			fmt.Fprintf(&sb, "Address=%s\n", port.AddrSubnet)
+		}
+		if port.Gateway != nil {
+			fmt.Fprintf(&sb, "Gateway=%s\n", port.Gateway)
+		}
+	}
+	for _, dnsServer := range port.DNSServers {
+		fmt.Fprintf(&sb, "DNS=%s\n", dnsServer)
+	}
+	if port.NTPServer != nil {
+		fmt.Fprintf(&sb, "NTP=%s\n", port.NTPServer)
+	}
+	if port.DomainName != "" {
+		fmt.Fprintf(&sb, "Domains=%s\n", port.DomainName)
+	}
+	// Sort for deterministic output (used for change detection).
+	sort.Strings(vlans)
+	for _, vlan := range vlans {
+		fmt.Fprintf(&sb, "VLAN=%s\n", vlan)
+	}
+	if bond != "" {
+		fmt.Fprintf(&sb, "Bond=%s\n", bond)
+	}
+	return sb.String()
+}
+
+// renderVLANNetdev renders the .netdev file for a VLAN sub-interface.
+func (r *NetworkdDpcReconciler) renderVLANNetdev(port types.NetworkPortConfig) string {
+	var sb strings.Builder
+	sb.WriteString("# Automatically generated by EVE - do not edit\n")
+	sb.WriteString("[NetDev]\n")
+	fmt.Fprintf(&sb, "Name=%s\n", port.IfName)
+	sb.WriteString("Kind=vlan\n")
+	sb.WriteString("\n[VLAN]\n")
+	fmt.Fprintf(&sb, "Id=%d\n", port.L2LinkConfig.VLAN.ID)
+	return sb.String()
+}
+
+// renderBondNetdev renders the .netdev file for a bond interface.
+func (r *NetworkdDpcReconciler) renderBondNetdev(port types.NetworkPortConfig) string {
+	var sb strings.Builder
+	sb.WriteString("# Automatically generated by EVE - do not edit\n")
+	sb.WriteString("[NetDev]\n")
+	fmt.Fprintf(&sb, "Name=%s\n", port.IfName)
+	sb.WriteString("Kind=bond\n")
+	sb.WriteString("\n[Bond]\n")
+	fmt.Fprintf(&sb, "Mode=%s\n", bondModeToNetworkd(port.L2LinkConfig.Bond.Mode))
+	if port.L2LinkConfig.Bond.Mode == types.BondMode802Dot3AD {
+		if port.L2LinkConfig.Bond.LacpRate == types.LacpRateFast {
+			sb.WriteString("LACPTransmitRate=fast\n")
+		} else {
+			sb.WriteString("LACPTransmitRate=slow\n")
+		}
+	}
+	if port.L2LinkConfig.Bond.MIIMonitor.Enabled {
+		fmt.Fprintf(&sb, "MIIMonitorSec=%dms\n",
+			port.L2LinkConfig.Bond.MIIMonitor.Interval)
+	}
+	return sb.String()
+}
+
+// bondModeToNetworkd translates BondMode to the mode name expected
+// by networkd in the [Bond] section.
+func bondModeToNetworkd(mode types.BondMode) string {
+	switch mode {
+	case types.BondModeActiveBackup:
+		return "active-backup"
+	case types.BondModeBalanceXOR:
+		return "balance-xor"
+	case types.BondModeBroadcast:
+		return "broadcast"
+	case types.BondMode802Dot3AD:
+		return "802.3ad"
+	case types.BondModeBalanceTLB:
+		return "balance-tlb"
+	case types.BondModeBalanceALB:
+		return "balance-alb"
+	}
+	return "balance-rr"
+}
+
+// logicalLabelToIfName translates port logical label to the interface name.
+func (r *NetworkdDpcReconciler) logicalLabelToIfName(
+	dpc types.DevicePortConfig, logicalLabel string) string {
+	if port := dpc.LookupPortByLogicallabel(logicalLabel); port != nil {
+		return port.IfName
+	}
+	// Assume that the label directly references the interface.
+	return logicalLabel
+}
+
+// filesUnchanged returns true if the given set of config files matches
+// the outcome of the last successful Reconcile.
+func (r *NetworkdDpcReconciler) filesUnchanged(files map[string]string) bool {
+	if len(files) != len(r.renderedFiles) {
+		return false
+	}
+	for fileName, content := range files {
+		if r.renderedFiles[fileName] != content {
+			return false
+		}
+	}
+	return true
+}
+
+// installConfigFiles writes the rendered files into the networkd config
+// directory and removes EVE-rendered files which are no longer needed.
+func (r *NetworkdDpcReconciler) installConfigFiles(files map[string]string) error {
+	configDir := r.ConfigDir
+	if configDir == "" {
+		configDir = NetworkdConfigDir
+	}
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", configDir, err)
+	}
+	for fileName, content := range files {
+		filePath := filepath.Join(configDir, fileName)
+		err := os.WriteFile(filePath, []byte(content), networkdFilePerm)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", filePath, err)
+		}
+	}
+	// Remove stale files rendered by a previous Reconcile.
+	dirEntries, err := os.ReadDir(configDir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", configDir, err)
+	}
+	for _, dirEntry := range dirEntries {
+		fileName := dirEntry.Name()
+		if !strings.HasPrefix(fileName, networkdFilePrefix) {
+			continue
+		}
+		if _, intended := files[fileName]; intended {
+			continue
+		}
+		filePath := filepath.Join(configDir, fileName)
+		if err := os.Remove(filePath); err != nil {
+			return fmt.Errorf("failed to remove stale file %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
+
+// reloadNetworkd asks systemd-networkd over DBus to reload configuration
+// files.
+func (r *NetworkdDpcReconciler) reloadNetworkd(ctx context.Context) error {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return fmt.Errorf("failed to connect to system DBus: %w", err)
+	}
+	obj := conn.Object(networkdDbusService, networkdDbusObject)
+	call := obj.CallWithContext(ctx, networkdReloadMethod, 0)
+	if call.Err != nil {
+		return fmt.Errorf("networkd Reload over DBus failed: %w", call.Err)
+	}
+	r.Log.Noticef("Reloaded systemd-networkd configuration")
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dpcreconciler
+
+import (
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// When the kernel/udev enumerates NICs in a different order than during
+// previous boots, a port configuration referring to an interface by name
+// may end up applied to a different physical NIC. Ports carrying a PCI or
+// USB address are, however, uniquely identified by it, which allows the
+// reconciler to detect such renaming and remap the configuration to the
+// interface actually backed by the configured device. Remappings are
+// reported back through ReconcileStatus.RenamedPorts and eventually
+// published as part of DeviceNetworkStatus.
+
+// resolvePortIfNames checks every physical port of the DPC which carries
+// a PCI or USB address and, if the corresponding network interface is found
+// under a different name than configured, remaps the port configuration
+// (in-place) to the actual interface name.
+// Returns the applied remappings as a map of port logical label to the actual
+// interface name (nil when no port is affected).
+func (r *LinuxDpcReconciler) resolvePortIfNames(
+	dpc *types.DevicePortConfig) map[string]string {
+	var renamedPorts map[string]string
+	for i := range dpc.Ports {
+		port := &dpc.Ports[i]
+		if port.L2Type != types.L2LinkTypeNone {
+			// Logical lower links (VLANs, bonds) take their names
+			// from the configuration.
+			continue
+		}
+		if port.PCIAddr == "" && port.USBAddr == "" {
+			// Without a stable device identifier we have no way to detect
+			// renaming - use the interface name as-is.
+			continue
+		}
+		actualIfName, found := r.findIfNameByBusAddr(port.PCIAddr, port.USBAddr)
+		if !found || actualIfName == port.IfName {
+			continue
+		}
+		r.Log.Warnf("Network interface of port %s (PCI: %s, USB: %s) was "+
+			"renamed from %s to %s, remapping port configuration",
+			port.Logicallabel, port.PCIAddr, port.USBAddr,
+			port.IfName, actualIfName)
+		if renamedPorts == nil {
+			renamedPorts = make(map[string]string)
+		}
+		renamedPorts[port.Logicallabel] = actualIfName
+		port.IfName = actualIfName
+	}
+	return renamedPorts
+}
+
+// findIfNameByBusAddr returns the name of the network interface backed
+// by the device with the given PCI or USB address.
+// The lookup is intentionally conservative - when the device cannot be
+// positively located (e.g. it is in PCIBack), it returns found=false
+// and the caller keeps the configured interface name.
+func (r *LinuxDpcReconciler) findIfNameByBusAddr(
+	pciAddr, usbAddr string) (ifName string, found bool) {
+	if pciAddr != "" {
+		found, ifName = types.PciLongToIfname(r.Log, pciAddr)
+		return ifName, found && ifName != ""
+	}
+	// For USB devices there is no direct sysfs mapping from the address
+	// to the interface name - scan the present interfaces instead.
+	ifNames, err := r.NetworkMonitor.ListInterfaces()
+	if err != nil {
+		r.Log.Errorf("findIfNameByBusAddr: failed to list interfaces: %v", err)
+		return "", false
+	}
+	for _, ifName = range ifNames {
+		_, ifUSBAddr, err := types.IfnameToPciAndUsbAddr(r.Log, ifName)
+		if err != nil {
+			// Most likely a virtual interface without an underlying bus device.
+			continue
+		}
+		if ifUSBAddr == usbAddr {
+			return ifName, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ipsets provides a goroutine-safe manager for Linux ipsets
+// (https://ipset.netfilter.org/index.html) shared by multiple reconcilers
+// running inside the same agent. Content of an already existing ipset is
+// never updated in-place (flush followed by add would leave a window
+// during which the referencing iptables rules match against a partially
+// loaded set) - instead, the new content is first loaded under a temporary
+// name and then atomically swapped with the original set. Sets used by
+// multiple components are reference-counted and destroyed only when
+// released by the last user.
+package ipsets
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
+	"github.com/vishvananda/netlink"
+)
+
+const ipsetCmd = "ipset"
+
+// Netfilter limits ipset name to contain at most 31 characters.
+const ipsetNameLenLimit = 31
+
+// IPSetManager manages Linux ipsets on behalf of multiple users
+// (the NI and DPC reconcilers). It is safe for concurrent use
+// by multiple goroutines.
+type IPSetManager struct {
+	sync.Mutex
+	log  *base.LogObject
+	sets map[string]*ipsetState
+}
+
+type ipsetState struct {
+	typeName   string
+	addrFamily int
+	entries    []string
+	refCount   int
+}
+
+var (
+	manager     *IPSetManager
+	managerOnce sync.Once
+)
+
+// GetIPSetManager returns the singleton instance of the ipset manager,
+// creating it on the first call.
+func GetIPSetManager(log *base.LogObject) *IPSetManager {
+	managerOnce.Do(func() {
+		manager = &IPSetManager{
+			log:  log,
+			sets: make(map[string]*ipsetState),
+		}
+	})
+	return manager
+}
+
+// AcquireIPSet registers a user of the given ipset, creating the set
+// if this is the first user. When the set already exists but with
+// a different content, the entries are replaced atomically.
+// Acquiring an existing set with a different type or address family
+// is refused - ipset swap requires both sets to be of the same type
+// and family.
+func (m *IPSetManager) AcquireIPSet(
+	setName, typeName string, addrFamily int, entries []string) error {
+	m.Lock()
+	defer m.Unlock()
+	set, exists := m.sets[setName]
+	if exists {
+		if set.typeName != typeName || set.addrFamily != addrFamily {
+			return fmt.Errorf("ipset %s already exists with type %s "+
+				"and family %d (requested: %s, %d)", setName,
+				set.typeName, set.addrFamily, typeName, addrFamily)
+		}
+		set.refCount++
+		if !generics.EqualSets(set.entries, entries) {
+			return m.swapEntries(setName, set, entries)
+		}
+		return nil
+	}
+	family, err := familyStr(addrFamily)
+	if err != nil {
+		return err
+	}
+	// The new set is not yet referenced by any iptables rule, hence
+	// it is safe to fill it in-place.
+	if err := m.createAndFill(setName, typeName, family, entries); err != nil {
+		return err
+	}
+	m.sets[setName] = &ipsetState{
+		typeName:   typeName,
+		addrFamily: addrFamily,
+		entries:    entries,
+		refCount:   1,
+	}
+	return nil
+}
+
+// UpdateIPSet atomically replaces the content of an already acquired ipset.
+func (m *IPSetManager) UpdateIPSet(setName string, entries []string) error {
+	m.Lock()
+	defer m.Unlock()
+	set, exists := m.sets[setName]
+	if !exists {
+		return fmt.Errorf("ipset %s is not acquired", setName)
+	}
+	if generics.EqualSets(set.entries, entries) {
+		return nil
+	}
+	return m.swapEntries(setName, set, entries)
+}
+
+// ReleaseIPSet unregisters a user of the given ipset. The set is destroyed
+// once it is released by the last user.
+func (m *IPSetManager) ReleaseIPSet(setName string) error {
+	m.Lock()
+	defer m.Unlock()
+	set, exists := m.sets[setName]
+	if !exists {
+		return fmt.Errorf("ipset %s is not acquired", setName)
+	}
+	set.refCount--
+	if set.refCount > 0 {
+		return nil
+	}
+	delete(m.sets, setName)
+	args := []string{"destroy", setName}
+	if _, err := base.Exec(m.log, ipsetCmd, args...).CombinedOutput(); err != nil {
+		err = fmt.Errorf("failed to destroy ipset %s: %w", setName, err)
+		m.log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// swapEntries atomically replaces the set of entries: the new content
+// is first loaded into a temporary ipset, which is then swapped with
+// the original and destroyed.
+// The caller must hold the manager lock.
+func (m *IPSetManager) swapEntries(
+	setName string, set *ipsetState, entries []string) error {
+	family, err := familyStr(set.addrFamily)
+	if err != nil {
+		return err
+	}
+	tmpName := "tmp." + setName
+	if len(tmpName) > ipsetNameLenLimit {
+		tmpName = tmpName[:ipsetNameLenLimit]
+	}
+	// Remove a potential leftover from a previously failed swap.
+	_, _ = base.Exec(m.log, ipsetCmd, "destroy", tmpName).CombinedOutput()
+	if err := m.createAndFill(tmpName, set.typeName, family, entries); err != nil {
+		return err
+	}
+	args := []string{"swap", tmpName, setName}
+	if output, err := base.Exec(m.log, ipsetCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to swap ipsets %s and %s: %s (err: %w)",
+			tmpName, setName, outputStr, err)
+		m.log.Error(err)
+		return err
+	}
+	if output, err := base.Exec(
+		m.log, ipsetCmd, "destroy", tmpName).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		m.log.Warnf("failed to destroy temporary ipset %s: %s (err: %v)",
+			tmpName, outputStr, err)
+	}
+	set.entries = entries
+	return nil
+}
+
+// createAndFill creates a new ipset and adds all the requested entries.
+// The caller must hold the manager lock.
+func (m *IPSetManager) createAndFill(
+	setName, typeName, family string, entries []string) error {
+	args := []string{"create", setName, typeName, "family", family}
+	if output, err := base.Exec(m.log, ipsetCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to create ipset %s (type: %s, family: %s): "+
+			"%s (err: %w)", setName, typeName, family, outputStr, err)
+		m.log.Error(err)
+		return err
+	}
+	for _, entry := range entries {
+		args = []string{"add", setName, entry}
+		if output, err := base.Exec(m.log, ipsetCmd, args...).CombinedOutput(); err != nil {
+			outputStr := strings.TrimSpace(string(output))
+			err = fmt.Errorf("failed to add entry %s into ipset %s: %s (err: %w)",
+				entry, setName, outputStr, err)
+			m.log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func familyStr(addrFamily int) (string, error) {
+	switch addrFamily {
+	case netlink.FAMILY_V4:
+		return "inet", nil
+	case netlink.FAMILY_V6:
+		return "inet6", nil
+	}
+	return "", fmt.Errorf("unsupported ipset address family: %d", addrFamily)
+}
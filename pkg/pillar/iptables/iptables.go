@@ -22,11 +22,26 @@ const (
 	AppChainSuffix = "-apps"
 )
 
-const (
+// Not constants so that UseLegacyCmds can switch to the legacy backend
+// binaries when the default (nft-based) backend is not usable.
+var (
 	iptablesCmd  = "iptables"
 	ip6tablesCmd = "ip6tables"
 )
 
+// UseLegacyCmds switches all iptables operations to the iptables-legacy
+// and ip6tables-legacy binaries. It is called at boot by NIM, before any
+// rules are installed, when the conformance checker finds the default
+// iptables backend broken but the legacy one available.
+func UseLegacyCmds() {
+	iptablesCmd = "iptables-legacy"
+	ip6tablesCmd = "ip6tables-legacy"
+	iptablesFn = map[string]iptablesFnType{
+		iptablesCmd:  IptableCmd,
+		ip6tablesCmd: Ip6tableCmd,
+	}
+}
+
 type iptablesFnType func(log *base.LogObject, args ...string) error
 
 var iptablesFn = map[string]iptablesFnType{
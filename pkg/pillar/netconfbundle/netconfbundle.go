@@ -0,0 +1,192 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package netconfbundle implements export and import of the full network
+// configuration of a device as a single signed bundle. It is meant to speed
+// up commissioning of identical sites: the effective DevicePortConfig,
+// the network instance definitions and references to the related encrypted
+// credentials of an already commissioned device are captured into a bundle,
+// which can then be applied on another device as an override network
+// configuration. Bundles are authenticated with HMAC-SHA256 computed using
+// a pre-shared key, typically provisioned into the read-only config
+// partition (just like the key of the out-of-band management channel,
+// see pkg oobchannel); a bundle with a missing or invalid signature is
+// rejected by the importer.
+// Note that secrets are never embedded in plain text - wireless credentials
+// are carried only as references to cipher contexts (see
+// types.CipherBlockStatus) and must be (re-)provisioned by the controller
+// on the importing device.
+package netconfbundle
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+// FormatVersion : version of the bundle format produced by Export.
+// Import refuses bundles with a different format version.
+const FormatVersion = 1
+
+// Bundle : the full network configuration of a device, exported to speed up
+// commissioning of other devices at identical sites.
+type Bundle struct {
+	// FormatVersion : version of the bundle format (see FormatVersion).
+	// Filled in by Export.
+	FormatVersion int
+	// CreatedAt : time when the bundle was exported.
+	CreatedAt time.Time
+	// DeviceUUID : UUID of the exporting device (zero UUID if the device
+	// was not yet onboarded).
+	DeviceUUID uuid.UUID
+	// DevicePortConfig : the effective port configuration of the exporting
+	// device. This is what the importing device applies as an override
+	// network configuration.
+	DevicePortConfig types.DevicePortConfig
+	// NetworkInstances : definitions of the network instances deployed
+	// on the exporting device. Included for reference and validation only -
+	// network instances are provisioned by the controller once the importing
+	// device is onboarded.
+	NetworkInstances []types.NetworkInstanceConfig
+	// CipherContextRefs : IDs of the cipher contexts referenced by
+	// the encrypted credentials inside DevicePortConfig. The referenced
+	// contexts (and the credentials themselves) are not part of the bundle
+	// and must be provisioned by the controller separately.
+	// Filled in by Export.
+	CipherContextRefs []string
+}
+
+// signedBundle : the wire format of an exported bundle.
+type signedBundle struct {
+	// Payload : JSON-serialized Bundle.
+	Payload json.RawMessage `json:"payload"`
+	// Signature : hex-encoded HMAC-SHA256 of Payload, computed using
+	// the pre-shared key.
+	Signature string `json:"signature"`
+}
+
+// Export serializes the bundle and signs it with the given pre-shared key.
+func Export(bundle Bundle, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.New("missing signing key")
+	}
+	bundle.FormatVersion = FormatVersion
+	bundle.CipherContextRefs = collectCipherContextRefs(bundle.DevicePortConfig)
+	if err := bundle.Validate(); err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+	signed := signedBundle{
+		Payload:   payload,
+		Signature: hex.EncodeToString(computeSignature(payload, key)),
+	}
+	data, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal signed bundle: %w", err)
+	}
+	return data, nil
+}
+
+// Import verifies the signature of an exported bundle using the given
+// pre-shared key, then deserializes and validates the carried network
+// configuration.
+func Import(data []byte, key []byte) (bundle Bundle, err error) {
+	if len(key) == 0 {
+		return bundle, errors.New("missing verification key")
+	}
+	var signed signedBundle
+	if err = json.Unmarshal(data, &signed); err != nil {
+		return bundle, fmt.Errorf("failed to unmarshal signed bundle: %w", err)
+	}
+	signature, err := hex.DecodeString(signed.Signature)
+	if err != nil {
+		return bundle, fmt.Errorf("malformed bundle signature: %w", err)
+	}
+	if !hmac.Equal(signature, computeSignature(signed.Payload, key)) {
+		return bundle, errors.New("invalid bundle signature")
+	}
+	if err = json.Unmarshal(signed.Payload, &bundle); err != nil {
+		return bundle, fmt.Errorf("failed to unmarshal bundle: %w", err)
+	}
+	if bundle.FormatVersion != FormatVersion {
+		return bundle, fmt.Errorf("unsupported bundle format version %d "+
+			"(expected %d)", bundle.FormatVersion, FormatVersion)
+	}
+	if err = bundle.Validate(); err != nil {
+		return bundle, err
+	}
+	return bundle, nil
+}
+
+// Validate performs static validation of the network configuration carried
+// by the bundle.
+func (b Bundle) Validate() error {
+	if len(b.DevicePortConfig.Ports) == 0 {
+		return errors.New("bundle without any port configuration")
+	}
+	var hasMgmtPort bool
+	for _, port := range b.DevicePortConfig.Ports {
+		if port.Logicallabel == "" {
+			return fmt.Errorf("port %s without logical label", port.IfName)
+		}
+		if port.IsMgmt {
+			hasMgmtPort = true
+		}
+	}
+	if !hasMgmtPort {
+		return errors.New("bundle without any management port")
+	}
+	for _, ni := range b.NetworkInstances {
+		if ni.UUID == uuid.Nil {
+			return fmt.Errorf("network instance %s without UUID", ni.DisplayName)
+		}
+		switch ni.Type {
+		case types.NetworkInstanceTypeLocal, types.NetworkInstanceTypeSwitch:
+		default:
+			return fmt.Errorf("network instance %s with unsupported type %d",
+				ni.DisplayName, ni.Type)
+		}
+	}
+	return nil
+}
+
+func computeSignature(payload []byte, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// collectCipherContextRefs returns IDs of all cipher contexts referenced
+// by the encrypted credentials inside the port configuration.
+func collectCipherContextRefs(dpc types.DevicePortConfig) (refs []string) {
+	addRef := func(cipherBlock types.CipherBlockStatus) {
+		if cipherBlock.CipherContextID == "" {
+			return
+		}
+		for _, ref := range refs {
+			if ref == cipherBlock.CipherContextID {
+				return
+			}
+		}
+		refs = append(refs, cipherBlock.CipherContextID)
+	}
+	for _, port := range dpc.Ports {
+		for _, wifi := range port.WirelessCfg.Wifi {
+			addRef(wifi.CipherBlockStatus)
+		}
+		for _, accessPoint := range port.WirelessCfg.CellularV2.AccessPoints {
+			addRef(accessPoint.EncryptedCredentials)
+		}
+	}
+	return refs
+}
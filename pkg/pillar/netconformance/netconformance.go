@@ -0,0 +1,194 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package netconformance verifies that the device provides everything
+// the network subsystems depend on: kernel modules, iptables/ipset
+// availability, sysctl prerequisites and required binaries.
+// The checker is run at boot, before agents start reconciling network
+// configuration, and produces a structured capability report
+// (types.NetworkCapabilities) which reconcilers use to pick compatible
+// item implementations (e.g. iptables-legacy vs. nft) and which is
+// published for troubleshooting purposes.
+package netconformance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"golang.org/x/sys/unix"
+)
+
+// Kernel modules required by the network subsystems.
+// A module is considered available if it is either loaded or built
+// into the kernel.
+var requiredKernelModules = []string{
+	"bridge",
+	"8021q",
+	"nf_conntrack",
+	"nf_nat",
+}
+
+// Sysctl nodes which NIM and zedrouter configure and therefore must exist.
+// Paths are relative to /proc/sys.
+var requiredSysctls = []string{
+	"net/ipv4/ip_forward",
+	"net/ipv6/conf/all/forwarding",
+	"net/bridge/bridge-nf-call-iptables",
+	"net/netfilter/nf_conntrack_max",
+}
+
+// Binaries required by the network subsystems, probed with the given
+// version-printing argument.
+var requiredBinaries = map[string]string{
+	"iptables":  "--version",
+	"ip6tables": "--version",
+	"ipset":     "version",
+	"dnsmasq":   "--version",
+	"radvd":     "--version",
+}
+
+// Checker runs the boot-time conformance checks for the Linux network stack.
+type Checker struct {
+	Log *base.LogObject
+}
+
+// Check runs all conformance checks and returns the capability report.
+// The checks are read-only and tolerate individual failures - a missing
+// prerequisite is recorded in the report, not returned as an error.
+func (c *Checker) Check() types.NetworkCapabilities {
+	caps := types.NetworkCapabilities{
+		BinaryVersions: make(map[string]string),
+	}
+	c.checkKernelModules(&caps)
+	c.checkSysctls(&caps)
+	c.checkBinaries(&caps)
+	c.checkIptablesBackend(&caps)
+	c.checkIpset(&caps)
+	return caps
+}
+
+func (c *Checker) checkKernelModules(caps *types.NetworkCapabilities) {
+	loaded, err := loadedKernelModules()
+	if err != nil {
+		caps.Errors = append(caps.Errors,
+			fmt.Sprintf("failed to list loaded kernel modules: %v", err))
+	}
+	builtin, err := builtinKernelModules()
+	if err != nil {
+		caps.Errors = append(caps.Errors,
+			fmt.Sprintf("failed to list built-in kernel modules: %v", err))
+	}
+	for _, module := range requiredKernelModules {
+		if _, isLoaded := loaded[module]; isLoaded {
+			continue
+		}
+		if _, isBuiltin := builtin[module]; isBuiltin {
+			continue
+		}
+		caps.MissingKernelModules = append(caps.MissingKernelModules, module)
+	}
+}
+
+// loadedKernelModules returns the set of currently loaded kernel modules.
+func loadedKernelModules() (map[string]struct{}, error) {
+	content, err := os.ReadFile("/proc/modules")
+	if err != nil {
+		return nil, err
+	}
+	modules := make(map[string]struct{})
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			modules[fields[0]] = struct{}{}
+		}
+	}
+	return modules, nil
+}
+
+// builtinKernelModules returns the set of modules built into the running
+// kernel, as listed in /lib/modules/<release>/modules.builtin.
+func builtinKernelModules() (map[string]struct{}, error) {
+	var uname unix.Utsname
+	if err := unix.Uname(&uname); err != nil {
+		return nil, err
+	}
+	release := string(uname.Release[:])
+	if idx := strings.IndexByte(release, 0); idx != -1 {
+		release = release[:idx]
+	}
+	content, err := os.ReadFile(
+		filepath.Join("/lib/modules", release, "modules.builtin"))
+	if err != nil {
+		return nil, err
+	}
+	modules := make(map[string]struct{})
+	for _, line := range strings.Split(string(content), "\n") {
+		module := strings.TrimSuffix(filepath.Base(line), ".ko")
+		if module != "" && module != "." {
+			modules[module] = struct{}{}
+		}
+	}
+	return modules, nil
+}
+
+func (c *Checker) checkSysctls(caps *types.NetworkCapabilities) {
+	for _, sysctl := range requiredSysctls {
+		path := filepath.Join("/proc/sys", sysctl)
+		if _, err := os.Stat(path); err != nil {
+			caps.MissingSysctls = append(caps.MissingSysctls, sysctl)
+		}
+	}
+}
+
+func (c *Checker) checkBinaries(caps *types.NetworkCapabilities) {
+	for binary, versionArg := range requiredBinaries {
+		caps.BinaryVersions[binary] = c.binaryVersion(binary, versionArg)
+	}
+	// The legacy backend binary is optional - record it only when present
+	// so that it can be used as a fallback if the default iptables backend
+	// is broken.
+	if version := c.binaryVersion("iptables-legacy", "--version"); version != "" {
+		caps.BinaryVersions["iptables-legacy"] = version
+	}
+}
+
+// binaryVersion returns the first line printed by the binary when asked
+// for its version, or an empty string if the binary is missing or fails.
+func (c *Checker) binaryVersion(binary, versionArg string) string {
+	output, err := base.Exec(c.Log, binary, versionArg).CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	version := string(output)
+	if idx := strings.IndexByte(version, '\n'); idx != -1 {
+		version = version[:idx]
+	}
+	return strings.TrimSpace(version)
+}
+
+func (c *Checker) checkIptablesBackend(caps *types.NetworkCapabilities) {
+	version := caps.BinaryVersions["iptables"]
+	switch {
+	case version == "":
+		caps.Errors = append(caps.Errors,
+			"iptables binary is missing or broken")
+	case strings.Contains(version, "nf_tables"):
+		caps.IptablesBackend = types.IptablesBackendNft
+	default:
+		// Older iptables releases do not print the backend name,
+		// but those only supported the legacy backend.
+		caps.IptablesBackend = types.IptablesBackendLegacy
+	}
+}
+
+func (c *Checker) checkIpset(caps *types.NetworkCapabilities) {
+	caps.HasIpset = caps.BinaryVersions["ipset"] != ""
+	if !caps.HasIpset {
+		caps.Errors = append(caps.Errors,
+			"ipset binary is missing or broken")
+	}
+}
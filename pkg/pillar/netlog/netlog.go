@@ -0,0 +1,155 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package netlog provides a rate-limited logging facility for the network
+// agents (zedrouter, NIM). Errors triggered by an invalid configuration
+// (e.g. an unparsable ACL rule) tend to repeat with every reconciliation
+// and can flood the persisted logs. The facility aggregates such repeats
+// per category, counts the suppressed entries and tags every logged entry
+// with the UUIDs of the affected network instance and application instance
+// for easy filtering.
+package netlog
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// DefaultBurst : default number of entries allowed per category within
+	// every DefaultWindow.
+	DefaultBurst = 3
+	// DefaultWindow : default length of the rate-limiting time window.
+	DefaultWindow = 10 * time.Minute
+)
+
+// RateLimitedLogger limits the number of log entries emitted per category
+// within a time window. Entries above the limit are suppressed and only
+// counted; when the window rolls over, a single summary entry is logged
+// for every category with suppressed repeats.
+type RateLimitedLogger struct {
+	sync.Mutex
+	log    *base.LogObject
+	burst  int
+	window time.Duration
+
+	categories map[string]*categoryState
+}
+
+// categoryState : rate-limiting state of a single log category.
+type categoryState struct {
+	windowStart time.Time
+	logged      int
+	// Number of entries suppressed within the current window.
+	suppressed uint64
+	// Total number of entries suppressed since the logger was created.
+	totalSuppressed uint64
+}
+
+// Tags attached to a rate-limited log entry.
+type Tags struct {
+	// Category under which repeated entries are aggregated and counted
+	// (e.g. "acl-parse"). Mandatory.
+	Category string
+	// NI : UUID of the affected network instance. Optional.
+	NI uuid.UUID
+	// App : UUID of the affected application instance. Optional.
+	App uuid.UUID
+}
+
+// NewRateLimitedLogger returns a logger allowing at most burst entries
+// per category within every window.
+func NewRateLimitedLogger(log *base.LogObject, burst int,
+	window time.Duration) *RateLimitedLogger {
+	return &RateLimitedLogger{
+		log:        log,
+		burst:      burst,
+		window:     window,
+		categories: make(map[string]*categoryState),
+	}
+}
+
+// Errorf logs an error entry, unless the per-category limit was already
+// reached within the current time window.
+func (l *RateLimitedLogger) Errorf(tags Tags, format string, args ...interface{}) {
+	l.logf(tags, false, format, args...)
+}
+
+// Warnf logs a warning entry, unless the per-category limit was already
+// reached within the current time window.
+func (l *RateLimitedLogger) Warnf(tags Tags, format string, args ...interface{}) {
+	l.logf(tags, true, format, args...)
+}
+
+func (l *RateLimitedLogger) logf(tags Tags, warning bool,
+	format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	state, haveState := l.categories[tags.Category]
+	if !haveState {
+		state = &categoryState{windowStart: now}
+		l.categories[tags.Category] = state
+	}
+	if now.Sub(state.windowStart) >= l.window {
+		if state.suppressed > 0 {
+			l.logObject(tags).Warnf(
+				"netlog: suppressed %d repeats of the %s category "+
+					"in the last %v (%d in total)", state.suppressed,
+				tags.Category, l.window, state.totalSuppressed)
+		}
+		state.windowStart = now
+		state.logged = 0
+		state.suppressed = 0
+	}
+	if state.logged >= l.burst {
+		state.suppressed++
+		state.totalSuppressed++
+		return
+	}
+	state.logged++
+	if warning {
+		l.logObject(tags).Warnf(format, args...)
+	} else {
+		l.logObject(tags).Errorf(format, args...)
+	}
+}
+
+// logObject returns the underlying log object with the entry tags attached
+// as structured fields.
+func (l *RateLimitedLogger) logObject(tags Tags) *base.LogObject {
+	fields := map[string]interface{}{
+		"netlog-category": tags.Category,
+	}
+	if tags.NI != uuid.Nil {
+		fields["ni-uuid"] = tags.NI.String()
+	}
+	if tags.App != uuid.Nil {
+		fields["app-uuid"] = tags.App.String()
+	}
+	return l.log.CloneAndAddFields(fields)
+}
+
+// SuppressionCounters returns the total number of suppressed log entries
+// per category, accumulated since the logger was created.
+func (l *RateLimitedLogger) SuppressionCounters() map[string]uint64 {
+	l.Lock()
+	defer l.Unlock()
+	counters := make(map[string]uint64, len(l.categories))
+	for category, state := range l.categories {
+		if state.totalSuppressed > 0 {
+			counters[category] = state.totalSuppressed
+		}
+	}
+	return counters
+}
+
+// String is only used for debugging purposes.
+func (l *RateLimitedLogger) String() string {
+	return fmt.Sprintf("RateLimitedLogger: {burst: %d, window: %v}",
+		l.burst, l.window)
+}
@@ -31,6 +31,11 @@ const (
 	// is buffered, in order to not lose any notification during bursts
 	// of events (e.g. after node reboot).
 	publishTimeout = 3 * time.Second
+
+	// The smallest supported sampling interval for interface counters.
+	// Intervals requested by WatchInterfaceCounters subscribers are raised
+	// to this minimum.
+	minCountersInterval = time.Second
 )
 
 // LinuxNetworkMonitor implements NetworkMonitor for the Linux network stack.
@@ -44,6 +49,13 @@ type LinuxNetworkMonitor struct {
 	// cached data during event processing.
 	subsLock sync.Mutex
 
+	// Subscribers receiving periodic interface counter samples.
+	// The sampler goroutine is started lazily with the first subscriber
+	// and exits when the last one unsubscribes.
+	countersLock   sync.Mutex
+	countersSubs   []*countersSubscriber
+	samplerRunning bool
+
 	// Cache
 	cacheLock      sync.Mutex
 	initialized    bool
@@ -66,6 +78,18 @@ type subscriber struct {
 	done   <-chan struct{}
 }
 
+type countersSubscriber struct {
+	name     string
+	interval time.Duration
+	events   chan IfCountersUpdate
+	done     <-chan struct{}
+	// lastSample : counters included in the last update delivered
+	// to this subscriber, used to compute deltas. Key = ifIndex.
+	lastSample map[int]IfCounters
+	// lastDelivery : when the last update was delivered to this subscriber.
+	lastDelivery time.Time
+}
+
 // Init should be called first to prepare the monitor.
 func (m *LinuxNetworkMonitor) init() {
 	if m.initialized {
@@ -415,6 +439,171 @@ func (m *LinuxNetworkMonitor) WatchEvents(ctx context.Context, subName string) <
 	return sub.events
 }
 
+// WatchInterfaceCounters allows to subscribe to receive periodic samples
+// of interface traffic counters. One central sampler goroutine serves all
+// the subscribers, reading the counters from netlink at the smallest
+// interval requested across the subscribers.
+func (m *LinuxNetworkMonitor) WatchInterfaceCounters(ctx context.Context,
+	subName string, interval time.Duration) <-chan IfCountersUpdate {
+	if interval < minCountersInterval {
+		interval = minCountersInterval
+	}
+	sub := &countersSubscriber{
+		name:       subName,
+		interval:   interval,
+		events:     make(chan IfCountersUpdate, eventChanBufSize),
+		done:       ctx.Done(),
+		lastSample: make(map[int]IfCounters),
+	}
+	// Deliver the first sample right away so that the subscriber does not
+	// have to wait the full interval for the initial data.
+	counters, err := m.readIfCounters()
+	if err != nil {
+		m.Log.Errorf("WatchInterfaceCounters: failed to read interface "+
+			"counters for subscriber %s: %v", subName, err)
+	} else {
+		sampledAt := time.Now()
+		update := IfCountersUpdate{SampledAt: sampledAt}
+		for _, total := range counters {
+			sub.lastSample[total.IfIndex] = total
+			update.Counters = append(update.Counters, IfCountersDelta{Total: total})
+		}
+		sub.events <- update // cannot block, the channel is new and buffered
+		sub.lastDelivery = sampledAt
+	}
+	m.countersLock.Lock()
+	defer m.countersLock.Unlock()
+	m.countersSubs = append(m.countersSubs, sub)
+	if !m.samplerRunning {
+		m.samplerRunning = true
+		go m.countersSampler()
+	}
+	return sub.events
+}
+
+// countersSampler periodically reads traffic counters of all interfaces
+// (one reader for all subscribers) and delivers updates to subscribers
+// whose sampling interval has elapsed.
+func (m *LinuxNetworkMonitor) countersSampler() {
+	for {
+		m.countersLock.Lock()
+		// Remove canceled subscriptions and determine the sampling interval.
+		var interval time.Duration
+		var activeSubs []*countersSubscriber
+		for _, sub := range m.countersSubs {
+			select {
+			case <-sub.done:
+				// unsubscribe
+				continue
+			default:
+				// continue subscription
+			}
+			if interval == 0 || sub.interval < interval {
+				interval = sub.interval
+			}
+			activeSubs = append(activeSubs, sub)
+		}
+		m.countersSubs = activeSubs
+		if len(activeSubs) == 0 {
+			// The last subscriber is gone, stop sampling.
+			m.samplerRunning = false
+			m.countersLock.Unlock()
+			return
+		}
+		m.countersLock.Unlock()
+		time.Sleep(interval)
+
+		counters, err := m.readIfCounters()
+		if err != nil {
+			m.Log.Errorf("countersSampler: failed to read interface counters: %v",
+				err)
+			continue
+		}
+		sampledAt := time.Now()
+		m.countersLock.Lock()
+		for _, sub := range m.countersSubs {
+			if !sub.lastDelivery.IsZero() &&
+				sampledAt.Sub(sub.lastDelivery) < sub.interval {
+				// Not yet due for an update.
+				continue
+			}
+			update := IfCountersUpdate{SampledAt: sampledAt}
+			if !sub.lastDelivery.IsZero() {
+				update.SinceLast = sampledAt.Sub(sub.lastDelivery)
+			}
+			newSample := make(map[int]IfCounters, len(counters))
+			for _, total := range counters {
+				newSample[total.IfIndex] = total
+				entry := IfCountersDelta{Total: total}
+				if prev, seen := sub.lastSample[total.IfIndex]; seen {
+					entry.Delta = subtractIfCounters(total, prev)
+				}
+				update.Counters = append(update.Counters, entry)
+			}
+			select {
+			case sub.events <- update:
+				sub.lastSample = newSample
+				sub.lastDelivery = sampledAt
+			default:
+				m.Log.Warnf("countersSampler: failed to deliver counters "+
+					"to subscriber %s: channel is full", sub.name)
+			}
+		}
+		m.countersLock.Unlock()
+	}
+}
+
+// readIfCounters reads traffic counters of all interfaces from netlink.
+func (m *LinuxNetworkMonitor) readIfCounters() (counters []IfCounters, err error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		stats := link.Attrs().Statistics
+		if stats == nil {
+			continue
+		}
+		counters = append(counters, IfCounters{
+			IfIndex:   link.Attrs().Index,
+			IfName:    link.Attrs().Name,
+			RxBytes:   stats.RxBytes,
+			RxPackets: stats.RxPackets,
+			RxErrors:  stats.RxErrors,
+			RxDrops:   stats.RxDropped,
+			TxBytes:   stats.TxBytes,
+			TxPackets: stats.TxPackets,
+			TxErrors:  stats.TxErrors,
+			TxDrops:   stats.TxDropped,
+		})
+	}
+	return counters, nil
+}
+
+// subtractIfCounters computes the difference between two samples of interface
+// counters. Counters going backwards (e.g. after being reset) yield zero
+// instead of wrapping around.
+func subtractIfCounters(current, previous IfCounters) IfCounters {
+	sub := func(a, b uint64) uint64 {
+		if a < b {
+			return 0
+		}
+		return a - b
+	}
+	return IfCounters{
+		IfIndex:   current.IfIndex,
+		IfName:    current.IfName,
+		RxBytes:   sub(current.RxBytes, previous.RxBytes),
+		RxPackets: sub(current.RxPackets, previous.RxPackets),
+		RxErrors:  sub(current.RxErrors, previous.RxErrors),
+		RxDrops:   sub(current.RxDrops, previous.RxDrops),
+		TxBytes:   sub(current.TxBytes, previous.TxBytes),
+		TxPackets: sub(current.TxPackets, previous.TxPackets),
+		TxErrors:  sub(current.TxErrors, previous.TxErrors),
+		TxDrops:   sub(current.TxDrops, previous.TxDrops),
+	}
+}
+
 // ClearCache clears cached state data.
 func (m *LinuxNetworkMonitor) ClearCache() {
 	m.cacheLock.Lock()
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package netmonitor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+func TestWatchInterfaceCounters(test *testing.T) {
+	t := NewGomegaWithT(test)
+	logger := logrus.StandardLogger()
+	logObj := base.NewSourceLogObject(logger, "test", 1234)
+	monitor := &LinuxNetworkMonitor{Log: logObj}
+	samplerRunning := func() bool {
+		monitor.countersLock.Lock()
+		defer monitor.countersLock.Unlock()
+		return monitor.samplerRunning
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ch1 := monitor.WatchInterfaceCounters(ctx1, "sub1", time.Second)
+	t.Expect(samplerRunning()).To(BeTrue())
+
+	// The first sample is delivered immediately, with zero deltas.
+	var update IfCountersUpdate
+	t.Expect(ch1).To(Receive(&update))
+	t.Expect(update.SampledAt.IsZero()).To(BeFalse())
+	t.Expect(update.SinceLast).To(BeZero())
+	t.Expect(update.Counters).ToNot(BeEmpty())
+	for _, entry := range update.Counters {
+		t.Expect(entry.Total.IfName).ToNot(BeEmpty())
+		t.Expect(entry.Delta).To(BeZero())
+	}
+
+	// Further samples arrive periodically, with deltas computed against
+	// the previous sample.
+	prevSampledAt := update.SampledAt
+	t.Eventually(ch1, 5*time.Second).Should(Receive(&update))
+	t.Expect(update.SampledAt.After(prevSampledAt)).To(BeTrue())
+	t.Expect(update.SinceLast).ToNot(BeZero())
+	t.Expect(update.Counters).ToNot(BeEmpty())
+
+	// A second subscriber is served by the same sampler goroutine.
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	ch2 := monitor.WatchInterfaceCounters(ctx2, "sub2", time.Second)
+	t.Expect(ch2).To(Receive(&update))
+	t.Expect(update.SinceLast).To(BeZero())
+
+	// Canceled subscriber is removed by the sampler while the other one
+	// continues receiving updates.
+	subsCount := func() int {
+		monitor.countersLock.Lock()
+		defer monitor.countersLock.Unlock()
+		return len(monitor.countersSubs)
+	}
+	cancel1()
+	t.Eventually(subsCount, 5*time.Second).Should(Equal(1))
+	// Drain updates which could have been delivered to the first subscriber
+	// before the cancellation was detected by the sampler.
+	for len(ch1) > 0 {
+		<-ch1
+	}
+	t.Consistently(ch1, 3*time.Second).ShouldNot(Receive())
+	t.Eventually(ch2, 5*time.Second).Should(Receive(&update))
+	t.Expect(update.SinceLast).ToNot(BeZero())
+
+	// The sampler goroutine exits when the last subscriber is gone.
+	cancel2()
+	t.Eventually(samplerRunning, 5*time.Second).Should(BeFalse())
+}
@@ -10,6 +10,7 @@ import (
 	"net"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/lf-edge/eve/pkg/pillar/base"
 )
@@ -27,11 +28,12 @@ type MockNetworkMonitor struct {
 
 // MockInterface : a simulated network interface and its state.
 type MockInterface struct {
-	Attrs   IfAttrs
-	IPAddrs []*net.IPNet
-	HwAddr  net.HardwareAddr
-	DNS     DNSInfo
-	DHCP    DHCPInfo
+	Attrs    IfAttrs
+	IPAddrs  []*net.IPNet
+	HwAddr   net.HardwareAddr
+	DNS      DNSInfo
+	DHCP     DHCPInfo
+	Counters IfCounters
 }
 
 // AddOrUpdateInterface : allows to simulate an event of interface being added
@@ -301,6 +303,63 @@ func (m *MockNetworkMonitor) WatchEvents(ctx context.Context, subName string) <-
 	return sub.events
 }
 
+// WatchInterfaceCounters returns a channel with periodic samples of traffic
+// counters of the mock interfaces (see MockInterface.Counters).
+func (m *MockNetworkMonitor) WatchInterfaceCounters(ctx context.Context,
+	subName string, interval time.Duration) <-chan IfCountersUpdate {
+	if interval < minCountersInterval {
+		interval = minCountersInterval
+	}
+	events := make(chan IfCountersUpdate, eventChanBufSize)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		lastSample := make(map[int]IfCounters)
+		var lastDelivery time.Time
+		// The first sample is delivered right away, just like with
+		// LinuxNetworkMonitor.
+		firstSample := true
+		for {
+			if !firstSample {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+			firstSample = false
+			sampledAt := time.Now()
+			update := IfCountersUpdate{SampledAt: sampledAt}
+			if !lastDelivery.IsZero() {
+				update.SinceLast = sampledAt.Sub(lastDelivery)
+			}
+			m.Lock()
+			for ifIndex, mockIf := range m.interfaces {
+				total := mockIf.Counters
+				total.IfIndex = ifIndex
+				total.IfName = mockIf.Attrs.IfName
+				entry := IfCountersDelta{Total: total}
+				if prev, seen := lastSample[ifIndex]; seen {
+					entry.Delta = subtractIfCounters(total, prev)
+				}
+				lastSample[ifIndex] = total
+				update.Counters = append(update.Counters, entry)
+			}
+			m.Unlock()
+			lastDelivery = sampledAt
+			select {
+			case events <- update:
+			default:
+				if m.Log != nil {
+					m.Log.Warnf("failed to deliver counters to subscriber %s",
+						subName)
+				}
+			}
+		}
+	}()
+	return events
+}
+
 // This method is run with the monitor in the locked state.
 func (m *MockNetworkMonitor) publishEvent(ev Event) {
 	var activeSubs []subscriber
@@ -6,6 +6,7 @@ package netmonitor
 import (
 	"context"
 	"net"
+	"time"
 )
 
 // NetworkMonitor should allow to:
@@ -53,6 +54,17 @@ type NetworkMonitor interface {
 	// related to interfaces, routes, DNS, etc.
 	// The returned channel should be reasonably buffered.
 	WatchEvents(ctx context.Context, subName string) <-chan Event
+	// WatchInterfaceCounters : subscribe to receive periodic samples of traffic
+	// counters of all network interfaces, including deltas computed against
+	// the previous sample delivered to the same subscriber.
+	// All subscriptions are driven by one central reader of the interface
+	// statistics, sampling at the smallest interval requested across
+	// the subscribers. This avoids duplicated polling by the agents and allows
+	// to increase the metric resolution where needed.
+	// The requested interval may get lowered to an implementation-defined
+	// minimum. The returned channel should be reasonably buffered.
+	WatchInterfaceCounters(ctx context.Context, subName string,
+		interval time.Duration) <-chan IfCountersUpdate
 }
 
 // Event received from the network stack.
@@ -185,3 +197,40 @@ type DHCPInfo struct {
 	Subnet     *net.IPNet
 	NtpServers []net.IP
 }
+
+// IfCounters : cumulative traffic counters of one network interface.
+type IfCounters struct {
+	IfIndex   int
+	IfName    string
+	RxBytes   uint64
+	RxPackets uint64
+	RxErrors  uint64
+	RxDrops   uint64
+	TxBytes   uint64
+	TxPackets uint64
+	TxErrors  uint64
+	TxDrops   uint64
+}
+
+// IfCountersDelta : traffic counters of one network interface from a single
+// sample.
+type IfCountersDelta struct {
+	// Total : cumulative counters as reported by the network stack.
+	Total IfCounters
+	// Delta : difference against the previous sample delivered to the same
+	// subscriber. All-zero for an interface appearing in the sample
+	// for the first time.
+	Delta IfCounters
+}
+
+// IfCountersUpdate : one periodic sample of traffic counters of all network
+// interfaces, delivered to subscribers of WatchInterfaceCounters.
+type IfCountersUpdate struct {
+	// SampledAt : time when the counters were read from the network stack.
+	SampledAt time.Time
+	// SinceLast : time elapsed since the previous update delivered
+	// to the same subscriber. Zero for the first update.
+	SinceLast time.Duration
+	// Counters : one entry per network interface.
+	Counters []IfCountersDelta
+}
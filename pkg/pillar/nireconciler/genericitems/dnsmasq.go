@@ -11,6 +11,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/lf-edge/eve-libs/reconciler"
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/devicenetwork"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
 	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
@@ -36,6 +38,13 @@ const (
 	// Tag prefix used for applications that, within a given NI, serve as IP routing
 	// gateways (i.e. routing some traffic between apps and external endpoints).
 	gatewayTagPrefix = "gateway-"
+	// Tag prefix used for applications that should receive additional custom
+	// static routes on top of those propagated to every endpoint
+	// (see MACToIP.PropagateRoutes).
+	hostTagPrefix = "host-"
+	// Tag prefix used for DHCP client classes matched by the vendor-class
+	// or user-class content (see NetworkInstanceConfig.DHCPClientClasses).
+	classTagPrefix = "class-"
 )
 
 // Dnsmasq : DNS and DHCP server (https://thekelleys.org.uk/dnsmasq/doc.html).
@@ -51,6 +60,12 @@ type Dnsmasq struct {
 	DHCPServer DHCPServer
 	// DNSServer : part of the dnsmasq config specific to DNS server.
 	DNSServer DNSServer
+	// NIConnGate : if enabled, dnsmasq is run only while the network instance
+	// has working external connectivity, i.e. it depends on the presence
+	// of the NIConn external item. With the item absent, the reconciler keeps
+	// dnsmasq down and no DHCP leases are handed out to applications
+	// (see NetworkInstanceConfig.GateDHCPOnConnectivity).
+	NIConnGate bool
 }
 
 // DHCPServer : part of the dnsmasq config specific to DHCP server.
@@ -89,16 +104,43 @@ type DHCPServer struct {
 	// MTU : Maximum transmission unit size to propagate to applications using the DHCP
 	// option 26.
 	MTU uint16
+	// ClientClasses : classification of DHCP clients based on the content
+	// of the vendor-class/user-class options they send. Classified clients
+	// receive the class-specific DHCP options and, if the class defines
+	// a dedicated address pool, an address allocated from that pool.
+	ClientClasses []types.DHCPClientClass
+	// SecondaryPools : dedicated address pools for the secondary IPv4
+	// subnets of the NI (see types.NISecondarySubnet). Clients allocated
+	// from a secondary pool receive the netmask and the default gateway
+	// of their subnet. Note that the AllOnesNetmask trick is not applied
+	// to secondary pools - their intra-subnet traffic is switched directly
+	// by the bridge.
+	SecondaryPools []DHCPPool
+	// PACFileURL : URL of the Proxy Auto-Configuration file to advertise
+	// to clients using the DHCP option 252. Empty to not advertise.
+	PACFileURL string
+}
+
+// DHCPPool : an address pool of a (secondary) IP subnet served by dnsmasq.
+type DHCPPool struct {
+	// Subnet : network address + netmask.
+	Subnet *net.IPNet
+	// IPRange : a range of IP addresses to allocate from.
+	IPRange IPRange
+	// GatewayIP : address of the default gateway to advertise to clients
+	// allocated from this pool (replacing DHCPServer.GatewayIP).
+	GatewayIP net.IP
 }
 
 // String describes DHCPServer config.
 func (d DHCPServer) String() string {
 	return fmt.Sprintf("DHCPServer: {subnet: %s, allOnesNetmask: %t, ipRange: <%s-%s>, "+
 		"gatewayIP: %s, withDefaultRoute: %t, domainName: %s, dnsServers: %v, ntpServers: %v, "+
-		"staticEntries: %v, propagateRoutes: %v, MTU: %d}",
+		"staticEntries: %v, propagateRoutes: %v, MTU: %d, clientClasses: %v, "+
+		"secondaryPools: %v, pacFileURL: %s}",
 		d.Subnet, d.AllOnesNetmask, d.IPRange.FromIP, d.IPRange.ToIP, d.GatewayIP,
 		d.WithDefaultRoute, d.DomainName, d.DNSServers, d.NTPServers, d.StaticEntries,
-		d.PropagateRoutes, d.MTU)
+		d.PropagateRoutes, d.MTU, d.ClientClasses, d.SecondaryPools, d.PACFileURL)
 }
 
 // Equal compares two DHCPServer instances
@@ -115,7 +157,10 @@ func (d DHCPServer) Equal(d2 DHCPServer, withStaticEntries bool) bool {
 		(!withStaticEntries ||
 			generics.EqualSetsFn(d.StaticEntries, d2.StaticEntries, equalMACToIP)) &&
 		generics.EqualSetsFn(d.PropagateRoutes, d2.PropagateRoutes, equalIPRoutes) &&
-		d.MTU == d2.MTU
+		d.MTU == d2.MTU &&
+		generics.EqualSetsFn(d.ClientClasses, d2.ClientClasses, equalDHCPClientClass) &&
+		generics.EqualSetsFn(d.SecondaryPools, d2.SecondaryPools, equalDHCPPool) &&
+		d.PACFileURL == d2.PACFileURL
 }
 
 // DNSServer : part of the dnsmasq config specific to DNS server.
@@ -129,6 +174,9 @@ type DNSServer struct {
 	// UpstreamServers : list of IP addresses of public DNS servers to forward
 	// requests to (unless there is a static entry).
 	UpstreamServers []net.IP
+	// SplitDNS : rules forwarding queries for specific domains (and their
+	// subdomains) to dedicated upstream servers instead of UpstreamServers.
+	SplitDNS []types.SplitDNSRule
 	// StaticEntries : list of hostname->IPs entries statically configured
 	// for the DNS server.
 	StaticEntries []HostnameToIPs
@@ -138,13 +186,18 @@ type DNSServer struct {
 	// For different network stacks we are likely going to need to come up with a different
 	// way of implementing hostname-referencing ACLs.
 	LinuxIPSets []LinuxIPSet
+	// DNS64Prefix : if defined, the DNS server will synthesize AAAA records
+	// from A records for IPv4-only destinations by embedding the IPv4 address
+	// into this prefix (RFC 6147). Used together with a NAT64 translator.
+	DNS64Prefix *net.IPNet
 }
 
 // String describes DNSServer config.
 func (d DNSServer) String() string {
 	return fmt.Sprintf("DNSServer: {listenIP: %s, uplinkIf: %s, upstreamServers: %v, "+
-		"staticEntries: %v, linuxIPSets: %v}",
-		d.ListenIP, d.UplinkIf.IfName, d.UpstreamServers, d.StaticEntries, d.LinuxIPSets)
+		"splitDNS: %v, staticEntries: %v, linuxIPSets: %v, dns64Prefix: %v}",
+		d.ListenIP, d.UplinkIf.IfName, d.UpstreamServers, d.SplitDNS, d.StaticEntries,
+		d.LinuxIPSets, d.DNS64Prefix)
 }
 
 // Equal compares two DNSServer instances
@@ -152,7 +205,9 @@ func (d DNSServer) Equal(d2 DNSServer, withStaticEntries bool) bool {
 	return netutils.EqualIPs(d.ListenIP, d2.ListenIP) &&
 		d.UplinkIf == d2.UplinkIf &&
 		generics.EqualSetsFn(d.UpstreamServers, d2.UpstreamServers, netutils.EqualIPs) &&
+		generics.EqualSetsFn(d.SplitDNS, d2.SplitDNS, equalSplitDNSRule) &&
 		generics.EqualSetsFn(d.LinuxIPSets, d2.LinuxIPSets, equalLinuxIPSet) &&
+		netutils.EqualIPNets(d.DNS64Prefix, d2.DNS64Prefix) &&
 		(!withStaticEntries ||
 			generics.EqualSetsFn(d.StaticEntries, d2.StaticEntries, equalHostnameToIP))
 }
@@ -170,12 +225,48 @@ type MACToIP struct {
 	MAC      net.HardwareAddr
 	IP       net.IP
 	Hostname string
+	// PropagateRoutes : additional IP routes to propagate only to this host
+	// using the DHCP option 121 (classless route option), on top of
+	// DHCPServer.PropagateRoutes shared by all hosts.
+	PropagateRoutes []types.IPRoute
+	// DhcpOverrides : optional overrides of selected DHCP parameters for this
+	// host, replacing the corresponding values shared by all hosts.
+	DhcpOverrides types.DhcpOverrides
 }
 
 func equalMACToIP(a, b MACToIP) bool {
 	return bytes.Equal(a.MAC, b.MAC) &&
 		netutils.EqualIPs(a.IP, b.IP) &&
-		a.Hostname == b.Hostname
+		a.Hostname == b.Hostname &&
+		generics.EqualSetsFn(a.PropagateRoutes, b.PropagateRoutes, equalIPRoutes) &&
+		a.DhcpOverrides.LeaseTime == b.DhcpOverrides.LeaseTime &&
+		equalDhcpOptionOverrides(a.DhcpOverrides, b.DhcpOverrides)
+}
+
+// equalDhcpOptionOverrides compares the DHCP option overrides of two hosts,
+// intentionally excluding the lease time (which is delivered through the host
+// file, not the main dnsmasq config).
+func equalDhcpOptionOverrides(a, b types.DhcpOverrides) bool {
+	return a.MTU == b.MTU &&
+		generics.EqualSetsFn(a.NTPServers, b.NTPServers, netutils.EqualIPs) &&
+		a.DomainName == b.DomainName
+}
+
+func equalDHCPPool(a, b DHCPPool) bool {
+	return netutils.EqualIPNets(a.Subnet, b.Subnet) &&
+		netutils.EqualIPs(a.IPRange.FromIP, b.IPRange.FromIP) &&
+		netutils.EqualIPs(a.IPRange.ToIP, b.IPRange.ToIP) &&
+		netutils.EqualIPs(a.GatewayIP, b.GatewayIP)
+}
+
+func equalDHCPClientClass(a, b types.DHCPClientClass) bool {
+	return a.Name == b.Name &&
+		a.VendorClass == b.VendorClass &&
+		a.UserClass == b.UserClass &&
+		netutils.EqualIPs(a.IPRange.Start, b.IPRange.Start) &&
+		netutils.EqualIPs(a.IPRange.End, b.IPRange.End) &&
+		a.DhcpOptions.LeaseTime == b.DhcpOptions.LeaseTime &&
+		equalDhcpOptionOverrides(a.DhcpOptions, b.DhcpOptions)
 }
 
 func equalIPRoutes(a, b types.IPRoute) bool {
@@ -183,6 +274,11 @@ func equalIPRoutes(a, b types.IPRoute) bool {
 		netutils.EqualIPs(a.Gateway, b.Gateway)
 }
 
+func equalSplitDNSRule(a, b types.SplitDNSRule) bool {
+	return a.Domain == b.Domain &&
+		generics.EqualSetsFn(a.Servers, b.Servers, netutils.EqualIPs)
+}
+
 // HostnameToIPs maps hostname to one or more IP addresses.
 type HostnameToIPs struct {
 	Hostname string
@@ -246,7 +342,8 @@ func (d Dnsmasq) Equal(other dg.Item) bool {
 	return d.ForNI == d2.ForNI &&
 		d.ListenIf == d2.ListenIf &&
 		d.DNSServer.Equal(d2.DNSServer, true) &&
-		d.DHCPServer.Equal(d2.DHCPServer, true)
+		d.DHCPServer.Equal(d2.DHCPServer, true) &&
+		d.NIConnGate == d2.NIConnGate
 }
 
 // External returns false.
@@ -256,15 +353,26 @@ func (d Dnsmasq) External() bool {
 
 // String describes the dnsmasq instance.
 func (d Dnsmasq) String() string {
-	return fmt.Sprintf("Dnsmasq: {NI: %s, listenIf: %s, %s, %s}",
-		d.ForNI.String(), d.ListenIf.IfName, d.DHCPServer, d.DNSServer)
+	return fmt.Sprintf("Dnsmasq: {NI: %s, listenIf: %s, niConnGate: %t, %s, %s}",
+		d.ForNI.String(), d.ListenIf.IfName, d.NIConnGate, d.DHCPServer, d.DNSServer)
 }
 
 // Dependencies returns:
 //   - the (downlink) interface and the IP on which the dnsmasq listens
 //   - the (uplink) interface used by dnsmasq to contact upstream DNS servers (if any)
 //   - every referenced ipset
+//   - the NI connectivity status item if DHCP is gated on connectivity
 func (d Dnsmasq) Dependencies() (deps []dg.Dependency) {
+	if d.NIConnGate {
+		deps = append(deps, dg.Dependency{
+			RequiredItem: dg.ItemRef{
+				ItemType: NIConnTypename,
+				ItemName: d.ForNI.String(),
+			},
+			Description: "network instance must have working external " +
+				"connectivity before DHCP leases are handed out",
+		})
+	}
 	deps = append(deps, dg.Dependency{
 		RequiredItem: d.ListenIf.ItemRef,
 		Description: "interface on which dnsmasq listens must exist " +
@@ -330,6 +438,10 @@ dhcp-ttl=600
 type DnsmasqConfigurator struct {
 	Log    *base.LogObject
 	Logger *logrus.Logger
+	// ProcSupervisor : if defined, every started dnsmasq instance is
+	// registered with the supervisor, which will monitor its liveness
+	// and restart it should it exit unexpectedly.
+	ProcSupervisor *procsupervisor.ProcSupervisor
 }
 
 // Create starts dnsmasq.
@@ -364,6 +476,9 @@ func (c *DnsmasqConfigurator) Create(ctx context.Context, item dg.Item) error {
 	done := reconciler.ContinueInBackground(ctx)
 	go func() {
 		err := c.startDnsmasq(ctx, dnsmasq.Name())
+		if err == nil {
+			c.superviseDnsmasq(dnsmasq.Name())
+		}
 		done(err)
 	}()
 	return nil
@@ -416,6 +531,11 @@ func (c *DnsmasqConfigurator) Delete(ctx context.Context, item dg.Item) error {
 	if !isDnsmasq {
 		return fmt.Errorf("invalid item type %T, expected Dnsmasq", item)
 	}
+	// Stop supervising first so that the supervisor will not try to restart
+	// dnsmasq while we are stopping it.
+	if c.ProcSupervisor != nil {
+		c.ProcSupervisor.ForgetProcess(dnsmasqProcName(dnsmasq.Name()))
+	}
 	done := reconciler.ContinueInBackground(ctx)
 	go func() {
 		err := c.stopDnsmasq(ctx, dnsmasq.Name())
@@ -424,6 +544,7 @@ func (c *DnsmasqConfigurator) Delete(ctx context.Context, item dg.Item) error {
 			_ = c.removeDnsmasqConfigFile(dnsmasq.Name())
 			_ = c.removeDnsmasqLeaseFile(dnsmasq.ListenIf.IfName)
 			_ = c.removeDnsmasqPidFile(dnsmasq.Name())
+			_ = c.removeDnsmasqLogFiles(dnsmasq.Name())
 			_ = c.removeDnsmasqDHCPHostDir(dnsmasq.Name())
 			_ = c.removeDnsmasqDNSHostDir(dnsmasq.Name())
 		}
@@ -445,7 +566,26 @@ func (c *DnsmasqConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate
 	return oldDnsmasq.ForNI != newDnsmasq.ForNI ||
 		oldDnsmasq.ListenIf != newDnsmasq.ListenIf ||
 		!oldDnsmasq.DNSServer.Equal(newDnsmasq.DNSServer, false) ||
-		!oldDnsmasq.DHCPServer.Equal(newDnsmasq.DHCPServer, false)
+		!oldDnsmasq.DHCPServer.Equal(newDnsmasq.DHCPServer, false) ||
+		taggedHostConfigChanged(oldDnsmasq.DHCPServer.StaticEntries,
+			newDnsmasq.DHCPServer.StaticEntries)
+}
+
+// Per-host custom routes and DHCP option overrides are published through
+// dhcp-option entries of the main dnsmasq config file (unlike the host files,
+// which can be reloaded with just the SIGHUP signal), hence their change
+// requires restarting the process. The per-host lease time is intentionally
+// not considered here - it is part of the host file.
+func taggedHostConfigChanged(oldEntries, newEntries []MACToIP) bool {
+	equalEntryConfig := func(a, b MACToIP) bool {
+		return netutils.EqualIPs(a.IP, b.IP) &&
+			generics.EqualSetsFn(a.PropagateRoutes, b.PropagateRoutes, equalIPRoutes) &&
+			equalDhcpOptionOverrides(a.DhcpOverrides, b.DhcpOverrides)
+	}
+	return !generics.EqualSetsFn(
+		generics.FilterList(oldEntries, hostNeedsExclusiveTag),
+		generics.FilterList(newEntries, hostNeedsExclusiveTag),
+		equalEntryConfig)
 }
 
 func (c *DnsmasqConfigurator) dnsmasqConfigPath(instanceName string) string {
@@ -456,6 +596,10 @@ func (c *DnsmasqConfigurator) dnsmasqPidFile(instanceName string) string {
 	return filepath.Join(zedrouterRunDir, "dnsmasq."+instanceName+".pid")
 }
 
+func (c *DnsmasqConfigurator) dnsmasqLogFile(instanceName string) string {
+	return filepath.Join(zedrouterRunDir, "dnsmasq."+instanceName+".log")
+}
+
 func (c *DnsmasqConfigurator) dnsmasqDHCPHostsDir(instanceName string) string {
 	return filepath.Join(zedrouterRunDir, "dhcp-hosts."+instanceName)
 }
@@ -531,6 +675,27 @@ func (c *DnsmasqConfigurator) CreateDnsmasqConfig(buffer io.Writer, dnsmasq Dnsm
 		}
 	}
 
+	// Split DNS: queries for these domains (and their subdomains) are forwarded
+	// to the dedicated upstream servers, taking precedence over the default
+	// resolvers configured above.
+	for _, rule := range dnsmasq.DNSServer.SplitDNS {
+		for _, server := range rule.Servers {
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("server=/%s/%s\n", rule.Domain, server)); err != nil {
+				return writeErr(err)
+			}
+		}
+	}
+
+	if dns64Prefix := dnsmasq.DNSServer.DNS64Prefix; dns64Prefix != nil {
+		// Synthesize AAAA records from A records for IPv4-only destinations
+		// (to be translated by the NAT64 gateway).
+		if _, err := io.WriteString(buffer,
+			fmt.Sprintf("dns64=%s\n", dns64Prefix.String())); err != nil {
+			return writeErr(err)
+		}
+	}
+
 	for _, ipset := range dnsmasq.DNSServer.LinuxIPSets {
 		if _, err := io.WriteString(buffer,
 			fmt.Sprintf("ipset=/%s/%s\n",
@@ -546,6 +711,14 @@ func (c *DnsmasqConfigurator) CreateDnsmasqConfig(buffer io.Writer, dnsmasq Dnsm
 		return writeErr(err)
 	}
 
+	// Log into a per-instance file (instead of syslog) so that the log can
+	// be rotated by the periodic NI maintenance when it grows too large.
+	logFile := c.dnsmasqLogFile(dnsmasq.Name())
+	if _, err := io.WriteString(buffer,
+		fmt.Sprintf("log-facility=%s\n", logFile)); err != nil {
+		return writeErr(err)
+	}
+
 	if _, err := io.WriteString(buffer,
 		fmt.Sprintf("interface=%s\n", dnsmasq.ListenIf.IfName)); err != nil {
 		return writeErr(err)
@@ -613,6 +786,14 @@ func (c *DnsmasqConfigurator) CreateDnsmasqConfig(buffer io.Writer, dnsmasq Dnsm
 		}
 	}
 
+	if pacFileURL := dnsmasq.DHCPServer.PACFileURL; pacFileURL != "" {
+		// Option 252 (proxy auto-discovery) carries the URL of the PAC file.
+		if _, err := io.WriteString(buffer,
+			fmt.Sprintf("dhcp-option=252,\"%s\"\n", pacFileURL)); err != nil {
+			return writeErr(err)
+		}
+	}
+
 	gatewayIP := dnsmasq.DHCPServer.GatewayIP
 	ipv4Netmask := "255.255.255.0" // Default unless there is a Subnet
 	subnet := dnsmasq.DHCPServer.Subnet
@@ -753,6 +934,35 @@ func (c *DnsmasqConfigurator) CreateDnsmasqConfig(buffer io.Writer, dnsmasq Dnsm
 		}
 	}
 
+	// Hosts with custom static routes or DHCP option overrides get their own
+	// exclusive tag, combining the shared configuration with the host-specific
+	// one.
+	for _, entry := range dnsmasq.DHCPServer.StaticEntries {
+		if !hostNeedsExclusiveTag(entry) {
+			continue
+		}
+		hostTag := c.getHostTag(entry.IP)
+		isRouteValid := func(route types.IPRoute) bool {
+			return !netutils.EqualIPs(route.Gateway, entry.IP)
+		}
+		hostRoutes := generics.FilterList(staticRoutes, isRouteValid)
+		hostRoutes = append(hostRoutes,
+			generics.FilterList(entry.PropagateRoutes, isRouteValid)...)
+		hostRoutes = generics.MapList(hostRoutes, enforceRouting)
+		hostRoutes = generics.FilterDuplicatesFn(hostRoutes, equalIPRoutes)
+		if len(hostRoutes) > 0 {
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("dhcp-option=tag:%s,option:classless-static-route,%s\n",
+					hostTag, c.formatRoutesForConfig(hostRoutes))); err != nil {
+				return writeErr(err)
+			}
+		}
+		if err := c.writeTaggedDHCPOptions(buffer, hostTag, isIPv6,
+			entry.DhcpOverrides); err != nil {
+			return writeErr(err)
+		}
+	}
+
 	if isIPv6 {
 		if _, err := io.WriteString(buffer, "dhcp-range=::static,0,60m\n"); err != nil {
 			return writeErr(err)
@@ -767,6 +977,41 @@ func (c *DnsmasqConfigurator) CreateDnsmasqConfig(buffer io.Writer, dnsmasq Dnsm
 			dhcpRange, ipv4Netmask)); err != nil {
 			return writeErr(err)
 		}
+		// Dedicated address pools for the secondary IPv4 subnets of the NI.
+		// Each pool sets a tag which scopes the netmask and default-gateway
+		// options of its subnet to the clients allocated from the pool.
+		for i, pool := range dnsmasq.DHCPServer.SecondaryPools {
+			tag := fmt.Sprintf("subnet%d", i)
+			dhcpRange, err := c.CreateDHCPv4RangeConfig(
+				pool.IPRange.FromIP, pool.IPRange.ToIP)
+			if err != nil {
+				return err
+			}
+			if dhcpRange == "" {
+				continue
+			}
+			poolNetmask := ipv4Netmask
+			if pool.Subnet != nil {
+				poolNetmask = net.IP(pool.Subnet.Mask).String()
+			}
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("dhcp-range=set:%s,%s,%s,60m\n",
+					tag, dhcpRange, poolNetmask)); err != nil {
+				return writeErr(err)
+			}
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("dhcp-option=tag:%s,option:netmask,%s\n",
+					tag, poolNetmask)); err != nil {
+				return writeErr(err)
+			}
+			if pool.GatewayIP != nil && dnsmasq.DHCPServer.WithDefaultRoute {
+				if _, err := io.WriteString(buffer,
+					fmt.Sprintf("dhcp-option=tag:%s,option:router,%s\n",
+						tag, pool.GatewayIP)); err != nil {
+					return writeErr(err)
+				}
+			}
+		}
 	}
 
 	// Propagate MTU to applications.
@@ -777,6 +1022,49 @@ func (c *DnsmasqConfigurator) CreateDnsmasqConfig(buffer io.Writer, dnsmasq Dnsm
 			return writeErr(err)
 		}
 	}
+
+	// Classify clients by the content of their vendor-class/user-class options.
+	// Matched clients get the class tag set, which scopes the class-specific
+	// DHCP options and the dedicated address pool (if defined) to them.
+	for _, class := range dnsmasq.DHCPServer.ClientClasses {
+		tag := c.getClientClassTag(class.Name)
+		if class.VendorClass != "" {
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("dhcp-vendorclass=set:%s,%s\n",
+					tag, class.VendorClass)); err != nil {
+				return writeErr(err)
+			}
+		}
+		if class.UserClass != "" {
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("dhcp-userclass=set:%s,%s\n",
+					tag, class.UserClass)); err != nil {
+				return writeErr(err)
+			}
+		}
+		if !isIPv6 && class.IPRange.Start != nil {
+			dhcpRange, err := c.CreateDHCPv4RangeConfig(
+				class.IPRange.Start, class.IPRange.End)
+			if err != nil {
+				return err
+			}
+			// The per-class lease time is configured with the dedicated
+			// DHCP range of the class.
+			leaseTime := "60m"
+			if lt := class.DhcpOptions.LeaseTime; lt != 0 {
+				leaseTime = fmt.Sprintf("%d", int(lt/time.Second))
+			}
+			if _, err := io.WriteString(buffer,
+				fmt.Sprintf("dhcp-range=tag:%s,%s,%s,%s\n",
+					tag, dhcpRange, ipv4Netmask, leaseTime)); err != nil {
+				return writeErr(err)
+			}
+		}
+		if err := c.writeTaggedDHCPOptions(buffer, tag, isIPv6,
+			class.DhcpOptions); err != nil {
+			return writeErr(err)
+		}
+	}
 	return nil
 }
 
@@ -841,7 +1129,7 @@ func (c *DnsmasqConfigurator) addDHCPHostFile(dnsmasq Dnsmasq, entry MACToIP) er
 	if isIPv6 {
 		suffix += "6"
 	}
-	tag := c.dhcpTagForHost(dnsmasq.DHCPServer, entry.IP)
+	tag := c.dhcpTagForHost(dnsmasq.DHCPServer, entry)
 	hostFilename := filepath.Join(c.dnsmasqDHCPHostsDir(dnsmasq.Name()),
 		entry.MAC.String()+suffix)
 	file, err := os.Create(hostFilename)
@@ -851,12 +1139,19 @@ func (c *DnsmasqConfigurator) addDHCPHostFile(dnsmasq Dnsmasq, entry MACToIP) er
 		return err
 	}
 	defer file.Close()
+	// The lease time can be overridden per-host as the last element
+	// of the dhcp-host entry (a plain number is interpreted by dnsmasq
+	// as seconds).
+	var leaseTime string
+	if lt := entry.DhcpOverrides.LeaseTime; lt != 0 {
+		leaseTime = fmt.Sprintf(",%d", int(lt/time.Second))
+	}
 	if isIPv6 {
-		_, err = file.WriteString(fmt.Sprintf("%s,set:%s,[%s],%s\n",
-			entry.MAC, tag, entry.IP, entry.Hostname))
+		_, err = file.WriteString(fmt.Sprintf("%s,set:%s,[%s],%s%s\n",
+			entry.MAC, tag, entry.IP, entry.Hostname, leaseTime))
 	} else {
-		_, err = file.WriteString(fmt.Sprintf("%s,id:*,set:%s,%s,%s\n",
-			entry.MAC, tag, entry.IP, entry.Hostname))
+		_, err = file.WriteString(fmt.Sprintf("%s,id:*,set:%s,%s,%s%s\n",
+			entry.MAC, tag, entry.IP, entry.Hostname, leaseTime))
 	}
 	if err != nil {
 		err = fmt.Errorf("failed to write into DHCP host file %s: %w", hostFilename, err)
@@ -866,15 +1161,75 @@ func (c *DnsmasqConfigurator) addDHCPHostFile(dnsmasq Dnsmasq, entry MACToIP) er
 	return nil
 }
 
-func (c *DnsmasqConfigurator) dhcpTagForHost(dhcpSrv DHCPServer, hostIP net.IP) string {
+// writeTaggedDHCPOptions outputs DHCP option overrides scoped to the given tag
+// (either the exclusive tag of a single host or the tag of a DHCP client
+// class). In dnsmasq, tagged options take precedence over the untagged options
+// shared by all hosts.
+func (c *DnsmasqConfigurator) writeTaggedDHCPOptions(buffer io.Writer, tag string,
+	isIPv6 bool, overrides types.DhcpOverrides) error {
+	if overrides.DomainName != "" {
+		option := "domain-name"
+		if isIPv6 {
+			option = "domain-search"
+		}
+		if _, err := io.WriteString(buffer,
+			fmt.Sprintf("dhcp-option=tag:%s,option:%s,%s\n",
+				tag, option, overrides.DomainName)); err != nil {
+			return err
+		}
+	}
+	if len(overrides.NTPServers) != 0 {
+		var ntpSrvList []string
+		for _, srvIP := range overrides.NTPServers {
+			ntpSrvList = append(ntpSrvList, srvIP.String())
+		}
+		if _, err := io.WriteString(buffer,
+			fmt.Sprintf("dhcp-option=tag:%s,option:ntp-server,%s\n",
+				tag, strings.Join(ntpSrvList, ","))); err != nil {
+			return err
+		}
+	}
+	if overrides.MTU != 0 {
+		if _, err := io.WriteString(buffer,
+			fmt.Sprintf("dhcp-option=tag:%s,26,%d\n",
+				tag, overrides.MTU)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *DnsmasqConfigurator) dhcpTagForHost(dhcpSrv DHCPServer, entry MACToIP) string {
+	if hostNeedsExclusiveTag(entry) {
+		return c.getHostTag(entry.IP)
+	}
 	for _, ipRoute := range dhcpSrv.PropagateRoutes {
-		if hostIP.Equal(ipRoute.Gateway) {
-			return c.getAppGatewayTag(hostIP)
+		if entry.IP.Equal(ipRoute.Gateway) {
+			return c.getAppGatewayTag(entry.IP)
 		}
 	}
 	return endpointTag
 }
 
+// Custom routes are delivered using the DHCPv4 classless route option, hence
+// they are only considered for hosts with IPv4 addresses.
+func hostHasCustomRoutes(entry MACToIP) bool {
+	return len(entry.PropagateRoutes) > 0 && entry.IP.To4() != nil
+}
+
+// hostNeedsExclusiveTag returns true for hosts that require their own dnsmasq
+// tag, either to receive custom static routes or host-specific DHCP options.
+func hostNeedsExclusiveTag(entry MACToIP) bool {
+	return hostHasCustomRoutes(entry) || entry.DhcpOverrides.OverridesOptions()
+}
+
+func (c *DnsmasqConfigurator) getHostTag(hostIP net.IP) string {
+	ipStr := hostIP.String()
+	ipStr = strings.ReplaceAll(ipStr, ":", "-")
+	ipStr = strings.ReplaceAll(ipStr, ".", "-")
+	return hostTagPrefix + ipStr
+}
+
 func (c *DnsmasqConfigurator) getAppGatewayTag(hostIP net.IP) string {
 	ipStr := hostIP.String()
 	ipStr = strings.ReplaceAll(ipStr, ":", "-")
@@ -882,6 +1237,19 @@ func (c *DnsmasqConfigurator) getAppGatewayTag(hostIP net.IP) string {
 	return gatewayTagPrefix + ipStr
 }
 
+// getClientClassTag derives the dnsmasq tag of a DHCP client class from
+// the class name, replacing characters that would break the config syntax.
+func (c *DnsmasqConfigurator) getClientClassTag(className string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+			(r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, className)
+	return classTagPrefix + sanitized
+}
+
 // Routes should be written to the dnsmasq config in one line, with comma separated
 // entries formatted as [<dst-net>,<gw>]
 func (c *DnsmasqConfigurator) formatRoutesForConfig(routes []types.IPRoute) string {
@@ -927,6 +1295,34 @@ func (c *DnsmasqConfigurator) stopDnsmasq(ctx context.Context, instanceName stri
 	return stopProcess(ctx, c.Log, pidFile, dnsmasqStopTimeout)
 }
 
+// superviseDnsmasq registers a started dnsmasq instance with the process
+// supervisor (if there is one), which will then monitor its liveness
+// and restart it should it exit unexpectedly.
+func (c *DnsmasqConfigurator) superviseDnsmasq(instanceName string) {
+	if c.ProcSupervisor == nil {
+		return
+	}
+	c.ProcSupervisor.TrackProcess(procsupervisor.ProcessConfig{
+		Name:       dnsmasqProcName(instanceName),
+		CheckAlive: procsupervisor.PidFileCheckAlive(c.dnsmasqPidFile(instanceName)),
+		Restart: func() error {
+			return c.startDnsmasq(context.Background(), instanceName)
+		},
+	})
+}
+
+// DnsmasqProcNamePrefix : prefix of names under which dnsmasq instances
+// are registered with the process supervisor. The prefix is followed
+// by the name of the network interface on which dnsmasq listens
+// (i.e. the NI bridge).
+const DnsmasqProcNamePrefix = "dnsmasq-"
+
+// dnsmasqProcName returns the name under which the given dnsmasq instance
+// is registered with the process supervisor.
+func dnsmasqProcName(instanceName string) string {
+	return DnsmasqProcNamePrefix + instanceName
+}
+
 func (c *DnsmasqConfigurator) removeDnsmasqConfigFile(instanceName string) error {
 	cfgPath := c.dnsmasqConfigPath(instanceName)
 	if err := os.Remove(cfgPath); err != nil {
@@ -960,6 +1356,23 @@ func (c *DnsmasqConfigurator) removeDnsmasqLeaseFile(listenIfName string) error
 	return nil
 }
 
+func (c *DnsmasqConfigurator) removeDnsmasqLogFiles(instanceName string) error {
+	logFile := c.dnsmasqLogFile(instanceName)
+	var errs []string
+	for _, file := range []string{logFile, logFile + ".1"} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		err := fmt.Errorf("failed to remove dnsmasq log file(s): %s",
+			strings.Join(errs, "; "))
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
 func (c *DnsmasqConfigurator) removeDnsmasqDHCPHostDir(instanceName string) error {
 	hostDir := c.dnsmasqDHCPHostsDir(instanceName)
 	if err := os.RemoveAll(hostDir); err != nil {
@@ -981,3 +1394,151 @@ func (c *DnsmasqConfigurator) removeDnsmasqDNSHostDir(instanceName string) error
 	}
 	return nil
 }
+
+// RemoveOrphanedDnsmasqArtifacts stops dnsmasq instances left behind by
+// a previous zedrouter run (e.g. after a crash) and removes their config
+// files, host directories and lease files. Instances for which keepInstance
+// returns true are left untouched (they are adopted by the reconciler).
+// Returns the names of the removed instances (= NI bridge interface names).
+func (c *DnsmasqConfigurator) RemoveOrphanedDnsmasqArtifacts(ctx context.Context,
+	keepInstance func(instanceName string) bool) (removed []string) {
+	instances := make(map[string]struct{})
+	for _, suffix := range []string{".conf", ".pid"} {
+		files, err := filepath.Glob(
+			filepath.Join(zedrouterRunDir, "dnsmasq.*"+suffix))
+		if err != nil {
+			// Can only happen with a malformed glob pattern.
+			c.Log.Errorf("failed to glob dnsmasq %s files: %v", suffix, err)
+			continue
+		}
+		for _, file := range files {
+			instanceName := strings.TrimSuffix(
+				strings.TrimPrefix(filepath.Base(file), "dnsmasq."), suffix)
+			instances[instanceName] = struct{}{}
+		}
+	}
+	for instanceName := range instances {
+		if keepInstance(instanceName) {
+			continue
+		}
+		if isProcessRunning(c.Log, c.dnsmasqPidFile(instanceName)) {
+			// Error is already logged, continue with the file removal
+			// to not leave the instance half-cleaned.
+			_ = c.stopDnsmasq(ctx, instanceName)
+		}
+		files := []string{
+			c.dnsmasqConfigPath(instanceName),
+			c.dnsmasqPidFile(instanceName),
+			c.dnsmasqLogFile(instanceName),
+			c.dnsmasqLogFile(instanceName) + ".1",
+			devicenetwork.DnsmasqLeaseFilePath(instanceName),
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				c.Log.Warnf("failed to remove %s of orphaned dnsmasq instance: %v",
+					file, err)
+			}
+		}
+		dirs := []string{
+			c.dnsmasqDHCPHostsDir(instanceName),
+			c.dnsmasqDNSHostsDir(instanceName),
+		}
+		for _, dir := range dirs {
+			if err := os.RemoveAll(dir); err != nil {
+				c.Log.Warnf("failed to remove %s of orphaned dnsmasq instance: %v",
+					dir, err)
+			}
+		}
+		removed = append(removed, instanceName)
+	}
+	return removed
+}
+
+// RotateDnsmasqLogFile rotates the log file of the given dnsmasq instance
+// if it has grown over maxSize bytes. The active log is moved aside, replacing
+// the previously rotated file (at most one rotation is kept), and dnsmasq
+// is signaled with SIGUSR2 to reopen the log file.
+// Returns the size of the rotated log, or zero if no rotation was needed.
+func (c *DnsmasqConfigurator) RotateDnsmasqLogFile(instanceName string,
+	maxSize int64) (rotatedBytes uint64, err error) {
+	logFile := c.dnsmasqLogFile(instanceName)
+	fileInfo, err := os.Stat(logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat dnsmasq log file %s: %w",
+			logFile, err)
+	}
+	if fileInfo.Size() <= maxSize {
+		return 0, nil
+	}
+	if err := os.Rename(logFile, logFile+".1"); err != nil {
+		err = fmt.Errorf("failed to rotate dnsmasq log file %s: %w", logFile, err)
+		c.Log.Error(err)
+		return 0, err
+	}
+	// SIGUSR2 makes dnsmasq close and reopen the log file.
+	pidFile := c.dnsmasqPidFile(instanceName)
+	if err := sendSignalToProcess(c.Log, pidFile, syscall.SIGUSR2); err != nil {
+		return uint64(fileInfo.Size()), err
+	}
+	return uint64(fileInfo.Size()), nil
+}
+
+// CompactDnsmasqLeaseDB removes entries which have already been expired
+// for longer than expiredFor from the lease DB of the given dnsmasq instance.
+// A running dnsmasq prunes expired leases from its in-memory DB, but the lease
+// file is only fully rewritten when the DB changes, so with mostly static
+// clients long-expired entries may linger in the file (and get loaded back
+// with every dnsmasq restart).
+// The file is rewritten in-place (keeping the inode) so that a running
+// dnsmasq instance, which keeps the file open, is not cut off from it.
+// A lease appended by dnsmasq within the short rewrite window may disappear
+// from the file, but it is still present in the dnsmasq memory and will be
+// written back with the next lease DB update.
+// Returns the number of removed entries.
+func (c *DnsmasqConfigurator) CompactDnsmasqLeaseDB(listenIfName string,
+	expiredFor time.Duration) (removedLeases int, err error) {
+	leaseFilepath := devicenetwork.DnsmasqLeaseFilePath(listenIfName)
+	content, err := os.ReadFile(leaseFilepath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read dnsmasq lease file %s: %w",
+			leaseFilepath, err)
+	}
+	deadline := time.Now().Add(-expiredFor).Unix()
+	var keptLines []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		// The first column is the lease expiry time as a Unix timestamp
+		// (zero for an infinite lease).
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			expiry, parseErr := strconv.ParseInt(fields[0], 10, 64)
+			if parseErr == nil && expiry != 0 && expiry < deadline {
+				removedLeases++
+				continue
+			}
+		}
+		keptLines = append(keptLines, line)
+	}
+	if removedLeases == 0 {
+		return 0, nil
+	}
+	var newContent string
+	if len(keptLines) > 0 {
+		newContent = strings.Join(keptLines, "\n") + "\n"
+	}
+	if err := os.WriteFile(leaseFilepath, []byte(newContent), 0644); err != nil {
+		err = fmt.Errorf("failed to rewrite dnsmasq lease file %s: %w",
+			leaseFilepath, err)
+		c.Log.Error(err)
+		return 0, err
+	}
+	return removedLeases, nil
+}
@@ -8,6 +8,7 @@ import (
 	"net"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
@@ -135,6 +136,7 @@ no-resolv
 ipset=/zededa.com/ipv4.zededa.com,ipv6.zededa.com
 ipset=/example.com/ipv4.example.com,ipv6.example.com
 pid-file=/run/zedrouter/dnsmasq.br0.pid
+log-facility=/run/zedrouter/dnsmasq.br0.log
 interface=br0
 listen-address=10.0.0.1
 hostsdir=/run/zedrouter/hosts.br0
@@ -195,6 +197,151 @@ func TestCreateDnsmasqConfigWithoutGateway(t *testing.T) {
 	}
 }
 
+func TestCreateDnsmasqConfigWithPerHostRoutes(t *testing.T) {
+	t.Parallel()
+
+	dnsmasq := exampleDnsmasqParams()
+	dnsmasq.DHCPServer.StaticEntries[0].PropagateRoutes = []types.IPRoute{
+		{
+			DstNetwork: &net.IPNet{
+				IP:   net.IP{10, 50, 0, 0},
+				Mask: net.IPv4Mask(255, 255, 0, 0),
+			},
+			Gateway: net.IP{10, 0, 0, 1},
+		},
+	}
+	config := createDnsmasqConfig(dnsmasq)
+
+	hostRoutesRex := "(?m)^dhcp-option=tag:host-10-0-0-5," +
+		"option:classless-static-route," +
+		"10.0.0.1/32,0.0.0.0,10.0.0.0/24,10.0.0.1,192.168.1.0/24,10.0.0.1," +
+		"172.30.0.0/16,10.0.0.1,0.0.0.0/0,10.0.0.1,10.50.0.0/16,10.0.0.1$"
+	ok, err := regexp.MatchString(hostRoutesRex, config)
+	if err != nil {
+		panic(err)
+	}
+	if !ok {
+		t.Fatalf("expected to match '%s', but got '%s'", hostRoutesRex, config)
+	}
+}
+
+func TestCreateDnsmasqConfigWithSplitDNS(t *testing.T) {
+	t.Parallel()
+
+	dnsmasq := exampleDnsmasqParams()
+	dnsmasq.DNSServer.SplitDNS = []types.SplitDNSRule{
+		{
+			Domain:  "corp.local",
+			Servers: []net.IP{{10, 8, 0, 1}, {10, 8, 0, 2}},
+		},
+	}
+	config := createDnsmasqConfig(dnsmasq)
+
+	for _, line := range []string{
+		"server=/corp.local/10.8.0.1",
+		"server=/corp.local/10.8.0.2",
+	} {
+		rex := "(?m)^" + regexp.QuoteMeta(line) + "$"
+		ok, err := regexp.MatchString(rex, config)
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			t.Fatalf("expected to match '%s', but got '%s'", rex, config)
+		}
+	}
+}
+
+func TestCreateDnsmasqConfigWithDhcpOverrides(t *testing.T) {
+	t.Parallel()
+
+	dnsmasq := exampleDnsmasqParams()
+	dnsmasq.DHCPServer.StaticEntries[0].DhcpOverrides = types.DhcpOverrides{
+		LeaseTime:  5 * time.Minute,
+		MTU:        1400,
+		NTPServers: []net.IP{{94, 130, 35, 4}},
+		DomainName: "example.test",
+	}
+	config := createDnsmasqConfig(dnsmasq)
+
+	expectedLines := []string{
+		// The host gets its own exclusive tag, but keeps the set of routes
+		// propagated to all endpoints.
+		"dhcp-option=tag:host-10-0-0-5,option:classless-static-route," +
+			"10.0.0.1/32,0.0.0.0,10.0.0.0/24,10.0.0.1,192.168.1.0/24,10.0.0.1," +
+			"172.30.0.0/16,10.0.0.1,0.0.0.0/0,10.0.0.1",
+		"dhcp-option=tag:host-10-0-0-5,option:domain-name,example.test",
+		"dhcp-option=tag:host-10-0-0-5,option:ntp-server,94.130.35.4",
+		"dhcp-option=tag:host-10-0-0-5,26,1400",
+	}
+	for _, line := range expectedLines {
+		rex := "(?m)^" + regexp.QuoteMeta(line) + "$"
+		ok, err := regexp.MatchString(rex, config)
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			t.Fatalf("expected to match '%s', but got '%s'", rex, config)
+		}
+	}
+}
+
+func TestCreateDnsmasqConfigWithClientClasses(t *testing.T) {
+	t.Parallel()
+
+	dnsmasq := exampleDnsmasqParams()
+	dnsmasq.DHCPServer.ClientClasses = []types.DHCPClientClass{
+		{
+			Name:        "ip cameras",
+			VendorClass: "ACME Camera",
+			IPRange: types.IPRange{
+				Start: net.IP{10, 0, 0, 200},
+				End:   net.IP{10, 0, 0, 220},
+			},
+			DhcpOptions: types.DhcpOverrides{
+				LeaseTime:  10 * time.Minute,
+				NTPServers: []net.IP{{94, 130, 35, 4}},
+			},
+		},
+		{
+			Name:      "kiosk",
+			UserClass: "kiosk-terminal",
+			DhcpOptions: types.DhcpOverrides{
+				MTU: 1400,
+			},
+		},
+	}
+	config := createDnsmasqConfig(dnsmasq)
+
+	expectedLines := []string{
+		// Invalid characters of the class name are replaced in the tag.
+		"dhcp-vendorclass=set:class-ip-cameras,ACME Camera",
+		"dhcp-range=tag:class-ip-cameras,10.0.0.200,10.0.0.220,255.255.255.0,600",
+		"dhcp-option=tag:class-ip-cameras,option:ntp-server,94.130.35.4",
+		"dhcp-userclass=set:class-kiosk,kiosk-terminal",
+		"dhcp-option=tag:class-kiosk,26,1400",
+	}
+	for _, line := range expectedLines {
+		rex := "(?m)^" + regexp.QuoteMeta(line) + "$"
+		ok, err := regexp.MatchString(rex, config)
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			t.Fatalf("expected to match '%s', but got '%s'", rex, config)
+		}
+	}
+	// The class without a dedicated pool must not get its own dhcp-range.
+	kioskRangeRex := "(?m)^dhcp-range=tag:class-kiosk"
+	ok, err := regexp.MatchString(kioskRangeRex, config)
+	if err != nil {
+		panic(err)
+	}
+	if ok {
+		t.Fatalf("expected not to match '%s', but got '%s'", kioskRangeRex, config)
+	}
+}
+
 func TestCreateDnsmasqConfigWithDisabledAllOnesNetmask(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,61 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package genericitems
+
+import (
+	"fmt"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	uuid "github.com/satori/go.uuid"
+)
+
+// NIConn : represents working external connectivity of a network instance
+// (the NI has a default route via its port). This is an external item - the
+// reconciler merely mirrors the observed connectivity status into the current
+// state depgraph so that other items can depend on it, such as the dnsmasq
+// instance of a network instance with gated DHCP
+// (see NetworkInstanceConfig.GateDHCPOnConnectivity).
+type NIConn struct {
+	// NI : UUID of the network instance.
+	NI uuid.UUID
+}
+
+// Name returns the UUID of the network instance.
+func (c NIConn) Name() string {
+	return c.NI.String()
+}
+
+// Label is more human-readable than Name.
+func (c NIConn) Label() string {
+	return "Connectivity of NI " + c.NI.String()
+}
+
+// Type of the item.
+func (c NIConn) Type() string {
+	return NIConnTypename
+}
+
+// Equal compares two NIConn instances.
+func (c NIConn) Equal(other dg.Item) bool {
+	c2, isNIConn := other.(NIConn)
+	if !isNIConn {
+		return false
+	}
+	return c == c2
+}
+
+// External returns true - connectivity status is observed, not configured.
+func (c NIConn) External() bool {
+	return true
+}
+
+// String describes NIConn.
+func (c NIConn) String() string {
+	return fmt.Sprintf("NIConn: {NI: %s}", c.NI.String())
+}
+
+// Dependencies returns nothing (external item).
+func (c NIConn) Dependencies() (deps []dg.Dependency) {
+	return nil
+}
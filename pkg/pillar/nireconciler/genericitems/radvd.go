@@ -7,13 +7,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	dg "github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve-libs/reconciler"
 	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
+	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -28,6 +34,16 @@ type Radvd struct {
 	ListenIf NetworkIf
 	// MTU : Maximum transmission unit size to advertise.
 	MTU uint16
+	// Prefix : IPv6 prefix of the network instance.
+	// Advertised with explicit lifetimes if RAConfig asks for it.
+	Prefix *net.IPNet
+	// RAConfig : router advertisement parameters
+	// (flags, prefix lifetimes, RDNSS, DNSSL).
+	RAConfig types.IPv6RAConfig
+	// PropagateRoutes : additional more-specific routes advertised to hosts
+	// using the Route Information Option (RFC 4191). The advertising router
+	// is implicitly the next hop.
+	PropagateRoutes []*net.IPNet
 }
 
 // Name returns the interface name on which radvd listens.
@@ -52,7 +68,11 @@ func (r Radvd) Equal(other dg.Item) bool {
 	r2 := other.(Radvd)
 	return r.ForNI == r2.ForNI &&
 		r.ListenIf == r2.ListenIf &&
-		r.MTU == r2.MTU
+		r.MTU == r2.MTU &&
+		netutils.EqualIPNets(r.Prefix, r2.Prefix) &&
+		r.RAConfig.Equal(r2.RAConfig) &&
+		generics.EqualSetsFn(r.PropagateRoutes, r2.PropagateRoutes,
+			netutils.EqualIPNets)
 }
 
 // External returns false.
@@ -62,8 +82,13 @@ func (r Radvd) External() bool {
 
 // String describes the radvd instance.
 func (r Radvd) String() string {
-	return fmt.Sprintf("Radvd: {NI: %s, listenIf: %s, MTU: %d}",
-		r.ForNI, r.ListenIf.IfName, r.MTU)
+	return fmt.Sprintf("Radvd: {NI: %s, listenIf: %s, MTU: %d, prefix: %v, "+
+		"managedFlag: %t, otherConfigFlag: %t, prefixPreferredLifetime: %d, "+
+		"prefixValidLifetime: %d, RDNSS: %v, DNSSL: %v, propagateRoutes: %v}",
+		r.ForNI, r.ListenIf.IfName, r.MTU, r.Prefix,
+		r.RAConfig.AdvManagedFlag, r.RAConfig.AdvOtherConfigFlag,
+		r.RAConfig.PrefixPreferredLifetime, r.RAConfig.PrefixValidLifetime,
+		r.RAConfig.RDNSS, r.RAConfig.DNSSL, r.PropagateRoutes)
 }
 
 // Dependencies returns returns the interface on which radvd listens
@@ -76,23 +101,6 @@ func (r Radvd) Dependencies() (deps []dg.Dependency) {
 	return deps
 }
 
-const radvdConfigTemplate = `
-# Automatically generated by zedrouter
-interface %s {
-	IgnoreIfMissing on;
-	AdvSendAdvert on;
-	MaxRtrAdvInterval 1800;
-	AdvManagedFlag on;
-	AdvLinkMTU %d;
-	AdvDefaultPreference low;
-	route fd00::/8
-	{
-		AdvRoutePreference high;
-		AdvRouteLifetime 1800;
-	};
-};
-`
-
 const (
 	radvdStartTimeout = 3 * time.Second
 	radvdStopTimeout  = 10 * time.Second
@@ -149,6 +157,78 @@ func (c *RadvdConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bo
 	return true
 }
 
+func boolToOnOff(flag bool) string {
+	if flag {
+		return "on"
+	}
+	return "off"
+}
+
+// CreateRadvdConfig builds the content of the radvd config file
+// (https://linux.die.net/man/5/radvd.conf) and writes it to the given writer.
+func (c *RadvdConfigurator) CreateRadvdConfig(w io.Writer, radvd Radvd) error {
+	raConfig := radvd.RAConfig
+	config := new(strings.Builder)
+	config.WriteString("# Automatically generated by zedrouter\n")
+	config.WriteString(fmt.Sprintf("interface %s {\n", radvd.ListenIf.IfName))
+	config.WriteString("\tIgnoreIfMissing on;\n")
+	config.WriteString("\tAdvSendAdvert on;\n")
+	config.WriteString("\tMaxRtrAdvInterval 1800;\n")
+	config.WriteString(fmt.Sprintf("\tAdvManagedFlag %s;\n",
+		boolToOnOff(raConfig.AdvManagedFlag)))
+	if raConfig.AdvOtherConfigFlag {
+		config.WriteString("\tAdvOtherConfigFlag on;\n")
+	}
+	config.WriteString(fmt.Sprintf("\tAdvLinkMTU %d;\n", radvd.MTU))
+	config.WriteString("\tAdvDefaultPreference low;\n")
+	if radvd.Prefix != nil &&
+		(raConfig.PrefixPreferredLifetime != 0 || raConfig.PrefixValidLifetime != 0) {
+		config.WriteString(fmt.Sprintf("\tprefix %s\n", radvd.Prefix.String()))
+		config.WriteString("\t{\n")
+		config.WriteString("\t\tAdvOnLink on;\n")
+		// With DHCPv6-managed address assignment, hosts should not
+		// autoconfigure addresses from the prefix.
+		config.WriteString(fmt.Sprintf("\t\tAdvAutonomous %s;\n",
+			boolToOnOff(!raConfig.AdvManagedFlag)))
+		if raConfig.PrefixPreferredLifetime != 0 {
+			config.WriteString(fmt.Sprintf("\t\tAdvPreferredLifetime %d;\n",
+				raConfig.PrefixPreferredLifetime))
+		}
+		if raConfig.PrefixValidLifetime != 0 {
+			config.WriteString(fmt.Sprintf("\t\tAdvValidLifetime %d;\n",
+				raConfig.PrefixValidLifetime))
+		}
+		config.WriteString("\t};\n")
+	}
+	if len(raConfig.RDNSS) != 0 {
+		var servers []string
+		for _, server := range raConfig.RDNSS {
+			servers = append(servers, server.String())
+		}
+		config.WriteString(fmt.Sprintf("\tRDNSS %s {};\n",
+			strings.Join(servers, " ")))
+	}
+	if len(raConfig.DNSSL) != 0 {
+		config.WriteString(fmt.Sprintf("\tDNSSL %s {};\n",
+			strings.Join(raConfig.DNSSL, " ")))
+	}
+	config.WriteString("\troute fd00::/8\n")
+	config.WriteString("\t{\n")
+	config.WriteString("\t\tAdvRoutePreference high;\n")
+	config.WriteString("\t\tAdvRouteLifetime 1800;\n")
+	config.WriteString("\t};\n")
+	for _, routePrefix := range radvd.PropagateRoutes {
+		config.WriteString(fmt.Sprintf("\troute %s\n", routePrefix.String()))
+		config.WriteString("\t{\n")
+		config.WriteString("\t\tAdvRoutePreference medium;\n")
+		config.WriteString("\t\tAdvRouteLifetime 1800;\n")
+		config.WriteString("\t};\n")
+	}
+	config.WriteString("};\n")
+	_, err := io.WriteString(w, config.String())
+	return err
+}
+
 func (c *RadvdConfigurator) radvdConfigPath(instanceName string) string {
 	return filepath.Join(zedrouterRunDir, "radvd."+instanceName+".conf")
 }
@@ -166,8 +246,7 @@ func (c *RadvdConfigurator) createRadvdConfigFile(radvd Radvd) error {
 		return err
 	}
 	defer file.Close()
-	_, err = file.WriteString(
-		fmt.Sprintf(radvdConfigTemplate, radvd.ListenIf.IfName, radvd.MTU))
+	err = c.CreateRadvdConfig(file, radvd)
 	if err != nil {
 		err = fmt.Errorf("failed to write radvd config to file %s: %w", cfgPath, err)
 		c.Log.Error(err)
@@ -0,0 +1,147 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package genericitems_test
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	uuid "github.com/satori/go.uuid"
+)
+
+func exampleRadvdParams() genericitems.Radvd {
+	var radvd genericitems.Radvd
+	radvd.ForNI, _ = uuid.FromString("d882ce20-fac2-448b-82e6-d411619d488f")
+	radvd.ListenIf.IfName = "bn1"
+	radvd.MTU = 1500
+	_, subnet, _ := net.ParseCIDR("2001:db8:1::/64")
+	radvd.Prefix = subnet
+	return radvd
+}
+
+func createRadvdConfig(radvd genericitems.Radvd) string {
+	configurator := &genericitems.RadvdConfigurator{Log: log}
+	var buf bytes.Buffer
+	err := configurator.CreateRadvdConfig(&buf, radvd)
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+func TestCreateRadvdConfigDefault(t *testing.T) {
+	t.Parallel()
+
+	radvd := exampleRadvdParams()
+	radvd.RAConfig = types.IPv6RAConfig{AdvManagedFlag: true}
+	config := createRadvdConfig(radvd)
+
+	configExpected := `# Automatically generated by zedrouter
+interface bn1 {
+	IgnoreIfMissing on;
+	AdvSendAdvert on;
+	MaxRtrAdvInterval 1800;
+	AdvManagedFlag on;
+	AdvLinkMTU 1500;
+	AdvDefaultPreference low;
+	route fd00::/8
+	{
+		AdvRoutePreference high;
+		AdvRouteLifetime 1800;
+	};
+};
+`
+	if configExpected != config {
+		t.Fatalf("expected '%s', but got '%s'", configExpected, config)
+	}
+}
+
+func TestCreateRadvdConfigWithPropagatedRoutes(t *testing.T) {
+	t.Parallel()
+
+	radvd := exampleRadvdParams()
+	radvd.RAConfig = types.IPv6RAConfig{AdvManagedFlag: true}
+	_, route1, _ := net.ParseCIDR("2001:db8:100::/48")
+	_, route2, _ := net.ParseCIDR("2001:db8:200::/64")
+	radvd.PropagateRoutes = []*net.IPNet{route1, route2}
+	config := createRadvdConfig(radvd)
+
+	configExpected := `# Automatically generated by zedrouter
+interface bn1 {
+	IgnoreIfMissing on;
+	AdvSendAdvert on;
+	MaxRtrAdvInterval 1800;
+	AdvManagedFlag on;
+	AdvLinkMTU 1500;
+	AdvDefaultPreference low;
+	route fd00::/8
+	{
+		AdvRoutePreference high;
+		AdvRouteLifetime 1800;
+	};
+	route 2001:db8:100::/48
+	{
+		AdvRoutePreference medium;
+		AdvRouteLifetime 1800;
+	};
+	route 2001:db8:200::/64
+	{
+		AdvRoutePreference medium;
+		AdvRouteLifetime 1800;
+	};
+};
+`
+	if configExpected != config {
+		t.Fatalf("expected '%s', but got '%s'", configExpected, config)
+	}
+}
+
+func TestCreateRadvdConfigWithRAOptions(t *testing.T) {
+	t.Parallel()
+
+	radvd := exampleRadvdParams()
+	radvd.RAConfig = types.IPv6RAConfig{
+		AdvOtherConfigFlag:      true,
+		PrefixPreferredLifetime: 1800,
+		PrefixValidLifetime:     3600,
+		RDNSS: []net.IP{
+			net.ParseIP("2001:4860:4860::8888"),
+			net.ParseIP("2001:4860:4860::8844"),
+		},
+		DNSSL: []string{"example.com", "branch.example.com"},
+	}
+	config := createRadvdConfig(radvd)
+
+	configExpected := `# Automatically generated by zedrouter
+interface bn1 {
+	IgnoreIfMissing on;
+	AdvSendAdvert on;
+	MaxRtrAdvInterval 1800;
+	AdvManagedFlag off;
+	AdvOtherConfigFlag on;
+	AdvLinkMTU 1500;
+	AdvDefaultPreference low;
+	prefix 2001:db8:1::/64
+	{
+		AdvOnLink on;
+		AdvAutonomous on;
+		AdvPreferredLifetime 1800;
+		AdvValidLifetime 3600;
+	};
+	RDNSS 2001:4860:4860::8888 2001:4860:4860::8844 {};
+	DNSSL example.com branch.example.com {};
+	route fd00::/8
+	{
+		AdvRoutePreference high;
+		AdvRouteLifetime 1800;
+	};
+};
+`
+	if configExpected != config {
+		t.Fatalf("expected '%s', but got '%s'", configExpected, config)
+	}
+}
@@ -6,21 +6,25 @@ package genericitems
 import (
 	"github.com/lf-edge/eve-libs/reconciler"
 	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/sirupsen/logrus"
 )
 
 // RegisterItems : register all configurators implemented by this package.
 func RegisterItems(log *base.LogObject, logger *logrus.Logger,
-	registry *reconciler.DefaultRegistry) error {
+	registry *reconciler.DefaultRegistry,
+	procSupervisor *procsupervisor.ProcSupervisor) error {
 	type configurator struct {
 		c reconciler.Configurator
 		t string
 	}
 	configurators := []configurator{
 		{c: &IPReserveConfigurator{Log: log}, t: IPReserveTypename},
-		{c: &DnsmasqConfigurator{Log: log, Logger: logger}, t: DnsmasqTypename},
+		{c: &DnsmasqConfigurator{Log: log, Logger: logger,
+			ProcSupervisor: procSupervisor}, t: DnsmasqTypename},
 		{c: &HTTPServerConfigurator{Log: log, Logger: logger}, t: HTTPServerTypename},
 		{c: &RadvdConfigurator{Log: log}, t: RadvdTypename},
+		{c: &TaygaConfigurator{Log: log}, t: TaygaTypename},
 	}
 	for _, configurator := range configurators {
 		err := registry.Register(configurator.c, configurator.t)
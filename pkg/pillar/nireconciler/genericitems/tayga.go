@@ -0,0 +1,286 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package genericitems
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Tayga : stateless NAT64 translator daemon (http://www.litech.org/tayga/).
+// One instance is run for every local IPv6-only network instance with
+// the NAT64 service enabled. The daemon translates between the IPv6 side
+// (addresses from NAT64Prefix) and the IPv4 side (addresses dynamically
+// allocated from IPv4Pool), using a TUN interface for the packet exchange
+// with the kernel.
+type Tayga struct {
+	// ForNI : UUID of the Network Instance for which this tayga instance is created.
+	// Mostly used just to force re-start of tayga when one NI is being deleted
+	// and subsequently another is created for the same bridge interface name
+	// (ForNI will differ in such case).
+	ForNI uuid.UUID
+	// TunIfName : name of the TUN interface created and used by tayga.
+	TunIfName string
+	// NAT64Prefix : IPv6 prefix used to embed IPv4 destination addresses.
+	NAT64Prefix *net.IPNet
+	// IPv4Pool : pool of IPv4 addresses dynamically mapped to IPv6 hosts.
+	IPv4Pool *net.IPNet
+	// IPv4Addr : IPv4 address of the translator itself (taken from IPv4Pool).
+	IPv4Addr net.IP
+}
+
+// Name returns the name of the TUN interface used by tayga.
+// This ensures that there cannot be two different tayga instances
+// that would attempt to use the same TUN interface at the same time.
+func (t Tayga) Name() string {
+	return t.TunIfName
+}
+
+// Label for the tayga instance.
+func (t Tayga) Label() string {
+	return "tayga for " + t.TunIfName
+}
+
+// Type of the item.
+func (t Tayga) Type() string {
+	return TaygaTypename
+}
+
+// Equal compares two Tayga instances.
+func (t Tayga) Equal(other dg.Item) bool {
+	t2 := other.(Tayga)
+	return t.ForNI == t2.ForNI &&
+		t.TunIfName == t2.TunIfName &&
+		netutils.EqualIPNets(t.NAT64Prefix, t2.NAT64Prefix) &&
+		netutils.EqualIPNets(t.IPv4Pool, t2.IPv4Pool) &&
+		netutils.EqualIPs(t.IPv4Addr, t2.IPv4Addr)
+}
+
+// External returns false.
+func (t Tayga) External() bool {
+	return false
+}
+
+// String describes the tayga instance.
+func (t Tayga) String() string {
+	return fmt.Sprintf("Tayga: {NI: %s, tunIf: %s, NAT64Prefix: %v, "+
+		"IPv4Pool: %v, IPv4Addr: %v}",
+		t.ForNI, t.TunIfName, t.NAT64Prefix, t.IPv4Pool, t.IPv4Addr)
+}
+
+// Dependencies returns nothing - tayga creates its own TUN interface.
+func (t Tayga) Dependencies() (deps []dg.Dependency) {
+	return nil
+}
+
+const (
+	taygaStartTimeout = 3 * time.Second
+	taygaStopTimeout  = 10 * time.Second
+)
+
+// TaygaConfigurator implements Configurator interface (libs/reconciler) for tayga.
+type TaygaConfigurator struct {
+	Log *base.LogObject
+}
+
+// Create creates the TUN interface and starts tayga.
+func (c *TaygaConfigurator) Create(ctx context.Context, item dg.Item) error {
+	tayga, isTayga := item.(Tayga)
+	if !isTayga {
+		return fmt.Errorf("invalid item type %T, expected Tayga", item)
+	}
+	if err := c.createTaygaConfigFile(tayga); err != nil {
+		return err
+	}
+	dataDir := taygaDataDir(tayga.Name())
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		err = fmt.Errorf("failed to create tayga data dir %s: %w", dataDir, err)
+		c.Log.Error(err)
+		return err
+	}
+	if err := c.createTunIf(tayga); err != nil {
+		return err
+	}
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := c.startTayga(ctx, tayga.Name())
+		done(err)
+	}()
+	return nil
+}
+
+// Modify is not implemented.
+func (c *TaygaConfigurator) Modify(ctx context.Context, oldItem, newItem dg.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete stops tayga and removes the TUN interface.
+func (c *TaygaConfigurator) Delete(ctx context.Context, item dg.Item) error {
+	tayga, isTayga := item.(Tayga)
+	if !isTayga {
+		return fmt.Errorf("invalid item type %T, expected Tayga", item)
+	}
+	done := reconciler.ContinueInBackground(ctx)
+	go func() {
+		err := c.stopTayga(ctx, tayga.Name())
+		if err == nil {
+			// Ignore errors from here.
+			_ = c.removeTunIf(tayga)
+			_ = c.removeTaygaConfigFile(tayga.Name())
+			_ = c.removeTaygaPidFile(tayga.Name())
+			_ = os.RemoveAll(taygaDataDir(tayga.Name()))
+		}
+		done(err)
+	}()
+	return nil
+}
+
+// NeedsRecreate always returns true - Modify is not implemented.
+func (c *TaygaConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
+	return true
+}
+
+// CreateTaygaConfig builds the content of the tayga config file
+// (http://www.litech.org/tayga/tayga.conf.5.html) and writes it to the given writer.
+func (c *TaygaConfigurator) CreateTaygaConfig(w io.Writer, tayga Tayga) error {
+	config := new(strings.Builder)
+	config.WriteString("# Automatically generated by zedrouter\n")
+	config.WriteString(fmt.Sprintf("tun-device %s\n", tayga.TunIfName))
+	config.WriteString(fmt.Sprintf("ipv4-addr %s\n", tayga.IPv4Addr))
+	config.WriteString(fmt.Sprintf("prefix %s\n", tayga.NAT64Prefix))
+	config.WriteString(fmt.Sprintf("dynamic-pool %s\n", tayga.IPv4Pool))
+	config.WriteString(fmt.Sprintf("data-dir %s\n", taygaDataDir(tayga.Name())))
+	_, err := io.WriteString(w, config.String())
+	return err
+}
+
+func taygaConfigPath(instanceName string) string {
+	return filepath.Join(zedrouterRunDir, "tayga."+instanceName+".conf")
+}
+
+func taygaPidFile(instanceName string) string {
+	return filepath.Join(zedrouterRunDir, "tayga."+instanceName+".pid")
+}
+
+func taygaDataDir(instanceName string) string {
+	return filepath.Join(zedrouterRunDir, "tayga."+instanceName)
+}
+
+func (c *TaygaConfigurator) createTaygaConfigFile(tayga Tayga) error {
+	cfgPath := taygaConfigPath(tayga.Name())
+	file, err := os.Create(cfgPath)
+	if err != nil {
+		err = fmt.Errorf("failed to create tayga config file %s: %w", cfgPath, err)
+		c.Log.Error(err)
+		return err
+	}
+	defer file.Close()
+	err = c.CreateTaygaConfig(file, tayga)
+	if err != nil {
+		err = fmt.Errorf("failed to write tayga config to file %s: %w", cfgPath, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *TaygaConfigurator) createTunIf(tayga Tayga) error {
+	cfgPath := taygaConfigPath(tayga.Name())
+	output, err := base.Exec(c.Log, "tayga", "--mktun", "-c", cfgPath).CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to create tayga TUN interface %s: %s (%w)",
+			tayga.TunIfName, output, err)
+		c.Log.Error(err)
+		return err
+	}
+	output, err = base.Exec(
+		c.Log, "ip", "link", "set", "dev", tayga.TunIfName, "up").CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to set tayga TUN interface %s UP: %s (%w)",
+			tayga.TunIfName, output, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *TaygaConfigurator) removeTunIf(tayga Tayga) error {
+	cfgPath := taygaConfigPath(tayga.Name())
+	output, err := base.Exec(c.Log, "tayga", "--rmtun", "-c", cfgPath).CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to remove tayga TUN interface %s: %s (%w)",
+			tayga.TunIfName, output, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Start tayga as a daemon process.
+func (c *TaygaConfigurator) startTayga(ctx context.Context, instanceName string) error {
+	cmd := "nohup"
+	pidFile := taygaPidFile(instanceName)
+	args := []string{
+		"tayga",
+		"-c", taygaConfigPath(instanceName),
+		"-p", pidFile,
+	}
+	return startProcess(ctx, c.Log, cmd, args, pidFile, taygaStartTimeout, true)
+}
+
+func (c *TaygaConfigurator) stopTayga(ctx context.Context, instanceName string) error {
+	pidFile := taygaPidFile(instanceName)
+	return stopProcess(ctx, c.Log, pidFile, taygaStopTimeout)
+}
+
+func (c *TaygaConfigurator) removeTaygaConfigFile(instanceName string) error {
+	cfgPath := taygaConfigPath(instanceName)
+	if err := os.Remove(cfgPath); err != nil {
+		err = fmt.Errorf("failed to remove tayga config %s: %w", cfgPath, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (c *TaygaConfigurator) removeTaygaPidFile(instanceName string) error {
+	pidPath := taygaPidFile(instanceName)
+	if err := os.Remove(pidPath); err != nil {
+		err = fmt.Errorf("failed to remove tayga PID file %s: %w", pidPath, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// TaygaDynamicMappingCount returns the number of IPv4 pool addresses currently
+// dynamically mapped to IPv6 hosts by the given tayga instance.
+// Tayga persists the dynamic mappings in the "dynamic.map" file inside its
+// data directory, one "map <ipv4> <ipv6>" line per allocated address.
+func TaygaDynamicMappingCount(instanceName string) (count uint32) {
+	mapPath := filepath.Join(taygaDataDir(instanceName), "dynamic.map")
+	content, err := os.ReadFile(mapPath)
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "map ") {
+			count++
+		}
+	}
+	return count
+}
@@ -23,9 +23,20 @@ const (
 	DnsmasqTypename = "Dnsmasq"
 	// RadvdTypename : typename for radvd program (router advertisement daemon).
 	RadvdTypename = "Radvd"
+	// TaygaTypename : typename for tayga program (NAT64 translator).
+	TaygaTypename = "Tayga"
 	// IPSetTypename : typename for Linux IP set (from netfilter).
 	// Implemented in linuxitems.
 	// Type definition is here because it is referenced by dnsmasq
 	// (when used with Linux ipsets).
 	IPSetTypename = "IPSet"
+	// NIConnTypename : typename for the external item representing working
+	// external connectivity of a network instance.
+	NIConnTypename = "NI-Connectivity"
+	// CTTimeoutPolicyTypename : typename for a connection tracking timeout
+	// policy (nfnetlink_cttimeout).
+	// Implemented in linuxitems.
+	// Type definition is here because it is referenced by iptables rules
+	// with the CT target (see the "--timeout" option).
+	CTTimeoutPolicyTypename = "CTTimeoutPolicy"
 )
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 
 	dg "github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/iptables"
+	"github.com/lf-edge/eve/pkg/pillar/netlog"
+	"github.com/lf-edge/eve/pkg/pillar/snifilter"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
 	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
@@ -20,6 +23,10 @@ import (
 const (
 	// dropCounterChain : chain with no rules, used merely to count dropped packets.
 	dropCounterChain = "DROP-COUNTER"
+	// aclParseLogCategory : netlog category for errors returned by
+	// parseUserACLRule. An invalid ACL rule repeats the same error with
+	// every reconciliation, hence the rate limiting.
+	aclParseLogCategory = "acl-parse"
 )
 
 // Describes protocol that is allowed implicitly because it provides some essential
@@ -58,6 +65,9 @@ type portMap struct {
 	protocol     string
 	externalPort string
 	targetPort   int
+	// sourceCIDR : optional allowlist of remote sources permitted to initiate
+	// connections via this port mapping (nil = any source).
+	sourceCIDR *net.IPNet
 }
 
 // These variables are used as constants.
@@ -239,10 +249,51 @@ func getEssentialIPv6Protos(niType types.NetworkInstanceType,
 	return
 }
 
+// validateICMPTypeValue checks the value of an icmp-type/icmp6-type ACE match.
+// Accepted is either a numeric ICMP type, optionally followed by a numeric
+// code ("<type>[/<code>]"), or a symbolic type name as recognized
+// by ip(6)tables (e.g. "echo-request").
+func validateICMPTypeValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("empty ICMP type")
+	}
+	typeStr, codeStr, hasCode := strings.Cut(value, "/")
+	if _, err := strconv.ParseUint(typeStr, 10, 8); err == nil {
+		if hasCode {
+			if _, err := strconv.ParseUint(codeStr, 10, 8); err != nil {
+				return fmt.Errorf("invalid ICMP code %s", codeStr)
+			}
+		}
+		return nil
+	}
+	// Symbolic type names are resolved by the iptables command itself.
+	if hasCode {
+		return fmt.Errorf("ICMP code combined with a symbolic type %s", typeStr)
+	}
+	for _, c := range value {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isLetter && c != '-' {
+			return fmt.Errorf("invalid ICMP type %s", value)
+		}
+	}
+	return nil
+}
+
 // Return errors without LogAndErrPrefix - it is prepended inside callers.
 func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 	niType types.NetworkInstanceType, vif vifInfo,
 	forIPv6 bool) (parsedRule userACLRule, skip bool, err error) {
+	// Skip rules explicitly restricted to the other IP address family.
+	switch aclRule.Family {
+	case types.AceFamilyIPv4:
+		if forIPv6 {
+			return parsedRule, true, nil
+		}
+	case types.AceFamilyIPv6:
+		if !forIPv6 {
+			return parsedRule, true, nil
+		}
+	}
 	if len(aclRule.Actions) > 1 {
 		return parsedRule, true, fmt.Errorf(
 			"ACL rule (%v) with multiple actions is not supported", aclRule)
@@ -292,11 +343,14 @@ func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 	}
 	// Parse match arguments.
 	var (
-		ipWithPrefix *net.IPNet
-		ipsetName    string
-		protocol     string
-		lport        string
-		fport        string
+		ipWithPrefix    *net.IPNet
+		srcIPWithPrefix *net.IPNet
+		ipsetName       string
+		protocol        string
+		lport           string
+		fport           string
+		icmpTypeMatch   string // "icmp-type" or "icmp6-type"
+		icmpType        string
 	)
 	for _, match := range aclRule.Matches {
 		switch match.Type {
@@ -309,6 +363,16 @@ func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 				err = fmt.Errorf("ACL rule with invalid IP/subnet (%+v) ", aclRule)
 				return parsedRule, true, err
 			}
+		case "srcip":
+			if ip := net.ParseIP(match.Value); ip != nil {
+				srcIPWithPrefix = netutils.HostSubnet(ip)
+			} else if _, subnet, err := net.ParseCIDR(match.Value); err == nil {
+				srcIPWithPrefix = subnet
+			} else {
+				err = fmt.Errorf("ACL rule with invalid source IP/subnet (%+v) ",
+					aclRule)
+				return parsedRule, true, err
+			}
 		case "protocol":
 			protocol = match.Value
 		case "fport":
@@ -317,6 +381,19 @@ func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 		case "lport":
 			// Need a protocol as well. Checked below.
 			lport = match.Value
+		case "icmp-type", "icmp6-type":
+			if (match.Type == "icmp6-type") != forIPv6 {
+				// Skip this rule, it is for the other IP version.
+				return parsedRule, true, nil
+			}
+			if err := validateICMPTypeValue(match.Value); err != nil {
+				err = fmt.Errorf("ACL rule (%+v) with invalid %s value: %w",
+					aclRule, match.Type, err)
+				return parsedRule, true, err
+			}
+			// Need a matching ICMP protocol as well. Checked below.
+			icmpTypeMatch = match.Type
+			icmpType = match.Value
 		case "host":
 			// Check if this should really be an "ip" ACL
 			if ip := net.ParseIP(match.Value); ip != nil {
@@ -374,10 +451,32 @@ func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 			aclRule, fport)
 		return parsedRule, true, err
 	}
+	if icmpType != "" {
+		switch {
+		case protocol == "":
+			err := fmt.Errorf("ACL rule (%+v) with %s %s and no protocol match",
+				aclRule, icmpTypeMatch, icmpType)
+			return parsedRule, true, err
+		case icmpTypeMatch == "icmp-type" && protocol != "icmp":
+			err := fmt.Errorf("ACL rule (%+v) combines icmp-type with "+
+				"protocol %s (expected icmp)", aclRule, protocol)
+			return parsedRule, true, err
+		case icmpTypeMatch == "icmp6-type" &&
+			protocol != "icmpv6" && protocol != "ipv6-icmp":
+			err := fmt.Errorf("ACL rule (%+v) combines icmp6-type with "+
+				"protocol %s (expected icmpv6)", aclRule, protocol)
+			return parsedRule, true, err
+		}
+	}
 	if parsedRule.isPortMap && lport == "" {
 		err := fmt.Errorf("portmap ACL rule (%+v) without lport", aclRule)
 		return parsedRule, true, err
 	}
+	if srcIPWithPrefix != nil && !parsedRule.isPortMap {
+		err := fmt.Errorf("ACL rule (%+v) with srcip match and no portmap action",
+			aclRule)
+		return parsedRule, true, err
+	}
 	if ipWithPrefix != nil {
 		ipv6 := ipWithPrefix.IP.To4() == nil
 		if ipv6 != forIPv6 {
@@ -394,6 +493,23 @@ func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 		parsedRule.postDNATIngressMatch = append(parsedRule.postDNATIngressMatch,
 			ingressMatch...)
 	}
+	if srcIPWithPrefix != nil {
+		ipv6 := srcIPWithPrefix.IP.To4() == nil
+		if ipv6 != forIPv6 {
+			// Skip this rule, it is for the other IP version.
+			return parsedRule, true, nil
+		}
+		// srcip limits which remote sources are allowed to initiate connections
+		// via the port mapping. The source address is not changed by DNAT, hence
+		// the same match is used before and after the DNAT stage.
+		parsedRule.egressMatch = append(parsedRule.egressMatch,
+			"-d", srcIPWithPrefix.String())
+		ingressMatch := []string{"-s", srcIPWithPrefix.String()}
+		parsedRule.preDNATIngressMatch = append(parsedRule.preDNATIngressMatch,
+			ingressMatch...)
+		parsedRule.postDNATIngressMatch = append(parsedRule.postDNATIngressMatch,
+			ingressMatch...)
+	}
 	// Make sure we put the protocol before any port numbers
 	if protocol != "" {
 		match := []string{"-p", protocol}
@@ -401,6 +517,17 @@ func parseUserACLRule(log *base.LogObject, aclRule types.ACE,
 		parsedRule.preDNATIngressMatch = append(parsedRule.preDNATIngressMatch, match...)
 		parsedRule.postDNATIngressMatch = append(parsedRule.postDNATIngressMatch, match...)
 	}
+	if icmpType != "" {
+		// The match is bidirectional, just like port matches - conntrack takes
+		// care of letting the corresponding replies through.
+		match := []string{"--icmp-type", icmpType}
+		if forIPv6 {
+			match = []string{"--icmpv6-type", icmpType}
+		}
+		parsedRule.egressMatch = append(parsedRule.egressMatch, match...)
+		parsedRule.preDNATIngressMatch = append(parsedRule.preDNATIngressMatch, match...)
+		parsedRule.postDNATIngressMatch = append(parsedRule.postDNATIngressMatch, match...)
+	}
 	if fport != "" {
 		parsedRule.egressMatch = append(parsedRule.egressMatch, "--dport", fport)
 		ingressMatch := []string{"--sport", fport}
@@ -503,7 +630,20 @@ func (r *LinuxNIReconciler) getIntendedAppConnACLs(niID uuid.UUID,
 	}
 	intendedAppConnACLs := dg.New(graphArgs)
 	for _, ipv6 := range []bool{true, false} {
-		// TODO: use IPv4RulesSG and IPv6RulesSG ?
+		// Keep IPv4 and IPv6 rules in separate subgraphs so that a change
+		// limited to one family (e.g. a per-family ACE) is reconciled without
+		// touching the rules of the other family.
+		sgName := IPv4RulesSG
+		sgDescription := "iptables rules implementing IPv4 ACLs of the app VIF"
+		if ipv6 {
+			sgName = IPv6RulesSG
+			sgDescription = "ip6tables rules implementing IPv6 ACLs of the app VIF"
+		}
+		rulesSG := dg.New(dg.InitArgs{
+			Name:        sgName,
+			Description: sgDescription,
+		})
+		intendedAppConnACLs.PutSubGraph(rulesSG)
 		if ni.config.Type == types.NetworkInstanceTypeLocal {
 			if ni.config.IsIPv6() != ipv6 {
 				continue
@@ -513,16 +653,16 @@ func (r *LinuxNIReconciler) getIntendedAppConnACLs(niID uuid.UUID,
 			return (ipNet.IP.To4() == nil) == ipv6
 		})
 		for _, item := range r.getIntendedAppConnRawIptables(vif, ul, ipv6) {
-			intendedAppConnACLs.PutItem(item, nil)
+			rulesSG.PutItem(item, nil)
 		}
 		for _, item := range r.getIntendedAppConnFilterIptables(vif, ul, ipv6) {
-			intendedAppConnACLs.PutItem(item, nil)
+			rulesSG.PutItem(item, nil)
 		}
 		for _, item := range r.getIntendedAppConnNATIptables(vif, ul, ipv6, uplinkIPvXs) {
-			intendedAppConnACLs.PutItem(item, nil)
+			rulesSG.PutItem(item, nil)
 		}
 		for _, item := range r.getIntendedAppConnMangleIptables(vif, ul, ipv6, uplinkIPvXs) {
-			intendedAppConnACLs.PutItem(item, nil)
+			rulesSG.PutItem(item, nil)
 		}
 	}
 	return intendedAppConnACLs
@@ -557,6 +697,73 @@ func (r *LinuxNIReconciler) getIntendedAppConnRawIptables(vif vifInfo,
 	// Put ACL rules into the VIF-specific chain.
 	// We have already applied physdev filter and get only traffic coming from the VIF.
 	var aclRules []iptables.Rule
+	// Drop traffic destined to blocklisted IPs first, taking precedence
+	// over the essential-protocol rules and user-configured ACLs.
+	if ni.config.IPBlocklist.Enable {
+		aclRules = append(aclRules, iptables.Rule{
+			RuleLabel: "Drop traffic to blocklisted IPs",
+			MatchOpts: []string{"-m", "set", "--match-set",
+				blocklistIpsetName(ni, ipv6), "dst"},
+			Target: "DROP",
+			Description: "Drop traffic destined to addresses from the IP " +
+				"blocklist to which the NI is subscribed",
+		})
+	}
+	// Enforce the egress proxy for HTTPS traffic, taking precedence over
+	// user-configured ACLs (enterprise proxy mandates must not be bypassable
+	// by an ACCEPT ACL rule). HTTPS cannot be transparently redirected
+	// without breaking TLS (unlike plain HTTP - see getIntendedEgressProxyCfg),
+	// so direct access to port 443 is blocked instead, except when destined
+	// to the proxy itself or staying inside the NI.
+	if proxy := ni.config.EgressProxy; proxy != nil && proxy.EnforceHTTPS &&
+		proxy.ProxyIP != nil && (proxy.ProxyIP.To4() == nil) == ipv6 &&
+		ni.config.Type == types.NetworkInstanceTypeLocal {
+		exemptSubnets := []*net.IPNet{r.getNISubnet(ni)}
+		for _, secSubnet := range r.getNISecondarySubnets(ni) {
+			exemptSubnets = append(exemptSubnets, &net.IPNet{
+				IP:   secSubnet.Subnet.IP,
+				Mask: secSubnet.Subnet.Mask,
+			})
+		}
+		for _, subnet := range exemptSubnets {
+			if subnet == nil || (subnet.IP.To4() == nil) != ipv6 {
+				continue
+			}
+			aclRules = append(aclRules, iptables.Rule{
+				RuleLabel: fmt.Sprintf("Allow intra-NI HTTPS (%s)", subnet),
+				MatchOpts: []string{"-d", subnet.String(),
+					"-p", "tcp", "--dport", "443"},
+				Target: "ACCEPT",
+				Description: "Allow HTTPS traffic which stays inside " +
+					"the network instance",
+			})
+		}
+		aclRules = append(aclRules, iptables.Rule{
+			RuleLabel: "Block HTTPS bypassing the egress proxy",
+			MatchOpts: []string{"-p", "tcp", "--dport", "443",
+				"!", "-d", proxy.ProxyIP.String()},
+			Target: "DROP",
+			Description: "Drop direct HTTPS traffic not destined to the " +
+				"egress proxy mandated for the NI",
+		})
+	}
+	// 0. Rate-limit DNS queries sent to the DNS server of the NI.
+	// This must precede the essential-protocol rules which accept DNS traffic.
+	if ni.config.DNSQueryRateLimit != 0 && bridgeIP != nil &&
+		ni.config.Type == types.NetworkInstanceTypeLocal {
+		limit := strconv.Itoa(int(ni.config.DNSQueryRateLimit))
+		aclRules = append(aclRules, iptables.Rule{
+			RuleLabel: "Rate-limit DNS queries",
+			MatchOpts: []string{"-d", bridgeIP.String(),
+				"-p", "udp", "--dport", "53",
+				"-m", "hashlimit", "--hashlimit-above", limit + "/sec",
+				"--hashlimit-burst", limit, "--hashlimit-mode", "srcip",
+				"--hashlimit-name", "dns-" + vif.hostIfName},
+			Target: "DROP",
+			Description: "Drop DNS queries sent above the configured " +
+				"per-VIF rate limit to protect the DNS server of the NI",
+		})
+	}
 	// 1. Essential protocols allowed implicitly.
 	var essentialProtos []essentialProto
 	if ipv6 {
@@ -579,11 +786,32 @@ func (r *LinuxNIReconciler) getIntendedAppConnRawIptables(vif vifInfo,
 			Target:    "ACCEPT",
 		})
 	}
-	// 3. User-configured ACL rules
+	// 3. Redirect new TLS connections to the SNI-inspecting filter.
+	if len(ul.SNIAllowlist) > 0 {
+		targetOpts := []string{"--queue-num",
+			strconv.Itoa(int(snifilter.DefaultQueueNum))}
+		if !ni.config.SNIFilterFallbackDrop {
+			// Let TLS traffic through if the SNI filter is not bound
+			// to the queue.
+			targetOpts = append(targetOpts, "--queue-bypass")
+		}
+		aclRules = append(aclRules, iptables.Rule{
+			RuleLabel:  "SNI allowlist",
+			MatchOpts:  []string{"-p", "tcp", "--dport", "443"},
+			Target:     "NFQUEUE",
+			TargetOpts: targetOpts,
+			Description: "Pass egress TLS connections to the SNI-inspecting " +
+				"filter which drops those not matching the configured " +
+				"domain allowlist",
+		})
+	}
+	// 4. User-configured ACL rules
 	for _, aclRule := range ul.ACLs {
 		parsedRule, skip, err := parseUserACLRule(r.log, aclRule, ni.config.Type, vif, ipv6)
 		if err != nil {
-			r.log.Errorf("%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
+			r.rlLog.Errorf(
+				netlog.Tags{Category: aclParseLogCategory, NI: vif.NI, App: vif.App},
+				"%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
 			continue
 		}
 		if skip {
@@ -622,7 +850,7 @@ func (r *LinuxNIReconciler) getIntendedAppConnRawIptables(vif vifInfo,
 			aclRules = append(aclRules, iptablesRule2)
 		}
 	}
-	// 4. Packet counting rule for the default drop.
+	// 5. Packet counting rule for the default drop.
 	aclRules = append(aclRules, iptables.Rule{
 		RuleLabel: "Count packets matched by the Default DROP",
 		Target:    dropCounterChain,
@@ -650,6 +878,37 @@ func (r *LinuxNIReconciler) getIntendedAppConnRawIptables(vif vifInfo,
 	return items
 }
 
+// isolatedPeerVIFs returns VIFs of other applications connected to the same
+// network instance which are assigned a different, non-empty isolation group
+// and therefore must not be able to exchange traffic with the given VIF.
+// A VIF with an empty isolation group is not isolated from anyone.
+func (r *LinuxNIReconciler) isolatedPeerVIFs(
+	vif vifInfo, isolationGroup string) (peers []vifInfo) {
+	if isolationGroup == "" {
+		return nil
+	}
+	for _, app := range r.apps {
+		if app.deleted {
+			continue
+		}
+		// app.vifs maps 1:1 to app.config.AppNetAdapterList.
+		for i, peerVif := range app.vifs {
+			if peerVif.NI != vif.NI {
+				continue
+			}
+			if peerVif.App == vif.App && peerVif.NetAdapterName == vif.NetAdapterName {
+				// This is the VIF itself, not a peer.
+				continue
+			}
+			peerGroup := app.config.AppNetAdapterList[i].IsolationGroup
+			if peerGroup != "" && peerGroup != isolationGroup {
+				peers = append(peers, peerVif)
+			}
+		}
+	}
+	return peers
+}
+
 // Table FILTER, chain FORWARD is used to:
 //   - Count packets of to-be-dropped traffic *coming into* local NIs
 //     (dropped during the routing phase)
@@ -704,6 +963,34 @@ func (r *LinuxNIReconciler) getIntendedAppConnFilterIptables(vif vifInfo,
 	// We have already applied physdev filter or destination IP match and get only traffic
 	// going into the VIF.
 	var aclRules []iptables.Rule
+	// Drop traffic coming from blocklisted IPs first, taking precedence
+	// over the essential-protocol rules and user-configured ACLs.
+	if ni.config.IPBlocklist.Enable {
+		aclRules = append(aclRules, iptables.Rule{
+			RuleLabel: "Drop traffic from blocklisted IPs",
+			MatchOpts: []string{"-m", "set", "--match-set",
+				blocklistIpsetName(ni, ipv6), "src"},
+			Target: "DROP",
+			Description: "Drop traffic coming from addresses from the IP " +
+				"blocklist to which the NI is subscribed",
+		})
+	}
+	// Drop traffic bridged from VIFs of applications assigned to a different
+	// isolation group. Enforced before the essential-protocol rules and
+	// user-configured ACLs, which are not allowed to override the isolation.
+	if ni.config.Type == types.NetworkInstanceTypeLocal {
+		for _, peerVif := range r.isolatedPeerVIFs(vif, ul.IsolationGroup) {
+			aclRules = append(aclRules, iptables.Rule{
+				RuleLabel: fmt.Sprintf("Isolate from VIF %s", peerVif.hostIfName),
+				MatchOpts: []string{"-m", "physdev",
+					"--physdev-in", matchVifIfName(peerVif)},
+				Target: "DROP",
+				Description: fmt.Sprintf("Drop traffic bridged from VIF %s "+
+					"of an application assigned to a different isolation group",
+					peerVif.hostIfName),
+			})
+		}
+	}
 	// 1. Essential protocols allowed implicitly.
 	var essentialProtos []essentialProto
 	if ipv6 {
@@ -725,7 +1012,9 @@ func (r *LinuxNIReconciler) getIntendedAppConnFilterIptables(vif vifInfo,
 	for _, aclRule := range ul.ACLs {
 		parsedRule, skip, err := parseUserACLRule(r.log, aclRule, ni.config.Type, vif, ipv6)
 		if err != nil {
-			r.log.Errorf("%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
+			r.rlLog.Errorf(
+				netlog.Tags{Category: aclParseLogCategory, NI: vif.NI, App: vif.App},
+				"%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
 			continue
 		}
 		if skip {
@@ -837,31 +1126,46 @@ func (r *LinuxNIReconciler) getIntendedAppConnNATIptables(vif vifInfo,
 	// Add DNAT rules for port-map ACLs.
 	portMapRules := collectPortMapRules(ul.ACLs)
 	for _, portMapRule := range portMapRules {
+		if portMapRule.sourceCIDR != nil &&
+			(portMapRule.sourceCIDR.IP.To4() == nil) != ipv6 {
+			// Source allowlist is for the other IP version.
+			continue
+		}
+		var srcMatch []string
+		if portMapRule.sourceCIDR != nil {
+			// Restrict the port mapping to the allowlisted remote sources.
+			srcMatch = []string{"-s", portMapRule.sourceCIDR.String()}
+		}
+		dnatIP := portMapDNATAddr(ni, vif, portMapRule.targetPort, ipv6)
 		for _, uplinkIP := range uplinkIPs {
-			target := fmt.Sprintf("%s:%d", vif.GuestIP, portMapRule.targetPort)
+			target := fmt.Sprintf("%s:%d", dnatIP, portMapRule.targetPort)
+			matchOpts := []string{"-i", ni.bridge.Uplink.IfName}
+			matchOpts = append(matchOpts, srcMatch...)
+			matchOpts = append(matchOpts, "-p", portMapRule.protocol,
+				"-d", uplinkIP.IP.String(), "--dport", portMapRule.externalPort)
 			items = append(items, iptables.Rule{
 				RuleLabel: fmt.Sprintf("User-configured PORTMAP ACL rule %d "+
 					"for uplink IP %s from outside", portMapRule.ruleID,
 					uplinkIP.IP.String()),
-				Table:     "nat",
-				ChainName: vifChain("PREROUTING", vif),
-				ForIPv6:   ipv6,
-				MatchOpts: []string{"-i", ni.bridge.Uplink.IfName,
-					"-p", portMapRule.protocol, "-d", uplinkIP.IP.String(),
-					"--dport", portMapRule.externalPort},
+				Table:      "nat",
+				ChainName:  vifChain("PREROUTING", vif),
+				ForIPv6:    ipv6,
+				MatchOpts:  matchOpts,
 				Target:     "DNAT",
 				TargetOpts: []string{"--to-destination", target},
 			})
+			matchOpts = []string{"-i", ni.brIfName}
+			matchOpts = append(matchOpts, srcMatch...)
+			matchOpts = append(matchOpts, "-p", portMapRule.protocol,
+				"-d", uplinkIP.IP.String(), "--dport", portMapRule.externalPort)
 			items = append(items, iptables.Rule{
 				RuleLabel: fmt.Sprintf("User-configured PORTMAP ACL rule %d "+
 					"for uplink IP %s from inside", portMapRule.ruleID,
 					uplinkIP.IP.String()),
-				Table:     "nat",
-				ChainName: vifChain("PREROUTING", vif),
-				ForIPv6:   ipv6,
-				MatchOpts: []string{"-i", ni.brIfName,
-					"-p", portMapRule.protocol, "-d", uplinkIP.IP.String(),
-					"--dport", portMapRule.externalPort},
+				Table:      "nat",
+				ChainName:  vifChain("PREROUTING", vif),
+				ForIPv6:    ipv6,
+				MatchOpts:  matchOpts,
 				Target:     "DNAT",
 				TargetOpts: []string{"--to-destination", target},
 			})
@@ -869,6 +1173,7 @@ func (r *LinuxNIReconciler) getIntendedAppConnNATIptables(vif vifInfo,
 	}
 	// Add SNAT rules for port-map ACLs.
 	for _, portMapRule := range portMapRules {
+		dnatIP := portMapDNATAddr(ni, vif, portMapRule.targetPort, ipv6)
 		items = append(items, iptables.Rule{
 			RuleLabel: fmt.Sprintf("User-configured PORTMAP ACL rule %d",
 				portMapRule.ruleID),
@@ -876,7 +1181,7 @@ func (r *LinuxNIReconciler) getIntendedAppConnNATIptables(vif vifInfo,
 			ChainName: vifChain("POSTROUTING", vif),
 			ForIPv6:   ipv6,
 			MatchOpts: []string{"-o", ni.brIfName,
-				"-p", portMapRule.protocol, "-d", vif.GuestIP.String(),
+				"-p", portMapRule.protocol, "-d", dnatIP.String(),
 				"--dport", strconv.Itoa(portMapRule.targetPort)},
 			Target:     "SNAT",
 			TargetOpts: []string{"--to", ni.bridge.IPAddress.IP.String()},
@@ -978,7 +1283,9 @@ func (r *LinuxNIReconciler) getIntendedAppConnMangleIptables(vif vifInfo,
 	for _, aclRule := range ul.ACLs {
 		parsedRule, skip, err := parseUserACLRule(r.log, aclRule, ni.config.Type, vif, ipv6)
 		if err != nil {
-			r.log.Errorf("%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
+			r.rlLog.Errorf(
+				netlog.Tags{Category: aclParseLogCategory, NI: vif.NI, App: vif.App},
+				"%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
 			continue
 		}
 		if skip {
@@ -1081,7 +1388,9 @@ mangleEgress:
 	for _, aclRule := range ul.ACLs {
 		parsedRule, skip, err := parseUserACLRule(r.log, aclRule, ni.config.Type, vif, ipv6)
 		if err != nil {
-			r.log.Errorf("%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
+			r.rlLog.Errorf(
+				netlog.Tags{Category: aclParseLogCategory, NI: vif.NI, App: vif.App},
+				"%s: parseUserACLRule failed: %v", LogAndErrPrefix, err)
 			continue
 		}
 		if skip {
@@ -1159,6 +1468,13 @@ func collectPortMapRules(acls []types.ACE) (portMapRules []portMap) {
 				portMapRule.protocol = match.Value
 			case "lport":
 				portMapRule.externalPort = match.Value
+			case "srcip":
+				if ip := net.ParseIP(match.Value); ip != nil {
+					portMapRule.sourceCIDR = netutils.HostSubnet(ip)
+				} else if _, subnet, err := net.ParseCIDR(match.Value); err == nil {
+					portMapRule.sourceCIDR = subnet
+				}
+				// Error already reported by parseUserACLRule.
 			}
 		}
 		if portMapRule.protocol == "" || portMapRule.externalPort == "" {
@@ -1180,6 +1496,25 @@ func collectPortMapRules(acls []types.ACE) (portMapRules []portMap) {
 	return
 }
 
+// portMapDNATAddr returns the IP address to which a port-map ACL rule
+// of the given VIF should DNAT. Normally this is the VIF IP address, but
+// when the app is a candidate of a virtual IP backing the target port
+// (see types.VirtualIPConfig), the virtual IP is used instead - external
+// clients then transparently follow a failover between the candidate
+// applications.
+func portMapDNATAddr(ni *niInfo, vif vifInfo, targetPort int, ipv6 bool) net.IP {
+	for _, vipConfig := range ni.config.VirtualIPs {
+		if !vipConfig.HasCandidate(vif.App) || !vipConfig.BacksPort(targetPort) {
+			continue
+		}
+		if (vipConfig.IP.To4() == nil) != ipv6 {
+			continue
+		}
+		return vipConfig.IP
+	}
+	return vif.GuestIP
+}
+
 func markToString(mark uint32) string {
 	return strconv.FormatUint(uint64(mark), 10)
 }
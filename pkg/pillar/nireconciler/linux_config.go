@@ -8,6 +8,8 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net"
+	"os"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -185,6 +187,10 @@ const (
 	GlobalSG = "Global"
 	// UplinksSG : name of the sub-graph with (external) uplink interfaces.
 	UplinksSG = "Uplinks"
+	// NIConnsSG : name of the sub-graph with (external) items representing
+	// working external connectivity of network instances with gated DHCP
+	// (see types.NetworkInstanceConfig.GateDHCPOnConnectivity).
+	NIConnsSG = "NIConns"
 	// IPSetsSG : subgraph with ipsets combined from all NIs.
 	IPSetsSG = "IPSets"
 	// BlackHoleSG : subgraph with config items creating a "black-hole" where traffic marked
@@ -244,10 +250,32 @@ const (
 	bridgeIfNamePrefix = "bn"
 )
 
+const (
+	// niDefRouteBaseMetric : explicit metric initially assigned to the default
+	// route copied from the main routing table into the per-NI routing table.
+	// With every make-before-break switch of the NI uplink, the metric assigned
+	// to the default route of the newly selected uplink is decremented by one,
+	// so that for the short duration of the switch, when default routes of both
+	// uplinks are present, the new uplink is preferred.
+	niDefRouteBaseMetric = 1000
+	// niDefRouteLowestMetric : once the metric of the NI default route drops
+	// to this value, the next uplink switch wraps it back to the base metric.
+	niDefRouteLowestMetric = 500
+)
+
 const (
 	metadataSrvIP = "169.254.169.254"
 )
 
+const (
+	// nat64TunIfNamePrefix : prefix used for the TUN interface of the tayga
+	// NAT64 translator (combined with the bridge interface name of the NI).
+	nat64TunIfNamePrefix = "nat64-"
+	// nat64WellKnownPrefix : the well-known NAT64 prefix (RFC 6052), used
+	// when no explicit prefix is configured for the NI.
+	nat64WellKnownPrefix = "64:ff9b::/96"
+)
+
 // NIToSGName returns the name of the subgraph encapsulating the entire configuration
 // of the given network instance.
 // There is one such subgraph for every network instance.
@@ -287,6 +315,15 @@ func eidsIpsetName(vif vifInfo, ipv6 bool) string {
 	return ipsetNamePrefixV4 + "eids." + vif.hostIfName
 }
 
+// Ipset with blocklisted IP addresses and subnets to which the network
+// instance is subscribed (see types.IPBlocklistConfig).
+func blocklistIpsetName(ni *niInfo, ipv6 bool) string {
+	if ipv6 {
+		return ipsetNamePrefixV6 + "blk." + ni.brIfName
+	}
+	return ipsetNamePrefixV4 + "blk." + ni.brIfName
+}
+
 func (r *LinuxNIReconciler) initialDepGraph() dg.Graph {
 	graphArgs := dg.InitArgs{
 		Name:        GraphName,
@@ -302,6 +339,7 @@ func (r *LinuxNIReconciler) getIntendedGlobalState() dg.Graph {
 	}
 	intendedCfg := dg.New(graphArgs)
 	intendedCfg.PutSubGraph(r.getIntendedUplinks())
+	intendedCfg.PutSubGraph(r.getIntendedNIConns())
 	intendedCfg.PutSubGraph(r.getIntendedGlobalIPSets())
 	intendedCfg.PutSubGraph(r.getIntendedBlackholeCfg())
 	intendedCfg.PutSubGraph(r.getIntendedACLRootChains())
@@ -318,6 +356,17 @@ func (r *LinuxNIReconciler) getIntendedUplinks() dg.Graph {
 		if ni.deleted {
 			continue
 		}
+		// Extra ports of a switch NI are enslaved under the NI bridge
+		// by the reconciler itself (see BridgePort with the PhysIfName
+		// variant).
+		for _, port := range ni.bridge.ExtraPorts {
+			intendedUplinks.PutItem(generic.Uplink{
+				IfName:       port.IfName,
+				LogicalLabel: port.LogicalLabel,
+				MasterIfName: ni.brIfName,
+				AdminUp:      true,
+			}, nil)
+		}
 		if ni.bridge.Uplink.IfName == "" {
 			// Air-gapped NI, no uplink.
 			continue
@@ -338,10 +387,39 @@ func (r *LinuxNIReconciler) getIntendedUplinks() dg.Graph {
 			MasterIfName: masterIfName,
 			AdminUp:      true,
 		}, nil)
+		if ni.mbbPrevUplink.IfName != "" &&
+			ni.config.Type == types.NetworkInstanceTypeLocal {
+			// Keep the previous uplink in place until the make-before-break
+			// switch of the NI routes is completed.
+			intendedUplinks.PutItem(generic.Uplink{
+				IfName:       ni.mbbPrevUplink.IfName,
+				LogicalLabel: ni.mbbPrevUplink.LogicalLabel,
+				AdminUp:      true,
+			}, nil)
+		}
 	}
 	return intendedUplinks
 }
 
+func (r *LinuxNIReconciler) getIntendedNIConns() dg.Graph {
+	graphArgs := dg.InitArgs{
+		Name:        NIConnsSG,
+		Description: "Connectivity status of NIs with gated DHCP",
+	}
+	intendedNIConns := dg.New(graphArgs)
+	for _, ni := range r.nis {
+		if ni.deleted || !ni.config.GateDHCPOnConnectivity {
+			continue
+		}
+		if ni.bridge.Uplink.IfName == "" {
+			// Air-gapped NI is never gated on connectivity.
+			continue
+		}
+		intendedNIConns.PutItem(generic.NIConn{NI: ni.config.UUID}, nil)
+	}
+	return intendedNIConns
+}
+
 func (r *LinuxNIReconciler) getIntendedGlobalIPSets() dg.Graph {
 	graphArgs := dg.InitArgs{
 		Name:        IPSetsSG,
@@ -392,9 +470,80 @@ func (r *LinuxNIReconciler) getIntendedGlobalIPSets() dg.Graph {
 			AddrFamily: netlink.FAMILY_V6,
 		}, nil)
 	}
+	// Materialize IP blocklists of the subscribed NIs as ipsets.
+	// When the blocklist changes, the ipset content is refreshed atomically
+	// (see pillar/ipsets).
+	for _, ni := range r.nis {
+		if ni.deleted || !ni.config.IPBlocklist.Enable {
+			continue
+		}
+		entriesV4, entriesV6 := r.loadIPBlocklist(ni.config.IPBlocklist)
+		intendedIPSets.PutItem(linux.IPSet{
+			SetName:    blocklistIpsetName(ni, false),
+			TypeName:   "hash:net",
+			Entries:    entriesV4,
+			AddrFamily: netlink.FAMILY_V4,
+		}, nil)
+		intendedIPSets.PutItem(linux.IPSet{
+			SetName:    blocklistIpsetName(ni, true),
+			TypeName:   "hash:net",
+			Entries:    entriesV6,
+			AddrFamily: netlink.FAMILY_V6,
+		}, nil)
+	}
 	return intendedIPSets
 }
 
+// loadIPBlocklist combines blocklist entries received directly from
+// the controller with those loaded from the referenced file, validates
+// them and splits the result by the IP version.
+func (r *LinuxNIReconciler) loadIPBlocklist(
+	config types.IPBlocklistConfig) (entriesV4, entriesV6 []string) {
+	entries := config.Entries
+	if config.Filename != "" {
+		content, err := os.ReadFile(config.Filename)
+		if err != nil {
+			r.log.Errorf("%s: failed to read IP blocklist file %s: %v",
+				LogAndErrPrefix, config.Filename, err)
+		} else {
+			for _, line := range strings.Split(string(content), "\n") {
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				entries = append(entries, line)
+			}
+		}
+	}
+	for _, entry := range entries {
+		var ip net.IP
+		if strings.Contains(entry, "/") {
+			var err error
+			ip, _, err = net.ParseCIDR(entry)
+			if err != nil {
+				r.log.Errorf("%s: skipping invalid IP blocklist entry %s: %v",
+					LogAndErrPrefix, entry, err)
+				continue
+			}
+		} else {
+			ip = net.ParseIP(entry)
+			if ip == nil {
+				r.log.Errorf("%s: skipping invalid IP blocklist entry %s",
+					LogAndErrPrefix, entry)
+				continue
+			}
+		}
+		if ip.To4() != nil {
+			entriesV4 = append(entriesV4, entry)
+		} else {
+			entriesV6 = append(entriesV6, entry)
+		}
+	}
+	entriesV4 = generics.FilterDuplicates(entriesV4)
+	entriesV6 = generics.FilterDuplicates(entriesV6)
+	return entriesV4, entriesV6
+}
+
 func (r *LinuxNIReconciler) getIntendedBlackholeCfg() dg.Graph {
 	graphArgs := dg.InitArgs{
 		Name:        BlackHoleSG,
@@ -511,6 +660,16 @@ func (r *LinuxNIReconciler) getIntendedNIL2Cfg(niID uuid.UUID) dg.Graph {
 	if bridgeIP != nil {
 		bridgeIPs = append(bridgeIPs, bridgeIP)
 	}
+	// The bridge gets a gateway address inside every secondary subnet
+	// of the NI (see types.NISecondarySubnet).
+	for _, secSubnet := range r.getNISecondarySubnets(ni) {
+		if gwIP := secSubnet.GatewayIP(); gwIP != nil {
+			bridgeIPs = append(bridgeIPs, &net.IPNet{
+				IP:   gwIP,
+				Mask: secSubnet.Subnet.Mask,
+			})
+		}
+	}
 	intendedL2Cfg.PutItem(linux.Bridge{
 		IfName:       ni.brIfName,
 		CreatedByNIM: r.niBridgeIsCreatedByNIM(ni),
@@ -518,6 +677,21 @@ func (r *LinuxNIReconciler) getIntendedNIL2Cfg(niID uuid.UUID) dg.Graph {
 		IPAddresses:  bridgeIPs,
 		MTU:          ni.bridge.MTU,
 	}, nil)
+	// Explicitly apply the bridge parameters (even when left at defaults)
+	// so that they persist through bridge recreation and IGMP snooping keeps
+	// multicast-heavy traffic (e.g. industrial protocols) forwarded only
+	// to VIFs with members of the target multicast group instead of being
+	// flooded to all apps.
+	// With multiple physical ports bridged under the NI (EVE acting as a small
+	// managed switch), STP is forcibly enabled to mitigate L2 loops through
+	// externally attached switches.
+	enableSTP := ni.config.BridgeParams.EnableSTP || len(ni.bridge.ExtraPorts) > 0
+	intendedL2Cfg.PutItem(linux.BridgeParams{
+		BridgeIfName:      ni.brIfName,
+		EnableSTP:         enableSTP,
+		AgeingTime:        ni.config.BridgeParams.AgeingTime,
+		MulticastSnooping: !ni.config.BridgeParams.DisableMulticastSnooping,
+	}, nil)
 	// For Switch NI also add the intended VLAN configuration.
 	// Here we put VLAN config only for the bridge itself and the uplink interface,
 	// downlinks have their config in AppConn-* subgraphs.
@@ -528,12 +702,24 @@ func (r *LinuxNIReconciler) getIntendedNIL2Cfg(niID uuid.UUID) dg.Graph {
 		BridgeIfName:        ni.brIfName,
 		EnableVLANFiltering: true,
 	}, nil)
-	if ni.bridge.Uplink.IfName == "" {
-		// Air-gapped, no uplink port to configure as trunk.
-		return intendedL2Cfg
-	}
-	// Find out which VLAN IDs should be allowed for the uplink trunk port.
+	// Find out which VLAN IDs should be allowed for the trunk ports
+	// (the uplink and every extra member port left in the trunk mode).
 	var trunkPort linux.TrunkPort
+	for _, port := range ni.bridge.ExtraPorts {
+		// A trunk must also carry VLANs used by the access-mode member ports.
+		if vid := port.AccessVlanID; vid > 1 {
+			var duplicate bool
+			for _, prevVID := range trunkPort.VIDs {
+				if prevVID == vid {
+					duplicate = true
+					break
+				}
+			}
+			if !duplicate {
+				trunkPort.VIDs = append(trunkPort.VIDs, vid)
+			}
+		}
+	}
 	for _, app := range r.apps {
 		if app.deleted {
 			continue
@@ -566,6 +752,33 @@ func (r *LinuxNIReconciler) getIntendedNIL2Cfg(niID uuid.UUID) dg.Graph {
 			break
 		}
 	}
+	// Additional member ports are enslaved under the NI bridge directly
+	// by the reconciler (NIM only bridges the uplink).
+	for _, port := range ni.bridge.ExtraPorts {
+		intendedL2Cfg.PutItem(linux.BridgePort{
+			BridgeIfName: ni.brIfName,
+			Variant: linux.BridgePortVariant{
+				PhysIfName: port.IfName,
+			},
+			MTU: ni.bridge.MTU,
+		}, nil)
+		var vlanConfig linux.VLANConfig
+		if port.AccessVlanID > 1 {
+			vlanConfig.AccessPort = &linux.AccessPort{VID: port.AccessVlanID}
+		} else {
+			portTrunk := trunkPort
+			vlanConfig.TrunkPort = &portTrunk
+		}
+		intendedL2Cfg.PutItem(linux.VLANPort{
+			BridgeIfName: ni.brIfName,
+			PortIfName:   port.IfName,
+			VLANConfig:   vlanConfig,
+		}, nil)
+	}
+	if ni.bridge.Uplink.IfName == "" {
+		// Air-gapped, no uplink port to configure as trunk.
+		return intendedL2Cfg
+	}
 	intendedL2Cfg.PutItem(linux.BridgePort{
 		BridgeIfName: ni.brIfName,
 		Variant: linux.BridgePortVariant{
@@ -602,6 +815,20 @@ func (r *LinuxNIReconciler) getIntendedNIL3Cfg(niID uuid.UUID) dg.Graph {
 			}, nil)
 		}
 	}
+	if ni.config.EnableARPProxy {
+		// Answer ARP requests received on the bridge on behalf of addresses
+		// the device knows how to route, allowing apps with static addresses
+		// from a subnet not directly present on the uplink to be reached
+		// via the device.
+		intendedL3Cfg.PutItem(linux.Sysctl{
+			NetIf: generic.NetworkIf{
+				IfName:  ni.brIfName,
+				ItemRef: dg.Reference(linux.Bridge{IfName: ni.brIfName}),
+			},
+			EnableDAD:      true,
+			EnableProxyARP: true,
+		}, nil)
+	}
 	if ni.config.Type == types.NetworkInstanceTypeSwitch {
 		// No more L3 config for switch network instance.
 		return intendedL3Cfg
@@ -640,6 +867,23 @@ func (r *LinuxNIReconciler) getIntendedNIL3Cfg(niID uuid.UUID) dg.Graph {
 			}
 		}
 	}
+	prevUplink := ni.mbbPrevUplink.IfName
+	if prevUplink != "" {
+		// Ongoing make-before-break switch of the uplink - temporarily retain
+		// routes of the previous uplink until routes of the new uplink
+		// are created.
+		ifIndex, found, err := r.netMonitor.GetInterfaceIndex(prevUplink)
+		if err != nil {
+			r.log.Errorf("%s: getIntendedNIL3Cfg: failed to get ifIndex "+
+				"for (previous NI uplink) %s: %v", LogAndErrPrefix, prevUplink, err)
+		}
+		if err == nil && found {
+			outIfs[ifIndex] = generic.NetworkIf{
+				IfName:  prevUplink,
+				ItemRef: dg.Reference(generic.Uplink{IfName: prevUplink}),
+			}
+		}
+	}
 	// User-defined static default route will override the original default route
 	// of the uplink interface.
 	var haveStaticDefRoute bool
@@ -669,6 +913,17 @@ func (r *LinuxNIReconciler) getIntendedNIL3Cfg(niID uuid.UUID) dg.Graph {
 			}
 			rtCopy := rt.Data.(netlink.Route)
 			rtCopy.Table = dstTable
+			if rt.IsDefaultRoute() {
+				// Assign an explicit metric to the default route so that
+				// during a make-before-break switch of the uplink, default
+				// routes of both the previous and the new uplink can coexist
+				// in the NI routing table, with the new uplink being preferred.
+				if rtOutIf.IfName == prevUplink {
+					rtCopy.Priority = ni.mbbPrevMetric
+				} else {
+					rtCopy.Priority = ni.defRouteMetric
+				}
+			}
 			// Multiple IPv6 link-locals can't be added to the same
 			// table unless the Priority differs.
 			// Different LinkIndex, Src, Scope doesn't matter.
@@ -777,7 +1032,7 @@ func (r *LinuxNIReconciler) getIntendedNIL3Cfg(niID uuid.UUID) dg.Graph {
 	// Add S-NAT iptables rule for the local network instance (only for IPv4).
 	if ni.config.Subnet.IP.To4() != nil {
 		if ni.config.Type == types.NetworkInstanceTypeLocal && uplink != "" {
-			intendedL3Cfg.PutItem(iptables.Rule{
+			snatRule := iptables.Rule{
 				RuleLabel: fmt.Sprintf("SNAT traffic from NI %s", ni.config.UUID),
 				Table:     "nat",
 				ChainName: appChain("POSTROUTING"),
@@ -786,12 +1041,270 @@ func (r *LinuxNIReconciler) getIntendedNIL3Cfg(niID uuid.UUID) dg.Graph {
 				Description: fmt.Sprintf("NAT traffic from the local network instance %s "+
 					"as it leaves node through the uplink %s", ni.config.DisplayName,
 					ni.bridge.Uplink.LogicalLabel),
+			}
+			if egressIP := ni.config.EgressIP; egressIP != nil {
+				// Pin the egress traffic of the NI to the requested uplink IP
+				// address. This matters when the uplink has multiple global
+				// addresses and MASQUERADE would pick an arbitrary one.
+				if r.uplinkHasIPAddr(ni.bridge.Uplink, egressIP) {
+					snatRule.Target = "SNAT"
+					snatRule.TargetOpts = []string{"--to-source", egressIP.String()}
+				} else {
+					r.log.Errorf("%s: uplink %s does not have the egress IP "+
+						"address %v requested for NI %s, falling back to MASQUERADE",
+						LogAndErrPrefix, ni.bridge.Uplink.LogicalLabel, egressIP,
+						ni.config.UUID)
+				}
+			}
+			intendedL3Cfg.PutItem(snatRule, nil)
+		}
+	}
+	// IP rules and SNAT for the secondary IPv4 subnets of the NI
+	// (see types.NISecondarySubnet), mirroring the primary-subnet handling
+	// above. The egress traffic of secondary subnets is always MASQUERADEd
+	// (EgressIP pinning applies to the primary subnet only).
+	for _, secSubnet := range r.getNISecondarySubnets(ni) {
+		subnet := &net.IPNet{
+			IP:   secSubnet.Subnet.IP,
+			Mask: secSubnet.Subnet.Mask,
+		}
+		if gwIP := secSubnet.GatewayIP(); gwIP != nil {
+			intendedL3Cfg.PutItem(linux.IPRule{
+				Priority: devicenetwork.PbrNatOutGatewayPrio,
+				Table:    syscall.RT_TABLE_LOCAL,
+				Src:      subnet,
+				Dst:      netutils.HostSubnet(gwIP),
 			}, nil)
 		}
+		intendedL3Cfg.PutItem(linux.IPRule{
+			Priority: devicenetwork.PbrNatOutPrio,
+			Table:    devicenetwork.NIBaseRTIndex + ni.bridge.BrNum,
+			Src:      subnet,
+		}, nil)
+		intendedL3Cfg.PutItem(linux.IPRule{
+			Priority: devicenetwork.PbrNatInPrio,
+			Table:    devicenetwork.NIBaseRTIndex + ni.bridge.BrNum,
+			Dst:      subnet,
+		}, nil)
+		if uplink != "" {
+			intendedL3Cfg.PutItem(iptables.Rule{
+				RuleLabel: fmt.Sprintf("SNAT traffic from subnet %s of NI %s",
+					subnet, ni.config.UUID),
+				Table:     "nat",
+				ChainName: appChain("POSTROUTING"),
+				MatchOpts: []string{"-o", uplink, "-s", subnet.String()},
+				Target:    "MASQUERADE",
+				Description: fmt.Sprintf("NAT traffic from the secondary "+
+					"subnet %s of the local network instance %s as it leaves "+
+					"node through the uplink %s", subnet, ni.config.DisplayName,
+					ni.bridge.Uplink.LogicalLabel),
+			}, nil)
+		}
+	}
+	for _, item := range r.getIntendedConntrackCfg(niID) {
+		intendedL3Cfg.PutItem(item, nil)
+	}
+	for _, item := range r.getIntendedConntrackZoneCfg(niID) {
+		intendedL3Cfg.PutItem(item, nil)
+	}
+	for _, item := range r.getIntendedEgressProxyCfg(niID) {
+		intendedL3Cfg.PutItem(item, nil)
 	}
 	return intendedL3Cfg
 }
 
+// getIntendedConntrackCfg returns items which tune connection tracking timeouts
+// for flows of the network instance (see types.ConntrackTimeouts).
+// For every protocol with a configured timeout, a named conntrack timeout
+// policy is created and flows initiated from the NI are attached to it using
+// a CT rule in the raw table (the policy is applied when the conntrack entry
+// is created, i.e. by the first packet of the flow).
+func (r *LinuxNIReconciler) getIntendedConntrackCfg(niID uuid.UUID) (items []dg.Item) {
+	ni := r.nis[niID]
+	timeouts := ni.config.ConntrackTimeouts
+	if !timeouts.IsDefined() {
+		return nil
+	}
+	protocols := []struct {
+		proto   string
+		timeout uint32
+	}{
+		{proto: "tcp", timeout: timeouts.TCPEstablished},
+		{proto: "udp", timeout: timeouts.UDP},
+		{proto: "icmp", timeout: timeouts.ICMP},
+	}
+	for _, p := range protocols {
+		if p.timeout == 0 {
+			// Global default is used for this protocol.
+			continue
+		}
+		for _, forIPv6 := range []bool{false, true} {
+			proto := p.proto
+			namePrefix := "ct"
+			if forIPv6 {
+				namePrefix = "ct6"
+				if proto == "icmp" {
+					proto = "icmpv6"
+				}
+			}
+			policyName := fmt.Sprintf("%s-%s-%s", namePrefix, p.proto, ni.brIfName)
+			items = append(items, linux.ConntrackTimeoutPolicy{
+				PolicyName: policyName,
+				Protocol:   proto,
+				ForIPv6:    forIPv6,
+				Timeout:    p.timeout,
+			})
+			items = append(items, iptables.Rule{
+				RuleLabel: fmt.Sprintf("Conntrack timeouts for %s flows of NI %s",
+					proto, ni.config.UUID),
+				Table:      "raw",
+				ChainName:  appChain("PREROUTING"),
+				ForIPv6:    forIPv6,
+				MatchOpts:  []string{"-i", ni.brIfName, "-p", proto},
+				Target:     "CT",
+				TargetOpts: []string{"--timeout", policyName},
+				Description: fmt.Sprintf("Attach %s flows initiated from the NI %s "+
+					"to the conntrack timeout policy %s", proto,
+					ni.config.DisplayName, policyName),
+			})
+		}
+	}
+	return items
+}
+
+// getIntendedConntrackZoneCfg returns an iptables rule which conntracks flows
+// initiated from the NI in a dedicated conntrack zone (zone number = BrNum).
+// With the default zone 0 shared by all NIs, two local network instances using
+// identical (overlapping) subnets could produce flows with colliding conntrack
+// 5-tuples, confusing NAT and flow accounting. Only the original direction
+// is scoped to the per-NI zone (--zone-orig) - reply tuples, which SNAT makes
+// unique, stay in the default zone so that reply packets arriving from
+// the uplink are matched without having to know the zone.
+// The zone is also included in the conntrack entries and used by the flow
+// collector (see nistate) to attribute flows to the correct NI.
+func (r *LinuxNIReconciler) getIntendedConntrackZoneCfg(niID uuid.UUID) (items []dg.Item) {
+	ni := r.nis[niID]
+	if ni.config.Type != types.NetworkInstanceTypeLocal {
+		// Traffic of switch NIs is not NATed and applications are addressed
+		// from the external subnet, hence there is no risk of colliding
+		// conntrack entries.
+		return nil
+	}
+	zone := strconv.Itoa(ni.bridge.BrNum)
+	items = append(items, iptables.Rule{
+		RuleLabel: fmt.Sprintf("Conntrack zone for flows of NI %s",
+			ni.config.UUID),
+		Table:      "raw",
+		ChainName:  appChain("PREROUTING"),
+		ForIPv6:    ni.config.IsIPv6(),
+		MatchOpts:  []string{"-i", ni.brIfName},
+		Target:     "CT",
+		TargetOpts: []string{"--zone-orig", zone},
+		Description: fmt.Sprintf("Conntrack flows initiated from the NI %s "+
+			"in the dedicated zone %s, so that network instances with "+
+			"overlapping subnets do not produce colliding conntrack entries",
+			ni.config.DisplayName, zone),
+	})
+	return items
+}
+
+// getIntendedEgressProxyCfg returns items which transparently redirect plain
+// HTTP traffic leaving the NI into the configured egress proxy
+// (see types.NIEgressProxy). The redirection rules are put into a separate
+// per-NI chain in the nat table, with exemptions for the metadata service,
+// the proxy itself and the intra-NI traffic, followed by a DNAT rule
+// rewriting the destination to the proxy address.
+// Blocking of direct HTTPS traffic (NIEgressProxy.EnforceHTTPS) is handled
+// separately in the per-VIF ACLs (see getIntendedAppConnRawIptables).
+func (r *LinuxNIReconciler) getIntendedEgressProxyCfg(niID uuid.UUID) (items []dg.Item) {
+	ni := r.nis[niID]
+	proxy := ni.config.EgressProxy
+	if proxy == nil || proxy.ProxyIP == nil || !proxy.RedirectHTTP {
+		return nil
+	}
+	if ni.config.Type != types.NetworkInstanceTypeLocal {
+		// Switched traffic does not pass through the dom0 router and therefore
+		// cannot be transparently redirected.
+		return nil
+	}
+	forIPv6 := proxy.ProxyIP.To4() == nil
+	chainName := "proxy-" + ni.brIfName
+	items = append(items, iptables.Chain{
+		Table:     "nat",
+		ChainName: chainName,
+		ForIPv6:   forIPv6,
+	})
+	items = append(items, iptables.Rule{
+		RuleLabel: fmt.Sprintf("Traverse egress proxy redirection rules of NI %s",
+			ni.config.UUID),
+		Table:     "nat",
+		ChainName: appChain("PREROUTING"),
+		ForIPv6:   forIPv6,
+		MatchOpts: []string{"-i", ni.brIfName, "-p", "tcp", "--dport", "80"},
+		Target:    chainName,
+	})
+	// Put redirection rules into the NI-specific chain.
+	// We have already matched on the input bridge and the destination port
+	// and get only HTTP traffic coming from the NI.
+	var rules []iptables.Rule
+	if !forIPv6 {
+		rules = append(rules, iptables.Rule{
+			RuleLabel: "No redirection for the metadata service",
+			MatchOpts: []string{"-d", metadataSrvIP + "/32"},
+			Target:    "RETURN",
+			Description: "Keep traffic headed towards the metadata service " +
+				"out of the egress proxy redirection",
+		})
+	}
+	rules = append(rules, iptables.Rule{
+		RuleLabel: "No redirection for the egress proxy itself",
+		MatchOpts: []string{"-d", proxy.ProxyIP.String()},
+		Target:    "RETURN",
+		Description: "Do not redirect traffic which is already headed " +
+			"towards the egress proxy",
+	})
+	exemptSubnets := []*net.IPNet{r.getNISubnet(ni)}
+	for _, secSubnet := range r.getNISecondarySubnets(ni) {
+		exemptSubnets = append(exemptSubnets, &net.IPNet{
+			IP:   secSubnet.Subnet.IP,
+			Mask: secSubnet.Subnet.Mask,
+		})
+	}
+	for _, subnet := range exemptSubnets {
+		if subnet == nil || (subnet.IP.To4() == nil) != forIPv6 {
+			continue
+		}
+		rules = append(rules, iptables.Rule{
+			RuleLabel: fmt.Sprintf("No redirection inside subnet %s", subnet),
+			MatchOpts: []string{"-d", subnet.String()},
+			Target:    "RETURN",
+			Description: "Do not redirect traffic which stays inside " +
+				"the network instance",
+		})
+	}
+	proxyAddr := net.JoinHostPort(proxy.ProxyIP.String(),
+		strconv.Itoa(int(proxy.ProxyPort)))
+	rules = append(rules, iptables.Rule{
+		RuleLabel:  fmt.Sprintf("Redirect HTTP of NI %s into the egress proxy", ni.config.UUID),
+		Target:     "DNAT",
+		TargetOpts: []string{"--to-destination", proxyAddr},
+		Description: fmt.Sprintf("Transparently redirect plain HTTP traffic "+
+			"leaving the network instance %s into the egress proxy %s",
+			ni.config.DisplayName, proxyAddr),
+	})
+	for i, rule := range rules {
+		rule.ChainName = chainName
+		rule.Table = "nat"
+		// Keep exact order.
+		if i < len(rules)-1 {
+			rule.AppliedBefore = []string{rules[i+1].RuleLabel}
+		}
+		rule.ForIPv6 = forIPv6
+		items = append(items, rule)
+	}
+	return items
+}
+
 func (r *LinuxNIReconciler) getIntendedNIServices(niID uuid.UUID) dg.Graph {
 	graphArgs := dg.InitArgs{
 		Name:        NIServicesSG,
@@ -807,6 +1320,9 @@ func (r *LinuxNIReconciler) getIntendedNIServices(niID uuid.UUID) dg.Graph {
 	for _, item := range r.getIntendedRadvdCfg(niID) {
 		intendedServices.PutItem(item, nil)
 	}
+	for _, item := range r.getIntendedNAT64Cfg(niID) {
+		intendedServices.PutItem(item, nil)
+	}
 	return intendedServices
 }
 
@@ -992,6 +1508,27 @@ func (r *LinuxNIReconciler) getIntendedDnsmasqCfg(niID uuid.UUID) (items []dg.It
 		NTPServers:       ntpServers,
 		PropagateRoutes:  propagateRoutes,
 		MTU:              ni.bridge.MTU,
+		ClientClasses:    ni.config.DHCPClientClasses,
+	}
+	// Advertise the PAC file of the egress proxy (if any) using
+	// the DHCP option 252.
+	if proxy := ni.config.EgressProxy; proxy != nil {
+		dhcpCfg.PACFileURL = proxy.PACFileURL
+	}
+	// Dedicated DHCP pools for the secondary IPv4 subnets of the NI
+	// (see types.NISecondarySubnet).
+	for _, secSubnet := range r.getNISecondarySubnets(ni) {
+		dhcpCfg.SecondaryPools = append(dhcpCfg.SecondaryPools, generic.DHCPPool{
+			Subnet: &net.IPNet{
+				IP:   secSubnet.Subnet.IP,
+				Mask: secSubnet.Subnet.Mask,
+			},
+			IPRange: generic.IPRange{
+				FromIP: secSubnet.DhcpRange.Start,
+				ToIP:   secSubnet.DhcpRange.End,
+			},
+			GatewayIP: secSubnet.GatewayIP(),
+		})
 	}
 	// IPRange set above does not matter that much - every VIF is statically
 	// assigned IP address using a host file.
@@ -1004,12 +1541,41 @@ func (r *LinuxNIReconciler) getIntendedDnsmasqCfg(niID uuid.UUID) (items []dg.It
 				continue
 			}
 			if vif.GuestIP != nil {
-				dhcpCfg.StaticEntries = append(dhcpCfg.StaticEntries,
-					generic.MACToIP{
-						MAC:      vif.GuestIfMAC,
-						IP:       vif.GuestIP,
-						Hostname: app.config.UUIDandVersion.UUID.String(),
-					})
+				entry := generic.MACToIP{
+					MAC:      vif.GuestIfMAC,
+					IP:       vif.GuestIP,
+					Hostname: app.config.UUIDandVersion.UUID.String(),
+				}
+				// Propagate custom per-adapter routes to this app using
+				// the DHCP option 121 (IPv6 routes are advertised by radvd
+				// instead, see getIntendedRadvdCfg).
+				for _, adapter := range app.config.AppNetAdapterList {
+					if adapter.Name != vif.NetAdapterName {
+						continue
+					}
+					// Apply user-configured overrides of the DHCP lease time
+					// and selected DHCP options for this VIF.
+					entry.DhcpOverrides = adapter.DhcpOverrides
+					for _, route := range adapter.StaticRoutes {
+						if route.DstNetwork == nil ||
+							route.DstNetwork.IP.To4() == nil {
+							continue
+						}
+						gateway := route.Gateway
+						if gateway == nil && bridgeIP != nil {
+							gateway = bridgeIP.IP
+						}
+						if gateway == nil {
+							continue
+						}
+						entry.PropagateRoutes = append(entry.PropagateRoutes,
+							types.IPRoute{
+								DstNetwork: route.DstNetwork,
+								Gateway:    gateway,
+							})
+					}
+				}
+				dhcpCfg.StaticEntries = append(dhcpCfg.StaticEntries, entry)
 			}
 		}
 	}
@@ -1023,6 +1589,13 @@ func (r *LinuxNIReconciler) getIntendedDnsmasqCfg(niID uuid.UUID) (items []dg.It
 		ListenIP:        listenIP,
 		UplinkIf:        uplinkIf,
 		UpstreamServers: ni.bridge.Uplink.DNSServers,
+		SplitDNS:        ni.config.SplitDNS,
+	}
+	if r.nat64Enabled(ni) {
+		// Synthesize AAAA records for IPv4-only destinations, to be translated
+		// by the NAT64 gateway of this NI (see getIntendedNAT64Cfg).
+		_, dns64Prefix, _, _ := r.nat64Params(ni)
+		dnsCfg.DNS64Prefix = dns64Prefix
 	}
 	for _, staticEntry := range ni.config.DnsNameToIPList {
 		dnsCfg.StaticEntries = append(dnsCfg.StaticEntries, generic.HostnameToIPs{
@@ -1104,6 +1677,8 @@ func (r *LinuxNIReconciler) getIntendedDnsmasqCfg(niID uuid.UUID) (items []dg.It
 		},
 		DHCPServer: dhcpCfg,
 		DNSServer:  dnsCfg,
+		// Air-gapped NI never has external connectivity to gate on.
+		NIConnGate: ni.config.GateDHCPOnConnectivity && !airGap,
 	})
 	return items
 }
@@ -1115,17 +1690,155 @@ func (r *LinuxNIReconciler) getIntendedRadvdCfg(niID uuid.UUID) (items []dg.Item
 	}
 	// XXX do we need same logic as for IPv4 dnsmasq to not advertise as default router?
 	// Might we need lower radvd preference if isolated local network?
+	raConfig := ni.config.IPv6RA
+	if !raConfig.IsDefined() {
+		// Without explicit RA options from the controller, keep the historical
+		// default of pointing hosts to DHCPv6 for address assignment.
+		raConfig.AdvManagedFlag = true
+	}
+	// Advertise user-configured IPv6 routes using the Route Information
+	// Option (RFC 4191), both those defined for the whole network instance
+	// and those specific to individual app adapters.
+	var propagateRoutes []*net.IPNet
+	for _, route := range ni.config.StaticRoutes {
+		if route.IsDefaultRoute() || route.DstNetwork == nil ||
+			route.DstNetwork.IP.To4() != nil {
+			continue
+		}
+		propagateRoutes = append(propagateRoutes, route.DstNetwork)
+	}
+	for _, app := range r.apps {
+		if app.deleted {
+			continue
+		}
+		for _, vif := range app.vifs {
+			if vif.NI != niID {
+				continue
+			}
+			for _, adapter := range app.config.AppNetAdapterList {
+				if adapter.Name != vif.NetAdapterName {
+					continue
+				}
+				for _, route := range adapter.StaticRoutes {
+					if route.DstNetwork == nil ||
+						route.DstNetwork.IP.To4() != nil {
+						continue
+					}
+					propagateRoutes = append(propagateRoutes, route.DstNetwork)
+				}
+			}
+		}
+	}
+	propagateRoutes = generics.FilterDuplicatesFn(propagateRoutes,
+		netutils.EqualIPNets)
 	items = append(items, generic.Radvd{
 		ForNI: niID,
 		ListenIf: generic.NetworkIf{
 			IfName:  ni.brIfName,
 			ItemRef: dg.Reference(linux.Bridge{IfName: ni.brIfName}),
 		},
-		MTU: ni.bridge.MTU,
+		MTU:             ni.bridge.MTU,
+		Prefix:          &ni.config.Subnet,
+		RAConfig:        raConfig,
+		PropagateRoutes: propagateRoutes,
 	})
 	return items
 }
 
+// nat64Enabled returns true if the NAT64+DNS64 service should be deployed
+// for the network instance.
+func (r *LinuxNIReconciler) nat64Enabled(ni *niInfo) bool {
+	return ni.config.Type == types.NetworkInstanceTypeLocal &&
+		ni.config.NAT64.Enable && ni.config.IsIPv6()
+}
+
+// nat64Params returns the effective parameters of the NAT64 translator
+// of the network instance, applying defaults where the config leaves them
+// undefined (see types.NAT64Config).
+func (r *LinuxNIReconciler) nat64Params(ni *niInfo) (
+	tunIfName string, nat64Prefix, ipv4Pool *net.IPNet, ipv4Addr net.IP) {
+	tunIfName = nat64TunIfNamePrefix + ni.brIfName
+	nat64Prefix = ni.config.NAT64.NAT64Prefix
+	if nat64Prefix == nil {
+		_, nat64Prefix, _ = net.ParseCIDR(nat64WellKnownPrefix)
+	}
+	ipv4Pool = ni.config.NAT64.IPv4Pool
+	if ipv4Pool == nil {
+		// Per-NI /24 subrange of the CGNAT space 100.88.0.0/16
+		// (wrapping around for bridge numbers above 255).
+		ipv4Pool = &net.IPNet{
+			IP:   net.IPv4(100, 88, byte(ni.bridge.BrNum%256), 0),
+			Mask: net.CIDRMask(24, 32),
+		}
+	}
+	// Address of the translator itself - the first host address of the pool.
+	ipv4Addr = netutils.AddToIP(ipv4Pool.IP.Mask(ipv4Pool.Mask), 1)
+	return tunIfName, nat64Prefix, ipv4Pool, ipv4Addr
+}
+
+// getIntendedNAT64Cfg returns items of the NAT64 gateway service of an IPv6-only
+// local network instance (see types.NAT64Config): the tayga translator with its
+// TUN interface, routes steering traffic through the translator and the S-NAT
+// rule for the translated (IPv4) traffic leaving through the uplink.
+// DNS64 is handled by dnsmasq (see getIntendedDnsmasqCfg).
+func (r *LinuxNIReconciler) getIntendedNAT64Cfg(niID uuid.UUID) (items []dg.Item) {
+	ni := r.nis[niID]
+	if !r.nat64Enabled(ni) {
+		return nil
+	}
+	tunIfName, nat64Prefix, ipv4Pool, ipv4Addr := r.nat64Params(ni)
+	items = append(items, generic.Tayga{
+		ForNI:       niID,
+		TunIfName:   tunIfName,
+		NAT64Prefix: nat64Prefix,
+		IPv4Pool:    ipv4Pool,
+		IPv4Addr:    ipv4Addr,
+	})
+	tunNetworkIf := generic.NetworkIf{
+		IfName:  tunIfName,
+		ItemRef: dg.Reference(generic.Tayga{TunIfName: tunIfName}),
+	}
+	// Steer traffic from apps destined to the NAT64 prefix into the translator.
+	items = append(items, linux.Route{
+		Route: netlink.Route{
+			Scope:    netlink.SCOPE_LINK,
+			Dst:      nat64Prefix,
+			Protocol: unix.RTPROT_STATIC,
+			Family:   netlink.FAMILY_V6,
+			Table:    devicenetwork.NIBaseRTIndex + ni.bridge.BrNum,
+		},
+		OutputIf: tunNetworkIf,
+	})
+	// Return IPv4 traffic (destined to the dynamic pool after the reverse
+	// S-NAT done by conntrack) is routed by the main table - steer it back
+	// into the translator.
+	items = append(items, linux.Route{
+		Route: netlink.Route{
+			Scope:    netlink.SCOPE_LINK,
+			Dst:      ipv4Pool,
+			Protocol: unix.RTPROT_STATIC,
+			Family:   netlink.FAMILY_V4,
+			Table:    unix.RT_TABLE_MAIN,
+		},
+		OutputIf: tunNetworkIf,
+	})
+	// S-NAT the translated IPv4 traffic as it leaves through the uplink
+	// (addresses from the dynamic pool are not routable outside of the node).
+	if uplink := ni.bridge.Uplink.IfName; uplink != "" {
+		items = append(items, iptables.Rule{
+			RuleLabel: fmt.Sprintf("SNAT NAT64 traffic from NI %s", ni.config.UUID),
+			Table:     "nat",
+			ChainName: appChain("POSTROUTING"),
+			MatchOpts: []string{"-o", uplink, "-s", ipv4Pool.String()},
+			Target:    "MASQUERADE",
+			Description: fmt.Sprintf("NAT IPv4 traffic translated by the NAT64 "+
+				"gateway of the network instance %s as it leaves node through "+
+				"the uplink %s", ni.config.DisplayName, ni.bridge.Uplink.LogicalLabel),
+		})
+	}
+	return items
+}
+
 func (r *LinuxNIReconciler) getIntendedAppConnCfg(niID uuid.UUID,
 	vif vifInfo, ul types.AppNetAdapterConfig) dg.Graph {
 	ni := r.nis[vif.NI]
@@ -1139,6 +1852,40 @@ func (r *LinuxNIReconciler) getIntendedAppConnCfg(niID uuid.UUID,
 		ID:        vif.App,
 		NetNsName: app.kubePod.netNsName,
 	}
+	if ul.VhostUser && !r.withKubernetesNetworking {
+		// The VIF is backed by a DPDK/vhost-user datapath and has no kernel
+		// netdev - there is nothing to configure in the kernel network stack
+		// (bridge port, ipsets and iptables rules do not apply).
+		// The VhostUser VIF variant merely records the app attachment
+		// in the dependency graph.
+		// Note that zedrouter denies activation of an app network combining
+		// a vhost-user VIF with user-configured ACLs unless an enforcement
+		// strategy available on the device is selected (see DpdkAclMode),
+		// so returning early here does not silently bypass security policy.
+		intendedAppConnCfg.PutItem(linux.VIF{
+			HostIfName:     vif.hostIfName,
+			NetAdapterName: vif.NetAdapterName,
+			Variant: linux.VIFVariant{
+				VhostUser: true,
+			},
+		}, nil)
+		return intendedAppConnCfg
+	}
+	if ul.AttachmentMode != types.VifAttachmentBridge &&
+		!r.withKubernetesNetworking {
+		// L2-only attachment: instead of a bridged tap, the app VIF is created
+		// as a MACVLAN/IPVLAN sub-interface of the NI port and plugged into
+		// the guest by the hypervisor (macvtap-style).
+		// User ACLs and other bridge-datapath features do not apply here
+		// (enforced by config validation inside zedrouter).
+		intendedAppConnCfg.PutItem(linux.MacVlan{
+			IfName:       vif.hostIfName,
+			ParentIfName: ni.bridge.Uplink.IfName,
+			IPVLAN:       ul.AttachmentMode == types.VifAttachmentIPVLAN,
+			MTU:          ni.bridge.MTU,
+		}, nil)
+		return intendedAppConnCfg
+	}
 	if r.withKubernetesNetworking {
 		if app.kubePod.netNsName != "" && vif.PodVIF.GuestIfName != "" {
 			var appIPs []*net.IPNet
@@ -1257,6 +2004,23 @@ func (r *LinuxNIReconciler) getIntendedAppConnCfg(niID uuid.UUID,
 			PortIfName:   vif.hostIfName,
 			VLANConfig:   vlanConfig,
 		}, nil)
+		if ul.StormControl.IsEnabled() {
+			intendedAppConnCfg.PutItem(linux.StormControl{
+				VIFIfName:      vif.hostIfName,
+				NetAdapterName: vif.NetAdapterName,
+				Limits:         ul.StormControl,
+			}, nil)
+		}
+	}
+	if ni.config.EnableARPProxy && vif.GuestIP != nil && len(vif.GuestIfMAC) > 0 {
+		// With proxy-ARP enabled, install a static neighbor entry so that
+		// the device is able to deliver routed traffic to the app without
+		// depending on dynamic neighbor resolution.
+		intendedAppConnCfg.PutItem(linux.Arp{
+			BridgeIfName: ni.brIfName,
+			IPAddr:       vif.GuestIP,
+			HwAddr:       vif.GuestIfMAC,
+		}, nil)
 	}
 	// Create ipset with all the addresses from the DNSNameToIPList plus the VIF IP itself.
 	var ips []net.IP
@@ -1268,6 +2032,13 @@ func (r *LinuxNIReconciler) getIntendedAppConnCfg(niID uuid.UUID,
 	if vif.GuestIP != nil {
 		ips = append(ips, vif.GuestIP)
 	}
+	// Include virtual IPs for which this app is a candidate, so that "eidset"
+	// ACL rules also cover traffic addressed to the virtual IP.
+	for _, vip := range ni.config.VirtualIPs {
+		if vip.HasCandidate(vif.App) {
+			ips = append(ips, vip.IP)
+		}
+	}
 	if r.withKubernetesNetworking {
 		for _, ip := range vif.PodVIF.IPAM.IPs {
 			ips = append(ips, ip.Address.IP)
@@ -1336,6 +2107,23 @@ func (r *LinuxNIReconciler) getNISubnet(ni *niInfo) *net.IPNet {
 	}
 }
 
+// getNISecondarySubnets returns the valid secondary IPv4 subnets of the NI
+// (see types.NISecondarySubnet). Only local network instances may carry
+// secondary subnets.
+func (r *LinuxNIReconciler) getNISecondarySubnets(
+	ni *niInfo) (subnets []types.NISecondarySubnet) {
+	if ni.config.Type != types.NetworkInstanceTypeLocal {
+		return nil
+	}
+	for _, secSubnet := range ni.config.SecondarySubnets {
+		if secSubnet.Subnet.IP.To4() == nil {
+			continue
+		}
+		subnets = append(subnets, secSubnet)
+	}
+	return subnets
+}
+
 // Check if network instance has default route.
 func (r *LinuxNIReconciler) niHasDefRoute(ni *niInfo) bool {
 	uplink := ni.bridge.Uplink.IfName
@@ -1375,6 +2163,32 @@ func (r *LinuxNIReconciler) niHasDefRoute(ni *niInfo) bool {
 	return false
 }
 
+// Check if the given IP address is assigned to the uplink port.
+func (r *LinuxNIReconciler) uplinkHasIPAddr(uplink Uplink, addr net.IP) bool {
+	ifIndex, found, err := r.netMonitor.GetInterfaceIndex(uplink.IfName)
+	if err != nil {
+		r.log.Errorf("%s: uplinkHasIPAddr: failed to get ifIndex "+
+			"for (NI uplink) %s: %v", LogAndErrPrefix, uplink.IfName, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	uplinkIPs, _, err := r.netMonitor.GetInterfaceAddrs(ifIndex)
+	if err != nil {
+		r.log.Errorf(
+			"%s: uplinkHasIPAddr: failed to get interface %s addresses: %v",
+			LogAndErrPrefix, uplink.IfName, err)
+		// Continue as if this uplink interface didn't have any IP addresses...
+	}
+	for _, uplinkIP := range uplinkIPs {
+		if uplinkIP.IP.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // Check if route gateway is inside the subnet of the uplink port.
 func (r *LinuxNIReconciler) routeGwIsConnected(route types.IPRoute, uplink Uplink) bool {
 	if uplink.IfName == "" {
@@ -32,10 +32,23 @@ func (r *LinuxNIReconciler) updateCurrentGlobalState(uplinksOnly bool) (changed
 	}
 	// Refresh the current state of uplinks.
 	currentUplinks := dg.New(dg.InitArgs{Name: UplinksSG})
+	type portRef struct {
+		ifName       string
+		logicalLabel string
+	}
+	var ports []portRef
 	for _, ni := range r.nis {
 		if ni.deleted {
 			continue
 		}
+		// Extra ports of a switch NI are tracked just like uplinks
+		// (the BridgePort enslaving them depends on their presence).
+		for _, port := range ni.bridge.ExtraPorts {
+			ports = append(ports, portRef{
+				ifName:       port.IfName,
+				logicalLabel: port.LogicalLabel,
+			})
+		}
 		if ni.bridge.Uplink.IfName == "" {
 			// Air-gapped NI, no uplink.
 			continue
@@ -57,6 +70,13 @@ func (r *LinuxNIReconciler) updateCurrentGlobalState(uplinksOnly bool) (changed
 			// was a physical interface.
 			uplinkIfName = ni.bridge.Uplink.IfName
 		}
+		ports = append(ports, portRef{
+			ifName:       uplinkIfName,
+			logicalLabel: ni.bridge.Uplink.LogicalLabel,
+		})
+	}
+	for _, port := range ports {
+		uplinkIfName := port.ifName
 		ifIndex, found, err := r.netMonitor.GetInterfaceIndex(uplinkIfName)
 		if err != nil {
 			r.log.Errorf("%s: updateCurrentGlobalState: failed to get ifIndex for %s: %v",
@@ -94,7 +114,7 @@ func (r *LinuxNIReconciler) updateCurrentGlobalState(uplinksOnly bool) (changed
 		}
 		currentUplinks.PutItem(generic.Uplink{
 			IfName:       uplinkIfName,
-			LogicalLabel: ni.bridge.Uplink.LogicalLabel,
+			LogicalLabel: port.logicalLabel,
 			MasterIfName: masterIfName,
 			AdminUp:      ifAttrs.AdminUp,
 			IPAddresses:  ips,
@@ -108,6 +128,27 @@ func (r *LinuxNIReconciler) updateCurrentGlobalState(uplinksOnly bool) (changed
 		globalSG.PutSubGraph(currentUplinks)
 		changed = true
 	}
+	// Refresh the observed connectivity status of NIs with gated DHCP
+	// (see generic.NIConn).
+	currentNIConns := dg.New(dg.InitArgs{Name: NIConnsSG})
+	for _, ni := range r.nis {
+		if ni.deleted || !ni.config.GateDHCPOnConnectivity {
+			continue
+		}
+		if ni.bridge.Uplink.IfName == "" || !r.niHasDefRoute(ni) {
+			continue
+		}
+		currentNIConns.PutItem(generic.NIConn{NI: ni.config.UUID},
+			&reconciler.ItemStateData{
+				State:         reconciler.ItemStateCreated,
+				LastOperation: reconciler.OperationCreate,
+			})
+	}
+	prevNIConns := globalSG.SubGraph(NIConnsSG)
+	if prevNIConns == nil || len(prevNIConns.DiffItems(currentNIConns)) > 0 {
+		globalSG.PutSubGraph(currentNIConns)
+		changed = true
+	}
 	if !uplinksOnly || globalSG.SubGraph(ACLRootChainsSG) == nil {
 		// Refresh the current state of external iptables chains.
 		// XXX For now assume that all application chains were created by NIM
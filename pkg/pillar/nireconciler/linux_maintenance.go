@@ -0,0 +1,132 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package nireconciler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	generic "github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
+	linux "github.com/lf-edge/eve/pkg/pillar/nireconciler/linuxitems"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// dnsmasqLogMaxSize : size limit for the log file of a single dnsmasq
+	// instance. The log is stored in tmpfs (/run), hence the rather low limit.
+	dnsmasqLogMaxSize = 1 << 20 // 1 MiB
+	// leaseExpiryGraceTime : for how long an already expired IP lease is kept
+	// in the dnsmasq lease DB before the periodic maintenance removes it.
+	leaseExpiryGraceTime = time.Hour
+)
+
+// RunMaintenance cleans up resources which accumulate while the given network
+// instance is running:
+//   - rotates the dnsmasq log file when it grows over the size limit,
+//   - removes long-expired entries from the dnsmasq lease DB,
+//   - destroys orphaned ipsets associated with the network instance
+//     (left behind by a failed atomic update or an unclean shutdown).
+//
+// Only resources which are not referenced by the current or the intended
+// state are removed, hence the cleanup cannot conflict with an ongoing
+// reconciliation. A failure of one maintenance step is logged and does not
+// abort the remaining steps.
+func (r *LinuxNIReconciler) RunMaintenance(ctx context.Context, niID uuid.UUID) (
+	report NIMaintenanceReport, err error) {
+	contWatcher := r.pauseWatcher()
+	defer contWatcher()
+	ni, exists := r.nis[niID]
+	if !exists || ni.deleted {
+		return report, fmt.Errorf("%s: RunMaintenance: unknown network instance %v",
+			LogAndErrPrefix, niID)
+	}
+	if reconciler.IsMockRun(ctx) {
+		// Unit tests run against a mocked network stack.
+		return report, nil
+	}
+	// dnsmasq uses the interface name of the NI bridge as the instance name.
+	// For network instances which do not run dnsmasq both helpers are no-ops
+	// (the files do not exist).
+	configurator := &generic.DnsmasqConfigurator{Log: r.log, Logger: r.logger}
+	rotated, rotateErr := configurator.RotateDnsmasqLogFile(
+		ni.brIfName, dnsmasqLogMaxSize)
+	if rotateErr != nil {
+		r.log.Errorf("%s: RunMaintenance: %v", LogAndErrPrefix, rotateErr)
+	}
+	report.RotatedLogBytes = rotated
+	compacted, compactErr := configurator.CompactDnsmasqLeaseDB(
+		ni.brIfName, leaseExpiryGraceTime)
+	if compactErr != nil {
+		r.log.Errorf("%s: RunMaintenance: %v", LogAndErrPrefix, compactErr)
+	}
+	report.CompactedLeases = uint32(compacted)
+	report.RemovedIPSets = r.gcOrphanedNIIpsets(ni)
+	return report, nil
+}
+
+// gcOrphanedNIIpsets destroys ipsets which are associated with the given
+// network instance by the naming convention (the set name ends with the
+// interface name of the NI bridge or of one of the app VIFs connected to
+// the NI) but are not known to the depgraph. This covers the temporary
+// ipsets left behind by a failed atomic update (see pillar/ipsets)
+// as well as per-VIF/per-bridge ipsets whose removal failed and is no longer
+// retried (e.g. because the VIF is already gone).
+// Note that ipsets left behind by a previous zedrouter run are swept
+// by cleanupOrphanedIpsets during the startup.
+func (r *LinuxNIReconciler) gcOrphanedNIIpsets(ni *niInfo) (removed uint32) {
+	output, err := base.Exec(r.log, "ipset", "list", "-n").CombinedOutput()
+	if err != nil {
+		r.log.Warnf("%s: gcOrphanedNIIpsets: failed to list ipsets: %v",
+			LogAndErrPrefix, err)
+		return 0
+	}
+	suffixes := []string{"." + ni.brIfName}
+	for _, app := range r.apps {
+		for _, vif := range app.vifs {
+			if vif.NI == ni.config.UUID {
+				suffixes = append(suffixes, "."+vif.hostIfName)
+			}
+		}
+	}
+	const tmpIpsetPrefix = "tmp."
+	for _, setName := range strings.Fields(string(output)) {
+		if !strings.HasPrefix(setName, ipsetNamePrefixV4) &&
+			!strings.HasPrefix(setName, ipsetNamePrefixV6) &&
+			!strings.HasPrefix(setName, tmpIpsetPrefix) {
+			continue
+		}
+		var matchesNI bool
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(setName, suffix) {
+				matchesNI = true
+				break
+			}
+		}
+		if !matchesNI {
+			continue
+		}
+		// Temporary ipsets are never part of the depgraph - with the reconcile
+		// lock held there is no atomic update in progress and any existing
+		// temporary set is a leftover.
+		if !strings.HasPrefix(setName, tmpIpsetPrefix) &&
+			r.itemIsKnown(dg.Reference(linux.IPSet{SetName: setName})) {
+			continue
+		}
+		if _, err := base.Exec(
+			r.log, "ipset", "destroy", setName).CombinedOutput(); err != nil {
+			r.log.Errorf("%s: gcOrphanedNIIpsets: failed to destroy "+
+				"ipset %s: %v", LogAndErrPrefix, setName, err)
+			continue
+		}
+		r.log.Noticef("%s: Destroyed orphaned ipset %s associated with NI %v",
+			LogAndErrPrefix, setName, ni.config.UUID)
+		removed++
+	}
+	return removed
+}
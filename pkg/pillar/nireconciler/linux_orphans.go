@@ -0,0 +1,213 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package nireconciler
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/iptables"
+	generic "github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
+	linux "github.com/lf-edge/eve/pkg/pillar/nireconciler/linuxitems"
+	"github.com/vishvananda/netlink"
+)
+
+// After a zedrouter crash, artifacts created for network instances and app
+// connections during the previous run - bridges, VIFs, ipsets, per-VIF iptables
+// chains, dnsmasq instances - may linger inside the network stack and conflict
+// with the newly built state (e.g. "ipset create" fails if the set already
+// exists). All these artifacts follow zedrouter naming conventions, which
+// allows to enumerate them at startup, compare them with the restored depgraph
+// and delete those that are orphaned, while items known to the graph are left
+// in place to be adopted by the regular current-state refresh.
+
+var (
+	// Matches interface name of a bridge created by zedrouter for a network
+	// instance (see generateBridgeIfName).
+	orphanBridgeIfNameRegex = regexp.MustCompile(
+		`^` + bridgeIfNamePrefix + `[0-9]+$`)
+	// Matches host-side interface name of an application VIF
+	// (see generateVifHostIfName).
+	orphanVifIfNameRegex = regexp.MustCompile(
+		`^` + vifIfNamePrefix + `[0-9]+x[0-9]+$`)
+	// Matches name of an iptables chain created for an application VIF
+	// (see vifChain, ingressVifChain, egressVifChain).
+	orphanVifChainRegex = regexp.MustCompile(
+		`-` + vifIfNamePrefix + `[0-9]+x[0-9]+(-IN|-OUT)?$`)
+)
+
+// cleanupOrphanedArtifacts sweeps the network stack for artifacts matching
+// zedrouter naming conventions which are not present in the depgraph,
+// i.e. they were left behind by a previous (crashed) run and would otherwise
+// conflict with the newly applied configuration.
+// Called from RunInitialReconcile before the first reconciliation.
+func (r *LinuxNIReconciler) cleanupOrphanedArtifacts(ctx context.Context) {
+	if reconciler.IsMockRun(ctx) {
+		// Unit tests run against a mocked network stack.
+		return
+	}
+	r.cleanupOrphanedInterfaces()
+	r.cleanupOrphanedIpsets()
+	r.cleanupOrphanedVIFChains()
+	r.cleanupOrphanedDnsmasqInstances(ctx)
+}
+
+// itemIsKnown returns true if the item is present in either the current
+// or the intended state depgraph.
+func (r *LinuxNIReconciler) itemIsKnown(ref dg.ItemRef) bool {
+	if _, _, _, found := r.currentState.Item(ref); found {
+		return true
+	}
+	_, _, _, found := r.intendedState.Item(ref)
+	return found
+}
+
+// cleanupOrphanedInterfaces deletes bridges and VIFs matching zedrouter
+// naming conventions which are not known to the depgraph.
+func (r *LinuxNIReconciler) cleanupOrphanedInterfaces() {
+	ifNames, err := r.netMonitor.ListInterfaces()
+	if err != nil {
+		r.log.Errorf("%s: cleanupOrphanedInterfaces: failed to list "+
+			"interfaces: %v", LogAndErrPrefix, err)
+		return
+	}
+	for _, ifName := range ifNames {
+		var ref dg.ItemRef
+		switch {
+		case orphanBridgeIfNameRegex.MatchString(ifName):
+			ref = dg.Reference(linux.Bridge{IfName: ifName})
+		case orphanVifIfNameRegex.MatchString(ifName):
+			ref = dg.Reference(linux.VIF{HostIfName: ifName})
+		default:
+			continue
+		}
+		if r.itemIsKnown(ref) {
+			r.log.Noticef("%s: Adopting interface %s known to the restored state",
+				LogAndErrPrefix, ifName)
+			continue
+		}
+		link, err := netlink.LinkByName(ifName)
+		if err != nil {
+			r.log.Warnf("%s: cleanupOrphanedInterfaces: failed to get link %s: %v",
+				LogAndErrPrefix, ifName, err)
+			continue
+		}
+		if err := netlink.LinkDel(link); err != nil {
+			r.log.Errorf("%s: cleanupOrphanedInterfaces: failed to delete "+
+				"interface %s: %v", LogAndErrPrefix, ifName, err)
+			continue
+		}
+		r.log.Noticef("%s: Deleted orphaned interface %s left behind "+
+			"by a previous run", LogAndErrPrefix, ifName)
+	}
+}
+
+// cleanupOrphanedIpsets destroys ipsets with the zedrouter name prefix
+// ("ipv4." or "ipv6.") which are not known to the depgraph.
+func (r *LinuxNIReconciler) cleanupOrphanedIpsets() {
+	output, err := base.Exec(r.log, "ipset", "list", "-n").CombinedOutput()
+	if err != nil {
+		r.log.Warnf("%s: cleanupOrphanedIpsets: failed to list ipsets: %v",
+			LogAndErrPrefix, err)
+		return
+	}
+	for _, setName := range strings.Fields(string(output)) {
+		if !strings.HasPrefix(setName, ipsetNamePrefixV4) &&
+			!strings.HasPrefix(setName, ipsetNamePrefixV6) {
+			continue
+		}
+		if r.itemIsKnown(dg.Reference(linux.IPSet{SetName: setName})) {
+			r.log.Noticef("%s: Adopting ipset %s known to the restored state",
+				LogAndErrPrefix, setName)
+			continue
+		}
+		if _, err := base.Exec(
+			r.log, "ipset", "destroy", setName).CombinedOutput(); err != nil {
+			r.log.Errorf("%s: cleanupOrphanedIpsets: failed to destroy "+
+				"ipset %s: %v", LogAndErrPrefix, setName, err)
+			continue
+		}
+		r.log.Noticef("%s: Destroyed orphaned ipset %s left behind "+
+			"by a previous run", LogAndErrPrefix, setName)
+	}
+}
+
+// cleanupOrphanedVIFChains removes iptables chains created for application
+// VIFs by a previous run. The application chains pre-created by NIM
+// (e.g. FORWARD-apps) are flushed first - the restored depgraph assumes
+// they are empty (see updateCurrentGlobalState) and any rules left inside,
+// including those dispatching traffic into the per-VIF chains, come from
+// the previous run and will be recreated by the first reconciliation.
+func (r *LinuxNIReconciler) cleanupOrphanedVIFChains() {
+	for _, forIPv6 := range []bool{false, true} {
+		cmdOut := iptables.IptableCmdOut
+		if forIPv6 {
+			cmdOut = iptables.Ip6tableCmdOut
+		}
+		for table, chains := range usedIptablesChains {
+			for _, chain := range chains {
+				if _, err := cmdOut(
+					r.log, "-t", table, "-F", appChain(chain)); err != nil {
+					r.log.Warnf("%s: cleanupOrphanedVIFChains: failed to flush "+
+						"chain %s in table %s: %v",
+						LogAndErrPrefix, appChain(chain), table, err)
+				}
+			}
+			output, err := cmdOut(r.log, "-t", table, "-S")
+			if err != nil {
+				r.log.Warnf("%s: cleanupOrphanedVIFChains: failed to list "+
+					"chains in table %s: %v", LogAndErrPrefix, table, err)
+				continue
+			}
+			for _, line := range strings.Split(output, "\n") {
+				fields := strings.Fields(line)
+				if len(fields) != 2 || fields[0] != "-N" {
+					continue
+				}
+				chain := fields[1]
+				if !orphanVifChainRegex.MatchString(chain) {
+					continue
+				}
+				ref := dg.Reference(iptables.Chain{
+					ChainName: chain, Table: table, ForIPv6: forIPv6})
+				if r.itemIsKnown(ref) {
+					r.log.Noticef("%s: Adopting iptables chain %s (table %s) "+
+						"known to the restored state", LogAndErrPrefix, chain, table)
+					continue
+				}
+				if _, err := cmdOut(r.log, "-t", table, "-F", chain); err != nil {
+					r.log.Errorf("%s: cleanupOrphanedVIFChains: failed to flush "+
+						"chain %s in table %s: %v", LogAndErrPrefix, chain, table, err)
+					continue
+				}
+				if _, err := cmdOut(r.log, "-t", table, "-X", chain); err != nil {
+					r.log.Errorf("%s: cleanupOrphanedVIFChains: failed to delete "+
+						"chain %s in table %s: %v", LogAndErrPrefix, chain, table, err)
+					continue
+				}
+				r.log.Noticef("%s: Deleted orphaned iptables chain %s (table %s) "+
+					"left behind by a previous run", LogAndErrPrefix, chain, table)
+			}
+		}
+	}
+}
+
+// cleanupOrphanedDnsmasqInstances stops dnsmasq instances started by
+// a previous run and removes their config files and host directories.
+func (r *LinuxNIReconciler) cleanupOrphanedDnsmasqInstances(ctx context.Context) {
+	configurator := &generic.DnsmasqConfigurator{Log: r.log, Logger: r.logger}
+	removed := configurator.RemoveOrphanedDnsmasqArtifacts(ctx,
+		func(instanceName string) bool {
+			return r.itemIsKnown(dg.Reference(generic.Dnsmasq{
+				ListenIf: generic.NetworkIf{IfName: instanceName}}))
+		})
+	for _, instanceName := range removed {
+		r.log.Noticef("%s: Removed orphaned dnsmasq instance %s left behind "+
+			"by a previous run", LogAndErrPrefix, instanceName)
+	}
+}
@@ -19,9 +19,11 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/conntrack"
 	"github.com/lf-edge/eve/pkg/pillar/iptables"
+	"github.com/lf-edge/eve/pkg/pillar/netlog"
 	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
 	generic "github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
 	linux "github.com/lf-edge/eve/pkg/pillar/nireconciler/linuxitems"
+	"github.com/lf-edge/eve/pkg/pillar/procsupervisor"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	fileutils "github.com/lf-edge/eve/pkg/pillar/utils/file"
 	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
@@ -40,6 +42,9 @@ const (
 	intendedStateFile = "/run/zedrouter-intended-state.dot"
 	// Directory with references to named network namespaces.
 	namedNsDir = "/var/run/netns"
+	// For how long the carrier of a guest-facing VIF is kept down when
+	// bouncing the link state (see RefreshVIFsOnReconnect in the NI config).
+	vifCarrierBounceTime = 200 * time.Millisecond
 )
 
 var emptyUUID = uuid.UUID{} // used as a constant
@@ -53,6 +58,13 @@ type LinuxNIReconciler struct {
 	logger          *logrus.Logger
 	netMonitor      netmonitor.NetworkMonitor
 	metadataHandler http.Handler
+	// Optional; when defined, daemons spawned by the reconciler (dnsmasq)
+	// are registered with the supervisor for liveness tracking and automatic
+	// restarts.
+	procSupervisor *procsupervisor.ProcSupervisor
+	// Rate-limited logger used for errors triggered by an invalid user
+	// configuration, which tend to repeat with every reconciliation.
+	rlLog *netlog.RateLimitedLogger
 
 	exportCurrentState       bool
 	exportIntendedState      bool
@@ -117,6 +129,23 @@ type niInfo struct {
 	brIfName string
 	deleted  bool
 	status   NIReconcileStatus
+	// defRouteMetric : explicit metric assigned to default routes copied
+	// from the main routing table into the per-NI routing table.
+	defRouteMetric int
+	// mbbPrevUplink : non-zero IfName marks an ongoing make-before-break
+	// switch of the NI uplink - routes of the previous uplink are temporarily
+	// retained in the intended state, with the original (worse) metric kept
+	// for the default route, until routes of the newly selected uplink
+	// are created (see UpdateNI).
+	mbbPrevUplink Uplink
+	// mbbPrevMetric : metric of the default route of the previous uplink,
+	// valid while mbbPrevUplink is defined.
+	mbbPrevMetric int
+	// refreshVIFs : request to bounce the carrier of the guest-facing VIFs
+	// of this NI during the next reconciliation (see RefreshVIFsOnReconnect
+	// in the NI config). Set after significant NI events, such as a change
+	// of the uplink port.
+	refreshVIFs bool
 }
 
 type appInfo struct {
@@ -154,18 +183,30 @@ type vifInfo struct {
 }
 
 // NewLinuxNIReconciler is a constructor for LinuxNIReconciler.
+// procSupervisor is optional; when defined, daemons spawned by the reconciler
+// (dnsmasq) are supervised and automatically restarted should they crash.
+// rlLog is optional; when defined, it is used to rate-limit errors triggered
+// by an invalid user configuration (which tend to repeat with every
+// reconciliation), otherwise the reconciler creates its own instance.
 // Enable exportCurrentState to have the current state exported to currentStateFile
 // on every change.
 // Enable exportIntendedState to have the intended state exported to intendedStateFile
 // on every change.
 func NewLinuxNIReconciler(log *base.LogObject, logger *logrus.Logger,
 	netMonitor netmonitor.NetworkMonitor, metadataHandler http.Handler,
+	procSupervisor *procsupervisor.ProcSupervisor,
+	rlLog *netlog.RateLimitedLogger,
 	exportCurrentState, exportIntendedState, withKubernetesNetworking bool) *LinuxNIReconciler {
+	if rlLog == nil {
+		rlLog = netlog.NewRateLimitedLogger(log, netlog.DefaultBurst, netlog.DefaultWindow)
+	}
 	return &LinuxNIReconciler{
 		log:                      log,
 		logger:                   logger,
 		netMonitor:               netMonitor,
 		metadataHandler:          metadataHandler,
+		procSupervisor:           procSupervisor,
+		rlLog:                    rlLog,
 		exportCurrentState:       exportCurrentState,
 		exportIntendedState:      exportIntendedState,
 		withKubernetesNetworking: withKubernetesNetworking,
@@ -191,7 +232,8 @@ func (r *LinuxNIReconciler) init() (startWatcher func()) {
 		r.log.Fatalf("%s: Already initialized", LogAndErrPrefix)
 	}
 	registry := &reconciler.DefaultRegistry{}
-	if err := generic.RegisterItems(r.log, r.logger, registry); err != nil {
+	if err := generic.RegisterItems(r.log, r.logger, registry,
+		r.procSupervisor); err != nil {
 		r.log.Fatal(err)
 	}
 	if err := linux.RegisterItems(r.log, registry, r.netMonitor); err != nil {
@@ -296,6 +338,12 @@ func (r *LinuxNIReconciler) runWatcher(netEvents <-chan netmonitor.Event) {
 							needReconcile = true
 						}
 					}
+					// Presence of the uplink default route determines the NI
+					// connectivity status mirrored into the current state
+					// (see generic.NIConn).
+					if r.updateCurrentGlobalState(true) {
+						needReconcile = true
+					}
 				}
 
 			case netmonitor.IfChange:
@@ -518,9 +566,78 @@ func (r *LinuxNIReconciler) reconcile(ctx context.Context) (updates []Reconciler
 			}
 		}
 	}
+
+	// Stage 5: Refresh the link state of guest-facing VIFs of NIs which went
+	// through a significant event (uplink change, bridge re-creation), so that
+	// guests re-ARP and refresh their DHCP state quickly.
+	for niID, ni := range r.nis {
+		if !ni.config.RefreshVIFsOnReconnect || ni.deleted {
+			continue
+		}
+		if !ni.refreshVIFs {
+			// A delete operation recorded for the (still existing) NI bridge
+			// means that the bridge was re-created during this reconciliation.
+			for _, log := range rs.OperationLog {
+				bridge, isBridge := log.Item.(linux.Bridge)
+				if isBridge && bridge.IfName == ni.brIfName &&
+					log.Operation == reconciler.OperationDelete {
+					ni.refreshVIFs = true
+					break
+				}
+			}
+		}
+		if ni.refreshVIFs {
+			ni.refreshVIFs = false
+			r.refreshVIFLinks(niID)
+		}
+	}
 	return updates
 }
 
+// refreshVIFLinks briefly bounces the carrier of every guest-facing VIF
+// connected to the given NI. Run asynchronously so that the reconciliation
+// is not delayed by the time the links are kept down.
+func (r *LinuxNIReconciler) refreshVIFLinks(niID uuid.UUID) {
+	var vifIfNames []string
+	for _, app := range r.apps {
+		if app.deleted {
+			continue
+		}
+		for _, vif := range app.vifs {
+			if vif.NI == niID && vif.hostIfName != "" {
+				vifIfNames = append(vifIfNames, vif.hostIfName)
+			}
+		}
+	}
+	if len(vifIfNames) == 0 {
+		return
+	}
+	r.log.Noticef("%s: Refreshing link state of VIFs %v of NI %v",
+		LogAndErrPrefix, vifIfNames, niID)
+	go func() {
+		for _, vifIfName := range vifIfNames {
+			link, err := netlink.LinkByName(vifIfName)
+			if err != nil {
+				r.log.Warnf("%s: refreshVIFLinks: failed to get link %s: %v",
+					LogAndErrPrefix, vifIfName, err)
+				continue
+			}
+			if err := netlink.LinkSetDown(link); err != nil {
+				r.log.Warnf("%s: refreshVIFLinks: failed to set link %s DOWN: %v",
+					LogAndErrPrefix, vifIfName, err)
+				continue
+			}
+			// Keep the link down long enough for the guest to notice
+			// the carrier loss.
+			time.Sleep(vifCarrierBounceTime)
+			if err := netlink.LinkSetUp(link); err != nil {
+				r.log.Warnf("%s: refreshVIFLinks: failed to set link %s UP: %v",
+					LogAndErrPrefix, vifIfName, err)
+			}
+		}
+	}()
+}
+
 func (r *LinuxNIReconciler) logReconciliation(rs reconciler.Status,
 	reconcileStartTime time.Time) {
 	// Log every executed operation.
@@ -766,6 +883,9 @@ func (r *LinuxNIReconciler) RunInitialReconcile(ctx context.Context) {
 	defer contWatcher()
 	// Initial state after a boot.
 	r.updateCurrentGlobalState(false)
+	// Remove artifacts left behind in the network stack by a previous
+	// (crashed) run before building the new configuration on top of them.
+	r.cleanupOrphanedArtifacts(ctx)
 	// Build and reconcile the global configuration (primarily for BlackHole config).
 	r.scheduleGlobalCfgRebuild("initial reconciliation")
 	updates := r.reconcile(ctx)
@@ -820,9 +940,10 @@ func (r *LinuxNIReconciler) AddNI(ctx context.Context,
 		return niStatus, err
 	}
 	r.nis[niID] = &niInfo{
-		config:   niConfig,
-		bridge:   br,
-		brIfName: brIfName,
+		config:         niConfig,
+		bridge:         br,
+		brIfName:       brIfName,
+		defRouteMetric: niDefRouteBaseMetric,
 	}
 	reconcileReason := fmt.Sprintf("adding new NI (%v)", niID)
 	// Rebuild and reconcile also global config to update the set of intended/current
@@ -850,8 +971,33 @@ func (r *LinuxNIReconciler) UpdateNI(ctx context.Context,
 		return niStatus, fmt.Errorf("%s: Cannot update NI %v: does not exist",
 			LogAndErrPrefix, niID)
 	}
+	prevUplink := r.nis[niID].bridge.Uplink
 	r.nis[niID].config = niConfig
 	r.nis[niID].bridge = br
+	if niConfig.RefreshVIFsOnReconnect &&
+		prevUplink.IfName != br.Uplink.IfName {
+		// Let the guests know that their connectivity has significantly
+		// changed and that they should refresh ARP/DHCP state.
+		r.nis[niID].refreshVIFs = true
+	}
+	if niConfig.Type == types.NetworkInstanceTypeLocal &&
+		prevUplink.IfName != "" && br.Uplink.IfName != "" &&
+		prevUplink.IfName != br.Uplink.IfName {
+		// Uplink selection changed - switch default routes of the NI routing
+		// table using the make-before-break approach: first create routes
+		// of the new uplink, with a better metric assigned to the default
+		// route, then remove routes of the previous uplink (see the second
+		// reconciliation run below). This avoids transient datapath gaps.
+		r.nis[niID].mbbPrevUplink = prevUplink
+		r.nis[niID].mbbPrevMetric = r.nis[niID].defRouteMetric
+		if r.nis[niID].defRouteMetric <= niDefRouteLowestMetric {
+			// Wrap around (the previous default route will be preferred
+			// for the short duration of the switch).
+			r.nis[niID].defRouteMetric = niDefRouteBaseMetric
+		} else {
+			r.nis[niID].defRouteMetric--
+		}
+	}
 	// Re-generate bridge interface name to support change in the select uplink port
 	// for switch network instances.
 	brIfName, err := r.generateBridgeIfName(niConfig, br)
@@ -869,6 +1015,19 @@ func (r *LinuxNIReconciler) UpdateNI(ctx context.Context,
 	r.scheduleNICfgRebuild(niID, reconcileReason)
 	updates := r.reconcile(ctx)
 	r.publishReconcilerUpdates(updates...)
+	if r.nis[niID].mbbPrevUplink.IfName != "" {
+		// The "break" phase of the make-before-break uplink switch:
+		// routes of the new uplink are in place, remove those of the previous
+		// uplink.
+		r.nis[niID].mbbPrevUplink = Uplink{}
+		reconcileReason = fmt.Sprintf(
+			"removing routes of the previous uplink of NI (%v)", niID)
+		r.updateCurrentGlobalState(true) // uplinks only
+		r.scheduleGlobalCfgRebuild(reconcileReason)
+		r.scheduleNICfgRebuild(niID, reconcileReason)
+		updates = r.reconcile(ctx)
+		r.publishReconcilerUpdates(updates...)
+	}
 	niStatus = r.nis[niID].status
 	return niStatus, nil
 }
@@ -1019,6 +1178,8 @@ func (r *LinuxNIReconciler) DelAppConn(ctx context.Context,
 		return appStatus, fmt.Errorf("%s: Cannot disconnect App %v: does not exist",
 			LogAndErrPrefix, appID)
 	}
+	startTime := time.Now()
+	appNum := r.apps[appID].appNum
 	// Deleted from the map when removal is completed successfully (incl. async ops).
 	r.apps[appID].deleted = true
 	reconcileReason := fmt.Sprintf("disconnecting app (%v)", appID)
@@ -1030,6 +1191,10 @@ func (r *LinuxNIReconciler) DelAppConn(ctx context.Context,
 	}
 	updates := r.reconcile(ctx)
 	r.publishReconcilerUpdates(updates...)
+	// With the app's ACL chains gone, also remove the conntrack entries of its
+	// flows. Deletion is targeted by the application ID bits of the connection
+	// mark, avoiding a conntrack scan per removed ACL rule.
+	r.flushAppConntrack(appID, appNum)
 	// r.apps[appID] could be deleted by this point
 	for _, update := range updates {
 		if update.UpdateType == AppConnReconcileStatusChanged &&
@@ -1037,9 +1202,35 @@ func (r *LinuxNIReconciler) DelAppConn(ctx context.Context,
 			appStatus = *update.AppConnStatus
 		}
 	}
+	appStatus.TeardownTime = time.Since(startTime)
+	r.log.Noticef("%s: Teardown of app %v connectivity took %v",
+		LogAndErrPrefix, appID, appStatus.TeardownTime)
 	return appStatus, nil
 }
 
+// flushAppConntrack removes all conntrack entries of flows established from/to
+// the given application, matched by the application ID bits of the connection
+// mark (see iptables.GetConnmark).
+func (r *LinuxNIReconciler) flushAppConntrack(appID uuid.UUID, appNum int) {
+	filter := conntrack.MarkFilter{
+		Mark:     iptables.GetConnmark(uint8(appNum), 0, false, false),
+		MarkMask: iptables.AppIDMask,
+	}
+	for _, family := range []netlink.InetFamily{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		flowsDeleted, err := netlink.ConntrackDeleteFilter(
+			netlink.ConntrackTable, family, filter)
+		if err != nil {
+			r.log.Errorf("%s: flushAppConntrack: failed to clear flows of app %v: %v",
+				LogAndErrPrefix, appID, err)
+			continue
+		}
+		if flowsDeleted > 0 {
+			r.log.Noticef("%s: flushAppConntrack: cleared %d flows of app %v",
+				LogAndErrPrefix, flowsDeleted, appID)
+		}
+	}
+}
+
 // GetAppConnStatus : get current status of app connectivity.
 func (r *LinuxNIReconciler) GetAppConnStatus(appID uuid.UUID) (AppConnReconcileStatus, error) {
 	contWatcher := r.pauseWatcher()
@@ -1059,6 +1250,42 @@ func (r *LinuxNIReconciler) GetAppConnStatus(appID uuid.UUID) (AppConnReconcileS
 	return appStatus, nil
 }
 
+// GetInstalledACLRuleCount returns the total number of iptables/ip6tables rules
+// currently installed for the given network instance, including rules created
+// for ACLs of the connected applications.
+func (r *LinuxNIReconciler) GetInstalledACLRuleCount(niID uuid.UUID) uint32 {
+	contWatcher := r.pauseWatcher()
+	defer contWatcher()
+	currSG := r.currentState.SubGraph(NIToSGName(niID))
+	if currSG == nil {
+		return 0
+	}
+	var ruleCount uint32
+	iter := currSG.Items(true)
+	for iter.Next() {
+		item, _ := iter.Item()
+		switch item.Type() {
+		case iptables.RuleV4Typename, iptables.RuleV6Typename:
+			ruleCount++
+		}
+	}
+	return ruleCount
+}
+
+// GetNAT64MappingCount returns the number of IPv4 addresses from the NAT64
+// pool currently dynamically mapped to IPv6 hosts by the NAT64 translator
+// of the given network instance. Returns zero if NAT64 is not enabled.
+func (r *LinuxNIReconciler) GetNAT64MappingCount(niID uuid.UUID) uint32 {
+	contWatcher := r.pauseWatcher()
+	defer contWatcher()
+	ni, exists := r.nis[niID]
+	if !exists || !r.nat64Enabled(ni) {
+		return 0
+	}
+	tunIfName, _, _, _ := r.nat64Params(ni)
+	return generic.TaygaDynamicMappingCount(tunIfName)
+}
+
 func (r *LinuxNIReconciler) getNIsUsingUplink(ifName string) (nis []*niInfo) {
 	for _, ni := range r.nis {
 		switch ni.config.Type {
@@ -45,7 +45,7 @@ func initTest(test *testing.T, withKube bool) *GomegaWithT {
 		MainRT: unix.RT_TABLE_MAIN,
 	}
 	niReconciler = nirec.NewLinuxNIReconciler(log, logger, networkMonitor, nil,
-		false, false, withKube)
+		nil, nil, false, false, withKube)
 	return t
 }
 
@@ -640,6 +640,24 @@ var (
 						},
 						RuleID: 4,
 					},
+					{
+						Matches: []types.ACEMatch{
+							{
+								Type:  "protocol",
+								Value: "icmp",
+							},
+							{
+								Type:  "icmp-type",
+								Value: "8", // echo-request
+							},
+						},
+						Actions: []types.ACEAction{
+							{
+								Drop: false,
+							},
+						},
+						RuleID: 5,
+					},
 				},
 			},
 		},
@@ -943,6 +961,24 @@ var (
 						},
 						RuleID: 5,
 					},
+					{
+						Matches: []types.ACEMatch{
+							{
+								Type:  "protocol",
+								Value: "icmpv6",
+							},
+							{
+								Type:  "icmp6-type",
+								Value: "128", // echo-request
+							},
+						},
+						Actions: []types.ACEAction{
+							{
+								Drop: false,
+							},
+						},
+						RuleID: 6,
+					},
 				},
 			},
 			{
@@ -1173,6 +1209,15 @@ func TestSingleLocalNI(test *testing.T) {
 		t.Expect(appSG).ToNot(BeNil())
 	}
 
+	// ACL rule 5 limits the allowed ICMP traffic to echo requests.
+	intendedIcmpRule := iptables.Rule{
+		RuleLabel: "User-configured ALLOW ACL rule 5",
+		Table:     "mangle",
+		ChainName: "PREROUTING-nbu1x1-OUT",
+	}
+	t.Expect(itemDescription(dg.Reference(intendedIcmpRule))).To(ContainSubstring(
+		"-p icmp --icmp-type 8 -j bn1-nbu1x1-5"))
+
 	// Simulate uplink losing IP address.
 	ips := eth0.IPAddrs
 	eth0.IPAddrs = nil
@@ -1857,6 +1902,14 @@ func TestIPv6LocalAndSwitchNIs(test *testing.T) {
 	}
 	t.Expect(itemDescription(dg.Reference(vif1IPRule))).To(ContainSubstring(
 		"-d 2610:20:6f96:96::4/128 -j bn3-nbu1x3-3"))
+	vif1IcmpRule := iptables.Rule{
+		RuleLabel: "User-configured ALLOW ACL rule 6",
+		Table:     "mangle",
+		ChainName: "PREROUTING-nbu1x3-OUT",
+		ForIPv6:   true,
+	}
+	t.Expect(itemDescription(dg.Reference(vif1IcmpRule))).To(ContainSubstring(
+		"-p icmpv6 --icmpv6-type 128 -j bn3-nbu1x3-6"))
 	ni3PortMapRuleIn := iptables.Rule{
 		RuleLabel: "User-configured PORTMAP ACL rule 5 for uplink IP 2001::20 from inside",
 		Table:     "nat",
@@ -2493,3 +2546,158 @@ func TestCNI(test *testing.T) {
 	_, err = niReconciler.DelNI(ctx, ni1UUID.UUID)
 	t.Expect(err).ToNot(HaveOccurred())
 }
+
+func TestSwitchNIWithMultiplePorts(test *testing.T) {
+	t := initTest(test, false)
+	networkMonitor.AddOrUpdateInterface(keth1)
+	networkMonitor.AddOrUpdateInterface(eth1)
+	// Extra physical ports to be bridged under the switch NI.
+	// Unlike the uplink, these are not managed (and not bridged) by NIM.
+	eth3 := netmonitor.MockInterface{
+		Attrs: netmonitor.IfAttrs{
+			IfIndex:       21,
+			IfName:        "eth3",
+			IfType:        "device",
+			WithBroadcast: true,
+			AdminUp:       true,
+			LowerUp:       true,
+		},
+		HwAddr: macAddress("02:00:00:00:00:04"),
+	}
+	eth4 := netmonitor.MockInterface{
+		Attrs: netmonitor.IfAttrs{
+			IfIndex:       22,
+			IfName:        "eth4",
+			IfType:        "device",
+			WithBroadcast: true,
+			AdminUp:       true,
+			LowerUp:       true,
+		},
+		HwAddr: macAddress("02:00:00:00:00:05"),
+	}
+	networkMonitor.AddOrUpdateInterface(eth3)
+	networkMonitor.AddOrUpdateInterface(eth4)
+	networkMonitor.UpdateRoutes(eth1Routes)
+	ctx := reconciler.MockRun(context.Background())
+	updatesCh := niReconciler.WatchReconcilerUpdates()
+	niReconciler.RunInitialReconcile(ctx)
+
+	// Create switch network instance with two extra member ports:
+	// eth3 in the access mode for VLAN 100, eth4 left in the trunk mode.
+	ni2MultiPortBridge := ni2Bridge
+	ni2MultiPortBridge.ExtraPorts = []nirec.SwitchPort{
+		{LogicalLabel: "ethernet3", IfName: "eth3", AccessVlanID: 100},
+		{LogicalLabel: "ethernet4", IfName: "eth4"},
+	}
+	niStatus, err := niReconciler.AddNI(ctx, ni2Config, ni2MultiPortBridge)
+	t.Expect(err).ToNot(HaveOccurred())
+	t.Expect(niStatus.NI).To(Equal(ni2UUID.UUID))
+	t.Expect(niStatus.Deleted).To(BeFalse())
+	t.Expect(niStatus.InProgress).To(BeFalse())
+	t.Expect(niStatus.BrIfName).To(Equal("eth1"))
+	t.Expect(niStatus.FailedItems).To(BeEmpty())
+
+	var recUpdate nirec.ReconcilerUpdate
+	t.Eventually(updatesCh).Should(Receive(&recUpdate))
+	t.Expect(recUpdate.UpdateType).To(Equal(nirec.NIReconcileStatusChanged))
+	t.Expect(recUpdate.NIStatus.Equal(niStatus)).To(BeTrue())
+
+	// Both extra ports should be enslaved under the NI bridge by the reconciler
+	// (not by NIM as is the case for the uplink).
+	eth3Port := dg.Reference(linuxitems.BridgePort{
+		BridgeIfName: "eth1",
+		Variant:      linuxitems.BridgePortVariant{PhysIfName: "eth3"},
+	})
+	eth4Port := dg.Reference(linuxitems.BridgePort{
+		BridgeIfName: "eth1",
+		Variant:      linuxitems.BridgePortVariant{PhysIfName: "eth4"},
+	})
+	t.Expect(itemIsCreated(eth3Port)).To(BeTrue())
+	t.Expect(itemIsCreated(eth4Port)).To(BeTrue())
+
+	// eth3 is an access port for VLAN 100, while eth4 and the uplink are trunks
+	// which must carry this VLAN.
+	eth3VLANPort := dg.Reference(linuxitems.VLANPort{
+		BridgeIfName: "eth1",
+		PortIfName:   "eth3",
+	})
+	eth4VLANPort := dg.Reference(linuxitems.VLANPort{
+		BridgeIfName: "eth1",
+		PortIfName:   "eth4",
+	})
+	uplinkVLANPort := dg.Reference(linuxitems.VLANPort{
+		BridgeIfName: "eth1",
+		PortIfName:   "keth1",
+	})
+	t.Expect(itemDescription(eth3VLANPort)).To(ContainSubstring(
+		"accessPort: {vid: 100}"))
+	t.Expect(itemDescription(eth4VLANPort)).To(ContainSubstring(
+		"trunkPort: {allVIDs: false, vids:[100]}"))
+	t.Expect(itemDescription(uplinkVLANPort)).To(ContainSubstring(
+		"trunkPort: {allVIDs: false, vids:[100]}"))
+	t.Expect(itemIsCreated(dg.Reference(
+		linuxitems.VLANBridge{BridgeIfName: "eth1"}))).To(BeTrue())
+
+	// With multiple physical ports bridged, STP is forcibly enabled.
+	t.Expect(itemDescription(dg.Reference(
+		linuxitems.BridgeParams{BridgeIfName: "eth1"}))).To(ContainSubstring(
+		"enableSTP: true"))
+
+	// Delete network instance - the extra ports should be released.
+	_, err = niReconciler.DelNI(ctx, ni2UUID.UUID)
+	t.Expect(err).ToNot(HaveOccurred())
+	t.Expect(itemIsCreated(eth3Port)).To(BeFalse())
+	t.Expect(itemIsCreated(eth4Port)).To(BeFalse())
+
+	networkMonitor.DelInterface("eth3")
+	networkMonitor.DelInterface("eth4")
+}
+
+func TestDHCPGatedOnConnectivity(test *testing.T) {
+	t := initTest(test, false)
+	// Start without the default route for eth0.
+	networkMonitor.AddOrUpdateInterface(eth0)
+	ctx := reconciler.MockRun(context.Background())
+	updatesCh := niReconciler.WatchReconcilerUpdates()
+	niReconciler.RunInitialReconcile(ctx)
+
+	// Create local network instance with DHCP gated on connectivity.
+	gatedNI1Config := ni1Config
+	gatedNI1Config.GateDHCPOnConnectivity = true
+	niStatus, err := niReconciler.AddNI(ctx, gatedNI1Config, ni1Bridge)
+	t.Expect(err).ToNot(HaveOccurred())
+	t.Expect(niStatus.NI).To(Equal(ni1UUID.UUID))
+	t.Expect(niStatus.BrIfName).To(Equal("bn1"))
+	var recUpdate nirec.ReconcilerUpdate
+	t.Eventually(updatesCh).Should(Receive(&recUpdate))
+	t.Expect(recUpdate.UpdateType).To(Equal(nirec.NIReconcileStatusChanged))
+	networkMonitor.AddOrUpdateInterface(ni1BridgeIf)
+
+	// Without the uplink default route the NI has no external connectivity
+	// and the reconciler keeps dnsmasq (DHCP leases) down.
+	dnsmasq := dg.Reference(
+		genericitems.Dnsmasq{ListenIf: genericitems.NetworkIf{IfName: "bn1"}})
+	niConn := dg.Reference(genericitems.NIConn{NI: ni1UUID.UUID})
+	t.Expect(itemIsCreated(niConn)).To(BeFalse())
+	t.Expect(itemIsCreated(dnsmasq)).To(BeFalse())
+
+	// Simulate the uplink receiving the default route.
+	networkMonitor.UpdateRoutes(eth0Routes)
+	t.Eventually(updatesCh).Should(Receive(&recUpdate))
+	niReconciler.ResumeReconcile(ctx)
+
+	t.Expect(itemIsCreated(niConn)).To(BeTrue())
+	t.Expect(itemIsCreated(dnsmasq)).To(BeTrue())
+
+	// With the default route lost, the DHCP gate closes again.
+	networkMonitor.UpdateRoutes(nil)
+	t.Eventually(updatesCh).Should(Receive(&recUpdate))
+	niReconciler.ResumeReconcile(ctx)
+
+	t.Expect(itemIsCreated(niConn)).To(BeFalse())
+	t.Expect(itemIsCreated(dnsmasq)).To(BeFalse())
+
+	// Delete network instance
+	_, err = niReconciler.DelNI(ctx, ni1UUID.UUID)
+	t.Expect(err).ToNot(HaveOccurred())
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package nireconciler
+
+import (
+	"fmt"
+	"time"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/iptables"
+	linux "github.com/lf-edge/eve/pkg/pillar/nireconciler/linuxitems"
+	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
+	uuid "github.com/satori/go.uuid"
+	"github.com/vishvananda/netlink"
+)
+
+// VerifyState audits the actual state of the Linux network stack against
+// the intended configuration of the given network instance.
+// It compares the intended graph with the graph of currently applied items,
+// and for item types which are directly observable in the kernel (links,
+// routes, iptables chains/rules) it additionally re-reads the kernel state
+// to detect changes made behind the back of the reconciler.
+func (r *LinuxNIReconciler) VerifyState(niID uuid.UUID) (StateVerificationReport, error) {
+	r.reconcileMu.Lock()
+	defer r.reconcileMu.Unlock()
+	report := StateVerificationReport{
+		NI:         niID,
+		VerifiedAt: time.Now(),
+	}
+	if _, exists := r.nis[niID]; !exists {
+		return report, fmt.Errorf("%s: VerifyState: unknown network instance %v",
+			LogAndErrPrefix, niID)
+	}
+	sgName := NIToSGName(niID)
+	intSG := r.intendedState.SubGraph(sgName)
+	currSG := r.currentState.SubGraph(sgName)
+
+	// 1. Check that every intended item is applied and matches the intended
+	//    attributes, plus audit the kernel for observable item types.
+	if intSG != nil {
+		iter := intSG.Items(true)
+		for iter.Next() {
+			item, _ := iter.Item()
+			if item.External() {
+				// Not managed by the reconciler.
+				continue
+			}
+			ref := dg.Reference(item)
+			currItem, state, _, found := r.currentState.Item(ref)
+			if !found || (state != nil && !state.IsCreated()) {
+				report.Discrepancies = append(report.Discrepancies,
+					StateDiscrepancy{
+						Item: ref,
+						Type: MissingItem,
+						Description: "item from the intended state " +
+							"is not applied in the network stack",
+					})
+				continue
+			}
+			if !currItem.Equal(item) {
+				report.Discrepancies = append(report.Discrepancies,
+					StateDiscrepancy{
+						Item: ref,
+						Type: MismatchedItem,
+						Description: "attributes of the applied item differ " +
+							"from the intended configuration",
+					})
+				continue
+			}
+			if inKernel, detail, auditable := r.auditItemInKernel(item); auditable && !inKernel {
+				report.Discrepancies = append(report.Discrepancies,
+					StateDiscrepancy{
+						Item:        ref,
+						Type:        MissingItem,
+						Description: detail,
+					})
+			}
+		}
+	}
+
+	// 2. Check for applied items which are not part of the intended state.
+	if currSG != nil {
+		iter := currSG.Items(true)
+		for iter.Next() {
+			item, _ := iter.Item()
+			if item.External() {
+				continue
+			}
+			// Ignore extra routes added by the kernel.
+			if route, isRoute := item.(linux.Route); isRoute {
+				if route.Dst != nil && route.Dst.IP.IsLinkLocalUnicast() {
+					continue
+				}
+			}
+			ref := dg.Reference(item)
+			if _, _, _, found := r.intendedState.Item(ref); !found {
+				report.Discrepancies = append(report.Discrepancies,
+					StateDiscrepancy{
+						Item: ref,
+						Type: UnexpectedItem,
+						Description: "item applied in the network stack " +
+							"is not part of the intended state",
+					})
+			}
+		}
+	}
+	return report, nil
+}
+
+// auditItemInKernel re-reads the kernel state for item types which are
+// directly observable. Returns auditable=false for item types which cannot
+// be (easily) verified against the kernel.
+func (r *LinuxNIReconciler) auditItemInKernel(item dg.Item) (
+	inKernel bool, detail string, auditable bool) {
+	switch it := item.(type) {
+	case linux.Bridge:
+		_, exists, _ := r.netMonitor.GetInterfaceIndex(it.IfName)
+		return exists, fmt.Sprintf("bridge interface %s does not exist "+
+			"in the kernel", it.IfName), true
+	case linux.Route:
+		if it.ForApp.ID != emptyUUID {
+			// Route inside an app network namespace, cannot audit from here.
+			return false, "", false
+		}
+		return r.routeExistsInKernel(it), fmt.Sprintf("route for %v is not "+
+			"present in the kernel routing table %d", it.Dst, it.Table), true
+	case iptables.Chain:
+		return it.ExistsInKernel(), fmt.Sprintf("%s does not exist "+
+			"in the kernel", it.String()), true
+	case iptables.Rule:
+		return it.InstalledInKernel(), fmt.Sprintf("%s is not installed "+
+			"in the kernel", it.String()), true
+	}
+	return false, "", false
+}
+
+// routeExistsInKernel checks if the given route is present in the kernel
+// routing table. If the kernel state cannot be read, the route is assumed
+// to be present to avoid false positives.
+func (r *LinuxNIReconciler) routeExistsInKernel(route linux.Route) bool {
+	family := netlink.FAMILY_V4
+	if route.Dst != nil && route.Dst.IP.To4() == nil {
+		family = netlink.FAMILY_V6
+	}
+	routes, err := netlink.RouteListFiltered(family,
+		&netlink.Route{Table: route.Table}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		r.log.Warnf("%s: routeExistsInKernel: RouteListFiltered failed: %v",
+			LogAndErrPrefix, err)
+		return true
+	}
+	for i := range routes {
+		if netutils.EqualIPNets(routes[i].Dst, route.Dst) &&
+			routes[i].Gw.Equal(route.Gw) {
+			return true
+		}
+	}
+	return false
+}
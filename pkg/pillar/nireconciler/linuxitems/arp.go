@@ -0,0 +1,157 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/vishvananda/netlink"
+)
+
+// Arp : static ARP (neighbor) entry installed on the NI bridge.
+// Used together with proxy-ARP (see NetworkInstanceConfig.EnableARPProxy)
+// so that the device is able to deliver routed traffic to an application
+// VIF without depending on dynamic neighbor resolution.
+type Arp struct {
+	// BridgeIfName : name of the NI bridge on which the entry is installed.
+	BridgeIfName string
+	// IPAddr : IP address of the neighbor (application VIF).
+	IPAddr net.IP
+	// HwAddr : MAC address of the neighbor (application VIF).
+	HwAddr net.HardwareAddr
+}
+
+// Name combines the bridge name with the IP address to create
+// a unique identifier for the ARP entry.
+func (a Arp) Name() string {
+	return fmt.Sprintf("%s/%v", a.BridgeIfName, a.IPAddr)
+}
+
+// Label is more human-readable than name.
+func (a Arp) Label() string {
+	return fmt.Sprintf("ARP entry %v / %v on bridge %s",
+		a.IPAddr, a.HwAddr, a.BridgeIfName)
+}
+
+// Type of the item.
+func (a Arp) Type() string {
+	return ArpTypename
+}
+
+// Equal is a comparison method for two equally-named Arp instances.
+func (a Arp) Equal(other dg.Item) bool {
+	a2, isArp := other.(Arp)
+	if !isArp {
+		return false
+	}
+	return bytes.Equal(a.HwAddr, a2.HwAddr)
+}
+
+// External returns false.
+func (a Arp) External() bool {
+	return false
+}
+
+// String describes the ARP entry.
+func (a Arp) String() string {
+	return fmt.Sprintf("ARP entry on bridge %s; IP: %v; MAC: %v",
+		a.BridgeIfName, a.IPAddr, a.HwAddr)
+}
+
+// Dependencies returns the bridge as the only dependency.
+func (a Arp) Dependencies() (deps []dg.Dependency) {
+	return []dg.Dependency{
+		{
+			RequiredItem: dg.ItemRef{
+				ItemType: BridgeTypename,
+				ItemName: a.BridgeIfName,
+			},
+			Description: "Bridge referenced by the ARP entry must exist",
+		},
+	}
+}
+
+// ArpConfigurator implements Configurator interface (libs/reconciler)
+// for static ARP entries.
+type ArpConfigurator struct {
+	Log *base.LogObject
+}
+
+// Create installs the ARP entry.
+func (c *ArpConfigurator) Create(ctx context.Context, item dg.Item) error {
+	arp, isArp := item.(Arp)
+	if !isArp {
+		return fmt.Errorf("invalid item type %T, expected Arp", item)
+	}
+	link, err := netlink.LinkByName(arp.BridgeIfName)
+	if err != nil {
+		err = fmt.Errorf("failed to get bridge %s: %w", arp.BridgeIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	err = netlink.NeighSet(&netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       neighFamily(arp.IPAddr),
+		State:        netlink.NUD_PERMANENT,
+		IP:           arp.IPAddr,
+		HardwareAddr: arp.HwAddr,
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to set neighbor entry %v->%v on %s: %w",
+			arp.IPAddr, arp.HwAddr, arp.BridgeIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *ArpConfigurator) Modify(ctx context.Context, oldItem, newItem dg.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete removes the ARP entry.
+func (c *ArpConfigurator) Delete(ctx context.Context, item dg.Item) error {
+	arp, isArp := item.(Arp)
+	if !isArp {
+		return fmt.Errorf("invalid item type %T, expected Arp", item)
+	}
+	link, err := netlink.LinkByName(arp.BridgeIfName)
+	if err != nil {
+		// Bridge is already gone together with its neighbor entries.
+		c.Log.Warnf("failed to get bridge %s: %v (assuming the ARP entry "+
+			"%v is already removed)", arp.BridgeIfName, err, arp.IPAddr)
+		return nil
+	}
+	err = netlink.NeighDel(&netlink.Neigh{
+		LinkIndex: link.Attrs().Index,
+		Family:    neighFamily(arp.IPAddr),
+		IP:        arp.IPAddr,
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to remove neighbor entry %v from %s: %w",
+			arp.IPAddr, arp.BridgeIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns true - Modify is not implemented.
+func (c *ArpConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
+	return true
+}
+
+func neighFamily(ip net.IP) int {
+	if ip.To4() == nil {
+		return netlink.FAMILY_V6
+	}
+	return netlink.FAMILY_V4
+}
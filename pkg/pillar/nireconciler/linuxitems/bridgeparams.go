@@ -0,0 +1,171 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+const (
+	// Kernel defaults for the bridge parameters, restored on Delete.
+	defaultSTPState          = false
+	defaultAgeingTimeSecs    = 300
+	defaultMulticastSnooping = true
+)
+
+// BridgeParams : sysfs-exposed parameters of a NI bridge (STP state, FDB
+// ageing time, multicast snooping). Modeled as a reconcilable item so that
+// the parameters are reapplied whenever the bridge is recreated instead
+// of silently falling back to the kernel defaults.
+type BridgeParams struct {
+	// BridgeIfName : name of the bridge interface inside the network stack.
+	BridgeIfName string
+	// EnableSTP : run the Spanning Tree Protocol on the bridge.
+	EnableSTP bool
+	// AgeingTime : time in seconds after which an inactive entry is removed
+	// from the bridge forwarding database. Zero means the kernel default
+	// (300 seconds).
+	AgeingTime uint32
+	// MulticastSnooping : enable IGMP/MLD snooping on the bridge.
+	// With snooping enabled, the bridge forwards multicast traffic only
+	// to ports with members of the target multicast group (learned from
+	// IGMP/MLD reports) instead of flooding it to all ports.
+	MulticastSnooping bool
+}
+
+// Name returns the name of the bridge on which the parameters are configured.
+func (p BridgeParams) Name() string {
+	return p.BridgeIfName
+}
+
+// Label is not provided.
+func (p BridgeParams) Label() string {
+	return ""
+}
+
+// Type of the item.
+func (p BridgeParams) Type() string {
+	return BridgeParamsTypename
+}
+
+// Equal compares two BridgeParams instances.
+func (p BridgeParams) Equal(other dg.Item) bool {
+	p2, isBridgeParams := other.(BridgeParams)
+	if !isBridgeParams {
+		return false
+	}
+	return p == p2
+}
+
+// External returns false.
+func (p BridgeParams) External() bool {
+	return false
+}
+
+// String describes BridgeParams.
+func (p BridgeParams) String() string {
+	return fmt.Sprintf("BridgeParams: {bridgeIfName: %s, enableSTP: %t, "+
+		"ageingTime: %d, multicastSnooping: %t}",
+		p.BridgeIfName, p.EnableSTP, p.AgeingTime, p.MulticastSnooping)
+}
+
+// Dependencies returns the bridge as the only dependency.
+func (p BridgeParams) Dependencies() (deps []dg.Dependency) {
+	return []dg.Dependency{
+		{
+			RequiredItem: dg.ItemRef{
+				ItemType: BridgeTypename,
+				ItemName: p.BridgeIfName,
+			},
+			Description: "Bridge must exist",
+		},
+	}
+}
+
+// BridgeParamsConfigurator implements Configurator interface (libs/reconciler)
+// for sysfs-based bridge parameters.
+type BridgeParamsConfigurator struct {
+	Log *base.LogObject
+}
+
+// Create applies the intended bridge parameters.
+func (c *BridgeParamsConfigurator) Create(ctx context.Context, item dg.Item) error {
+	params, isBridgeParams := item.(BridgeParams)
+	if !isBridgeParams {
+		return fmt.Errorf("invalid item type %T, expected BridgeParams", item)
+	}
+	return c.applyParams(params)
+}
+
+// Modify updates the bridge parameters.
+func (c *BridgeParamsConfigurator) Modify(
+	ctx context.Context, oldItem, newItem dg.Item) error {
+	params, isBridgeParams := newItem.(BridgeParams)
+	if !isBridgeParams {
+		return fmt.Errorf("invalid item type %T, expected BridgeParams", newItem)
+	}
+	return c.applyParams(params)
+}
+
+// Delete restores the kernel defaults.
+func (c *BridgeParamsConfigurator) Delete(ctx context.Context, item dg.Item) error {
+	params, isBridgeParams := item.(BridgeParams)
+	if !isBridgeParams {
+		return fmt.Errorf("invalid item type %T, expected BridgeParams", item)
+	}
+	return c.applyParams(BridgeParams{
+		BridgeIfName:      params.BridgeIfName,
+		EnableSTP:         defaultSTPState,
+		AgeingTime:        defaultAgeingTimeSecs,
+		MulticastSnooping: defaultMulticastSnooping,
+	})
+}
+
+func (c *BridgeParamsConfigurator) applyParams(params BridgeParams) error {
+	stpState := "0"
+	if params.EnableSTP {
+		stpState = "1"
+	}
+	if err := c.writeParam(params.BridgeIfName, "stp_state", stpState); err != nil {
+		return err
+	}
+	ageingTime := params.AgeingTime
+	if ageingTime == 0 {
+		ageingTime = defaultAgeingTimeSecs
+	}
+	// The sysfs attribute is in units of 1/100th of a second.
+	err := c.writeParam(params.BridgeIfName, "ageing_time",
+		strconv.FormatUint(uint64(ageingTime)*100, 10))
+	if err != nil {
+		return err
+	}
+	snooping := "0"
+	if params.MulticastSnooping {
+		snooping = "1"
+	}
+	return c.writeParam(params.BridgeIfName, "multicast_snooping", snooping)
+}
+
+func (c *BridgeParamsConfigurator) writeParam(
+	brIfName, param, value string) error {
+	sysfsPath := fmt.Sprintf("/sys/class/net/%s/bridge/%s", brIfName, param)
+	if err := os.WriteFile(sysfsPath, []byte(value), 0644); err != nil {
+		err = fmt.Errorf("failed to set %s (%s) for bridge %s: %w",
+			param, value, brIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns false - Modify is able to apply any change.
+func (c *BridgeParamsConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
+	return false
+}
@@ -32,6 +32,10 @@ type BridgePortVariant struct {
 	UplinkIfName string
 	// VIFIfName : bridged VIF.
 	VIFIfName string
+	// PhysIfName : bridged physical interface which is not under the NIM
+	// management (an extra member port of a switch NI). Unlike for uplinks,
+	// the enslaving of the port is done by the configurator itself.
+	PhysIfName string
 }
 
 // Name returns the interface name of the bridged port
@@ -111,6 +115,19 @@ func (p BridgePort) Dependencies() (deps []dg.Dependency) {
 				AutoDeletedByExternal: true,
 			},
 		})
+	} else if p.Variant.PhysIfName != "" {
+		// No MustSatisfy for the master - the configurator itself enslaves
+		// the port under the bridge.
+		deps = append(deps, dg.Dependency{
+			RequiredItem: dg.ItemRef{
+				ItemType: generic.UplinkTypename,
+				ItemName: p.Variant.PhysIfName,
+			},
+			Description: "Physical port must exist",
+			Attributes: dg.DependencyAttributes{
+				AutoDeletedByExternal: true,
+			},
+		})
 	}
 	return deps
 }
@@ -122,6 +139,9 @@ func (p BridgePort) portIfName() string {
 	if p.Variant.UplinkIfName != "" {
 		return p.Variant.UplinkIfName
 	}
+	if p.Variant.PhysIfName != "" {
+		return p.Variant.PhysIfName
+	}
 	return ""
 }
 
@@ -0,0 +1,153 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	generic "github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
+)
+
+// ConntrackTimeoutPolicy : a named connection tracking timeout policy
+// (nfnetlink_cttimeout), overriding the global nf_conntrack timeout defaults
+// for flows attached to the policy. Flows are attached using iptables rules
+// with the CT target and the "--timeout" option referencing the policy
+// by its name.
+type ConntrackTimeoutPolicy struct {
+	// PolicyName : name of the timeout policy, referenced by iptables
+	// CT rules.
+	PolicyName string
+	// Protocol : one of "tcp", "udp", "icmp", "icmpv6".
+	Protocol string
+	// ForIPv6 : if true the policy applies to IPv6 flows (inet6 family).
+	ForIPv6 bool
+	// Timeout : timeout in seconds. For TCP it is applied to the established
+	// state, for UDP to both unreplied and replied states and for ICMP(v6)
+	// to the request/reply entries.
+	Timeout uint32
+}
+
+// Name returns the policy name.
+func (p ConntrackTimeoutPolicy) Name() string {
+	return p.PolicyName
+}
+
+// Label is the same as the name.
+func (p ConntrackTimeoutPolicy) Label() string {
+	return p.PolicyName
+}
+
+// Type of the item.
+func (p ConntrackTimeoutPolicy) Type() string {
+	return generic.CTTimeoutPolicyTypename
+}
+
+// Equal compares two ConntrackTimeoutPolicy instances.
+func (p ConntrackTimeoutPolicy) Equal(other dg.Item) bool {
+	p2, isCTTimeoutPolicy := other.(ConntrackTimeoutPolicy)
+	if !isCTTimeoutPolicy {
+		return false
+	}
+	return p == p2
+}
+
+// External returns false.
+func (p ConntrackTimeoutPolicy) External() bool {
+	return false
+}
+
+// String describes the timeout policy.
+func (p ConntrackTimeoutPolicy) String() string {
+	return fmt.Sprintf("ConntrackTimeoutPolicy: {policyName: %s, protocol: %s, "+
+		"forIPv6: %t, timeout: %d}", p.PolicyName, p.Protocol, p.ForIPv6, p.Timeout)
+}
+
+// Dependencies returns nothing - timeout policy is self-contained.
+func (p ConntrackTimeoutPolicy) Dependencies() (deps []dg.Dependency) {
+	return nil
+}
+
+func (p ConntrackTimeoutPolicy) family() string {
+	if p.ForIPv6 {
+		return "inet6"
+	}
+	return "inet"
+}
+
+// ConntrackTimeoutPolicyConfigurator implements Configurator interface
+// (libs/reconciler) for conntrack timeout policies, managed using the nfct
+// command line tool from conntrack-tools.
+type ConntrackTimeoutPolicyConfigurator struct {
+	Log *base.LogObject
+}
+
+const nfctCmd = "nfct"
+
+// Create adds the timeout policy.
+func (c *ConntrackTimeoutPolicyConfigurator) Create(
+	ctx context.Context, item dg.Item) error {
+	policy, isCTTimeoutPolicy := item.(ConntrackTimeoutPolicy)
+	if !isCTTimeoutPolicy {
+		return fmt.Errorf("invalid item type %T, expected ConntrackTimeoutPolicy",
+			item)
+	}
+	timeout := strconv.FormatUint(uint64(policy.Timeout), 10)
+	args := []string{"timeout", "add", policy.PolicyName, policy.family()}
+	switch policy.Protocol {
+	case "tcp":
+		args = append(args, "tcp", "established", timeout)
+	case "udp":
+		args = append(args, "udp", "unreplied", timeout, "replied", timeout)
+	case "icmp", "icmpv6":
+		args = append(args, policy.Protocol, "timeout", timeout)
+	default:
+		return fmt.Errorf("unsupported protocol %s for conntrack timeout policy %s",
+			policy.Protocol, policy.PolicyName)
+	}
+	if output, err := base.Exec(c.Log, nfctCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to add conntrack timeout policy %s: %s (err: %w)",
+			policy.PolicyName, outputStr, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *ConntrackTimeoutPolicyConfigurator) Modify(
+	ctx context.Context, oldItem, newItem dg.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete removes the timeout policy.
+func (c *ConntrackTimeoutPolicyConfigurator) Delete(
+	ctx context.Context, item dg.Item) error {
+	policy, isCTTimeoutPolicy := item.(ConntrackTimeoutPolicy)
+	if !isCTTimeoutPolicy {
+		return fmt.Errorf("invalid item type %T, expected ConntrackTimeoutPolicy",
+			item)
+	}
+	args := []string{"timeout", "delete", policy.PolicyName}
+	if output, err := base.Exec(c.Log, nfctCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to delete conntrack timeout policy %s: %s (err: %w)",
+			policy.PolicyName, outputStr, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns true - Modify is not implemented.
+func (c *ConntrackTimeoutPolicyConfigurator) NeedsRecreate(
+	oldItem, newItem dg.Item) (recreate bool) {
+	return true
+}
@@ -5,15 +5,13 @@ package linuxitems
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"strings"
 
 	dg "github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/ipsets"
 	generic "github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
 	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
-	"github.com/vishvananda/netlink"
 )
 
 // IPSet : Linux ipset (https://ipset.netfilter.org/index.html).
@@ -78,71 +76,61 @@ func (s IPSet) Dependencies() (deps []dg.Dependency) {
 
 // IPSetConfigurator implements Configurator interface (libs/reconciler)
 // for Linux ipset (from netfilter).
+// All the actual ipset operations are delegated to the shared ipset
+// manager (see pillar/ipsets), which serializes concurrent access from
+// multiple reconcilers and reference-counts sets with multiple users.
 type IPSetConfigurator struct {
 	Log *base.LogObject
 }
 
-const ipsetCmd = "ipset"
-
-// Create adds new ipset.
+// Create adds new ipset (or registers another user of an existing one).
 func (c *IPSetConfigurator) Create(ctx context.Context, item dg.Item) error {
 	ipset, isIPSet := item.(IPSet)
 	if !isIPSet {
 		return fmt.Errorf("invalid item type %T, expected IPSet", item)
 	}
-	var family string
-	switch ipset.AddrFamily {
-	case netlink.FAMILY_V4:
-		family = "inet"
-	case netlink.FAMILY_V6:
-		family = "inet6"
-	default:
-		return fmt.Errorf("unsupported ipset address type: %d", ipset.AddrFamily)
-	}
-	args := []string{"create", ipset.SetName, ipset.TypeName, "family", family}
-	if output, err := base.Exec(c.Log, ipsetCmd, args...).CombinedOutput(); err != nil {
-		outputStr := strings.TrimSpace(string(output))
-		err = fmt.Errorf("failed to create ipset %+v: %s (err: %w)",
-			ipset, outputStr, err)
-		c.Log.Error(err)
-		return err
-	}
-	for _, entry := range ipset.Entries {
-		args = []string{"add", ipset.SetName, entry}
-		if output, err := base.Exec(c.Log, ipsetCmd, args...).CombinedOutput(); err != nil {
-			outputStr := strings.TrimSpace(string(output))
-			err = fmt.Errorf("failed to add entry %s into ipset %s: %s (err: %w)",
-				entry, ipset.SetName, outputStr, err)
-			c.Log.Error(err)
-			return err
-		}
-	}
-	return nil
+	return ipsets.GetIPSetManager(c.Log).AcquireIPSet(
+		ipset.SetName, ipset.TypeName, ipset.AddrFamily, ipset.Entries)
 }
 
-// Modify is not implemented.
-// Note that zedrouter does not need to ever modify Entries - they are either static
-// or dynamically managed by dnsmasq, i.e. outside zedrouter.
+// Modify atomically replaces the set of entries: the manager first loads
+// the new content into a temporary ipset, which is then swapped with the
+// original and destroyed. This way there is no window during which the
+// referencing iptables rules would match against a partially loaded set.
+// Used to refresh IP blocklists (see types.IPBlocklistConfig).
+// Entries dynamically managed by dnsmasq are never modified by zedrouter
+// and the static ipsets (e.g. for link-local addresses) never change.
 func (c *IPSetConfigurator) Modify(ctx context.Context, oldItem, newItem dg.Item) (err error) {
-	return errors.New("not implemented")
+	ipset, isIPSet := newItem.(IPSet)
+	if !isIPSet {
+		return fmt.Errorf("invalid item type %T, expected IPSet", newItem)
+	}
+	return ipsets.GetIPSetManager(c.Log).UpdateIPSet(ipset.SetName, ipset.Entries)
 }
 
-// Delete removes ipset.
+// Delete releases ipset (destroyed by the manager with the last user).
 func (c *IPSetConfigurator) Delete(ctx context.Context, item dg.Item) error {
 	ipset, isIPSet := item.(IPSet)
 	if !isIPSet {
 		return fmt.Errorf("invalid item type %T, expected IPSet", item)
 	}
-	args := []string{"destroy", ipset.SetName}
-	if _, err := base.Exec(c.Log, ipsetCmd, args...).CombinedOutput(); err != nil {
-		err = fmt.Errorf("failed to remove ipset %+v: %w", ipset, err)
-		c.Log.Error(err)
-		return err
-	}
-	return nil
+	return ipsets.GetIPSetManager(c.Log).ReleaseIPSet(ipset.SetName)
 }
 
-// NeedsRecreate always returns true - Modify is not implemented.
+// NeedsRecreate returns true if the ipset type or the address family changed
+// (ipset swap requires both sets to be of the same type and family).
+// A change limited to the set of entries is applied atomically by Modify.
 func (c *IPSetConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
-	return true
+	oldCfg, isIPSet := oldItem.(IPSet)
+	if !isIPSet {
+		// unreachable
+		return true
+	}
+	newCfg, isIPSet := newItem.(IPSet)
+	if !isIPSet {
+		// unreachable
+		return true
+	}
+	return oldCfg.TypeName != newCfg.TypeName ||
+		oldCfg.AddrFamily != newCfg.AddrFamily
 }
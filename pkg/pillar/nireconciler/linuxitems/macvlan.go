@@ -0,0 +1,165 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	generic "github.com/lf-edge/eve/pkg/pillar/nireconciler/genericitems"
+	"github.com/vishvananda/netlink"
+)
+
+// MacVlan : MACVLAN (or IPVLAN) sub-interface of a network instance port,
+// used for low-overhead L2-only attachment of an application VIF directly
+// to the port, bypassing the NI bridge (see types.VifAttachmentMode).
+// The sub-interface is plugged into the guest by the hypervisor
+// (macvtap-style) instead of a bridged tap.
+type MacVlan struct {
+	// IfName : name of the MACVLAN/IPVLAN interface inside the network stack.
+	IfName string
+	// ParentIfName : name of the parent (network instance port) interface.
+	ParentIfName string
+	// IPVLAN : use IPVLAN (L2 mode) instead of MACVLAN.
+	// IPVLAN shares the MAC address of the parent interface, which is
+	// preferable when the upstream switch limits the number of MAC
+	// addresses per port.
+	IPVLAN bool
+	// MTU : Maximum transmission unit size.
+	MTU uint16
+}
+
+// Name returns the interface name of the sub-interface.
+func (v MacVlan) Name() string {
+	return v.IfName
+}
+
+// Label is not provided.
+func (v MacVlan) Label() string {
+	return ""
+}
+
+// Type of the item.
+func (v MacVlan) Type() string {
+	return MacVlanTypename
+}
+
+// Equal compares two MacVlan instances.
+func (v MacVlan) Equal(other dg.Item) bool {
+	v2, isMacVlan := other.(MacVlan)
+	if !isMacVlan {
+		return false
+	}
+	return v == v2
+}
+
+// External returns false.
+func (v MacVlan) External() bool {
+	return false
+}
+
+// String describes the MACVLAN/IPVLAN sub-interface.
+func (v MacVlan) String() string {
+	return fmt.Sprintf("MacVlan: {ifName: %s, parentIfName: %s, "+
+		"ipvlan: %t, mtu: %d}", v.IfName, v.ParentIfName, v.IPVLAN, v.MTU)
+}
+
+// Dependencies returns the parent port as the only dependency.
+func (v MacVlan) Dependencies() (deps []dg.Dependency) {
+	return []dg.Dependency{
+		{
+			RequiredItem: dg.ItemRef{
+				ItemType: generic.UplinkTypename,
+				ItemName: v.ParentIfName,
+			},
+			Description: "Parent interface must exist",
+		},
+	}
+}
+
+// MacVlanConfigurator implements Configurator interface (libs/reconciler)
+// for MACVLAN/IPVLAN sub-interfaces.
+type MacVlanConfigurator struct {
+	Log *base.LogObject
+}
+
+// Create adds new MACVLAN/IPVLAN sub-interface.
+func (c *MacVlanConfigurator) Create(ctx context.Context, item dg.Item) error {
+	macVlan, isMacVlan := item.(MacVlan)
+	if !isMacVlan {
+		return fmt.Errorf("invalid item type %T, expected MacVlan", item)
+	}
+	parentLink, err := netlink.LinkByName(macVlan.ParentIfName)
+	if err != nil {
+		err = fmt.Errorf("failed to get parent interface %s: %w",
+			macVlan.ParentIfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = macVlan.IfName
+	attrs.ParentIndex = parentLink.Attrs().Index
+	attrs.MTU = int(macVlan.MTU)
+	var link netlink.Link
+	if macVlan.IPVLAN {
+		link = &netlink.IPVlan{
+			LinkAttrs: attrs,
+			Mode:      netlink.IPVLAN_MODE_L2,
+		}
+	} else {
+		link = &netlink.Macvlan{
+			LinkAttrs: attrs,
+			Mode:      netlink.MACVLAN_MODE_BRIDGE,
+		}
+	}
+	if err := netlink.LinkAdd(link); err != nil {
+		err = fmt.Errorf("failed to add macvlan/ipvlan sub-interface %s: %w",
+			macVlan.IfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		err = fmt.Errorf("failed to set macvlan/ipvlan sub-interface %s UP: %w",
+			macVlan.IfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *MacVlanConfigurator) Modify(ctx context.Context, oldItem, newItem dg.Item) (err error) {
+	return errors.New("not implemented")
+}
+
+// Delete removes the MACVLAN/IPVLAN sub-interface.
+func (c *MacVlanConfigurator) Delete(ctx context.Context, item dg.Item) error {
+	macVlan, isMacVlan := item.(MacVlan)
+	if !isMacVlan {
+		return fmt.Errorf("invalid item type %T, expected MacVlan", item)
+	}
+	link, err := netlink.LinkByName(macVlan.IfName)
+	if err != nil {
+		err = fmt.Errorf("failed to select macvlan/ipvlan sub-interface %s "+
+			"for removal: %w", macVlan.IfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	err = netlink.LinkDel(link)
+	if err != nil {
+		err = fmt.Errorf("failed to delete macvlan/ipvlan sub-interface %s: %w",
+			macVlan.IfName, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate always returns true - Modify is not implemented.
+func (c *MacVlanConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
+	return true
+}
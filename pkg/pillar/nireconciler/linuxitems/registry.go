@@ -21,6 +21,7 @@ func RegisterItems(log *base.LogObject, registry *reconciler.DefaultRegistry,
 		{c: &BridgeConfigurator{Log: log}, t: BridgeTypename},
 		{c: &BridgePortConfigurator{Log: log}, t: BridgePortTypename},
 		{c: &DummyIfConfigurator{Log: log}, t: DummyIfTypename},
+		{c: &MacVlanConfigurator{Log: log}, t: MacVlanTypename},
 		{c: &IPRuleConfigurator{Log: log}, t: IPRuleTypename},
 		{c: &IPSetConfigurator{Log: log}, t: generic.IPSetTypename},
 		{c: &RouteConfigurator{Log: log, NetworkMonitor: monitor}, t: generic.IPv4RouteTypename},
@@ -29,6 +30,10 @@ func RegisterItems(log *base.LogObject, registry *reconciler.DefaultRegistry,
 		{c: &VLANPortConfigurator{Log: log, NetworkMonitor: monitor}, t: VLANPortTypename},
 		{c: &SysctlConfigurator{Log: log}, t: SysctlTypename},
 		{c: &VIFConfigurator{Log: log}, t: VIFTypename},
+		{c: &StormControlConfigurator{Log: log}, t: StormControlTypename},
+		{c: &ConntrackTimeoutPolicyConfigurator{Log: log}, t: generic.CTTimeoutPolicyTypename},
+		{c: &BridgeParamsConfigurator{Log: log}, t: BridgeParamsTypename},
+		{c: &ArpConfigurator{Log: log}, t: ArpTypename},
 	}
 	for _, configurator := range configurators {
 		err := registry.Register(configurator.c, configurator.t)
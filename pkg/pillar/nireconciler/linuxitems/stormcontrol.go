@@ -0,0 +1,180 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package linuxitems
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	dg "github.com/lf-edge/eve-libs/depgraph"
+	"github.com/lf-edge/eve-libs/reconciler"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// StormControl : tc-based policing of flooded traffic (broadcast, multicast,
+// unknown-unicast) received from an application VIF bridged into a switch
+// network instance.
+type StormControl struct {
+	// VIFIfName : name of the VIF interface on the host side.
+	VIFIfName string
+	// NetAdapterName is the logical name for the VIF received from
+	// the controller in NetworkAdapter.Name.
+	NetAdapterName string
+	// Limits : packets-per-second limits for the individual traffic classes.
+	// Zero value means no limit for the given class.
+	Limits types.StormControlConfig
+}
+
+// Name returns the host-side interface name of the policed VIF.
+func (s StormControl) Name() string {
+	return s.VIFIfName
+}
+
+// Label is used only for the visualization purposes of the config/state depgraph.
+func (s StormControl) Label() string {
+	return s.NetAdapterName + " (storm control)"
+}
+
+// Type of the item.
+func (s StormControl) Type() string {
+	return StormControlTypename
+}
+
+// Equal compares two StormControl instances.
+func (s StormControl) Equal(other dg.Item) bool {
+	s2, isStormControl := other.(StormControl)
+	if !isStormControl {
+		return false
+	}
+	return s == s2
+}
+
+// External returns false.
+func (s StormControl) External() bool {
+	return false
+}
+
+// String describes StormControl.
+func (s StormControl) String() string {
+	return fmt.Sprintf("StormControl: {vifIfName: %s, netAdapterName: %s, "+
+		"broadcastPPS: %d, multicastPPS: %d, unknownUnicastPPS: %d}",
+		s.VIFIfName, s.NetAdapterName, s.Limits.BroadcastPPS,
+		s.Limits.MulticastPPS, s.Limits.UnknownUnicastPPS)
+}
+
+// Dependencies returns the policed VIF as the only dependency.
+func (s StormControl) Dependencies() (deps []dg.Dependency) {
+	return []dg.Dependency{
+		{
+			RequiredItem: dg.ItemRef{
+				ItemType: VIFTypename,
+				ItemName: s.VIFIfName,
+			},
+			Description: "VIF must exist",
+			Attributes: dg.DependencyAttributes{
+				AutoDeletedByExternal: true,
+			},
+		},
+	}
+}
+
+// StormControlConfigurator implements Configurator interface (libs/reconciler)
+// for tc-based VIF ingress policing.
+type StormControlConfigurator struct {
+	Log *base.LogObject
+}
+
+const tcCmd = "tc"
+
+// Create installs the clsact qdisc with flower filters policing broadcast,
+// multicast and unicast packet rates on the VIF ingress (i.e. in the direction
+// from the application towards the bridge).
+func (c *StormControlConfigurator) Create(ctx context.Context, item dg.Item) error {
+	stormControl, isStormControl := item.(StormControl)
+	if !isStormControl {
+		return fmt.Errorf("invalid item type %T, expected StormControl", item)
+	}
+	vifIfName := stormControl.VIFIfName
+	args := []string{"qdisc", "add", "dev", vifIfName, "clsact"}
+	if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to add clsact qdisc to VIF %s: %s (err: %w)",
+			vifIfName, outputStr, err)
+		c.Log.Error(err)
+		return err
+	}
+	// Ordered by preference - broadcast frames are matched before the filter
+	// for (the remaining) multicast, which is matched before the filter
+	// for unicast.
+	filters := []struct {
+		pref   string
+		dstMAC string
+		pps    uint32
+	}{
+		{pref: "1", dstMAC: "ff:ff:ff:ff:ff:ff",
+			pps: stormControl.Limits.BroadcastPPS},
+		{pref: "2", dstMAC: "01:00:00:00:00:00/01:00:00:00:00:00",
+			pps: stormControl.Limits.MulticastPPS},
+		{pref: "3", dstMAC: "00:00:00:00:00:00/01:00:00:00:00:00",
+			pps: stormControl.Limits.UnknownUnicastPPS},
+	}
+	for _, filter := range filters {
+		if filter.pps == 0 {
+			// No limit configured for this traffic class.
+			continue
+		}
+		pps := strconv.FormatUint(uint64(filter.pps), 10)
+		args = []string{"filter", "add", "dev", vifIfName, "ingress",
+			"pref", filter.pref, "protocol", "all",
+			"flower", "dst_mac", filter.dstMAC,
+			"action", "police", "pkts_rate", pps, "pkts_burst", pps,
+			"conform-exceed", "drop/ok"}
+		if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+			outputStr := strings.TrimSpace(string(output))
+			err = fmt.Errorf("failed to add policing filter (dst_mac %s) "+
+				"to VIF %s: %s (err: %w)", filter.dstMAC, vifIfName, outputStr, err)
+			c.Log.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Modify is not implemented.
+func (c *StormControlConfigurator) Modify(ctx context.Context, oldItem, newItem dg.Item) (err error) {
+	return fmt.Errorf("not implemented")
+}
+
+// Delete removes the clsact qdisc from the VIF, which also removes all
+// the attached policing filters.
+func (c *StormControlConfigurator) Delete(ctx context.Context, item dg.Item) error {
+	stormControl, isStormControl := item.(StormControl)
+	if !isStormControl {
+		return fmt.Errorf("invalid item type %T, expected StormControl", item)
+	}
+	vifIfName := stormControl.VIFIfName
+	if reconciler.GetOperationReason(ctx) == reconciler.ReasonDepCascade {
+		// The VIF itself is going away and the qdisc disappears together
+		// with the interface - running tc would only fail with
+		// "Cannot find device".
+		return nil
+	}
+	args := []string{"qdisc", "del", "dev", vifIfName, "clsact"}
+	if output, err := base.Exec(c.Log, tcCmd, args...).CombinedOutput(); err != nil {
+		outputStr := strings.TrimSpace(string(output))
+		err = fmt.Errorf("failed to delete clsact qdisc from VIF %s: %s (err: %w)",
+			vifIfName, outputStr, err)
+		c.Log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// NeedsRecreate returns true - Modify is not implemented.
+func (c *StormControlConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
+	return true
+}
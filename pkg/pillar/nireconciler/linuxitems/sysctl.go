@@ -27,6 +27,9 @@ type Sysctl struct {
 	// EnableARPNotify : generate gratuitous arp requests when device is brought up
 	// or hardware address changes
 	EnableARPNotify bool
+	// EnableProxyARP : answer ARP requests on behalf of addresses the kernel
+	// knows how to route (see NetworkInstanceConfig.EnableARPProxy).
+	EnableProxyARP bool
 }
 
 // Name of the item instance.
@@ -70,8 +73,9 @@ func (s Sysctl) String() string {
 		prefix = fmt.Sprintf("App %s", s.ForApp)
 	}
 	return fmt.Sprintf(
-		"%s Sysctl: {ifName: %s, enableDAD: %t, enableARPNotify: %t}",
-		prefix, s.NetIf.IfName, s.EnableDAD, s.EnableARPNotify)
+		"%s Sysctl: {ifName: %s, enableDAD: %t, enableARPNotify: %t, "+
+			"enableProxyARP: %t}",
+		prefix, s.NetIf.IfName, s.EnableDAD, s.EnableARPNotify, s.EnableProxyARP)
 }
 
 // Dependencies returns the target interface as the only dependency.
@@ -98,7 +102,11 @@ func (c *SysctlConfigurator) Create(ctx context.Context, item dg.Item) error {
 	if err != nil {
 		return err
 	}
-	return c.setArpNotify(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, sysctl.EnableARPNotify)
+	err = c.setArpNotify(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, sysctl.EnableARPNotify)
+	if err != nil {
+		return err
+	}
+	return c.setProxyArp(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, sysctl.EnableProxyARP)
 }
 
 // Modify updates sysctl settings.
@@ -111,7 +119,11 @@ func (c *SysctlConfigurator) Modify(ctx context.Context, oldItem, newItem dg.Ite
 	if err != nil {
 		return err
 	}
-	return c.setArpNotify(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, sysctl.EnableARPNotify)
+	err = c.setArpNotify(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, sysctl.EnableARPNotify)
+	if err != nil {
+		return err
+	}
+	return c.setProxyArp(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, sysctl.EnableProxyARP)
 }
 
 // Delete sets default sysctl settings.
@@ -124,7 +136,11 @@ func (c *SysctlConfigurator) Delete(ctx context.Context, item dg.Item) error {
 	if err != nil {
 		return err
 	}
-	return c.setArpNotify(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, false)
+	err = c.setArpNotify(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, false)
+	if err != nil {
+		return err
+	}
+	return c.setProxyArp(sysctl.ForApp.NetNsName, sysctl.NetIf.IfName, false)
 }
 
 func (c *SysctlConfigurator) setDAD(netNs string, ifName string, enable bool) error {
@@ -151,6 +167,18 @@ func (c *SysctlConfigurator) setArpNotify(netNs string, ifName string, enable bo
 	return nil
 }
 
+func (c *SysctlConfigurator) setProxyArp(netNs string, ifName string, enable bool) error {
+	value := c.boolValueToStr(enable)
+	sysctlKV := fmt.Sprintf("net/ipv4/conf/%s/proxy_arp=%s", ifName, value)
+	out, err := namespacedCmd(netNs, "sysctl", "-w", sysctlKV).CombinedOutput()
+	if err != nil {
+		errMsg := fmt.Errorf("failed to set proxy-ARP for interface %s: %s", ifName, out)
+		c.Log.Error(errMsg)
+		return err
+	}
+	return nil
+}
+
 // NeedsRecreate returns false - Modify is able to apply any change.
 func (c *SysctlConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool) {
 	return false
@@ -14,10 +14,19 @@ const (
 	BridgePortTypename = "BridgePort"
 	// DummyIfTypename : typename for Linux dummy interface.
 	DummyIfTypename = "DummyInterface"
+	// MacVlanTypename : typename for MACVLAN/IPVLAN sub-interface.
+	MacVlanTypename = "MacVlan"
 	// VLANBridgeTypename : typename for (Linux bridge) enabled for VLANs.
 	VLANBridgeTypename = "VLANBridge"
 	// VLANPortTypename : typename for bridged port with configured VLAN(s).
 	VLANPortTypename = "VLANPort"
 	// SysctlTypename : typename for kernel config applied via sysctl.
 	SysctlTypename = "Sysctl"
+	// StormControlTypename : typename for tc-based VIF ingress policing.
+	StormControlTypename = "StormControl"
+	// BridgeParamsTypename : typename for sysfs-based parameters of a bridge
+	// (STP state, FDB ageing time, multicast snooping).
+	BridgeParamsTypename = "BridgeParams"
+	// ArpTypename : typename for static ARP (neighbor) entries.
+	ArpTypename = "ArpEntry"
 )
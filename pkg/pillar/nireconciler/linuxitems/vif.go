@@ -38,6 +38,13 @@ type VIFVariant struct {
 	External bool
 	// Veth configured by zedrouter between the host and the app (container).
 	Veth Veth
+	// VhostUser : the VIF is backed by a DPDK/vhost-user datapath created
+	// by the hypervisor together with the guest. There is no kernel netdev
+	// representing such VIF and nothing to configure in the kernel network
+	// stack - the item exists only to record the app attachment in the
+	// dependency graph (e.g. for troubleshooting with the exported DOT
+	// rendering of the graph).
+	VhostUser bool
 }
 
 // Veth : virtual ethernet pair created between the host and the (container) app
@@ -78,6 +85,7 @@ func (v VIF) Equal(other dg.Item) bool {
 	return v.HostIfName == v2.HostIfName &&
 		v.NetAdapterName == v2.NetAdapterName &&
 		v.Variant.External == v2.Variant.External &&
+		v.Variant.VhostUser == v2.Variant.VhostUser &&
 		v.Variant.Veth.ForApp == v2.Variant.Veth.ForApp &&
 		v.Variant.Veth.AppIfName == v2.Variant.Veth.AppIfName &&
 		v.Variant.Veth.MTU == v2.Variant.Veth.MTU &&
@@ -93,6 +101,11 @@ func (v VIF) External() bool {
 
 // String describes VIF.
 func (v VIF) String() string {
+	if v.Variant.VhostUser {
+		return fmt.Sprintf(
+			"Vhost-user VIF: {hostIfName: %s, netAdapterName: %s}",
+			v.HostIfName, v.NetAdapterName)
+	}
 	if v.External() {
 		return fmt.Sprintf(
 			"External VIF: {hostIfName: %s, netAdapterName: %s}",
@@ -146,6 +159,13 @@ func (c *VIFConfigurator) Create(ctx context.Context, item dg.Item) error {
 		// Should be unreachable.
 		return errors.New("invalid VIF variant, expected Veth")
 	}
+	if vif.Variant.VhostUser {
+		// The VIF is backed by a DPDK/vhost-user datapath created by the
+		// hypervisor - there is no kernel netdev and therefore nothing
+		// to configure in the kernel network stack.
+		c.Log.Noticef("Nothing to create for vhost-user VIF %s", vif.HostIfName)
+		return nil
+	}
 	appPeer := vif.Variant.Veth
 	attrs := netlink.NewLinkAttrs()
 	attrs.Name = vif.HostIfName
@@ -331,6 +351,11 @@ func (c *VIFConfigurator) Delete(ctx context.Context, item dg.Item) error {
 		// Should be unreachable.
 		return fmt.Errorf("invalid item type %T, expected VIF", item)
 	}
+	if vif.Variant.VhostUser {
+		// No kernel netdev exists for a vhost-user VIF.
+		c.Log.Noticef("Nothing to delete for vhost-user VIF %s", vif.HostIfName)
+		return nil
+	}
 	ifName := vif.HostIfName
 	link, err := netlink.LinkByName(ifName)
 	if err != nil {
@@ -358,6 +383,7 @@ func (c *VIFConfigurator) NeedsRecreate(oldItem, newItem dg.Item) (recreate bool
 	return oldVif.HostIfName != newVif.HostIfName ||
 		oldVif.NetAdapterName != newVif.NetAdapterName ||
 		oldVif.Variant.External != newVif.Variant.External ||
+		oldVif.Variant.VhostUser != newVif.Variant.VhostUser ||
 		oldVif.Variant.Veth.ForApp != newVif.Variant.Veth.ForApp ||
 		oldVif.Variant.Veth.AppIfName != newVif.Variant.Veth.AppIfName ||
 		!bytes.Equal(oldVif.Variant.Veth.AppIfMAC, newVif.Variant.Veth.AppIfMAC)
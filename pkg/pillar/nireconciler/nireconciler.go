@@ -14,6 +14,7 @@ import (
 	"context"
 	"net"
 	"sort"
+	"time"
 
 	dg "github.com/lf-edge/eve-libs/depgraph"
 	"github.com/lf-edge/eve/pkg/kube/cnirpc"
@@ -75,10 +76,34 @@ type NIReconciler interface {
 	// GetAppConnStatus : get current status of app connectivity.
 	GetAppConnStatus(app uuid.UUID) (AppConnReconcileStatus, error)
 
+	// GetInstalledACLRuleCount : return the total number of iptables/ip6tables
+	// rules currently installed for the given network instance, including rules
+	// created for ACLs of the connected applications.
+	GetInstalledACLRuleCount(niID uuid.UUID) uint32
+
+	// GetNAT64MappingCount : return the number of IPv4 addresses from the NAT64
+	// pool currently dynamically mapped to IPv6 hosts by the NAT64 translator
+	// of the given network instance. Returns zero if NAT64 is not enabled.
+	GetNAT64MappingCount(niID uuid.UUID) uint32
+
 	// WatchReconcilerUpdates returns channel with updates about the reconciliation
 	// status, which is provided separately for every network instance and connected
 	// application.
 	WatchReconcilerUpdates() <-chan ReconcilerUpdate
+
+	// VerifyState : audit the actual state of the network stack against
+	// the intended configuration of the given network instance and return
+	// a structured report of discrepancies (missing, unexpected and mismatched
+	// items). Intended for periodic self-audit and for support bundles.
+	VerifyState(niID uuid.UUID) (StateVerificationReport, error)
+
+	// RunMaintenance : clean up resources which accumulate while the given
+	// network instance is running (rotate an oversized dnsmasq log file,
+	// compact the dnsmasq lease DB, garbage-collect orphaned ipsets).
+	// Only resources which are not part of the currently applied configuration
+	// are touched, so the cleanup never conflicts with the reconciliation.
+	// Called periodically by zedrouter for every active network instance.
+	RunMaintenance(ctx context.Context, niID uuid.UUID) (NIMaintenanceReport, error)
 }
 
 // NIBridge either references an already created bridge (by NIM) that Reconciler
@@ -103,6 +128,13 @@ type NIBridge struct {
 	// Uplink interface selected for this network instance.
 	// Zero value if network instance is air-gapped.
 	Uplink Uplink
+	// ExtraPorts : additional physical ports bridged under a switch NI,
+	// next to the (NIM-bridged) Uplink. Unlike the uplink, these ports
+	// are enslaved under the NI bridge by the Reconciler itself.
+	// With multiple member ports the Reconciler forcibly enables STP
+	// on the bridge to mitigate L2 loops through externally attached
+	// switches. Unused for local (L3) network instances.
+	ExtraPorts []SwitchPort
 	// IPConflict is used to mark (Local) NI with IP subnet that overlaps with the network
 	// of one of the uplink ports.
 	// Currently, for conflicting NI, NIReconciler keeps only app VIFs configured, and even
@@ -134,6 +166,24 @@ func (u Uplink) Equal(u2 Uplink) bool {
 		generics.EqualSetsFn(u.NTPServers, u2.NTPServers, netutils.EqualIPs)
 }
 
+// SwitchPort : an additional physical port bridged as a member of a switch
+// network instance (see NIBridge.ExtraPorts).
+type SwitchPort struct {
+	// LogicalLabel : logical label assigned to the port.
+	LogicalLabel string
+	// IfName : name of the port interface in the network stack.
+	IfName string
+	// AccessVlanID : put the port into the access mode for the given VLAN ID.
+	// Values 0 and 1 configure the port as a trunk carrying all VLANs used
+	// by the network instance.
+	AccessVlanID uint16
+}
+
+// Equal compares two switch ports for equality.
+func (p SwitchPort) Equal(p2 SwitchPort) bool {
+	return p == p2
+}
+
 // AppVIF : describes interface created to connect application with network instance.
 // This comes from zedrouter.
 type AppVIF struct {
@@ -238,6 +288,10 @@ type AppConnReconcileStatus struct {
 	Deleted bool
 	// VIFs : the reconciliation status reported separately for each VIF.
 	VIFs []AppVIFReconcileStatus
+	// TeardownTime : how long it took to unconfigure the app connectivity
+	// (zero unless Deleted is true). Purely informational, not considered
+	// by Equal().
+	TeardownTime time.Duration
 }
 
 // Equal compares two instances of AppConnReconcileStatus.
@@ -291,3 +345,68 @@ func (s AppVIFReconcileStatus) Equal(s2 AppVIFReconcileStatus) bool {
 	return s.NetAdapterName == s2.NetAdapterName && s.VIFNum == s2.VIFNum &&
 		s.HostIfName == s2.HostIfName && s.InProgress == s2.InProgress
 }
+
+// DiscrepancyType : type of a discrepancy found by VerifyState.
+type DiscrepancyType uint8
+
+const (
+	// MissingItem : item is part of the intended state but is not applied
+	// in the network stack.
+	MissingItem DiscrepancyType = iota
+	// UnexpectedItem : item is applied in the network stack but is not part
+	// of the intended state.
+	UnexpectedItem
+	// MismatchedItem : item is applied but its actual attributes differ
+	// from the intended ones.
+	MismatchedItem
+)
+
+// String returns human-readable description of the discrepancy type.
+func (t DiscrepancyType) String() string {
+	switch t {
+	case MissingItem:
+		return "missing"
+	case UnexpectedItem:
+		return "unexpected"
+	case MismatchedItem:
+		return "mismatched"
+	}
+	return "unknown"
+}
+
+// StateDiscrepancy : a single discrepancy between the intended and the actual
+// state of the network stack, found by VerifyState.
+type StateDiscrepancy struct {
+	// Item : reference to the affected configuration item.
+	Item dg.ItemRef
+	// Type of the discrepancy.
+	Type DiscrepancyType
+	// Description explains the discrepancy.
+	Description string
+}
+
+// StateVerificationReport : the outcome of a VerifyState call for one
+// network instance.
+type StateVerificationReport struct {
+	// NI : UUID of the audited network instance.
+	NI uuid.UUID
+	// VerifiedAt : time when the audit was performed.
+	VerifiedAt time.Time
+	// Discrepancies found between the intended and the actual state.
+	// Empty if the network instance is fully in-sync.
+	Discrepancies []StateDiscrepancy
+}
+
+// NIMaintenanceReport : resources reclaimed by a single RunMaintenance call
+// for one network instance.
+type NIMaintenanceReport struct {
+	// RotatedLogBytes : size of the dnsmasq log file which was rotated out
+	// because it grew over the size limit. Zero if no rotation was needed.
+	RotatedLogBytes uint64
+	// CompactedLeases : the number of long-expired entries removed from
+	// the dnsmasq lease DB.
+	CompactedLeases uint32
+	// RemovedIPSets : the number of orphaned ipsets (left behind by an unclean
+	// shutdown or a failed atomic update) removed from the kernel.
+	RemovedIPSets uint32
+}
@@ -15,15 +15,19 @@ import (
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/devicenetwork"
 	"github.com/lf-edge/eve/pkg/pillar/flextimer"
+	"github.com/lf-edge/eve/pkg/pillar/netmonitor"
 	"github.com/lf-edge/eve/pkg/pillar/types"
 	uuid "github.com/satori/go.uuid"
-	psutilnet "github.com/shirou/gopsutil/net"
 	"golang.org/x/net/context"
 )
 
 const (
 	// How often are conntrack entries listed and app flows collected and published.
 	flowCollectInterval = 120 * time.Second
+
+	// How often are interface counters sampled by NetworkMonitor for the state
+	// collector (see GetNetworkMetrics).
+	ifCountersInterval = 10 * time.Second
 )
 
 // LinuxCollector implements state data collecting for network instances
@@ -37,6 +41,11 @@ type LinuxCollector struct {
 	flowWatchers     []chan types.IPFlow
 	ipAssignWatchers []chan []VIFAddrsUpdate
 	capturedPackets  chan capturedPacket
+
+	// The latest sample of interface counters, periodically received from
+	// NetworkMonitor and used by GetNetworkMetrics.
+	ifCountersChan <-chan netmonitor.IfCountersUpdate
+	ifCounters     []netmonitor.IfCounters
 }
 
 type niInfo struct {
@@ -50,13 +59,16 @@ type niInfo struct {
 }
 
 // NewLinuxCollector is a constructor for LinuxCollector.
-func NewLinuxCollector(log *base.LogObject) *LinuxCollector {
+func NewLinuxCollector(log *base.LogObject,
+	netMonitor netmonitor.NetworkMonitor) *LinuxCollector {
 	var err error
 	sc := &LinuxCollector{
 		log: log,
 		nis: make(map[uuid.UUID]*niInfo),
 	}
 	sc.capturedPackets = make(chan capturedPacket, 100)
+	sc.ifCountersChan = netMonitor.WatchInterfaceCounters(
+		context.Background(), "nistate", ifCountersInterval)
 	sc.ipLeaseWatcher, err = fsnotify.NewWatcher()
 	if err != nil {
 		log.Fatalf("%s: NewWatcher: %v", LogAndErrPrefix, err)
@@ -171,15 +183,17 @@ func (lc *LinuxCollector) WatchIPAssignments() <-chan []VIFAddrsUpdate {
 // by zedrouter, but also wireless physical ports and bridges created for wired
 // ports by NIM.
 func (lc *LinuxCollector) GetNetworkMetrics() (types.NetworkMetrics, error) {
-	interfaces, err := psutilnet.IOCounters(true)
-	if err != nil {
-		err = fmt.Errorf("%s: GetNetworkMetrics failed to read IO counters: %v",
-			LogAndErrPrefix, err)
-		return types.NetworkMetrics{}, err
-	}
 	// Call iptables once to get counters
 	ac := lc.fetchIptablesCounters()
 
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	// Use the latest sample of interface counters received from NetworkMonitor
+	// (one central sampler shared by all the subscribed agents).
+	// Copy the cached slice - interface names are modified below.
+	interfaces := make([]netmonitor.IfCounters, len(lc.ifCounters))
+	copy(interfaces, lc.ifCounters)
+
 	// If we have both ethN and kethN then rename ethN to eethN ('e' for EVE)
 	// and kethN to ethN (the actual port).
 	// This ensures that ethN has the total counters for the actual port
@@ -187,46 +201,44 @@ func (lc *LinuxCollector) GetNetworkMetrics() (types.NetworkMetrics, error) {
 	// used to indicate how much EVE is doing. However, we wouldn't have
 	// that separation for wlan and wwan interfaces.
 	for i := range interfaces {
-		if !strings.HasPrefix(interfaces[i].Name, "eth") {
+		if !strings.HasPrefix(interfaces[i].IfName, "eth") {
 			continue
 		}
-		kernIfname := "k" + interfaces[i].Name
+		kernIfname := "k" + interfaces[i].IfName
 		for j := range interfaces {
-			if interfaces[j].Name != kernIfname {
+			if interfaces[j].IfName != kernIfname {
 				continue
 			}
-			interfaces[j].Name = interfaces[i].Name
-			interfaces[i].Name = "e" + interfaces[i].Name
+			interfaces[j].IfName = interfaces[i].IfName
+			interfaces[i].IfName = "e" + interfaces[i].IfName
 			break
 		}
 	}
 
-	lc.mu.Lock()
-	defer lc.mu.Unlock()
 	var metrics []types.NetworkMetric
 	for _, intf := range interfaces {
 		metric := types.NetworkMetric{
-			IfName:   intf.Name,
-			TxPkts:   intf.PacketsSent,
-			RxPkts:   intf.PacketsRecv,
-			TxBytes:  intf.BytesSent,
-			RxBytes:  intf.BytesRecv,
-			TxDrops:  intf.Dropout,
-			RxDrops:  intf.Dropin,
-			TxErrors: intf.Errout,
-			RxErrors: intf.Errin,
+			IfName:   intf.IfName,
+			TxPkts:   intf.TxPackets,
+			RxPkts:   intf.RxPackets,
+			TxBytes:  intf.TxBytes,
+			RxBytes:  intf.RxBytes,
+			TxDrops:  intf.TxDrops,
+			RxDrops:  intf.RxDrops,
+			TxErrors: intf.TxErrors,
+			RxErrors: intf.RxErrors,
 		}
 
 		// Is this interface associated with any network instance?
 		var brIfName, vifName string
 		ipVer := 4
-		if vif, isVIF := lc.getVIFByIfName(intf.Name); isVIF {
+		if vif, isVIF := lc.getVIFByIfName(intf.IfName); isVIF {
 			ipVer = addrTypeToIPVer(lc.nis[vif.NI].config.IpType)
 			brIfName = lc.nis[vif.NI].bridge.BrIfName
 			vifName = vif.HostIfName
-		} else if br, isBr := lc.getBridgeByIfName(intf.Name); isBr {
+		} else if br, isBr := lc.getBridgeByIfName(intf.IfName); isBr {
 			ipVer = addrTypeToIPVer(lc.nis[br.NI].config.IpType)
-			brIfName = intf.Name
+			brIfName = intf.IfName
 		} else {
 			// Not part of any NI, probably uplink interface.
 			metrics = append(metrics, metric)
@@ -242,6 +254,11 @@ func (lc *LinuxCollector) GetNetworkMetrics() (types.NetworkMetrics, error) {
 			ac, brIfName, vifName, ipVer, true)
 		metric.RxAclRateLimitDrops = lc.getIptablesACLRateLimitDrop(
 			ac, brIfName, vifName, ipVer, false)
+		metric.DNSRateLimitDrops = lc.getIptablesDNSRateLimitDrop(
+			ac, brIfName, vifName, ipVer)
+		metric.IPBlocklistHits = lc.getIptablesBlocklistHits(
+			ac, brIfName, vifName, ipVer, true) +
+			lc.getIptablesBlocklistHits(ac, brIfName, vifName, ipVer, false)
 		metrics = append(metrics, metric)
 	}
 	return types.NetworkMetrics{
@@ -297,6 +314,13 @@ func (lc *LinuxCollector) runStateCollecting() {
 					}
 				}
 			}
+		case update := <-lc.ifCountersChan:
+			lc.mu.Lock()
+			lc.ifCounters = make([]netmonitor.IfCounters, 0, len(update.Counters))
+			for _, entry := range update.Counters {
+				lc.ifCounters = append(lc.ifCounters, entry.Total)
+			}
+			lc.mu.Unlock()
 		case <-gcIPLeases.C:
 			lc.mu.Lock()
 			var addrChanges []VIFAddrsUpdate
@@ -305,6 +329,7 @@ func (lc *LinuxCollector) runStateCollecting() {
 				if removedAny {
 					addrChanges = append(addrChanges, lc.processIPLeases(ni)...)
 				}
+				addrChanges = append(addrChanges, lc.removeStaleVIFAddrs(ni)...)
 			}
 			watchers := lc.ipAssignWatchers
 			lc.mu.Unlock()
@@ -316,9 +341,16 @@ func (lc *LinuxCollector) runStateCollecting() {
 			}
 		case <-flowCollectTimer.C:
 			lc.mu.Lock()
-			flows := lc.collectFlows()
+			flows, addrChanges := lc.collectFlows()
 			watchers := lc.flowWatchers
+			addrWatchers := lc.ipAssignWatchers
 			lc.mu.Unlock()
+			if len(addrChanges) != 0 {
+				lc.logAddrChanges("Flow collecting event", addrChanges)
+				for _, watcherCh := range addrWatchers {
+					watcherCh <- addrChanges
+				}
+			}
 			if len(flows) != 0 {
 				for _, watcherCh := range watchers {
 					for _, flow := range flows {
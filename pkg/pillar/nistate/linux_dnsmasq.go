@@ -127,22 +127,33 @@ func (lc *LinuxCollector) processIPLeases(niInfo *niInfo) (
 		vifAddrs := &niInfo.vifs[i]
 		vif := vifAddrs.VIF
 		ipLease := niInfo.ipLeases.findLease(vif.App.String(), vif.GuestIfMAC, true)
-		if ipLease == nil && vifAddrs.IPv4Addr != nil {
+		if ipLease == nil && vifAddrs.IPv4Addr.Address != nil {
 			prevAddrs := *vifAddrs
-			vifAddrs.IPv4Addr = nil
-			newAddrs := *vifAddrs
-			addrUpdates = append(addrUpdates, VIFAddrsUpdate{
-				Prev: prevAddrs,
-				New:  newAddrs,
-			})
-		} else if ipLease != nil && !ipLease.ipAddr.Equal(vifAddrs.IPv4Addr) {
-			prevAddrs := *vifAddrs
-			vifAddrs.IPv4Addr = ipLease.ipAddr
+			vifAddrs.IPv4Addr = types.AssignedAddr{}
 			newAddrs := *vifAddrs
 			addrUpdates = append(addrUpdates, VIFAddrsUpdate{
 				Prev: prevAddrs,
 				New:  newAddrs,
 			})
+		} else if ipLease != nil {
+			update := lc.learnVIFIPv4Addr(vifAddrs, ipLease.ipAddr,
+				types.AddressSourceInternalDHCP)
+			// Attach the name of the DHCP client class from whose dedicated
+			// address pool this lease was allocated (if any).
+			className := niInfo.config.DHCPClientClassForIP(ipLease.ipAddr)
+			if vifAddrs.IPv4Addr.Address.Equal(ipLease.ipAddr) &&
+				vifAddrs.IPv4Addr.DhcpClientClass != className {
+				prevAddrs := *vifAddrs
+				vifAddrs.IPv4Addr.DhcpClientClass = className
+				if update != nil {
+					update.New = *vifAddrs
+				} else {
+					update = &VIFAddrsUpdate{Prev: prevAddrs, New: *vifAddrs}
+				}
+			}
+			if update != nil {
+				addrUpdates = append(addrUpdates, *update)
+			}
 		}
 	}
 	return addrUpdates
@@ -62,7 +62,10 @@ type dnsReq struct {
 }
 
 // Collect stats about active application IP flows using Netfilter conntrack.
-func (lc *LinuxCollector) collectFlows() (flows []types.IPFlow) {
+// Additionally, returns the set of changes in IP address assignments as learned
+// from the conntrack entries.
+func (lc *LinuxCollector) collectFlows() (
+	flows []types.IPFlow, addrUpdates []VIFAddrsUpdate) {
 	var timeoutedFlows []flowRec
 	var totalFlow int
 
@@ -73,11 +76,12 @@ func (lc *LinuxCollector) collectFlows() (flows []types.IPFlow) {
 		if err != nil {
 			lc.log.Errorf("%s: ContrackTableList failed: %v",
 				flowLogPrefix, err)
-			return nil
+			return nil, addrUpdates
 		}
 
 		// Loop through and process timeout-ed flows.
 		for _, entry := range connT {
+			addrUpdates = append(addrUpdates, lc.learnAddrsFromConntrack(entry)...)
 			flow, skip := lc.convertConntrackToFlow(entry)
 			if skip {
 				continue
@@ -142,7 +146,7 @@ func (lc *LinuxCollector) collectFlows() (flows []types.IPFlow) {
 			}
 		}
 	}
-	return flows
+	return flows, addrUpdates
 }
 
 // Merge conntrack flow of two uni-directional stats into one
@@ -165,6 +169,27 @@ func (lc *LinuxCollector) convertConntrackToFlow(
 	if len(vifs) == 0 {
 		return ipFlow, true
 	}
+	if entry.Zone != 0 {
+		// Flows initiated from local NIs are conntracked in a per-NI zone
+		// (zone number = BrNum, see nireconciler). Scope the candidate VIFs
+		// to the NI of the zone, so that the flow is attributed to the correct
+		// NI even when multiple NIs use overlapping subnets and the app IP
+		// address alone would be ambiguous.
+		var zoneVIFs VIFAddrsList
+		for _, vif := range vifs {
+			niState, exists := lc.nis[vif.VIF.NI]
+			if exists && niState.bridge.BrNum == int(entry.Zone) {
+				zoneVIFs = append(zoneVIFs, vif)
+			}
+		}
+		if len(zoneVIFs) == 0 {
+			lc.log.Warnf("%s: Flow entry with conntrack zone %d not matching "+
+				"any VIF of app with appNum %d, entry: %s", flowLogPrefix,
+				entry.Zone, appNum, entry.String())
+			return ipFlow, true
+		}
+		vifs = zoneVIFs
+	}
 
 	// ACL applied to this flow.
 	if aclID != iptables.DefaultDropAceID {
@@ -520,21 +545,15 @@ func (lc *LinuxCollector) processARPPacket(
 		return nil
 	}
 
-	prevAddrs := *vif
 	if weAreSource {
 		gotAddress = arp.SourceProtAddress
 	} else {
 		gotAddress = arp.DstProtAddress
 	}
-	if vif.IPv4Addr.Equal(gotAddress) {
-		return nil
+	update := lc.learnVIFIPv4Addr(vif, gotAddress, types.AddressSourceARPSnoop)
+	if update != nil {
+		addrUpdates = append(addrUpdates, *update)
 	}
-	vif.IPv4Addr = gotAddress
-	newAddrs := *vif
-	addrUpdates = append(addrUpdates, VIFAddrsUpdate{
-		Prev: prevAddrs,
-		New:  newAddrs,
-	})
 	return addrUpdates
 }
 
@@ -611,16 +630,11 @@ func (lc *LinuxCollector) processDHCPPacket(
 		if vif == nil {
 			return nil, true
 		}
-		if vif.IPv4Addr.Equal(dhcpv4.YourClientIP) {
-			return nil, true
+		update := lc.learnVIFIPv4Addr(vif, dhcpv4.YourClientIP,
+			types.AddressSourceExternalDHCP)
+		if update != nil {
+			addrUpdates = append(addrUpdates, *update)
 		}
-		prevAddrs := *vif
-		vif.IPv4Addr = dhcpv4.YourClientIP
-		newAddrs := *vif
-		addrUpdates = append(addrUpdates, VIFAddrsUpdate{
-			Prev: prevAddrs,
-			New:  newAddrs,
-		})
 		return addrUpdates, true
 	}
 
@@ -650,16 +664,11 @@ func (lc *LinuxCollector) processDHCPPacket(
 		if vif == nil {
 			return nil, true
 		}
-		if isAddrPresent(vif.IPv6Addrs, dhcpv6.LinkAddr) {
-			return nil, true
+		update := lc.learnVIFIPv6Addr(vif, dhcpv6.LinkAddr,
+			types.AddressSourceExternalDHCP)
+		if update != nil {
+			addrUpdates = append(addrUpdates, *update)
 		}
-		prevAddrs := *vif
-		vif.IPv6Addrs = append(vif.IPv6Addrs, dhcpv6.LinkAddr)
-		newAddrs := *vif
-		addrUpdates = append(addrUpdates, VIFAddrsUpdate{
-			Prev: prevAddrs,
-			New:  newAddrs,
-		})
 		return addrUpdates, true
 	}
 	return nil, true
@@ -695,16 +704,11 @@ func (lc *LinuxCollector) processDADProbe(
 		return
 	}
 	icmp6 := icmp6Layer.(*layers.ICMPv6NeighborSolicitation)
-	if isAddrPresent(vif.IPv6Addrs, icmp6.TargetAddress) {
-		return nil
+	update := lc.learnVIFIPv6Addr(vif, icmp6.TargetAddress,
+		types.AddressSourceNDPSnoop)
+	if update != nil {
+		addrUpdates = append(addrUpdates, *update)
 	}
-	prevAddrs := *vif
-	vif.IPv6Addrs = append(vif.IPv6Addrs, icmp6.TargetAddress)
-	newAddrs := *vif
-	addrUpdates = append(addrUpdates, VIFAddrsUpdate{
-		Prev: prevAddrs,
-		New:  newAddrs,
-	})
 	return addrUpdates
 }
 
@@ -764,12 +768,3 @@ func (lc *LinuxCollector) processDNSPacketInfo(
 		}
 	}
 }
-
-func isAddrPresent(list []net.IP, addr net.IP) bool {
-	for i := 0; i < len(list); i++ {
-		if addr.Equal(list[i]) {
-			return true
-		}
-	}
-	return false
-}
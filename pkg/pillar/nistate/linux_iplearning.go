@@ -0,0 +1,210 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package nistate
+
+import (
+	"net"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/iptables"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+	"github.com/vishvananda/netlink"
+)
+
+// learnedAddrMaxAge returns for how long an IP address assignment learned
+// from the given source remains valid without being re-confirmed (by any
+// source). Once this age is exceeded, the address is considered stale
+// and is removed by removeStaleVIFAddrs.
+// Zero value means that addresses learned from the source do not age out
+// (their removal is driven by explicit events, such as a removed DHCP lease).
+func learnedAddrMaxAge(source types.AddressSource) time.Duration {
+	switch source {
+	case types.AddressSourceConntrack:
+		// The least reliable source - require frequent re-confirmation.
+		return 10 * time.Minute
+	case types.AddressSourceARPSnoop:
+		// Active guests refresh their ARP entries much more often than this.
+		return time.Hour
+	case types.AddressSourceNDPSnoop, types.AddressSourceExternalDHCP:
+		// DAD probes are sent only when an address is being assigned
+		// and external DHCP leases are typically valid for hours,
+		// hence a rather long expiration period.
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// learnVIFIPv4Addr updates the IPv4 address assignment of the given VIF based
+// on information obtained from the given source.
+// A conflicting address reported by a lower-confidence source does not
+// overwrite an address learned from a higher-confidence source, unless
+// the existing entry is already stale.
+// Returns nil if the assignment did not change (the entry might still have
+// its metadata refreshed).
+func (lc *LinuxCollector) learnVIFIPv4Addr(vif *VIFAddrs, addr net.IP,
+	source types.AddressSource) (update *VIFAddrsUpdate) {
+	now := time.Now()
+	prevAssignment := &vif.IPv4Addr
+	if prevAssignment.Address.Equal(addr) {
+		// Assignment re-confirmed, possibly by a more trusted source.
+		if source > prevAssignment.Source {
+			prevAssignment.Source = source
+		}
+		prevAssignment.LastSeen = now
+		return nil
+	}
+	if prevAssignment.Address != nil && source < prevAssignment.Source &&
+		!isStaleAddr(*prevAssignment, now) {
+		// Ignore conflicting info from a less trusted source.
+		return nil
+	}
+	prevAddrs := *vif
+	vif.IPv4Addr = types.AssignedAddr{
+		Address:  addr,
+		Source:   source,
+		LastSeen: now,
+	}
+	newAddrs := *vif
+	return &VIFAddrsUpdate{Prev: prevAddrs, New: newAddrs}
+}
+
+// learnVIFIPv6Addr updates the set of IPv6 addresses assigned to the given VIF
+// based on information obtained from the given source.
+// Returns nil if the assignment did not change (the matched entry might still
+// have its metadata refreshed).
+func (lc *LinuxCollector) learnVIFIPv6Addr(vif *VIFAddrs, addr net.IP,
+	source types.AddressSource) (update *VIFAddrsUpdate) {
+	now := time.Now()
+	for i := range vif.IPv6Addrs {
+		assignment := &vif.IPv6Addrs[i]
+		if !assignment.Address.Equal(addr) {
+			continue
+		}
+		// Assignment re-confirmed, possibly by a more trusted source.
+		if source > assignment.Source {
+			assignment.Source = source
+		}
+		assignment.LastSeen = now
+		return nil
+	}
+	prevAddrs := *vif
+	vif.IPv6Addrs = append(vif.IPv6Addrs, types.AssignedAddr{
+		Address:  addr,
+		Source:   source,
+		LastSeen: now,
+	})
+	newAddrs := *vif
+	return &VIFAddrsUpdate{Prev: prevAddrs, New: newAddrs}
+}
+
+// isStaleAddr returns true if the learned address assignment was not
+// re-confirmed for longer than the maximum age given by its source.
+func isStaleAddr(addr types.AssignedAddr, now time.Time) bool {
+	maxAge := learnedAddrMaxAge(addr.Source)
+	return maxAge != 0 && now.Sub(addr.LastSeen) > maxAge
+}
+
+// removeStaleVIFAddrs deterministically ages out learned IP address
+// assignments which have not been re-confirmed by any source within
+// the source-specific expiration period.
+func (lc *LinuxCollector) removeStaleVIFAddrs(niInfo *niInfo) (
+	addrUpdates []VIFAddrsUpdate) {
+	now := time.Now()
+	for i := range niInfo.vifs {
+		vif := &niInfo.vifs[i]
+		var changed bool
+		newIPv4Addr := vif.IPv4Addr
+		if vif.IPv4Addr.Address != nil && isStaleAddr(vif.IPv4Addr, now) {
+			newIPv4Addr = types.AssignedAddr{}
+			changed = true
+		}
+		var newIPv6Addrs []types.AssignedAddr
+		for _, addr := range vif.IPv6Addrs {
+			if isStaleAddr(addr, now) {
+				changed = true
+				continue
+			}
+			newIPv6Addrs = append(newIPv6Addrs, addr)
+		}
+		if !changed {
+			continue
+		}
+		prevAddrs := *vif
+		vif.IPv4Addr = newIPv4Addr
+		vif.IPv6Addrs = newIPv6Addrs
+		newAddrs := *vif
+		addrUpdates = append(addrUpdates, VIFAddrsUpdate{
+			Prev: prevAddrs,
+			New:  newAddrs,
+		})
+	}
+	return addrUpdates
+}
+
+// learnAddrsFromConntrack tries to learn IP addresses assigned to app VIFs
+// from a conntrack entry. This is the lowest-confidence source, used only
+// to fill assignments not (yet) known from any other source and only
+// for switch network instances (inside local NIs apps get their addresses
+// from a DHCP server run by EVE).
+// Conntrack entries do not carry MAC addresses, therefore the flow is
+// attributed to a VIF using the connection mark (put there by ACL iptables
+// rules) and only when the app has a single VIF (otherwise the particular
+// VIF cannot be determined).
+func (lc *LinuxCollector) learnAddrsFromConntrack(
+	entry *netlink.ConntrackFlow) (addrUpdates []VIFAddrsUpdate) {
+	appNum, _, _, _ := iptables.ParseConnmark(entry.Mark)
+	if appNum == 0 {
+		return nil
+	}
+	var vif *VIFAddrs
+	var vifNI *niInfo
+	for _, ni := range lc.nis {
+		for i := range ni.vifs {
+			if ni.vifs[i].VIF.AppNum != int(appNum) {
+				continue
+			}
+			if vif != nil {
+				// Ambiguous - the app has multiple VIFs.
+				return nil
+			}
+			vif = &ni.vifs[i]
+			vifNI = ni
+		}
+	}
+	if vif == nil || vifNI.config.Type != types.NetworkInstanceTypeSwitch {
+		return nil
+	}
+	// The app side of the connection is assumed to be the initiator.
+	candidate := entry.Forward.SrcIP
+	if candidate == nil || candidate.IsUnspecified() || candidate.IsLoopback() ||
+		candidate.IsMulticast() || candidate.Equal(net.IPv4bcast) {
+		return nil
+	}
+	isIPv4 := candidate.To4() != nil
+	// Only fill the void - never overwrite or extend assignments learned
+	// from more reliable sources.
+	if isIPv4 {
+		if vif.IPv4Addr.Address != nil {
+			return nil
+		}
+	} else if len(vif.IPv6Addrs) > 0 {
+		return nil
+	}
+	// Do not learn an address which clearly belongs to someone else.
+	for _, ni := range lc.nis {
+		if ni.vifs.LookupByIP(candidate) != nil {
+			return nil
+		}
+	}
+	var update *VIFAddrsUpdate
+	if isIPv4 {
+		update = lc.learnVIFIPv4Addr(vif, candidate, types.AddressSourceConntrack)
+	} else {
+		update = lc.learnVIFIPv6Addr(vif, candidate, types.AddressSourceConntrack)
+	}
+	if update != nil {
+		addrUpdates = append(addrUpdates, *update)
+	}
+	return addrUpdates
+}
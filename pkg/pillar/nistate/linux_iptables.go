@@ -23,6 +23,8 @@ type aclCounters struct {
 	drop        bool
 	dropCounter bool
 	limit       bool
+	hashlimit   bool
+	ipset       bool
 	more        bool // Has fields we didn't explicitly parse; user specified.
 	accept      bool
 	dest        string
@@ -138,6 +140,27 @@ func (lc *LinuxCollector) parseIptablesLine(
 			i += 2
 			continue
 		}
+		// Mark hashlimit flag (used by the DNS query rate limiter)
+		if items[i] == "-m" && items[i+1] == "hashlimit" {
+			ac.hashlimit = true
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(items[i], "--hashlimit-") {
+			i += 2
+			continue
+		}
+		// Mark ipset flag (used by the IP blocklist rules)
+		if items[i] == "-m" && items[i+1] == "set" {
+			ac.ipset = true
+			i += 2
+			continue
+		}
+		// Skip "--match-set <set-name> <src|dst>"
+		if items[i] == "--match-set" {
+			i += 3
+			continue
+		}
 		// Need to allow -A FORWARD -d 10.0.1.11/32 -o bn1
 		// without setting More.
 		if forward && items[i] == "-d" && i == 2 {
@@ -221,7 +244,8 @@ func (lc *LinuxCollector) getIptablesCounters(
 	counters []aclCounters, match aclCounters) aclCounters {
 	for i, c := range counters {
 		if c.ipVer != match.ipVer || c.dropCounter != match.dropCounter ||
-			c.drop != match.drop || c.limit != match.limit {
+			c.drop != match.drop || c.limit != match.limit ||
+			c.hashlimit != match.hashlimit {
 			continue
 		}
 		if c.inIf != match.inIf || c.outIf != match.outIf {
@@ -291,3 +315,40 @@ func (lc *LinuxCollector) getIptablesACLRateLimitDrop(counters []aclCounters,
 	c := lc.getIptablesCounters(counters, matcher)
 	return c.pkts
 }
+
+// getIptablesDNSRateLimitDrop : get number of DNS queries from the given VIF
+// dropped by the per-VIF DNS query rate limiter (hashlimit + DROP rule,
+// see nireconciler/linux_acl.go).
+func (lc *LinuxCollector) getIptablesDNSRateLimitDrop(counters []aclCounters,
+	bridgeName string, vifName string, ipVer int) uint64 {
+	matcher := lc.makeIptablesCountersMatcher(bridgeName, vifName, ipVer, true)
+	matcher.hashlimit = true
+	matcher.drop = true
+	c := lc.getIptablesCounters(counters, matcher)
+	return c.pkts
+}
+
+// getIptablesBlocklistHits : get number of packets from/to the given VIF
+// dropped by the IP blocklist of the network instance (DROP rules matching
+// against the blocklist ipset, see nireconciler/linux_acl.go).
+// The blocklist rules have no other match options next to the ipset match,
+// which differentiates them from the user-configured ACL rules.
+func (lc *LinuxCollector) getIptablesBlocklistHits(counters []aclCounters,
+	bridgeName string, vifName string, ipVer int, brInput bool) uint64 {
+	matcher := lc.makeIptablesCountersMatcher(bridgeName, vifName, ipVer, brInput)
+	var pkts uint64
+	for _, c := range counters {
+		if !c.ipset || !c.drop || c.more || c.limit || c.hashlimit {
+			continue
+		}
+		if c.ipVer != matcher.ipVer || c.inIf != matcher.inIf ||
+			c.outIf != matcher.outIf {
+			continue
+		}
+		if c.pInIf != matcher.pInIf || c.pOutIf != matcher.pOutIf {
+			continue
+		}
+		pkts += c.pkts
+	}
+	return pkts
+}
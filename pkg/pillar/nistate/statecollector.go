@@ -165,11 +165,11 @@ type VIFAddrs struct {
 
 // HasIP returns true if the given IP address is assigned to this VIF.
 func (vif VIFAddrs) HasIP(ip net.IP) bool {
-	if ip.Equal(vif.IPv4Addr) {
+	if ip.Equal(vif.IPv4Addr.Address) {
 		return true
 	}
 	for _, ipv6Addr := range vif.IPv6Addrs {
-		if ip.Equal(ipv6Addr) {
+		if ip.Equal(ipv6Addr.Address) {
 			return true
 		}
 	}
@@ -0,0 +1,253 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oobchannel implements a minimal out-of-band management channel
+// for NIM, running over a serial/console device (or USB gadget serial).
+// It is a last-resort rescue mechanism for field devices which lost all IP
+// connectivity due to a network misconfiguration: a technician attached
+// to the serial port can read the current DeviceNetworkStatus and inject
+// an override DevicePortConfig without any working network.
+// The channel speaks a simple newline-delimited JSON protocol and every
+// request must be authenticated with an HMAC-SHA256 computed using
+// a device-specific pre-shared key. Without the key provisioned on the
+// device, the channel refuses to start - there is no unauthenticated mode.
+// The serial device is expected to be already configured (baud rate, etc.)
+// by the platform; this package only reads and writes it.
+package oobchannel
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// DefaultKeyFile : default location of the pre-shared key used
+// to authenticate requests. The key is provisioned into the (read-only)
+// config partition, typically during device manufacturing.
+const DefaultKeyFile = "/config/oob-channel.key"
+
+// reopenDelay : how long to wait before re-opening the serial device
+// after a read/write error.
+const reopenDelay = 10 * time.Second
+
+// Supported commands.
+const (
+	// CommandGetStatus : read the current DeviceNetworkStatus.
+	CommandGetStatus = "get-status"
+	// CommandInjectDPC : inject an override DevicePortConfig.
+	CommandInjectDPC = "inject-dpc"
+)
+
+// Request : a single request received over the channel (one JSON per line).
+type Request struct {
+	// Command : one of CommandGetStatus, CommandInjectDPC.
+	Command string `json:"command"`
+	// Nonce : must be strictly increasing between requests to prevent
+	// replaying of previously captured (valid) requests.
+	Nonce uint64 `json:"nonce"`
+	// Payload : command-specific argument. For CommandInjectDPC this
+	// carries the DevicePortConfig to inject.
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Auth : hex-encoded HMAC-SHA256 of "<command>|<nonce>|<payload>"
+	// computed with the pre-shared key.
+	Auth string `json:"auth"`
+}
+
+// Response : a single response sent over the channel (one JSON per line).
+type Response struct {
+	// Nonce : copied from the request.
+	Nonce uint64 `json:"nonce"`
+	// Ok : true if the command was executed successfully.
+	Ok bool `json:"ok"`
+	// Error : human-readable error description if Ok is false.
+	Error string `json:"error,omitempty"`
+	// Payload : command-specific result. For CommandGetStatus this
+	// carries the DeviceNetworkStatus.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Handlers : callbacks into NIM used to execute authenticated commands.
+type Handlers struct {
+	// GetStatus should return the current DeviceNetworkStatus.
+	GetStatus func() types.DeviceNetworkStatus
+	// InjectDPC should validate and apply the given override
+	// DevicePortConfig.
+	InjectDPC func(types.DevicePortConfig) error
+}
+
+// Config : configuration for the out-of-band channel.
+type Config struct {
+	// Device : path to the serial device to listen on.
+	Device string
+	// KeyFile : path to the file with the pre-shared key.
+	// DefaultKeyFile is used if left empty.
+	KeyFile string
+}
+
+// OOBChannel listens on a serial device for authenticated out-of-band
+// management requests.
+type OOBChannel struct {
+	log      *base.LogObject
+	config   Config
+	handlers Handlers
+	key      []byte
+	// lastNonce : the highest nonce seen in an authenticated request.
+	lastNonce uint64
+}
+
+// NewOOBChannel is a constructor for OOBChannel.
+// Fails if the pre-shared key is not provisioned on the device - the channel
+// is never run unauthenticated.
+func NewOOBChannel(log *base.LogObject, config Config,
+	handlers Handlers) (*OOBChannel, error) {
+	if config.KeyFile == "" {
+		config.KeyFile = DefaultKeyFile
+	}
+	keyData, err := os.ReadFile(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pre-shared key from %s: %w",
+			config.KeyFile, err)
+	}
+	key := []byte(strings.TrimSpace(string(keyData)))
+	if len(key) < 16 {
+		return nil, fmt.Errorf("pre-shared key from %s is too short "+
+			"(%d bytes, expecting at least 16)", config.KeyFile, len(key))
+	}
+	return &OOBChannel{
+		log:      log,
+		config:   config,
+		handlers: handlers,
+		key:      key,
+	}, nil
+}
+
+// Run listens on the serial device and serves requests until the context
+// is canceled. The device is re-opened after errors (e.g. USB gadget serial
+// being unplugged and plugged back).
+func (c *OOBChannel) Run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		c.serveDevice(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reopenDelay):
+		}
+	}
+}
+
+// serveDevice opens the serial device and serves requests until
+// a read/write error occurs.
+func (c *OOBChannel) serveDevice(ctx context.Context) {
+	dev, err := os.OpenFile(c.config.Device, os.O_RDWR, 0)
+	if err != nil {
+		c.log.Errorf("oobchannel: failed to open %s: %v", c.config.Device, err)
+		return
+	}
+	defer dev.Close()
+	c.log.Noticef("oobchannel: listening on %s", c.config.Device)
+	// Close the device when the context is canceled to unblock the reader.
+	go func() {
+		<-ctx.Done()
+		dev.Close()
+	}()
+	scanner := bufio.NewScanner(dev)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		resp := c.serveRequest(line)
+		respData, err := json.Marshal(resp)
+		if err != nil {
+			c.log.Errorf("oobchannel: failed to marshal response: %v", err)
+			continue
+		}
+		if _, err := dev.Write(append(respData, '\n')); err != nil {
+			c.log.Errorf("oobchannel: failed to write response: %v", err)
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		c.log.Errorf("oobchannel: read from %s failed: %v", c.config.Device, err)
+	}
+}
+
+// serveRequest authenticates and executes a single request.
+func (c *OOBChannel) serveRequest(line string) Response {
+	var req Request
+	if err := json.Unmarshal([]byte(line), &req); err != nil {
+		return Response{Error: fmt.Sprintf("malformed request: %v", err)}
+	}
+	resp := Response{Nonce: req.Nonce}
+	if err := c.authenticate(req); err != nil {
+		c.log.Warnf("oobchannel: rejected %s request: %v", req.Command, err)
+		resp.Error = err.Error()
+		return resp
+	}
+	c.lastNonce = req.Nonce
+	switch req.Command {
+	case CommandGetStatus:
+		dns := c.handlers.GetStatus()
+		payload, err := json.Marshal(dns)
+		if err != nil {
+			resp.Error = fmt.Sprintf("failed to marshal status: %v", err)
+			return resp
+		}
+		resp.Ok = true
+		resp.Payload = payload
+	case CommandInjectDPC:
+		var dpc types.DevicePortConfig
+		if err := json.Unmarshal(req.Payload, &dpc); err != nil {
+			resp.Error = fmt.Sprintf("malformed DevicePortConfig: %v", err)
+			return resp
+		}
+		if err := c.handlers.InjectDPC(dpc); err != nil {
+			resp.Error = err.Error()
+			return resp
+		}
+		c.log.Noticef("oobchannel: injected override DPC")
+		resp.Ok = true
+	default:
+		resp.Error = fmt.Sprintf("unknown command %q", req.Command)
+	}
+	return resp
+}
+
+// authenticate verifies the HMAC and the nonce of the request.
+func (c *OOBChannel) authenticate(req Request) error {
+	expected := ComputeAuth(c.key, req.Command, req.Nonce, req.Payload)
+	received, err := hex.DecodeString(req.Auth)
+	if err != nil {
+		return fmt.Errorf("malformed auth field: %v", err)
+	}
+	if !hmac.Equal(expected, received) {
+		return fmt.Errorf("authentication failed")
+	}
+	if req.Nonce <= c.lastNonce {
+		return fmt.Errorf("nonce %d is not greater than the last "+
+			"accepted nonce %d (replay?)", req.Nonce, c.lastNonce)
+	}
+	return nil
+}
+
+// ComputeAuth computes the HMAC-SHA256 authenticating a request.
+// Exported for use by clients of the channel (and tests).
+func ComputeAuth(key []byte, command string, nonce uint64,
+	payload json.RawMessage) []byte {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s|%d|%s", command, nonce, string(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package procsupervisor implements a watchdog for network helper daemons
+// spawned by pillar microservices, such as dhcpcd (from NIM) and dnsmasq
+// (from zedrouter). These daemons may crash silently, leaving device ports
+// without leases or network instances without DHCP/DNS services, with nothing
+// in the device status pointing at the root cause.
+// The supervisor periodically checks the liveness of every tracked daemon,
+// restarts dead daemons using a caller-provided callback (with a truncated
+// exponential backoff to avoid tight restart loops), and records per-daemon
+// health information (PID, restart counter, time of the last unexpected exit)
+// which the caller is expected to publish alongside its status.
+package procsupervisor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+const (
+	// Maximum number of process health updates queued for a single watcher
+	// channel.
+	watcherChanBufSize = 16
+)
+
+// Config : configuration for the process supervisor.
+// Currently, this is not configurable via controller.
+type Config struct {
+	// CheckInterval : how often to check liveness of the tracked daemons.
+	CheckInterval time.Duration
+	// RestartMinBackoff : initial interval between successive restart attempts
+	// of a crashing daemon. The interval doubles with every attempt.
+	RestartMinBackoff time.Duration
+	// RestartMaxBackoff : upper limit for the interval between successive
+	// restart attempts of a crashing daemon.
+	RestartMaxBackoff time.Duration
+	// StableRunPeriod : how long a daemon must keep running after a restart
+	// for the restart backoff to reset back to RestartMinBackoff.
+	StableRunPeriod time.Duration
+}
+
+// DefaultConfig : default configuration for the process supervisor.
+func DefaultConfig() Config {
+	return Config{
+		CheckInterval:     10 * time.Second,
+		RestartMinBackoff: 10 * time.Second,
+		RestartMaxBackoff: 5 * time.Minute,
+		StableRunPeriod:   10 * time.Minute,
+	}
+}
+
+// ProcessConfig : a daemon instance to supervise.
+type ProcessConfig struct {
+	// Name uniquely identifies the daemon instance among all tracked
+	// processes, e.g. "dhcpcd-eth0" or "dnsmasq-bn1".
+	Name string
+	// CheckAlive returns the PID of the daemon and whether it is currently
+	// running. Return zero PID if it cannot be determined.
+	CheckAlive func() (pid int, running bool)
+	// Restart is called to start the daemon again after an unexpected exit.
+	// Leave as nil for daemons which the caller merely monitors but does not
+	// spawn itself (e.g. wpa_supplicant, managed by a separate service)
+	// - the supervisor will then only report health without attempting
+	// restarts.
+	Restart func() error
+}
+
+// ProcessStatus : health information recorded for a tracked daemon instance.
+type ProcessStatus struct {
+	// Name of the daemon instance (see ProcessConfig.Name).
+	Name string
+	// Running is true if the daemon was alive at the last liveness check.
+	Running bool
+	// Pid of the running daemon (zero if not running or not known).
+	Pid int
+	// Restarts : how many times the supervisor restarted the daemon.
+	Restarts uint32
+	// LastExitTime : time when the supervisor last detected an unexpected
+	// exit of the daemon.
+	LastExitTime time.Time
+	// LastRestartTime : time of the last restart attempt.
+	LastRestartTime time.Time
+	// LastRestartError : error returned by the last restart attempt
+	// (empty string if it succeeded).
+	LastRestartError string
+}
+
+// supervisedProc : run-time state of a single tracked daemon.
+type supervisedProc struct {
+	config ProcessConfig
+	status ProcessStatus
+	// backoff : current interval to wait before the next restart attempt.
+	// Zero until the first restart attempt, reset back to zero after
+	// a stable run.
+	backoff time.Duration
+	// nextRestart : time when the next restart attempt is due.
+	// Zero time if the daemon is running.
+	nextRestart time.Time
+	// runningSince : time when the daemon was last seen transitioning
+	// into the running state.
+	runningSince time.Time
+}
+
+// ProcSupervisor tracks liveness of registered daemon instances, restarts
+// those that exited unexpectedly and records per-daemon health information.
+type ProcSupervisor struct {
+	sync.Mutex
+	log        *base.LogObject
+	config     Config
+	procs      map[string]*supervisedProc // key: ProcessConfig.Name
+	watcherChs []chan []ProcessStatus
+}
+
+// NewProcSupervisor is a constructor for the process supervisor.
+func NewProcSupervisor(log *base.LogObject, config Config) *ProcSupervisor {
+	s := &ProcSupervisor{
+		log:    log,
+		config: config,
+		procs:  make(map[string]*supervisedProc),
+	}
+	go s.runSupervision()
+	return s
+}
+
+// WatchHealthUpdates returns a channel where the supervisor publishes
+// the health status of all tracked daemons whenever it changes.
+func (s *ProcSupervisor) WatchHealthUpdates() <-chan []ProcessStatus {
+	s.Lock()
+	defer s.Unlock()
+	watcherCh := make(chan []ProcessStatus, watcherChanBufSize)
+	s.watcherChs = append(s.watcherChs, watcherCh)
+	return watcherCh
+}
+
+// TrackProcess starts supervision of the given daemon instance.
+// If the instance is already tracked (by name), only the callbacks are
+// updated while the recorded health status is preserved.
+func (s *ProcSupervisor) TrackProcess(config ProcessConfig) {
+	s.Lock()
+	defer s.Unlock()
+	if proc, tracked := s.procs[config.Name]; tracked {
+		proc.config = config
+		return
+	}
+	proc := &supervisedProc{
+		config: config,
+		status: ProcessStatus{Name: config.Name},
+	}
+	s.procs[config.Name] = proc
+	s.checkProcess(proc, time.Now())
+	s.notifyWatchers()
+}
+
+// ForgetProcess stops supervision of the given daemon instance.
+func (s *ProcSupervisor) ForgetProcess(name string) {
+	s.Lock()
+	defer s.Unlock()
+	if _, tracked := s.procs[name]; !tracked {
+		return
+	}
+	delete(s.procs, name)
+	s.notifyWatchers()
+}
+
+// GetProcessStatus returns the currently recorded health status of all
+// tracked daemon instances, sorted by name.
+func (s *ProcSupervisor) GetProcessStatus() []ProcessStatus {
+	s.Lock()
+	defer s.Unlock()
+	return s.getProcessStatus()
+}
+
+// getProcessStatus returns health status of all tracked daemons.
+// The caller must hold the supervisor lock.
+func (s *ProcSupervisor) getProcessStatus() []ProcessStatus {
+	statuses := make([]ProcessStatus, 0, len(s.procs))
+	for _, proc := range s.procs {
+		statuses = append(statuses, proc.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// runSupervision periodically checks liveness of all tracked daemons.
+func (s *ProcSupervisor) runSupervision() {
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.Lock()
+		now := time.Now()
+		var changed bool
+		for _, proc := range s.procs {
+			if s.checkProcess(proc, now) {
+				changed = true
+			}
+		}
+		if changed {
+			s.notifyWatchers()
+		}
+		s.Unlock()
+	}
+}
+
+// checkProcess performs a single liveness check of the given daemon,
+// potentially attempting a restart if the daemon is not running.
+// Returns true if the recorded health status has changed.
+// The caller must hold the supervisor lock.
+func (s *ProcSupervisor) checkProcess(proc *supervisedProc, now time.Time) (changed bool) {
+	pid, running := proc.config.CheckAlive()
+	if running {
+		if !proc.status.Running {
+			proc.status.Running = true
+			proc.runningSince = now
+			proc.nextRestart = time.Time{}
+			changed = true
+		}
+		if proc.status.Pid != pid {
+			proc.status.Pid = pid
+			changed = true
+		}
+		if proc.backoff != 0 && now.Sub(proc.runningSince) >= s.config.StableRunPeriod {
+			// The daemon has been running long enough to consider it stable
+			// again - do not delay a potential future restart.
+			proc.backoff = 0
+		}
+		return changed
+	}
+	if proc.status.Running {
+		// Unexpected exit detected.
+		s.log.Warnf("procsupervisor: process %s (PID %d) exited unexpectedly",
+			proc.status.Name, proc.status.Pid)
+		proc.status.Running = false
+		proc.status.Pid = 0
+		proc.status.LastExitTime = now
+		proc.nextRestart = now.Add(proc.backoff)
+		changed = true
+	}
+	if proc.config.Restart == nil {
+		// Monitor-only daemon - somebody else is responsible for restarts.
+		return changed
+	}
+	if proc.nextRestart.IsZero() || now.Before(proc.nextRestart) {
+		return changed
+	}
+	s.log.Noticef("procsupervisor: restarting process %s (restart count: %d)",
+		proc.status.Name, proc.status.Restarts+1)
+	err := proc.config.Restart()
+	proc.status.Restarts++
+	proc.status.LastRestartTime = now
+	proc.status.LastRestartError = ""
+	if err != nil {
+		s.log.Errorf("procsupervisor: failed to restart process %s: %v",
+			proc.status.Name, err)
+		proc.status.LastRestartError = err.Error()
+	}
+	if proc.backoff == 0 {
+		proc.backoff = s.config.RestartMinBackoff
+	} else {
+		proc.backoff *= 2
+		if proc.backoff > s.config.RestartMaxBackoff {
+			proc.backoff = s.config.RestartMaxBackoff
+		}
+	}
+	proc.nextRestart = now.Add(proc.backoff)
+	return true
+}
+
+// notifyWatchers sends the current health status of all tracked daemons
+// to all registered watchers. The caller must hold the supervisor lock.
+func (s *ProcSupervisor) notifyWatchers() {
+	statuses := s.getProcessStatus()
+	for _, watcherCh := range s.watcherChs {
+		select {
+		case watcherCh <- statuses:
+		default:
+			// Watcher is lagging behind, drop the update.
+		}
+	}
+}
+
+// PidFileCheckAlive returns a CheckAlive callback (see ProcessConfig) which
+// determines daemon liveness by reading the given PID file and checking
+// the existence of the recorded process.
+func PidFileCheckAlive(pidFile string) func() (pid int, running bool) {
+	return func() (pid int, running bool) {
+		content, err := os.ReadFile(pidFile)
+		if err != nil {
+			return 0, false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(content)))
+		if err != nil {
+			return 0, false
+		}
+		return pid, processExists(pid)
+	}
+}
+
+// ProcNameCheckAlive returns a CheckAlive callback (see ProcessConfig) which
+// determines daemon liveness by searching for a process with the given
+// command name (as reported by /proc/<pid>/comm).
+// This is useful for daemons which do not maintain a PID file.
+func ProcNameCheckAlive(procName string) func() (pid int, running bool) {
+	return func() (pid int, running bool) {
+		dirEntries, err := os.ReadDir("/proc")
+		if err != nil {
+			return 0, false
+		}
+		for _, dirEntry := range dirEntries {
+			pid, err := strconv.Atoi(dirEntry.Name())
+			if err != nil {
+				continue
+			}
+			comm, err := os.ReadFile(
+				filepath.Join("/proc", dirEntry.Name(), "comm"))
+			if err != nil {
+				continue
+			}
+			if strings.TrimSpace(string(comm)) == procName {
+				return pid, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// processExists returns true if a process with the given PID is running.
+func processExists(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// String describes the process status.
+func (s ProcessStatus) String() string {
+	return fmt.Sprintf("ProcessStatus: {Name: %s, Running: %t, Pid: %d, "+
+		"Restarts: %d, LastExitTime: %v, LastRestartTime: %v, "+
+		"LastRestartError: %s}", s.Name, s.Running, s.Pid, s.Restarts,
+		s.LastExitTime, s.LastRestartTime, s.LastRestartError)
+}
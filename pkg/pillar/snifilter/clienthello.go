@@ -0,0 +1,120 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package snifilter
+
+// Parsing of the Server Name Indication extension from a TLS ClientHello.
+// Only the fields needed to locate the extension are interpreted, everything
+// else is skipped over. The parser expects the ClientHello to be fully
+// contained in the given TCP payload - in the rare case of a ClientHello
+// split across multiple TCP segments, isClientHello is returned as false
+// and the caller applies the configured fallback behavior.
+
+const (
+	tlsRecordTypeHandshake      = 0x16
+	tlsHandshakeTypeClientHello = 0x01
+	tlsExtensionServerName      = 0x0000
+	sniTypeHostName             = 0x00
+)
+
+// parseTLSClientHelloSNI extracts the server name from a TLS ClientHello.
+// isClientHello is true if the payload carries a complete ClientHello
+// handshake message (even if it contains no SNI extension, in which case
+// sni is returned empty).
+func parseTLSClientHelloSNI(payload []byte) (sni string, isClientHello bool) {
+	// TLS record header: type (1B), version (2B), length (2B).
+	if len(payload) < 5 || payload[0] != tlsRecordTypeHandshake {
+		return "", false
+	}
+	recordLen := int(payload[3])<<8 | int(payload[4])
+	record := payload[5:]
+	if len(record) < recordLen {
+		// ClientHello split across TCP segments.
+		return "", false
+	}
+	record = record[:recordLen]
+	// Handshake header: type (1B), length (3B).
+	if len(record) < 4 || record[0] != tlsHandshakeTypeClientHello {
+		return "", false
+	}
+	helloLen := int(record[1])<<16 | int(record[2])<<8 | int(record[3])
+	hello := record[4:]
+	if len(hello) < helloLen {
+		return "", false
+	}
+	hello = hello[:helloLen]
+	// ClientHello: version (2B), random (32B).
+	if len(hello) < 34 {
+		return "", false
+	}
+	hello = hello[34:]
+	// Session ID: length (1B) + data.
+	if len(hello) < 1 {
+		return "", false
+	}
+	sessionIDLen := int(hello[0])
+	if len(hello) < 1+sessionIDLen {
+		return "", false
+	}
+	hello = hello[1+sessionIDLen:]
+	// Cipher suites: length (2B) + data.
+	if len(hello) < 2 {
+		return "", false
+	}
+	cipherLen := int(hello[0])<<8 | int(hello[1])
+	if len(hello) < 2+cipherLen {
+		return "", false
+	}
+	hello = hello[2+cipherLen:]
+	// Compression methods: length (1B) + data.
+	if len(hello) < 1 {
+		return "", false
+	}
+	compLen := int(hello[0])
+	if len(hello) < 1+compLen {
+		return "", false
+	}
+	hello = hello[1+compLen:]
+	if len(hello) < 2 {
+		// No extensions - a valid ClientHello, just without SNI.
+		return "", true
+	}
+	extsLen := int(hello[0])<<8 | int(hello[1])
+	exts := hello[2:]
+	if len(exts) < extsLen {
+		return "", false
+	}
+	exts = exts[:extsLen]
+	for len(exts) >= 4 {
+		extType := int(exts[0])<<8 | int(exts[1])
+		extLen := int(exts[2])<<8 | int(exts[3])
+		if len(exts) < 4+extLen {
+			return "", false
+		}
+		extData := exts[4 : 4+extLen]
+		exts = exts[4+extLen:]
+		if extType != tlsExtensionServerName {
+			continue
+		}
+		// Server name list: length (2B), then entries of
+		// type (1B) + length (2B) + name.
+		if len(extData) < 2 {
+			return "", true
+		}
+		extData = extData[2:]
+		for len(extData) >= 3 {
+			nameType := extData[0]
+			nameLen := int(extData[1])<<8 | int(extData[2])
+			if len(extData) < 3+nameLen {
+				return "", true
+			}
+			name := extData[3 : 3+nameLen]
+			extData = extData[3+nameLen:]
+			if nameType == sniTypeHostName {
+				return string(name), true
+			}
+		}
+		return "", true
+	}
+	return "", true
+}
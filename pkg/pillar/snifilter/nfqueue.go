@@ -0,0 +1,201 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package snifilter
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/mdlayher/netlink"
+	"github.com/ti-mo/netfilter"
+	"golang.org/x/sys/unix"
+)
+
+// Minimal client for the netfilter queue (nfnetlink_queue) subsystem,
+// built on top of the generic netfilter netlink support already used
+// for conntrack. Only the features needed by the SNI filter are
+// implemented: binding to a queue, receiving packets in the COPY_PACKET
+// mode and issuing accept/drop verdicts.
+
+// Message types of the netfilter queue subsystem
+// (uapi/linux/netfilter/nfnetlink_queue.h).
+const (
+	nfqnlMsgPacket  = 0 // packet delivered from the kernel
+	nfqnlMsgVerdict = 1 // verdict from userspace
+	nfqnlMsgConfig  = 2 // queue configuration
+)
+
+// Attributes of NFQNL_MSG_CONFIG messages.
+const (
+	nfqaCfgCmd         = 1
+	nfqaCfgParams      = 2
+	nfqaCfgQueueMaxLen = 3
+)
+
+// Commands carried by the NFQA_CFG_CMD attribute.
+const (
+	nfqnlCfgCmdBind   = 1
+	nfqnlCfgCmdUnbind = 2
+)
+
+// Copy modes carried by the NFQA_CFG_PARAMS attribute.
+const (
+	nfqnlCopyPacket = 2
+)
+
+// Attributes of NFQNL_MSG_PACKET and NFQNL_MSG_VERDICT messages.
+const (
+	nfqaPacketHdr  = 1
+	nfqaVerdictHdr = 2
+	nfqaPayload    = 10
+)
+
+// Netfilter verdicts (uapi/linux/netfilter.h).
+const (
+	nfDrop   = 0
+	nfAccept = 1
+)
+
+// queueMaxLen : how many packets the kernel will hold waiting for a verdict
+// before applying the queue fallback (drop or bypass).
+const queueMaxLen = 1024
+
+// queuedPacket : one packet delivered from the kernel for a verdict.
+type queuedPacket struct {
+	// id : packet identifier to use in the verdict.
+	id uint32
+	// payload : packet content starting with the IP header.
+	payload []byte
+}
+
+// nfQueue represents a bound netfilter queue.
+type nfQueue struct {
+	queueNum uint16
+	conn     *netlink.Conn
+	packets  chan queuedPacket
+}
+
+// newNFQueue binds to the given netfilter queue and starts delivering
+// queued packets on the packets channel.
+func newNFQueue(queueNum uint16) (*nfQueue, error) {
+	conn, err := netlink.Dial(unix.NETLINK_NETFILTER, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netfilter socket: %w", err)
+	}
+	q := &nfQueue{
+		queueNum: queueNum,
+		conn:     conn,
+		packets:  make(chan queuedPacket),
+	}
+	params := make([]byte, 5) // struct nfqnl_msg_config_params
+	binary.BigEndian.PutUint32(params[0:4], 0xffff)
+	params[4] = nfqnlCopyPacket
+	maxLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(maxLen, queueMaxLen)
+	cfgAttrs := [][]netfilter.Attribute{
+		{{Type: nfqaCfgCmd, Data: []byte{nfqnlCfgCmdBind, 0, 0, 0}}},
+		{{Type: nfqaCfgParams, Data: params}},
+		{{Type: nfqaCfgQueueMaxLen, Data: maxLen}},
+	}
+	for _, attrs := range cfgAttrs {
+		if err := q.sendConfig(attrs); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	go q.receivePackets()
+	return q, nil
+}
+
+// close unbinds from the queue and closes the netlink socket.
+func (q *nfQueue) close() error {
+	err := q.sendConfig([]netfilter.Attribute{
+		{Type: nfqaCfgCmd, Data: []byte{nfqnlCfgCmdUnbind, 0, 0, 0}},
+	})
+	if err2 := q.conn.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// sendConfig sends one NFQNL_MSG_CONFIG message and waits for the kernel ack.
+func (q *nfQueue) sendConfig(attrs []netfilter.Attribute) error {
+	msg, err := netfilter.MarshalNetlink(netfilter.Header{
+		SubsystemID: netfilter.NFSubsysQueue,
+		MessageType: nfqnlMsgConfig,
+		Family:      netfilter.ProtoUnspec,
+		ResourceID:  q.queueNum,
+		Flags:       netlink.Request | netlink.Acknowledge,
+	}, attrs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue config: %w", err)
+	}
+	if _, err := q.conn.Execute(msg); err != nil {
+		return fmt.Errorf("netfilter queue config failed: %w", err)
+	}
+	return nil
+}
+
+// setVerdict informs the kernel what to do with the given queued packet.
+func (q *nfQueue) setVerdict(packetID uint32, verdict uint32) error {
+	verdictHdr := make([]byte, 8) // struct nfqnl_msg_verdict_hdr
+	binary.BigEndian.PutUint32(verdictHdr[0:4], verdict)
+	binary.BigEndian.PutUint32(verdictHdr[4:8], packetID)
+	msg, err := netfilter.MarshalNetlink(netfilter.Header{
+		SubsystemID: netfilter.NFSubsysQueue,
+		MessageType: nfqnlMsgVerdict,
+		Family:      netfilter.ProtoUnspec,
+		ResourceID:  q.queueNum,
+		Flags:       netlink.Request,
+	}, []netfilter.Attribute{
+		{Type: nfqaVerdictHdr, Data: verdictHdr},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal verdict: %w", err)
+	}
+	// Verdicts are fire-and-forget - no ack is requested.
+	if _, err := q.conn.Send(msg); err != nil {
+		return fmt.Errorf("failed to send verdict: %w", err)
+	}
+	return nil
+}
+
+// receivePackets reads NFQNL_MSG_PACKET messages from the kernel and passes
+// them on for inspection. Runs until the socket is closed.
+func (q *nfQueue) receivePackets() {
+	defer close(q.packets)
+	for {
+		msgs, err := q.conn.Receive()
+		if err != nil {
+			// Socket closed (or a fatal error) - stop delivering packets.
+			return
+		}
+		for _, msg := range msgs {
+			header, attrs, err := netfilter.UnmarshalNetlink(msg)
+			if err != nil {
+				continue
+			}
+			if header.SubsystemID != netfilter.NFSubsysQueue ||
+				header.MessageType != nfqnlMsgPacket {
+				continue
+			}
+			var packet queuedPacket
+			var haveID bool
+			for _, attr := range attrs {
+				switch attr.Type {
+				case nfqaPacketHdr:
+					if len(attr.Data) >= 4 {
+						packet.id = binary.BigEndian.Uint32(attr.Data[0:4])
+						haveID = true
+					}
+				case nfqaPayload:
+					packet.payload = attr.Data
+				}
+			}
+			if haveID {
+				q.packets <- packet
+			}
+		}
+	}
+}
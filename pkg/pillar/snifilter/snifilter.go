@@ -0,0 +1,289 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package snifilter implements an SNI-inspecting allowlist for egress TLS
+// traffic of application VIFs. For TLS traffic, IP-based ACLs are often
+// impractical - CDN-hosted services resolve to large and frequently changing
+// IP ranges. Instead, NIReconciler installs an iptables NFQUEUE rule for
+// VIFs with a configured allowlist (see AppNetAdapterConfig.SNIAllowlist)
+// and this package reads the queued packets, extracts the Server Name
+// Indication from the TLS ClientHello and issues an allow/drop verdict
+// based on the configured domain patterns.
+// Connections whose server name cannot be determined (e.g. ClientHello
+// split across TCP segments, or non-TLS traffic on the TLS port) are
+// subject to a per-NI configurable fallback behavior.
+package snifilter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	uuid "github.com/satori/go.uuid"
+)
+
+// DefaultQueueNum : the netfilter queue number used to pass TLS packets
+// subject to SNI filtering from the kernel to this package.
+// Referenced by NIReconciler when generating the NFQUEUE iptables rules.
+const DefaultQueueNum = 924
+
+// flowIdleTimeout : how long to keep the verdict cached for a flow without
+// seeing any of its packets.
+const flowIdleTimeout = 10 * time.Minute
+
+// SNIFilter inspects TLS ClientHello packets redirected from the kernel
+// via NFQUEUE and allows or drops the corresponding connections based
+// on per-VIF domain allowlists.
+type SNIFilter struct {
+	sync.Mutex
+	log    *base.LogObject
+	config Config
+
+	// key: VIF guest IP as string
+	vifs map[string]VIFConfig
+	// key: flow 5-tuple rendered as string
+	flows map[string]*flowVerdict
+
+	queue     *nfQueue
+	queueErr  error
+	cancelRun context.CancelFunc
+}
+
+// Config : configuration for SNIFilter.
+// Currently, this is not configurable via controller.
+type Config struct {
+	// QueueNum : netfilter queue number to read packets from.
+	// Must match the queue number used in the NFQUEUE iptables rules.
+	QueueNum uint16
+}
+
+// DefaultConfig returns the default SNIFilter configuration.
+func DefaultConfig() Config {
+	return Config{
+		QueueNum: DefaultQueueNum,
+	}
+}
+
+// VIFConfig : SNI allowlist configured for one application VIF.
+type VIFConfig struct {
+	// App : UUID of the application owning the VIF.
+	App uuid.UUID
+	// NI : UUID of the network instance the VIF is connected to.
+	NI uuid.UUID
+	// GuestIP : IP address used by the application on this VIF.
+	// Used to match queued packets with the VIF they were sent from.
+	GuestIP net.IP
+	// Allowlist : domain patterns which the server name carried
+	// in the TLS ClientHello is matched against. Suffix-matched,
+	// i.e. "zededa.net" also matches any subdomain of zededa.net.
+	Allowlist []string
+	// FallbackDrop : if true, connections whose server name cannot
+	// be determined are dropped instead of allowed.
+	FallbackDrop bool
+}
+
+// flowVerdict : cached verdict for one TCP connection.
+type flowVerdict struct {
+	allowed  bool
+	decided  bool
+	lastSeen time.Time
+}
+
+// NewSNIFilter is the constructor for SNIFilter.
+// The netfilter queue is opened lazily, once the first VIF with an SNI
+// allowlist is tracked.
+func NewSNIFilter(log *base.LogObject, config Config) *SNIFilter {
+	return &SNIFilter{
+		log:    log,
+		config: config,
+		vifs:   make(map[string]VIFConfig),
+		flows:  make(map[string]*flowVerdict),
+	}
+}
+
+// TrackVIFs : replace the set of VIFs with SNI allowlists to enforce.
+// The netfilter queue is opened when the set becomes non-empty and closed
+// when it becomes empty.
+func (f *SNIFilter) TrackVIFs(vifs []VIFConfig) {
+	f.Lock()
+	defer f.Unlock()
+	f.vifs = make(map[string]VIFConfig)
+	for _, vif := range vifs {
+		if vif.GuestIP == nil {
+			// Without the guest IP we cannot attribute queued packets
+			// to the VIF - its TLS traffic is allowed through until
+			// the IP address is learned.
+			continue
+		}
+		f.vifs[vif.GuestIP.String()] = vif
+	}
+	if len(f.vifs) > 0 && f.queue == nil {
+		f.openQueue()
+	}
+	if len(f.vifs) == 0 && f.queue != nil {
+		f.closeQueue()
+	}
+}
+
+// openQueue binds to the netfilter queue and starts the packet processing
+// loop. Caller should hold the lock.
+func (f *SNIFilter) openQueue() {
+	queue, err := newNFQueue(f.config.QueueNum)
+	if err != nil {
+		// NFQUEUE rules are installed with or without --queue-bypass
+		// depending on the configured fallback, so the kernel applies
+		// the intended fallback behavior while the queue is not bound.
+		f.log.Errorf("snifilter: failed to open netfilter queue %d: %v",
+			f.config.QueueNum, err)
+		f.queueErr = err
+		return
+	}
+	f.queue = queue
+	f.queueErr = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancelRun = cancel
+	go f.runPacketProcessing(ctx, queue)
+	f.log.Noticef("snifilter: bound to netfilter queue %d", f.config.QueueNum)
+}
+
+// closeQueue stops the packet processing loop and unbinds from the netfilter
+// queue. Caller should hold the lock.
+func (f *SNIFilter) closeQueue() {
+	f.cancelRun()
+	if err := f.queue.close(); err != nil {
+		f.log.Warnf("snifilter: failed to close netfilter queue: %v", err)
+	}
+	f.queue = nil
+	f.flows = make(map[string]*flowVerdict)
+	f.log.Noticef("snifilter: unbound from netfilter queue %d", f.config.QueueNum)
+}
+
+// runPacketProcessing reads queued packets and issues verdicts until
+// the queue is closed.
+func (f *SNIFilter) runPacketProcessing(ctx context.Context, queue *nfQueue) {
+	gcTicker := time.NewTicker(flowIdleTimeout)
+	defer gcTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-gcTicker.C:
+			f.gcIdleFlows()
+		case packet, ok := <-queue.packets:
+			if !ok {
+				return
+			}
+			allow := f.inspectPacket(packet.payload)
+			var verdict uint32 = nfDrop
+			if allow {
+				verdict = nfAccept
+			}
+			if err := queue.setVerdict(packet.id, verdict); err != nil {
+				f.log.Warnf("snifilter: failed to set verdict for packet %d: %v",
+					packet.id, err)
+			}
+		}
+	}
+}
+
+// inspectPacket decides whether the given queued packet (starting with
+// the IP header) should be allowed through.
+func (f *SNIFilter) inspectPacket(payload []byte) (allow bool) {
+	if len(payload) == 0 {
+		return true
+	}
+	var firstLayer gopacket.LayerType
+	switch payload[0] >> 4 {
+	case 4:
+		firstLayer = layers.LayerTypeIPv4
+	case 6:
+		firstLayer = layers.LayerTypeIPv6
+	default:
+		return true
+	}
+	packet := gopacket.NewPacket(payload, firstLayer,
+		gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+	netLayer := packet.NetworkLayer()
+	transLayer := packet.Layer(layers.LayerTypeTCP)
+	if netLayer == nil || transLayer == nil {
+		return true
+	}
+	tcp := transLayer.(*layers.TCP)
+	srcIP := netLayer.NetworkFlow().Src().String()
+	flowKey := fmt.Sprintf("%s:%d-%s:%d", srcIP, tcp.SrcPort,
+		netLayer.NetworkFlow().Dst().String(), tcp.DstPort)
+
+	f.Lock()
+	defer f.Unlock()
+	vif, haveVIF := f.vifs[srcIP]
+	if !haveVIF {
+		// Not from a VIF with an SNI allowlist (e.g. a stale iptables rule
+		// or the VIF config was just removed). Do not interfere.
+		return true
+	}
+	flow := f.flows[flowKey]
+	if flow == nil {
+		flow = &flowVerdict{}
+		f.flows[flowKey] = flow
+	}
+	flow.lastSeen = time.Now()
+	if flow.decided {
+		return flow.allowed
+	}
+	if len(tcp.Payload) == 0 {
+		// TCP handshake or bare ACK - let it through, the verdict is made
+		// on the first data segment (expected to carry the ClientHello).
+		return true
+	}
+	sni, isClientHello := parseTLSClientHelloSNI(tcp.Payload)
+	if !isClientHello || sni == "" {
+		// Server name cannot be determined - apply the configured fallback.
+		flow.decided = true
+		flow.allowed = !vif.FallbackDrop
+		if !flow.allowed {
+			f.log.Noticef("snifilter: dropping connection %s from app %s: "+
+				"server name could not be determined", flowKey, vif.App)
+		}
+		return flow.allowed
+	}
+	flow.decided = true
+	flow.allowed = matchSNI(sni, vif.Allowlist)
+	if !flow.allowed {
+		f.log.Noticef("snifilter: dropping connection %s from app %s: "+
+			"server name %s does not match the allowlist", flowKey, vif.App, sni)
+	}
+	return flow.allowed
+}
+
+// gcIdleFlows removes cached verdicts of flows without recent activity.
+func (f *SNIFilter) gcIdleFlows() {
+	f.Lock()
+	defer f.Unlock()
+	now := time.Now()
+	for key, flow := range f.flows {
+		if now.Sub(flow.lastSeen) > flowIdleTimeout {
+			delete(f.flows, key)
+		}
+	}
+}
+
+// matchSNI returns true if the server name matches at least one
+// of the given domain patterns. Patterns are suffix-matched using
+// the same semantics as the "host" ACL match, i.e. "zededa.net"
+// matches both "zededa.net" and any of its subdomains.
+func matchSNI(sni string, patterns []string) bool {
+	sni = strings.TrimSuffix(strings.ToLower(sni), ".")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(pattern), ".")
+		if sni == pattern || strings.HasSuffix(sni, "."+pattern) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"net"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// MaxAppDestinationBuckets : at most this many top destination buckets
+// are published per application in AppDestinationStats.
+const MaxAppDestinationBuckets = 10
+
+// AppDestinationStats : on-device aggregate of application network usage
+// towards remote destinations, published periodically by zedrouter.
+// Flow records are bucketed by the remote CIDR prefix and only the top
+// buckets by total byte count are retained, so that bandwidth hogs can be
+// identified in the controller without shipping full flow logs.
+type AppDestinationStats struct {
+	// AppUUID : UUID of the application.
+	AppUUID uuid.UUID
+	// WindowStart/WindowEnd : time window over which the statistics
+	// were aggregated.
+	WindowStart time.Time
+	WindowEnd   time.Time
+	// TopDestinations : destination buckets with the highest total byte
+	// counts within the window, ordered from the highest to the lowest.
+	// At most MaxAppDestinationBuckets entries.
+	TopDestinations []AppDestinationBucket
+}
+
+// Key : pubsub key used by zedrouter to publish per-app destination
+// statistics.
+func (s AppDestinationStats) Key() string {
+	return s.AppUUID.String()
+}
+
+// AppDestinationBucket : traffic aggregated towards one remote CIDR prefix
+// (/24 for IPv4, /64 for IPv6).
+type AppDestinationBucket struct {
+	// Prefix : the remote CIDR prefix of this bucket.
+	Prefix net.IPNet
+	// TxBytes : bytes sent by the application towards this prefix.
+	TxBytes uint64
+	// RxBytes : bytes received by the application from this prefix.
+	RxBytes uint64
+	// FlowCount : number of flow records aggregated into this bucket.
+	FlowCount uint32
+}
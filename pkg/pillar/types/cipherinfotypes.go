@@ -4,6 +4,7 @@
 package types
 
 import (
+	"bytes"
 	"encoding/hex"
 
 	"github.com/google/go-cmp/cmp"
@@ -91,6 +92,32 @@ type CipherBlockStatus struct {
 	ErrorAndTime
 }
 
+// EquivalentTo compares two cipher blocks by the identity of the encrypted
+// payload rather than by the exact ciphertext bytes.
+// When the controller rotates encryption keys, unchanged secrets get
+// re-encrypted: CipherData (and possibly the cipher context) change while
+// the hash of the decrypted content stays the same. Comparing the cleartext
+// hashes therefore detects real changes of the secret without mistaking
+// mere re-encryption for a config change.
+// Without cleartext hashes available on both sides, the comparison falls
+// back to the ciphertext itself.
+func (status CipherBlockStatus) EquivalentTo(status2 CipherBlockStatus) bool {
+	if status.IsCipher != status2.IsCipher {
+		return false
+	}
+	if !status.IsCipher {
+		// Nothing is encrypted. Cleartext attributes (stored outside
+		// of the cipher block) are compared by the caller.
+		return true
+	}
+	if len(status.ClearTextHash) > 0 && len(status2.ClearTextHash) > 0 {
+		return bytes.Equal(status.ClearTextHash, status2.ClearTextHash)
+	}
+	return status.CipherContextID == status2.CipherContextID &&
+		bytes.Equal(status.InitialValue, status2.InitialValue) &&
+		bytes.Equal(status.CipherData, status2.CipherData)
+}
+
 // Key :
 func (status *CipherBlockStatus) Key() string {
 	return status.CipherBlockID
@@ -15,6 +15,8 @@ type TestResults struct {
 	LastFailed    time.Time
 	LastSucceeded time.Time
 	LastError     string // Set when LastFailed is updated
+	// LastErrorCode : machine-readable classification of LastError.
+	LastErrorCode NetworkErrorCode // Set when LastFailed is updated
 }
 
 // RecordSuccess records a success
@@ -22,6 +24,7 @@ type TestResults struct {
 func (trPtr *TestResults) RecordSuccess() {
 	trPtr.LastSucceeded = time.Now()
 	trPtr.LastError = ""
+	trPtr.LastErrorCode = NetworkErrorCodeUnspecified
 }
 
 // RecordFailure records a failure
@@ -32,6 +35,19 @@ func (trPtr *TestResults) RecordFailure(errStr string) {
 	}
 	trPtr.LastFailed = time.Now()
 	trPtr.LastError = errStr
+	trPtr.LastErrorCode = NetworkErrorCodeUnspecified
+}
+
+// RecordFailureFromError records a failure just like RecordFailure, but
+// additionally tries to classify the error and stores the obtained
+// machine-readable error code alongside the error message.
+func (trPtr *TestResults) RecordFailureFromError(err error) {
+	if err == nil {
+		logrus.Fatal("Missing error")
+	}
+	trPtr.LastFailed = time.Now()
+	trPtr.LastError = err.Error()
+	trPtr.LastErrorCode = GetNetworkErrorCode(err)
 }
 
 // HasError returns true if there is an error
@@ -46,12 +62,14 @@ func (trPtr *TestResults) Update(src TestResults) {
 	if src.HasError() {
 		trPtr.LastFailed = src.LastFailed
 		trPtr.LastError = src.LastError
+		trPtr.LastErrorCode = src.LastErrorCode
 		if src.LastSucceeded.After(trPtr.LastSucceeded) {
 			trPtr.LastSucceeded = src.LastSucceeded
 		}
 	} else {
 		trPtr.LastSucceeded = src.LastSucceeded
 		trPtr.LastError = ""
+		trPtr.LastErrorCode = NetworkErrorCodeUnspecified
 		if src.LastFailed.After(trPtr.LastFailed) {
 			trPtr.LastFailed = src.LastFailed
 		}
@@ -63,6 +81,7 @@ func (trPtr *TestResults) Clear() {
 	trPtr.LastFailed = time.Time{}
 	trPtr.LastSucceeded = time.Time{}
 	trPtr.LastError = ""
+	trPtr.LastErrorCode = NetworkErrorCodeUnspecified
 }
 
 // IntfStatusMap - Used to return per-interface test results (success and failures)
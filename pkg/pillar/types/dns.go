@@ -25,21 +25,96 @@ type DeviceNetworkStatus struct {
 	State        DPCState                // Details about testing state
 	CurrentIndex int                     // For logs
 	RadioSilence RadioSilence            // The actual state of the radio-silence mode
-	Ports        []NetworkPortStatus
+	// DeferredDpcKey is the key of a disruptive DPC queued until the next
+	// maintenance window opens (empty string if there is none).
+	DeferredDpcKey string
+	// DeferredSince : time when the currently queued DPC was deferred.
+	DeferredSince time.Time
+	// ABTestDpcKey is the key of a candidate DPC currently being A/B tested
+	// on the secondary uplinks (empty string if there is none).
+	ABTestDpcKey string
+	// ABTestSince : time when the A/B testing of the candidate DPC started.
+	ABTestSince time.Time
+	// PinnedMgmtPort : logical label of the port to which all management
+	// (controller) traffic is temporarily pinned by the operator
+	// (see MgmtTrafficPin). Empty if no pin is active.
+	PinnedMgmtPort string
+	// PinnedMgmtPortUntil : time when the management traffic pin expires.
+	PinnedMgmtPortUntil time.Time
+	// PinnedDpcKey : key of the DPC list entry to which the device is
+	// temporarily pinned by the operator (see DPCPinRequest). Empty if no
+	// pin is active. The index of the pinned entry is CurrentIndex.
+	PinnedDpcKey string
+	// PinnedDpcUntil : time when the DPC pin expires and the device returns
+	// to the standard priority-based DPC selection.
+	PinnedDpcUntil time.Time
+	// LastDPCRetest : time when the last operator-requested retest of the
+	// DPC list was started (see DPCRetestRequest). The test results are
+	// reported via the published DevicePortConfigList.
+	LastDPCRetest time.Time
+	Ports         []NetworkPortStatus
+	// MgmtPortExplanations : for every port which is currently not used
+	// to carry management (controller) traffic, a human-readable explanation
+	// of why the port was skipped (e.g. no IP address, higher cost, failed
+	// connectivity test, assigned to PCIBack, radio-silence), keyed by the
+	// port logical label. Ports used for management have no entry.
+	// Re-generated by DpcManager on every port selection decision, intended
+	// for direct consumption by the UI and diag.
+	MgmtPortExplanations map[string]string
+	// SharedPortLabels : controller-defined labels, each referencing the set
+	// of ports matching the configured expressions (next to the built-in
+	// labels "uplink" and "freeuplink", see types.BuiltinSharedPortLabels).
+	SharedPortLabels []SharedPortLabel
+	// ProcessHealth : health of network helper daemons (dhcpcd,
+	// wpa_supplicant, ...) spawned or monitored by NIM.
+	ProcessHealth []ProcessHealth
+}
+
+// ProcessHealth : health of a single network helper daemon instance,
+// as recorded by the process supervisor (see pillar/procsupervisor).
+type ProcessHealth struct {
+	// ProcessName : name of the daemon instance, e.g. "dhcpcd-eth0".
+	ProcessName string
+	// Running is true if the daemon was alive at the last liveness check.
+	Running bool
+	// Pid of the running daemon (zero if not running or not known).
+	Pid int
+	// Restarts : how many times the supervisor restarted the daemon
+	// after an unexpected exit.
+	Restarts uint32
+	// LastExitTime : time of the last unexpected exit of the daemon.
+	LastExitTime time.Time
+	// LastRestartTime : time of the last restart attempt.
+	LastRestartTime time.Time
+	// LastRestartError : error returned by the last restart attempt
+	// (empty string if it succeeded).
+	LastRestartError string
 }
 
 type NetworkPortStatus struct {
-	IfName       string
-	Phylabel     string // Physical name set by controller/model
-	Logicallabel string
-	Alias        string // From SystemAdapter's alias
-	IsMgmt       bool   // Used to talk to controller
-	IsL3Port     bool   // True if port is applicable to operate on the network layer
+	IfName string
+	// ConfiguredIfName : interface name from the port configuration.
+	// Only set when it differs from IfName, i.e. when the underlying NIC was
+	// found under a different name than configured (e.g. after a change
+	// in the kernel/udev enumeration order across reboots) and the port
+	// configuration was remapped to the actual interface at runtime
+	// (see dpcreconciler).
+	ConfiguredIfName string
+	Phylabel         string // Physical name set by controller/model
+	Logicallabel     string
+	Alias            string // From SystemAdapter's alias
+	IsMgmt           bool   // Used to talk to controller
+	IsL3Port         bool   // True if port is applicable to operate on the network layer
 	// InvalidConfig is used to flag port config which failed parsing or (static) validation
 	// checks, such as: malformed IP address, undefined required field, IP address not inside
 	// the subnet, etc.
-	InvalidConfig  bool
-	Cost           uint8
+	InvalidConfig bool
+	Cost          uint8
+	// CostBumpReason : non-empty when the port cost was automatically increased
+	// above the configured value, e.g. because a cellular connection is roaming
+	// or is close to exhausting its data-plan quota (see dpcmanager/cost.go).
+	// In such case Cost contains the bumped value.
+	CostBumpReason string
 	Dhcp           DhcpType
 	Type           NetworkType // IPv4 or IPv6 or Dual stack
 	Subnet         net.IPNet
@@ -48,12 +123,47 @@ type NetworkPortStatus struct {
 	DNSServers     []net.IP // If not set we use Gateway as DNS server
 	NtpServers     []net.IP // This comes from DHCP done on uplink port
 	AddrInfoList   []AddrInfo
+	// SecondaryAddrs : extra static IP addresses (aliases) assigned
+	// to the port (see NetworkPortConfig.SecondaryAddresses).
+	SecondaryAddrs []SecondaryAddress
 	Up             bool
-	MacAddr        net.HardwareAddr
-	DefaultRouters []net.IP
-	MTU            uint16
+	// FlapCount : total number of carrier (link up/down) transitions observed
+	// on the port. A rapidly increasing value typically points to bad cabling.
+	FlapCount uint32
+	// CaptivePortalURL : URL of a captive portal detected on the port during
+	// the latest connectivity testing (empty if none was detected).
+	// Surfaced so that field staff know that the local network (e.g. hotel
+	// or branch Wi-Fi) requires a click-through before the device can reach
+	// the controller.
+	CaptivePortalURL string
+	MacAddr          net.HardwareAddr
+	DefaultRouters   []net.IP
+	// DNSReachability : results of the latest probing of the port's
+	// DNS servers, done by NIM to distinguish "internet reachable
+	// but DNS broken" from full outages.
+	DNSReachability []DNSServerReachability
+	// NTPReachability : results of the latest probing of the port's
+	// NTP servers.
+	NTPReachability []NTPServerReachability
+	MTU             uint16
+	// EthLink : actual negotiated link parameters (speed/duplex).
+	// Only applicable to Ethernet ports.
+	EthLink EthLinkStatus
+	// CableTest : result of the latest on-demand cable test run on the port
+	// (see CableTestRequest). Only applicable to Ethernet ports.
+	CableTest CableTestStatus
+	// Transceiver : diagnostics of the pluggable transceiver (SFP) module
+	// of the port, if there is one (see TransceiverStatus).
+	Transceiver    TransceiverStatus
 	WirelessCfg    WirelessConfig
 	WirelessStatus WirelessStatus
+	// BondStatus : operational state of the bond.
+	// Only applicable to ports with L2Type == L2LinkTypeBond.
+	BondStatus BondStatus
+	// AsymRouteWarning : explains a detected risk of asymmetric routing
+	// on this port (e.g. a subnet shared with another port) and the corrective
+	// action taken. Empty if no such risk was detected.
+	AsymRouteWarning string
 	ProxyConfig
 	L2LinkConfig
 	// TestResults provides recording of failure and success
@@ -66,11 +176,136 @@ type AddrInfo struct {
 	LastGeoTimestamp time.Time
 }
 
+// DNSServerReachability : measured reachability of one DNS server used
+// by a port.
+type DNSServerReachability struct {
+	// Server : IP address of the DNS server.
+	Server net.IP
+	// Reachable : true if the server responded to a test query.
+	Reachable bool
+	// LatencyInMsec : how long the test query took. Zero if it failed.
+	LatencyInMsec uint32
+	// TestedAt : time when the server was last probed.
+	TestedAt time.Time
+}
+
+// NTPServerReachability : measured reachability of one NTP server used
+// by a port.
+type NTPServerReachability struct {
+	// Server : IP address of the NTP server.
+	Server net.IP
+	// Reachable : true if the server responded to a test query.
+	Reachable bool
+	// OffsetInMsec : clock offset between the device and the server.
+	// Only valid if the server is reachable.
+	OffsetInMsec int64
+	// TestedAt : time when the server was last probed.
+	TestedAt time.Time
+}
+
+// CableTestStatus : result of the latest on-demand Ethernet cable test
+// (requested by the operator via CableTestRequest and executed by NIM
+// using the ethtool cable-test facility of the NIC PHY).
+type CableTestStatus struct {
+	// RequestedAt : time when the test was requested (copied from
+	// CableTestRequest; identifies the request this result belongs to).
+	RequestedAt time.Time
+	// CompletedAt : time when the test finished.
+	CompletedAt time.Time
+	// Pairs : per-wire-pair results reported by the PHY.
+	Pairs []CableTestPairStatus
+	// Error : non-empty if the test could not be executed, e.g. because
+	// the NIC does not support cable testing.
+	Error string
+}
+
+// CableTestPairStatus : cable test result for a single wire pair.
+type CableTestPairStatus struct {
+	// Pair : name of the wire pair as reported by ethtool (e.g. "Pair A").
+	Pair string
+	// Code : result code as reported by ethtool, e.g. "OK", "Open Circuit",
+	// "Short within Pair".
+	Code string
+	// FaultDistanceMeters : distance from the NIC to the detected fault.
+	// Only valid when HasFaultDistance is true.
+	FaultDistanceMeters float32
+	// HasFaultDistance : true if the PHY reported the fault distance
+	// for this pair.
+	HasFaultDistance bool
+}
+
+// TransceiverStatus : diagnostics of a pluggable transceiver (SFP/SFP+)
+// module of an Ethernet port, periodically read by NIM from the module
+// EEPROM (using ethtool -m). Where the module supports digital optical
+// monitoring (DOM), the measured optical parameters allow to spot
+// a degrading fiber link and replace it before it fails completely.
+type TransceiverStatus struct {
+	// Present : true if a pluggable transceiver module was detected
+	// in the port.
+	Present bool
+	// HasDOM : true if the module reports digital optical monitoring data.
+	HasDOM bool
+	// TemperatureC : module temperature in degrees Celsius.
+	// Only valid when HasDOM is true.
+	TemperatureC float32
+	// RxPowerDBm : received optical power in dBm.
+	// Only valid when HasDOM is true.
+	RxPowerDBm float32
+	// TxPowerDBm : transmitted (laser output) power in dBm.
+	// Only valid when HasDOM is true.
+	TxPowerDBm float32
+	// Warnings : alarm and warning flags raised by the module, i.e. monitored
+	// values which crossed the thresholds programmed into the module by its
+	// vendor (e.g. "Laser output power low warning").
+	Warnings []string
+	// CollectedAt : time when the diagnostics were last read from the module.
+	CollectedAt time.Time
+}
+
 // WirelessStatus : state information for a single wireless device
 type WirelessStatus struct {
 	WType    WirelessType
 	Cellular WwanNetworkStatus
-	// TODO: Wifi status
+	// Wifi : association status reported for every configured WiFi network.
+	Wifi []WifiNetworkStatus
+}
+
+// WifiNetworkStatus : state of association between a WiFi adapter and one
+// of the configured WiFi networks.
+type WifiNetworkStatus struct {
+	// SSID of the configured network.
+	SSID string
+	// Connected : true if the adapter is currently associated with this network.
+	Connected bool
+}
+
+// BondStatus : operational state of a bond port, collected from the kernel
+// (netlink and /proc/net/bonding).
+type BondStatus struct {
+	// Mode : bonding policy as applied by the kernel.
+	Mode BondMode
+	// ActiveSlave : interface name of the currently active slave.
+	// Only applicable to the active-backup mode.
+	ActiveSlave string
+	// AggregatorID : ID of the active 802.3ad aggregator.
+	// Only applicable to the 802.3ad mode.
+	AggregatorID uint16
+	// Slaves : operational state of every aggregated interface.
+	Slaves []BondSlaveStatus
+}
+
+// BondSlaveStatus : operational state of a single interface aggregated
+// by a bond.
+type BondSlaveStatus struct {
+	// IfName : interface name of the aggregated interface.
+	IfName string
+	// MiiUp : true if the MII status of the slave is "up".
+	MiiUp bool
+	// LinkFailureCount : number of link failures detected on this slave.
+	LinkFailureCount uint32
+	// AggregatorID : ID of the aggregator this slave belongs to.
+	// Only applicable to the 802.3ad mode.
+	AggregatorID uint16
 }
 
 // HasIPAndDNS - Check if the given port has a valid unicast IP along with DNS & Gateway.
@@ -209,6 +444,7 @@ func (status DeviceNetworkStatus) MostlyEqual(status2 DeviceNetworkStatus) bool
 	for i, p1 := range status.Ports {
 		p2 := status2.Ports[i]
 		if p1.IfName != p2.IfName ||
+			p1.ConfiguredIfName != p2.ConfiguredIfName ||
 			p1.Phylabel != p2.Phylabel ||
 			p1.Logicallabel != p2.Logicallabel ||
 			p1.Alias != p2.Alias ||
@@ -216,6 +452,7 @@ func (status DeviceNetworkStatus) MostlyEqual(status2 DeviceNetworkStatus) bool
 			p1.IsL3Port != p2.IsL3Port ||
 			p1.InvalidConfig != p2.InvalidConfig ||
 			p1.Cost != p2.Cost ||
+			p1.CostBumpReason != p2.CostBumpReason ||
 			p1.MTU != p2.MTU {
 			return false
 		}
@@ -255,9 +492,44 @@ func (status DeviceNetworkStatus) MostlyEqual(status2 DeviceNetworkStatus) bool
 		}
 
 		if !reflect.DeepEqual(p1.ProxyConfig, p2.ProxyConfig) ||
-			!reflect.DeepEqual(p1.WirelessStatus, p2.WirelessStatus) {
+			!reflect.DeepEqual(p1.WirelessStatus, p2.WirelessStatus) ||
+			!reflect.DeepEqual(p1.SecondaryAddrs, p2.SecondaryAddrs) {
+			return false
+		}
+		if p1.AsymRouteWarning != p2.AsymRouteWarning {
+			return false
+		}
+		// For DNS/NTP server probing results compare only the reachability
+		// flags - latency/offset and timestamps change with every probe
+		// and should not trigger re-publishing.
+		if len(p1.DNSReachability) != len(p2.DNSReachability) ||
+			len(p1.NTPReachability) != len(p2.NTPReachability) {
 			return false
 		}
+		for i := range p1.DNSReachability {
+			if !p1.DNSReachability[i].Server.Equal(p2.DNSReachability[i].Server) ||
+				p1.DNSReachability[i].Reachable != p2.DNSReachability[i].Reachable {
+				return false
+			}
+		}
+		for i := range p1.NTPReachability {
+			if !p1.NTPReachability[i].Server.Equal(p2.NTPReachability[i].Server) ||
+				p1.NTPReachability[i].Reachable != p2.NTPReachability[i].Reachable {
+				return false
+			}
+		}
+	}
+	if status.DeferredDpcKey != status2.DeferredDpcKey {
+		return false
+	}
+	if status.ABTestDpcKey != status2.ABTestDpcKey {
+		return false
+	}
+	if status.PinnedMgmtPort != status2.PinnedMgmtPort {
+		return false
+	}
+	if !reflect.DeepEqual(status.MgmtPortExplanations, status2.MgmtPortExplanations) {
+		return false
 	}
 	return reflect.DeepEqual(status.RadioSilence, status2.RadioSilence)
 }
@@ -302,24 +574,16 @@ func (status *DeviceNetworkStatus) GetPortByIfName(
 func (status *DeviceNetworkStatus) GetPortsByLogicallabel(
 	label string) (ports []*NetworkPortStatus) {
 	// Check for shared labels first.
-	switch label {
-	case UplinkLabel:
-		for i := range status.Ports {
-			if status.Version >= DPCIsMgmt && !status.Ports[i].IsMgmt {
-				continue
-			}
-			ports = append(ports, &status.Ports[i])
+	if sharedLabel := LookupSharedPortLabel(
+		label, status.SharedPortLabels); sharedLabel != nil {
+		if status.Version < DPCIsMgmt {
+			// IsMgmt flag is not defined in older DPC versions.
+			sharedLabel.MgmtOnly = false
 		}
-		return ports
-	case FreeUplinkLabel:
 		for i := range status.Ports {
-			if status.Version >= DPCIsMgmt && !status.Ports[i].IsMgmt {
-				continue
-			}
-			if status.Ports[i].Cost > 0 {
-				continue
+			if sharedLabel.MatchesPort(&status.Ports[i]) {
+				ports = append(ports, &status.Ports[i])
 			}
-			ports = append(ports, &status.Ports[i])
 		}
 		return ports
 	}
@@ -397,6 +661,22 @@ func getPortsSortedCostImpl(dns DeviceNetworkStatus, rotation int, maxCost uint8
 		ifnameList = append(ifnameList,
 			getPortsImpl(dns, rotation, true, cost, l3Only, mgmtOnly, dropFailed)...)
 	}
+	if mgmtOnly && dns.PinnedMgmtPort != "" {
+		// Management traffic is temporarily pinned to a single port
+		// (see MgmtTrafficPin). If the pinned port is not among the selected
+		// ports (e.g. it is missing or was dropped as failed), the pin is
+		// ignored rather than losing the controller connectivity altogether.
+		for i := range dns.Ports {
+			if dns.Ports[i].Logicallabel != dns.PinnedMgmtPort {
+				continue
+			}
+			for _, ifName := range ifnameList {
+				if ifName == dns.Ports[i].IfName {
+					return []string{ifName}
+				}
+			}
+		}
+	}
 	return ifnameList
 }
 
@@ -777,6 +1057,9 @@ func getLocalAddrIf(dns DeviceNetworkStatus, ifname string,
 			if i.Addr == nil {
 				continue
 			}
+			if isExcludedSecondaryAddr(us, i.Addr) {
+				continue
+			}
 			switch af {
 			case 0:
 				// Accept any
@@ -798,6 +1081,18 @@ func getLocalAddrIf(dns DeviceNetworkStatus, ifname string,
 	return addrs, nil
 }
 
+// isExcludedSecondaryAddr returns true if the given address is a secondary
+// (alias) address of the port which is not flagged as usable for management
+// traffic and therefore must be excluded from source-address selection.
+func isExcludedSecondaryAddr(port NetworkPortStatus, addr net.IP) bool {
+	for _, secondary := range port.SecondaryAddrs {
+		if secondary.Address != nil && secondary.Address.IP.Equal(addr) {
+			return !secondary.UsableForMgmt
+		}
+	}
+	return false
+}
+
 // UpdatePortStatusFromIntfStatusMap - Set TestResults for ports in DeviceNetworkStatus to
 // those from intfStatusMap. If a port is not found in intfStatusMap, it means
 // the port was not tested, so we retain the original TestResults for the port.
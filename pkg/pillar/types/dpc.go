@@ -55,6 +55,9 @@ const (
 	// DPCStateWwanWait : waiting for the wwan microservice to apply the latest
 	// cellular configuration.
 	DPCStateWwanWait
+	// DPCStateWifiWait : waiting for a WiFi adapter to associate with any
+	// of the configured SSIDs.
+	DPCStateWifiWait
 )
 
 // String returns the string name
@@ -80,6 +83,8 @@ func (status DPCState) String() string {
 		return "DPC_ASYNC_WAIT"
 	case DPCStateWwanWait:
 		return "DPC_WWAN_WAIT"
+	case DPCStateWifiWait:
+		return "DPC_WIFI_WAIT"
 	default:
 		return fmt.Sprintf("Unknown status %d", status)
 	}
@@ -397,28 +402,77 @@ func (config *DevicePortConfig) MostlyEqual(config2 *DevicePortConfig) bool {
 	if len(config.Ports) != len(config2.Ports) {
 		return false
 	}
-	for i, p1 := range config.Ports {
-		p2 := config2.Ports[i]
-		if p1.IfName != p2.IfName ||
-			p1.PCIAddr != p2.PCIAddr ||
-			p1.USBAddr != p2.USBAddr ||
-			p1.Phylabel != p2.Phylabel ||
-			p1.Logicallabel != p2.Logicallabel ||
-			p1.Alias != p2.Alias ||
-			p1.IsMgmt != p2.IsMgmt ||
-			p1.Cost != p2.Cost ||
-			p1.MTU != p2.MTU {
-			return false
-		}
-		if !reflect.DeepEqual(p1.DhcpConfig, p2.DhcpConfig) ||
-			!reflect.DeepEqual(p1.ProxyConfig, p2.ProxyConfig) ||
-			!reflect.DeepEqual(p1.WirelessCfg, p2.WirelessCfg) {
+	for i := range config.Ports {
+		p1 := &config.Ports[i]
+		p2 := &config2.Ports[i]
+		if !p1.MostlyEqual(p2) {
 			return false
 		}
 	}
 	return true
 }
 
+// MostlyEqual compares two NetworkPortConfig but skips things that are
+// more of status such as the TestResults.
+func (port *NetworkPortConfig) MostlyEqual(port2 *NetworkPortConfig) bool {
+	if port.IfName != port2.IfName ||
+		port.PCIAddr != port2.PCIAddr ||
+		port.USBAddr != port2.USBAddr ||
+		port.Phylabel != port2.Phylabel ||
+		port.Logicallabel != port2.Logicallabel ||
+		port.Alias != port2.Alias ||
+		port.IsMgmt != port2.IsMgmt ||
+		port.Cost != port2.Cost ||
+		port.MTU != port2.MTU {
+		return false
+	}
+	if !reflect.DeepEqual(port.DhcpConfig, port2.DhcpConfig) ||
+		!reflect.DeepEqual(port.DNSOverride, port2.DNSOverride) ||
+		!reflect.DeepEqual(port.ProxyConfig, port2.ProxyConfig) ||
+		!port.WirelessCfg.Equal(port2.WirelessCfg) ||
+		!reflect.DeepEqual(port.SecondaryAddresses, port2.SecondaryAddresses) ||
+		port.EthLink != port2.EthLink ||
+		port.Mirroring != port2.Mirroring {
+		return false
+	}
+	return true
+}
+
+// UpdateCipherBlocks copies the (possibly re-encrypted) credentials from
+// the other, semantically equal config into this one.
+// Should be called when a newly received DPC is found MostlyEqual to
+// an already known one and is therefore not re-applied - the cipher blocks
+// may still differ due to encryption key rotation by the controller,
+// and the latest ciphertext must be retained so that future decryption
+// uses a cipher context which the controller still recognizes.
+func (config *DevicePortConfig) UpdateCipherBlocks(from DevicePortConfig) {
+	if len(config.Ports) != len(from.Ports) {
+		return
+	}
+	for i := range config.Ports {
+		port := &config.Ports[i]
+		fromPort := from.Ports[i]
+		if port.IfName != fromPort.IfName {
+			continue
+		}
+		for j := range port.WirelessCfg.Wifi {
+			if j >= len(fromPort.WirelessCfg.Wifi) {
+				break
+			}
+			port.WirelessCfg.Wifi[j].CipherBlockStatus =
+				fromPort.WirelessCfg.Wifi[j].CipherBlockStatus
+		}
+		accessPoints := port.WirelessCfg.CellularV2.AccessPoints
+		for j := range accessPoints {
+			if j >= len(fromPort.WirelessCfg.CellularV2.AccessPoints) {
+				break
+			}
+			accessPoints[j].EncryptedCredentials =
+				fromPort.WirelessCfg.CellularV2.AccessPoints[j].EncryptedCredentials
+		}
+	}
+}
+
 // IsDPCTestable - Return false if recent failure (less than "minTimeSinceFailure")
 // Also returns false if it isn't usable
 func (config DevicePortConfig) IsDPCTestable(minTimeSinceFailure time.Duration) bool {
@@ -540,14 +594,116 @@ type NetworkPortConfig struct {
 	InvalidConfig bool
 	Cost          uint8 // Zero is free
 	MTU           uint16
+	// EthLink : ethtool-style link parameters (forced speed/duplex, EEE, flow
+	// control). Only applicable to Ethernet ports.
+	EthLink EthLinkConfig
+	// Mirroring : optionally mirror (a subset of) the port traffic into
+	// a dedicated capture interface, e.g. for compliance recording.
+	Mirroring PortMirrorConfig
+	// DNSOverride : optional overrides of the DNS configuration received
+	// from DHCP on this port (see DNSOverride).
+	DNSOverride DNSOverride
 	DhcpConfig
 	ProxyConfig
 	L2LinkConfig
 	WirelessCfg WirelessConfig
+	// SecondaryAddresses : extra static IP addresses (aliases) to assign
+	// to the port alongside the primary address (obtained from DHCP or
+	// configured statically), e.g. for a local service VIP.
+	SecondaryAddresses []SecondaryAddress
 	// TestResults - Errors from parsing plus success/failure from testing
 	TestResults
 }
 
+// SecondaryAddress : an extra static IP address (alias) assigned
+// to a management port.
+type SecondaryAddress struct {
+	// Address : IP address with the subnet mask.
+	Address *net.IPNet
+	// UsableForMgmt : allow management traffic (controller connectivity)
+	// to use this address as the source. By default, secondary addresses
+	// are excluded from the source-address selection.
+	UsableForMgmt bool
+}
+
+// PortMirrorConfig : configuration for mirroring traffic of a port into
+// a dedicated capture interface, typically a VIF of an approved monitoring
+// application. Both received and transmitted packets are mirrored.
+type PortMirrorConfig struct {
+	// Enable mirroring of the port traffic.
+	Enable bool
+	// CaptureIfName : name of the interface into which the mirrored copies
+	// of the packets are injected.
+	CaptureIfName string
+	// VLANID : when non-zero, only packets tagged with this VLAN ID
+	// are mirrored.
+	VLANID uint16
+	// BPFFilter : optional classic BPF program (bytecode in the tc format,
+	// as produced e.g. by "tcpdump -ddd") limiting the mirrored traffic.
+	// When defined, it is used instead of the VLANID filter.
+	BPFFilter string
+	// RateLimitMbps : when non-zero, cap the bandwidth of the mirrored
+	// copies to this value (in Mbps). Excess mirrored packets are dropped
+	// without affecting the original traffic of the port.
+	RateLimitMbps uint32
+}
+
+// DuplexMode : duplex mode of an Ethernet link.
+type DuplexMode uint8
+
+const (
+	// DuplexUnspecified : duplex mode is not specified.
+	DuplexUnspecified DuplexMode = iota
+	// DuplexHalf : half-duplex link.
+	DuplexHalf
+	// DuplexFull : full-duplex link.
+	DuplexFull
+)
+
+// String returns human-readable representation of the duplex mode.
+func (d DuplexMode) String() string {
+	switch d {
+	case DuplexHalf:
+		return "half"
+	case DuplexFull:
+		return "full"
+	}
+	return "unspecified"
+}
+
+// EthLinkConfig : ethtool-style link-layer settings for an Ethernet port.
+// Useful for NICs which fail to correctly auto-negotiate link parameters
+// with (typically older) switches.
+type EthLinkConfig struct {
+	// ForcedSpeed : when non-zero, auto-negotiation is disabled and the link
+	// speed is forced to this value (in Mbps). ForcedDuplex should be defined
+	// alongside this field.
+	ForcedSpeed uint32
+	// ForcedDuplex : duplex mode to force when auto-negotiation is disabled
+	// (i.e. when ForcedSpeed is non-zero).
+	ForcedDuplex DuplexMode
+	// DisableEEE : disable Energy-Efficient Ethernet (IEEE 802.3az).
+	DisableEEE bool
+	// DisableFlowControl : disable Ethernet flow control (IEEE 802.3x pause
+	// frames), both for Rx and Tx.
+	DisableFlowControl bool
+}
+
+// IsDefined returns true if any non-default link parameter is requested.
+func (c EthLinkConfig) IsDefined() bool {
+	return c != EthLinkConfig{}
+}
+
+// EthLinkStatus : actual negotiated link parameters of an Ethernet port.
+type EthLinkStatus struct {
+	// Speed : negotiated link speed in Mbps. Zero if the link is down
+	// or the speed is not known.
+	Speed uint32
+	// Duplex : negotiated duplex mode. DuplexUnspecified if the link is down
+	// or the duplex mode is not known.
+	Duplex DuplexMode
+}
+
 // DhcpType decides how EVE should obtain IP address for a given network port.
 type DhcpType uint8
 
@@ -601,6 +757,21 @@ type DhcpConfig struct {
 	Type       NetworkType // IPv4 or IPv6 or Dual stack
 }
 
+// DNSOverride : overrides of the DNS configuration received from DHCP
+// on a network port. Useful in field networks where DHCP hands out broken
+// resolvers.
+type DNSOverride struct {
+	// IgnoreDhcpDNS : do not use the DNS servers and search domains
+	// received from DHCP on this port.
+	IgnoreDhcpDNS bool
+	// DNSServers : DNS servers to use for this port. Combined with
+	// the DHCP-provided servers unless IgnoreDhcpDNS is set.
+	DNSServers []net.IP
+	// SearchDomains : DNS search domains to use for this port. Appended
+	// to the DHCP-provided domains unless IgnoreDhcpDNS is set.
+	SearchDomains []string
+}
+
 // NetworkProxyType is used to differentiate proxies for different network protocols.
 type NetworkProxyType uint8
 
@@ -687,10 +858,83 @@ type WifiConfig struct {
 	Password string // string of pass phrase or password hash
 	Priority int32
 
+	// Bgscan : optional background scanning parameters, used by wpa_supplicant
+	// to evaluate roaming between APs of the configured networks.
+	Bgscan WifiBgscan
+
 	// CipherBlockStatus, for encrypted credentials
 	CipherBlockStatus
 }
 
+// Equal compares two wireless configurations.
+// Encrypted credentials are compared by the identity of the encrypted payload
+// (see CipherBlockStatus.EquivalentTo), so that mere re-encryption of unchanged
+// credentials after an encryption key rotation does not appear as a change.
+func (wc WirelessConfig) Equal(wc2 WirelessConfig) bool {
+	if wc.WType != wc2.WType {
+		return false
+	}
+	if len(wc.Wifi) != len(wc2.Wifi) ||
+		len(wc.Cellular) != len(wc2.Cellular) ||
+		len(wc.CellularV2.AccessPoints) != len(wc2.CellularV2.AccessPoints) {
+		return false
+	}
+	for i := range wc.Wifi {
+		if !wc.Wifi[i].Equal(wc2.Wifi[i]) {
+			return false
+		}
+	}
+	for i := range wc.Cellular {
+		if wc.Cellular[i] != wc2.Cellular[i] {
+			return false
+		}
+	}
+	for i := range wc.CellularV2.AccessPoints {
+		if !wc.CellularV2.AccessPoints[i].EquivalentTo(
+			wc2.CellularV2.AccessPoints[i]) {
+			return false
+		}
+	}
+	return wc.CellularV2.Probe == wc2.CellularV2.Probe &&
+		wc.CellularV2.LocationTracking == wc2.CellularV2.LocationTracking
+}
+
+// Equal compares two WiFi configurations.
+// Encrypted credentials are compared by the identity of the encrypted payload
+// (see CipherBlockStatus.EquivalentTo).
+func (w WifiConfig) Equal(w2 WifiConfig) bool {
+	if w.SSID != w2.SSID ||
+		w.KeyScheme != w2.KeyScheme ||
+		w.Identity != w2.Identity ||
+		w.Password != w2.Password ||
+		w.Priority != w2.Priority ||
+		w.Bgscan != w2.Bgscan {
+		return false
+	}
+	return w.CipherBlockStatus.EquivalentTo(w2.CipherBlockStatus)
+}
+
+// WifiBgscan : parameters for the "simple" bgscan module of wpa_supplicant.
+// With background scanning enabled, the supplicant periodically scans for
+// better APs of the configured networks and roams to them, instead of sticking
+// to the currently associated AP until the signal is lost completely.
+type WifiBgscan struct {
+	// Enable background scanning.
+	Enable bool
+	// ShortInterval : interval (in seconds) between background scans used when
+	// the signal of the current AP is below SignalThreshold.
+	// Default (zero value) is 30 seconds.
+	ShortInterval uint16
+	// LongInterval : interval (in seconds) between background scans used when
+	// the signal of the current AP is at or above SignalThreshold.
+	// Default (zero value) is 300 seconds.
+	LongInterval uint16
+	// SignalThreshold : signal strength (in dBm, thus negative) deciding
+	// between ShortInterval and LongInterval.
+	// Default (zero value) is -70 dBm.
+	SignalThreshold int16
+}
+
 // DeprecatedCellConfig : old and now deprecated structure for storing cellular
 // network port config. It is preserved only to support upgrades from older EVE
 // versions where this is still being used (under the original struct name "CellConfig")
@@ -764,6 +1008,27 @@ func (ap CellularAccessPoint) Equal(ap2 CellularAccessPoint) bool {
 	return true
 }
 
+// EquivalentTo is like Equal, except that the encrypted credentials
+// are compared by the identity of the encrypted payload
+// (see CipherBlockStatus.EquivalentTo) and not by the ciphertext bytes.
+func (ap CellularAccessPoint) EquivalentTo(ap2 CellularAccessPoint) bool {
+	if ap.SIMSlot != ap2.SIMSlot ||
+		ap.Activated != ap2.Activated ||
+		ap.APN != ap2.APN ||
+		ap.AuthProtocol != ap2.AuthProtocol {
+		return false
+	}
+	if !ap.EncryptedCredentials.EquivalentTo(ap2.EncryptedCredentials) {
+		return false
+	}
+	if !generics.EqualLists(ap.PreferredPLMNs, ap2.PreferredPLMNs) ||
+		!generics.EqualLists(ap.PreferredRATs, ap2.PreferredRATs) ||
+		ap.ForbidRoaming != ap2.ForbidRoaming {
+		return false
+	}
+	return true
+}
+
 // L2LinkType - supported types of an L2 link
 type L2LinkType uint8
 
@@ -790,6 +1055,14 @@ type VLANConfig struct {
 	ParentPort string
 	// VLAN ID.
 	ID uint16
+	// DynamicID : the VLAN ID is not statically configured but assigned
+	// dynamically by the upstream switch (advertised with the LLDP-MED
+	// Network Policy TLV or the 802.1 Port VLAN ID TLV). The reconciler
+	// creates the VLAN sub-interface once the assigned VLAN ID is learned
+	// from the LLDP neighbor of the parent port and attaches the L3
+	// configuration of this port to it. The ID field is ignored when
+	// DynamicID is enabled.
+	DynamicID bool
 }
 
 // BondMode specifies the policy indicating how bonding slaves are used
@@ -0,0 +1,93 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import "time"
+
+// DPCMetricsKey : key for the singleton instance of DPCMetrics.
+const DPCMetricsKey = "global"
+
+// Maximum number of DPC applications for which KPIs are retained
+// in DPCMetrics.
+const maxDPCApplicationKPIs = 10
+
+// DPCMetrics : metrics collected by DpcManager for applications of device
+// port configurations, published by NIM and included in the info/metrics
+// messages sent to the controller.
+// These time-to-connectivity KPIs give fleet operators visibility into slow
+// onboarding sites and allow to track regressions across EVE releases.
+type DPCMetrics struct {
+	// AppliedKPIs : KPIs recorded for the most recent DPC applications,
+	// ordered from the newest to the oldest.
+	// Only the last few applications are retained (see AddKPIs).
+	AppliedKPIs []DPCApplicationKPIs
+}
+
+// Key : pubsub key used by NIM to publish DPC metrics.
+func (m DPCMetrics) Key() string {
+	return DPCMetricsKey
+}
+
+// AddKPIs prepends KPIs of the latest DPC application, dropping the oldest
+// recorded entry if the history limit is exceeded.
+func (m *DPCMetrics) AddKPIs(kpis DPCApplicationKPIs) {
+	m.AppliedKPIs = append([]DPCApplicationKPIs{kpis}, m.AppliedKPIs...)
+	if len(m.AppliedKPIs) > maxDPCApplicationKPIs {
+		m.AppliedKPIs = m.AppliedKPIs[:maxDPCApplicationKPIs]
+	}
+}
+
+// DPCApplicationKPIs : time-to-connectivity KPIs recorded for a single
+// application of a device port configuration (i.e. one run of the DPC
+// verification with the given DPC; re-application of the same DPC produces
+// a new record).
+// A zero timestamp means that the corresponding milestone was not (yet)
+// reached.
+type DPCApplicationKPIs struct {
+	// DPCKey : key of the applied DPC ("zedagent", "lastresort", etc.).
+	DPCKey string
+	// DPCTimePriority : priority timestamp of the applied DPC.
+	// Together with DPCKey it identifies the DPC inside DevicePortConfigList.
+	DPCTimePriority time.Time
+	// ReceivedAt : time when the DPC was received by DpcManager (or loaded
+	// from persisted storage after boot).
+	ReceivedAt time.Time
+	// AppliedAt : time when the configuration was fully applied to the network
+	// stack, i.e. when the config reconciliation completed, including
+	// asynchronous operations.
+	AppliedAt time.Time
+	// IPAcquiredAt : time when at least one management port obtained a usable
+	// IP address together with DNS server(s).
+	IPAcquiredAt time.Time
+	// VerifiedAt : time when the connectivity with the controller was
+	// successfully verified with this DPC applied.
+	VerifiedAt time.Time
+}
+
+// TimeToApply : how long it took to apply the DPC into the network stack
+// from the moment it was received. Zero if not applied (yet).
+func (k DPCApplicationKPIs) TimeToApply() time.Duration {
+	return kpiLatency(k.ReceivedAt, k.AppliedAt)
+}
+
+// TimeToAcquireIP : how long it took from the moment the DPC was received
+// until a management port obtained a usable IP address with DNS server(s).
+// Zero if IP was not acquired (yet).
+func (k DPCApplicationKPIs) TimeToAcquireIP() time.Duration {
+	return kpiLatency(k.ReceivedAt, k.IPAcquiredAt)
+}
+
+// TimeToVerify : how long it took from the moment the DPC was received
+// until the controller connectivity was verified. Zero if the connectivity
+// was not verified (yet).
+func (k DPCApplicationKPIs) TimeToVerify() time.Duration {
+	return kpiLatency(k.ReceivedAt, k.VerifiedAt)
+}
+
+func kpiLatency(from, until time.Time) time.Duration {
+	if from.IsZero() || until.IsZero() {
+		return 0
+	}
+	return until.Sub(from)
+}
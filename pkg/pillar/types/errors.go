@@ -26,3 +26,24 @@ type DNSNotAvailError struct {
 func (e *DNSNotAvailError) Error() string {
 	return fmt.Sprintf("interface %s: no DNS server available", e.IfName)
 }
+
+// CaptivePortalError is returned when connectivity testing detects that
+// HTTP(S) requests are being intercepted by a captive portal (e.g. hotel
+// or branch Wi-Fi requiring a click-through) instead of reaching
+// the controller.
+type CaptivePortalError struct {
+	IfName string
+	// PortalURL : URL of the captive portal login page, if it was learned
+	// (from the Location header of the intercepting HTTP redirect).
+	// Empty otherwise.
+	PortalURL string
+}
+
+// Error message.
+func (e *CaptivePortalError) Error() string {
+	if e.PortalURL == "" {
+		return fmt.Sprintf("interface %s: captive portal detected", e.IfName)
+	}
+	return fmt.Sprintf("interface %s: captive portal detected (portal URL: %s)",
+		e.IfName, e.PortalURL)
+}
@@ -198,6 +198,13 @@ const (
 	LocationAppInterval GlobalSettingKey = "timer.location.app.interval"
 	// NTPSourcesInterval global setting key
 	NTPSourcesInterval GlobalSettingKey = "timer.ntpsources.interval"
+	// NetworkMaintWindowStart : hour of the day (UTC, 0-23) at which the daily
+	// maintenance window for disruptive device port configuration changes opens.
+	NetworkMaintWindowStart GlobalSettingKey = "network.maintenance.window.start"
+	// NetworkMaintWindowDuration : duration of the maintenance window in seconds.
+	// Zero (the default) disables the gating and all port configuration changes
+	// are applied immediately.
+	NetworkMaintWindowDuration GlobalSettingKey = "network.maintenance.window.duration"
 	// Dom0MinDiskUsagePercent global setting key
 	Dom0MinDiskUsagePercent GlobalSettingKey = "storage.dom0.disk.minusage.percent"
 	// Dom0DiskUsageMaxBytes - Max disk usage for Dom0. Dom0 can use
@@ -241,6 +248,22 @@ const (
 	EnableARPSnoop GlobalSettingKey = "network.switch.enable.arpsnoop"
 	// WwanQueryVisibleProviders : periodically query visible cellular service providers
 	WwanQueryVisibleProviders GlobalSettingKey = "wwan.query.visible.providers"
+	// NetworkMaintWindowOverride : emergency override applying any queued disruptive
+	// device port configuration change immediately, without waiting for the next
+	// maintenance window to open.
+	NetworkMaintWindowOverride GlobalSettingKey = "network.maintenance.window.override"
+	// NetworkSharedPortLabels : JSON-encoded array of controller-defined
+	// shared port labels (see types.SharedPortLabel), each referencing
+	// the set of ports matching the configured expressions. These labels
+	// extend the built-in labels "uplink" and "freeuplink" and are usable
+	// wherever a port logical label is expected.
+	NetworkSharedPortLabels GlobalSettingKey = "network.shared.port.labels"
+	// NetworkABTestingEnable : on devices with multiple management ports, verify
+	// a newly received device port configuration first only on the secondary
+	// uplinks, while the primary uplink continues running the current
+	// configuration. Full cutover happens only after the new configuration
+	// proves to provide working controller connectivity.
+	NetworkABTestingEnable GlobalSettingKey = "network.abtest.enable"
 
 	// TriState Items
 	// NetworkFallbackAnyEth global setting key
@@ -300,6 +323,23 @@ const (
 	// address, and MAC address change on EVE node upgrade (switch from old
 	// generation logic to new one) can cause problems with the guest network.
 	NetworkLocalLegacyMACAddress GlobalSettingKey = "network.local.legacy.mac.address"
+	// NIDatapathProbe : enable periodic probing (ICMP ping) of the selected
+	// uplink gateway and a remote endpoint from the bridge IP address of every
+	// local network instance. Measured loss/latency is published as part of
+	// NetworkInstanceMetrics, making degradation in the application datapath
+	// visible separately from management-plane probing.
+	NIDatapathProbe GlobalSettingKey = "network.instance.datapath.probe"
+	// NetworkMaxACLRulesPerVIF : the maximum number of ACL rules (ACEs)
+	// allowed per application VIF. Very large ACE lists blow up the iptables
+	// rule counts and slow down the config reconciliation. App networks
+	// exceeding the limit are rejected with an explicit error reported
+	// in AppNetworkStatus. Zero means no limit.
+	NetworkMaxACLRulesPerVIF GlobalSettingKey = "network.acl.max.rules.per.vif"
+	// NetworkMaxACLRulesPerNI : the maximum total number of ACL rules (ACEs)
+	// allowed across all VIFs connected to one network instance.
+	// App networks which would exceed the limit are rejected with an explicit
+	// error reported in AppNetworkStatus. Zero means no limit.
+	NetworkMaxACLRulesPerNI GlobalSettingKey = "network.acl.max.rules.per.ni"
 )
 
 // AgentSettingKey - keys for per-agent settings
@@ -869,6 +909,8 @@ func NewConfigItemSpecMap() ConfigItemSpecMap {
 	configItemSpecMap.AddIntItem(LocationCloudInterval, HourInSec, 5*MinuteInSec, 0xFFFFFFFF)
 	configItemSpecMap.AddIntItem(LocationAppInterval, 20, 5, HourInSec)
 	configItemSpecMap.AddIntItem(NTPSourcesInterval, 10*MinuteInSec, MinuteInSec, 30*MinuteInSec)
+	configItemSpecMap.AddIntItem(NetworkMaintWindowStart, 0, 0, 23)
+	configItemSpecMap.AddIntItem(NetworkMaintWindowDuration, 0, 0, 24*HourInSec)
 	configItemSpecMap.AddIntItem(Dom0MinDiskUsagePercent, 20, 20, 80)
 	configItemSpecMap.AddIntItem(AppContainerStatsInterval, 300, 1, 0xFFFFFFFF)
 	configItemSpecMap.AddIntItem(VaultReadyCutOffTime, 300, 60, 0xFFFFFFFF)
@@ -894,12 +936,17 @@ func NewConfigItemSpecMap() ConfigItemSpecMap {
 	configItemSpecMap.AddBoolItem(IgnoreDiskCheckForApps, false)
 	configItemSpecMap.AddBoolItem(AllowLogFastupload, false)
 	configItemSpecMap.AddBoolItem(DisableDHCPAllOnesNetMask, false)
+	configItemSpecMap.AddBoolItem(NetworkMaintWindowOverride, false)
+	configItemSpecMap.AddBoolItem(NetworkABTestingEnable, false)
 	configItemSpecMap.AddBoolItem(ProcessCloudInitMultiPart, false)
 	configItemSpecMap.AddBoolItem(ConsoleAccess, true) // Controller likely default to false
 	configItemSpecMap.AddBoolItem(VncShimVMAccess, false)
 	configItemSpecMap.AddBoolItem(EnableARPSnoop, true)
 	configItemSpecMap.AddBoolItem(WwanQueryVisibleProviders, false)
 	configItemSpecMap.AddBoolItem(NetworkLocalLegacyMACAddress, false)
+	configItemSpecMap.AddBoolItem(NIDatapathProbe, false)
+	configItemSpecMap.AddIntItem(NetworkMaxACLRulesPerVIF, 150, 0, 0xFFFFFFFF)
+	configItemSpecMap.AddIntItem(NetworkMaxACLRulesPerNI, 1000, 0, 0xFFFFFFFF)
 
 	// Add TriState Items
 	configItemSpecMap.AddTriStateItem(NetworkFallbackAnyEth, TS_DISABLED)
@@ -911,6 +958,7 @@ func NewConfigItemSpecMap() ConfigItemSpecMap {
 	configItemSpecMap.AddStringItem(DefaultRemoteLogLevel, "info", validateLogrusLevel)
 	configItemSpecMap.AddStringItem(SyslogLogLevel, "info", validateSyslogKernelLevel)
 	configItemSpecMap.AddStringItem(KernelLogLevel, "info", validateSyslogKernelLevel)
+	configItemSpecMap.AddStringItem(NetworkSharedPortLabels, "", validateSharedPortLabels)
 
 	// Add Agent Settings
 	configItemSpecMap.AddAgentSettingStringItem(LogLevel, "info", validateLogrusLevel)
@@ -948,6 +996,13 @@ func blankValidator(s string) error {
 	return nil
 }
 
+// validateSharedPortLabels - Wrapper for validating the JSON-encoded array
+// of controller-defined shared port labels.
+func validateSharedPortLabels(labels string) error {
+	_, err := ParseSharedPortLabels(labels)
+	return err
+}
+
 // NewConfigItemValueMap - Create new instance of ConfigItemValueMap
 func NewConfigItemValueMap() *ConfigItemValueMap {
 	var valueMap ConfigItemValueMap
@@ -177,6 +177,8 @@ func TestNewConfigItemSpecMap(t *testing.T) {
 		ForceFallbackCounter,
 		LogRemainToSendMBytes,
 		DownloadMaxPortCost,
+		NetworkMaintWindowStart,
+		NetworkMaintWindowDuration,
 		// Bool Items
 		UsbAccess,
 		VgaAccess,
@@ -190,7 +192,12 @@ func TestNewConfigItemSpecMap(t *testing.T) {
 		AllowLogFastupload,
 		EnableARPSnoop,
 		WwanQueryVisibleProviders,
+		NetworkMaintWindowOverride,
+		NetworkABTestingEnable,
 		NetworkLocalLegacyMACAddress,
+		NIDatapathProbe,
+		NetworkMaxACLRulesPerVIF,
+		NetworkMaxACLRulesPerNI,
 		// TriState Items
 		NetworkFallbackAnyEth,
 		MaintenanceMode,
@@ -200,6 +207,7 @@ func TestNewConfigItemSpecMap(t *testing.T) {
 		DefaultRemoteLogLevel,
 		SyslogLogLevel,
 		KernelLogLevel,
+		NetworkSharedPortLabels,
 		DisableDHCPAllOnesNetMask,
 		ProcessCloudInitMultiPart,
 		NetDumpEnable,
@@ -130,6 +130,14 @@ func ifNameToPciAndUsbAddr(log *base.LogObject, ifName string) (string, string,
 	return target, usbAddr, fmt.Errorf("Not PCI %s", target)
 }
 
+// IfnameToPciAndUsbAddr returns the long PCI ID and the USB address
+// (if available) of the physical device backing the given network interface.
+// Returns an error for virtual interfaces without an underlying bus device.
+func IfnameToPciAndUsbAddr(log *base.LogObject, ifName string) (
+	pciLong, usbAddr string, err error) {
+	return ifNameToPciAndUsbAddr(log, ifName)
+}
+
 // Returns the long PCI IDs for Virtual function
 func vfIfNameToPci(ifName string) (string, error) {
 	index, parentIface, err := sriov.ParseVfIfaceName(ifName)
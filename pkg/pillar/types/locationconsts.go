@@ -27,6 +27,9 @@ const (
 	VolumeClearDirName = ClearDirName + "/volumes"
 	// PersistDebugDir - Location for service specific debug/traces
 	PersistDebugDir = PersistDir + "/agentdebug"
+	// PersistDeferredDir - location where zedcloud stores publications
+	// which could not be sent to the controller yet
+	PersistDeferredDir = PersistDir + "/deferred"
 	// PersistInstallerDir - location for installer output
 	PersistInstallerDir = PersistDir + "/installer"
 	// IngestedDirname - location for shas of files we pulled from /config
@@ -0,0 +1,98 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+// IptablesBackend : backend used by the iptables command.
+type IptablesBackend uint8
+
+const (
+	// IptablesBackendUnknown : iptables backend was not determined
+	// (e.g. the iptables binary is missing or broken).
+	IptablesBackendUnknown IptablesBackend = iota
+	// IptablesBackendLegacy : iptables directly programs the legacy
+	// x_tables kernel interface.
+	IptablesBackendLegacy
+	// IptablesBackendNft : iptables translates rules into nf_tables.
+	IptablesBackendNft
+)
+
+// String returns the iptables backend name as printed by iptables --version.
+func (b IptablesBackend) String() string {
+	switch b {
+	case IptablesBackendLegacy:
+		return "legacy"
+	case IptablesBackendNft:
+		return "nf_tables"
+	}
+	return "unknown"
+}
+
+// NetworkCapabilities : report summarizing which prerequisites of the network
+// subsystems are available on the device. It is built by the conformance
+// checker at boot, before agents start reconciling network configuration,
+// and is used by reconcilers to pick compatible item implementations
+// (e.g. iptables-legacy vs. nft) and by support engineers to spot devices
+// with broken network tooling.
+type NetworkCapabilities struct {
+	// IptablesBackend : backend used by the iptables command.
+	IptablesBackend IptablesBackend
+	// HasIpset : true if the ipset command is available and functional.
+	HasIpset bool
+	// MissingKernelModules : required kernel modules which are neither
+	// loaded nor built into the kernel.
+	MissingKernelModules []string
+	// MissingSysctls : required sysctl nodes not present under /proc/sys.
+	MissingSysctls []string
+	// BinaryVersions : versions of binaries required by the network
+	// subsystems, keyed by the binary name. Missing binaries have
+	// an empty string as the value.
+	BinaryVersions map[string]string
+	// Errors : problems encountered by the conformance checker itself.
+	Errors []string
+}
+
+// Key is used for pubsub
+func (nc NetworkCapabilities) Key() string {
+	return "global"
+}
+
+// LogCreate :
+func (nc NetworkCapabilities) LogCreate(logBase *base.LogObject) {
+	logObject := base.NewLogObject(logBase, base.NetworkCapabilitiesLogType, "",
+		nilUUID, nc.LogKey())
+	if logObject == nil {
+		return
+	}
+	logObject.Noticef("Network capabilities create")
+}
+
+// LogModify :
+func (nc NetworkCapabilities) LogModify(logBase *base.LogObject, old interface{}) {
+	logObject := base.EnsureLogObject(logBase, base.NetworkCapabilitiesLogType, "",
+		nilUUID, nc.LogKey())
+	oldNc, ok := old.(NetworkCapabilities)
+	if !ok {
+		logObject.Clone().Fatalf("LogModify: Old object passed is not of NetworkCapabilities type")
+	}
+	logObject.CloneAndAddField("diff", cmp.Diff(oldNc, nc)).
+		Noticef("Network capabilities modify")
+}
+
+// LogDelete :
+func (nc NetworkCapabilities) LogDelete(logBase *base.LogObject) {
+	logObject := base.EnsureLogObject(logBase, base.NetworkCapabilitiesLogType, "",
+		nilUUID, nc.LogKey())
+	logObject.Noticef("Network capabilities delete")
+	base.DeleteLogObject(logBase, nc.LogKey())
+}
+
+// LogKey :
+func (nc NetworkCapabilities) LogKey() string {
+	return string(base.NetworkCapabilitiesLogType) + "-" + nc.Key()
+}
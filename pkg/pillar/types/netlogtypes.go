@@ -0,0 +1,23 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+// NetLogCountersKey : key of the singleton NetLogCounters instance
+// published by a network agent.
+const NetLogCountersKey = "global"
+
+// NetLogCounters : counters maintained by the rate-limited logging facility
+// of the network agents (see pillar/netlog). Published periodically so that
+// it is visible how many repeated datapath/reconciliation errors were
+// suppressed and do not appear in the persisted logs.
+type NetLogCounters struct {
+	// SuppressedEntries : total number of suppressed log entries per log
+	// category, accumulated since the agent started.
+	SuppressedEntries map[string]uint64
+}
+
+// Key : pubsub key of the published counters.
+func (c NetLogCounters) Key() string {
+	return NetLogCountersKey
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
+)
+
+// NetworkReadiness is published by NIM (under the key "global") to provide
+// other agents with an explicit "network ready" barrier for clean startup
+// ordering, instead of having them guess the readiness from the contents
+// of DeviceNetworkStatus.
+// The fields transition as follows:
+//   - MgmtConnectivityVerified switches to true whenever a connectivity test
+//     towards the controller succeeds over at least one management port
+//     (or the controller replies with an error status code, proving that it
+//     is reachable), and back to false when the test fails with the network
+//     connectivity being at fault.
+//   - LastSucceededAt is bumped with every fully successful connectivity test.
+//   - DegradedReasons is non-empty while the management connectivity works
+//     but in a degraded manner (e.g. a fallback port configuration is applied
+//     or some of the management ports are failing).
+type NetworkReadiness struct {
+	// MgmtConnectivityVerified : true if connectivity with the controller
+	// was verified over at least one management port.
+	MgmtConnectivityVerified bool
+	// LastSucceededAt : time of the last successful connectivity test
+	// (zero timestamp if connectivity was never verified successfully).
+	LastSucceededAt time.Time
+	// DegradedReasons : human-readable reasons explaining why the network
+	// connectivity is degraded. Empty if not degraded.
+	DegradedReasons []string
+}
+
+// Key is used for pubsub.
+func (nr NetworkReadiness) Key() string {
+	return "global"
+}
+
+// Equal compares two NetworkReadiness instances.
+func (nr NetworkReadiness) Equal(nr2 NetworkReadiness) bool {
+	return nr.MgmtConnectivityVerified == nr2.MgmtConnectivityVerified &&
+		nr.LastSucceededAt.Equal(nr2.LastSucceededAt) &&
+		generics.EqualLists(nr.DegradedReasons, nr2.DegradedReasons)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"net"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	uuid "github.com/satori/go.uuid"
+)
+
+// NetworkTopology : a consolidated snapshot of the device networking topology,
+// periodically published by zedrouter. It allows controllers and debug tooling
+// to consume the network layout in one shot, instead of correlating
+// NetworkInstanceStatus, AppNetworkStatus, DeviceNetworkStatus and flow data
+// across multiple pubsub topics.
+type NetworkTopology struct {
+	// UpdatedAt : time when this snapshot was assembled.
+	UpdatedAt time.Time
+	// Uplinks : summary of device ports used for management or shared
+	// with applications.
+	Uplinks []UplinkTopology
+	// NIs : summary of network instances with their bridges and connected
+	// application interfaces.
+	NIs []NITopology
+}
+
+// UplinkTopology : summary of a single uplink port.
+type UplinkTopology struct {
+	// LogicalLabel : logical name of the port assigned by the controller.
+	LogicalLabel string
+	// IfName : interface name inside the network stack.
+	IfName string
+	// IsMgmt : true if the port can be used for device management.
+	IsMgmt bool
+	// Cost : port cost (0 is free, higher implies more expensive).
+	Cost uint8
+	// Up : true if the port passed the last connectivity test.
+	Up bool
+	// Addrs : IP addresses assigned to the port.
+	Addrs []net.IP
+}
+
+// NITopology : summary of a single network instance.
+type NITopology struct {
+	// NI : network instance UUID.
+	NI uuid.UUID
+	// DisplayName : network instance name from the config.
+	DisplayName string
+	// Type of the network instance (local, switch, ...).
+	Type NetworkInstanceType
+	// Activated is true if the network instance has been created
+	// in the network stack.
+	Activated bool
+	// BridgeName : interface name of the NI bridge.
+	BridgeName string
+	// BridgeIPAddr : IP address of the NI bridge
+	// (used for DNS/DHCP services; nil for switch NI).
+	BridgeIPAddr net.IP
+	// Subnet : IP subnet of the network instance.
+	Subnet net.IPNet
+	// SelectedUplinkLogicalLabel : logical label of the uplink port currently
+	// selected (by probing) to provide external connectivity for this NI.
+	SelectedUplinkLogicalLabel string
+	// SelectedUplinkIfName : interface name of the selected uplink port.
+	SelectedUplinkIfName string
+	// VIFs : application interfaces connected to this network instance.
+	VIFs []VIFTopology
+}
+
+// VIFTopology : summary of an application interface connected to a network
+// instance.
+type VIFTopology struct {
+	// App : application UUID.
+	App uuid.UUID
+	// AppName : application display name.
+	AppName string
+	// NetAdapterName : logical name of the adapter received from
+	// the controller in NetworkAdapter.Name.
+	NetAdapterName string
+	// HostIfName : name of the VIF interface on the host side.
+	HostIfName string
+	// GuestIfMAC : MAC address of the VIF on the guest side.
+	GuestIfMAC string
+	// IPv4Addr : IPv4 address assigned to the VIF (nil if none).
+	IPv4Addr net.IP
+	// ACLCount : number of ACL rules configured for this adapter.
+	ACLCount int
+}
+
+// Key is used for pubsub
+func (nt NetworkTopology) Key() string {
+	return "global"
+}
+
+// LogCreate :
+func (nt NetworkTopology) LogCreate(logBase *base.LogObject) {
+	logObject := base.NewLogObject(logBase, base.NetworkTopologyLogType, "",
+		nilUUID, nt.LogKey())
+	if logObject == nil {
+		return
+	}
+	logObject.Metricf("Network topology create")
+}
+
+// LogModify :
+func (nt NetworkTopology) LogModify(logBase *base.LogObject, old interface{}) {
+	logObject := base.EnsureLogObject(logBase, base.NetworkTopologyLogType, "",
+		nilUUID, nt.LogKey())
+	oldNt, ok := old.(NetworkTopology)
+	if !ok {
+		logObject.Clone().Fatalf("LogModify: Old object passed is not of NetworkTopology type")
+	}
+	logObject.CloneAndAddField("diff", cmp.Diff(oldNt, nt)).
+		Metricf("Network topology modify")
+}
+
+// LogDelete :
+func (nt NetworkTopology) LogDelete(logBase *base.LogObject) {
+	logObject := base.EnsureLogObject(logBase, base.NetworkTopologyLogType, "",
+		nilUUID, nt.LogKey())
+	logObject.Metricf("Network topology delete")
+	base.DeleteLogObject(logBase, nt.LogKey())
+}
+
+// LogKey :
+func (nt NetworkTopology) LogKey() string {
+	return string(base.NetworkTopologyLogType) + "-" + nt.Key()
+}
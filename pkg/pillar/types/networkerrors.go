@@ -0,0 +1,111 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NetworkErrorCode is a machine-readable classification of a network-related
+// error. Unlike the free-form error strings published in statuses, these codes
+// allow the controller to programmatically categorize failures and trigger
+// alerting without parsing human-readable messages.
+type NetworkErrorCode int32
+
+const (
+	// NetworkErrorCodeUnspecified : error is not classified.
+	NetworkErrorCodeUnspecified NetworkErrorCode = iota
+	// NetworkErrorCodeDHCPTimeout : DHCP client failed to obtain a lease in time.
+	NetworkErrorCodeDHCPTimeout
+	// NetworkErrorCodeDNSFail : DNS resolution failed or no DNS server is available.
+	NetworkErrorCodeDNSFail
+	// NetworkErrorCodeTLSCertExpired : TLS handshake failed due to an expired certificate.
+	NetworkErrorCodeTLSCertExpired
+	// NetworkErrorCodePortInPCIBack : port is assigned to pciback and not available
+	// for network configuration.
+	NetworkErrorCodePortInPCIBack
+	// NetworkErrorCodeSubnetOverlap : configured subnet overlaps with another
+	// network (instance or port).
+	NetworkErrorCodeSubnetOverlap
+	// NetworkErrorCodeIPAddrNotAvail : no suitable IP address is assigned
+	// to the interface.
+	NetworkErrorCodeIPAddrNotAvail
+	// NetworkErrorCodeNoConnectivity : controller or a test endpoint is not
+	// reachable over the interface.
+	NetworkErrorCodeNoConnectivity
+	// NetworkErrorCodeClockSkew : TLS handshake failed because the device
+	// clock is badly skewed (certificate appears expired or not yet valid).
+	NetworkErrorCodeClockSkew
+)
+
+// String returns the code in the stable upper-snake-case form expected
+// by the controller API.
+func (c NetworkErrorCode) String() string {
+	switch c {
+	case NetworkErrorCodeUnspecified:
+		return "UNSPECIFIED"
+	case NetworkErrorCodeDHCPTimeout:
+		return "DHCP_TIMEOUT"
+	case NetworkErrorCodeDNSFail:
+		return "DNS_FAIL"
+	case NetworkErrorCodeTLSCertExpired:
+		return "TLS_CERT_EXPIRED"
+	case NetworkErrorCodePortInPCIBack:
+		return "PORT_IN_PCIBACK"
+	case NetworkErrorCodeSubnetOverlap:
+		return "SUBNET_OVERLAP"
+	case NetworkErrorCodeIPAddrNotAvail:
+		return "IP_ADDR_NOT_AVAIL"
+	case NetworkErrorCodeNoConnectivity:
+		return "NO_CONNECTIVITY"
+	case NetworkErrorCodeClockSkew:
+		return "CLOCK_SKEW"
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", c)
+}
+
+// NetworkError wraps a network-related error with a machine-readable code.
+// The human-readable message of the wrapped error is preserved and continues
+// to be published in statuses, while the code enables programmatic handling.
+type NetworkError struct {
+	Code NetworkErrorCode
+	// WrappedErr : the underlying error with the human-readable message.
+	WrappedErr error
+}
+
+// Error message combines the code with the wrapped error message.
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Code, e.WrappedErr)
+}
+
+// Unwrap returns the wrapped error, allowing the use of errors.Is/As.
+func (e *NetworkError) Unwrap() error {
+	return e.WrappedErr
+}
+
+// NewNetworkError wraps the given error with a network error code.
+func NewNetworkError(code NetworkErrorCode, err error) *NetworkError {
+	return &NetworkError{Code: code, WrappedErr: err}
+}
+
+// GetNetworkErrorCode tries to classify the given error.
+// It recognizes NetworkError anywhere in the wrapped chain as well as some
+// well-known typed errors (e.g. IPAddrNotAvailError, DNSNotAvailError).
+// Returns NetworkErrorCodeUnspecified if the error cannot be classified.
+func GetNetworkErrorCode(err error) NetworkErrorCode {
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return netErr.Code
+	}
+	var ipErr *IPAddrNotAvailError
+	if errors.As(err, &ipErr) {
+		return NetworkErrorCodeIPAddrNotAvail
+	}
+	var dnsErr *DNSNotAvailError
+	if errors.As(err, &dnsErr) {
+		return NetworkErrorCodeDNSFail
+	}
+	return NetworkErrorCodeUnspecified
+}
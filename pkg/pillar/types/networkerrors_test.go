@@ -0,0 +1,46 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNetworkErrorCode(t *testing.T) {
+	err := NewNetworkError(NetworkErrorCodeDHCPTimeout,
+		errors.New("dhcpcd timed out on eth0"))
+	assert.Equal(t, NetworkErrorCodeDHCPTimeout, GetNetworkErrorCode(err))
+	assert.Contains(t, err.Error(), "DHCP_TIMEOUT")
+	assert.Contains(t, err.Error(), "dhcpcd timed out on eth0")
+
+	// Code should be found even deeper in the wrapped chain.
+	wrapped := fmt.Errorf("connectivity test failed: %w", err)
+	assert.Equal(t, NetworkErrorCodeDHCPTimeout, GetNetworkErrorCode(wrapped))
+
+	// Well-known typed errors are classified without explicit wrapping.
+	assert.Equal(t, NetworkErrorCodeIPAddrNotAvail,
+		GetNetworkErrorCode(&IPAddrNotAvailError{IfName: "eth0"}))
+	assert.Equal(t, NetworkErrorCodeDNSFail,
+		GetNetworkErrorCode(&DNSNotAvailError{IfName: "eth0"}))
+
+	// Unrecognized errors map to the unspecified code.
+	assert.Equal(t, NetworkErrorCodeUnspecified,
+		GetNetworkErrorCode(errors.New("some other failure")))
+}
+
+func TestTestResultsErrorCode(t *testing.T) {
+	tr := TestResults{}
+	tr.RecordFailureFromError(NewNetworkError(NetworkErrorCodeDNSFail,
+		errors.New("DNS resolution failed for zedcloud")))
+	assert.True(t, tr.HasError())
+	assert.Equal(t, NetworkErrorCodeDNSFail, tr.LastErrorCode)
+
+	tr.RecordSuccess()
+	assert.False(t, tr.HasError())
+	assert.Equal(t, NetworkErrorCodeUnspecified, tr.LastErrorCode)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// PortMediaFilter : optionally restricts shared-label matching to wired
+// or wireless ports.
+type PortMediaFilter uint8
+
+const (
+	// PortMediaAny : match ports regardless of the medium.
+	PortMediaAny PortMediaFilter = iota
+	// PortMediaWired : match only wired (Ethernet) ports.
+	PortMediaWired
+	// PortMediaWireless : match only wireless (WiFi, cellular) ports.
+	PortMediaWireless
+)
+
+// SharedPortLabel : a label referencing the (possibly changing) set of ports
+// matching the configured expressions, usable wherever a port logical label
+// is expected (NetworkInstanceConfig.PortLogicalLabel, ACL adapter matches).
+// Next to the built-in labels (see BuiltinSharedPortLabels), additional
+// labels with custom match expressions can be defined by the controller
+// and published as part of DeviceNetworkStatus.
+type SharedPortLabel struct {
+	// Label : name under which the set of ports is referenced.
+	Label string
+	// MgmtOnly : match only management ports.
+	MgmtOnly bool
+	// MinCost, MaxCost : inclusive range of port costs to match.
+	MinCost uint8
+	MaxCost uint8
+	// Media : optionally match only wired or only wireless ports.
+	Media PortMediaFilter
+	// LogicallabelPattern : optional regular expression which the logical
+	// label of the port must (fully) match.
+	LogicallabelPattern string
+}
+
+// String describes the shared label with its match expressions.
+func (sl SharedPortLabel) String() string {
+	return fmt.Sprintf("SharedPortLabel: {label: %s, mgmtOnly: %t, "+
+		"minCost: %d, maxCost: %d, media: %d, logicallabelPattern: %s}",
+		sl.Label, sl.MgmtOnly, sl.MinCost, sl.MaxCost, sl.Media,
+		sl.LogicallabelPattern)
+}
+
+// MatchesPort returns true if the given port belongs to the set of ports
+// referenced by the shared label.
+func (sl SharedPortLabel) MatchesPort(port *NetworkPortStatus) bool {
+	if sl.MgmtOnly && !port.IsMgmt {
+		return false
+	}
+	if port.Cost < sl.MinCost || port.Cost > sl.MaxCost {
+		return false
+	}
+	switch sl.Media {
+	case PortMediaWired:
+		if port.WirelessCfg.WType != WirelessTypeNone {
+			return false
+		}
+	case PortMediaWireless:
+		if port.WirelessCfg.WType == WirelessTypeNone {
+			return false
+		}
+	}
+	if sl.LogicallabelPattern != "" {
+		re, err := regexp.Compile("^(?:" + sl.LogicallabelPattern + ")$")
+		if err != nil {
+			// Invalid pattern matches nothing.
+			return false
+		}
+		if !re.MatchString(port.Logicallabel) {
+			return false
+		}
+	}
+	return true
+}
+
+// BuiltinSharedPortLabels : shared labels available on every device,
+// expressed through the generic matching mechanism:
+//   - "uplink": any management port
+//   - "freeuplink": any management port with 0 cost
+func BuiltinSharedPortLabels() []SharedPortLabel {
+	return []SharedPortLabel{
+		{Label: UplinkLabel, MgmtOnly: true, MaxCost: 255},
+		{Label: FreeUplinkLabel, MgmtOnly: true, MaxCost: 0},
+	}
+}
+
+// ParseSharedPortLabels parses controller-defined shared port labels from
+// their JSON encoding (this is how they are configured, see the global config
+// item NetworkSharedPortLabels).
+func ParseSharedPortLabels(jsonStr string) ([]SharedPortLabel, error) {
+	if jsonStr == "" {
+		return nil, nil
+	}
+	var labels []SharedPortLabel
+	if err := json.Unmarshal([]byte(jsonStr), &labels); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal shared port labels: %w", err)
+	}
+	for _, label := range labels {
+		if label.Label == "" {
+			return nil, fmt.Errorf("shared port label without a name: %v", label)
+		}
+		for _, builtin := range BuiltinSharedPortLabels() {
+			if label.Label == builtin.Label {
+				return nil, fmt.Errorf("shared port label %s collides "+
+					"with a built-in label", label.Label)
+			}
+		}
+		if label.LogicallabelPattern != "" {
+			if _, err := regexp.Compile(label.LogicallabelPattern); err != nil {
+				return nil, fmt.Errorf("shared port label %s has invalid "+
+					"logical label pattern: %w", label.Label, err)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// LookupSharedPortLabel searches for the shared label of the given name,
+// first among the built-in labels, then among the given controller-defined
+// labels. Returns nil if the name does not reference a shared label
+// (i.e. it is a logical label of a single port at most).
+func LookupSharedPortLabel(label string,
+	customLabels []SharedPortLabel) *SharedPortLabel {
+	for _, sharedLabel := range BuiltinSharedPortLabels() {
+		if sharedLabel.Label == label {
+			return &sharedLabel
+		}
+	}
+	for _, sharedLabel := range customLabels {
+		if sharedLabel.Label == label {
+			return &sharedLabel
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupSharedPortLabel(t *testing.T) {
+	customLabels := []SharedPortLabel{
+		{Label: "wwan", MgmtOnly: true, MinCost: 1, MaxCost: 255,
+			Media: PortMediaWireless},
+	}
+	assert.NotNil(t, LookupSharedPortLabel(UplinkLabel, nil))
+	assert.NotNil(t, LookupSharedPortLabel(FreeUplinkLabel, nil))
+	assert.Nil(t, LookupSharedPortLabel("wwan", nil))
+	assert.NotNil(t, LookupSharedPortLabel("wwan", customLabels))
+	assert.Nil(t, LookupSharedPortLabel("eth0", customLabels))
+}
+
+func TestSharedPortLabelMatchesPort(t *testing.T) {
+	mgmtEth := &NetworkPortStatus{
+		IfName:       "eth0",
+		Logicallabel: "ethernet0",
+		IsMgmt:       true,
+	}
+	appSharedEth := &NetworkPortStatus{
+		IfName:       "eth1",
+		Logicallabel: "ethernet1",
+	}
+	cellModem := &NetworkPortStatus{
+		IfName:       "wwan0",
+		Logicallabel: "modem0",
+		IsMgmt:       true,
+		Cost:         10,
+		WirelessCfg:  WirelessConfig{WType: WirelessTypeCellular},
+	}
+
+	uplink := *LookupSharedPortLabel(UplinkLabel, nil)
+	assert.True(t, uplink.MatchesPort(mgmtEth))
+	assert.False(t, uplink.MatchesPort(appSharedEth))
+	assert.True(t, uplink.MatchesPort(cellModem))
+
+	freeUplink := *LookupSharedPortLabel(FreeUplinkLabel, nil)
+	assert.True(t, freeUplink.MatchesPort(mgmtEth))
+	assert.False(t, freeUplink.MatchesPort(appSharedEth))
+	assert.False(t, freeUplink.MatchesPort(cellModem))
+
+	paidWireless := SharedPortLabel{
+		Label:   "paid-wireless",
+		MinCost: 1,
+		MaxCost: 255,
+		Media:   PortMediaWireless,
+	}
+	assert.False(t, paidWireless.MatchesPort(mgmtEth))
+	assert.True(t, paidWireless.MatchesPort(cellModem))
+
+	ethPattern := SharedPortLabel{
+		Label:               "all-ethernets",
+		MaxCost:             255,
+		Media:               PortMediaWired,
+		LogicallabelPattern: "ethernet[0-9]+",
+	}
+	assert.True(t, ethPattern.MatchesPort(mgmtEth))
+	assert.True(t, ethPattern.MatchesPort(appSharedEth))
+	assert.False(t, ethPattern.MatchesPort(cellModem))
+}
+
+func TestParseSharedPortLabels(t *testing.T) {
+	labels, err := ParseSharedPortLabels("")
+	assert.NoError(t, err)
+	assert.Nil(t, labels)
+
+	labels, err = ParseSharedPortLabels(
+		`[{"Label": "wireless", "MgmtOnly": true, "MaxCost": 255, "Media": 2},
+		  {"Label": "all-ethernets", "MaxCost": 255,
+		   "LogicallabelPattern": "ethernet[0-9]+"}]`)
+	assert.NoError(t, err)
+	assert.Len(t, labels, 2)
+	assert.Equal(t, "wireless", labels[0].Label)
+	assert.Equal(t, PortMediaWireless, labels[0].Media)
+	assert.Equal(t, "all-ethernets", labels[1].Label)
+
+	// Not a valid JSON.
+	_, err = ParseSharedPortLabels(`[{"Label": "wireless"`)
+	assert.Error(t, err)
+	// Missing label name.
+	_, err = ParseSharedPortLabels(`[{"MaxCost": 255}]`)
+	assert.Error(t, err)
+	// Collision with a built-in label.
+	_, err = ParseSharedPortLabels(`[{"Label": "uplink"}]`)
+	assert.Error(t, err)
+	// Invalid logical label pattern.
+	_, err = ParseSharedPortLabels(
+		`[{"Label": "bad", "LogicallabelPattern": "eth["}]`)
+	assert.Error(t, err)
+}
+
+func TestGetPortsBySharedLabel(t *testing.T) {
+	dns := DeviceNetworkStatus{
+		Version: DPCIsMgmt,
+		Ports: []NetworkPortStatus{
+			{IfName: "eth0", Logicallabel: "ethernet0", IsMgmt: true},
+			{IfName: "eth1", Logicallabel: "ethernet1"},
+			{IfName: "wwan0", Logicallabel: "modem0", IsMgmt: true, Cost: 10,
+				WirelessCfg: WirelessConfig{WType: WirelessTypeCellular}},
+		},
+		SharedPortLabels: []SharedPortLabel{
+			{Label: "wireless", MgmtOnly: true, MaxCost: 255,
+				Media: PortMediaWireless},
+		},
+	}
+	ports := dns.GetPortsByLogicallabel(UplinkLabel)
+	assert.Len(t, ports, 2)
+	ports = dns.GetPortsByLogicallabel(FreeUplinkLabel)
+	assert.Len(t, ports, 1)
+	assert.Equal(t, "eth0", ports[0].IfName)
+	ports = dns.GetPortsByLogicallabel("wireless")
+	assert.Len(t, ports, 1)
+	assert.Equal(t, "wwan0", ports[0].IfName)
+	ports = dns.GetPortsByLogicallabel("ethernet1")
+	assert.Len(t, ports, 1)
+	assert.Equal(t, "eth1", ports[0].IfName)
+}
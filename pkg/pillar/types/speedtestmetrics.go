@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import "time"
+
+// SpeedTestMetricsKey : key for the singleton instance of SpeedTestMetrics.
+const SpeedTestMetricsKey = "global"
+
+// SpeedTestMetrics : results of on-demand uplink throughput measurements
+// (see SpeedTestRequest), published by NIM.
+// Only the result of the most recent measurement is retained per port.
+type SpeedTestMetrics struct {
+	// Results of the last executed speed test, keyed by the logical label
+	// of the measured port.
+	Results map[string]SpeedTestResult
+}
+
+// Key : pubsub key used by NIM to publish speed test metrics.
+func (m SpeedTestMetrics) Key() string {
+	return SpeedTestMetricsKey
+}
+
+// SpeedTestResult : result of a single throughput measurement executed
+// on one port.
+type SpeedTestResult struct {
+	// DownloadURL : URL of the file downloaded for the measurement.
+	DownloadURL string
+	// RequestedAt : RequestedAt copied from the executed SpeedTestRequest.
+	RequestedAt time.Time
+	// CompletedAt : time when the measurement of this port finished.
+	CompletedAt time.Time
+	// DownloadedBytes : amount of data actually downloaded (downloads are
+	// capped, see SpeedTestRequest.MaxBytes).
+	DownloadedBytes uint64
+	// ThroughputMbps : application-layer download throughput in megabits
+	// per second, measured from the first received byte until the download
+	// completed.
+	ThroughputMbps float32
+	// LatencyMsec : time from sending the request until the response headers
+	// were received (i.e. including connection establishment and the TLS
+	// handshake).
+	LatencyMsec uint32
+	// JitterMsec : mean deviation of the inter-arrival times of downloaded
+	// data chunks.
+	JitterMsec uint32
+	// Error : description of a failed measurement. Empty on success.
+	Error string
+}
@@ -338,6 +338,21 @@ type WwanNetworkStatus struct {
 	IPSettings WwanIPSettings
 	// True if location tracking is successfully running.
 	LocationTracking bool
+	// Data-plan usage information for the currently used SIM card.
+	// All-zero value if the wwan service has no data-plan metadata available.
+	DataPlan WwanDataPlan
+}
+
+// WwanDataPlan contains data-plan usage information for a cellular connection.
+// Reported by the wwan service when the metadata is made available by the modem
+// or the operator.
+type WwanDataPlan struct {
+	// Total amount of bytes included in the data plan for the current
+	// billing period. Zero value means that the quota is not known
+	// (or the plan is unlimited).
+	TotalBytes uint64
+	// Amount of bytes already consumed in the current billing period.
+	UsedBytes uint64
 }
 
 // WwanCellModule contains cellular module specs.
@@ -480,7 +495,8 @@ func (wns WwanNetworkStatus) Equal(wns2 WwanNetworkStatus) bool {
 	}
 	if wns.ConnectedAt != wns2.ConnectedAt ||
 		!wns.IPSettings.Equal(wns2.IPSettings) ||
-		wns.LocationTracking != wns2.LocationTracking {
+		wns.LocationTracking != wns2.LocationTracking ||
+		wns.DataPlan != wns2.DataPlan {
 		return false
 	}
 	return true
@@ -524,12 +524,30 @@ type ZedAgentStatus struct {
 	ForceFallbackCounter  int          // Try image fallback when counter changes
 	CurrentProfile        string       // Current profile
 	RadioSilence          RadioSilence // Currently requested state of radio devices
-	DeviceState           DeviceState
-	AttestState           AttestState
-	AttestError           string
-	VaultStatus           info.DataSecAtRestStatus
-	PCRStatus             info.PCRStatus
-	VaultErr              string
+	// MgmtTrafficPin : operator request to temporarily pin all management
+	// (controller) traffic to a single port (see MgmtTrafficPin type).
+	MgmtTrafficPin MgmtTrafficPin
+	// CableTest : operator request to run an on-demand Ethernet cable test
+	// on a port (see CableTestRequest type).
+	CableTest CableTestRequest
+	// SpeedTest : operator request to run an on-demand throughput measurement
+	// on one or all uplink ports (see SpeedTestRequest type).
+	SpeedTest SpeedTestRequest
+	// DPCRetest : operator request to immediately re-run the connectivity
+	// verification of the DPC list (see DPCRetestRequest type).
+	DPCRetest DPCRetestRequest
+	// DPCPin : operator request to temporarily switch to a specific entry
+	// of the DPC list (see DPCPinRequest type).
+	DPCPin DPCPinRequest
+	// NetconfExport : operator request to export the full network
+	// configuration into a signed bundle (see NetconfExportRequest type).
+	NetconfExport NetconfExportRequest
+	DeviceState   DeviceState
+	AttestState   AttestState
+	AttestError   string
+	VaultStatus   info.DataSecAtRestStatus
+	PCRStatus     info.PCRStatus
+	VaultErr      string
 }
 
 // DeviceState represents overall state
@@ -677,6 +695,113 @@ func (am RadioSilence) String() string {
 	return "Radio transmitters ON"
 }
 
+// MgmtTrafficPin : operator request to temporarily pin all management
+// (controller) traffic to a single port, overriding the cost-based uplink
+// selection. Useful for validating a newly installed link before changing
+// port costs. The pin automatically expires after the requested duration.
+// An empty PortLabel clears any active pin.
+type MgmtTrafficPin struct {
+	// PortLabel : logical label of the port to pin management traffic to.
+	PortLabel string
+	// Duration : how long the pin stays active before it is automatically
+	// reverted. Zero duration as well as values above one hour are clamped
+	// to one hour.
+	Duration time.Duration
+	// RequestedAt : time when the pin was requested. A request is applied
+	// only if RequestedAt is newer than that of the last applied request.
+	RequestedAt time.Time
+}
+
+// DPCRetestRequest : operator request to immediately re-run the connectivity
+// verification of the DPC (device port configuration) list, starting from
+// the highest-priority entry, without waiting for the next periodic test.
+// Useful after fixing an external problem (cabling, upstream router, firewall
+// rules), when waiting out the timer-driven testing cadence would needlessly
+// prolong the outage. The results are reported back via the published
+// DevicePortConfigList (per-DPC and per-port test results) and
+// DeviceNetworkStatus (see LastDPCRetest).
+type DPCRetestRequest struct {
+	// RequestedAt : time when the retest was requested. A request is executed
+	// only if RequestedAt is newer than that of the last executed request.
+	RequestedAt time.Time
+}
+
+// DPCPinRequest : operator request to temporarily switch to the DPC at the
+// given index of the published DevicePortConfigList and stay on it, disabling
+// the automatic priority-based selection and fallback for the duration of
+// the pin. Useful for remotely validating an older or a fallback network
+// configuration. The pin automatically expires after the requested duration,
+// at which point the device returns to the standard DPC selection.
+// The pinned entry and the expiration time are published in
+// DeviceNetworkStatus (see PinnedDpcKey and PinnedDpcUntil).
+type DPCPinRequest struct {
+	// Index : index of the entry to pin inside the published
+	// DevicePortConfigList. A negative index clears any active pin.
+	Index int
+	// Duration : how long the pin stays active before the device returns
+	// to the standard DPC selection. Zero duration as well as values above
+	// one hour are clamped to one hour.
+	Duration time.Duration
+	// RequestedAt : time when the pin was requested. A request is applied
+	// only if RequestedAt is newer than that of the last applied request.
+	RequestedAt time.Time
+}
+
+// CableTestRequest : operator request to run an on-demand Ethernet cable
+// test (ethtool --cable-test) on a port. Where the NIC PHY supports it,
+// the test reports the status of every wire pair together with the distance
+// to a detected fault, allowing to remotely distinguish cabling faults from
+// configuration issues on dead uplinks. The result is published in
+// DeviceNetworkStatus (see CableTestStatus).
+// Note that the test takes the link down for a few seconds.
+type CableTestRequest struct {
+	// PortLabel : logical label of the Ethernet port to test.
+	// An empty label means no test is requested.
+	PortLabel string
+	// RequestedAt : time when the test was requested. A request is executed
+	// only if RequestedAt is newer than that of the last executed request.
+	RequestedAt time.Time
+}
+
+// SpeedTestRequest : operator request to run an on-demand throughput
+// measurement (HTTPS download of a capped amount of data) on one or all
+// uplink ports, allowing to validate bandwidth SLAs of the underlying links
+// without having to deploy a measurement application.
+// The results are published in SpeedTestMetrics.
+// Note that the download competes with management and application traffic
+// for the uplink bandwidth for the duration of the test.
+type SpeedTestRequest struct {
+	// PortLabel : logical label of the port to measure.
+	// An empty label means that all management ports are measured.
+	PortLabel string
+	// DownloadURL : HTTP(S) URL of the file to download for the measurement.
+	// Typically points at a file hosted by the controller or at a designated
+	// measurement endpoint reachable over the uplink.
+	DownloadURL string
+	// MaxBytes : cap on the amount of data downloaded per port.
+	// Zero selects the default of 8 MB, while values above 100 MB
+	// are clamped to 100 MB.
+	MaxBytes uint64
+	// RequestedAt : time when the measurement was requested. A request is
+	// executed only if RequestedAt is newer than that of the last executed
+	// request.
+	RequestedAt time.Time
+}
+
+// NetconfExportRequest : operator request to export the full network
+// configuration of the device (effective DevicePortConfig, network instance
+// definitions and references to the related encrypted credentials) into
+// a signed bundle, written to a well-known location inside /persist.
+// The bundle can then be applied as an override network configuration
+// on another device, to speed up commissioning of identical sites
+// (handled by NIM, see pkg netconfbundle).
+type NetconfExportRequest struct {
+	// RequestedAt : time when the export was requested. A request is
+	// executed only if RequestedAt is newer than that of the last executed
+	// request.
+	RequestedAt time.Time
+}
+
 // LocalCommands : commands triggered locally via Local profile server.
 type LocalCommands struct {
 	// Locally issued app commands.
@@ -21,6 +21,26 @@ type ZedcloudMetric struct {
 	LastSuccess   time.Time
 	URLCounters   map[string]UrlcloudMetrics
 	AuthFailCount uint64
+	// EnvelopeCounters : metrics per controller API envelope version
+	// (key: envelope version, e.g. "v2").
+	EnvelopeCounters map[string]EnvelopeMetrics
+	// BreakerTripCount : how many times a circuit breaker of a controller
+	// endpoint accessed over this interface has tripped open after repeated
+	// transport failures (see pillar/zedcloud/circuitbreaker.go).
+	BreakerTripCount uint64
+	// BreakerFastFailCount : number of requests rejected immediately because
+	// the circuit breaker of the target endpoint was open. A growing value
+	// tells the agent that the controller is unreachable over this interface
+	// without the requests paying the full network timeout.
+	BreakerFastFailCount uint64
+}
+
+// EnvelopeMetrics are metrics for a particular version of the controller
+// API envelope (framing + signing scheme).
+type EnvelopeMetrics struct {
+	WrapMsgCount    uint64 // requests wrapped into the envelope
+	UnwrapMsgCount  uint64 // responses successfully unwrapped
+	VerifyFailCount uint64 // responses which failed envelope verification
 }
 
 // UrlcloudMetrics are metrics for a particular URL
@@ -64,6 +84,8 @@ func (m MetricsMap) AddInto(toMap MetricsMap) {
 		dst.FailureCount += src.FailureCount
 		dst.SuccessCount += src.SuccessCount
 		dst.AuthFailCount += src.AuthFailCount
+		dst.BreakerTripCount += src.BreakerTripCount
+		dst.BreakerFastFailCount += src.BreakerFastFailCount
 		if dst.URLCounters == nil {
 			dst.URLCounters = make(map[string]UrlcloudMetrics)
 		}
@@ -85,6 +107,16 @@ func (m MetricsMap) AddInto(toMap MetricsMap) {
 			dstURL.SessionResume += srcURL.SessionResume
 			dstURLs[url] = dstURL
 		}
+		if dst.EnvelopeCounters == nil {
+			dst.EnvelopeCounters = make(map[string]EnvelopeMetrics)
+		}
+		for version, srcEnv := range src.EnvelopeCounters {
+			dstEnv := dst.EnvelopeCounters[version]
+			dstEnv.WrapMsgCount += srcEnv.WrapMsgCount
+			dstEnv.UnwrapMsgCount += srcEnv.UnwrapMsgCount
+			dstEnv.VerifyFailCount += srcEnv.VerifyFailCount
+			dst.EnvelopeCounters[version] = dstEnv
+		}
 		toMap[ifname] = dst
 	}
 }
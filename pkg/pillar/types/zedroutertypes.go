@@ -13,6 +13,7 @@ import (
 	"github.com/lf-edge/eve/pkg/kube/cnirpc"
 	"github.com/lf-edge/eve/pkg/pillar/base"
 	"github.com/lf-edge/eve/pkg/pillar/objtonum"
+	"github.com/lf-edge/eve/pkg/pillar/utils/generics"
 	"github.com/lf-edge/eve/pkg/pillar/utils/netutils"
 	uuid "github.com/satori/go.uuid"
 	"github.com/sirupsen/logrus"
@@ -116,6 +117,13 @@ type AppNetworkStatus struct {
 	AwaitNetworkInstance bool // If any Missing flag is set in the networks
 	// ID of the MAC generator variant that was used to generate MAC addresses for this app.
 	MACGenerator int
+	// VIFOrdering : the final guest-facing ordering of application VIFs,
+	// derived from AppNetAdapterConfig.IntfOrder (ties broken by the adapter
+	// name). VIFs are created and attached to the guest in this order, making
+	// interface enumeration inside the guest deterministic. Entries are
+	// sorted by Order. Reported to the controller so that operators can map
+	// guest interfaces to network instances.
+	VIFOrdering []VIFOrderEntry
 	// Any errors from provisioning the network
 	// ErrorAndTime provides SetErrorNow() and ClearError()
 	ErrorAndTime
@@ -295,6 +303,138 @@ type AppNetAdapterConfig struct {
 	AccessVlanID    uint32
 	IfIdx           uint32 // If we have multiple interfaces on that network, we will increase the index
 	AllowToDiscover bool
+	// StormControl : optional ingress packet-rate limits applied to the VIF
+	// on a Switch network instance (ignored for other NI types).
+	StormControl StormControlConfig
+	// SNIAllowlist : optional list of domain patterns restricting egress TLS
+	// connections initiated from this VIF. When non-empty, new TLS connections
+	// are redirected to an SNI-inspecting filter and are allowed only if the
+	// Server Name Indication from the ClientHello matches one of the patterns
+	// (suffix-matched, i.e. "zededa.net" also matches any of its subdomains).
+	// See NetworkInstanceConfig.SNIFilterFallbackDrop for the handling
+	// of connections whose server name cannot be determined.
+	SNIAllowlist []string
+	// IsolationGroup : optional label grouping applications for intra-NI
+	// traffic isolation. On a local network instance, a VIF assigned
+	// a non-empty isolation group cannot exchange traffic with VIFs
+	// of applications from a different non-empty group, even though they
+	// share the bridge and the subnet. VIFs with an empty isolation group
+	// (the default) are not isolated from anyone.
+	IsolationGroup string
+	// AttachmentMode : how the application VIF attaches to the network
+	// instance. The default is to enslave the VIF into the NI bridge.
+	// The MACVLAN/IPVLAN modes attach the VIF directly to the NI port
+	// as a sub-interface for low-overhead L2-only connectivity (no bridge
+	// hairpin), intended for high-throughput workloads on switch network
+	// instances. User-configured ACLs are not supported in these modes
+	// (the traffic does not traverse the bridge datapath).
+	AttachmentMode VifAttachmentMode
+	// VhostUser : the VIF is attached to the application via a DPDK/vhost-user
+	// interface (a shared-memory datapath between the guest and a user-space
+	// switch) instead of a kernel tap/veth device. There is no kernel netdev
+	// representing such VIF, hence its traffic bypasses kernel netfilter and
+	// is NOT subject to the iptables-based ACL implementation.
+	// See DpdkAclMode for the available ACL enforcement strategies.
+	VhostUser bool
+	// DpdkAclMode : strategy used to enforce user-configured ACLs on
+	// a vhost-user VIF (ignored when VhostUser is disabled).
+	DpdkAclMode DpdkAclMode
+	// StaticRoutes : classless static routes to deliver to the application
+	// instance through this adapter, on top of the routes propagated to all
+	// applications on the network instance. IPv4 routes are pushed using
+	// the DHCP option 121 (RFC 3442); IPv6 routes are advertised using
+	// the Route Information Option of router advertisements (RFC 4191),
+	// with the NI bridge as the implicit next hop.
+	// IPv4 routes with undefined gateway use the NI bridge IP as the next hop.
+	StaticRoutes []IPRoute
+	// DhcpOverrides : optional overrides of selected DHCP parameters for this
+	// adapter, replacing the network instance defaults for this VIF only.
+	DhcpOverrides DhcpOverrides
+}
+
+// DhcpOverrides : optional per-VIF overrides of selected DHCP parameters.
+// Some guest operating systems require a short lease or specific option
+// values to behave properly, hence these can be tuned per adapter without
+// affecting other applications on the same network instance.
+// Zero value of every field means "use the network instance default".
+type DhcpOverrides struct {
+	// LeaseTime : DHCP lease time granted to this VIF
+	// (default is 1 hour, note that dnsmasq does not accept leases
+	// shorter than 2 minutes).
+	LeaseTime time.Duration
+	// MTU : MTU to propagate to this VIF using the DHCP option 26.
+	MTU uint16
+	// NTPServers : NTP servers to announce to this VIF using the DHCP
+	// option 42 (56 in DHCPv6).
+	NTPServers []net.IP
+	// DomainName : domain name to propagate to this VIF using the DHCP
+	// option 15 (24 in DHCPv6).
+	DomainName string
+}
+
+// OverridesOptions returns true if at least one of the DHCP options
+// (i.e. anything apart from the lease time) is overridden.
+func (do DhcpOverrides) OverridesOptions() bool {
+	return do.MTU != 0 || len(do.NTPServers) != 0 || do.DomainName != ""
+}
+
+// VifAttachmentMode : method used to attach an application VIF
+// to a network instance.
+type VifAttachmentMode uint8
+
+const (
+	// VifAttachmentBridge : the default mode - VIF is enslaved into
+	// the NI bridge.
+	VifAttachmentBridge VifAttachmentMode = iota
+	// VifAttachmentMACVLAN : VIF is a MACVLAN sub-interface of the NI port.
+	VifAttachmentMACVLAN
+	// VifAttachmentIPVLAN : VIF is an IPVLAN (L2 mode) sub-interface
+	// of the NI port. Unlike MACVLAN, it shares the MAC address with
+	// the port, which is preferable when the upstream switch limits
+	// the number of MAC addresses per port.
+	VifAttachmentIPVLAN
+)
+
+// DpdkAclMode : strategy used to enforce user-configured ACLs on a VIF backed
+// by a DPDK/vhost-user datapath, whose traffic bypasses kernel netfilter
+// and therefore cannot be filtered with iptables.
+type DpdkAclMode uint8
+
+const (
+	// DpdkAclModeDeny : refuse to activate the application network if its
+	// vhost-user VIF has any user-configured ACLs. This is the default -
+	// security policy must never be silently bypassed.
+	DpdkAclModeDeny DpdkAclMode = iota
+	// DpdkAclModeOVS : program equivalent OpenFlow rules into the user-space
+	// Open vSwitch datapath. Only available when the device runs with
+	// an OVS-provided datapath (not the case for stock EVE images).
+	DpdkAclModeOVS
+	// DpdkAclModeEBPF : enforce ACLs with an eBPF program attached at the
+	// point where the user-space datapath hands packets over to the guest.
+	DpdkAclModeEBPF
+)
+
+// StormControlConfig : packets-per-second limits for flooded traffic received
+// from an application VIF bridged into a switch network instance.
+// Protects the shared uplink and the host CPU from a misbehaving bridged
+// appliance generating a broadcast/multicast storm.
+// Zero value means no limit for the given traffic class.
+type StormControlConfig struct {
+	// BroadcastPPS : limit for broadcast packets (per second).
+	BroadcastPPS uint32
+	// MulticastPPS : limit for (non-broadcast) multicast packets (per second).
+	MulticastPPS uint32
+	// UnknownUnicastPPS : limit for unicast packets (per second).
+	// Note that at the VIF ingress it is not possible to determine whether
+	// the bridge will flood a given unicast frame (this depends on the content
+	// of the FDB at that moment). The limit is therefore applied to all unicast
+	// frames received from the application and should be sized accordingly.
+	UnknownUnicastPPS uint32
+}
+
+// IsEnabled returns true if at least one traffic class has a limit configured.
+func (sc StormControlConfig) IsEnabled() bool {
+	return sc.BroadcastPPS != 0 || sc.MulticastPPS != 0 || sc.UnknownUnicastPPS != 0
 }
 
 // ACEDirection determines rule direction.
@@ -309,6 +449,19 @@ const (
 	AceDirEgress ACEDirection = 2
 )
 
+// ACEIPFamily :
+// IP address family to which an ACE is restricted.
+type ACEIPFamily uint8
+
+const (
+	// AceFamilyAny : ACE applies to both IPv4 and IPv6 traffic.
+	AceFamilyAny ACEIPFamily = iota
+	// AceFamilyIPv4 : ACE applies to IPv4 traffic only.
+	AceFamilyIPv4
+	// AceFamilyIPv6 : ACE applies to IPv6 traffic only.
+	AceFamilyIPv6
+)
+
 // ACE definition is very similar to draft-ietf-netmod-acl-model
 type ACE struct {
 	Matches []ACEMatch
@@ -316,12 +469,28 @@ type ACE struct {
 	Name    string
 	RuleID  int32
 	Dir     ACEDirection
+	// Family restricts the ACE to a single IP address family, allowing
+	// dual-stack apps to have independent IPv4 and IPv6 ACL sets
+	// (e.g. IPv6-only lockdown while IPv4 stays open).
+	// With AceFamilyAny (the default), the ACE is applied to both families
+	// (restricted implicitly by any family-specific matches, such as
+	// an "ip" match with an IPv4 address).
+	Family ACEIPFamily
 }
 
 // ACEMatch determines which traffic is matched by a given ACE.
 // The Type can be "ip" or "host" (aka domain name), "eidset", "protocol",
-// "fport", or "lport" for now. The ip and host matches the remote IP/hostname.
+// "fport", "lport", "srcip", "icmp-type" or "icmp6-type" for now.
+// The ip and host matches the remote IP/hostname.
+// The "srcip" match restricts which remote source IPs/subnets are allowed
+// to initiate connections via a port-map rule (ingress allowlist, e.g. to
+// limit remote management ports of apps to operator networks). It is valid
+// only in combination with the portmap action.
 // The host matching is suffix-matching thus zededa.net matches *.zededa.net.
+// The "icmp-type" / "icmp6-type" matches a specific ICMP(v4) / ICMPv6 type,
+// optionally including the code ("<type>[/<code>]"), and is valid only
+// in combination with the matching "protocol" ("icmp" and "icmpv6",
+// respectively).
 // XXX Need "interface"... e.g. "uplink" or "eth1"? Implicit in network used?
 // For now the matches are bidirectional.
 // XXX Add directionality? Different rate limits in different directions?
@@ -358,6 +527,22 @@ type AppNetAdapterStatus struct {
 	IPv4Assigned      bool     // Set to true once DHCP has assigned it to domU
 	IPAddrMisMatch    bool
 	HostName          string
+	// ACLFlowSamples : the first few flows that matched each dropping ACL rule
+	// (incl. traffic exceeding the rate of a LIMIT rule and the implicit
+	// default-drop) since the last ACL change.
+	// At most MaxACLFlowSamplesPerRule entries are kept per rule.
+	ACLFlowSamples []ACLFlowSample
+}
+
+// VIFOrderEntry : one entry of the final guest-facing VIF ordering
+// (see AppNetworkStatus.VIFOrdering).
+type VIFOrderEntry struct {
+	// Order : requested interface order (AppNetAdapterConfig.IntfOrder).
+	Order int32
+	// MAC : MAC address of the guest-side interface.
+	MAC net.HardwareAddr
+	// Network : UUID of the network instance the VIF is connected to.
+	Network uuid.UUID
 }
 
 // NetworkInstanceInfo : info about created Network instance.
@@ -379,12 +564,109 @@ type NetworkInstanceInfo struct {
 	VlanMap map[uint32]uint32
 	// Counts the number of trunk ports attached to this network instance
 	NumTrunkPorts uint32
+
+	// MulticastGroups : multicast group addresses with members behind each
+	// VIF (key: VIF interface name), learned by IGMP/MLD snooping running
+	// on the NI bridge.
+	MulticastGroups map[string][]string
+}
+
+// AddressSource : source from which an IP address assignment was learned.
+// Sources are ordered by increasing confidence in the learned assignment,
+// i.e. an address learned from a source with a higher value should not be
+// overwritten based on information from a source with a lower value.
+type AddressSource uint8
+
+const (
+	// AddressSourceUndefined : source of the address is not known.
+	AddressSourceUndefined AddressSource = iota
+	// AddressSourceConntrack : address was observed inside a connection
+	// tracking entry attributed to the app.
+	AddressSourceConntrack
+	// AddressSourceARPSnoop : address was learned by snooping ARP traffic.
+	AddressSourceARPSnoop
+	// AddressSourceNDPSnoop : address was learned by snooping IPv6 NDP traffic
+	// (e.g. DAD probes).
+	AddressSourceNDPSnoop
+	// AddressSourceExternalDHCP : address was learned by snooping replies
+	// from an external DHCP server (used with switch network instances).
+	AddressSourceExternalDHCP
+	// AddressSourceEVEInternal : address was selected by EVE for the app
+	// (but not necessarily confirmed in the data-plane yet).
+	AddressSourceEVEInternal
+	// AddressSourceInternalDHCP : address was found in a lease granted
+	// by a DHCP server run by EVE (used with local network instances).
+	AddressSourceInternalDHCP
+	// AddressSourceStatic : address is statically configured (e.g. bridge IP
+	// managed by EVE).
+	AddressSourceStatic
+)
+
+// String returns the string representation of the address source.
+func (s AddressSource) String() string {
+	switch s {
+	case AddressSourceUndefined:
+		return "undefined"
+	case AddressSourceConntrack:
+		return "conntrack"
+	case AddressSourceARPSnoop:
+		return "arp-snoop"
+	case AddressSourceNDPSnoop:
+		return "ndp-snoop"
+	case AddressSourceExternalDHCP:
+		return "external-dhcp"
+	case AddressSourceEVEInternal:
+		return "eve-internal"
+	case AddressSourceInternalDHCP:
+		return "internal-dhcp"
+	case AddressSourceStatic:
+		return "static"
+	}
+	return "unknown"
+}
+
+// AssignedAddr : IP address assigned to an application network adapter,
+// together with the source from which the assignment was learned
+// and the time when the assignment was last confirmed by any source.
+type AssignedAddr struct {
+	Address  net.IP
+	Source   AddressSource
+	LastSeen time.Time
+	// DhcpClientClass : name of the DHCP client class from whose dedicated
+	// address pool this address was leased (see NetworkInstanceConfig.
+	// DHCPClientClasses). Empty if the address was not assigned by the
+	// internal DHCP server or the client was not matched by any class
+	// with a dedicated pool.
+	DhcpClientClass string
 }
 
 // AssignedAddrs : IP addresses assigned to application network adapter.
 type AssignedAddrs struct {
-	IPv4Addr  net.IP
-	IPv6Addrs []net.IP
+	IPv4Addr  AssignedAddr
+	IPv6Addrs []AssignedAddr
+}
+
+// AssignedAddrsFromIPs builds AssignedAddrs from plain IP addresses,
+// i.e. without the address assignment metadata.
+func AssignedAddrsFromIPs(ipv4Addr net.IP, ipv6Addrs []net.IP) AssignedAddrs {
+	addrs := AssignedAddrs{IPv4Addr: AssignedAddr{Address: ipv4Addr}}
+	for _, ipv6Addr := range ipv6Addrs {
+		addrs.IPv6Addrs = append(addrs.IPv6Addrs, AssignedAddr{Address: ipv6Addr})
+	}
+	return addrs
+}
+
+// GetIPv4Addr returns the assigned IPv4 address (without metadata).
+func (a AssignedAddrs) GetIPv4Addr() net.IP {
+	return a.IPv4Addr.Address
+}
+
+// GetIPv6Addrs returns the assigned IPv6 addresses (without metadata).
+func (a AssignedAddrs) GetIPv6Addrs() (addrs []net.IP) {
+	for _, addr := range a.IPv6Addrs {
+		addrs = append(addrs, addr.Address)
+	}
+	return addrs
 }
 
 // VifNameMac : name and MAC address assigned to app VIF.
@@ -456,6 +738,63 @@ type NetworkInstanceMetrics struct {
 	NetworkMetrics NetworkMetrics
 	ProbeMetrics   ProbeMetrics
 	VlanMetrics    VlanMetrics
+	DNSMetrics     []DNSServerMetrics
+	// DatapathMetrics : loss/latency measured on the application datapath
+	// (from the NI bridge IP towards the selected uplink gateway and a remote
+	// endpoint). Empty if the datapath probing is disabled.
+	DatapathMetrics []DatapathEndpointMetrics
+	// InstalledACLRules : the total number of iptables/ip6tables rules currently
+	// installed for this network instance, including rules created for ACLs
+	// of the connected applications.
+	InstalledACLRules uint32
+	// NAT64 : metrics of the NAT64 translator of this network instance.
+	// All-zero if NAT64 is not enabled.
+	NAT64 NAT64Metrics
+	// Maintenance : resources reclaimed by the periodic per-NI maintenance.
+	Maintenance NIMaintenanceMetrics
+}
+
+// NIMaintenanceMetrics : resources reclaimed by the periodic maintenance
+// of a network instance (dnsmasq log rotation, lease DB compaction,
+// garbage collection of orphaned ipsets). The counters are cumulative
+// since zedrouter started.
+type NIMaintenanceMetrics struct {
+	// LastRun : when the maintenance last ran for this network instance.
+	// Zero time if it has not run yet.
+	LastRun time.Time
+	// RotatedLogBytes : total size of dnsmasq logs rotated out because
+	// the log file grew over the size limit.
+	RotatedLogBytes uint64
+	// CompactedLeases : total number of long-expired entries removed
+	// from the dnsmasq lease DB.
+	CompactedLeases uint32
+	// RemovedIPSets : total number of orphaned ipsets removed from the kernel.
+	RemovedIPSets uint32
+}
+
+// NAT64Metrics : metrics of the NAT64 translator of a network instance.
+type NAT64Metrics struct {
+	// DynamicMappings : the number of IPv4 addresses from the NAT64 pool
+	// currently mapped to IPv6 hosts by the translator.
+	DynamicMappings uint32
+}
+
+// DatapathEndpointMetrics : loss/latency statistics for one endpoint probed
+// over the application datapath of a NI.
+type DatapathEndpointMetrics struct {
+	Endpoint         string // IP address of the probed endpoint
+	IsGateway        bool   // true if the endpoint is the selected uplink gateway
+	SentCnt          uint32 // total number of probes sent
+	RecvCnt          uint32 // total number of probe replies received
+	RecentLossPct    uint8  // percentage of probes lost in the recent window
+	AvgLatencyInMsec uint32 // average latency of recent successful probes
+}
+
+// DNSServerMetrics : health metrics for one upstream DNS server used by a NI.
+type DNSServerMetrics struct {
+	Server           string // IP address of the DNS server
+	Reachable        bool   // Is the server responding to queries
+	AvgLatencyInMsec uint32 // average latency of recent successful queries
 }
 
 // VlanMetrics : VLAN metrics for a given NI.
@@ -605,6 +944,16 @@ type NetworkMetric struct {
 	RxAclDrops          uint64 // For implicit deny/drop at end
 	TxAclRateLimitDrops uint64 // For all rate limited rules
 	RxAclRateLimitDrops uint64 // For all rate limited rules
+	// DNSRateLimitDrops : number of DNS queries from the app dropped
+	// by the per-VIF DNS query rate limiter
+	// (see NetworkInstanceConfig.DNSQueryRateLimit).
+	// Only collected for app VIFs.
+	DNSRateLimitDrops uint64
+	// IPBlocklistHits : number of packets dropped in either direction by the
+	// IP reputation blocklist of the network instance
+	// (see NetworkInstanceConfig.IPBlocklist).
+	// Only collected for app VIFs.
+	IPBlocklistHits uint64
 }
 
 // NetworkInstanceType : type of network instance.
@@ -662,15 +1011,425 @@ type NetworkInstanceConfig struct {
 	DnsNameToIPList []DNSNameToIP // Used for DNS and ACL ipset
 	MTU             uint16        // IP MTU
 
+	// IPv6RA : router advertisement options (only applicable to IPv6 NIs).
+	IPv6RA IPv6RAConfig
+
+	// EgressIP : pin the egress traffic of this (local) network instance
+	// to the given uplink IP address, i.e. SNAT to this specific address
+	// instead of letting MASQUERADE pick an arbitrary one.
+	// The address must be assigned to the uplink port selected for the NI,
+	// otherwise MASQUERADE is used as a fallback.
+	EgressIP net.IP
+
 	// Route configuration
 	PropagateConnRoutes bool
 	StaticRoutes        []IPRoute
 
+	// SNIFilterFallbackDrop : what to do with egress TLS connections subject
+	// to SNI allowlisting (see AppNetAdapterConfig.SNIAllowlist) when
+	// the server name cannot be determined or the SNI-inspecting filter
+	// is not running. By default (false) such connections are allowed through.
+	SNIFilterFallbackDrop bool
+
+	// ConntrackTimeouts : optional tuning of connection tracking timeouts
+	// applied to flows of this network instance.
+	ConntrackTimeouts ConntrackTimeouts
+
+	// DNSQueryRateLimit : maximum number of DNS queries per second that each
+	// application VIF is allowed to send to the DNS server of this (local)
+	// network instance. Queries above the limit are dropped (enforced with
+	// iptables hashlimit, counted per source IP), protecting dnsmasq from
+	// a misbehaving app starving the other apps on the NI.
+	// Zero value means no limit.
+	DNSQueryRateLimit uint32
+
+	// SplitDNS : optional set of rules forwarding DNS queries for specific
+	// domains to dedicated upstream resolvers, bypassing the default
+	// resolvers of this (local) network instance.
+	SplitDNS []SplitDNSRule
+
+	// NAT64 : optional NAT64+DNS64 gateway allowing IPv6-only application
+	// instances connected to this (local, IPv6-only) network instance
+	// to reach IPv4-only destinations.
+	NAT64 NAT64Config
+
+	// IPBlocklist : optional subscription of this network instance to an IP
+	// reputation blocklist (threat feed). Traffic between the applications
+	// and the blocklisted addresses is dropped on the device.
+	IPBlocklist IPBlocklistConfig
+
+	// BridgeParams : optional tuning of the Linux bridge parameters
+	// (STP, FDB ageing time, multicast snooping) of this network instance.
+	BridgeParams BridgeParamsConfig
+
+	// ExtraBridgePorts : additional physical ports bridged under this
+	// (switch) network instance, next to the port referenced by
+	// PortLogicalLabel. With multiple member ports the device acts as
+	// a small managed switch between the attached L2 segments and the
+	// applications; STP is then forcibly enabled on the bridge to mitigate
+	// loops through externally attached switches. See SwitchPortConfig.
+	ExtraBridgePorts []SwitchPortConfig
+
+	// RefreshVIFsOnReconnect : briefly bounce the carrier of the guest-facing
+	// VIFs after significant events affecting this network instance (uplink
+	// port change, bridge re-creation), so that guests re-ARP and refresh
+	// their DHCP state quickly instead of keeping stale network configuration.
+	RefreshVIFsOnReconnect bool
+
+	// EnableARPProxy : enable proxy-ARP on the NI bridge. The device then
+	// answers ARP requests received on the bridge (including the uplink
+	// port of a switch NI) on behalf of addresses it knows how to route,
+	// allowing applications with static addresses from a subnet not
+	// directly present on the uplink to be reached via the device.
+	// The reconciler complements the proxy_arp sysctl with static neighbor
+	// entries for application VIFs with known addresses.
+	EnableARPProxy bool
+
+	// VirtualIPs : optional floating service IPs shared by groups of
+	// application instances connected to this (local) network instance,
+	// with health-check driven failover (VRRP-like) managed by zedrouter.
+	VirtualIPs []VirtualIPConfig
+
+	// DHCPClientClasses : optional classification of DHCP clients of this
+	// (local) network instance based on the vendor-class and user-class
+	// options they send with their requests. Clients matched by a class
+	// receive the class-specific DHCP options and, if the class defines
+	// a dedicated address pool, an address allocated from that pool instead
+	// of the NI-wide DhcpRange. Useful with third-party devices where
+	// the client type is only recognizable from the vendor/user class.
+	DHCPClientClasses []DHCPClientClass
+
+	// GateDHCPOnConnectivity : defer handing out DHCP leases to applications
+	// connected to this (local) network instance until the NI has working
+	// external connectivity (i.e. a default route via the selected port).
+	// Useful for applications which boot faster than the port obtains its
+	// IP address and would otherwise give up on their in-guest connectivity
+	// retries. Note that while the gate is closed, the DNS server of the NI
+	// is down as well. Not applicable to air-gapped network instances.
+	GateDHCPOnConnectivity bool
+
+	// SecondarySubnets : additional IPv4 subnets carried by this (local)
+	// network instance next to the primary Subnet, all sharing the same
+	// bridge. Intended for application migrations where the old and the new
+	// address plan must coexist temporarily (see NISecondarySubnet).
+	SecondarySubnets []NISecondarySubnet
+
+	// EgressProxy : optional enforcement of an egress web proxy for
+	// applications connected to this (local) network instance
+	// (see NIEgressProxy). Disabled when nil.
+	EgressProxy *NIEgressProxy
+
 	// Any errors from the parser
 	// ErrorAndTime provides SetErrorNow() and ClearError()
 	ErrorAndTime
 }
 
+// NIEgressProxy : enforcement of an egress web proxy for applications
+// connected to a local network instance, intended for enterprises mandating
+// that all web traffic leaves through a proxy. Plain HTTP traffic can be
+// transparently redirected to the proxy, while for HTTPS (which cannot be
+// transparently redirected without breaking TLS) direct access to port 443
+// is blocked except towards the proxy itself, forcing applications to use
+// the proxy explicitly. The proxy location can be advertised to applications
+// via the DHCP option 252 (PAC file URL).
+type NIEgressProxy struct {
+	// ProxyIP : IP address of the proxy.
+	ProxyIP net.IP
+	// ProxyPort : port of the proxy.
+	ProxyPort uint16
+	// RedirectHTTP : transparently redirect (DNAT) plain HTTP traffic
+	// (TCP port 80) leaving the network instance to the proxy.
+	RedirectHTTP bool
+	// EnforceHTTPS : block direct HTTPS traffic (TCP port 443) leaving
+	// the network instance, except when destined to the proxy itself.
+	EnforceHTTPS bool
+	// PACFileURL : URL of the Proxy Auto-Configuration file, advertised
+	// to applications via the DHCP option 252. Empty to not advertise.
+	PACFileURL string
+}
+
+// NISecondarySubnet : an additional IPv4 subnet carried by a local network
+// instance next to the primary subnet (see NetworkInstanceConfig.Subnet).
+// All subnets share the NI bridge - the bridge is assigned a gateway address
+// inside every subnet and the DHCP server of the NI serves a dedicated
+// address pool per subnet. Traffic leaving the device is SNATed regardless
+// of which subnet it originates from.
+type NISecondarySubnet struct {
+	// Subnet : the secondary IPv4 prefix.
+	Subnet net.IPNet
+	// Gateway : address inside Subnet to assign to the NI bridge and to
+	// advertise as the default gateway to applications addressed from this
+	// subnet. If undefined, the first host address of Subnet is used.
+	Gateway net.IP
+	// DhcpRange : range of addresses from Subnet to allocate to applications.
+	DhcpRange IPRange
+}
+
+// GatewayIP returns the bridge (gateway) address of the secondary subnet:
+// the configured Gateway or, if undefined, the first host address of Subnet.
+func (s NISecondarySubnet) GatewayIP() net.IP {
+	if s.Gateway != nil {
+		return s.Gateway
+	}
+	if s.Subnet.IP == nil {
+		return nil
+	}
+	return netutils.AddToIP(s.Subnet.IP, 1)
+}
+
+// VirtualIPConfig : a floating (virtual) service IP shared by a group of
+// application instances connected to the same local network instance.
+// Zedrouter health-checks the candidate applications and assigns the IP
+// to the highest-priority healthy one by performing an ARP takeover on
+// the NI bridge (gratuitous ARP plus a static neighbor entry), so that
+// both the other applications and external clients coming in through
+// port maps reach the current owner. The owner application is expected
+// to accept traffic destined to the virtual IP (e.g. by having it
+// configured as a secondary address or by a local DNAT).
+type VirtualIPConfig struct {
+	// IP : the virtual IP address. Must be inside the NI subnet and outside
+	// of the DHCP range. Currently only IPv4 is supported (the failover
+	// is performed using ARP).
+	IP net.IP
+	// Apps : candidate application instances in the decreasing order of
+	// priority. The first healthy candidate becomes the owner of the
+	// virtual IP (i.e. a recovered higher-priority candidate preempts
+	// a lower-priority owner).
+	Apps []uuid.UUID
+	// Ports : TCP/UDP target ports of the service provided behind
+	// the virtual IP. Port-map ACL rules of the candidate applications
+	// whose target port is listed here are DNATed to the virtual IP
+	// instead of the application's own address, so that external clients
+	// transparently follow a failover.
+	Ports []uint16
+	// ProbePort : TCP port health-checked by zedrouter on the real IP
+	// address of every candidate application.
+	ProbePort uint16
+	// ProbeInterval : seconds between health probes of every candidate.
+	// Zero means the zedrouter default.
+	ProbeInterval uint32
+	// ProbeFailThreshold : number of continuous probe failures after which
+	// a candidate is declared unhealthy. Zero means the zedrouter default.
+	ProbeFailThreshold uint8
+}
+
+// HasCandidate returns true if the given application instance is one
+// of the candidates for owning the virtual IP.
+func (vip VirtualIPConfig) HasCandidate(app uuid.UUID) bool {
+	for _, candidate := range vip.Apps {
+		if candidate == app {
+			return true
+		}
+	}
+	return false
+}
+
+// BacksPort returns true if the given target port is one of the service
+// ports provided behind the virtual IP.
+func (vip VirtualIPConfig) BacksPort(port int) bool {
+	for _, vipPort := range vip.Ports {
+		if int(vipPort) == port {
+			return true
+		}
+	}
+	return false
+}
+
+// DHCPClientClass : classification of DHCP clients of a network instance
+// based on the content of the vendor-class (DHCP option 60) and user-class
+// (option 77) options they send. In dnsmasq, clients matched by a class get
+// the class tag set, which scopes the class-specific DHCP options and
+// address pool to them. The name of the matched class is reported with
+// every address assignment made from the dedicated pool of the class
+// (see AssignedAddr.DhcpClientClass).
+type DHCPClientClass struct {
+	// Name : class name, unique among the classes of the network instance.
+	// Used to derive the dnsmasq tag of the class and reported with address
+	// assignments made from the dedicated pool of the class.
+	Name string
+	// VendorClass : match clients whose vendor-class identifier contains
+	// this substring. Leave empty to not match on the vendor class.
+	VendorClass string
+	// UserClass : match clients whose user-class contains this substring.
+	// Leave empty to not match on the user class.
+	UserClass string
+	// IPRange : optional dedicated address pool for the clients of this
+	// class. Must be inside the NI subnet and must not overlap with
+	// the NI-wide DhcpRange. Leave zero to allocate addresses of classified
+	// clients from the NI-wide range.
+	IPRange IPRange
+	// DhcpOptions : overrides of selected DHCP options applied to the
+	// clients of this class, replacing the corresponding NI-wide values.
+	// Note that the lease-time override takes effect only for classes
+	// with a dedicated address pool (it is configured per DHCP range).
+	DhcpOptions DhcpOverrides
+}
+
+// DHCPClientClassForIP returns the name of the DHCP client class from whose
+// dedicated address pool the given (leased) IP address was allocated.
+// Returns an empty string if the address does not fall into any class pool
+// (which includes clients of classes without a dedicated pool).
+func (config *NetworkInstanceConfig) DHCPClientClassForIP(ip net.IP) string {
+	for _, class := range config.DHCPClientClasses {
+		if class.IPRange.Start == nil {
+			continue
+		}
+		if class.IPRange.Contains(ip) {
+			return class.Name
+		}
+	}
+	return ""
+}
+
+// SplitDNSRule : forward DNS queries for the given domain (and all of its
+// subdomains) to dedicated upstream DNS servers, instead of the default
+// resolvers of the network instance. Useful e.g. for a corporate domain
+// whose records are only resolvable by a DNS server reachable via VPN.
+type SplitDNSRule struct {
+	// Domain : domain suffix to match (without the leading dot).
+	Domain string
+	// Servers : upstream DNS servers to forward the matching queries to.
+	Servers []net.IP
+}
+
+// ConntrackTimeouts : per-NI connection tracking timeouts, overriding
+// the global nf_conntrack defaults for flows of the network instance.
+// Useful to keep long-lived (e.g. industrial protocol) connections in the
+// conntrack table beyond the default timeouts, or conversely to time-out
+// entries aggressively and cap the table size.
+// Zero value for any of the timeouts means that the global default is used.
+type ConntrackTimeouts struct {
+	// TCPEstablished : timeout in seconds for established TCP connections.
+	TCPEstablished uint32
+	// UDP : timeout in seconds for UDP flows (applied to both unreplied
+	// and replied/assured entries).
+	UDP uint32
+	// ICMP : timeout in seconds for ICMP request/reply entries.
+	ICMP uint32
+}
+
+// IsDefined returns true if at least one of the timeouts is set.
+func (ct ConntrackTimeouts) IsDefined() bool {
+	return ct != ConntrackTimeouts{}
+}
+
+// IPBlocklistConfig : subscription of a network instance to an IP reputation
+// blocklist. Blocklisted IPv4/IPv6 addresses and subnets are materialized
+// as ipsets (refreshed atomically on every change) and traffic from the apps
+// to these destinations, as well as traffic coming from these sources into
+// the apps, is dropped by per-VIF ACL rules. The number of dropped packets
+// is reported per VIF in NetworkMetric.IPBlocklistHits.
+type IPBlocklistConfig struct {
+	// Enable : enable blocklist-based filtering for this network instance.
+	Enable bool
+	// Filename : path to a file with blocklist entries, one IPv4/IPv6 address
+	// or CIDR per line (empty lines and '#' comments are ignored). The file is
+	// installed on the device out-of-band or by the controller agent, which is
+	// also responsible for converting structured threat feeds (e.g. STIX) into
+	// this plain format.
+	Filename string
+	// Entries : blocklist entries (IP addresses and CIDRs) provided directly
+	// by the controller as part of the network instance config.
+	// Combined with the content of Filename (if defined).
+	Entries []string
+}
+
+// BridgeParamsConfig : tuning of the (sysfs-exposed) parameters of the Linux
+// bridge backing a network instance. The parameters are modeled as an item
+// of the intended state, meaning that they are reapplied whenever the bridge
+// is recreated instead of silently falling back to the kernel defaults.
+type BridgeParamsConfig struct {
+	// EnableSTP : run the Spanning Tree Protocol on the bridge to mitigate
+	// L2 loops (mostly useful on switch network instances where external
+	// switches may be attached via the uplink port).
+	EnableSTP bool
+	// AgeingTime : time in seconds after which an inactive entry is removed
+	// from the bridge forwarding database. Zero means the kernel default
+	// (300 seconds).
+	AgeingTime uint32
+	// DisableMulticastSnooping : disable IGMP/MLD snooping on the bridge,
+	// flooding multicast traffic to all ports. By default snooping is enabled
+	// and multicast is forwarded only to ports with members of the target
+	// multicast group.
+	DisableMulticastSnooping bool
+}
+
+// SwitchPortConfig : a physical port bridged as an additional member
+// of a switch network instance (see NetworkInstanceConfig.ExtraBridgePorts).
+// The port must not be used for device management or by another network
+// instance.
+type SwitchPortConfig struct {
+	// LogicalLabel : logical label of the port (from DevicePortConfig).
+	LogicalLabel string
+	// AccessVlanID : put the port into the access mode for the given VLAN ID
+	// (untagged traffic on the wire, tagged inside the bridge). Values 0
+	// and 1 configure the port as a trunk carrying all VLANs used by the
+	// network instance.
+	AccessVlanID uint16
+}
+
+// NAT64Config : configuration of an optional NAT64+DNS64 gateway service
+// of a local IPv6-only network instance (RFC 6146 + RFC 6147).
+// When enabled, DNS queries of the connected applications for IPv4-only
+// destinations are answered with AAAA records synthesized from the NAT64
+// prefix, and traffic sent to such addresses is translated to IPv4
+// before leaving through the uplink port.
+type NAT64Config struct {
+	// Enable : enable the NAT64+DNS64 service for this network instance.
+	Enable bool
+	// NAT64Prefix : IPv6 prefix used to embed IPv4 destination addresses
+	// (also announced by DNS64). Nil value means that the well-known prefix
+	// 64:ff9b::/96 is used.
+	NAT64Prefix *net.IPNet
+	// IPv4Pool : pool of IPv4 addresses which the translator dynamically
+	// maps to the IPv6 hosts. Nil value means that a per-NI /24 subrange
+	// of the CGNAT space 100.88.0.0/16 is used (derived from the bridge
+	// number allocated to the network instance).
+	IPv4Pool *net.IPNet
+}
+
+// IPv6RAConfig : router advertisement parameters for an IPv6 network instance.
+// Zero value means that defaults hardcoded in the RA daemon config template
+// are used instead.
+type IPv6RAConfig struct {
+	// AdvManagedFlag : tell hosts to use DHCPv6 for address assignment.
+	AdvManagedFlag bool
+	// AdvOtherConfigFlag : tell hosts to use DHCPv6 for other configuration
+	// (e.g. DNS servers), while addresses are still obtained via SLAAC.
+	AdvOtherConfigFlag bool
+	// PrefixPreferredLifetime : number of seconds for which addresses generated
+	// from the advertised NI prefix remain preferred.
+	// Zero value means that the prefix is not advertised with explicit lifetimes.
+	PrefixPreferredLifetime uint32
+	// PrefixValidLifetime : number of seconds for which addresses generated
+	// from the advertised NI prefix remain valid.
+	// Zero value means that the prefix is not advertised with explicit lifetimes.
+	PrefixValidLifetime uint32
+	// RDNSS : recursive DNS servers advertised to hosts inside RA messages
+	// (RFC 8106).
+	RDNSS []net.IP
+	// DNSSL : DNS search list (domain names) advertised to hosts inside RA
+	// messages (RFC 8106).
+	DNSSL []string
+}
+
+// IsDefined returns true if at least one RA option was explicitly configured.
+func (c IPv6RAConfig) IsDefined() bool {
+	return c.AdvManagedFlag || c.AdvOtherConfigFlag ||
+		c.PrefixPreferredLifetime != 0 || c.PrefixValidLifetime != 0 ||
+		len(c.RDNSS) != 0 || len(c.DNSSL) != 0
+}
+
+// Equal compares two IPv6RAConfig instances.
+func (c IPv6RAConfig) Equal(c2 IPv6RAConfig) bool {
+	return c.AdvManagedFlag == c2.AdvManagedFlag &&
+		c.AdvOtherConfigFlag == c2.AdvOtherConfigFlag &&
+		c.PrefixPreferredLifetime == c2.PrefixPreferredLifetime &&
+		c.PrefixValidLifetime == c2.PrefixValidLifetime &&
+		generics.EqualSetsFn(c.RDNSS, c2.RDNSS, netutils.EqualIPs) &&
+		generics.EqualSets(c.DNSSL, c2.DNSSL)
+}
+
 // IPRoute : single IP route entry.
 type IPRoute struct {
 	DstNetwork *net.IPNet
@@ -753,10 +1512,11 @@ func (config *NetworkInstanceConfig) IsIPv6() bool {
 // Even if a network instance is eligible for probing as determined by this method,
 // the actual process of connectivity probing may still be inactive if there are
 // no uplink ports available that match the label.
-func (config *NetworkInstanceConfig) WithUplinkProbing() bool {
+func (config *NetworkInstanceConfig) WithUplinkProbing(
+	customLabels ...SharedPortLabel) bool {
 	switch config.Type {
 	case NetworkInstanceTypeLocal:
-		return IsSharedPortLabel(config.PortLogicalLabel)
+		return IsSharedPortLabel(config.PortLogicalLabel, customLabels...)
 	default:
 		return false
 	}
@@ -781,19 +1541,16 @@ const (
 	FreeUplinkLabel = "freeuplink"
 )
 
-// IsSharedPortLabel : returns true if the logical label references multiple
-// ports.
-// Currently used labels are:
+// IsSharedPortLabel : returns true if the logical label (potentially)
+// references multiple ports. This covers the built-in labels
+// (see BuiltinSharedPortLabels):
 //   - "uplink": any management interface
 //   - "freeuplink": any management interface with 0 cost
-func IsSharedPortLabel(label string) bool {
-	switch label {
-	case UplinkLabel:
-		return true
-	case FreeUplinkLabel:
-		return true
-	}
-	return false
+//
+// plus any controller-defined labels with custom match expressions
+// passed as extra arguments (see DeviceNetworkStatus.SharedPortLabels).
+func IsSharedPortLabel(label string, customLabels ...SharedPortLabel) bool {
+	return LookupSharedPortLabel(label, customLabels) != nil
 }
 
 type ChangeInProgressType int32
@@ -839,6 +1596,20 @@ type NetworkInstanceStatus struct {
 	// for the associated port (e.g. NI MTU is higher than MTU of the uplink port).
 	MTUConflictErr ErrorAndTime
 
+	// ActiveSplitDNS : split-DNS mappings currently applied in the DNS server
+	// of this network instance (see NetworkInstanceConfig.SplitDNS).
+	// Empty when the network instance is not activated.
+	ActiveSplitDNS []SplitDNSRule
+
+	// ActiveVirtualIPs : current state of the floating service IPs of this
+	// network instance (see NetworkInstanceConfig.VirtualIPs).
+	// Empty when the network instance is not activated.
+	ActiveVirtualIPs []VirtualIPStatus
+
+	// ProcessHealth : health of network helper daemons (dnsmasq, ...)
+	// spawned by zedrouter for this network instance.
+	ProcessHealth []ProcessHealth
+
 	// Decided by local/remote probing
 	SelectedUplinkLogicalLabel string
 	SelectedUplinkIntfName     string
@@ -857,6 +1628,21 @@ type NetworkInstanceStatus struct {
 	ErrorAndTime
 }
 
+// VirtualIPStatus : current state of one floating service IP of a network
+// instance (see NetworkInstanceConfig.VirtualIPs).
+type VirtualIPStatus struct {
+	// IP : the virtual IP address.
+	IP net.IP
+	// ActiveApp : application instance currently owning the virtual IP.
+	// Nil-UUID when no candidate is healthy.
+	ActiveApp uuid.UUID
+	// FailoverCount : how many times the ownership of the virtual IP
+	// changed since the network instance was activated.
+	FailoverCount uint32
+	// LastFailover : time of the last ownership change.
+	LastFailover time.Time
+}
+
 // LogCreate :
 func (status NetworkInstanceStatus) LogCreate(logBase *base.LogObject) {
 	logObject := base.NewLogObject(logBase, base.NetworkInstanceStatusLogType, "",
@@ -898,11 +1684,11 @@ func (status NetworkInstanceStatus) LogKey() string {
 // IsIpAssigned returns true if the given IP address is assigned to any app VIF.
 func (status *NetworkInstanceStatus) IsIpAssigned(ip net.IP) bool {
 	for _, assignments := range status.IPAssignments {
-		if ip.Equal(assignments.IPv4Addr) {
+		if ip.Equal(assignments.IPv4Addr.Address) {
 			return true
 		}
 		for _, nip := range assignments.IPv6Addrs {
-			if ip.Equal(nip) {
+			if ip.Equal(nip.Address) {
 				return true
 			}
 		}
@@ -999,6 +1785,26 @@ type FlowRec struct {
 	RxPkts    int64
 }
 
+// MaxACLFlowSamplesPerRule : at most this many flow tuples are sampled
+// per ACL rule for AppNetAdapterStatus.ACLFlowSamples.
+const MaxACLFlowSamplesPerRule = 5
+
+// ACLFlowSample : one of the first few flows that matched a dropping ACL rule
+// since the last ACL change. While ACE counters tell how often a rule fires,
+// the sampled flow tuples reveal which traffic the rule is actually catching.
+type ACLFlowSample struct {
+	// ACLID : ID of the matched ACL rule.
+	// 0 stands for the implicit default-drop rule.
+	ACLID int32
+	// Flow : the sampled flow tuple (Src refers to the application,
+	// Dst to the remote endpoint).
+	Flow IPTuple
+	// Inbound : true if the flow was initiated from outside.
+	Inbound bool
+	// RecordedAt : when the sample was recorded.
+	RecordedAt time.Time
+}
+
 // DNSReq :
 type DNSReq struct {
 	HostName    string
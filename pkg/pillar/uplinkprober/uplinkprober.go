@@ -303,8 +303,8 @@ func (p *UplinkProber) GetProbeMetrics(
 	remoteIntvl := time.Duration(p.config.NHToRemoteProbeRatio) * p.config.NHProbeInterval
 	metrics.RemotePingIntvl = uint32(remoteIntvl / time.Second)
 	config := p.niConfig[ni.String()]
-	for _, uplinkStatus := range p.uplinkProbeStatus {
-		if config.PortLogicalLabel == types.FreeUplinkLabel && uplinkStatus.cost > 0 {
+	for uplinkLL, uplinkStatus := range p.uplinkProbeStatus {
+		if !p.uplinkMatchesLabel(config.PortLogicalLabel, uplinkLL) {
 			continue
 		}
 		metrics.UplinkCount++
@@ -618,12 +618,12 @@ func (p *UplinkProber) pickUplinkForNI(ni uuid.UUID) (changed bool) {
 	//      with at least one UP state.
 	lowestCost := uint8(255)
 	anyUPState := false
-	for _, uplinkStatus := range p.uplinkProbeStatus {
+	for uplinkLL, uplinkStatus := range p.uplinkProbeStatus {
 		if uplinkStatus.newlyAdded {
 			// Not yet probed, skip.
 			continue
 		}
-		if config.PortLogicalLabel == types.FreeUplinkLabel && uplinkStatus.cost > 0 {
+		if !p.uplinkMatchesLabel(config.PortLogicalLabel, uplinkLL) {
 			continue
 		}
 		if uplinkStatus.upCount() == 0 {
@@ -688,7 +688,7 @@ func (p *UplinkProber) pickUplinkForNI(ni uuid.UUID) (changed bool) {
 	}
 	// 3. Find any uplink matching the label that has unicast IP address.
 	for uplinkLL, uplinkStatus := range p.uplinkProbeStatus {
-		if config.PortLogicalLabel == types.FreeUplinkLabel && uplinkStatus.cost > 0 {
+		if !p.uplinkMatchesLabel(config.PortLogicalLabel, uplinkLL) {
 			continue
 		}
 		for _, ip := range uplinkStatus.localAddrs {
@@ -703,7 +703,7 @@ func (p *UplinkProber) pickUplinkForNI(ni uuid.UUID) (changed bool) {
 	}
 	// 4. Find any uplink matching the label that at least has a local IP address.
 	for uplinkLL, uplinkStatus := range p.uplinkProbeStatus {
-		if config.PortLogicalLabel == types.FreeUplinkLabel && uplinkStatus.cost > 0 {
+		if !p.uplinkMatchesLabel(config.PortLogicalLabel, uplinkLL) {
 			continue
 		}
 		if len(uplinkStatus.localAddrs) > 0 {
@@ -716,8 +716,8 @@ func (p *UplinkProber) pickUplinkForNI(ni uuid.UUID) (changed bool) {
 	}
 	// 5. If none of the uplinks have valid unicast/local IP address just pick
 	//    the first that matches the label
-	for uplinkLL, uplinkStatus := range p.uplinkProbeStatus {
-		if config.PortLogicalLabel == types.FreeUplinkLabel && uplinkStatus.cost > 0 {
+	for uplinkLL := range p.uplinkProbeStatus {
+		if !p.uplinkMatchesLabel(config.PortLogicalLabel, uplinkLL) {
 			continue
 		}
 		p.log.Noticef(
@@ -730,6 +730,20 @@ func (p *UplinkProber) pickUplinkForNI(ni uuid.UUID) (changed bool) {
 	return false
 }
 
+// uplinkMatchesLabel : returns true if the uplink port belongs to the set
+// of ports referenced by the given (possibly shared) port label.
+// Shared labels, including those defined by the controller with custom
+// match expressions, are resolved by DeviceNetworkStatus against the current
+// set of ports.
+func (p *UplinkProber) uplinkMatchesLabel(label, uplinkLL string) bool {
+	for _, port := range p.dns.GetPortsByLogicallabel(label) {
+		if port.Logicallabel == uplinkLL {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *UplinkProber) getIntfOrderInDNS(ifNameOrLabel string, isLabel bool) int {
 	for i := range p.dns.Ports {
 		if isLabel && p.dns.Ports[i].Logicallabel == ifNameOrLabel {
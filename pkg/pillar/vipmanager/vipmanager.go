@@ -0,0 +1,540 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vipmanager is used by zedrouter to manage floating (virtual)
+// service IPs shared by groups of application instances connected to the
+// same local network instance (see types.VirtualIPConfig). The manager
+// periodically health-checks the candidate applications with TCP probes
+// and assigns every virtual IP to the highest-priority healthy candidate.
+// The failover itself is VRRP-like: the manager broadcasts gratuitous ARP
+// on the NI bridge (so that the other applications update their ARP caches)
+// and installs a static neighbor entry for the virtual IP (so that traffic
+// routed through the host, e.g. DNATed port maps, reaches the new owner).
+package vipmanager
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+	uuid "github.com/satori/go.uuid"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// How often the manager wakes up to check which virtual IPs are due
+// for the next round of health probes.
+const schedulingTickInterval = time.Second
+
+// VIPManager periodically health-checks candidate applications of every
+// tracked virtual IP and performs an ARP takeover on the NI bridge whenever
+// the ownership of a virtual IP should change. Watchers are notified about
+// every ownership change so that zedrouter can update the published network
+// instance status.
+type VIPManager struct {
+	sync.Mutex
+	log    *base.LogObject
+	config Config
+
+	nis        map[uuid.UUID]*niVIPs
+	watcherChs []chan []FailoverEvent
+
+	tickTicker *time.Ticker
+}
+
+// Config : configuration for VIPManager.
+// Currently, this is not configurable via controller - the per-VIP probing
+// parameters from the controller are part of VIPConfig instead.
+type Config struct {
+	// ProbeInterval : default interval between health probes of every
+	// candidate, used when VIPConfig leaves the interval unspecified.
+	ProbeInterval time.Duration
+	// ProbeTimeout : timeout for a single TCP health probe.
+	ProbeTimeout time.Duration
+	// ProbeFailThreshold : default number of continuous probe failures
+	// after which a candidate is declared unhealthy, used when VIPConfig
+	// leaves the threshold unspecified.
+	ProbeFailThreshold uint8
+	// GratuitousARPCount : how many copies of the gratuitous ARP
+	// announcement are broadcast during a takeover (ARP is unreliable,
+	// multiple copies increase the chance of delivery).
+	GratuitousARPCount int
+}
+
+// DefaultConfig : default configuration for VIPManager.
+func DefaultConfig() Config {
+	return Config{
+		ProbeInterval:      5 * time.Second,
+		ProbeTimeout:       2 * time.Second,
+		ProbeFailThreshold: 3,
+		GratuitousARPCount: 3,
+	}
+}
+
+// VIPCandidate : one candidate application instance for owning a virtual IP.
+type VIPCandidate struct {
+	// App : UUID of the application instance.
+	App uuid.UUID
+	// IP : real IP address of the application VIF connected to the NI.
+	// Nil when not (yet) known - such candidate is not eligible to own
+	// the virtual IP.
+	IP net.IP
+	// MAC : MAC address of the application VIF connected to the NI.
+	MAC net.HardwareAddr
+}
+
+// VIPConfig : configuration of a single virtual IP
+// (derived from types.VirtualIPConfig with candidates resolved
+// to their current IP/MAC addresses by zedrouter).
+type VIPConfig struct {
+	// IP : the virtual IP address.
+	IP net.IP
+	// Candidates : candidate applications in the decreasing order
+	// of priority. The first healthy one becomes the owner.
+	Candidates []VIPCandidate
+	// ProbePort : TCP port health-checked on the real IP address of every
+	// candidate.
+	ProbePort uint16
+	// ProbeInterval : interval between health probes.
+	// Zero means Config.ProbeInterval.
+	ProbeInterval time.Duration
+	// ProbeFailThreshold : number of continuous probe failures after which
+	// a candidate is declared unhealthy. A single successful probe is
+	// enough to declare it healthy again.
+	// Zero means Config.ProbeFailThreshold.
+	ProbeFailThreshold uint8
+}
+
+// NIConfig : virtual IPs of a single network instance.
+type NIConfig struct {
+	// NI : network instance UUID.
+	NI uuid.UUID
+	// BridgeIfName : interface name of the NI bridge on which the ARP
+	// takeover is performed.
+	BridgeIfName string
+	// VIPs : all virtual IPs of the network instance.
+	VIPs []VIPConfig
+}
+
+// VIPStatus : current state of one tracked virtual IP.
+type VIPStatus struct {
+	// IP : the virtual IP address.
+	IP net.IP
+	// Owner : application instance currently owning the virtual IP.
+	// Nil-UUID when no candidate is healthy.
+	Owner uuid.UUID
+	// FailoverCount : how many times the ownership changed since the
+	// virtual IP started being tracked.
+	FailoverCount uint32
+	// LastFailover : time of the last ownership change.
+	LastFailover time.Time
+}
+
+// FailoverEvent : reported to watchers whenever the ownership of a virtual
+// IP changes.
+type FailoverEvent struct {
+	// NI : network instance to which the virtual IP belongs.
+	NI uuid.UUID
+	// VIP : the virtual IP address.
+	VIP net.IP
+	// PrevOwner : application instance which owned the virtual IP before
+	// the failover. Nil-UUID when no candidate was healthy.
+	PrevOwner uuid.UUID
+	// NewOwner : application instance owning the virtual IP after
+	// the failover. Nil-UUID when no candidate is healthy.
+	NewOwner uuid.UUID
+}
+
+type niVIPs struct {
+	config NIConfig
+	vips   []*vipState
+}
+
+type vipState struct {
+	config        VIPConfig
+	candidates    []*candidateState
+	owner         uuid.UUID
+	failoverCount uint32
+	lastFailover  time.Time
+	lastProbe     time.Time
+}
+
+type candidateState struct {
+	VIPCandidate
+	// Optimistically assume that a newly tracked candidate is healthy
+	// until probes prove otherwise - with all candidates starting
+	// unhealthy, the service behind the virtual IP would be unavailable
+	// for the first ProbeFailThreshold probing rounds.
+	healthy     bool
+	contFailCnt uint8
+}
+
+// NewVIPManager is a constructor for VIPManager.
+// The manager starts probing as soon as it is given some virtual IPs
+// to track (see TrackNI).
+func NewVIPManager(log *base.LogObject, config Config) *VIPManager {
+	m := &VIPManager{
+		log:        log,
+		config:     config,
+		nis:        make(map[uuid.UUID]*niVIPs),
+		tickTicker: time.NewTicker(schedulingTickInterval),
+	}
+	go m.runProbing()
+	return m
+}
+
+// WatchFailoverUpdates returns channel where the manager publishes
+// ownership changes of the tracked virtual IPs.
+func (m *VIPManager) WatchFailoverUpdates() <-chan []FailoverEvent {
+	m.Lock()
+	defer m.Unlock()
+	watcherCh := make(chan []FailoverEvent, 1)
+	m.watcherChs = append(m.watcherChs, watcherCh)
+	return watcherCh
+}
+
+// TrackNI : add or update the set of virtual IPs tracked for the given
+// network instance. The health-check state of candidates which are present
+// in both the old and the new config is preserved. Ownership is re-evaluated
+// immediately, so a config update (e.g. a candidate VIF obtaining its IP
+// address) may trigger a failover without waiting for the next probe.
+func (m *VIPManager) TrackNI(niConfig NIConfig) {
+	m.Lock()
+	var events []FailoverEvent
+	ni, haveIt := m.nis[niConfig.NI]
+	if !haveIt {
+		ni = &niVIPs{}
+		m.nis[niConfig.NI] = ni
+	}
+	prevVIPs := ni.vips
+	ni.config = niConfig
+	ni.vips = make([]*vipState, 0, len(niConfig.VIPs))
+	for _, vipConfig := range niConfig.VIPs {
+		vip := &vipState{config: vipConfig}
+		var prevVIP *vipState
+		for _, prev := range prevVIPs {
+			if prev.config.IP.Equal(vipConfig.IP) {
+				prevVIP = prev
+				break
+			}
+		}
+		if prevVIP != nil {
+			vip.owner = prevVIP.owner
+			vip.failoverCount = prevVIP.failoverCount
+			vip.lastFailover = prevVIP.lastFailover
+			vip.lastProbe = prevVIP.lastProbe
+		}
+		for _, candConfig := range vipConfig.Candidates {
+			candidate := &candidateState{
+				VIPCandidate: candConfig,
+				healthy:      true,
+			}
+			if prevVIP != nil {
+				for _, prev := range prevVIP.candidates {
+					if prev.App == candConfig.App {
+						candidate.healthy = prev.healthy
+						candidate.contFailCnt = prev.contFailCnt
+						break
+					}
+				}
+			}
+			vip.candidates = append(vip.candidates, candidate)
+		}
+		events = append(events, m.reselectOwner(ni, vip)...)
+	}
+	// Withdraw virtual IPs removed from the config.
+	for _, prevVIP := range prevVIPs {
+		removed := true
+		for _, vip := range ni.vips {
+			if vip.config.IP.Equal(prevVIP.config.IP) {
+				removed = false
+				break
+			}
+		}
+		if removed && !isNilUUID(prevVIP.owner) {
+			m.withdrawVIP(niConfig.BridgeIfName, prevVIP.config.IP)
+		}
+	}
+	m.Unlock()
+	m.notifyWatchers(events)
+}
+
+// ForgetNI : stop tracking virtual IPs of the given network instance
+// and withdraw the corresponding neighbor entries from the NI bridge.
+// Should be called before the network instance is inactivated.
+func (m *VIPManager) ForgetNI(niID uuid.UUID) {
+	m.Lock()
+	defer m.Unlock()
+	ni, haveIt := m.nis[niID]
+	if !haveIt {
+		return
+	}
+	for _, vip := range ni.vips {
+		if !isNilUUID(vip.owner) {
+			m.withdrawVIP(ni.config.BridgeIfName, vip.config.IP)
+		}
+	}
+	delete(m.nis, niID)
+}
+
+// GetVIPStatus returns the current state of all virtual IPs tracked
+// for the given network instance.
+func (m *VIPManager) GetVIPStatus(niID uuid.UUID) []VIPStatus {
+	m.Lock()
+	defer m.Unlock()
+	ni, haveIt := m.nis[niID]
+	if !haveIt {
+		return nil
+	}
+	statuses := make([]VIPStatus, 0, len(ni.vips))
+	for _, vip := range ni.vips {
+		statuses = append(statuses, VIPStatus{
+			IP:            vip.config.IP,
+			Owner:         vip.owner,
+			FailoverCount: vip.failoverCount,
+			LastFailover:  vip.lastFailover,
+		})
+	}
+	return statuses
+}
+
+func (m *VIPManager) runProbing() {
+	for range m.tickTicker.C {
+		events := m.probeDueVIPs()
+		m.notifyWatchers(events)
+	}
+}
+
+// probeDueVIPs probes candidates of all virtual IPs which are due for the
+// next probing round and re-evaluates ownership.
+func (m *VIPManager) probeDueVIPs() (events []FailoverEvent) {
+	type dueVIP struct {
+		ni  *niVIPs
+		vip *vipState
+	}
+	now := time.Now()
+	m.Lock()
+	var dueVIPs []dueVIP
+	for _, ni := range m.nis {
+		for _, vip := range ni.vips {
+			interval := vip.config.ProbeInterval
+			if interval == 0 {
+				interval = m.config.ProbeInterval
+			}
+			if now.Sub(vip.lastProbe) >= interval {
+				vip.lastProbe = now
+				dueVIPs = append(dueVIPs, dueVIP{ni: ni, vip: vip})
+			}
+		}
+	}
+	m.Unlock()
+	for _, due := range dueVIPs {
+		for _, candidate := range due.vip.candidates {
+			if candidate.IP == nil {
+				// Not eligible until the VIF IP address is known.
+				continue
+			}
+			err := m.probe(candidate.IP, due.vip.config.ProbePort)
+			m.Lock()
+			failThreshold := due.vip.config.ProbeFailThreshold
+			if failThreshold == 0 {
+				failThreshold = m.config.ProbeFailThreshold
+			}
+			if err == nil {
+				candidate.contFailCnt = 0
+				if !candidate.healthy {
+					m.log.Noticef("VIPManager: candidate %v of virtual IP %v "+
+						"is healthy again", candidate.App, due.vip.config.IP)
+					candidate.healthy = true
+				}
+			} else {
+				if candidate.contFailCnt < failThreshold {
+					candidate.contFailCnt++
+				}
+				if candidate.healthy && candidate.contFailCnt >= failThreshold {
+					m.log.Warnf("VIPManager: candidate %v of virtual IP %v "+
+						"is unhealthy: %v", candidate.App, due.vip.config.IP, err)
+					candidate.healthy = false
+				}
+			}
+			m.Unlock()
+		}
+		m.Lock()
+		events = append(events, m.reselectOwner(due.ni, due.vip)...)
+		m.Unlock()
+	}
+	return events
+}
+
+// probe runs a single TCP health probe against the given candidate address.
+func (m *VIPManager) probe(ip net.IP, port uint16) error {
+	addr := net.JoinHostPort(ip.String(), strconv.Itoa(int(port)))
+	conn, err := net.DialTimeout("tcp", addr, m.config.ProbeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// reselectOwner picks the highest-priority healthy candidate as the owner
+// of the virtual IP and performs the ARP takeover if the ownership changed.
+// The caller must hold the lock.
+func (m *VIPManager) reselectOwner(ni *niVIPs, vip *vipState) (
+	events []FailoverEvent) {
+	var newOwner *candidateState
+	for _, candidate := range vip.candidates {
+		if candidate.IP == nil || len(candidate.MAC) == 0 {
+			continue
+		}
+		if candidate.healthy {
+			newOwner = candidate
+			break
+		}
+	}
+	var newOwnerApp uuid.UUID
+	if newOwner != nil {
+		newOwnerApp = newOwner.App
+	}
+	if newOwner != nil {
+		// Re-apply the takeover even for an unchanged owner - the neighbor
+		// entry is lost when the bridge is re-created and the candidate
+		// IP/MAC addresses may have changed.
+		err := m.takeoverVIP(ni.config.BridgeIfName, vip.config.IP, newOwner.MAC)
+		if err != nil {
+			m.log.Errorf("VIPManager: ARP takeover of virtual IP %v by %v "+
+				"failed: %v", vip.config.IP, newOwner.App, err)
+		}
+	} else if !isNilUUID(vip.owner) {
+		m.withdrawVIP(ni.config.BridgeIfName, vip.config.IP)
+	}
+	if newOwnerApp == vip.owner {
+		return nil
+	}
+	m.log.Noticef("VIPManager: virtual IP %v of NI %v failed over "+
+		"from app %v to app %v", vip.config.IP, ni.config.NI,
+		vip.owner, newOwnerApp)
+	events = append(events, FailoverEvent{
+		NI:        ni.config.NI,
+		VIP:       vip.config.IP,
+		PrevOwner: vip.owner,
+		NewOwner:  newOwnerApp,
+	})
+	vip.owner = newOwnerApp
+	vip.failoverCount++
+	vip.lastFailover = time.Now()
+	return events
+}
+
+// takeoverVIP installs a static neighbor entry for the virtual IP pointing
+// to the new owner and broadcasts gratuitous ARP on the NI bridge.
+func (m *VIPManager) takeoverVIP(brIfName string, vip net.IP,
+	ownerMAC net.HardwareAddr) error {
+	link, err := netlink.LinkByName(brIfName)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge %s: %w", brIfName, err)
+	}
+	err = netlink.NeighSet(&netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       netlink.FAMILY_V4,
+		State:        netlink.NUD_PERMANENT,
+		IP:           vip,
+		HardwareAddr: ownerMAC,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set neighbor entry %v->%v on %s: %w",
+			vip, ownerMAC, brIfName, err)
+	}
+	return m.sendGratuitousARP(link.Attrs().Index, vip, ownerMAC)
+}
+
+// withdrawVIP removes the static neighbor entry of the virtual IP.
+func (m *VIPManager) withdrawVIP(brIfName string, vip net.IP) {
+	link, err := netlink.LinkByName(brIfName)
+	if err != nil {
+		// Bridge is already gone together with its neighbor entries.
+		return
+	}
+	err = netlink.NeighDel(&netlink.Neigh{
+		LinkIndex: link.Attrs().Index,
+		Family:    netlink.FAMILY_V4,
+		IP:        vip,
+	})
+	if err != nil {
+		m.log.Warnf("VIPManager: failed to remove neighbor entry of virtual "+
+			"IP %v from %s: %v", vip, brIfName, err)
+	}
+}
+
+// sendGratuitousARP broadcasts gratuitous ARP announcing the new owner
+// of the virtual IP, so that applications connected to the bridge update
+// their ARP caches without waiting for the old entries to expire.
+func (m *VIPManager) sendGratuitousARP(ifIndex int, vip net.IP,
+	ownerMAC net.HardwareAddr) error {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW,
+		int(htons(unix.ETH_P_ARP)))
+	if err != nil {
+		return fmt.Errorf("failed to create AF_PACKET socket: %w", err)
+	}
+	defer unix.Close(fd)
+	bcastMAC := net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	frame := buildGratuitousARP(vip, ownerMAC, bcastMAC)
+	sockAddr := &unix.SockaddrLinklayer{
+		Protocol: htons(unix.ETH_P_ARP),
+		Ifindex:  ifIndex,
+		Halen:    uint8(len(bcastMAC)),
+	}
+	copy(sockAddr.Addr[:], bcastMAC)
+	for i := 0; i < m.config.GratuitousARPCount; i++ {
+		if err := unix.Sendto(fd, frame, 0, sockAddr); err != nil {
+			return fmt.Errorf("failed to send gratuitous ARP: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildGratuitousARP returns Ethernet frame with a gratuitous ARP reply,
+// announcing that the virtual IP is owned by the given MAC address.
+func buildGratuitousARP(vip net.IP, ownerMAC, bcastMAC net.HardwareAddr) []byte {
+	var frame []byte
+	// Ethernet header.
+	frame = append(frame, bcastMAC...)
+	frame = append(frame, ownerMAC...)
+	frame = append(frame, 0x08, 0x06) // EtherType = ARP
+	// ARP payload.
+	frame = append(frame, 0x00, 0x01) // hardware type = Ethernet
+	frame = append(frame, 0x08, 0x00) // protocol type = IPv4
+	frame = append(frame, 0x06, 0x04) // hardware/protocol address length
+	frame = append(frame, 0x00, 0x02) // opcode = reply
+	frame = append(frame, ownerMAC...)
+	frame = append(frame, vip.To4()...)
+	frame = append(frame, bcastMAC...)
+	frame = append(frame, vip.To4()...)
+	return frame
+}
+
+func htons(i uint16) uint16 {
+	return (i<<8)&0xff00 | i>>8
+}
+
+func isNilUUID(id uuid.UUID) bool {
+	return id == uuid.UUID{}
+}
+
+func (m *VIPManager) notifyWatchers(events []FailoverEvent) {
+	if len(events) == 0 {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	for _, watcherCh := range m.watcherChs {
+		select {
+		case watcherCh <- events:
+		default:
+			// Watcher is lagging behind, drop the update - zedrouter
+			// re-reads the full VIP status on every event anyway.
+		}
+	}
+}
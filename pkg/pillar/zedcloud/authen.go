@@ -22,7 +22,6 @@ import (
 	"mime"
 	"net/http"
 	"os"
-	"strings"
 
 	zauth "github.com/lf-edge/eve-api/go/auth"
 	zcert "github.com/lf-edge/eve-api/go/certs"
@@ -47,44 +46,11 @@ const (
 //   - SendRetval.Status is potentially updated to reflect the result of auth verification
 //
 // If skipVerify we remove the envelope but do not verify the signature.
+// The actual envelope handling is delegated to the codec matching the used
+// controller API version (see EnvelopeCodec).
 func RemoveAndVerifyAuthContainer(ctx *ZedCloudContext,
 	sendRV *SendRetval, skipVerify bool) error {
-	var reqURL string
-	if strings.HasPrefix(sendRV.ReqURL, "http:") {
-		reqURL = sendRV.ReqURL
-	} else {
-		if strings.HasPrefix(sendRV.ReqURL, "https:") {
-			reqURL = sendRV.ReqURL
-		} else {
-			reqURL = "https://" + sendRV.ReqURL
-		}
-	}
-	if !ctx.V2API {
-		return nil
-	}
-	contents, status, err := removeAndVerifyAuthContainer(ctx,
-		sendRV.RespContents, skipVerify)
-	if status != types.SenderStatusNone {
-		sendRV.Status = status
-	}
-	if err != nil {
-		var envelopeErr bool
-		if sendRV.Status == types.SenderStatusHashSizeError ||
-			sendRV.Status == types.SenderStatusAlgoFail {
-			// server may not support V2 envelope
-			envelopeErr = true
-		}
-		ctx.log.Errorf("RemoveAndVerifyAuthContainer verify auth error %v, "+
-			"V2 server %v, content len %d, url %s, senderStatus %v",
-			err, !envelopeErr, len(contents), reqURL, sendRV.Status)
-		if ctx.FailureFunc != nil {
-			ctx.FailureFunc(ctx.log, "", reqURL, 0, 0, true)
-		}
-		return err
-	}
-	sendRV.RespContents = contents
-	ctx.log.Tracef("RemoveAndVerifyAuthContainer verify auth ok, url %s", reqURL)
-	return nil
+	return ctx.envelopeCodec().UnwrapResponse(sendRV, skipVerify)
 }
 
 // given an envelope protobuf received from controller, verify the authentication
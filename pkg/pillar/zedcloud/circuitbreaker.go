@@ -0,0 +1,159 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedcloud
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Circuit breaker for controller endpoints.
+// When a controller (or LOC) endpoint repeatedly fails to respond over
+// a given port, every agent keeps paying the full network timeout on each
+// publish attempt, extending its whole publish cycle. To avoid that,
+// a circuit breaker is maintained per endpoint+interface pair: after
+// a number of consecutive transport failures the breaker trips open and
+// further requests are fast-failed with CircuitOpenError. While open,
+// a single recovery probe request is let through every breakerProbeInterval
+// and the first success closes the breaker again. Connectivity verification
+// requests (see VerifyAllIntf) are never fast-failed - they themselves act
+// as recovery probes. Breaker trips and fast-fails are counted in the agent
+// metrics (see types.ZedcloudMetric) so that agents can adapt their schedules.
+
+const (
+	// breakerTripThreshold : number of consecutive transport failures
+	// (i.e. all send attempts failed) at which the breaker trips open.
+	breakerTripThreshold = 3
+	// breakerProbeInterval : while the breaker is open, how often a single
+	// recovery probe request is let through.
+	breakerProbeInterval = 30 * time.Second
+)
+
+// CircuitOpenError is returned by the Send* functions when a request was
+// fast-failed because the circuit breaker of the target endpoint is open.
+type CircuitOpenError struct {
+	// Endpoint : host[:port] of the controller endpoint.
+	Endpoint string
+	// IfName : interface over which the request was attempted.
+	IfName string
+}
+
+// Error message.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for endpoint %s on interface %s",
+		e.Endpoint, e.IfName)
+}
+
+type breakerKey struct {
+	endpoint string // host[:port] of the controller endpoint
+	ifName   string
+}
+
+type endpointBreaker struct {
+	open           bool
+	consecFailures int
+	// While open: when a recovery probe was last let through.
+	lastProbe time.Time
+}
+
+// circuitBreakers tracks the circuit breakers of all controller endpoints
+// accessed by the agent, one breaker per endpoint+interface pair.
+// Able to properly handle concurrent access.
+type circuitBreakers struct {
+	sync.Mutex
+	breakers map[breakerKey]*endpointBreaker
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{
+		breakers: make(map[breakerKey]*endpointBreaker),
+	}
+}
+
+// endpointFromURL extracts host[:port] of the endpoint from the request URL.
+func endpointFromURL(reqURL string) string {
+	if parsed, err := url.Parse(reqURL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return reqURL
+}
+
+// allowRequest returns CircuitOpenError if requests towards the given
+// endpoint over the given interface are currently being fast-failed.
+// While the breaker is open, a single recovery probe is let through
+// every breakerProbeInterval.
+func (cb *circuitBreakers) allowRequest(reqURL, ifName string) error {
+	if cb == nil {
+		return nil
+	}
+	cb.Lock()
+	defer cb.Unlock()
+	key := breakerKey{endpoint: endpointFromURL(reqURL), ifName: ifName}
+	breaker := cb.breakers[key]
+	if breaker == nil || !breaker.open {
+		return nil
+	}
+	if time.Since(breaker.lastProbe) >= breakerProbeInterval {
+		// Let this request through as a recovery probe.
+		breaker.lastProbe = time.Now()
+		return nil
+	}
+	return &CircuitOpenError{Endpoint: key.endpoint, IfName: key.ifName}
+}
+
+// recordSuccess closes the breaker of the given endpoint+interface pair.
+func (cb *circuitBreakers) recordSuccess(reqURL, ifName string) {
+	if cb == nil {
+		return
+	}
+	cb.Lock()
+	defer cb.Unlock()
+	key := breakerKey{endpoint: endpointFromURL(reqURL), ifName: ifName}
+	if breaker := cb.breakers[key]; breaker != nil {
+		breaker.open = false
+		breaker.consecFailures = 0
+	}
+}
+
+// recordFailure records a transport failure (all send attempts failed)
+// towards the given endpoint+interface pair. Returns true if this failure
+// tripped the breaker open.
+func (cb *circuitBreakers) recordFailure(reqURL, ifName string) (tripped bool) {
+	if cb == nil {
+		return false
+	}
+	cb.Lock()
+	defer cb.Unlock()
+	key := breakerKey{endpoint: endpointFromURL(reqURL), ifName: ifName}
+	breaker := cb.breakers[key]
+	if breaker == nil {
+		breaker = &endpointBreaker{}
+		cb.breakers[key] = breaker
+	}
+	breaker.consecFailures++
+	if !breaker.open && breaker.consecFailures >= breakerTripThreshold {
+		breaker.open = true
+		breaker.lastProbe = time.Now()
+		return true
+	}
+	return false
+}
+
+type breakerBypassKey struct{}
+
+// bypassCircuitBreaker marks the given context so that requests sent with
+// it are never fast-failed by an open circuit breaker (their outcome still
+// updates the breaker state). Used for connectivity verification requests,
+// which themselves act as recovery probes.
+func bypassCircuitBreaker(ctx context.Context) context.Context {
+	return context.WithValue(ctx, breakerBypassKey{}, true)
+}
+
+func breakerBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(breakerBypassKey{}).(bool)
+	return bypass
+}
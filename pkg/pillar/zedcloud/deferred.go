@@ -7,6 +7,11 @@ package zedcloud
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -46,6 +51,8 @@ type deferredItem struct {
 	bailOnHTTPErr  bool // Return 4xx and 5xx without trying other interfaces
 	withNetTracing bool
 	ignoreErr      bool
+	created        time.Time
+	persistFile    string // non-empty if the item is stored on disk
 }
 
 // We create a timer with really a huge duration to avoid any problems
@@ -53,6 +60,15 @@ type deferredItem struct {
 const longTime1 = time.Hour * 24
 const longTime2 = time.Hour * 48
 
+// The queue is bounded - items of the same type (itemType) exceeding the quota
+// are dropped, oldest first. Items are also dropped once they exceed the max
+// age, so that long-disconnected device does not replay hopelessly outdated
+// publications when the connectivity finally returns.
+const (
+	deferredMaxItemsPerType = 100
+	deferredItemMaxAge      = 24 * time.Hour
+)
+
 // DeferredContext is part of ZedcloudContext
 type DeferredContext struct {
 	deferredItems          []*deferredItem
@@ -62,6 +78,10 @@ type DeferredContext struct {
 	sentHandler            *SentHandlerFunction
 	zedcloudCtx            *ZedCloudContext
 	iteration              int
+	persistDir             string // empty if persistence is not enabled
+	persistSeq             uint64
+	trafficScheduler       *TrafficScheduler // nil if not attached
+	classifyItem           func(itemType interface{}) TrafficClass
 }
 
 // TypePriorityCheckFunction returns true in case of find type with high priority
@@ -132,6 +152,28 @@ func (ctx *DeferredContext) processQueueTask(ps *pubsub.PubSub,
 	}
 }
 
+// SetTrafficScheduler attaches a scheduler used to order deferred items
+// by traffic-class priority and to enforce per-class rate budgets.
+// The classify callback maps the type of a deferred item to its traffic
+// class; items of types it does not recognize should be classified as
+// TrafficClassUnspecified. Items postponed by a budget stay in the queue
+// and are retried by the timer.
+func (ctx *DeferredContext) SetTrafficScheduler(scheduler *TrafficScheduler,
+	classify func(itemType interface{}) TrafficClass) {
+	ctx.deferredItemsLock.Lock()
+	defer ctx.deferredItemsLock.Unlock()
+	ctx.trafficScheduler = scheduler
+	ctx.classifyItem = classify
+}
+
+// classify returns the traffic class of the given deferred item type.
+func (ctx *DeferredContext) classify(itemType interface{}) TrafficClass {
+	if ctx.classifyItem == nil {
+		return TrafficClassUnspecified
+	}
+	return ctx.classifyItem(itemType)
+}
+
 // mergeQueuesNoLock merges requests which were not sent (argument)
 // with incoming requests, accumulated in the `ctx.deferredItems`.
 // Context: `ctx.deferredItemsLock` held.
@@ -159,9 +201,43 @@ func (ctx *DeferredContext) mergeQueuesNoLock(notSentReqs []*deferredItem) {
 	ctx.deferredItems = append(notSentReqs, ctx.deferredItems...)
 }
 
+// applyLimitsNoLock drops items which exceeded the max age and enforces
+// the per-type quota, dropping the oldest items of the offending type first.
+// Context: `ctx.deferredItemsLock` held.
+func (ctx *DeferredContext) applyLimitsNoLock(now time.Time) {
+	log := ctx.zedcloudCtx.log
+	countByType := make(map[string]int)
+	var kept []*deferredItem
+	// Iterate from the newest to the oldest.
+	for i := len(ctx.deferredItems) - 1; i >= 0; i-- {
+		item := ctx.deferredItems[i]
+		if now.Sub(item.created) > deferredItemMaxAge {
+			log.Warnf("Dropping deferred item %s: older than %v",
+				item.key, deferredItemMaxAge)
+			ctx.unpersistItem(item)
+			continue
+		}
+		typeName := fmt.Sprintf("%T", item.itemType)
+		if countByType[typeName] >= deferredMaxItemsPerType {
+			log.Warnf("Dropping deferred item %s: exceeded quota of %d items "+
+				"for type %s", item.key, deferredMaxItemsPerType, typeName)
+			ctx.unpersistItem(item)
+			continue
+		}
+		countByType[typeName]++
+		kept = append(kept, item)
+	}
+	// Put the kept items back into the original (oldest first) order.
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	ctx.deferredItems = kept
+}
+
 // handleDeferred try to send all deferred items
 func (ctx *DeferredContext) handleDeferred() bool {
 	ctx.deferredItemsLock.Lock()
+	ctx.applyLimitsNoLock(time.Now())
 	reqs := ctx.deferredItems
 	ctx.deferredItems = []*deferredItem{}
 	ctx.deferredItemsLock.Unlock()
@@ -174,6 +250,15 @@ func (ctx *DeferredContext) handleDeferred() bool {
 
 	log.Functionf("handleDeferred items %d", len(reqs))
 
+	if ctx.trafficScheduler != nil {
+		// Order by traffic-class priority so that bulk uploads never starve
+		// the critical config/info exchange.
+		sort.SliceStable(reqs, func(i, j int) bool {
+			return ctx.classify(reqs[i].itemType).Priority() <
+				ctx.classify(reqs[j].itemType).Priority()
+		})
+	}
+
 	exit := false
 	sent := 0
 	ctxWork, cancel := GetContextForAllIntfFunctions(ctx.zedcloudCtx)
@@ -194,6 +279,16 @@ func (ctx *DeferredContext) handleDeferred() bool {
 					key)
 				continue
 			}
+			if ctx.trafficScheduler != nil {
+				class := ctx.classify(item.itemType)
+				if !ctx.trafficScheduler.Admit(class, item.size) {
+					// Keep the item queued, the timer will retry it once
+					// the class budget allows.
+					log.Functionf("handleDeferred: %s postponed by the %v "+
+						"traffic budget", key, class)
+					continue
+				}
+			}
 
 			//SenderStatusNone indicates no problems
 			rv, err := SendOnAllIntf(ctxWork, ctx.zedcloudCtx, item.url,
@@ -230,6 +325,7 @@ func (ctx *DeferredContext) handleDeferred() bool {
 				break
 			}
 			item.buf = nil
+			ctx.unpersistItem(item)
 			sent++
 		}
 		if exit {
@@ -302,6 +398,7 @@ func (ctx *DeferredContext) SetDeferred(
 		bailOnHTTPErr:  bailOnHTTPErr,
 		withNetTracing: withNetTracing,
 		ignoreErr:      ignoreErr,
+		created:        time.Now(),
 	}
 	found := false
 	ind := 0
@@ -314,11 +411,14 @@ func (ctx *DeferredContext) SetDeferred(
 	}
 	if found {
 		log.Tracef("Replacing key %s", key)
+		ctx.unpersistItem(ctx.deferredItems[ind])
 		ctx.deferredItems[ind] = &item
 	} else {
 		log.Tracef("Adding key %s", key)
 		ctx.deferredItems = append(ctx.deferredItems, &item)
 	}
+	ctx.persistItemNoLock(&item)
+	ctx.applyLimitsNoLock(time.Now())
 
 	// Run to a completion from the processing task
 	ctx.KickTimer()
@@ -336,6 +436,7 @@ func (ctx *DeferredContext) RemoveDeferred(key string) {
 	for ind, itemList := range ctx.deferredItems {
 		if itemList.key == key {
 			log.Tracef("Deleting key %s", key)
+			ctx.unpersistItem(itemList)
 			ctx.deferredItems = append(ctx.deferredItems[:ind], ctx.deferredItems[ind+1:]...)
 			break
 		}
@@ -364,3 +465,143 @@ func stopTimer(log *base.LogObject, ctx *DeferredContext) {
 	log.Functionf("stopTimer()")
 	ctx.Ticker.UpdateRangeTicker(longTime1, longTime2)
 }
+
+// persistedDeferredItem : on-disk representation of a deferred item.
+type persistedDeferredItem struct {
+	Key            string    `json:"key"`
+	TypeName       string    `json:"typeName"`
+	URL            string    `json:"url"`
+	Payload        []byte    `json:"payload"`
+	Size           int64     `json:"size"`
+	BailOnHTTPErr  bool      `json:"bailOnHTTPErr"`
+	WithNetTracing bool      `json:"withNetTracing"`
+	IgnoreErr      bool      `json:"ignoreErr"`
+	Created        time.Time `json:"created"`
+}
+
+// EnablePersistence makes the deferred queue survive agent restarts.
+// Queued items are stored inside the given directory (one file per item)
+// and removed once they are successfully sent, dropped by the aging/quota
+// limits or removed with RemoveDeferred. Items persisted by a previous
+// incarnation of the agent are loaded from the directory and prepended
+// to the queue, preserving their original order.
+// Note that the original Go type of a reloaded item is not recoverable -
+// priority check functions and the sent handler will receive the type name
+// (as produced by %T) instead.
+func (ctx *DeferredContext) EnablePersistence(dir string) error {
+	ctx.deferredItemsLock.Lock()
+	defer ctx.deferredItemsLock.Unlock()
+
+	log := ctx.zedcloudCtx.log
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	ctx.persistDir = dir
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+	var fileNames []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		fileNames = append(fileNames, entry.Name())
+	}
+	// File names are sequentially numbered, hence the lexicographic order
+	// matches the order in which the items were queued.
+	sort.Strings(fileNames)
+	var loaded []*deferredItem
+	for _, fileName := range fileNames {
+		filePath := filepath.Join(dir, fileName)
+		var seq uint64
+		if _, err := fmt.Sscanf(fileName, "%d.json", &seq); err == nil &&
+			seq >= ctx.persistSeq {
+			ctx.persistSeq = seq + 1
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			log.Errorf("EnablePersistence: failed to read %s: %v", filePath, err)
+			continue
+		}
+		var persisted persistedDeferredItem
+		if err := json.Unmarshal(data, &persisted); err != nil {
+			log.Errorf("EnablePersistence: failed to parse %s: %v", filePath, err)
+			if err := os.Remove(filePath); err != nil {
+				log.Errorf("EnablePersistence: failed to remove %s: %v",
+					filePath, err)
+			}
+			continue
+		}
+		loaded = append(loaded, &deferredItem{
+			itemType:       persisted.TypeName,
+			key:            persisted.Key,
+			buf:            bytes.NewBuffer(persisted.Payload),
+			size:           persisted.Size,
+			url:            persisted.URL,
+			bailOnHTTPErr:  persisted.BailOnHTTPErr,
+			withNetTracing: persisted.WithNetTracing,
+			ignoreErr:      persisted.IgnoreErr,
+			created:        persisted.Created,
+			persistFile:    filePath,
+		})
+	}
+	if len(loaded) == 0 {
+		return nil
+	}
+	log.Noticef("EnablePersistence: re-queued %d deferred items from %s",
+		len(loaded), dir)
+	// Loaded items were queued before anything accumulated in this incarnation.
+	if len(ctx.deferredItems) == 0 {
+		startTimer(log, ctx)
+	}
+	ctx.deferredItems = append(loaded, ctx.deferredItems...)
+	ctx.applyLimitsNoLock(time.Now())
+	ctx.KickTimer()
+	return nil
+}
+
+// persistItemNoLock stores the item on disk if persistence is enabled.
+// Context: `ctx.deferredItemsLock` held.
+func (ctx *DeferredContext) persistItemNoLock(item *deferredItem) {
+	if ctx.persistDir == "" {
+		return
+	}
+	log := ctx.zedcloudCtx.log
+	persisted := persistedDeferredItem{
+		Key:            item.key,
+		TypeName:       fmt.Sprintf("%T", item.itemType),
+		URL:            item.url,
+		Payload:        item.buf.Bytes(),
+		Size:           item.size,
+		BailOnHTTPErr:  item.bailOnHTTPErr,
+		WithNetTracing: item.withNetTracing,
+		IgnoreErr:      item.ignoreErr,
+		Created:        item.created,
+	}
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		log.Errorf("persistItem: failed to marshal item %s: %v", item.key, err)
+		return
+	}
+	item.persistFile = filepath.Join(ctx.persistDir,
+		fmt.Sprintf("%020d.json", ctx.persistSeq))
+	ctx.persistSeq++
+	if err := os.WriteFile(item.persistFile, data, 0600); err != nil {
+		log.Errorf("persistItem: failed to write %s: %v", item.persistFile, err)
+		item.persistFile = ""
+	}
+}
+
+// unpersistItem removes the on-disk copy of the item, if there is any.
+func (ctx *DeferredContext) unpersistItem(item *deferredItem) {
+	if item.persistFile == "" {
+		return
+	}
+	log := ctx.zedcloudCtx.log
+	if err := os.Remove(item.persistFile); err != nil {
+		log.Errorf("unpersistItem: failed to remove %s: %v",
+			item.persistFile, err)
+	}
+	item.persistFile = ""
+}
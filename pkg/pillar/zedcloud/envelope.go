@@ -0,0 +1,150 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zedcloud
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lf-edge/eve/pkg/pillar/types"
+)
+
+// EnvelopeCodec wraps outgoing controller API requests into the envelope
+// (framing + signing scheme) of a particular API version and unwraps
+// (+ verifies) enveloped responses. Keeping the envelope handling behind
+// this interface allows to add new controller API versions with different
+// signing or framing schemes without touching every send call site.
+// The codec used by a ZedCloudContext is selected based on the configured
+// API version, unless overridden with SetEnvelopeCodec.
+type EnvelopeCodec interface {
+	// Version of the controller API envelope implemented by this codec.
+	Version() string
+	// WrapRequest wraps the given request payload into the envelope.
+	// intf is the interface through which the request will be sent
+	// (used for metrics).
+	WrapRequest(b *bytes.Buffer, useOnboard bool, intf string) (*bytes.Buffer, error)
+	// UnwrapResponse removes the envelope from the response payload
+	// and verifies the signature (unless skipVerify is set).
+	// Modifies SendRetval: RespContents is replaced with the unwrapped
+	// payload and Status potentially updated with the verification result.
+	UnwrapResponse(sendRV *SendRetval, skipVerify bool) error
+}
+
+// SetEnvelopeCodec overrides the envelope codec selected based on
+// the configured controller API version.
+func (ctx *ZedCloudContext) SetEnvelopeCodec(codec EnvelopeCodec) {
+	ctx.envCodec = codec
+}
+
+// envelopeCodec returns the codec to use for wrapping/unwrapping
+// of the controller API requests/responses.
+func (ctx *ZedCloudContext) envelopeCodec() EnvelopeCodec {
+	if ctx.envCodec != nil {
+		return ctx.envCodec
+	}
+	if ctx.V2API {
+		return &V2EnvelopeCodec{ctx: ctx}
+	}
+	return &V1EnvelopeCodec{ctx: ctx}
+}
+
+// V1EnvelopeCodec implements EnvelopeCodec for the v1 controller API,
+// where payloads are sent as-is, protected only by TLS.
+type V1EnvelopeCodec struct {
+	ctx *ZedCloudContext
+}
+
+// Version returns "v1".
+func (c *V1EnvelopeCodec) Version() string {
+	return "v1"
+}
+
+// WrapRequest returns the payload unchanged - the v1 API has no envelope.
+func (c *V1EnvelopeCodec) WrapRequest(b *bytes.Buffer, useOnboard bool,
+	intf string) (*bytes.Buffer, error) {
+	c.ctx.recordEnvelopeWrap(intf, c.Version())
+	return b, nil
+}
+
+// UnwrapResponse is a no-op - the v1 API has no envelope.
+func (c *V1EnvelopeCodec) UnwrapResponse(sendRV *SendRetval, skipVerify bool) error {
+	return nil
+}
+
+// V2EnvelopeCodec implements EnvelopeCodec for the v2 controller API,
+// where payloads are wrapped inside a signed AuthContainer.
+type V2EnvelopeCodec struct {
+	ctx *ZedCloudContext
+}
+
+// Version returns "v2".
+func (c *V2EnvelopeCodec) Version() string {
+	return "v2"
+}
+
+// WrapRequest wraps the payload inside an AuthContainer and signs it.
+func (c *V2EnvelopeCodec) WrapRequest(b *bytes.Buffer, useOnboard bool,
+	intf string) (*bytes.Buffer, error) {
+	b2, err := AddAuthentication(c.ctx, b, useOnboard)
+	if err != nil {
+		return nil, err
+	}
+	c.ctx.recordEnvelopeWrap(intf, c.Version())
+	return b2, nil
+}
+
+// UnwrapResponse removes the AuthContainer envelope and verifies
+// the signature of the protected payload (unless skipVerify is set).
+func (c *V2EnvelopeCodec) UnwrapResponse(sendRV *SendRetval, skipVerify bool) error {
+	ctx := c.ctx
+	var reqURL string
+	if strings.HasPrefix(sendRV.ReqURL, "http:") {
+		reqURL = sendRV.ReqURL
+	} else {
+		if strings.HasPrefix(sendRV.ReqURL, "https:") {
+			reqURL = sendRV.ReqURL
+		} else {
+			reqURL = "https://" + sendRV.ReqURL
+		}
+	}
+	contents, status, err := removeAndVerifyAuthContainer(ctx,
+		sendRV.RespContents, skipVerify)
+	if status != types.SenderStatusNone {
+		sendRV.Status = status
+	}
+	if err != nil {
+		var envelopeErr bool
+		if sendRV.Status == types.SenderStatusHashSizeError ||
+			sendRV.Status == types.SenderStatusAlgoFail {
+			// server may not support V2 envelope
+			envelopeErr = true
+		}
+		ctx.log.Errorf("RemoveAndVerifyAuthContainer verify auth error %v, "+
+			"V2 server %v, content len %d, url %s, senderStatus %v",
+			err, !envelopeErr, len(contents), reqURL, sendRV.Status)
+		if ctx.FailureFunc != nil {
+			ctx.FailureFunc(ctx.log, "", reqURL, 0, 0, true)
+		}
+		ctx.recordEnvelopeUnwrap(sendRV.UsedIfName, c.Version(), true)
+		return err
+	}
+	sendRV.RespContents = contents
+	ctx.recordEnvelopeUnwrap(sendRV.UsedIfName, c.Version(), false)
+	ctx.log.Tracef("RemoveAndVerifyAuthContainer verify auth ok, url %s", reqURL)
+	return nil
+}
+
+func (ctx *ZedCloudContext) recordEnvelopeWrap(intf, version string) {
+	if ctx.agentMetrics == nil {
+		return
+	}
+	ctx.agentMetrics.RecordEnvelopeWrap(ctx.log, intf, version)
+}
+
+func (ctx *ZedCloudContext) recordEnvelopeUnwrap(intf, version string, verifyFail bool) {
+	if ctx.agentMetrics == nil {
+		return
+	}
+	ctx.agentMetrics.RecordEnvelopeUnwrap(ctx.log, intf, version, verifyFail)
+}
@@ -71,6 +71,13 @@ type ZedCloudContext struct {
 	serverSigningCertHash []byte
 	onBoardCertBytes      []byte
 	log                   *base.LogObject
+	agentMetrics          *AgentMetrics
+	// Circuit breakers of the controller endpoints, one per
+	// endpoint+interface pair (see circuitbreaker.go).
+	circuitBreakers *circuitBreakers
+	// Overrides the envelope codec selected based on V2API
+	// (see SetEnvelopeCodec).
+	envCodec EnvelopeCodec
 	// All controller HTTP requests which can't be dropped and send
 	// should be repeated in case of a transmission error are added to
 	// this queue.
@@ -148,6 +155,7 @@ func (e *SendError) Unwrap() error {
 // (from SendOnAllIntf and SendOnIntf).
 type SendRetval struct {
 	ReqURL       string // Used by e.g. RemoveAndVerifyAuthContainer().
+	UsedIfName   string // Interface through which the request was sent.
 	Status       types.SenderStatus
 	HTTPResp     *http.Response
 	RespContents []byte
@@ -334,6 +342,9 @@ func VerifyAllIntf(ctx *ZedCloudContext, url string, requiredSuccessCount uint,
 	intfs := types.GetAllPortsSortedCost(*ctx.DeviceNetworkStatus, false, iteration)
 	ctxWork, cancel := GetContextForAllIntfFunctions(ctx)
 	defer cancel()
+	// Connectivity verification requests are never fast-failed by an open
+	// circuit breaker - they themselves act as recovery probes.
+	ctxWork = bypassCircuitBreaker(ctxWork)
 
 	// Always iterate through *all* uplink interfaces, never break out of the loop.
 	// However, some of the interfaces might be verified only using local checks
@@ -385,7 +396,11 @@ func VerifyAllIntf(ctx *ZedCloudContext, url string, requiredSuccessCount uint,
 		}
 		if rv.HTTPResp != nil &&
 			(rv.HTTPResp.StatusCode >= http.StatusInternalServerError &&
-				rv.HTTPResp.StatusCode <= http.StatusNetworkAuthenticationRequired) {
+				rv.HTTPResp.StatusCode < http.StatusNetworkAuthenticationRequired) {
+			// Note that code 511 (Network Authentication Required) is excluded
+			// here - it signals a captive portal standing between the device
+			// and the controller, i.e. a local network problem and not
+			// a temporary failure on the controller side.
 			verifyRV.RemoteTempFailure = true
 		}
 		if err != nil {
@@ -440,8 +455,19 @@ func VerifyAllIntf(ctx *ZedCloudContext, url string, requiredSuccessCount uint,
 			}
 			intfSuccessCount++
 		default:
-			err = fmt.Errorf("controller with URL %s returned status code %d (%s)",
-				url, rv.HTTPResp.StatusCode, http.StatusText(rv.HTTPResp.StatusCode))
+			if rv.HTTPResp.StatusCode >= http.StatusMultipleChoices &&
+				rv.HTTPResp.StatusCode < http.StatusBadRequest ||
+				rv.HTTPResp.StatusCode == http.StatusNetworkAuthenticationRequired {
+				// Redirect or code 511 instead of a controller response
+				// - most likely a captive portal intercepting the traffic.
+				err = &types.CaptivePortalError{
+					IfName:    intf,
+					PortalURL: rv.HTTPResp.Header.Get("Location"),
+				}
+			} else {
+				err = fmt.Errorf("controller with URL %s returned status code %d (%s)",
+					url, rv.HTTPResp.StatusCode, http.StatusText(rv.HTTPResp.StatusCode))
+			}
 			log.Errorf("Uplink test FAILED via %s: %v", intf, err)
 			attempts = append(attempts, SendAttempt{
 				Err:    err,
@@ -661,6 +687,7 @@ func SendOnIntf(workContext context.Context, ctx *ZedCloudContext, destURL strin
 		useTLS = true
 	}
 	rv.ReqURL = reqURL
+	rv.UsedIfName = intf
 
 	if strings.Contains(destURL, "/edgedevice/") {
 		isEdgenode = true
@@ -771,6 +798,18 @@ func SendOnIntf(workContext context.Context, ctx *ZedCloudContext, destURL strin
 		return rv, nil
 	}
 
+	// Fast-fail when the circuit breaker for this endpoint+interface pair
+	// is currently open (see circuitbreaker.go).
+	if !breakerBypassed(workContext) {
+		if err := ctx.circuitBreakers.allowRequest(reqURL, intf); err != nil {
+			log.Tracef("unable to connect to %s: %v", reqURL, err)
+			if ctx.agentMetrics != nil {
+				ctx.agentMetrics.RecordBreakerFastFail(log, intf, reqURL)
+			}
+			return rv, err
+		}
+	}
+
 	var attempts []SendAttempt
 	var sessionResume bool
 	transport := &http.Transport{
@@ -811,13 +850,15 @@ func SendOnIntf(workContext context.Context, ctx *ZedCloudContext, destURL strin
 		// Prepare the HTTP request.
 		var req *http.Request
 		var b2 *bytes.Buffer
-		if ctx.V2API && isEdgenode && !isGet {
-			b2, err = AddAuthentication(ctx, b, useOnboard)
+		if isEdgenode && !isGet {
+			b2, err = ctx.envelopeCodec().WrapRequest(b, useOnboard, intf)
 			if err != nil {
 				log.Errorf("SendOnIntf: auth error %v\n", err)
 				return rv, err
 			}
-			reqlen = int64(b2.Len())
+			if b2 != nil {
+				reqlen = int64(b2.Len())
+			}
 			log.Tracef("SendOnIntf: add auth for %s\n", reqURL)
 		} else {
 			b2 = b
@@ -1100,6 +1141,7 @@ func SendOnIntf(workContext context.Context, ctx *ZedCloudContext, destURL strin
 		if ctx.SuccessFunc != nil {
 			ctx.SuccessFunc(log, intf, reqURL, reqlen, resplen, totalTimeMillis, sessionResume)
 		}
+		ctx.circuitBreakers.recordSuccess(reqURL, intf)
 
 		switch resp.StatusCode {
 		case http.StatusOK, http.StatusCreated, http.StatusNotModified, http.StatusNoContent:
@@ -1137,6 +1179,13 @@ func SendOnIntf(workContext context.Context, ctx *ZedCloudContext, destURL strin
 	if ctx.FailureFunc != nil {
 		ctx.FailureFunc(log, intf, reqURL, 0, 0, false)
 	}
+	if tripped := ctx.circuitBreakers.recordFailure(reqURL, intf); tripped {
+		log.Warnf("Circuit breaker for endpoint %s on interface %s "+
+			"tripped open", endpointFromURL(reqURL), intf)
+		if ctx.agentMetrics != nil {
+			ctx.agentMetrics.RecordBreakerTrip(log, intf, reqURL)
+		}
+	}
 	errStr := fmt.Sprintf("All attempts to connect to %s failed: %s",
 		reqURL, describeSendAttempts(attempts))
 	log.Errorln(errStr)
@@ -1411,10 +1460,12 @@ func NewContext(log *base.LogObject, opt ContextOptions) ZedCloudContext {
 		AgentName:           opt.AgentName,
 		NetTraceOpts:        opt.NetTraceOpts,
 		log:                 log,
+		circuitBreakers:     newCircuitBreakers(),
 	}
 	if opt.AgentMetrics != nil {
 		ctx.FailureFunc = opt.AgentMetrics.RecordFailure
 		ctx.SuccessFunc = opt.AgentMetrics.RecordSuccess
+		ctx.agentMetrics = opt.AgentMetrics
 	}
 	return ctx
 }
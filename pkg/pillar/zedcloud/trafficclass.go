@@ -0,0 +1,168 @@
+// Copyright (c) 2026 Zededa, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Traffic classes and per-class rate budgets for requests sent to the
+// controller. On a saturated or metered uplink, bulk uploads (metrics, logs,
+// flow logs) could otherwise starve the critical config/info exchange.
+// Requests are classified by the sending agent, higher-priority classes are
+// scheduled first and classes with a configured budget are rate-limited
+// using a token bucket.
+
+package zedcloud
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lf-edge/eve/pkg/pillar/base"
+)
+
+// TrafficClass : class of traffic exchanged with the controller.
+type TrafficClass uint8
+
+const (
+	// TrafficClassUnspecified : class was not determined.
+	// Such requests are scheduled with the lowest priority,
+	// but are never rate-limited.
+	TrafficClassUnspecified TrafficClass = iota
+	// TrafficClassConfig : device configuration fetch and attestation.
+	TrafficClassConfig
+	// TrafficClassInfo : info messages published to the controller.
+	TrafficClassInfo
+	// TrafficClassMetrics : periodic metrics upload.
+	TrafficClassMetrics
+	// TrafficClassLogs : log upload.
+	TrafficClassLogs
+	// TrafficClassFlowlog : network flow log upload.
+	TrafficClassFlowlog
+)
+
+// String representation of the traffic class.
+func (tc TrafficClass) String() string {
+	switch tc {
+	case TrafficClassConfig:
+		return "config"
+	case TrafficClassInfo:
+		return "info"
+	case TrafficClassMetrics:
+		return "metrics"
+	case TrafficClassLogs:
+		return "logs"
+	case TrafficClassFlowlog:
+		return "flowlog"
+	}
+	return "unspecified"
+}
+
+// Priority returns the scheduling priority of the traffic class.
+// Lower value means higher priority. Config and info exchange is always
+// preferred over the bulk metric/log/flowlog uploads.
+func (tc TrafficClass) Priority() int {
+	switch tc {
+	case TrafficClassConfig:
+		return 0
+	case TrafficClassInfo:
+		return 1
+	case TrafficClassMetrics:
+		return 2
+	case TrafficClassLogs:
+		return 3
+	case TrafficClassFlowlog:
+		return 4
+	}
+	return 5
+}
+
+// ClassBudget limits the rate of data sent to the controller within
+// one traffic class.
+type ClassBudget struct {
+	// BytesPerSecond : sustained rate allowed for the class.
+	// Zero value means that the class is not rate-limited.
+	BytesPerSecond uint64
+	// BurstBytes : depth of the token bucket, i.e. how much data can be
+	// sent at once after a period of inactivity. Defaults to the amount
+	// accumulated over 10 seconds when left as zero.
+	BurstBytes uint64
+}
+
+// TrafficScheduler maintains a token bucket for every traffic class with
+// a configured budget and admits or postpones requests accordingly.
+// A single scheduler instance is expected to be shared by all senders
+// of the agent so that the budgets apply globally.
+type TrafficScheduler struct {
+	sync.Mutex
+	log     *base.LogObject
+	buckets map[TrafficClass]*tokenBucket
+	nowFunc func() time.Time
+}
+
+type tokenBucket struct {
+	budget     ClassBudget
+	tokens     float64 // in bytes
+	lastRefill time.Time
+}
+
+// NewTrafficScheduler is a constructor for TrafficScheduler.
+// Initially no class has a budget configured, i.e. everything is admitted.
+func NewTrafficScheduler(log *base.LogObject) *TrafficScheduler {
+	return &TrafficScheduler{
+		log:     log,
+		buckets: make(map[TrafficClass]*tokenBucket),
+		nowFunc: time.Now,
+	}
+}
+
+// SetBudget installs or replaces the rate budget of the given traffic class.
+// Budget with zero BytesPerSecond removes any rate-limiting for the class.
+func (s *TrafficScheduler) SetBudget(class TrafficClass, budget ClassBudget) {
+	s.Lock()
+	defer s.Unlock()
+	if budget.BytesPerSecond == 0 {
+		delete(s.buckets, class)
+		return
+	}
+	if budget.BurstBytes == 0 {
+		budget.BurstBytes = 10 * budget.BytesPerSecond
+	}
+	s.buckets[class] = &tokenBucket{
+		budget: budget,
+		// Start with a full bucket.
+		tokens:     float64(budget.BurstBytes),
+		lastRefill: s.nowFunc(),
+	}
+	s.log.Noticef("TrafficScheduler: class %v budget set to %d B/s "+
+		"(burst %d B)", class, budget.BytesPerSecond, budget.BurstBytes)
+}
+
+// Admit decides if a request of the given class and size may be sent now.
+// When admitted, the size is charged against the class budget.
+// Classes without a budget are always admitted. A request larger than the
+// bucket depth is admitted whenever the bucket is full, so that oversized
+// requests are merely delayed, never blocked forever.
+func (s *TrafficScheduler) Admit(class TrafficClass, size int64) bool {
+	s.Lock()
+	defer s.Unlock()
+	bucket := s.buckets[class]
+	if bucket == nil {
+		return true
+	}
+	now := s.nowFunc()
+	elapsed := now.Sub(bucket.lastRefill).Seconds()
+	if elapsed > 0 {
+		bucket.tokens += elapsed * float64(bucket.budget.BytesPerSecond)
+		if bucket.tokens > float64(bucket.budget.BurstBytes) {
+			bucket.tokens = float64(bucket.budget.BurstBytes)
+		}
+		bucket.lastRefill = now
+	}
+	if bucket.tokens < float64(size) &&
+		bucket.tokens < float64(bucket.budget.BurstBytes) {
+		s.log.Functionf("TrafficScheduler: class %v request of %d B exceeds "+
+			"the budget (%.0f B available)", class, size, bucket.tokens)
+		return false
+	}
+	// May drop below zero for an oversized request sent with a full bucket,
+	// in which case the class pays the debt off before the next admission.
+	bucket.tokens -= float64(size)
+	return true
+}
@@ -108,6 +108,63 @@ func (am *AgentMetrics) RecordSuccess(log *base.LogObject, ifname, url string, r
 	am.metrics[ifname] = m
 }
 
+// RecordBreakerTrip records a circuit breaker of a controller endpoint
+// tripping open (see circuitbreaker.go).
+func (am *AgentMetrics) RecordBreakerTrip(log *base.LogObject, ifname, url string) {
+	release := am.acquire(log)
+	defer release()
+	log.Tracef("RecordBreakerTrip(%s, %s)", ifname, url)
+	m := am.getInterfaceMetrics(ifname)
+	m.BreakerTripCount++
+	am.metrics[ifname] = m
+}
+
+// RecordBreakerFastFail records a request rejected immediately because
+// the circuit breaker of the target endpoint was open.
+func (am *AgentMetrics) RecordBreakerFastFail(log *base.LogObject, ifname, url string) {
+	release := am.acquire(log)
+	defer release()
+	log.Tracef("RecordBreakerFastFail(%s, %s)", ifname, url)
+	m := am.getInterfaceMetrics(ifname)
+	m.BreakerFastFailCount++
+	am.metrics[ifname] = m
+}
+
+// RecordEnvelopeWrap records a request wrapped into the controller API
+// envelope of the given version.
+func (am *AgentMetrics) RecordEnvelopeWrap(log *base.LogObject, ifname, version string) {
+	release := am.acquire(log)
+	defer release()
+	m := am.getInterfaceMetrics(ifname)
+	if m.EnvelopeCounters == nil {
+		m.EnvelopeCounters = make(map[string]types.EnvelopeMetrics)
+	}
+	env := m.EnvelopeCounters[version]
+	env.WrapMsgCount++
+	m.EnvelopeCounters[version] = env
+	am.metrics[ifname] = m
+}
+
+// RecordEnvelopeUnwrap records the outcome of unwrapping (and verifying)
+// a response framed by the controller API envelope of the given version.
+func (am *AgentMetrics) RecordEnvelopeUnwrap(log *base.LogObject, ifname, version string,
+	verifyFail bool) {
+	release := am.acquire(log)
+	defer release()
+	m := am.getInterfaceMetrics(ifname)
+	if m.EnvelopeCounters == nil {
+		m.EnvelopeCounters = make(map[string]types.EnvelopeMetrics)
+	}
+	env := m.EnvelopeCounters[version]
+	if verifyFail {
+		env.VerifyFailCount++
+	} else {
+		env.UnwrapMsgCount++
+	}
+	m.EnvelopeCounters[version] = env
+	am.metrics[ifname] = m
+}
+
 // Publish the recorded metrics through the given publisher.
 func (am *AgentMetrics) Publish(log *base.LogObject, publication pubsub.Publication, key string) error {
 	release := am.acquire(log)